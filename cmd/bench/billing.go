@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/services"
+)
+
+// seedBenchmarkFleet adds servers to project $2: every other one running with an
+// open usage segment, the rest stopped since with a closed segment still to be
+// written to the ledger. Each holds a disk from the time its segment started, and
+// running servers have an hour of egress then. Segments start up to 25 minutes
+// ago, or, if $3 is set, in the previous month, so the tick crosses a period
+// boundary: every segment and the egress are written to the ledger and the
+// project's previous period is invoiced.
+const seedBenchmarkFleet = `
+WITH seeded AS (
+    INSERT INTO servers (name, hostname, region, project, status, type, hourly_cost)
+    SELECT $2::TEXT || '-' || g, $2::TEXT || '-' || g || '.bench.invalid', 'us-east-1', $2::TEXT,
+        CASE WHEN g % 2 = 0 THEN 'running' ELSE 'stopped' END, 't2.micro', 0.0116
+    FROM generate_series(1, $1::INT) AS g
+    RETURNING id, status
+), started AS (
+    SELECT id, status, CASE WHEN $3::BOOLEAN
+        THEN date_trunc('month', NOW()) - INTERVAL '1 day' - random() * INTERVAL '20 days'
+        ELSE NOW() - INTERVAL '5 minutes' - random() * INTERVAL '20 minutes'
+    END AS started_at
+    FROM seeded
+), segments AS (
+    INSERT INTO usage_segments (server_id, server_type, hourly_rate, started_at, ended_at, billed_until)
+    SELECT id, 't2.micro', 0.0116, started_at,
+        CASE WHEN status = 'stopped' THEN started_at + INTERVAL '5 minutes' END, started_at
+    FROM started
+), egress AS (
+    INSERT INTO egress_usage (server_id, hour, gb)
+    SELECT id, date_trunc('hour', started_at), 0.01
+    FROM started
+    WHERE status = 'running'
+)
+INSERT INTO resource_segments (server_id, resource, quantity, started_at, billed_until)
+SELECT id, 'disk', 8, started_at, started_at
+FROM started`
+
+// seedBenchmarkScope gives the project a prepaid account and a budget, so the
+// tick draws it down and evaluates it.
+var seedBenchmarkScope = []string{
+	`INSERT INTO accounts (project, balance) VALUES ($1, 1e9)`,
+	`INSERT INTO budgets (name, scope_type, scope_value, amount) VALUES ($1, 'project', $1, 1e9)`,
+}
+
+// runBillingBenchmark seeds a fleet of servers under a project of its own, with a
+// prepaid account and a budget, runs one whole billing tick, from the per-server
+// updates to the reaper, and reports how long each step took against the billing
+// interval. Seeding and tick run in one transaction that is rolled back, so
+// nothing the benchmark writes outlives it, ledger entries and invoices included;
+// the tick also processes the rest of the database, as the daemon's next tick
+// would.
+func runBillingBenchmark(args []string) int {
+	fs := flag.NewFlagSet("billing", flag.ExitOnError)
+	servers := fs.Int("servers", 100000, "number of servers to seed")
+	periodBoundary := fs.Bool("period-boundary", false, "start the fleet's usage in the previous month, so the tick writes it all to the ledger and invoices it")
+	interval := fs.Duration("interval", 0, "billing interval to compare against; BILLING_DAEMON_INTERVAL if zero")
+	_ = fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return 1
+	}
+	if *interval == 0 {
+		*interval = cfg.BillingDaemonInterval
+	}
+
+	ctx := services.WithEventActor(context.Background(), services.ActorBilling)
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+		cfg.DBSSLMode,
+	)
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer conn.Close(ctx)
+
+	// Nothing the benchmark writes outlives it
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to begin transaction: %v\n", err)
+		return 1
+	}
+	defer tx.Rollback(ctx)
+
+	project := fmt.Sprintf("bench-%d", time.Now().Unix())
+	start := time.Now()
+	if _, err := tx.Exec(ctx, seedBenchmarkFleet, *servers, project, *periodBoundary); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to seed servers: %v\n", err)
+		return 1
+	}
+	for _, statement := range seedBenchmarkScope {
+		if _, err := tx.Exec(ctx, statement, project); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to seed account and budget: %v\n", err)
+			return 1
+		}
+	}
+	if _, err := tx.Exec(ctx, "ANALYZE servers, usage_segments, resource_segments, egress_usage"); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to analyze seeded tables: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Seeded %d servers in project %s in %v\n", *servers, project, time.Since(start).Round(time.Millisecond))
+
+	// The same services the server runs the daemon with, inside the transaction
+	dbClient := database.NewTxClient(tx)
+	logger := zap.NewNop()
+	ipAllocator := services.NewIPAllocator(dbClient.Queries, logger)
+	billingService := services.NewBillingService(dbClient, logger, cfg)
+	serverService := services.NewServerService(dbClient, ipAllocator, logger, cfg)
+	budgetService := services.NewBudgetService(dbClient.Queries, serverService, logger, cfg)
+	accountService := services.NewAccountService(dbClient.Queries, billingService, serverService, logger, cfg)
+	reaperService := services.NewReaperService(dbClient.Queries, serverService, logger, cfg)
+	daemon := services.NewBillingAndReaperDaemon(dbClient.Queries, billingService, budgetService, accountService, reaperService, serverService, logger, *interval)
+
+	start = time.Now()
+	stats, err := daemon.Tick(ctx, start)
+	total := time.Since(start)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Billing tick failed: %v\n", err)
+		return 1
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "step\tduration\t")
+	for _, step := range stats.Steps {
+		fmt.Fprintf(tw, "%s\t%v\t\n", step.Name, step.Duration.Round(time.Microsecond))
+	}
+	fmt.Fprintf(tw, "total\t%v\t\n", total.Round(time.Microsecond))
+	tw.Flush()
+
+	fmt.Printf("\nTick took %.1f%% of the %v interval\n", 100*total.Seconds()/interval.Seconds(), *interval)
+	if total > *interval {
+		fmt.Println("FAIL: the tick does not fit in the interval")
+		return 1
+	}
+	fmt.Println("PASS: the tick fits in the interval")
+	return 0
+}
@@ -0,0 +1,36 @@
+// Command bench runs the simulator's benchmarks that need a database as a
+// standalone binary: billing runs a whole billing tick against the database of
+// the server's configuration, on a fleet it seeds in a transaction that is rolled
+// back afterwards. The IP allocation micro-benchmarks are regular Go benchmarks:
+// go test -bench . ./internal/ipam.
+//
+//	go run ./cmd/bench billing -servers 100000
+//	go run ./cmd/bench billing -servers 100000 -period-boundary
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s <benchmark> [flags]\n\nBenchmarks:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "  billing   duration of each step of a billing tick for a large fleet\n")
+	}
+	flag.Parse()
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch flag.Arg(0) {
+	case "billing":
+		os.Exit(runBillingBenchmark(flag.Args()[1:]))
+	default:
+		fmt.Fprintf(os.Stderr, "unknown benchmark %q\n", flag.Arg(0))
+		flag.Usage()
+		os.Exit(2)
+	}
+}
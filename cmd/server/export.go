@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/services"
+)
+
+// runExport writes the cost export of a date range to a file or stdout, using the
+// same database configuration as the server but without starting it:
+//
+//	server export -from 2023-10-01 -to 2023-11-01 -format ndjson -out costs.ndjson
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	fromFlag := fs.String("from", "", "start of the range, YYYY-MM-DD or RFC 3339 (required)")
+	toFlag := fs.String("to", "", "end of the range (exclusive), YYYY-MM-DD or RFC 3339 (required)")
+	format := fs.String("format", services.ExportFormatCSV, "export format, csv or ndjson")
+	project := fs.String("project", "", "only export servers of this project")
+	outPath := fs.String("out", "", "file to write, stdout if empty")
+	_ = fs.Parse(args)
+
+	from, err := services.ParseExportTime(*fromFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp\n")
+		return 2
+	}
+	to, err := services.ParseExportTime(*toFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "-to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp\n")
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
+		return 1
+	}
+	// Logs go to stderr so the export can be written to stdout
+	logConfig := zap.NewDevelopmentConfig()
+	logConfig.Level = zap.NewAtomicLevelAt(zap.WarnLevel)
+	logger, err := logConfig.Build()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error initializing logger: %v\n", err)
+		return 1
+	}
+
+	ctx := context.Background()
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+		cfg.DBSSLMode,
+	)
+	dbClient, err := database.NewDBClient(ctx, databaseURL, cfg.DBMaxRetries, cfg.DBRetryDelay, logger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect to database: %v\n", err)
+		return 1
+	}
+	defer dbClient.Close()
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to create %s: %v\n", *outPath, err)
+			return 1
+		}
+		defer file.Close()
+		out = file
+	}
+	exporter, err := services.NewCostExportWriter(out, *format)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 2
+	}
+
+	billingService := services.NewBillingService(dbClient, logger, cfg)
+	if err := billingService.ExportCosts(ctx, from, to, *project, exporter.Write); err != nil {
+		fmt.Fprintf(os.Stderr, "Export failed: %v\n", err)
+		return 1
+	}
+	if err := exporter.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write export: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d rows\n", exporter.Rows())
+	return 0
+}
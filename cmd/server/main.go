@@ -35,6 +35,10 @@ var logger *zap.Logger
 
 // go:generate swag init --parseDependency --parseInternal
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		os.Exit(runExport(os.Args[2:]))
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading configuration: %v\n", err)
@@ -86,25 +90,93 @@ func main() {
 	// queries  // Initialize sqlc queries object
 	dbCleanup := services.NewIPAllocator(dbClient.Queries, logger)
 
-	// Pre-populate IP pool in the database (this will always run after clearing data)
-	if err := dbCleanup.TerminateAllServers(ctx, cfg.IPAllocationCIDR, cfg.IPExclusionList); err != nil {
-		logger.Fatal("Failed to pre-populate IP pool", zap.Error(err), zap.String("cidr", cfg.IPAllocationCIDR))
+	// Background jobs run on whichever replica holds their lease
+	leaderElector := services.NewLeaderElector(dbClient, logger, cfg)
+
+	// Reset servers and IP bindings, unless other replicas are already running them
+	err = leaderElector.RunIfFirstReplica(ctx, func() error {
+		return dbCleanup.TerminateAllServers(ctx)
+	})
+	if err != nil {
+		logger.Fatal("Failed to reset servers", zap.Error(err))
+	}
+
+	// Register the default IP pool; addresses are allocated on demand from its bitmap
+	if err := dbCleanup.RegisterPool(ctx, services.DefaultIPPoolName, cfg.IPAllocationCIDR, cfg.IPExclusionList, cfg.IPAllocationStrategy); err != nil {
+		logger.Fatal("Failed to register IP pool", zap.Error(err), zap.String("cidr", cfg.IPAllocationCIDR))
 	} else {
-		logger.Info("IP pool pre-populated successfully", zap.String("cidr", cfg.IPAllocationCIDR))
+		logger.Info("IP pool registered successfully", zap.String("cidr", cfg.IPAllocationCIDR), zap.String("strategy", cfg.IPAllocationStrategy))
 	}
 
-	// Start a Go routine to run the billing and reaper daemon
-	billingAndReaperDaemon := services.NewBillingAndReaperDaemon(dbClient.Queries, logger, cfg.BillingDaemonInterval)
-	go billingAndReaperDaemon.Start(ctx)
-	logger.Info("Billing and Reaper daemon started in background", zap.Duration("interval", cfg.BillingDaemonInterval))
+	// Register the per-region private pools and the public pool used for NAT
+	for region, cidr := range cfg.PrivateIPPools {
+		if err := dbCleanup.RegisterPool(ctx, services.PrivateIPPoolName(region), cidr, cfg.PrivateIPExclusions, cfg.IPAllocationStrategy); err != nil {
+			logger.Fatal("Failed to register private IP pool", zap.Error(err), zap.String("region", region), zap.String("cidr", cidr))
+		}
+	}
+	if err := dbCleanup.RegisterPool(ctx, services.PublicIPPoolName, cfg.PublicIPCIDR, nil, cfg.PublicIPStrategy); err != nil {
+		logger.Fatal("Failed to register public IP pool", zap.Error(err), zap.String("cidr", cfg.PublicIPCIDR))
+	}
 
-	// Start a Go routine to update Prometheus metrics
+	// Register the additional named pools used for secondary interfaces and IPs
+	for _, pool := range cfg.IPPools {
+		if err := dbCleanup.RegisterPool(ctx, pool.Name, pool.CIDR, nil, pool.Strategy); err != nil {
+			logger.Fatal("Failed to register IP pool", zap.Error(err), zap.String("pool", pool.Name), zap.String("cidr", pool.CIDR))
+		}
+	}
+
+	// Start a Go routine to run the billing and reaper daemon
+	billingService := services.NewBillingService(dbClient, logger, cfg)
+	if err := billingService.SeedPrices(ctx); err != nil {
+		logger.Fatal("Failed to seed price catalog", zap.Error(err))
+	}
+	if err := billingService.SeedExchangeRates(ctx); err != nil {
+		logger.Fatal("Failed to seed exchange rates", zap.Error(err))
+	}
+	serverService := services.NewServerService(dbClient, dbCleanup, logger, cfg)
+	budgetService := services.NewBudgetService(dbClient.Queries, serverService, logger, cfg)
+	accountService := services.NewAccountService(dbClient.Queries, billingService, serverService, logger, cfg)
+	reaperService := services.NewReaperService(dbClient.Queries, serverService, logger, cfg)
+	recommendationService := services.NewRecommendationService(dbClient.Queries, serverService, logger, cfg)
+	billingAndReaperDaemon := services.NewBillingAndReaperDaemon(dbClient.Queries, billingService, budgetService, accountService, reaperService, serverService, logger, cfg.BillingDaemonInterval)
+	leaderElector.Register("billing", billingAndReaperDaemon.Start)
+
+	// Move the simulated spot market and interrupt outbid spot servers
+	spotMarket := services.NewSpotMarketDaemon(dbClient.Queries, serverService, logger, cfg)
+	leaderElector.Register("spot_market", spotMarket.Start)
+
+	// Generate and prune server telemetry
+	telemetryService := services.NewTelemetryService(dbClient.Queries, logger, cfg)
+	leaderElector.Register("telemetry", telemetryService.Start)
+
+	// Check that servers and their addresses agree, repairing them if enabled
+	consistencyService := services.NewConsistencyService(dbClient.Queries, serverService, logger, cfg)
+	leaderElector.Register("consistency", consistencyService.Start)
+
+	// Move servers stuck in a transient status to error
+	watchdog := services.NewStuckStateWatchdog(dbClient.Queries, serverService, logger, cfg)
+	leaderElector.Register("watchdog", watchdog.Start)
+
+	// Update Prometheus metrics
 	metricsUpdater := services.NewMetricsUpdater(ctx, cancel, dbClient.Queries, cfg, logger)
-	go metricsUpdater.Start(ctx)
-	logger.Info("Metrics updater started in background")
+	leaderElector.Register("metrics", metricsUpdater.Start)
+
+	go leaderElector.Start(ctx)
+	logger.Info("Background jobs started under leader election",
+		zap.Bool("enabled", cfg.LeaderElection),
+		zap.Duration("billing_interval", cfg.BillingDaemonInterval),
+	)
+
+	// Start the embedded DNS server for server hostnames
+	if cfg.DNSEnabled {
+		dnsServer := services.NewDNSServer(dbClient.Queries, logger, cfg)
+		if err := dnsServer.Start(ctx); err != nil {
+			logger.Fatal("Failed to start DNS server", zap.Error(err))
+		}
+	}
 
 	// Initialize server API
-	serverAPI := api.NewServerAPI(cfg, dbClient, services.NewServerService(dbClient.Queries, dbCleanup, logger, cfg), cfg, logger)
+	serverAPI := api.NewServerAPI(cfg, dbClient, serverService, billingService, budgetService, accountService, reaperService, telemetryService, consistencyService, recommendationService, spotMarket, leaderElector, logger)
 	router := serverAPI.Routes()
 
 	httpServer := &http.Server{
@@ -122,6 +194,24 @@ func main() {
 		}
 	}()
 
+	// The metadata service gets its own listener, like the link-local endpoint of a real cloud
+	var metadataServer *http.Server
+	if cfg.MetadataEnabled {
+		metadataServer = &http.Server{
+			Addr:              cfg.MetadataListenAddr,
+			Handler:           serverAPI.MetadataRoutes(),
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      10 * time.Second,
+			IdleTimeout:       120 * time.Second,
+		}
+		go func() {
+			logger.Info("Metadata server starting", zap.String("address", metadataServer.Addr))
+			if err := metadataServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Fatal("Metadata server failed to start", zap.Error(err))
+			}
+		}()
+	}
+
 	stopSignal := make(chan os.Signal, 1)
 	signal.Notify(stopSignal, syscall.SIGINT, syscall.SIGTERM)
 	<-stopSignal
@@ -137,6 +227,12 @@ func main() {
 		logger.Info("HTTP server gracefully stopped")
 	}
 
+	if metadataServer != nil {
+		if err := metadataServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Metadata server shutdown failed", zap.Error(err))
+		}
+	}
+
 	cancel()
 	logger.Info("Application exiting")
 }
@@ -15,55 +15,108 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/healthz": {
+        "/accounts/{project}": {
             "get": {
-                "description": "Checks if the application is alive and responding.",
+                "description": "Retrieves the prepaid balance of a project in USD. The billing daemon draws it down as the project's servers accrue cost; once it is exhausted the running servers are suspended until the account is topped up.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "accounts"
+                ],
+                "summary": "Retrieve a project's prepaid account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the project",
+                        "name": "project",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
-                "summary": "Application Liveness Probe",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.AccountResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/readyz": {
+        "/accounts/{project}/events": {
             "get": {
-                "description": "Checks if the application is ready to serve traffic, including dependencies like the database.",
+                "description": "Lists top-ups, low-balance warnings, suspensions and resumptions of a project's account, newest first.",
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Health"
+                    "accounts"
+                ],
+                "summary": "List the events of a project's prepaid account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the project",
+                        "name": "project",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
                 ],
-                "summary": "Application Readiness Probe",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListAccountEventsResponse"
                         }
                     },
-                    "503": {
-                        "description": "Service Unavailable",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     }
                 }
             }
         },
-        "/server": {
+        "/accounts/{project}/topup": {
             "post": {
-                "description": "Provisions a new virtual server with specified details.",
+                "description": "Adds funds in USD to a project's balance, opening its prepaid account on the first top-up; from then on the project's servers draw it down. If the project was suspended and the balance is positive again, its suspended servers are resumed.",
                 "consumes": [
                     "application/json"
                 ],
@@ -71,25 +124,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "server"
+                    "accounts"
                 ],
-                "summary": "Provision a new virtual server",
+                "summary": "Top up a project's prepaid account",
                 "parameters": [
                     {
-                        "description": "Server provision request",
-                        "name": "request",
+                        "type": "string",
+                        "description": "Name of the project",
+                        "name": "project",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Top-up",
+                        "name": "topup",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ProvisionServerRequest"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.TopUpAccountRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.AccountResponse"
                         }
                     },
                     "400": {
@@ -98,11 +158,31 @@ const docTemplate = `{
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     },
-                    "409": {
-                        "description": "Conflict",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
+                    }
+                }
+            }
+        },
+        "/admin/consistency": {
+            "get": {
+                "description": "Lists the inconsistencies between servers, their IP addresses and NAT mappings, without repairing them: live servers whose address is not the primary address of their primary interface (address_mismatch), addresses and active NAT mappings still held by terminated servers (ip_on_terminated_server, nat_on_terminated_server), addresses reserved but never bound to a server (orphaned_ip) and servers provisioning for longer than the provisioning entry of STUCK_STATE_TIMEOUTS (stuck_provisioning). With CONSISTENCY_AUTO_REPAIR the consistency daemon repairs them every CONSISTENCY_INTERVAL and records each repair in the server's lifecycle logs.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Check servers against their addresses",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ConsistencyReportResponse"
+                        }
                     },
                     "500": {
                         "description": "Internal Server Error",
@@ -113,58 +193,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/servers": {
-            "get": {
-                "description": "Lists all virtual servers, filterable by region, status, type; supports pagination (limit, offset); sorted (newest first).",
+        "/admin/credits": {
+            "post": {
+                "description": "Grants a project a promotional credit in USD, scoped to all its charges, one server type or one region. When a billing period overlapping startsAt to expiresAt is invoiced, credits pay for what is left after volume tiers and discounts, soonest to expire first, each as its own invoice line.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "servers"
+                    "admin"
                 ],
-                "summary": "List all servers",
+                "summary": "Grant a credit",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "Filter by region",
-                        "name": "region",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by status (e.g., provisioning, running, stopped, terminated, error)",
-                        "name": "status",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Filter by server type (e.g., t2.micro, m5.large)",
-                        "name": "type",
-                        "in": "query"
-                    },
-                    {
-                        "maximum": 100,
-                        "minimum": 1,
-                        "type": "integer",
-                        "default": 10,
-                        "description": "Number of results to return (default 10, max 100)",
-                        "name": "limit",
-                        "in": "query"
-                    },
-                    {
-                        "minimum": 0,
-                        "type": "integer",
-                        "default": 0,
-                        "description": "Number of results to skip",
-                        "name": "offset",
-                        "in": "query"
+                        "description": "Credit",
+                        "name": "credit",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateCreditRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ListServersResponse"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreditResponse"
                         }
                     },
                     "400": {
@@ -182,30 +239,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/servers/{serverID}": {
-            "get": {
-                "description": "Retrieves full metadata for a specific virtual server, including live uptime and billing.",
+        "/admin/discounts": {
+            "post": {
+                "description": "Adds a percentage discount on the compute charges of a server type in a region (either may be empty to match any), for billing periods overlapping startsAt to expiresAt. When a period is invoiced the largest matching discount applies, after volume tiers and before credits, as its own invoice line.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "servers"
+                    "admin"
                 ],
-                "summary": "Retrieve full metadata for a server",
+                "summary": "Add a discount",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID of the server",
-                        "name": "serverID",
-                        "in": "path",
-                        "required": true
+                        "description": "Discount",
+                        "name": "discount",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateDiscountRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.DiscountResponse"
                         }
                     },
                     "400": {
@@ -214,12 +276,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
-                        }
-                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -229,9 +285,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/servers/{serverID}/action": {
+        "/admin/exchange-rates": {
             "post": {
-                "description": "Performs actions like start, stop, reboot, terminate on a virtual server. Enforces valid FSM transitions.",
+                "description": "Adds a version of a currency's exchange rate, in effect from effectiveFrom (default now) until the next version. Issued invoices keep the rate they were issued at.",
                 "consumes": [
                     "application/json"
                 ],
@@ -239,32 +295,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "servers"
+                    "admin"
                 ],
-                "summary": "Perform an action on a server",
+                "summary": "Add an exchange rate",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID of the server",
-                        "name": "serverID",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Action to perform (start, stop, reboot, terminate)",
-                        "name": "request",
+                        "description": "Exchange rate version",
+                        "name": "rate",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerActionRequest"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateExchangeRateRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ExchangeRateResponse"
                         }
                     },
                     "400": {
@@ -273,12 +322,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
-                        }
-                    },
                     "409": {
                         "description": "Conflict",
                         "schema": {
@@ -294,30 +337,35 @@ const docTemplate = `{
                 }
             }
         },
-        "/servers/{serverID}/logs": {
-            "get": {
-                "description": "Retrieves the last 100 lifecycle events for a specific virtual server.",
+        "/admin/pricing": {
+            "post": {
+                "description": "Adds a price version for a server type in a region (or \"*\" for all regions without their own price), taking effect at a future time. Usage from that time on is billed at the new price.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "servers"
+                    "admin"
                 ],
-                "summary": "Return last 100 lifecycle events",
+                "summary": "Schedule a price change",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "ID of the server",
-                        "name": "serverID",
-                        "in": "path",
-                        "required": true
+                        "description": "Price version",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreatePriceRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerLogsResponse"
+                            "$ref": "#/definitions/go-virtual-server_internal_models.PriceResponse"
                         }
                     },
                     "400": {
@@ -326,8 +374,8 @@ const docTemplate = `{
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
                         }
@@ -340,78 +388,4058 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "go-virtual-server_internal_models.BillingInfo": {
-            "type": "object",
-            "properties": {
-                "billingModel": {
-                    "description": "e.g., \"hourly\", \"monthly\", \"per_request\"",
-                    "type": "string",
-                    "example": "hourly"
-                },
-                "currencyUnit": {
-                    "description": "e.g., \"USD\", \"EUR\", \"GBP\"",
-                    "type": "string",
-                    "example": "USD"
-                },
-                "estimatedCurrentCost": {
-                    "description": "Estimated cost for the current (partial) billing cycle or total accumulated cost",
-                    "type": "number",
-                    "example": 0.01
-                },
-                "totalUptimeSeconds": {
-                    "description": "Total cumulative active time for the server",
-                    "type": "integer",
-                    "example": 3600
-                },
+        },
+        "/admin/pricing/spot": {
+            "post": {
+                "description": "Sets the spot price of a server type in a region now, to test preemption handling. Running spot servers bidding below it get their interruption notice and are stopped or terminated once SPOT_INTERRUPTION_NOTICE has passed. The market daemon keeps moving the price from there.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Move a spot price",
+                "parameters": [
+                    {
+                        "description": "Spot price",
+                        "name": "price",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.SetSpotPriceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.SpotPriceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reaper/policies": {
+            "post": {
+                "description": "Adds a rule to the idle reaper, which the billing daemon applies on every tick. Servers of the given type, region and tag (any if empty) that have been stopped (stopped_for), running (running_for) or running with CPU telemetry averaging at most maxCpuPercent (idle_for) for durationSeconds are stopped, terminated or posted to webhookUrl (notify). Each server gets the action of the first policy, in creation order, that applies to it, once per status; servers tagged with REAPER_EXEMPT_TAG are never reaped. stopped_for only counts servers stopped through the API or by the reaper, not by budget enforcement, spot interruptions or other daemons.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reaper"
+                ],
+                "summary": "Create a reaper policy",
+                "parameters": [
+                    {
+                        "description": "Reaper policy",
+                        "name": "policy",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateReaperPolicyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ReaperPolicyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/reaper/policies/{policyID}": {
+            "delete": {
+                "description": "Deletes a reaper policy and the record of its actions. Servers it stopped or terminated stay that way.",
+                "tags": [
+                    "reaper"
+                ],
+                "summary": "Delete a reaper policy",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the reaper policy",
+                        "name": "policyID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/volume-tiers": {
+            "post": {
+                "description": "Adds a tier to the volume pricing of a server type: compute hours of a project's billing period beyond fromHours, across regions, get percentOff until the next tier. Tiers are applied when the period is invoiced, before discounts and credits, as their own invoice lines.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Add a volume pricing tier",
+                "parameters": [
+                    {
+                        "description": "Volume tier",
+                        "name": "tier",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateVolumeTierRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.VolumeTierResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/billing/export": {
+            "get": {
+                "description": "Exports one row per server, charge and hour over [from, to) in a FOCUS-style cost-and-usage schema (resource ID, type, region, tags, consumed quantity, list unit price, list and effective cost), derived from metered uptime, resources and egress at catalog prices. Amounts are in USD. The range may span at most 366 days.",
+                "produces": [
+                    "text/csv",
+                    "application/x-ndjson"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Export hourly usage and cost",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Start of the range, a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+                        "name": "from",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range (exclusive), a date (YYYY-MM-DD) or an RFC 3339 timestamp",
+                        "name": "to",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only export servers of this project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "csv",
+                            "ndjson"
+                        ],
+                        "type": "string",
+                        "description": "Export format (default csv). Only csv and ndjson are implemented; Parquet is not supported",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Export rows",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/billing/forecast": {
+            "get": {
+                "description": "Projects the spend of every project to the end of the current billing period, on the same basis as the server forecast.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Forecast spend per project",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only forecast this project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the amounts, defaults to the project's, or USD for all projects",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.BillingForecastResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "List budgets",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListBudgetsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Sets a spending limit on the servers of a project, a region or a tag value. The billing daemon evaluates budgets on every tick: each threshold crossed raises one alert per period (posted to webhookUrl if set), and enforced budgets stop, without terminating, the running servers in scope once the amount is spent.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Create a budget",
+                "parameters": [
+                    {
+                        "description": "Budget",
+                        "name": "budget",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateBudgetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.BudgetResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/{budgetID}": {
+            "get": {
+                "description": "Retrieves a budget with its spend in the current period.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Retrieve a budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the budget",
+                        "name": "budgetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.BudgetResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Deletes a budget and its alerts. Servers stopped by the budget stay stopped.",
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "Delete a budget",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the budget",
+                        "name": "budgetID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/{budgetID}/alerts": {
+            "get": {
+                "description": "Lists the thresholds a budget has crossed, newest first, with the outcome of their webhook.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "budgets"
+                ],
+                "summary": "List budget alerts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the budget",
+                        "name": "budgetID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListBudgetAlertsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/credits": {
+            "get": {
+                "description": "Lists credits, newest first, with the amount left.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List credits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListCreditsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/discounts": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List discounts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListDiscountsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/exchange-rates": {
+            "get": {
+                "description": "Lists the exchange rate versions billing responses and invoices are converted at, per unit of USD.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List exchange rates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by currency",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListExchangeRatesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Checks if the application is alive and responding.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Application Liveness Probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/invoices": {
+            "get": {
+                "description": "Lists issued invoices, newest period first, without their line items. Responds with CSV when format=csv or the Accept header asks for text/csv.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List invoices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by billing period (YYYY-MM)",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Restate invoices in this currency, at the rate in effect when their period ended",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListInvoicesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/invoices/{invoiceID}": {
+            "get": {
+                "description": "Retrieves an invoice with its line items, grouped by server, charge type and unit price. Responds with CSV (one row per line) when format=csv or the Accept header asks for text/csv.",
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Retrieve an invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the invoice",
+                        "name": "invoiceID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Restate the invoice in this currency, at the rate in effect when its period ended",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "json",
+                            "csv"
+                        ],
+                        "type": "string",
+                        "description": "Response format",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.InvoiceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/nat-mappings": {
+            "get": {
+                "description": "Lists 1:1 NAT mappings between public and private addresses, newest first. Released mappings are kept as history unless active=true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "network"
+                ],
+                "summary": "List NAT mappings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by server ID",
+                        "name": "serverId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by public or private address",
+                        "name": "address",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Only return mappings that are currently active",
+                        "name": "active",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListNATMappingsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pricing": {
+            "get": {
+                "description": "Lists every price version, including scheduled ones, by type, region and effective time. Region \"*\" prices every region without a price of its own. With ` + "`" + `at` + "`" + `, only the versions in effect at that time are returned.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List the price catalog",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by server type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by catalog region (\\",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only versions in effect at this time (RFC 3339)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListPricesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pricing/quote": {
+            "post": {
+                "description": "Prices a number of servers of one type in one region running for the given hours from now, broken down by the price versions in effect, including scheduled price changes. Each server's time is rounded as its billing model charges it; reserved quotes draw down the project's remaining reserved hours first.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Quote the cost of servers",
+                "parameters": [
+                    {
+                        "description": "Servers to quote",
+                        "name": "quote",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.QuoteRequest"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the amounts, defaults to the project's",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.QuoteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/pricing/spot": {
+            "get": {
+                "description": "Lists the current simulated spot price of every server type and region with spot servers. The market daemon moves each price every SPOT_MARKET_INTERVAL.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List spot prices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by server type",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by region",
+                        "name": "region",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListSpotPricesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/projects/{project}": {
+            "get": {
+                "description": "Retrieves the currency a project is billed in; projects that were never configured are billed in USD.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Retrieve a project's billing settings",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the project",
+                        "name": "project",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ProjectResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Sets the preferred currency of a project. Invoices of periods closed from now on are issued in it, and billing responses about the project default to it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Set a project's currency",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name of the project",
+                        "name": "project",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Billing settings",
+                        "name": "settings",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.UpdateProjectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ProjectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks if the application is ready to serve traffic, including dependencies like the database, and reports which background jobs this replica runs as their leader.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Application Readiness Probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ReadyzResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/reaper/actions": {
+            "get": {
+                "description": "Lists the servers the reaper acted on, newest first, with the outcome of each action.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reaper"
+                ],
+                "summary": "List reaper actions",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only actions of this policy",
+                        "name": "policyId",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListReaperActionsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reaper/dry-run": {
+            "get": {
+                "description": "Evaluates the reaper policies without acting on any server, listing each server that would be reaped with the policy that applies to it.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reaper"
+                ],
+                "summary": "List what the reaper would do now",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ReaperDryRunResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reaper/policies": {
+            "get": {
+                "description": "Lists the idle reaper's policies in the order they are applied.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "reaper"
+                ],
+                "summary": "List reaper policies",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListReaperPoliciesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations": {
+            "get": {
+                "description": "Suggests how to lower the cost of running servers from their running time and CPU telemetry over RIGHTSIZING_LOOKBACK; servers younger than that are left out. Servers whose CPU load never exceeded 5% are to be stopped; on-demand servers whose peak load would stay under RIGHTSIZING_MAX_CPU_PERCENT on a smaller, cheaper type are to be downsized to the smallest such type; on-demand servers of the right size that ran at least RIGHTSIZING_RESERVE_MIN_UPTIME of the time are to be covered by reserved hours. Savings are estimated from the servers' hourly cost and the catalog price, for a month of running as much as over the lookback.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List right-sizing recommendations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only servers of this project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the costs and savings, defaults to the project's",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListRecommendationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/recommendations/apply": {
+            "post": {
+                "description": "Applies the current recommendation of a kind to a server through the regular server lifecycle: downsize changes its type and hourly cost (a running server keeps running), stop stops it. The change is recorded in the server's lifecycle logs. Reserve recommendations are applied by buying a reservation with POST /reservations.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Apply a right-sizing recommendation",
+                "parameters": [
+                    {
+                        "description": "Recommendation to apply",
+                        "name": "recommendation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ApplyRecommendationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ApplyRecommendationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations": {
+            "get": {
+                "description": "Lists reservations, newest first, with the hours used so far.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List reservations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListReservationsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Prepays hours of a server type in a region for a project, at the current catalog price less the reservation discount. The amount is charged upfront; running time of the project's servers on the reserved billing model draws the hours down before it is charged, until the reservation expires.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Reserve server hours",
+                "parameters": [
+                    {
+                        "description": "Reservation",
+                        "name": "reservation",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.CreateReservationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ReservationResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/reservations/{reservationID}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Retrieve a reservation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the reservation",
+                        "name": "reservationID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ReservationResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/server": {
+            "post": {
+                "description": "Provisions a new virtual server with specified details.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "server"
+                ],
+                "summary": "Provision a new virtual server",
+                "parameters": [
+                    {
+                        "description": "Server provision request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ProvisionServerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers": {
+            "get": {
+                "description": "Lists all virtual servers, filterable by project, region, status, type; supports pagination (limit, offset); sorted (newest first).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "servers"
+                ],
+                "summary": "List all servers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Filter by project",
+                        "name": "project",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by region",
+                        "name": "region",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by status (e.g., provisioning, running, stopped, suspended, terminated, error)",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Filter by server type (e.g., t2.micro, m5.large)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Number of results to return (default 10, max 100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 0,
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Number of results to skip",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListServersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}": {
+            "get": {
+                "description": "Retrieves full metadata for a specific virtual server, including live uptime and billing.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "servers"
+                ],
+                "summary": "Retrieve full metadata for a server",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of billingInfo, defaults to the project's",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Renames a server. Its hostname follows the new name and is resolvable by the embedded DNS server right away.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "servers"
+                ],
+                "summary": "Rename a server",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "New server name",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.RenameServerRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/action": {
+            "post": {
+                "description": "Performs actions like start, stop, reboot, terminate on a virtual server. Enforces valid FSM transitions. Suspended servers can only be terminated; they run again once their project's account is topped up, and servers of a project whose prepaid balance is exhausted cannot be started.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "servers"
+                ],
+                "summary": "Perform an action on a server",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Action to perform (start, stop, reboot, terminate)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerActionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/forecast": {
+            "get": {
+                "description": "Projects a server's spend to the end of the current billing period: the usage so far, priced as it is invoiced, plus the rest of the period. A running server is expected to keep running; a stopped one to run as much as it did over the last FORECAST_LOOKBACK. Disk and public IPs it holds are expected to be kept.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "Forecast a server's spend",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Currency of the amounts, defaults to the project's",
+                        "name": "currency",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ForecastResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/interfaces": {
+            "post": {
+                "description": "Attaches a secondary network interface to a server, with one address from the requested IP pool.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "network"
+                ],
+                "summary": "Attach a network interface",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "IP pool for the interface's address",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.AttachInterfaceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.NetworkInterfaceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/interfaces/{interfaceID}": {
+            "delete": {
+                "description": "Detaches a secondary network interface from a server and releases all of its addresses. The primary interface cannot be detached.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "network"
+                ],
+                "summary": "Detach a network interface",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the network interface",
+                        "name": "interfaceID",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/interfaces/{interfaceID}/ips": {
+            "post": {
+                "description": "Assigns an additional address from the requested IP pool to one of the server's network interfaces.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "network"
+                ],
+                "summary": "Assign a secondary IP",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID of the network interface",
+                        "name": "interfaceID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "IP pool for the secondary address",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.AssignIPRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.NetworkInterfaceResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/logs": {
+            "get": {
+                "description": "Retrieves the lifecycle events of a server, newest first: status changes (with fromStatus and toStatus), resizes, network changes and daemon actions, each with the actor that caused it and the request ID. Events are kept for SERVER_EVENTS_RETENTION.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "servers"
+                ],
+                "summary": "List a server's lifecycle events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events of these types, comma-separated",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events at or after this RFC 3339 timestamp",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only events before this RFC 3339 timestamp",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 10,
+                        "description": "Page size (1-100)",
+                        "name": "limit",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "default": 0,
+                        "description": "Page offset",
+                        "name": "offset",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ServerLogsResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/servers/{serverID}/telemetry": {
+            "get": {
+                "description": "Returns a server's CPU load (average and peak) and network traffic over [from, to) in steps of step, one point per step with samples. Telemetry is kept for TELEMETRY_RETENTION at one-minute resolution.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "telemetry"
+                ],
+                "summary": "Retrieve a server's utilization over time",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Start of the range, an RFC 3339 timestamp; defaults to an hour before to",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "End of the range (exclusive), an RFC 3339 timestamp; defaults to now",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Width of a point, a whole number of minutes such as 1m, 15m or 1h (default 1m); at most 1440 points",
+                        "name": "step",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.TelemetrySeriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Records a utilization sample of a running server, doubling as its heartbeat: CPU load in percent and the bytes received and sent since the previous sample. Samples are folded into one point per minute. With TELEMETRY_GENERATOR_ENABLED the simulator also reports synthesized samples for every running server.",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "telemetry"
+                ],
+                "summary": "Report a server's utilization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID of the server",
+                        "name": "serverID",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Sample",
+                        "name": "sample",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.RecordTelemetryRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/volume-tiers": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "billing"
+                ],
+                "summary": "List volume pricing tiers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.ListVolumeTiersResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/go-virtual-server_internal_util.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "go-virtual-server_internal_models.AccountEventResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 0
+                },
+                "balance": {
+                    "description": "After the event",
+                    "type": "number",
+                    "example": 9.75
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9c0d1e2f-3a4b-4c5d-e6f7-a8b9c0d1e2f3"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "type": {
+                    "description": "topup, low_balance, exhausted or resumed",
+                    "type": "string",
+                    "example": "low_balance"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.AccountResponse": {
+            "type": "object",
+            "properties": {
+                "balance": {
+                    "type": "number",
+                    "example": 42.5
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "drawnUntil": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "lowBalanceThreshold": {
+                    "type": "number",
+                    "example": 10
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "suspended": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "suspendedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "updatedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ApplyRecommendationRequest": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "description": "downsize or stop",
+                    "type": "string",
+                    "example": "downsize"
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ApplyRecommendationResponse": {
+            "type": "object",
+            "properties": {
+                "recommendation": {
+                    "$ref": "#/definitions/go-virtual-server_internal_models.RecommendationResponse"
+                },
+                "server": {
+                    "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.AssignIPRequest": {
+            "type": "object",
+            "properties": {
+                "pool": {
+                    "description": "IP pool for the secondary address, defaults to \"default\"",
+                    "type": "string",
+                    "example": "default"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.AttachInterfaceRequest": {
+            "type": "object",
+            "properties": {
+                "pool": {
+                    "description": "IP pool for the interface's address, defaults to \"default\"",
+                    "type": "string",
+                    "example": "default"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BillingCharge": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 0.02
+                },
+                "chargeType": {
+                    "description": "compute, compute_rounding, compute_reserved, storage, public_ip or egress",
+                    "type": "string",
+                    "example": "storage"
+                },
+                "quantity": {
+                    "type": "number",
+                    "example": 160
+                },
+                "unit": {
+                    "description": "hour, GB-hour or GB",
+                    "type": "string",
+                    "example": "GB-hour"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BillingForecastResponse": {
+            "type": "object",
+            "properties": {
+                "actualCost": {
+                    "type": "number",
+                    "example": 41.2
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "forecastCost": {
+                    "type": "number",
+                    "example": 71.7
+                },
+                "periodEnd": {
+                    "type": "string",
+                    "example": "2023-11-01T00:00:00Z"
+                },
+                "periodStart": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "projectedCost": {
+                    "type": "number",
+                    "example": 30.5
+                },
+                "projects": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ProjectForecastResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BillingInfo": {
+            "type": "object",
+            "properties": {
+                "billingModel": {
+                    "description": "per_second, hourly or reserved",
+                    "type": "string",
+                    "example": "hourly"
+                },
+                "charges": {
+                    "description": "EstimatedCurrentCost by charge type",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.BillingCharge"
+                    }
+                },
+                "currencyUnit": {
+                    "description": "e.g., \"USD\", \"EUR\", \"GBP\"",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "estimatedCurrentCost": {
+                    "description": "Estimated cost for the current (partial) billing cycle or total accumulated cost",
+                    "type": "number",
+                    "example": 0.01
+                },
+                "totalUptimeSeconds": {
+                    "description": "Total cumulative active time for the server",
+                    "type": "integer",
+                    "example": 3600
+                },
+                "unitPrice": {
+                    "description": "Price per unit (e.g., per hour, per request)",
+                    "type": "number",
+                    "example": 0.01
+                },
+                "updatedTime": {
+                    "description": "Start of the current billing period",
+                    "type": "string",
+                    "example": "2023-10-27T09:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BudgetAlertResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "budgetId": {
+                    "type": "string",
+                    "example": "7d3c2b1a-0f9e-8d7c-6b5a-4f3e2d1c0b9a"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-21T08:13:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "1a2b3c4d-5e6f-7a8b-9c0d-e1f2a3b4c5d6"
+                },
+                "periodStart": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "spend": {
+                    "type": "number",
+                    "example": 200.1
+                },
+                "threshold": {
+                    "type": "integer",
+                    "example": 80
+                },
+                "webhookStatus": {
+                    "description": "none, delivered or failed: \u003creason\u003e",
+                    "type": "string",
+                    "example": "delivered"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BudgetResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-26T10:00:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "enforce": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "id": {
+                    "type": "string",
+                    "example": "7d3c2b1a-0f9e-8d7c-6b5a-4f3e2d1c0b9a"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "checkout monthly"
+                },
+                "period": {
+                    "type": "string",
+                    "example": "monthly"
+                },
+                "scopeKey": {
+                    "type": "string",
+                    "example": ""
+                },
+                "scopeType": {
+                    "type": "string",
+                    "example": "project"
+                },
+                "scopeValue": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "status": {
+                    "$ref": "#/definitions/go-virtual-server_internal_models.BudgetStatusResponse"
+                },
+                "thresholds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        50,
+                        80,
+                        100
+                    ]
+                },
+                "webhookUrl": {
+                    "type": "string",
+                    "example": "https://hooks.example.com/budget"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.BudgetStatusResponse": {
+            "type": "object",
+            "properties": {
+                "percentUsed": {
+                    "type": "number",
+                    "example": 85
+                },
+                "periodEnd": {
+                    "type": "string",
+                    "example": "2023-11-01T00:00:00Z"
+                },
+                "periodStart": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "spend": {
+                    "type": "number",
+                    "example": 212.5
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ConsistencyAnomalyResponse": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "IP 10.0.0.12 still bound to the terminated server"
+                },
+                "ipAddressId": {
+                    "description": "The address, or the NAT mapping for nat_on_terminated_server",
+                    "type": "string",
+                    "example": "9f8e7d6c-5b4a-3f2e-1d0c-b9a8f7e6d5c4"
+                },
+                "kind": {
+                    "description": "address_mismatch, ip_on_terminated_server, nat_on_terminated_server, orphaned_ip or stuck_provisioning",
+                    "type": "string",
+                    "example": "ip_on_terminated_server"
+                },
+                "repairError": {
+                    "type": "string",
+                    "example": ""
+                },
+                "repaired": {
+                    "type": "boolean",
+                    "example": false
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ConsistencyReportResponse": {
+            "type": "object",
+            "properties": {
+                "anomalies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ConsistencyAnomalyResponse"
+                    }
+                },
+                "autoRepair": {
+                    "description": "Whether the consistency daemon repairs what it finds",
+                    "type": "boolean",
+                    "example": false
+                },
+                "checkedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateBudgetRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 250
+                },
+                "enforce": {
+                    "description": "Stop running servers in scope once the amount is spent",
+                    "type": "boolean",
+                    "example": false
+                },
+                "name": {
+                    "type": "string",
+                    "example": "checkout monthly"
+                },
+                "period": {
+                    "description": "daily or monthly (default)",
+                    "type": "string",
+                    "example": "monthly"
+                },
+                "scopeKey": {
+                    "description": "Tag key, for tag budgets",
+                    "type": "string",
+                    "example": "team"
+                },
+                "scopeType": {
+                    "description": "project, region or tag",
+                    "type": "string",
+                    "example": "project"
+                },
+                "scopeValue": {
+                    "description": "Project, region or tag value",
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "thresholds": {
+                    "description": "Percentages of the amount; default 50, 80, 100",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    },
+                    "example": [
+                        50,
+                        80,
+                        100
+                    ]
+                },
+                "webhookUrl": {
+                    "type": "string",
+                    "example": "https://hooks.example.com/budget"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateCreditRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "In USD",
+                    "type": "number",
+                    "example": 100
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "welcome credit"
+                },
+                "project": {
+                    "description": "Defaults to \"default\"",
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "scopeType": {
+                    "description": "project (default), server_type or region",
+                    "type": "string",
+                    "example": "project"
+                },
+                "scopeValue": {
+                    "description": "Server type or region, for scoped credits",
+                    "type": "string",
+                    "example": ""
+                },
+                "startsAt": {
+                    "description": "Defaults to now",
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateDiscountRequest": {
+            "type": "object",
+            "properties": {
+                "expiresAt": {
+                    "description": "Never expires if empty",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "launch promotion"
+                },
+                "percentOff": {
+                    "description": "Off the compute charges left after volume tiers",
+                    "type": "number",
+                    "example": 10
+                },
+                "region": {
+                    "description": "Any if empty",
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "startsAt": {
+                    "description": "Defaults to now",
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "type": {
+                    "description": "Server type; any if empty",
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateExchangeRateRequest": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "effectiveFrom": {
+                    "description": "Defaults to now",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "rate": {
+                    "description": "Units of the currency one USD buys",
+                    "type": "number",
+                    "example": 0.92
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreatePriceRequest": {
+            "type": "object",
+            "properties": {
+                "effectiveFrom": {
+                    "description": "Must be in the future",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "hourlyRate": {
+                    "type": "number",
+                    "example": 0.0104
+                },
+                "region": {
+                    "description": "Defaults to \"*\"",
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "t2.micro"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateReaperPolicyRequest": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "stop, terminate or notify",
+                    "type": "string",
+                    "example": "stop"
+                },
+                "condition": {
+                    "description": "stopped_for, running_for or idle_for",
+                    "type": "string",
+                    "example": "idle_for"
+                },
+                "durationSeconds": {
+                    "type": "integer",
+                    "example": 3600
+                },
+                "matchRegion": {
+                    "description": "Region; empty matches any",
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "matchTagKey": {
+                    "description": "Tag key; empty matches any",
+                    "type": "string",
+                    "example": "env"
+                },
+                "matchTagValue": {
+                    "type": "string",
+                    "example": "dev"
+                },
+                "matchType": {
+                    "description": "Server type; empty matches any",
+                    "type": "string",
+                    "example": "m5.large"
+                },
+                "maxCpuPercent": {
+                    "description": "CPU average over the duration at or below which a running server is idle; default 5",
+                    "type": "number",
+                    "example": 5
+                },
+                "name": {
+                    "type": "string",
+                    "example": "stop idle dev servers"
+                },
+                "webhookUrl": {
+                    "description": "Required for notify",
+                    "type": "string",
+                    "example": "https://hooks.example.com/reaper"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateReservationRequest": {
+            "type": "object",
+            "properties": {
+                "hours": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "project": {
+                    "description": "Defaults to \"default\"",
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreateVolumeTierRequest": {
+            "type": "object",
+            "properties": {
+                "fromHours": {
+                    "description": "Compute hours of a project period after which the tier applies",
+                    "type": "number",
+                    "example": 1000
+                },
+                "percentOff": {
+                    "type": "number",
+                    "example": 20
+                },
+                "type": {
+                    "type": "string",
+                    "example": "t2.micro"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.CreditResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 100
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "8b9c0d1e-2f3a-4b4c-d5e6-f7a8b9c0d1e2"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "welcome credit"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "remaining": {
+                    "type": "number",
+                    "example": 62.5
+                },
+                "scopeType": {
+                    "type": "string",
+                    "example": "project"
+                },
+                "scopeValue": {
+                    "type": "string",
+                    "example": ""
+                },
+                "startsAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.DiscountResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "7a8b9c0d-1e2f-4a3b-c4d5-e6f7a8b9c0d1"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "launch promotion"
+                },
+                "percentOff": {
+                    "type": "number",
+                    "example": 10
+                },
+                "region": {
+                    "description": "* matches any",
+                    "type": "string",
+                    "example": "*"
+                },
+                "startsAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "type": {
+                    "description": "* matches any",
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ExchangeRateResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "current": {
+                    "description": "In effect now",
+                    "type": "boolean",
+                    "example": true
+                },
+                "effectiveFrom": {
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "effectiveUntil": {
+                    "description": "Unset for the latest version",
+                    "type": "string",
+                    "example": "2024-02-01T00:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "7a6b5c4d-3e2f-4a1b-9c8d-7e6f5a4b3c2d"
+                },
+                "rate": {
+                    "description": "Units of the currency one USD buys",
+                    "type": "number",
+                    "example": 0.92
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ForecastResponse": {
+            "type": "object",
+            "properties": {
+                "actualCost": {
+                    "description": "Metered so far this period",
+                    "type": "number",
+                    "example": 4.12
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "forecastCost": {
+                    "description": "Actual plus projected",
+                    "type": "number",
+                    "example": 7.17
+                },
+                "periodEnd": {
+                    "type": "string",
+                    "example": "2023-11-01T00:00:00Z"
+                },
+                "periodStart": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "projectedCost": {
+                    "description": "Expected until the period ends",
+                    "type": "number",
+                    "example": 3.05
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "utilization": {
+                    "description": "Expected share of the remaining period spent running",
+                    "type": "number",
+                    "example": 1
+                }
+            }
+        },
+        "go-virtual-server_internal_models.InterfaceAddressResult": {
+            "type": "object",
+            "properties": {
+                "address": {
+                    "type": "string",
+                    "example": "192.168.1.10"
+                },
+                "isPrimary": {
+                    "description": "The interface's primary address",
+                    "type": "boolean",
+                    "example": true
+                },
+                "pool": {
+                    "type": "string",
+                    "example": "default"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.InvoiceLineResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number",
+                    "example": 7.2
+                },
+                "chargeType": {
+                    "type": "string",
+                    "example": "compute"
+                },
+                "quantity": {
+                    "type": "number",
+                    "example": 720
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "serverName": {
+                    "type": "string",
+                    "example": "my-app-server"
+                },
+                "serverType": {
+                    "type": "string",
+                    "example": "t2.micro"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "hour"
+                },
                 "unitPrice": {
-                    "description": "Price per unit (e.g., per hour, per request)",
                     "type": "number",
-                    "example": 0.01
+                    "example": 0.01
+                }
+            }
+        },
+        "go-virtual-server_internal_models.InvoiceResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "exchangeRate": {
+                    "description": "Units of currency per USD the ledger was converted at",
+                    "type": "number",
+                    "example": 0.92
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5c0e7d1a-2b3c-4d5e-8f90-a1b2c3d4e5f6"
+                },
+                "issuedAt": {
+                    "type": "string",
+                    "example": "2023-11-01T00:01:00Z"
+                },
+                "lines": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.InvoiceLineResponse"
+                    }
+                },
+                "periodEnd": {
+                    "description": "Exclusive",
+                    "type": "string",
+                    "example": "2023-11-01"
+                },
+                "periodStart": {
+                    "type": "string",
+                    "example": "2023-10-01"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "total": {
+                    "type": "number",
+                    "example": 12.34
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListAccountEventsResponse": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.AccountEventResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListBudgetAlertsResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.BudgetAlertResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListBudgetsResponse": {
+            "type": "object",
+            "properties": {
+                "budgets": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.BudgetResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListCreditsResponse": {
+            "type": "object",
+            "properties": {
+                "credits": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.CreditResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListDiscountsResponse": {
+            "type": "object",
+            "properties": {
+                "discounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.DiscountResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListExchangeRatesResponse": {
+            "type": "object",
+            "properties": {
+                "base": {
+                    "description": "Currency prices and the ledger are kept in",
+                    "type": "string",
+                    "example": "USD"
+                },
+                "rates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ExchangeRateResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListInvoicesResponse": {
+            "type": "object",
+            "properties": {
+                "invoices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.InvoiceResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListNATMappingsResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "mappings": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.NATMappingResponse"
+                    }
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListPricesResponse": {
+            "type": "object",
+            "properties": {
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.PriceResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListReaperActionsResponse": {
+            "type": "object",
+            "properties": {
+                "actions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ReaperActionResponse"
+                    }
+                },
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListReaperPoliciesResponse": {
+            "type": "object",
+            "properties": {
+                "policies": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ReaperPolicyResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListRecommendationsResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "generatedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
+                },
+                "lookback": {
+                    "type": "string",
+                    "example": "168h0m0s"
+                },
+                "recommendations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.RecommendationResponse"
+                    }
+                },
+                "totalMonthlySavings": {
+                    "type": "number",
+                    "example": 54.02
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListReservationsResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "reservations": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ReservationResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListServersResponse": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "servers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListSpotPricesResponse": {
+            "type": "object",
+            "properties": {
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.SpotPriceResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ListVolumeTiersResponse": {
+            "type": "object",
+            "properties": {
+                "tiers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.VolumeTierResponse"
+                    }
+                }
+            }
+        },
+        "go-virtual-server_internal_models.NATMappingResponse": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean",
+                    "example": true
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "3f1d2c4b-5a69-4e7f-8d9c-0b1a2c3d4e5f"
+                },
+                "privateAddress": {
+                    "type": "string",
+                    "example": "10.0.0.12"
+                },
+                "publicAddress": {
+                    "type": "string",
+                    "example": "203.0.113.25"
+                },
+                "releasedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T12:00:00Z"
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.NetworkInterfaceResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-27T09:55:00Z"
+                },
+                "deviceIndex": {
+                    "type": "integer",
+                    "example": 0
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0b6f1c2e-7d0a-4a39-9f57-1d2c3b4a5e6f"
+                },
+                "ipAddresses": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.InterfaceAddressResult"
+                    }
+                },
+                "isPrimary": {
+                    "type": "boolean",
+                    "example": true
+                }
+            }
+        },
+        "go-virtual-server_internal_models.PriceResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "current": {
+                    "description": "In effect now",
+                    "type": "boolean",
+                    "example": true
+                },
+                "effectiveFrom": {
+                    "type": "string",
+                    "example": "2023-11-01T00:00:00Z"
+                },
+                "effectiveUntil": {
+                    "description": "Unset for the latest version",
+                    "type": "string",
+                    "example": "2024-01-01T00:00:00Z"
+                },
+                "hourlyRate": {
+                    "type": "number",
+                    "example": 0.0116
+                },
+                "id": {
+                    "type": "string",
+                    "example": "0f1e2d3c-4b5a-6978-8695-a4b3c2d1e0f9"
+                },
+                "region": {
+                    "description": "\"*\" applies to regions without a price of their own",
+                    "type": "string",
+                    "example": "*"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "t2.micro"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ProjectForecastResponse": {
+            "type": "object",
+            "properties": {
+                "actualCost": {
+                    "type": "number",
+                    "example": 41.2
+                },
+                "forecastCost": {
+                    "type": "number",
+                    "example": 71.7
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "projectedCost": {
+                    "type": "number",
+                    "example": 30.5
+                },
+                "servers": {
+                    "type": "integer",
+                    "example": 4
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ProjectResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Invoices and billing responses are in this currency",
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "checkout"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ProvisionServerRequest": {
+            "type": "object",
+            "properties": {
+                "assignPublicIp": {
+                    "description": "Also map a public address to the server's private address",
+                    "type": "boolean",
+                    "example": false
+                },
+                "billingModel": {
+                    "description": "per_second (default, 60s minimum), hourly or reserved",
+                    "type": "string",
+                    "example": "hourly"
+                },
+                "diskGb": {
+                    "description": "Disk size, billed per GB-hour until terminated; defaults to DEFAULT_DISK_GB",
+                    "type": "integer",
+                    "example": 20
+                },
+                "interruptionBehavior": {
+                    "description": "What happens to an outbid spot server: terminate (default) or stop",
+                    "type": "string",
+                    "example": "stop"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "my-app-server"
+                },
+                "project": {
+                    "description": "Project invoiced for the server, defaults to \"default\"",
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "purchaseOption": {
+                    "description": "on_demand (default) or spot",
+                    "type": "string",
+                    "example": "spot"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "spotMaxPrice": {
+                    "description": "Highest hourly spot price to run at, defaults to the on-demand price",
+                    "type": "number",
+                    "example": 0.05
+                },
+                "tags": {
+                    "description": "Free-form labels, served by the metadata service",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "t2.micro"
+                },
+                "userData": {
+                    "description": "Served as-is at /latest/user-data, max 16 KiB",
+                    "type": "string",
+                    "example": "#!/bin/sh\necho hello"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.QuoteLineResponse": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "For all servers",
+                    "type": "number",
+                    "example": 207.36
+                },
+                "from": {
+                    "type": "string",
+                    "example": "2023-10-26T10:00:00Z"
+                },
+                "hourlyRate": {
+                    "type": "number",
+                    "example": 0.096
+                },
+                "hours": {
+                    "type": "number",
+                    "example": 720
+                },
+                "until": {
+                    "type": "string",
+                    "example": "2023-11-25T10:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.QuoteRequest": {
+            "type": "object",
+            "properties": {
+                "billingModel": {
+                    "description": "per_second (default), hourly or reserved",
+                    "type": "string",
+                    "example": "reserved"
+                },
+                "count": {
+                    "description": "Defaults to 1",
+                    "type": "integer",
+                    "example": 3
+                },
+                "hours": {
+                    "type": "number",
+                    "example": 720
+                },
+                "project": {
+                    "description": "Whose reservations a reserved quote draws down, defaults to \"default\"",
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.QuoteResponse": {
+            "type": "object",
+            "properties": {
+                "billedHours": {
+                    "description": "Time the billing model charges per server",
+                    "type": "number",
+                    "example": 720
+                },
+                "billingModel": {
+                    "type": "string",
+                    "example": "reserved"
+                },
+                "count": {
+                    "type": "integer",
+                    "example": 3
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "hours": {
+                    "type": "number",
+                    "example": 720
+                },
+                "lines": {
+                    "description": "Metered time, per hourly rate",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.QuoteLineResponse"
+                    }
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "reservedHours": {
+                    "description": "Time of all servers covered by the project's reservations",
+                    "type": "number",
+                    "example": 1000
+                },
+                "total": {
+                    "description": "Cost of all servers, less the reserved hours",
+                    "type": "number",
+                    "example": 111.36
+                },
+                "type": {
+                    "type": "string",
+                    "example": "m5.large"
+                },
+                "unitCost": {
+                    "description": "Cost of one server without reservations",
+                    "type": "number",
+                    "example": 69.12
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReadyzResponse": {
+            "type": "object",
+            "properties": {
+                "leader": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "boolean"
+                    }
+                },
+                "status": {
+                    "type": "string",
+                    "example": "OK"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReaperActionResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "stop"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
+                },
+                "id": {
+                    "type": "string",
+                    "example": "9f8e7d6c-5b4a-3f2e-1d0c-b9a8f7e6d5c4"
+                },
+                "outcome": {
+                    "description": "pending, done or failed: \u003creason\u003e",
+                    "type": "string",
+                    "example": "done"
+                },
+                "policyId": {
+                    "type": "string",
+                    "example": "5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "stateSince": {
+                    "type": "string",
+                    "example": "2023-10-27T09:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReaperCandidateResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "stop"
+                },
+                "policyId": {
+                    "type": "string",
+                    "example": "5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"
+                },
+                "policyName": {
+                    "type": "string",
+                    "example": "stop idle dev servers"
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "serverName": {
+                    "type": "string",
+                    "example": "my-app-server"
+                },
+                "since": {
+                    "description": "When the server entered its status",
+                    "type": "string",
+                    "example": "2023-10-27T09:00:00Z"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "running"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReaperDryRunResponse": {
+            "type": "object",
+            "properties": {
+                "candidates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ReaperCandidateResponse"
+                    }
+                },
+                "evaluatedAt": {
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
+                },
+                "exemptTag": {
+                    "type": "string",
+                    "example": "reaper-exempt"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReaperPolicyResponse": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string",
+                    "example": "stop"
+                },
+                "condition": {
+                    "type": "string",
+                    "example": "idle_for"
+                },
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-26T10:00:00Z"
+                },
+                "durationSeconds": {
+                    "type": "integer",
+                    "example": 3600
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"
+                },
+                "matchRegion": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "matchTagKey": {
+                    "type": "string",
+                    "example": "env"
+                },
+                "matchTagValue": {
+                    "type": "string",
+                    "example": "dev"
+                },
+                "matchType": {
+                    "type": "string",
+                    "example": "m5.large"
+                },
+                "maxCpuPercent": {
+                    "type": "number",
+                    "example": 5
+                },
+                "name": {
+                    "type": "string",
+                    "example": "stop idle dev servers"
+                },
+                "webhookUrl": {
+                    "type": "string",
+                    "example": "https://hooks.example.com/reaper"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.RecommendationResponse": {
+            "type": "object",
+            "properties": {
+                "cpuPercentAvg": {
+                    "description": "0 without enough telemetry",
+                    "type": "number",
+                    "example": 12.4
                 },
-                "updatedTime": {
-                    "description": "Start of the current billing period",
+                "cpuPercentMax": {
+                    "type": "number",
+                    "example": 31.7
+                },
+                "hourlyCost": {
+                    "type": "number",
+                    "example": 0.17
+                },
+                "kind": {
+                    "description": "downsize, stop or reserve",
                     "type": "string",
-                    "example": "2023-10-27T09:00:00Z"
+                    "example": "downsize"
+                },
+                "monthlySavings": {
+                    "description": "For a month of running as much as over the lookback",
+                    "type": "number",
+                    "example": 54.02
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
+                "reason": {
+                    "type": "string",
+                    "example": "CPU load peaked at 31.7% (12.4% on average) over the last 168h0m0s, about 63.4% on m5.large"
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "reservedHours": {
+                    "description": "Hours to reserve for RESERVATION_TERM, set for reserve",
+                    "type": "number",
+                    "example": 8760
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "serverName": {
+                    "type": "string",
+                    "example": "my-app-server"
+                },
+                "targetHourlyCost": {
+                    "description": "Once applied; 0 for stop",
+                    "type": "number",
+                    "example": 0.096
+                },
+                "targetType": {
+                    "description": "Set for downsize",
+                    "type": "string",
+                    "example": "m5.large"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "c5.xlarge"
+                },
+                "uptimePercent": {
+                    "description": "Share of the lookback the server was running",
+                    "type": "number",
+                    "example": 100
                 }
             }
         },
-        "go-virtual-server_internal_models.ListServersResponse": {
+        "go-virtual-server_internal_models.RecordTelemetryRequest": {
             "type": "object",
             "properties": {
-                "limit": {
-                    "type": "integer"
+                "cpuPercent": {
+                    "type": "number",
+                    "example": 37.5
                 },
-                "offset": {
-                    "type": "integer"
+                "networkRxBytes": {
+                    "description": "Received since the previous sample",
+                    "type": "integer",
+                    "example": 1048576
                 },
-                "servers": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/go-virtual-server_internal_models.ServerResponse"
-                    }
+                "networkTxBytes": {
+                    "description": "Sent since the previous sample",
+                    "type": "integer",
+                    "example": 524288
                 },
-                "total": {
-                    "type": "integer"
+                "sampledAt": {
+                    "description": "Defaults to now",
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
                 }
             }
         },
-        "go-virtual-server_internal_models.ProvisionServerRequest": {
+        "go-virtual-server_internal_models.RenameServerRequest": {
             "type": "object",
             "properties": {
                 "name": {
                     "type": "string",
-                    "example": "my-app-server"
+                    "example": "my-renamed-server"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.ReservationResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "expiresAt": {
+                    "type": "string",
+                    "example": "2024-09-30T00:00:00Z"
+                },
+                "hourlyRate": {
+                    "description": "Catalog price at purchase, less the discount",
+                    "type": "number",
+                    "example": 0.0672
+                },
+                "hours": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "hoursRemaining": {
+                    "type": "number",
+                    "example": 749.5
+                },
+                "hoursUsed": {
+                    "type": "number",
+                    "example": 250.5
+                },
+                "id": {
+                    "type": "string",
+                    "example": "5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
                 },
                 "region": {
                     "type": "string",
                     "example": "us-east-1"
                 },
+                "startsAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
                 "type": {
                     "type": "string",
-                    "example": "t2.micro"
+                    "example": "m5.large"
+                },
+                "upfrontAmount": {
+                    "type": "number",
+                    "example": 67.2
                 }
             }
         },
@@ -425,31 +4453,65 @@ const docTemplate = `{
                 }
             }
         },
-        "go-virtual-server_internal_models.ServerLifecycleLogEntry": {
+        "go-virtual-server_internal_models.ServerEventResponse": {
             "type": "object",
             "properties": {
-                "ACTION": {
-                    "type": "string"
+                "actor": {
+                    "description": "api, system or the daemon that caused the event",
+                    "type": "string",
+                    "example": "api"
                 },
-                "REQUEST_ID": {
-                    "type": "string"
+                "fromStatus": {
+                    "description": "Set for status changes",
+                    "type": "string",
+                    "example": "stopped"
                 },
-                "SERVER_ID": {
-                    "type": "string"
+                "id": {
+                    "type": "string",
+                    "example": "3c2b1a0f-9e8d-7c6b-5a4f-3e2d1c0b9a8f"
                 },
-                "TIME": {
-                    "type": "string"
+                "payload": {
+                    "description": "The event's message and details",
+                    "type": "object"
+                },
+                "requestId": {
+                    "type": "string",
+                    "example": "host/abcdef-000001"
+                },
+                "sequence": {
+                    "description": "Numbers the server's events in the order they were recorded",
+                    "type": "integer",
+                    "example": 42
+                },
+                "timestamp": {
+                    "type": "string",
+                    "example": "2023-10-27T10:15:00Z"
+                },
+                "toStatus": {
+                    "description": "Set for status changes",
+                    "type": "string",
+                    "example": "running"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "status_change"
                 }
             }
         },
         "go-virtual-server_internal_models.ServerLogsResponse": {
             "type": "object",
             "properties": {
+                "limit": {
+                    "type": "integer"
+                },
                 "logs": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/go-virtual-server_internal_models.ServerLifecycleLogEntry"
+                        "$ref": "#/definitions/go-virtual-server_internal_models.ServerEventResponse"
                     }
+                },
+                "offset": {
+                    "type": "integer"
                 }
             }
         },
@@ -459,10 +4521,22 @@ const docTemplate = `{
                 "billingInfo": {
                     "$ref": "#/definitions/go-virtual-server_internal_models.BillingInfo"
                 },
+                "billingModel": {
+                    "type": "string",
+                    "example": "per_second"
+                },
                 "createdAt": {
                     "type": "string",
                     "example": "2023-10-27T09:55:00Z"
                 },
+                "diskGb": {
+                    "type": "integer",
+                    "example": 8
+                },
+                "hostname": {
+                    "type": "string",
+                    "example": "my-app-server.vs.internal"
+                },
                 "hourlyCost": {
                     "type": "number",
                     "example": 0.01
@@ -471,7 +4545,14 @@ const docTemplate = `{
                     "type": "string",
                     "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
                 },
+                "interfaces": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.NetworkInterfaceResponse"
+                    }
+                },
                 "ipAddress": {
+                    "description": "Primary address of the primary interface",
                     "type": "string",
                     "example": "192.168.1.10"
                 },
@@ -479,28 +4560,58 @@ const docTemplate = `{
                     "type": "string",
                     "example": "2023-10-27T10:15:00Z"
                 },
-                "lifecycleLogs": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                },
                 "name": {
                     "type": "string",
                     "example": "my-app-server"
                 },
+                "privateIpAddress": {
+                    "type": "string",
+                    "example": "10.0.0.12"
+                },
+                "project": {
+                    "type": "string",
+                    "example": "checkout"
+                },
                 "provisionedAt": {
                     "type": "string",
                     "example": "2023-10-27T10:00:00Z"
                 },
+                "publicIpAddress": {
+                    "description": "Set while a NAT mapping is active",
+                    "type": "string",
+                    "example": "203.0.113.25"
+                },
+                "purchaseOption": {
+                    "type": "string",
+                    "example": "on_demand"
+                },
                 "region": {
                     "type": "string",
                     "example": "us-east-1"
                 },
+                "spot": {
+                    "description": "Set for spot servers",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/go-virtual-server_internal_models.SpotInfo"
+                        }
+                    ]
+                },
                 "status": {
                     "type": "string",
                     "example": "running"
                 },
+                "stuckSince": {
+                    "description": "Set while the watchdog retries a stuck transition and in the error status: when the server entered the status it got stuck in",
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "tags": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
                 "type": {
                     "type": "string",
                     "example": "t2.micro"
@@ -515,6 +4626,173 @@ const docTemplate = `{
                 }
             }
         },
+        "go-virtual-server_internal_models.SetSpotPriceRequest": {
+            "type": "object",
+            "properties": {
+                "price": {
+                    "type": "number",
+                    "example": 0.09
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.SpotInfo": {
+            "type": "object",
+            "properties": {
+                "interruptionBehavior": {
+                    "type": "string",
+                    "example": "stop"
+                },
+                "interruptionNoticeAt": {
+                    "description": "When the server was told it will be interrupted",
+                    "type": "string",
+                    "example": "2023-10-27T10:20:00Z"
+                },
+                "maxPrice": {
+                    "type": "number",
+                    "example": 0.05
+                }
+            }
+        },
+        "go-virtual-server_internal_models.SpotPriceResponse": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "USD"
+                },
+                "effectiveFrom": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                },
+                "price": {
+                    "type": "number",
+                    "example": 0.0288
+                },
+                "region": {
+                    "type": "string",
+                    "example": "us-east-1"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "m5.large"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.TelemetryPointResponse": {
+            "type": "object",
+            "properties": {
+                "cpuPercentAvg": {
+                    "type": "number",
+                    "example": 35.2
+                },
+                "cpuPercentMax": {
+                    "type": "number",
+                    "example": 61.8
+                },
+                "networkRxBytes": {
+                    "type": "integer",
+                    "example": 52428800
+                },
+                "networkTxBytes": {
+                    "type": "integer",
+                    "example": 26214400
+                },
+                "samples": {
+                    "type": "integer",
+                    "example": 5
+                },
+                "time": {
+                    "description": "Start of the step",
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.TelemetrySeriesResponse": {
+            "type": "object",
+            "properties": {
+                "from": {
+                    "type": "string",
+                    "example": "2023-10-27T09:00:00Z"
+                },
+                "points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/go-virtual-server_internal_models.TelemetryPointResponse"
+                    }
+                },
+                "serverId": {
+                    "type": "string",
+                    "example": "a1b2c3d4-e5f6-7890-1234-567890abcdef"
+                },
+                "step": {
+                    "type": "string",
+                    "example": "5m0s"
+                },
+                "to": {
+                    "type": "string",
+                    "example": "2023-10-27T10:00:00Z"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.TopUpAccountRequest": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "In USD",
+                    "type": "number",
+                    "example": 50
+                },
+                "lowBalanceThreshold": {
+                    "description": "Keeps the current threshold if omitted",
+                    "type": "number",
+                    "example": 10
+                }
+            }
+        },
+        "go-virtual-server_internal_models.UpdateProjectRequest": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "description": "Needs an exchange rate in effect",
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "go-virtual-server_internal_models.VolumeTierResponse": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string",
+                    "example": "2023-10-01T00:00:00Z"
+                },
+                "fromHours": {
+                    "type": "number",
+                    "example": 1000
+                },
+                "id": {
+                    "type": "string",
+                    "example": "6f7a8b9c-0d1e-4f2a-b3c4-d5e6f7a8b9c0"
+                },
+                "percentOff": {
+                    "type": "number",
+                    "example": 20
+                },
+                "type": {
+                    "type": "string",
+                    "example": "t2.micro"
+                }
+            }
+        },
         "go-virtual-server_internal_util.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// GetAccount godoc
+// @Summary Retrieve a project's prepaid account
+// @Description Retrieves the prepaid balance of a project in USD. The billing daemon draws it down as the project's servers accrue cost; once it is exhausted the running servers are suspended until the account is topped up.
+// @Tags accounts
+// @Produce json
+// @Param project path string true "Name of the project"
+// @Success 200 {object} models.AccountResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /accounts/{project} [get]
+func (api *ServerAPI) GetAccount(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetAccount handler")
+
+	account, err := api.accounts.GetAccount(r.Context(), chi.URLParam(r, "project"))
+	if errors.Is(err, services.ErrAccountNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Account not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve account", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve account")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, models.ToAccountResponse(account))
+
+	api.logger.Info("Exiting GetAccount handler")
+}
+
+// TopUpAccount godoc
+// @Summary Top up a project's prepaid account
+// @Description Adds funds in USD to a project's balance, opening its prepaid account on the first top-up; from then on the project's servers draw it down. If the project was suspended and the balance is positive again, its suspended servers are resumed.
+// @Tags accounts
+// @Accept json
+// @Produce json
+// @Param project path string true "Name of the project"
+// @Param topup body models.TopUpAccountRequest true "Top-up"
+// @Success 200 {object} models.AccountResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /accounts/{project}/topup [post]
+func (api *ServerAPI) TopUpAccount(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering TopUpAccount handler")
+
+	var req models.TopUpAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	account, err := api.accounts.TopUp(r.Context(), chi.URLParam(r, "project"), req.Amount, req.LowBalanceThreshold)
+	if errors.Is(err, services.ErrInvalidTopUp) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to top up account", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to top up account")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, models.ToAccountResponse(account))
+
+	api.logger.Info("Exiting TopUpAccount handler")
+}
+
+// ListAccountEvents godoc
+// @Summary List the events of a project's prepaid account
+// @Description Lists top-ups, low-balance warnings, suspensions and resumptions of a project's account, newest first.
+// @Tags accounts
+// @Produce json
+// @Param project path string true "Name of the project"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListAccountEventsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /accounts/{project}/events [get]
+func (api *ServerAPI) ListAccountEvents(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListAccountEvents handler")
+
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	events, err := api.accounts.ListAccountEvents(r.Context(), chi.URLParam(r, "project"), limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list account events", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list account events")
+		return
+	}
+
+	response := models.ListAccountEventsResponse{
+		Events: make([]models.AccountEventResponse, 0, len(events)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, models.ToAccountEventResponse(event))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListAccountEvents handler")
+}
@@ -0,0 +1,254 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// CreateVolumeTier godoc
+// @Summary Add a volume pricing tier
+// @Description Adds a tier to the volume pricing of a server type: compute hours of a project's billing period beyond fromHours, across regions, get percentOff until the next tier. Tiers are applied when the period is invoiced, before discounts and credits, as their own invoice lines.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param tier body models.CreateVolumeTierRequest true "Volume tier"
+// @Success 201 {object} models.VolumeTierResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/volume-tiers [post]
+func (api *ServerAPI) CreateVolumeTier(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateVolumeTier handler")
+
+	var req models.CreateVolumeTierRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !util.IsValidServerType(req.Type) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Type must be one of %s, %s, %s",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+
+	tier, err := api.billing.CreateVolumeTier(r.Context(), req.Type, req.FromHours, req.PercentOff)
+	if errors.Is(err, services.ErrInvalidVolumeTier) || errors.Is(err, services.ErrInvalidPercentOff) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrVolumeTierExists) {
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create volume tier", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create volume tier")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToVolumeTierResponse(tier))
+
+	api.logger.Info("Exiting CreateVolumeTier handler")
+}
+
+// ListVolumeTiers godoc
+// @Summary List volume pricing tiers
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.ListVolumeTiersResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /volume-tiers [get]
+func (api *ServerAPI) ListVolumeTiers(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListVolumeTiers handler")
+
+	tiers, err := api.billing.ListVolumeTiers(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to list volume tiers", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list volume tiers")
+		return
+	}
+
+	response := models.ListVolumeTiersResponse{Tiers: make([]models.VolumeTierResponse, 0, len(tiers))}
+	for _, tier := range tiers {
+		response.Tiers = append(response.Tiers, models.ToVolumeTierResponse(tier))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListVolumeTiers handler")
+}
+
+// CreateDiscount godoc
+// @Summary Add a discount
+// @Description Adds a percentage discount on the compute charges of a server type in a region (either may be empty to match any), for billing periods overlapping startsAt to expiresAt. When a period is invoiced the largest matching discount applies, after volume tiers and before credits, as its own invoice line.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param discount body models.CreateDiscountRequest true "Discount"
+// @Success 201 {object} models.DiscountResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/discounts [post]
+func (api *ServerAPI) CreateDiscount(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateDiscount handler")
+
+	var req models.CreateDiscountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Name == "" || (req.Type != "" && !util.IsValidServerType(req.Type)) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Name is required and type must be empty or one of %s, %s, %s",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+
+	params := sqlc.CreateDiscountParams{
+		Name:       req.Name,
+		ServerType: req.Type,
+		Region:     req.Region,
+		PercentOff: req.PercentOff,
+		StartsAt:   pgtype.Timestamptz{Time: req.StartsAt, Valid: !req.StartsAt.IsZero()},
+	}
+	if req.ExpiresAt != nil {
+		params.ExpiresAt = pgtype.Timestamptz{Time: *req.ExpiresAt, Valid: true}
+	}
+
+	discount, err := api.billing.CreateDiscount(r.Context(), params)
+	if errors.Is(err, services.ErrInvalidPercentOff) || errors.Is(err, services.ErrInvalidDiscount) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create discount", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create discount")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToDiscountResponse(discount))
+
+	api.logger.Info("Exiting CreateDiscount handler")
+}
+
+// ListDiscounts godoc
+// @Summary List discounts
+// @Tags billing
+// @Produce json
+// @Success 200 {object} models.ListDiscountsResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /discounts [get]
+func (api *ServerAPI) ListDiscounts(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListDiscounts handler")
+
+	discounts, err := api.billing.ListDiscounts(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to list discounts", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list discounts")
+		return
+	}
+
+	response := models.ListDiscountsResponse{Discounts: make([]models.DiscountResponse, 0, len(discounts))}
+	for _, discount := range discounts {
+		response.Discounts = append(response.Discounts, models.ToDiscountResponse(discount))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListDiscounts handler")
+}
+
+// CreateCredit godoc
+// @Summary Grant a credit
+// @Description Grants a project a promotional credit in USD, scoped to all its charges, one server type or one region. When a billing period overlapping startsAt to expiresAt is invoiced, credits pay for what is left after volume tiers and discounts, soonest to expire first, each as its own invoice line.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param credit body models.CreateCreditRequest true "Credit"
+// @Success 201 {object} models.CreditResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/credits [post]
+func (api *ServerAPI) CreateCredit(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateCredit handler")
+
+	var req models.CreateCreditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Name == "" {
+		util.RespondWithError(w, http.StatusBadRequest, "Name is required")
+		return
+	}
+
+	credit, err := api.billing.CreateCredit(r.Context(), sqlc.CreateCreditParams{
+		Project:    req.Project,
+		Name:       req.Name,
+		ScopeType:  req.ScopeType,
+		ScopeValue: req.ScopeValue,
+		Amount:     req.Amount,
+		StartsAt:   pgtype.Timestamptz{Time: req.StartsAt, Valid: !req.StartsAt.IsZero()},
+		ExpiresAt:  pgtype.Timestamptz{Time: req.ExpiresAt, Valid: !req.ExpiresAt.IsZero()},
+	})
+	if errors.Is(err, services.ErrInvalidCredit) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create credit", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create credit")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToCreditResponse(credit))
+
+	api.logger.Info("Exiting CreateCredit handler")
+}
+
+// ListCredits godoc
+// @Summary List credits
+// @Description Lists credits, newest first, with the amount left.
+// @Tags billing
+// @Produce json
+// @Param project query string false "Filter by project" example:"checkout"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListCreditsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /credits [get]
+func (api *ServerAPI) ListCredits(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListCredits handler")
+
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	credits, err := api.billing.ListCredits(r.Context(), r.URL.Query().Get("project"), limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list credits", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list credits")
+		return
+	}
+
+	response := models.ListCreditsResponse{
+		Credits: make([]models.CreditResponse, 0, len(credits)),
+		Limit:   limit,
+		Offset:  offset,
+	}
+	for _, credit := range credits {
+		response.Credits = append(response.Credits, models.ToCreditResponse(credit))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListCredits handler")
+}
@@ -2,15 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	// Added for time.Now()
 
-	"github.com/go-chi/chi/middleware"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 
 	"go-virtual-server/internal/database/sqlc"
@@ -56,7 +60,24 @@ func (api *ServerAPI) ProvisionServer(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	server, err := api.serverService.ProvisionNewServer(r.Context(), req.Name, req.Region, req.Type)
+	server, err := api.serverService.ProvisionNewServer(r.Context(), req.Name, req.Region, req.Type, services.ProvisionOptions{
+		AssignPublicIP: req.AssignPublicIP,
+		Tags:           req.Tags,
+		UserData:       req.UserData,
+		Project:        req.Project,
+		BillingModel:   req.BillingModel,
+		DiskGB:         req.DiskGB,
+
+		PurchaseOption:       req.PurchaseOption,
+		SpotMaxPrice:         req.SpotMaxPrice,
+		InterruptionBehavior: req.InterruptionBehavior,
+	})
+	if errors.Is(err, services.ErrInvalidTag) || errors.Is(err, services.ErrUserDataTooLarge) || errors.Is(err, services.ErrNoPrice) ||
+		errors.Is(err, services.ErrInvalidBillingModel) || errors.Is(err, services.ErrInvalidSpotOptions) || errors.Is(err, services.ErrSpotBidTooLow) ||
+		errors.Is(err, services.ErrInvalidDiskSize) || errors.Is(err, services.ErrNoPrivateIPPool) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
 		api.logger.Error("Failed to provision server", zap.Error(err))
 		util.RespondWithError(w, http.StatusInternalServerError, "Failed to provision server")
@@ -65,6 +86,7 @@ func (api *ServerAPI) ProvisionServer(w http.ResponseWriter, r *http.Request) {
 
 	// Respond with the full ServerResponse object
 	response := models.ToServerResponse(server)
+	api.withNetworking(r.Context(), &response)
 	api.logger.Info("New virtual server provisioned successfully",
 		zap.String("server_id", response.ID),
 		zap.String("server_name", response.Name))
@@ -79,6 +101,7 @@ func (api *ServerAPI) ProvisionServer(w http.ResponseWriter, r *http.Request) {
 // @Tags servers
 // @Produce json
 // @Param serverID path string true "ID of the server"
+// @Param currency query string false "Currency of billingInfo, defaults to the project's" example:"EUR"
 // @Success 200 {object} models.ServerResponse
 // @Failure 400 {object} util.ErrorResponse
 // @Failure 404 {object} util.ErrorResponse
@@ -103,7 +126,20 @@ func (api *ServerAPI) GetServer(w http.ResponseWriter, r *http.Request) {
 	// Convert sqlc.Server to models.ServerResponse
 	response := models.ToServerResponse(server)
 
-	response.BillingInfo = models.ToBillingInfo(server)
+	usage, err := api.serverService.GetServerUsage(r.Context(), server.ID)
+	if err != nil {
+		api.logger.Error("Failed to retrieve server usage", zap.String("serverID", serverIDStr), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve server details")
+		return
+	}
+	currency, rate, ok := api.currencyFor(w, r, server.Project, time.Now())
+	if !ok {
+		return
+	}
+	response.UptimeSeconds = usage[response.ID].UptimeSeconds
+	response.BillingInfo = models.ToBillingInfo(server, usage[response.ID].UptimeSeconds, usage[response.ID].Cost,
+		toBillingCharges(usage[response.ID]), currency, rate)
+	api.withNetworking(r.Context(), &response)
 
 	api.logger.Info("Successfully retrieved server details", zap.String("serverID", response.ID))
 	util.RespondWithJSON(w, http.StatusOK, response)
@@ -111,9 +147,59 @@ func (api *ServerAPI) GetServer(w http.ResponseWriter, r *http.Request) {
 	api.logger.Info("Exiting GetServer handler", zap.String("serverID", serverIDStr))
 }
 
+// RenameServer godoc
+// @Summary Rename a server
+// @Description Renames a server. Its hostname follows the new name and is resolvable by the embedded DNS server right away.
+// @Tags servers
+// @Accept json
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param request body models.RenameServerRequest true "New server name"
+// @Success 200 {object} models.ServerResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID} [patch]
+func (api *ServerAPI) RenameServer(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering RenameServer handler")
+
+	var req models.RenameServerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload for rename", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	updatedServer, err := api.serverService.RenameServer(r.Context(), server, req.Name)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptyServerName):
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, services.ErrServerTerminated):
+			util.RespondWithError(w, http.StatusConflict, err.Error())
+		default:
+			api.logger.Error("Failed to rename server", zap.String("serverID", server.ID.String()), zap.Error(err))
+			util.RespondWithError(w, http.StatusInternalServerError, "Failed to rename server")
+		}
+		return
+	}
+
+	response := models.ToServerResponse(updatedServer)
+	api.withNetworking(r.Context(), &response)
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting RenameServer handler")
+}
+
 // PerformServerAction godoc
 // @Summary Perform an action on a server
-// @Description Performs actions like start, stop, reboot, terminate on a virtual server. Enforces valid FSM transitions.
+// @Description Performs actions like start, stop, reboot, terminate on a virtual server. Enforces valid FSM transitions. Suspended servers can only be terminated; they run again once their project's account is topped up, and servers of a project whose prepaid balance is exhausted cannot be started.
 // @Tags servers
 // @Accept json
 // @Produce json
@@ -121,6 +207,7 @@ func (api *ServerAPI) GetServer(w http.ResponseWriter, r *http.Request) {
 // @Param request body models.ServerActionRequest true "Action to perform (start, stop, reboot, terminate)"
 // @Success 200 {object} models.ServerResponse
 // @Failure 400 {object} util.ErrorResponse
+// @Failure 402 {object} util.ErrorResponse
 // @Failure 404 {object} util.ErrorResponse
 // @Failure 409 {object} util.ErrorResponse
 // @Failure 500 {object} util.ErrorResponse
@@ -163,6 +250,14 @@ func (api *ServerAPI) PerformServerAction(w http.ResponseWriter, r *http.Request
 
 	if err != nil {
 
+		if errors.Is(err, services.ErrSpotBidTooLow) {
+			util.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrAccountExhausted) {
+			util.RespondWithError(w, http.StatusPaymentRequired, err.Error())
+			return
+		}
 		if strings.Contains(err.Error(), "invalid state transition") {
 			api.logger.Warn("Invalid server action requested", zap.String("action", req.Action))
 			util.RespondWithError(w, http.StatusConflict, "Invalid action: must be start, stop, reboot, or terminate")
@@ -178,6 +273,8 @@ func (api *ServerAPI) PerformServerAction(w http.ResponseWriter, r *http.Request
 	}
 
 	response := models.ToServerResponse(updatedServer)
+	api.withNetworking(r.Context(), &response)
+	api.withUsage(r.Context(), &response)
 	api.logger.Info("Server action completed successfully",
 		zap.String("serverID", response.ID),
 		zap.String("action", req.Action),
@@ -189,11 +286,12 @@ func (api *ServerAPI) PerformServerAction(w http.ResponseWriter, r *http.Request
 
 // ListServers godoc
 // @Summary List all servers
-// @Description Lists all virtual servers, filterable by region, status, type; supports pagination (limit, offset); sorted (newest first).
+// @Description Lists all virtual servers, filterable by project, region, status, type; supports pagination (limit, offset); sorted (newest first).
 // @Tags servers
 // @Produce json
+// @Param project query string false "Filter by project" example:"checkout"
 // @Param region query string false "Filter by region" example:"us-east-1"
-// @Param status query string false "Filter by status (e.g., provisioning, running, stopped, terminated, error)" example:"running"
+// @Param status query string false "Filter by status (e.g., provisioning, running, stopped, suspended, terminated, error)" example:"running"
 // @Param type query string false "Filter by server type (e.g., t2.micro, m5.large)" example:"t2.micro"
 // @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
 // @Param offset query int false "Number of results to skip" default(0) minimum(0)
@@ -209,6 +307,7 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 	// Optional: set it in the response header
 	w.Header().Set("X-Request-ID", reqID)
 
+	projectParam := r.URL.Query().Get("project")
 	regionParam := r.URL.Query().Get("region")
 	statusParam := r.URL.Query().Get("status")
 	typeParam := r.URL.Query().Get("type")
@@ -219,14 +318,20 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 	// Start building the query
 	baseQuery := `
         SELECT
-            s.id, s.name, s.region, s.status, s.type, s.address,
-            s.provisioned_at, s.last_status_update, s.uptime_seconds, s.hourly_cost, s.created_at, s.updated_at
+            s.id, s.name, s.hostname, s.project, s.region, s.status, s.type, s.disk_gb, s.tags, s.address,
+            s.provisioned_at, s.last_status_update, s.stuck_since, s.uptime_seconds, s.hourly_cost, s.billing_model,
+            s.purchase_option, s.spot_max_price, s.interruption_behavior, s.interruption_notice_at, s.created_at, s.updated_at
         FROM servers s
     `
 	conditions := []string{}
 	args := []interface{}{}
 	paramCounter := 0 // To track the placeholder number ($1, $2, etc.)
 
+	if projectParam != "" {
+		paramCounter++
+		conditions = append(conditions, fmt.Sprintf("s.project = $%d", paramCounter))
+		args = append(args, projectParam)
+	}
 	if regionParam != "" {
 		paramCounter++
 		conditions = append(conditions, fmt.Sprintf("s.region = $%d", paramCounter))
@@ -273,19 +378,34 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 	var servers []models.ServerResponse
 	for rows.Next() {
 		var s models.ServerResponse
+		var tags []byte
+		var spotMaxPrice float64
+		var interruptionBehavior string
+		var interruptionNoticeAt pgtype.Timestamptz
+		var stuckSince pgtype.Timestamptz
 		// Manually scan each column into the struct fields.
 		// The order here MUST match the order in the SELECT statement.
 		err := rows.Scan(
 			&s.ID,
 			&s.Name,
+			&s.Hostname,
+			&s.Project,
 			&s.Region,
 			&s.Status,
 			&s.Type,
+			&s.DiskGB,
+			&tags,
 			&s.IPAddress,
 			&s.ProvisionedAt,
 			&s.LastStatusUpdate,
+			&stuckSince,
 			&s.UptimeSeconds,
 			&s.HourlyCost,
+			&s.BillingModel,
+			&s.PurchaseOption,
+			&spotMaxPrice,
+			&interruptionBehavior,
+			&interruptionNoticeAt,
 			&s.CreatedAt,
 			&s.UpdatedAt,
 		)
@@ -295,6 +415,17 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.PrivateIPAddress = s.IPAddress
+		if stuckSince.Valid {
+			s.StuckSince = &stuckSince.Time
+		}
+		s.Spot = models.ToSpotInfo(s.PurchaseOption, spotMaxPrice, interruptionBehavior, interruptionNoticeAt)
+		s.Tags, err = services.UnmarshalTags(tags)
+		if err != nil {
+			api.logger.Error("Failed to decode server tags", zap.Error(err))
+			util.RespondWithError(w, http.StatusInternalServerError, "Failed to scan server data")
+			return
+		}
 		servers = append(servers, s)
 	}
 
@@ -304,6 +435,13 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	serverRefs := make([]*models.ServerResponse, len(servers))
+	for i := range servers {
+		serverRefs[i] = &servers[i]
+	}
+	api.withNetworking(r.Context(), serverRefs...)
+	api.withUsage(r.Context(), serverRefs...)
+
 	limitVal, err := strconv.Atoi(limit)
 	if err != nil {
 		limitVal = 10
@@ -325,11 +463,16 @@ func (api *ServerAPI) ListServers(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetServerLogs godoc
-// @Summary Return last 100 lifecycle events
-// @Description Retrieves the last 100 lifecycle events for a specific virtual server.
+// @Summary List a server's lifecycle events
+// @Description Retrieves the lifecycle events of a server, newest first: status changes (with fromStatus and toStatus), resizes, network changes and daemon actions, each with the actor that caused it and the request ID. Events are kept for SERVER_EVENTS_RETENTION.
 // @Tags servers
 // @Produce json
 // @Param serverID path string true "ID of the server"
+// @Param type query string false "Only events of these types, comma-separated" example:"status_change,resized"
+// @Param from query string false "Only events at or after this RFC 3339 timestamp" example:"2023-10-27T09:00:00Z"
+// @Param to query string false "Only events before this RFC 3339 timestamp" example:"2023-10-27T10:00:00Z"
+// @Param limit query int false "Page size (1-100)" default(10)
+// @Param offset query int false "Page offset" default(0)
 // @Success 200 {object} models.ServerLogsResponse
 // @Failure 400 {object} util.ErrorResponse
 // @Failure 404 {object} util.ErrorResponse
@@ -339,30 +482,56 @@ func (api *ServerAPI) GetServerLogs(w http.ResponseWriter, r *http.Request) {
 
 	api.logger.Info("Entering GetServerLogs handler")
 
-	serverIDStr := chi.URLParam(r, "serverID")
-	serverUUID := services.StringToPGUUID(serverIDStr) // Convert to pgtype.UUID
-	server, err := api.dbconn.Queries.GetServer(r.Context(), serverUUID)
-	if err != nil {
-		api.logger.Error("Failed to retrieve server for action", zap.String("serverID", serverIDStr), zap.Error(err))
-		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve server details")
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
 		return
 	}
 
-	// server.LifecycleLogs is already json.RawMessage from GetServer query or ServerCtx
-	var logs []models.ServerLifecycleLogEntry
-	if len(server.LifecycleLogs) > 0 { // Only unmarshal if there's data
-		if err := json.Unmarshal(server.LifecycleLogs, &logs); err != nil {
-			api.logger.Error("Failed to parse server lifecycle logs from database", zap.String("serverID", server.ID.String()), zap.Error(err))
-			util.RespondWithError(w, http.StatusInternalServerError, "Failed to parse server logs")
+	query := r.URL.Query()
+	var filter services.ServerEventFilter
+	if v := query.Get("type"); v != "" {
+		for _, eventType := range strings.Split(v, ",") {
+			if !slices.Contains(services.ServerEventTypes, eventType) {
+				util.RespondWithError(w, http.StatusBadRequest, "type must be one of "+strings.Join(services.ServerEventTypes, ", "))
+				return
+			}
+			filter.Types = append(filter.Types, eventType)
+		}
+	}
+	if v := query.Get("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "from must be an RFC 3339 timestamp")
+			return
+		}
+		filter.From = parsed
+	}
+	if v := query.Get("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "to must be an RFC 3339 timestamp")
 			return
 		}
-	} else {
-		api.logger.Debug("No lifecycle logs found for server", zap.String("serverID", server.ID.String()))
-		// logs is already an empty slice, which is fine
+		filter.To = parsed
 	}
 
-	api.logger.Info("Successfully retrieved server lifecycle logs", zap.String("serverID", server.ID.String()), zap.Int("log_count", len(logs)))
-	util.RespondWithJSON(w, http.StatusOK, models.ServerLogsResponse{Logs: logs})
+	events, err := api.serverService.ListEvents(r.Context(), server.ID, filter, limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list server events", zap.String("serverID", server.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list server logs")
+		return
+	}
+
+	response := models.ServerLogsResponse{Logs: make([]models.ServerEventResponse, 0, len(events)), Limit: limit, Offset: offset}
+	for _, event := range events {
+		response.Logs = append(response.Logs, models.ToServerEventResponse(event))
+	}
+	api.logger.Info("Successfully retrieved server lifecycle logs", zap.String("serverID", server.ID.String()), zap.Int("log_count", len(events)))
+	util.RespondWithJSON(w, http.StatusOK, response)
 
 	api.logger.Info("Exiting GetServerLogs handler")
 }
@@ -383,10 +552,10 @@ func (api *ServerAPI) HealthzHandler(w http.ResponseWriter, r *http.Request) {
 
 // ReadyzHandler godoc
 // @Summary Application Readiness Probe
-// @Description Checks if the application is ready to serve traffic, including dependencies like the database.
+// @Description Checks if the application is ready to serve traffic, including dependencies like the database, and reports which background jobs this replica runs as their leader.
 // @Tags Health
-// @Produce plain
-// @Success 200 {string} string "OK"
+// @Produce json
+// @Success 200 {object} models.ReadyzResponse
 // @Failure 503 {string} string "Service Unavailable"
 // @Router /readyz [get]
 func (api *ServerAPI) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
@@ -406,6 +575,12 @@ func (api *ServerAPI) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Add other critical dependency checks here if needed (e.g., message queues, external APIs)
-	util.RespondWithJSON(w, http.StatusOK, "OK")
+	// Followers are ready too; leadership only tells which replica runs the background jobs.
+	// With leader election disabled there is no leadership to report.
+	response := models.ReadyzResponse{Status: "OK"}
+	if api.elector != nil {
+		response.Leader = api.elector.Leadership()
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
 	api.logger.Info("Exiting ReadyzHandler handler")
 }
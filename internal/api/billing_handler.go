@@ -0,0 +1,343 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// ListInvoices godoc
+// @Summary List invoices
+// @Description Lists issued invoices, newest period first, without their line items. Responds with CSV when format=csv or the Accept header asks for text/csv.
+// @Tags billing
+// @Produce json,text/csv
+// @Param project query string false "Filter by project" example:"checkout"
+// @Param period query string false "Filter by billing period (YYYY-MM)" example:"2023-10"
+// @Param currency query string false "Restate invoices in this currency, at the rate in effect when their period ended" example:"EUR"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListInvoicesResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /invoices [get]
+func (api *ServerAPI) ListInvoices(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListInvoices handler")
+
+	query := r.URL.Query()
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	params := sqlc.ListInvoicesParams{
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	}
+	if project := query.Get("project"); project != "" {
+		params.Project = pgtype.Text{String: project, Valid: true}
+	}
+	if period := query.Get("period"); period != "" {
+		periodStart, err := time.Parse("2006-01", period)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "period must be formatted as YYYY-MM")
+			return
+		}
+		params.PeriodStart = pgtype.Date{Time: periodStart, Valid: true}
+	}
+
+	invoices, err := api.billing.ListInvoices(r.Context(), params)
+	if err != nil {
+		api.logger.Error("Failed to list invoices", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list invoices")
+		return
+	}
+	currency, rates, ok := api.invoiceCurrency(w, r)
+	if !ok {
+		return
+	}
+	if currency != "" {
+		for i := range invoices {
+			if invoices[i], _, err = rates.ConvertInvoice(invoices[i], nil, currency); err != nil {
+				util.RespondWithError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+		}
+	}
+
+	if wantsCSV(r) {
+		rows := [][]string{{"id", "project", "period_start", "period_end", "currency", "total", "issued_at"}}
+		for _, invoice := range invoices {
+			response := models.ToInvoiceResponse(invoice, nil)
+			rows = append(rows, []string{
+				response.ID, response.Project, response.PeriodStart, response.PeriodEnd,
+				response.Currency, formatAmount(response.Total), response.IssuedAt.Format(time.RFC3339),
+			})
+		}
+		respondWithCSV(w, "invoices.csv", rows)
+		return
+	}
+
+	response := models.ListInvoicesResponse{
+		Invoices: make([]models.InvoiceResponse, 0, len(invoices)),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, invoice := range invoices {
+		response.Invoices = append(response.Invoices, models.ToInvoiceResponse(invoice, nil))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListInvoices handler")
+}
+
+// GetInvoice godoc
+// @Summary Retrieve an invoice
+// @Description Retrieves an invoice with its line items, grouped by server, charge type and unit price. Responds with CSV (one row per line) when format=csv or the Accept header asks for text/csv.
+// @Tags billing
+// @Produce json,text/csv
+// @Param invoiceID path string true "ID of the invoice"
+// @Param currency query string false "Restate the invoice in this currency, at the rate in effect when its period ended" example:"EUR"
+// @Param format query string false "Response format" Enums(json, csv)
+// @Success 200 {object} models.InvoiceResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /invoices/{invoiceID} [get]
+func (api *ServerAPI) GetInvoice(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetInvoice handler")
+
+	invoiceID := services.StringToPGUUID(chi.URLParam(r, "invoiceID"))
+	if !invoiceID.Valid {
+		util.RespondWithError(w, http.StatusNotFound, "Invoice not found")
+		return
+	}
+
+	invoice, lines, err := api.billing.GetInvoice(r.Context(), invoiceID)
+	if errors.Is(err, services.ErrInvoiceNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Invoice not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve invoice", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve invoice")
+		return
+	}
+	currency, rates, ok := api.invoiceCurrency(w, r)
+	if !ok {
+		return
+	}
+	if currency != "" {
+		if invoice, lines, err = rates.ConvertInvoice(invoice, lines, currency); err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	response := models.ToInvoiceResponse(invoice, lines)
+	if wantsCSV(r) {
+		rows := [][]string{{
+			"invoice_id", "project", "period_start", "server_id", "server_name", "charge_type",
+			"server_type", "region", "quantity", "unit", "unit_price", "amount", "currency",
+		}}
+		for _, line := range response.Lines {
+			rows = append(rows, []string{
+				response.ID, response.Project, response.PeriodStart, line.ServerID, line.ServerName, line.ChargeType,
+				line.ServerType, line.Region, strconv.FormatFloat(line.Quantity, 'f', -1, 64), line.Unit,
+				strconv.FormatFloat(line.UnitPrice, 'f', -1, 64), formatAmount(line.Amount), response.Currency,
+			})
+		}
+		respondWithCSV(w, "invoice-"+response.ID+".csv", rows)
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting GetInvoice handler")
+}
+
+// GetServerForecast godoc
+// @Summary Forecast a server's spend
+// @Description Projects a server's spend to the end of the current billing period: the usage so far, priced as it is invoiced, plus the rest of the period. A running server is expected to keep running; a stopped one to run as much as it did over the last FORECAST_LOOKBACK. Disk and public IPs it holds are expected to be kept.
+// @Tags billing
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param currency query string false "Currency of the amounts, defaults to the project's" example:"EUR"
+// @Success 200 {object} models.ForecastResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/forecast [get]
+func (api *ServerAPI) GetServerForecast(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetServerForecast handler")
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	currency, rate, ok := api.currencyFor(w, r, server.Project, now)
+	if !ok {
+		return
+	}
+	forecast, err := api.billing.ForecastServer(r.Context(), server, now)
+	if err != nil {
+		api.logger.Error("Failed to forecast server spend", zap.String("serverID", server.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to forecast server spend")
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusOK, models.ForecastResponse{
+		ServerID:      forecast.ServerID.String(),
+		Project:       forecast.Project,
+		PeriodStart:   forecast.PeriodStart,
+		PeriodEnd:     forecast.PeriodEnd,
+		Currency:      currency,
+		ActualCost:    forecast.ActualCost * rate,
+		ProjectedCost: forecast.ProjectedCost * rate,
+		ForecastCost:  (forecast.ActualCost + forecast.ProjectedCost) * rate,
+		Utilization:   forecast.Utilization,
+	})
+
+	api.logger.Info("Exiting GetServerForecast handler")
+}
+
+// GetBillingForecast godoc
+// @Summary Forecast spend per project
+// @Description Projects the spend of every project to the end of the current billing period, on the same basis as the server forecast.
+// @Tags billing
+// @Produce json
+// @Param project query string false "Only forecast this project" example:"checkout"
+// @Param currency query string false "Currency of the amounts, defaults to the project's, or USD for all projects" example:"EUR"
+// @Success 200 {object} models.BillingForecastResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /billing/forecast [get]
+func (api *ServerAPI) GetBillingForecast(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetBillingForecast handler")
+
+	now := time.Now()
+	project := r.URL.Query().Get("project")
+	currency, rate, ok := api.currencyFor(w, r, project, now)
+	if !ok {
+		return
+	}
+	forecasts, err := api.billing.ForecastProjects(r.Context(), project, now)
+	if err != nil {
+		api.logger.Error("Failed to forecast spend", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to forecast spend")
+		return
+	}
+
+	response := models.BillingForecastResponse{
+		PeriodStart: services.PeriodStart(now),
+		PeriodEnd:   services.PeriodEnd(now),
+		Currency:    currency,
+		Projects:    make([]models.ProjectForecastResponse, 0, len(forecasts)),
+	}
+	for _, forecast := range forecasts {
+		actual, projected := forecast.ActualCost*rate, forecast.ProjectedCost*rate
+		response.ActualCost += actual
+		response.ProjectedCost += projected
+		response.Projects = append(response.Projects, models.ProjectForecastResponse{
+			Project:       forecast.Project,
+			Servers:       forecast.Servers,
+			ActualCost:    actual,
+			ProjectedCost: projected,
+			ForecastCost:  actual + projected,
+		})
+	}
+	response.ForecastCost = response.ActualCost + response.ProjectedCost
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting GetBillingForecast handler")
+}
+
+// withUsage replaces the cached uptime of each server response with the exact
+// value metered from usage segments. Failures are logged and leave the cached value.
+func (api *ServerAPI) withUsage(ctx context.Context, responses ...*models.ServerResponse) {
+	serverIDs := make([]pgtype.UUID, 0, len(responses))
+	for _, response := range responses {
+		serverIDs = append(serverIDs, services.StringToPGUUID(response.ID))
+	}
+
+	usage, err := api.serverService.GetServerUsage(ctx, serverIDs...)
+	if err != nil {
+		api.logger.Error("Failed to load server usage", zap.Error(err))
+		return
+	}
+	for _, response := range responses {
+		response.UptimeSeconds = usage[response.ID].UptimeSeconds
+	}
+}
+
+// invoiceCurrency returns the ?currency= to restate invoices in, empty if they
+// are to be returned as issued, with the exchange rates to convert them at. It
+// responds with an error itself if it fails.
+func (api *ServerAPI) invoiceCurrency(w http.ResponseWriter, r *http.Request) (string, services.ExchangeRates, bool) {
+	currency := r.URL.Query().Get("currency")
+	if currency == "" {
+		return "", services.ExchangeRates{}, true
+	}
+	currency, err := services.NormalizeCurrency(currency)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return "", services.ExchangeRates{}, false
+	}
+	rates, err := api.billing.ExchangeRates(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to load exchange rates", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to load exchange rates")
+		return "", services.ExchangeRates{}, false
+	}
+	return currency, rates, true
+}
+
+// toBillingCharges lists a server's usage by charge type, in a stable order.
+func toBillingCharges(usage services.ServerUsage) []models.BillingCharge {
+	charges := make([]models.BillingCharge, 0, len(usage.Charges))
+	for chargeType, charge := range usage.Charges {
+		charges = append(charges, models.BillingCharge{
+			ChargeType: chargeType,
+			Quantity:   charge.Quantity,
+			Unit:       charge.Unit,
+			Amount:     charge.Amount,
+		})
+	}
+	sort.Slice(charges, func(i, j int) bool { return charges[i].ChargeType < charges[j].ChargeType })
+	return charges
+}
+
+// wantsCSV reports whether the client asked for CSV with ?format=csv or an Accept header.
+func wantsCSV(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == "csv"
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/csv")
+}
+
+func respondWithCSV(w http.ResponseWriter, filename string, rows [][]string) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	writer := csv.NewWriter(w)
+	writer.WriteAll(rows)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
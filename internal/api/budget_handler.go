@@ -0,0 +1,213 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// CreateBudget godoc
+// @Summary Create a budget
+// @Description Sets a spending limit on the servers of a project, a region or a tag value. The billing daemon evaluates budgets on every tick: each threshold crossed raises one alert per period (posted to webhookUrl if set), and enforced budgets stop, without terminating, the running servers in scope once the amount is spent.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body models.CreateBudgetRequest true "Budget"
+// @Success 201 {object} models.BudgetResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /budgets [post]
+func (api *ServerAPI) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateBudget handler")
+
+	var req models.CreateBudgetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	budget, err := api.budgets.CreateBudget(r.Context(), sqlc.CreateBudgetParams{
+		Name:       req.Name,
+		ScopeType:  req.ScopeType,
+		ScopeKey:   req.ScopeKey,
+		ScopeValue: req.ScopeValue,
+		Amount:     req.Amount,
+		Period:     req.Period,
+		Thresholds: req.Thresholds,
+		WebhookUrl: req.WebhookURL,
+		Enforce:    req.Enforce,
+	})
+	if errors.Is(err, services.ErrInvalidBudget) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create budget", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create budget")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToBudgetResponse(budget))
+
+	api.logger.Info("Exiting CreateBudget handler")
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Tags budgets
+// @Produce json
+// @Success 200 {object} models.ListBudgetsResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /budgets [get]
+func (api *ServerAPI) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListBudgets handler")
+
+	budgets, err := api.budgets.ListBudgets(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to list budgets", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list budgets")
+		return
+	}
+
+	response := models.ListBudgetsResponse{Budgets: make([]models.BudgetResponse, 0, len(budgets))}
+	for _, budget := range budgets {
+		response.Budgets = append(response.Budgets, models.ToBudgetResponse(budget))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListBudgets handler")
+}
+
+// GetBudget godoc
+// @Summary Retrieve a budget
+// @Description Retrieves a budget with its spend in the current period.
+// @Tags budgets
+// @Produce json
+// @Param budgetID path string true "ID of the budget"
+// @Success 200 {object} models.BudgetResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /budgets/{budgetID} [get]
+func (api *ServerAPI) GetBudget(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetBudget handler")
+
+	budget, ok := api.loadBudget(w, r)
+	if !ok {
+		return
+	}
+	status, err := api.budgets.GetBudgetStatus(r.Context(), budget, time.Now())
+	if err != nil {
+		api.logger.Error("Failed to compute budget spend", zap.String("budgetID", budget.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to compute budget spend")
+		return
+	}
+
+	response := models.ToBudgetResponse(budget)
+	response.Status = &models.BudgetStatusResponse{
+		PeriodStart: status.PeriodStart,
+		PeriodEnd:   status.PeriodEnd,
+		Spend:       status.Spend,
+		PercentUsed: status.Spend / budget.Amount * 100,
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting GetBudget handler")
+}
+
+// DeleteBudget godoc
+// @Summary Delete a budget
+// @Description Deletes a budget and its alerts. Servers stopped by the budget stay stopped.
+// @Tags budgets
+// @Param budgetID path string true "ID of the budget"
+// @Success 204
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /budgets/{budgetID} [delete]
+func (api *ServerAPI) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering DeleteBudget handler")
+
+	err := api.budgets.DeleteBudget(r.Context(), services.StringToPGUUID(chi.URLParam(r, "budgetID")))
+	if errors.Is(err, services.ErrBudgetNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Budget not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to delete budget", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to delete budget")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	api.logger.Info("Exiting DeleteBudget handler")
+}
+
+// ListBudgetAlerts godoc
+// @Summary List budget alerts
+// @Description Lists the thresholds a budget has crossed, newest first, with the outcome of their webhook.
+// @Tags budgets
+// @Produce json
+// @Param budgetID path string true "ID of the budget"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListBudgetAlertsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /budgets/{budgetID}/alerts [get]
+func (api *ServerAPI) ListBudgetAlerts(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListBudgetAlerts handler")
+
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	budget, ok := api.loadBudget(w, r)
+	if !ok {
+		return
+	}
+
+	alerts, err := api.budgets.ListBudgetAlerts(r.Context(), budget.ID, limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list budget alerts", zap.String("budgetID", budget.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list budget alerts")
+		return
+	}
+
+	response := models.ListBudgetAlertsResponse{
+		Alerts: make([]models.BudgetAlertResponse, 0, len(alerts)),
+		Limit:  limit,
+		Offset: offset,
+	}
+	for _, alert := range alerts {
+		response.Alerts = append(response.Alerts, models.ToBudgetAlertResponse(alert))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListBudgetAlerts handler")
+}
+
+// loadBudget fetches the budget named by the budgetID URL parameter, writing an
+// error response if there is none.
+func (api *ServerAPI) loadBudget(w http.ResponseWriter, r *http.Request) (sqlc.Budget, bool) {
+	budgetIDStr := chi.URLParam(r, "budgetID")
+	budget, err := api.budgets.GetBudget(r.Context(), services.StringToPGUUID(budgetIDStr))
+	if errors.Is(err, services.ErrBudgetNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Budget not found")
+		return sqlc.Budget{}, false
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve budget", zap.String("budgetID", budgetIDStr), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve budget")
+		return sqlc.Budget{}, false
+	}
+	return budget, true
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/util"
+)
+
+// GetConsistencyReport godoc
+// @Summary Check servers against their addresses
+// @Description Lists the inconsistencies between servers, their IP addresses and NAT mappings, without repairing them: live servers whose address is not the primary address of their primary interface (address_mismatch), addresses and active NAT mappings still held by terminated servers (ip_on_terminated_server, nat_on_terminated_server), addresses reserved but never bound to a server (orphaned_ip) and servers provisioning for longer than the provisioning entry of STUCK_STATE_TIMEOUTS (stuck_provisioning). With CONSISTENCY_AUTO_REPAIR the consistency daemon repairs them every CONSISTENCY_INTERVAL and records each repair in the server's lifecycle logs.
+// @Tags admin
+// @Produce json
+// @Success 200 {object} models.ConsistencyReportResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/consistency [get]
+func (api *ServerAPI) GetConsistencyReport(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetConsistencyReport handler")
+
+	report, err := api.consistency.Check(r.Context(), time.Now(), false)
+	if err != nil {
+		api.logger.Error("Failed to check consistency", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to check consistency")
+		return
+	}
+
+	response := models.ConsistencyReportResponse{
+		CheckedAt:  report.CheckedAt,
+		AutoRepair: api.cfg.ConsistencyAutoRepair,
+		Anomalies:  make([]models.ConsistencyAnomalyResponse, 0, len(report.Anomalies)),
+	}
+	for _, anomaly := range report.Anomalies {
+		response.Anomalies = append(response.Anomalies, models.ConsistencyAnomalyResponse{
+			Kind:        anomaly.Kind,
+			ServerID:    anomaly.ServerID.String(),
+			IPAddressID: anomaly.IPAddressID.String(),
+			Detail:      anomaly.Detail,
+			Repaired:    anomaly.Repaired,
+			RepairError: anomaly.RepairError,
+		})
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting GetConsistencyReport handler")
+}
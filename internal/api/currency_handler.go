@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// ListExchangeRates godoc
+// @Summary List exchange rates
+// @Description Lists the exchange rate versions billing responses and invoices are converted at, per unit of USD.
+// @Tags billing
+// @Produce json
+// @Param currency query string false "Filter by currency" example:"EUR"
+// @Success 200 {object} models.ListExchangeRatesResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /exchange-rates [get]
+func (api *ServerAPI) ListExchangeRates(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListExchangeRates handler")
+
+	currency := r.URL.Query().Get("currency")
+	if currency != "" {
+		normalized, err := services.NormalizeCurrency(currency)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		currency = normalized
+	}
+
+	rates, err := api.billing.ExchangeRates(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to list exchange rates", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list exchange rates")
+		return
+	}
+
+	now := time.Now()
+	versions := rates.Versions(currency)
+	response := models.ListExchangeRatesResponse{
+		Base:  services.CurrencyUSD,
+		Rates: make([]models.ExchangeRateResponse, 0, len(versions)),
+	}
+	for _, version := range versions {
+		response.Rates = append(response.Rates, models.ToExchangeRateResponse(version.Rate, version.EffectiveUntil, now))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListExchangeRates handler")
+}
+
+// CreateExchangeRate godoc
+// @Summary Add an exchange rate
+// @Description Adds a version of a currency's exchange rate, in effect from effectiveFrom (default now) until the next version. Issued invoices keep the rate they were issued at.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param rate body models.CreateExchangeRateRequest true "Exchange rate version"
+// @Success 201 {object} models.ExchangeRateResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/exchange-rates [post]
+func (api *ServerAPI) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateExchangeRate handler")
+
+	var req models.CreateExchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	version, err := api.billing.CreateExchangeRate(r.Context(), req.Currency, req.Rate, req.EffectiveFrom)
+	if errors.Is(err, services.ErrInvalidCurrency) || errors.Is(err, services.ErrInvalidExchangeRate) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrExchangeRateExists) {
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create exchange rate", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create exchange rate")
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusCreated, models.ToExchangeRateResponse(version.Rate, version.EffectiveUntil, time.Now()))
+
+	api.logger.Info("Exiting CreateExchangeRate handler")
+}
+
+// GetProject godoc
+// @Summary Retrieve a project's billing settings
+// @Description Retrieves the currency a project is billed in; projects that were never configured are billed in USD.
+// @Tags billing
+// @Produce json
+// @Param project path string true "Name of the project"
+// @Success 200 {object} models.ProjectResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /projects/{project} [get]
+func (api *ServerAPI) GetProject(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetProject handler")
+
+	project, err := api.billing.GetProject(r.Context(), chi.URLParam(r, "project"))
+	if err != nil {
+		api.logger.Error("Failed to retrieve project", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve project")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, models.ProjectResponse{Name: project.Name, Currency: project.Currency})
+
+	api.logger.Info("Exiting GetProject handler")
+}
+
+// UpdateProject godoc
+// @Summary Set a project's currency
+// @Description Sets the preferred currency of a project. Invoices of periods closed from now on are issued in it, and billing responses about the project default to it.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param project path string true "Name of the project"
+// @Param settings body models.UpdateProjectRequest true "Billing settings"
+// @Success 200 {object} models.ProjectResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /projects/{project} [put]
+func (api *ServerAPI) UpdateProject(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering UpdateProject handler")
+
+	var req models.UpdateProjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	project, err := api.billing.SetProjectCurrency(r.Context(), chi.URLParam(r, "project"), req.Currency)
+	if errors.Is(err, services.ErrInvalidCurrency) || errors.Is(err, services.ErrUnknownCurrency) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to update project", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to update project")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, models.ProjectResponse{Name: project.Name, Currency: project.Currency})
+
+	api.logger.Info("Exiting UpdateProject handler")
+}
+
+// currencyFor resolves the currency of a billing response about a project from
+// ?currency=, see services.BillingService.ResolveCurrency, and returns it with the
+// rate to convert USD amounts at. It responds with an error itself if it fails.
+func (api *ServerAPI) currencyFor(w http.ResponseWriter, r *http.Request, project string, at time.Time) (string, float64, bool) {
+	currency, rate, err := api.billing.ResolveCurrency(r.Context(), r.URL.Query().Get("currency"), project, at)
+	if errors.Is(err, services.ErrInvalidCurrency) || errors.Is(err, services.ErrUnknownCurrency) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return "", 0, false
+	}
+	if err != nil {
+		api.logger.Error("Failed to resolve currency", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to resolve currency")
+		return "", 0, false
+	}
+	return currency, rate, true
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// ExportCosts godoc
+// @Summary Export hourly usage and cost
+// @Description Exports one row per server, charge and hour over [from, to) in a FOCUS-style cost-and-usage schema (resource ID, type, region, tags, consumed quantity, list unit price, list and effective cost), derived from metered uptime, resources and egress at catalog prices. Amounts are in USD. The range may span at most 366 days.
+// @Tags billing
+// @Produce text/csv,application/x-ndjson
+// @Param from query string true "Start of the range, a date (YYYY-MM-DD) or an RFC 3339 timestamp" example:"2023-10-01"
+// @Param to query string true "End of the range (exclusive), a date (YYYY-MM-DD) or an RFC 3339 timestamp" example:"2023-11-01"
+// @Param project query string false "Only export servers of this project" example:"checkout"
+// @Param format query string false "Export format (default csv). Only csv and ndjson are implemented; Parquet is not supported" Enums(csv, ndjson)
+// @Success 200 {string} string "Export rows"
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /billing/export [get]
+func (api *ServerAPI) ExportCosts(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ExportCosts handler")
+
+	query := r.URL.Query()
+	from, err := services.ParseExportTime(query.Get("from"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		return
+	}
+	to, err := services.ParseExportTime(query.Get("to"))
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, "to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		return
+	}
+	format := query.Get("format")
+	if format == "" {
+		format = services.ExportFormatCSV
+	}
+	exporter, err := services.NewCostExportWriter(w, format)
+	if err != nil {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Large exports take longer than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	w.Header().Set("Content-Type", exporter.ContentType())
+	w.Header().Set("Content-Disposition", `attachment; filename="cost-export.`+format+`"`)
+
+	err = api.billing.ExportCosts(r.Context(), from, to, query.Get("project"), exporter.Write)
+	if err != nil && !exporter.Sent() {
+		// Nothing reached the client yet; drop the buffered rows and report the error
+		w.Header().Del("Content-Disposition")
+		if errors.Is(err, services.ErrInvalidExportRange) {
+			util.RespondWithError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		api.logger.Error("Failed to export costs", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to export costs")
+		return
+	}
+	if err != nil {
+		// The 200 and part of the rows are out; abort the response so the client
+		// sees a broken transfer rather than an export that looks complete
+		api.logger.Error("Cost export aborted", zap.Error(err), zap.Int("rows", exporter.Rows()))
+		panic(http.ErrAbortHandler)
+	}
+	if err := exporter.Flush(); err != nil {
+		api.logger.Error("Failed to write cost export", zap.Error(err))
+		return
+	}
+
+	api.logger.Info("Exiting ExportCosts handler")
+}
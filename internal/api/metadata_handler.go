@@ -0,0 +1,183 @@
+package api
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/services"
+)
+
+// MetadataRoutes sets up the instance-metadata service. It is served on its own
+// listener (METADATA_LISTEN_ADDR) and identifies the calling server by the
+// request's source address, or X-Forwarded-For/X-Real-IP when set.
+func (api *ServerAPI) MetadataRoutes() http.Handler {
+	route := chi.NewRouter()
+
+	route.Use(middleware.RealIP)
+	route.Use(middleware.Recoverer)
+
+	// GET /latest/
+	route.Get("/latest", api.GetMetadataIndex)
+	route.Get("/latest/", api.GetMetadataIndex)
+	// GET /latest/meta-data/...
+	route.Get("/latest/meta-data", api.GetInstanceMetadata)
+	route.Get("/latest/meta-data/*", api.GetInstanceMetadata)
+	// GET /latest/user-data
+	route.Get("/latest/user-data", api.GetUserData)
+
+	return route
+}
+
+// GetMetadataIndex lists the top-level metadata categories.
+func (api *ServerAPI) GetMetadataIndex(w http.ResponseWriter, r *http.Request) {
+	respondWithText(w, http.StatusOK, "meta-data/\nuser-data")
+}
+
+// GetInstanceMetadata serves one metadata value, or the listing of a metadata
+// directory, for the calling server.
+func (api *ServerAPI) GetInstanceMetadata(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetInstanceMetadata handler")
+
+	metadata, ok := api.callerMetadata(w, r)
+	if !ok {
+		return
+	}
+
+	leaves := metadataLeaves(metadata)
+	path := strings.Trim(chi.URLParam(r, "*"), "/")
+	if value, ok := leaves[path]; ok {
+		respondWithText(w, http.StatusOK, value)
+	} else if children := metadataChildren(leaves, path); len(children) > 0 {
+		respondWithText(w, http.StatusOK, strings.Join(children, "\n"))
+	} else {
+		respondWithText(w, http.StatusNotFound, "Not Found")
+	}
+
+	api.logger.Info("Exiting GetInstanceMetadata handler")
+}
+
+// GetUserData serves the calling server's user-data exactly as it was provisioned.
+func (api *ServerAPI) GetUserData(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetUserData handler")
+
+	metadata, ok := api.callerMetadata(w, r)
+	if !ok {
+		return
+	}
+	if metadata.Server.UserData == "" {
+		respondWithText(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(metadata.Server.UserData))
+
+	api.logger.Info("Exiting GetUserData handler")
+}
+
+// callerMetadata looks up the server bound to the request's source address,
+// writing an error response if there is none.
+func (api *ServerAPI) callerMetadata(w http.ResponseWriter, r *http.Request) (services.InstanceMetadata, bool) {
+	callerIP := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(callerIP); err == nil {
+		callerIP = host
+	}
+
+	metadata, err := api.serverService.GetInstanceMetadata(r.Context(), callerIP)
+	if errors.Is(err, services.ErrUnknownCaller) {
+		api.logger.Warn("Metadata request from unknown address", zap.String("caller_ip", callerIP))
+		respondWithText(w, http.StatusNotFound, "Not Found")
+		return services.InstanceMetadata{}, false
+	}
+	if err != nil {
+		api.logger.Error("Failed to load instance metadata", zap.String("caller_ip", callerIP), zap.Error(err))
+		respondWithText(w, http.StatusInternalServerError, "Internal Server Error")
+		return services.InstanceMetadata{}, false
+	}
+	return metadata, true
+}
+
+// metadataLeaves flattens a server's metadata into path → value pairs.
+func metadataLeaves(metadata services.InstanceMetadata) map[string]string {
+	server := metadata.Server
+	leaves := map[string]string{
+		"instance-id":      server.ID.String(),
+		"instance-type":    server.Type,
+		"placement/region": server.Region,
+		"hostname":         server.Hostname,
+		"local-hostname":   server.Hostname,
+		"local-ipv4":       server.Address,
+	}
+	if metadata.PublicIP != "" {
+		leaves["public-ipv4"] = metadata.PublicIP
+	}
+	leaves["instance-life-cycle"] = "on-demand"
+	if server.PurchaseOption == services.PurchaseOptionSpot {
+		leaves["instance-life-cycle"] = "spot"
+	}
+	// Like the real service, spot/instance-action only exists once the interruption notice is given
+	if !metadata.InterruptAt.IsZero() {
+		leaves["spot/instance-action"] = `{"action":"` + server.InterruptionBehavior + `","time":"` + metadata.InterruptAt.UTC().Format(time.RFC3339) + `"}`
+	}
+	for key, value := range metadata.Tags {
+		leaves["tags/instance/"+key] = value
+	}
+
+	addresses := make(map[string][]string)
+	for _, address := range metadata.Addresses {
+		interfaceID := address.InterfaceID.String()
+		// Keep each interface's primary address first, like the real service.
+		if address.IsPrimary {
+			addresses[interfaceID] = append([]string{address.Address}, addresses[interfaceID]...)
+		} else {
+			addresses[interfaceID] = append(addresses[interfaceID], address.Address)
+		}
+	}
+	for _, networkInterface := range metadata.Interfaces {
+		prefix := "network/interfaces/" + strconv.Itoa(int(networkInterface.DeviceIndex)) + "/"
+		leaves[prefix+"interface-id"] = networkInterface.ID.String()
+		leaves[prefix+"local-ipv4s"] = strings.Join(addresses[networkInterface.ID.String()], "\n")
+	}
+	return leaves
+}
+
+// metadataChildren lists the entries directly under dir, with a trailing "/" on sub-directories.
+func metadataChildren(leaves map[string]string, dir string) []string {
+	prefix := ""
+	if dir != "" {
+		prefix = dir + "/"
+	}
+
+	seen := make(map[string]bool)
+	var children []string
+	for path := range leaves {
+		if !strings.HasPrefix(path, prefix) {
+			continue
+		}
+		child := strings.TrimPrefix(path, prefix)
+		if i := strings.Index(child, "/"); i >= 0 {
+			child = child[:i+1]
+		}
+		if !seen[child] {
+			seen[child] = true
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+func respondWithText(w http.ResponseWriter, code int, body string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write([]byte(body))
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/services"
+)
+
+func testMetadata() services.InstanceMetadata {
+	eth0 := pgtype.UUID{Bytes: [16]byte{1}, Valid: true}
+	eth1 := pgtype.UUID{Bytes: [16]byte{2}, Valid: true}
+	return services.InstanceMetadata{
+		Server: sqlc.Server{
+			ID:                   pgtype.UUID{Bytes: [16]byte{9}, Valid: true},
+			Hostname:             "web.vs.internal",
+			Region:               "us-east-1",
+			Type:                 "t3.micro",
+			Address:              "10.0.0.5",
+			PurchaseOption:       services.PurchaseOptionSpot,
+			InterruptionBehavior: "stop",
+		},
+		Tags:     map[string]string{"env": "prod", "team": "infra"},
+		PublicIP: "203.0.113.7",
+		Interfaces: []sqlc.NetworkInterface{
+			{ID: eth0, DeviceIndex: 0, IsPrimary: true},
+			{ID: eth1, DeviceIndex: 1},
+		},
+		Addresses: []sqlc.ListIPAddressesByServerIDsRow{
+			{Address: "10.0.0.6", InterfaceID: eth0},
+			{Address: "10.0.0.5", InterfaceID: eth0, IsPrimary: true},
+			{Address: "10.0.1.9", InterfaceID: eth1, IsPrimary: true},
+		},
+		InterruptAt: time.Date(2026, 3, 1, 12, 2, 0, 0, time.UTC),
+	}
+}
+
+func TestMetadataLeaves(t *testing.T) {
+	onDemand := testMetadata()
+	onDemand.Server.PurchaseOption = services.PurchaseOptionOnDemand
+	onDemand.PublicIP = ""
+	onDemand.InterruptAt = time.Time{}
+
+	tests := []struct {
+		name     string
+		metadata services.InstanceMetadata
+		path     string
+		want     string
+		wantOK   bool
+	}{
+		{name: "instance id", metadata: testMetadata(), path: "instance-id", want: testMetadata().Server.ID.String(), wantOK: true},
+		{name: "region", metadata: testMetadata(), path: "placement/region", want: "us-east-1", wantOK: true},
+		{name: "hostname", metadata: testMetadata(), path: "local-hostname", want: "web.vs.internal", wantOK: true},
+		{name: "public address", metadata: testMetadata(), path: "public-ipv4", want: "203.0.113.7", wantOK: true},
+		{name: "no public address", metadata: onDemand, path: "public-ipv4"},
+		{name: "spot life cycle", metadata: testMetadata(), path: "instance-life-cycle", want: "spot", wantOK: true},
+		{name: "on-demand life cycle", metadata: onDemand, path: "instance-life-cycle", want: "on-demand", wantOK: true},
+		{name: "interruption notice", metadata: testMetadata(), path: "spot/instance-action", want: `{"action":"stop","time":"2026-03-01T12:02:00Z"}`, wantOK: true},
+		{name: "no interruption notice", metadata: onDemand, path: "spot/instance-action"},
+		{name: "tag", metadata: testMetadata(), path: "tags/instance/env", want: "prod", wantOK: true},
+		{name: "primary address listed first", metadata: testMetadata(), path: "network/interfaces/0/local-ipv4s", want: "10.0.0.5\n10.0.0.6", wantOK: true},
+		{name: "second interface", metadata: testMetadata(), path: "network/interfaces/1/local-ipv4s", want: "10.0.1.9", wantOK: true},
+		{name: "directory is not a leaf", metadata: testMetadata(), path: "tags/instance"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := metadataLeaves(tt.metadata)[tt.path]
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("metadataLeaves()[%q] = %q, %v; want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMetadataChildren(t *testing.T) {
+	leaves := metadataLeaves(testMetadata())
+	tests := []struct {
+		name string
+		dir  string
+		want []string
+	}{
+		{name: "root", dir: "", want: []string{
+			"hostname", "instance-id", "instance-life-cycle", "instance-type", "local-hostname",
+			"local-ipv4", "network/", "placement/", "public-ipv4", "spot/", "tags/",
+		}},
+		{name: "sub-directory", dir: "tags", want: []string{"instance/"}},
+		{name: "tags", dir: "tags/instance", want: []string{"env", "team"}},
+		{name: "interfaces", dir: "network/interfaces", want: []string{"0/", "1/"}},
+		{name: "interface", dir: "network/interfaces/0", want: []string{"interface-id", "local-ipv4s"}},
+		{name: "leaf has no children", dir: "hostname"},
+		{name: "prefix of a name is not a directory", dir: "host"},
+		{name: "unknown", dir: "nope"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := metadataChildren(leaves, tt.dir); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("metadataChildren(%q) = %q; want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMetadataCaller checks that the metadata service identifies the caller by
+// its source address, or by X-Forwarded-For/X-Real-IP when set, and answers 404
+// for addresses no live server holds. It runs against the database of the
+// repository's .env and is skipped when there is none.
+func TestMetadataCaller(t *testing.T) {
+	cfg, dbClient := testDBClient(t)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	name := "metadata-caller-" + uuid.NewString()[:8]
+	const address = "198.51.100.77"
+	var serverID, poolID pgtype.UUID
+	err := dbClient.Pool.QueryRow(ctx, `
+		INSERT INTO servers (name, hostname, region, project, status, address, type, hourly_cost)
+		VALUES ($1, $1 || '.test.invalid', 'us-east-1', $1, 'running', $2, 't2.micro', 0.0116)
+		RETURNING id`, name, address).Scan(&serverID)
+	if err != nil {
+		t.Fatalf("failed to seed server: %v", err)
+	}
+	defer func() {
+		if _, err := dbClient.Pool.Exec(ctx, `DELETE FROM servers WHERE id = $1`, serverID); err != nil {
+			t.Errorf("failed to delete server: %v", err)
+		}
+	}()
+	err = dbClient.Pool.QueryRow(ctx, `
+		INSERT INTO ip_pools (name, cidr) VALUES ($1, '198.51.100.0/24') RETURNING id`, name).Scan(&poolID)
+	if err != nil {
+		t.Fatalf("failed to seed pool: %v", err)
+	}
+	defer func() {
+		if _, err := dbClient.Pool.Exec(ctx, `DELETE FROM ip_pools WHERE id = $1`, poolID); err != nil {
+			t.Errorf("failed to delete pool: %v", err)
+		}
+	}()
+	_, err = dbClient.Pool.Exec(ctx, `
+		INSERT INTO ip_addresses (pool_id, address, is_allocated, server_id, is_primary)
+		VALUES ($1, $2, TRUE, $3, TRUE)`, poolID, address, serverID)
+	if err != nil {
+		t.Fatalf("failed to seed address: %v", err)
+	}
+
+	serverService := services.NewServerService(dbClient, services.NewIPAllocator(dbClient.Queries, logger), logger, cfg)
+	api := NewServerAPI(cfg, dbClient, serverService, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		header     string // X-Real-IP, if set
+		forwarded  string // X-Forwarded-For, if set
+		path       string
+		wantCode   int
+		wantBody   string
+	}{
+		{name: "source address", remoteAddr: address + ":40000", path: "/latest/meta-data/instance-id", wantCode: http.StatusOK, wantBody: serverID.String()},
+		{name: "unknown source address", remoteAddr: "192.0.2.1:40000", path: "/latest/meta-data/instance-id", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+		{name: "X-Real-IP", remoteAddr: "127.0.0.1:40000", header: address, path: "/latest/meta-data/local-ipv4", wantCode: http.StatusOK, wantBody: address},
+		{name: "X-Forwarded-For", remoteAddr: "127.0.0.1:40000", forwarded: address + ", 10.9.9.9", path: "/latest/meta-data/hostname", wantCode: http.StatusOK, wantBody: name + ".test.invalid"},
+		{name: "spoofed unknown X-Real-IP", remoteAddr: address + ":40000", header: "192.0.2.1", path: "/latest/meta-data/instance-id", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+		{name: "spoofed unknown X-Forwarded-For", remoteAddr: address + ":40000", forwarded: "192.0.2.1", path: "/latest/meta-data/", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+		{name: "garbage X-Real-IP", remoteAddr: "192.0.2.1:40000", header: "not-an-ip", path: "/latest/meta-data/instance-id", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+		{name: "user-data of unknown caller", remoteAddr: "192.0.2.1:40000", path: "/latest/user-data", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+		{name: "unknown path", remoteAddr: address + ":40000", path: "/latest/meta-data/nope", wantCode: http.StatusNotFound, wantBody: "Not Found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.header != "" {
+				req.Header.Set("X-Real-IP", tt.header)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			rec := httptest.NewRecorder()
+			api.MetadataRoutes().ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || rec.Body.String() != tt.wantBody {
+				t.Errorf("GET %s = %d %q; want %d %q", tt.path, rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
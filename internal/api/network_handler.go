@@ -0,0 +1,309 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/ipam"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// AttachNetworkInterface godoc
+// @Summary Attach a network interface
+// @Description Attaches a secondary network interface to a server, with one address from the requested IP pool.
+// @Tags network
+// @Accept json
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param request body models.AttachInterfaceRequest false "IP pool for the interface's address"
+// @Success 201 {object} models.NetworkInterfaceResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/interfaces [post]
+func (api *ServerAPI) AttachNetworkInterface(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering AttachNetworkInterface handler")
+
+	var req models.AttachInterfaceRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.logger.Error("Invalid request payload for attach interface", zap.Error(err))
+			util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	networkInterface, err := api.serverService.AttachNetworkInterface(r.Context(), server, req.Pool)
+	if err != nil {
+		api.respondWithNetworkError(w, err, "Failed to attach network interface")
+		return
+	}
+
+	response, ok := api.networkInterfaceResponse(w, r, server.ID, networkInterface.ID)
+	if !ok {
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, response)
+
+	api.logger.Info("Exiting AttachNetworkInterface handler")
+}
+
+// DetachNetworkInterface godoc
+// @Summary Detach a network interface
+// @Description Detaches a secondary network interface from a server and releases all of its addresses. The primary interface cannot be detached.
+// @Tags network
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param interfaceID path string true "ID of the network interface"
+// @Success 200 {object} models.ServerResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/interfaces/{interfaceID} [delete]
+func (api *ServerAPI) DetachNetworkInterface(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering DetachNetworkInterface handler")
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	interfaceID := services.StringToPGUUID(chi.URLParam(r, "interfaceID"))
+	if err := api.serverService.DetachNetworkInterface(r.Context(), server, interfaceID); err != nil {
+		api.respondWithNetworkError(w, err, "Failed to detach network interface")
+		return
+	}
+
+	response := models.ToServerResponse(server)
+	api.withNetworking(r.Context(), &response)
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting DetachNetworkInterface handler")
+}
+
+// AssignSecondaryIP godoc
+// @Summary Assign a secondary IP
+// @Description Assigns an additional address from the requested IP pool to one of the server's network interfaces.
+// @Tags network
+// @Accept json
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param interfaceID path string true "ID of the network interface"
+// @Param request body models.AssignIPRequest false "IP pool for the secondary address"
+// @Success 201 {object} models.NetworkInterfaceResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/interfaces/{interfaceID}/ips [post]
+func (api *ServerAPI) AssignSecondaryIP(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering AssignSecondaryIP handler")
+
+	var req models.AssignIPRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.logger.Error("Invalid request payload for assign IP", zap.Error(err))
+			util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+			return
+		}
+	}
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	interfaceID := services.StringToPGUUID(chi.URLParam(r, "interfaceID"))
+	if _, err := api.serverService.AssignSecondaryIP(r.Context(), server, interfaceID, req.Pool); err != nil {
+		api.respondWithNetworkError(w, err, "Failed to assign secondary IP")
+		return
+	}
+
+	response, ok := api.networkInterfaceResponse(w, r, server.ID, interfaceID)
+	if !ok {
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, response)
+
+	api.logger.Info("Exiting AssignSecondaryIP handler")
+}
+
+// ListNATMappings godoc
+// @Summary List NAT mappings
+// @Description Lists 1:1 NAT mappings between public and private addresses, newest first. Released mappings are kept as history unless active=true.
+// @Tags network
+// @Produce json
+// @Param serverId query string false "Filter by server ID"
+// @Param address query string false "Filter by public or private address" example:"203.0.113.25"
+// @Param active query bool false "Only return mappings that are currently active"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListNATMappingsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /nat-mappings [get]
+func (api *ServerAPI) ListNATMappings(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListNATMappings handler")
+
+	query := r.URL.Query()
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	params := sqlc.ListNATMappingsParams{
+		ActiveOnly: query.Get("active") == "true",
+		RowLimit:   int32(limit),
+		RowOffset:  int32(offset),
+	}
+	if serverID := query.Get("serverId"); serverID != "" {
+		params.ServerID = services.StringToPGUUID(serverID)
+		if !params.ServerID.Valid {
+			util.RespondWithError(w, http.StatusBadRequest, "Invalid serverId")
+			return
+		}
+	}
+	if address := query.Get("address"); address != "" {
+		params.Address = pgtype.Text{String: address, Valid: true}
+	}
+
+	mappings, err := api.serverService.ListNATMappings(r.Context(), params)
+	if err != nil {
+		api.logger.Error("Failed to list NAT mappings", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list NAT mappings")
+		return
+	}
+
+	response := models.ListNATMappingsResponse{
+		Mappings: make([]models.NATMappingResponse, 0, len(mappings)),
+		Limit:    limit,
+		Offset:   offset,
+	}
+	for _, mapping := range mappings {
+		response.Mappings = append(response.Mappings, models.ToNATMappingResponse(mapping))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListNATMappings handler")
+}
+
+// loadServer fetches the server named by the serverID URL parameter, writing an error response if it cannot.
+func (api *ServerAPI) loadServer(w http.ResponseWriter, r *http.Request) (sqlc.Server, bool) {
+	serverIDStr := chi.URLParam(r, "serverID")
+	server, err := api.dbconn.Queries.GetServer(r.Context(), services.StringToPGUUID(serverIDStr))
+	if errors.Is(err, pgx.ErrNoRows) {
+		util.RespondWithError(w, http.StatusNotFound, "Server not found")
+		return sqlc.Server{}, false
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve server", zap.String("serverID", serverIDStr), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve server details")
+		return sqlc.Server{}, false
+	}
+	return server, true
+}
+
+// withNetworking fills in the interfaces and public address of each server response.
+// Failures are logged and leave those fields empty rather than failing the request.
+func (api *ServerAPI) withNetworking(ctx context.Context, responses ...*models.ServerResponse) {
+	serverIDs := make([]pgtype.UUID, 0, len(responses))
+	for _, response := range responses {
+		serverIDs = append(serverIDs, services.StringToPGUUID(response.ID))
+	}
+
+	interfaces, addresses, err := api.serverService.GetNetworkInterfaces(ctx, serverIDs...)
+	if err != nil {
+		api.logger.Error("Failed to load network interfaces", zap.Error(err))
+	}
+	mappings, err := api.serverService.GetActiveNATMappings(ctx, serverIDs...)
+	if err != nil {
+		api.logger.Error("Failed to load NAT mappings", zap.Error(err))
+	}
+
+	publicAddresses := make(map[string]string, len(mappings))
+	for _, mapping := range mappings {
+		publicAddresses[mapping.ServerID.String()] = mapping.PublicAddress
+	}
+
+	byServer := models.ToNetworkInterfaceResponses(interfaces, addresses)
+	for _, response := range responses {
+		response.Interfaces = byServer[response.ID]
+		if response.Interfaces == nil {
+			response.Interfaces = []models.NetworkInterfaceResponse{}
+		}
+		response.PublicIPAddress = publicAddresses[response.ID]
+	}
+}
+
+// networkInterfaceResponse builds the response for a single interface of a server.
+func (api *ServerAPI) networkInterfaceResponse(w http.ResponseWriter, r *http.Request, serverID pgtype.UUID, interfaceID pgtype.UUID) (models.NetworkInterfaceResponse, bool) {
+	interfaces, addresses, err := api.serverService.GetNetworkInterfaces(r.Context(), serverID)
+	if err != nil {
+		api.logger.Error("Failed to load network interfaces", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to load network interfaces")
+		return models.NetworkInterfaceResponse{}, false
+	}
+	for _, ni := range models.ToNetworkInterfaceResponses(interfaces, addresses)[serverID.String()] {
+		if ni.ID == interfaceID.String() {
+			return ni, true
+		}
+	}
+	util.RespondWithError(w, http.StatusNotFound, "Network interface not found")
+	return models.NetworkInterfaceResponse{}, false
+}
+
+// respondWithNetworkError maps interface and IP allocation errors to HTTP status codes.
+func (api *ServerAPI) respondWithNetworkError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInterfaceNotFound):
+		util.RespondWithError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, services.ErrUnknownIPPool):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+	case errors.Is(err, services.ErrPrimaryInterface),
+		errors.Is(err, services.ErrServerTerminated),
+		errors.Is(err, ipam.ErrExhausted):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+	default:
+		api.logger.Error(message, zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// parsePagination reads the limit (default 10, max 100) and offset query parameters.
+func parsePagination(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	limit, offset := 10, 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 1 || parsed > 100 {
+			util.RespondWithError(w, http.StatusBadRequest, "limit must be between 1 and 100")
+			return 0, 0, false
+		}
+		limit = parsed
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed < 0 {
+			util.RespondWithError(w, http.StatusBadRequest, "offset must be zero or greater")
+			return 0, 0, false
+		}
+		offset = parsed
+	}
+	return limit, offset, true
+}
@@ -0,0 +1,253 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// ListPrices godoc
+// @Summary List the price catalog
+// @Description Lists every price version, including scheduled ones, by type, region and effective time. Region "*" prices every region without a price of its own. With `at`, only the versions in effect at that time are returned.
+// @Tags billing
+// @Produce json
+// @Param type query string false "Filter by server type" example:"t2.micro"
+// @Param region query string false "Filter by catalog region (\"*\" for the all-regions prices)" example:"us-east-1"
+// @Param at query string false "Only versions in effect at this time (RFC 3339)" example:"2024-01-01T00:00:00Z"
+// @Success 200 {object} models.ListPricesResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /pricing [get]
+func (api *ServerAPI) ListPrices(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListPrices handler")
+
+	query := r.URL.Query()
+	var at *time.Time
+	if atParam := query.Get("at"); atParam != "" {
+		parsed, err := time.Parse(time.RFC3339, atParam)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "at must be an RFC 3339 timestamp")
+			return
+		}
+		at = &parsed
+	}
+
+	versions, err := api.billing.ListPrices(r.Context(), query.Get("type"), query.Get("region"), at)
+	if err != nil {
+		api.logger.Error("Failed to list prices", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list prices")
+		return
+	}
+
+	now := time.Now()
+	response := models.ListPricesResponse{Prices: make([]models.PriceResponse, 0, len(versions))}
+	for _, version := range versions {
+		response.Prices = append(response.Prices, models.ToPriceResponse(version.Price, version.EffectiveUntil, now))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListPrices handler")
+}
+
+// CreatePrice godoc
+// @Summary Schedule a price change
+// @Description Adds a price version for a server type in a region (or "*" for all regions without their own price), taking effect at a future time. Usage from that time on is billed at the new price.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param price body models.CreatePriceRequest true "Price version"
+// @Success 201 {object} models.PriceResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/pricing [post]
+func (api *ServerAPI) CreatePrice(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreatePrice handler")
+
+	var req models.CreatePriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if !util.IsValidServerType(req.Type) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Invalid server type. Only %s, %s, %s are allowed",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+	if req.HourlyRate < 0 {
+		util.RespondWithError(w, http.StatusBadRequest, "hourlyRate must not be negative")
+		return
+	}
+
+	version, err := api.billing.CreatePrice(r.Context(), req.Type, req.Region, req.HourlyRate, req.EffectiveFrom)
+	if errors.Is(err, services.ErrPriceNotInFuture) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrPriceExists) {
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create price", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create price")
+		return
+	}
+
+	util.RespondWithJSON(w, http.StatusCreated, models.ToPriceResponse(version.Price, version.EffectiveUntil, time.Now()))
+
+	api.logger.Info("Exiting CreatePrice handler")
+}
+
+// QuotePrice godoc
+// @Summary Quote the cost of servers
+// @Description Prices a number of servers of one type in one region running for the given hours from now, broken down by the price versions in effect, including scheduled price changes. Each server's time is rounded as its billing model charges it; reserved quotes draw down the project's remaining reserved hours first.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param quote body models.QuoteRequest true "Servers to quote"
+// @Param currency query string false "Currency of the amounts, defaults to the project's" example:"EUR"
+// @Success 200 {object} models.QuoteResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /pricing/quote [post]
+func (api *ServerAPI) QuotePrice(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering QuotePrice handler")
+
+	var req models.QuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Region == "" || !util.IsValidServerType(req.Type) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Region is required and type must be one of %s, %s, %s",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	now := time.Now()
+	currency, rate, ok := api.currencyFor(w, r, req.Project, now)
+	if !ok {
+		return
+	}
+	quote, err := api.billing.QuotePrice(r.Context(), req.Project, req.Type, req.Region, req.Count, req.Hours, req.BillingModel, now)
+	if errors.Is(err, services.ErrInvalidBillingModel) || errors.Is(err, services.ErrInvalidQuote) || errors.Is(err, services.ErrNoPrice) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to quote price", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to quote price")
+		return
+	}
+
+	response := models.QuoteResponse{
+		Type:          quote.ServerType,
+		Region:        quote.Region,
+		Count:         quote.Count,
+		Hours:         quote.Hours,
+		BillingModel:  quote.BillingModel,
+		Currency:      currency,
+		Lines:         make([]models.QuoteLineResponse, 0, len(quote.Slices)),
+		BilledHours:   quote.BilledHours,
+		ReservedHours: quote.ReservedHours,
+		UnitCost:      quote.UnitCost * rate,
+		Total:         quote.Total * rate,
+	}
+	for _, slice := range quote.Slices {
+		hours := slice.End.Sub(slice.Start).Hours()
+		response.Lines = append(response.Lines, models.QuoteLineResponse{
+			From:       slice.Start,
+			Until:      slice.End,
+			Hours:      hours,
+			HourlyRate: slice.Rate * rate,
+			Amount:     hours * slice.Rate * rate * float64(quote.Count),
+		})
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting QuotePrice handler")
+}
+
+// ListSpotPrices godoc
+// @Summary List spot prices
+// @Description Lists the current simulated spot price of every server type and region with spot servers. The market daemon moves each price every SPOT_MARKET_INTERVAL.
+// @Tags billing
+// @Produce json
+// @Param type query string false "Filter by server type" example:"m5.large"
+// @Param region query string false "Filter by region" example:"us-east-1"
+// @Success 200 {object} models.ListSpotPricesResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /pricing/spot [get]
+func (api *ServerAPI) ListSpotPrices(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListSpotPrices handler")
+
+	prices, err := api.spotMarket.ListPrices(r.Context(), r.URL.Query().Get("type"), r.URL.Query().Get("region"))
+	if err != nil {
+		api.logger.Error("Failed to list spot prices", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list spot prices")
+		return
+	}
+
+	response := models.ListSpotPricesResponse{Prices: make([]models.SpotPriceResponse, 0, len(prices))}
+	for _, price := range prices {
+		response.Prices = append(response.Prices, models.ToSpotPriceResponse(price))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListSpotPrices handler")
+}
+
+// SetSpotPrice godoc
+// @Summary Move a spot price
+// @Description Sets the spot price of a server type in a region now, to test preemption handling. Running spot servers bidding below it get their interruption notice and are stopped or terminated once SPOT_INTERRUPTION_NOTICE has passed. The market daemon keeps moving the price from there.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Param price body models.SetSpotPriceRequest true "Spot price"
+// @Success 201 {object} models.SpotPriceResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/pricing/spot [post]
+func (api *ServerAPI) SetSpotPrice(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering SetSpotPrice handler")
+
+	var req models.SetSpotPriceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Region == "" || !util.IsValidServerType(req.Type) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Region is required and type must be one of %s, %s, %s",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+	if req.Price < 0 {
+		util.RespondWithError(w, http.StatusBadRequest, "price must not be negative")
+		return
+	}
+
+	price, err := api.spotMarket.SetPrice(r.Context(), req.Type, req.Region, req.Price, time.Now())
+	if err != nil {
+		api.logger.Error("Failed to set spot price", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to set spot price")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToSpotPriceResponse(price))
+
+	api.logger.Info("Exiting SetSpotPrice handler")
+}
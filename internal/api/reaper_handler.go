@@ -0,0 +1,202 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// CreateReaperPolicy godoc
+// @Summary Create a reaper policy
+// @Description Adds a rule to the idle reaper, which the billing daemon applies on every tick. Servers of the given type, region and tag (any if empty) that have been stopped (stopped_for), running (running_for) or running with CPU telemetry averaging at most maxCpuPercent (idle_for) for durationSeconds are stopped, terminated or posted to webhookUrl (notify). Each server gets the action of the first policy, in creation order, that applies to it, once per status; servers tagged with REAPER_EXEMPT_TAG are never reaped. stopped_for only counts servers stopped through the API or by the reaper, not by budget enforcement, spot interruptions or other daemons.
+// @Tags reaper
+// @Accept json
+// @Produce json
+// @Param policy body models.CreateReaperPolicyRequest true "Reaper policy"
+// @Success 201 {object} models.ReaperPolicyResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/reaper/policies [post]
+func (api *ServerAPI) CreateReaperPolicy(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateReaperPolicy handler")
+
+	var req models.CreateReaperPolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+
+	maxCPUPercent := services.DefaultIdleCPUPercent
+	if req.MaxCPUPercent != nil {
+		maxCPUPercent = *req.MaxCPUPercent
+	}
+	policy, err := api.reaper.CreatePolicy(r.Context(), sqlc.CreateReaperPolicyParams{
+		Name:            req.Name,
+		MatchType:       req.MatchType,
+		MatchRegion:     req.MatchRegion,
+		MatchTagKey:     req.MatchTagKey,
+		MatchTagValue:   req.MatchTagValue,
+		Condition:       req.Condition,
+		DurationSeconds: req.DurationSeconds,
+		MaxCpuPercent:   maxCPUPercent,
+		Action:          req.Action,
+		WebhookUrl:      req.WebhookURL,
+	})
+	if errors.Is(err, services.ErrInvalidReaperPolicy) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create reaper policy", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create reaper policy")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToReaperPolicyResponse(policy))
+
+	api.logger.Info("Exiting CreateReaperPolicy handler")
+}
+
+// ListReaperPolicies godoc
+// @Summary List reaper policies
+// @Description Lists the idle reaper's policies in the order they are applied.
+// @Tags reaper
+// @Produce json
+// @Success 200 {object} models.ListReaperPoliciesResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reaper/policies [get]
+func (api *ServerAPI) ListReaperPolicies(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListReaperPolicies handler")
+
+	policies, err := api.reaper.ListPolicies(r.Context())
+	if err != nil {
+		api.logger.Error("Failed to list reaper policies", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list reaper policies")
+		return
+	}
+
+	response := models.ListReaperPoliciesResponse{Policies: make([]models.ReaperPolicyResponse, 0, len(policies))}
+	for _, policy := range policies {
+		response.Policies = append(response.Policies, models.ToReaperPolicyResponse(policy))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListReaperPolicies handler")
+}
+
+// DeleteReaperPolicy godoc
+// @Summary Delete a reaper policy
+// @Description Deletes a reaper policy and the record of its actions. Servers it stopped or terminated stay that way.
+// @Tags reaper
+// @Param policyID path string true "ID of the reaper policy"
+// @Success 204
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /admin/reaper/policies/{policyID} [delete]
+func (api *ServerAPI) DeleteReaperPolicy(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering DeleteReaperPolicy handler")
+
+	err := api.reaper.DeletePolicy(r.Context(), services.StringToPGUUID(chi.URLParam(r, "policyID")))
+	if errors.Is(err, services.ErrReaperPolicyNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Reaper policy not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to delete reaper policy", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to delete reaper policy")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	api.logger.Info("Exiting DeleteReaperPolicy handler")
+}
+
+// DryRunReaper godoc
+// @Summary List what the reaper would do now
+// @Description Evaluates the reaper policies without acting on any server, listing each server that would be reaped with the policy that applies to it.
+// @Tags reaper
+// @Produce json
+// @Success 200 {object} models.ReaperDryRunResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reaper/dry-run [get]
+func (api *ServerAPI) DryRunReaper(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering DryRunReaper handler")
+
+	now := time.Now()
+	candidates, err := api.reaper.Candidates(r.Context(), now)
+	if err != nil {
+		api.logger.Error("Failed to evaluate reaper policies", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to evaluate reaper policies")
+		return
+	}
+
+	response := models.ReaperDryRunResponse{
+		EvaluatedAt: now,
+		ExemptTag:   api.cfg.ReaperExemptTag,
+		Candidates:  make([]models.ReaperCandidateResponse, 0, len(candidates)),
+	}
+	for _, candidate := range candidates {
+		response.Candidates = append(response.Candidates, models.ToReaperCandidateResponse(candidate.Policy, candidate.Server))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting DryRunReaper handler")
+}
+
+// ListReaperActions godoc
+// @Summary List reaper actions
+// @Description Lists the servers the reaper acted on, newest first, with the outcome of each action.
+// @Tags reaper
+// @Produce json
+// @Param policyId query string false "Only actions of this policy"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListReaperActionsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reaper/actions [get]
+func (api *ServerAPI) ListReaperActions(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListReaperActions handler")
+
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+	var policyID pgtype.UUID
+	if policyIDParam := r.URL.Query().Get("policyId"); policyIDParam != "" {
+		policyID = services.StringToPGUUID(policyIDParam)
+		if !policyID.Valid {
+			util.RespondWithError(w, http.StatusBadRequest, "Invalid policyId")
+			return
+		}
+	}
+
+	actions, err := api.reaper.ListActions(r.Context(), policyID, limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list reaper actions", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list reaper actions")
+		return
+	}
+
+	response := models.ListReaperActionsResponse{
+		Actions: make([]models.ReaperActionResponse, 0, len(actions)),
+		Limit:   limit,
+		Offset:  offset,
+	}
+	for _, action := range actions {
+		response.Actions = append(response.Actions, models.ToReaperActionResponse(action))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListReaperActions handler")
+}
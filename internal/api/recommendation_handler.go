@@ -0,0 +1,152 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// ListRecommendations godoc
+// @Summary List right-sizing recommendations
+// @Description Suggests how to lower the cost of running servers from their running time and CPU telemetry over RIGHTSIZING_LOOKBACK; servers younger than that are left out. Servers whose CPU load never exceeded 5% are to be stopped; on-demand servers whose peak load would stay under RIGHTSIZING_MAX_CPU_PERCENT on a smaller, cheaper type are to be downsized to the smallest such type; on-demand servers of the right size that ran at least RIGHTSIZING_RESERVE_MIN_UPTIME of the time are to be covered by reserved hours. Savings are estimated from the servers' hourly cost and the catalog price, for a month of running as much as over the lookback.
+// @Tags billing
+// @Produce json
+// @Param project query string false "Only servers of this project" example:"checkout"
+// @Param currency query string false "Currency of the costs and savings, defaults to the project's" example:"EUR"
+// @Success 200 {object} models.ListRecommendationsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /recommendations [get]
+func (api *ServerAPI) ListRecommendations(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListRecommendations handler")
+
+	now := time.Now()
+	project := r.URL.Query().Get("project")
+	currency, rate, ok := api.currencyFor(w, r, project, now)
+	if !ok {
+		return
+	}
+
+	recommendations, err := api.recommendations.List(r.Context(), project, now)
+	if err != nil {
+		api.logger.Error("Failed to list recommendations", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list recommendations")
+		return
+	}
+
+	response := models.ListRecommendationsResponse{
+		GeneratedAt:     now,
+		Lookback:        api.cfg.RightsizingLookback.String(),
+		Currency:        currency,
+		Recommendations: make([]models.RecommendationResponse, 0, len(recommendations)),
+	}
+	for _, recommendation := range recommendations {
+		response.TotalMonthlySavings += recommendation.MonthlySavings * rate
+		response.Recommendations = append(response.Recommendations, toRecommendationResponse(recommendation, rate))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListRecommendations handler")
+}
+
+// ApplyRecommendation godoc
+// @Summary Apply a right-sizing recommendation
+// @Description Applies the current recommendation of a kind to a server through the regular server lifecycle: downsize changes its type and hourly cost (a running server keeps running), stop stops it. The change is recorded in the server's lifecycle logs. Reserve recommendations are applied by buying a reservation with POST /reservations.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param recommendation body models.ApplyRecommendationRequest true "Recommendation to apply"
+// @Success 200 {object} models.ApplyRecommendationResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 402 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /recommendations/apply [post]
+func (api *ServerAPI) ApplyRecommendation(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ApplyRecommendation handler")
+
+	var req models.ApplyRecommendationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	serverID := services.StringToPGUUID(req.ServerID)
+	if !serverID.Valid {
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid serverId")
+		return
+	}
+	server, err := api.dbconn.Queries.GetServer(r.Context(), serverID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		util.RespondWithError(w, http.StatusNotFound, "Server not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve server", zap.String("serverID", req.ServerID), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve server details")
+		return
+	}
+
+	updatedServer, recommendation, err := api.recommendations.Apply(r.Context(), server, req.Kind, time.Now())
+	switch {
+	case errors.Is(err, services.ErrInvalidRecommendation), errors.Is(err, services.ErrReserveNotApplicable):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrRecommendationNotFound), errors.Is(err, services.ErrSpotResize),
+		errors.Is(err, services.ErrServerTerminated):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	case errors.Is(err, services.ErrAccountExhausted):
+		util.RespondWithError(w, http.StatusPaymentRequired, err.Error())
+		return
+	case err != nil && strings.Contains(err.Error(), "invalid state transition"):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		api.logger.Error("Failed to apply recommendation", zap.String("serverID", req.ServerID), zap.String("kind", req.Kind), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to apply recommendation")
+		return
+	}
+
+	response := models.ApplyRecommendationResponse{
+		Recommendation: toRecommendationResponse(recommendation, 1),
+		Server:         models.ToServerResponse(updatedServer),
+	}
+	api.withNetworking(r.Context(), &response.Server)
+	api.withUsage(r.Context(), &response.Server)
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ApplyRecommendation handler")
+}
+
+// toRecommendationResponse converts a recommendation, with its USD amounts
+// converted at rate units per USD.
+func toRecommendationResponse(recommendation services.Recommendation, rate float64) models.RecommendationResponse {
+	return models.RecommendationResponse{
+		Kind:             recommendation.Kind,
+		ServerID:         recommendation.ServerID.String(),
+		ServerName:       recommendation.ServerName,
+		Project:          recommendation.Project,
+		Region:           recommendation.Region,
+		Type:             recommendation.Type,
+		TargetType:       recommendation.TargetType,
+		HourlyCost:       recommendation.HourlyCost * rate,
+		TargetHourlyCost: recommendation.TargetHourlyCost * rate,
+		UptimePercent:    recommendation.UptimeShare * 100,
+		CPUPercentAvg:    recommendation.CPUPercentAvg,
+		CPUPercentMax:    recommendation.CPUPercentMax,
+		ReservedHours:    recommendation.ReservedHours,
+		MonthlySavings:   recommendation.MonthlySavings * rate,
+		Reason:           recommendation.Reason,
+	}
+}
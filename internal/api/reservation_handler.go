@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// CreateReservation godoc
+// @Summary Reserve server hours
+// @Description Prepays hours of a server type in a region for a project, at the current catalog price less the reservation discount. The amount is charged upfront; running time of the project's servers on the reserved billing model draws the hours down before it is charged, until the reservation expires.
+// @Tags billing
+// @Accept json
+// @Produce json
+// @Param reservation body models.CreateReservationRequest true "Reservation"
+// @Success 201 {object} models.ReservationResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reservations [post]
+func (api *ServerAPI) CreateReservation(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering CreateReservation handler")
+
+	var req models.CreateReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	if req.Region == "" || !util.IsValidServerType(req.Type) {
+		util.RespondWithError(w, http.StatusBadRequest, fmt.Sprintf("Region is required and type must be one of %s, %s, %s",
+			util.ServerTypeC5Xlarge, util.ServerTypeM5Large, util.ServerTypeT2Micro))
+		return
+	}
+
+	reservation, err := api.billing.CreateReservation(r.Context(), req.Project, req.Type, req.Region, req.Hours)
+	if errors.Is(err, services.ErrInvalidReservation) || errors.Is(err, services.ErrNoPrice) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to create reservation", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to create reservation")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusCreated, models.ToReservationResponse(reservation))
+
+	api.logger.Info("Exiting CreateReservation handler")
+}
+
+// ListReservations godoc
+// @Summary List reservations
+// @Description Lists reservations, newest first, with the hours used so far.
+// @Tags billing
+// @Produce json
+// @Param project query string false "Filter by project" example:"checkout"
+// @Param limit query int false "Number of results to return (default 10, max 100)" default(10) minimum(1) maximum(100)
+// @Param offset query int false "Number of results to skip" default(0) minimum(0)
+// @Success 200 {object} models.ListReservationsResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reservations [get]
+func (api *ServerAPI) ListReservations(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering ListReservations handler")
+
+	limit, offset, ok := parsePagination(w, r)
+	if !ok {
+		return
+	}
+
+	reservations, err := api.billing.ListReservations(r.Context(), r.URL.Query().Get("project"), limit, offset)
+	if err != nil {
+		api.logger.Error("Failed to list reservations", zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to list reservations")
+		return
+	}
+
+	response := models.ListReservationsResponse{
+		Reservations: make([]models.ReservationResponse, 0, len(reservations)),
+		Limit:        limit,
+		Offset:       offset,
+	}
+	for _, reservation := range reservations {
+		response.Reservations = append(response.Reservations, models.ToReservationResponse(reservation))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting ListReservations handler")
+}
+
+// GetReservation godoc
+// @Summary Retrieve a reservation
+// @Tags billing
+// @Produce json
+// @Param reservationID path string true "ID of the reservation"
+// @Success 200 {object} models.ReservationResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /reservations/{reservationID} [get]
+func (api *ServerAPI) GetReservation(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetReservation handler")
+
+	reservationIDStr := chi.URLParam(r, "reservationID")
+	reservation, err := api.billing.GetReservation(r.Context(), services.StringToPGUUID(reservationIDStr))
+	if errors.Is(err, services.ErrReservationNotFound) {
+		util.RespondWithError(w, http.StatusNotFound, "Reservation not found")
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to retrieve reservation", zap.String("reservationID", reservationIDStr), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to retrieve reservation")
+		return
+	}
+	util.RespondWithJSON(w, http.StatusOK, models.ToReservationResponse(reservation))
+
+	api.logger.Info("Exiting GetReservation handler")
+}
@@ -23,21 +23,37 @@ import (
 
 // ServerAPI represents the API handlers and dependencies
 type ServerAPI struct {
-	cfg           *config.Config
-	dbconn        *database.DBClient
-	serverService *services.ServerService
-	logger        *zap.Logger
-	config        *config.Config
+	cfg             *config.Config
+	dbconn          *database.DBClient
+	serverService   *services.ServerService
+	billing         *services.BillingService
+	budgets         *services.BudgetService
+	accounts        *services.AccountService
+	reaper          *services.ReaperService
+	telemetry       *services.TelemetryService
+	consistency     *services.ConsistencyService
+	recommendations *services.RecommendationService
+	spotMarket      *services.SpotMarket
+	elector         *services.LeaderElector
+	logger          *zap.Logger
 }
 
 // NewServerAPI creates a new ServerAPI instance
-func NewServerAPI(cfg *config.Config, dbClient *database.DBClient, serverService *services.ServerService, config *config.Config, logger *zap.Logger) *ServerAPI {
+func NewServerAPI(cfg *config.Config, dbClient *database.DBClient, serverService *services.ServerService, billing *services.BillingService, budgets *services.BudgetService, accounts *services.AccountService, reaper *services.ReaperService, telemetry *services.TelemetryService, consistency *services.ConsistencyService, recommendations *services.RecommendationService, spotMarket *services.SpotMarket, elector *services.LeaderElector, logger *zap.Logger) *ServerAPI {
 	return &ServerAPI{
-		cfg:           cfg,
-		dbconn:        dbClient,
-		serverService: serverService,
-		logger:        logger,
-		config:        config,
+		cfg:             cfg,
+		dbconn:          dbClient,
+		serverService:   serverService,
+		billing:         billing,
+		budgets:         budgets,
+		accounts:        accounts,
+		reaper:          reaper,
+		telemetry:       telemetry,
+		consistency:     consistency,
+		recommendations: recommendations,
+		spotMarket:      spotMarket,
+		elector:         elector,
+		logger:          logger,
 	}
 }
 
@@ -53,7 +69,7 @@ func (api *ServerAPI) Routes() http.Handler {
 	// Basic CORS setup - adjust as needed for production
 	route.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
@@ -79,10 +95,122 @@ func (api *ServerAPI) Routes() http.Handler {
 			r.Post("/action", api.PerformServerAction)
 			// GET /servers/:id
 			r.Get("/", api.GetServer)
+			// PATCH /servers/:id
+			r.Patch("/", api.RenameServer)
 			// GET /servers/:id/logs
 			r.Get("/logs", api.GetServerLogs)
+			// GET /servers/:id/forecast
+			r.Get("/forecast", api.GetServerForecast)
+			// POST /servers/:id/telemetry
+			r.Post("/telemetry", api.RecordServerTelemetry)
+			// GET /servers/:id/telemetry
+			r.Get("/telemetry", api.GetServerTelemetry)
+			// POST /servers/:id/interfaces
+			r.Post("/interfaces", api.AttachNetworkInterface)
+			// DELETE /servers/:id/interfaces/:interfaceID
+			r.Delete("/interfaces/{interfaceID}", api.DetachNetworkInterface)
+			// POST /servers/:id/interfaces/:interfaceID/ips
+			r.Post("/interfaces/{interfaceID}/ips", api.AssignSecondaryIP)
 		})
 	})
+	// GET /invoices
+	route.Route("/invoices", func(r chi.Router) {
+		r.Get("/", api.ListInvoices)
+		// GET /invoices/:id
+		r.Get("/{invoiceID}", api.GetInvoice)
+	})
+	// GET /pricing
+	route.Route("/pricing", func(r chi.Router) {
+		r.Get("/", api.ListPrices)
+		// POST /pricing/quote
+		r.Post("/quote", api.QuotePrice)
+		// GET /pricing/spot
+		r.Get("/spot", api.ListSpotPrices)
+	})
+	// GET /budgets
+	route.Route("/budgets", func(r chi.Router) {
+		r.Get("/", api.ListBudgets)
+		// POST /budgets
+		r.Post("/", api.CreateBudget)
+		r.Route("/{budgetID}", func(r chi.Router) {
+			// GET /budgets/:id
+			r.Get("/", api.GetBudget)
+			// DELETE /budgets/:id
+			r.Delete("/", api.DeleteBudget)
+			// GET /budgets/:id/alerts
+			r.Get("/alerts", api.ListBudgetAlerts)
+		})
+	})
+	// GET /reservations
+	route.Route("/reservations", func(r chi.Router) {
+		r.Get("/", api.ListReservations)
+		// POST /reservations
+		r.Post("/", api.CreateReservation)
+		// GET /reservations/:id
+		r.Get("/{reservationID}", api.GetReservation)
+	})
+	// GET /billing/forecast
+	route.Get("/billing/forecast", api.GetBillingForecast)
+	// GET /billing/export
+	route.Get("/billing/export", api.ExportCosts)
+	// GET /exchange-rates
+	route.Get("/exchange-rates", api.ListExchangeRates)
+	// GET /volume-tiers
+	route.Get("/volume-tiers", api.ListVolumeTiers)
+	// GET /discounts
+	route.Get("/discounts", api.ListDiscounts)
+	// GET /credits
+	route.Get("/credits", api.ListCredits)
+	route.Route("/projects/{project}", func(r chi.Router) {
+		// GET /projects/:project
+		r.Get("/", api.GetProject)
+		// PUT /projects/:project
+		r.Put("/", api.UpdateProject)
+	})
+	route.Route("/accounts/{project}", func(r chi.Router) {
+		// GET /accounts/:project
+		r.Get("/", api.GetAccount)
+		// POST /accounts/:project/topup
+		r.Post("/topup", api.TopUpAccount)
+		// GET /accounts/:project/events
+		r.Get("/events", api.ListAccountEvents)
+	})
+	// GET /recommendations
+	route.Route("/recommendations", func(r chi.Router) {
+		r.Get("/", api.ListRecommendations)
+		// POST /recommendations/apply
+		r.Post("/apply", api.ApplyRecommendation)
+	})
+	route.Route("/reaper", func(r chi.Router) {
+		// GET /reaper/policies
+		r.Get("/policies", api.ListReaperPolicies)
+		// GET /reaper/dry-run
+		r.Get("/dry-run", api.DryRunReaper)
+		// GET /reaper/actions
+		r.Get("/actions", api.ListReaperActions)
+	})
+	route.Route("/admin", func(r chi.Router) {
+		// POST /admin/pricing
+		r.Post("/pricing", api.CreatePrice)
+		// POST /admin/pricing/spot
+		r.Post("/pricing/spot", api.SetSpotPrice)
+		// POST /admin/exchange-rates
+		r.Post("/exchange-rates", api.CreateExchangeRate)
+		// POST /admin/volume-tiers
+		r.Post("/volume-tiers", api.CreateVolumeTier)
+		// POST /admin/discounts
+		r.Post("/discounts", api.CreateDiscount)
+		// POST /admin/credits
+		r.Post("/credits", api.CreateCredit)
+		// POST /admin/reaper/policies
+		r.Post("/reaper/policies", api.CreateReaperPolicy)
+		// DELETE /admin/reaper/policies/:id
+		r.Delete("/reaper/policies/{policyID}", api.DeleteReaperPolicy)
+		// GET /admin/consistency
+		r.Get("/consistency", api.GetConsistencyReport)
+	})
+	// GET /nat-mappings
+	route.Get("/nat-mappings", api.ListNATMappings)
 	// Swagger UI
 	route.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+)
+
+// testDBClient connects to the database of the repository's .env, skipping the
+// test when there is none.
+func testDBClient(t *testing.T) (*config.Config, *database.DBClient) {
+	t.Helper()
+	t.Chdir("../..")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.DBUser,
+		cfg.DBPassword,
+		cfg.DBHost,
+		cfg.DBPort,
+		cfg.DBName,
+		cfg.DBSSLMode,
+	)
+	dbClient, err := database.NewDBClient(context.Background(), databaseURL, 1, time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Skipf("no database: %v", err)
+	}
+	t.Cleanup(dbClient.Close)
+	return cfg, dbClient
+}
+
+// TestRequestEventActor sends a request through the router and checks that the
+// server event it records is attributed to the API and to the request's ID. It
+// runs against the database of the repository's .env and is skipped when there
+// is none.
+func TestRequestEventActor(t *testing.T) {
+	cfg, dbClient := testDBClient(t)
+	ctx := context.Background()
+	logger := zap.NewNop()
+
+	name := "event-actor-" + uuid.NewString()[:8]
+	var serverID pgtype.UUID
+	err := dbClient.Pool.QueryRow(ctx, `
+		INSERT INTO servers (name, hostname, region, project, status, type, hourly_cost)
+		VALUES ($1, $1 || '.test.invalid', 'us-east-1', $1, 'stopped', 't2.micro', 0.0116)
+		RETURNING id`, name).Scan(&serverID)
+	if err != nil {
+		t.Fatalf("failed to seed server: %v", err)
+	}
+	defer func() {
+		if _, err := dbClient.Pool.Exec(ctx, `DELETE FROM servers WHERE id = $1`, serverID); err != nil {
+			t.Errorf("failed to delete server: %v", err)
+		}
+	}()
+
+	serverService := services.NewServerService(dbClient, services.NewIPAllocator(dbClient.Queries, logger), logger, cfg)
+	api := NewServerAPI(cfg, dbClient, serverService, nil, nil, nil, nil, nil, nil, nil, nil, nil, logger)
+
+	requestID := "req-" + name
+	req := httptest.NewRequest(http.MethodPatch, "/servers/"+serverID.String(), strings.NewReader(`{"name":"`+name+`-renamed"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-Id", requestID)
+	rec := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rename returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	events, err := dbClient.Queries.ListServerEvents(ctx, sqlc.ListServerEventsParams{
+		ServerID: serverID,
+		Types:    []string{services.EventRenamed},
+		RowLimit: 1,
+	})
+	if err != nil {
+		t.Fatalf("failed to list server events: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("got %d rename events, want 1", len(events))
+	}
+	if events[0].Actor != services.ActorAPI {
+		t.Errorf("actor = %q, want %q", events[0].Actor, services.ActorAPI)
+	}
+	if events[0].RequestID.String != requestID {
+		t.Errorf("request_id = %q, want %q", events[0].RequestID.String, requestID)
+	}
+}
+
+// TestReadyzLeadership checks that /readyz reports which jobs this replica leads
+// only when leader election is enabled. It runs against the database of the
+// repository's .env and is skipped when there is none.
+func TestReadyzLeadership(t *testing.T) {
+	cfg, dbClient := testDBClient(t)
+
+	for _, enabled := range []bool{false, true} {
+		t.Run(fmt.Sprintf("election enabled %v", enabled), func(t *testing.T) {
+			electionCfg := *cfg
+			electionCfg.LeaderElection = enabled
+			elector := services.NewLeaderElector(dbClient, zap.NewNop(), &electionCfg)
+			elector.Register("readyz-test", func(ctx context.Context) { <-ctx.Done() })
+			api := NewServerAPI(&electionCfg, dbClient, nil, nil, nil, nil, nil, nil, nil, nil, nil, elector, zap.NewNop())
+
+			rec := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("/readyz returned %d: %s", rec.Code, rec.Body.String())
+			}
+			var response models.ReadyzResponse
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode /readyz: %v", err)
+			}
+			switch {
+			case !enabled && response.Leader != nil:
+				t.Errorf("leader = %v with election disabled, want none", response.Leader)
+			case enabled && (len(response.Leader) != 1 || response.Leader["readyz-test"]):
+				t.Errorf("leader = %v before campaigning, want readyz-test not led", response.Leader)
+			}
+		})
+	}
+}
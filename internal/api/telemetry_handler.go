@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/models"
+	"go-virtual-server/internal/services"
+	"go-virtual-server/internal/util"
+)
+
+// RecordServerTelemetry godoc
+// @Summary Report a server's utilization
+// @Description Records a utilization sample of a running server, doubling as its heartbeat: CPU load in percent and the bytes received and sent since the previous sample. Samples are folded into one point per minute. With TELEMETRY_GENERATOR_ENABLED the simulator also reports synthesized samples for every running server.
+// @Tags telemetry
+// @Accept json
+// @Param serverID path string true "ID of the server"
+// @Param sample body models.RecordTelemetryRequest true "Sample"
+// @Success 204
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 409 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/telemetry [post]
+func (api *ServerAPI) RecordServerTelemetry(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering RecordServerTelemetry handler")
+
+	var req models.RecordTelemetryRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.logger.Error("Invalid request payload", zap.Error(err))
+		util.RespondWithError(w, http.StatusBadRequest, "Invalid request payload")
+		return
+	}
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+
+	sample := services.TelemetrySample{
+		CPUPercent:     req.CPUPercent,
+		NetworkRxBytes: req.NetworkRxBytes,
+		NetworkTxBytes: req.NetworkTxBytes,
+	}
+	if req.SampledAt != nil {
+		sample.SampledAt = *req.SampledAt
+	}
+	err := api.telemetry.Record(r.Context(), server, sample, time.Now())
+	switch {
+	case errors.Is(err, services.ErrInvalidTelemetry):
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	case errors.Is(err, services.ErrServerNotRunning):
+		util.RespondWithError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		api.logger.Error("Failed to record telemetry", zap.String("serverID", server.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to record telemetry")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	api.logger.Info("Exiting RecordServerTelemetry handler")
+}
+
+// GetServerTelemetry godoc
+// @Summary Retrieve a server's utilization over time
+// @Description Returns a server's CPU load (average and peak) and network traffic over [from, to) in steps of step, one point per step with samples. Telemetry is kept for TELEMETRY_RETENTION at one-minute resolution.
+// @Tags telemetry
+// @Produce json
+// @Param serverID path string true "ID of the server"
+// @Param from query string false "Start of the range, an RFC 3339 timestamp; defaults to an hour before to" example:"2023-10-27T09:00:00Z"
+// @Param to query string false "End of the range (exclusive), an RFC 3339 timestamp; defaults to now" example:"2023-10-27T10:00:00Z"
+// @Param step query string false "Width of a point, a whole number of minutes such as 1m, 15m or 1h (default 1m); at most 1440 points" example:"5m"
+// @Success 200 {object} models.TelemetrySeriesResponse
+// @Failure 400 {object} util.ErrorResponse
+// @Failure 404 {object} util.ErrorResponse
+// @Failure 500 {object} util.ErrorResponse
+// @Router /servers/{serverID}/telemetry [get]
+func (api *ServerAPI) GetServerTelemetry(w http.ResponseWriter, r *http.Request) {
+	api.logger.Info("Entering GetServerTelemetry handler")
+
+	query := r.URL.Query()
+	to := time.Now()
+	if v := query.Get("to"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "to must be an RFC 3339 timestamp")
+			return
+		}
+		to = parsed
+	}
+	from := to.Add(-time.Hour)
+	if v := query.Get("from"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "from must be an RFC 3339 timestamp")
+			return
+		}
+		from = parsed
+	}
+	step := time.Minute
+	if v := query.Get("step"); v != "" {
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			util.RespondWithError(w, http.StatusBadRequest, "step must be a duration such as 5m")
+			return
+		}
+		step = parsed
+	}
+
+	server, ok := api.loadServer(w, r)
+	if !ok {
+		return
+	}
+	series, err := api.telemetry.Series(r.Context(), server.ID, from, to, step)
+	if errors.Is(err, services.ErrInvalidTelemetryQuery) {
+		util.RespondWithError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.logger.Error("Failed to query telemetry", zap.String("serverID", server.ID.String()), zap.Error(err))
+		util.RespondWithError(w, http.StatusInternalServerError, "Failed to query telemetry")
+		return
+	}
+
+	response := models.TelemetrySeriesResponse{
+		ServerID: server.ID.String(),
+		From:     from,
+		To:       to,
+		Step:     step.String(),
+		Points:   make([]models.TelemetryPointResponse, 0, len(series)),
+	}
+	for _, point := range series {
+		response.Points = append(response.Points, models.ToTelemetryPointResponse(point))
+	}
+	util.RespondWithJSON(w, http.StatusOK, response)
+
+	api.logger.Info("Exiting GetServerTelemetry handler")
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,27 +12,131 @@ import (
 )
 
 // ServerPricingMap to store the price details for each type of servers.
+// It only seeds the price catalog; see services.BillingService.SeedPrices.
 type ServerPricingMap map[string]float64
 
+// IPPoolSpec describes an additional named IP pool.
+type IPPoolSpec struct {
+	Name     string
+	CIDR     string
+	Strategy string
+}
+
+// IPPoolSpecs holds the IP_POOLS entries, comma-separated name:cidr[:strategy] triples.
+type IPPoolSpecs []IPPoolSpec
+
+// Decode implements envconfig.Decoder.
+func (specs *IPPoolSpecs) Decode(value string) error {
+	*specs = nil
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+			return fmt.Errorf("invalid IP pool %q: expected name:cidr[:strategy]", entry)
+		}
+		spec := IPPoolSpec{Name: parts[0], CIDR: parts[1]}
+		if len(parts) == 3 {
+			spec.Strategy = parts[2]
+		}
+		*specs = append(*specs, spec)
+	}
+	return nil
+}
+
+// StateTimeouts holds the STUCK_STATE_TIMEOUTS entries, comma-separated
+// status:duration pairs.
+type StateTimeouts map[string]time.Duration
+
+// Decode implements envconfig.Decoder.
+func (timeouts *StateTimeouts) Decode(value string) error {
+	*timeouts = StateTimeouts{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		status, duration, ok := strings.Cut(entry, ":")
+		if !ok || status == "" {
+			return fmt.Errorf("invalid state timeout %q: expected status:duration", entry)
+		}
+		timeout, err := time.ParseDuration(duration)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid state timeout %q: expected a positive duration", entry)
+		}
+		(*timeouts)[status] = timeout
+	}
+	return nil
+}
+
 // Config holds the application configuration.
 type Config struct {
-	HTTP_IP               string           `envconfig:"HTTP_IP" default:"0.0.0.0"`
-	HTTPPort              int              `envconfig:"HTTP_PORT" default:"8080"`
-	DBHost                string           `envconfig:"DB_HOST" default:"127.0.0.1"`
-	DBPort                int              `envconfig:"DB_PORT" default:"5432"`
-	DBUser                string           `envconfig:"DB_USER" default:"postgres"`
-	DBPassword            string           `envconfig:"DB_PASSWORD" default:"mysecretpassword"`
-	DBName                string           `envconfig:"DB_NAME" default:"postgres"`
-	DBSSLMode             string           `envconfig:"DB_SSLMODE" default:"disable"`
-	IPAllocationCIDR      string           `envconfig:"IP_ALLOCATION_CIDR" default:"192.168.0.0/24"`
-	IPExclusionList       []string         `envconfig:"IP_EXCLUSION_LIST" default:""`
-	LogLevel              string           `envconfig:"LOG_LEVEL" default:"info"`
-	Environment           string           `envconfig:"ENVIRONMENT" default:"development"`
-	LogFileCapacityInMB   int              `envconfig:"LOG_FILE_CAPACITY_IN_MB" default:"10"`
-	DBMaxRetries          int              `envconfig:"DB_MAX_RETRIES" default:"10s"`
-	DBRetryDelay          time.Duration    `envconfig:"DB_RETRY_DELAY" default:"5s"`
-	BillingDaemonInterval time.Duration    `envconfig:"BILLING_DAEMON_INTERVAL" default:"1m"`
-	ServerTypeWisePricing ServerPricingMap `envconfig:"SERVER_TYPE_WISE_PRICING" default:"micro:0.01,small:0.05,medium:0.10,large:0.20,xlarge:0.40"`
+	HTTP_IP               string            `envconfig:"HTTP_IP" default:"0.0.0.0"`
+	HTTPPort              int               `envconfig:"HTTP_PORT" default:"8080"`
+	DBHost                string            `envconfig:"DB_HOST" default:"127.0.0.1"`
+	DBPort                int               `envconfig:"DB_PORT" default:"5432"`
+	DBUser                string            `envconfig:"DB_USER" default:"postgres"`
+	DBPassword            string            `envconfig:"DB_PASSWORD" default:"mysecretpassword"`
+	DBName                string            `envconfig:"DB_NAME" default:"postgres"`
+	DBSSLMode             string            `envconfig:"DB_SSLMODE" default:"disable"`
+	IPAllocationCIDR      string            `envconfig:"IP_ALLOCATION_CIDR" default:"192.168.0.0/24"`
+	IPExclusionList       []string          `envconfig:"IP_EXCLUSION_LIST" default:""`
+	IPAllocationStrategy  string            `envconfig:"IP_ALLOCATION_STRATEGY" default:"sequential"`
+	IPPools               IPPoolSpecs       `envconfig:"IP_POOLS" default:""`
+	PrivateIPPools        map[string]string `envconfig:"PRIVATE_IP_POOLS" default:"us-east-1:10.1.0.0/16,eu-west-1:10.2.0.0/16"`
+	PrivateIPExclusions   []string          `envconfig:"PRIVATE_IP_EXCLUSION_LIST" default:"10.1.0.1,10.2.0.1"`
+	PublicIPCIDR          string            `envconfig:"PUBLIC_IP_CIDR" default:"203.0.113.0/24"`
+	PublicIPStrategy      string            `envconfig:"PUBLIC_IP_STRATEGY" default:"random"`
+	ReleasePublicIPOnStop bool              `envconfig:"RELEASE_PUBLIC_IP_ON_STOP" default:"true"`
+	DNSZone               string            `envconfig:"DNS_ZONE" default:"vs.internal"`
+	DNSEnabled            bool              `envconfig:"DNS_ENABLED" default:"false"`
+	DNSListenAddr         string            `envconfig:"DNS_LISTEN_ADDR" default:"127.0.0.1:5353"`
+	DNSTTL                time.Duration     `envconfig:"DNS_TTL" default:"30s"`
+	MetadataEnabled       bool              `envconfig:"METADATA_ENABLED" default:"false"`
+	MetadataListenAddr    string            `envconfig:"METADATA_LISTEN_ADDR" default:"127.0.0.1:8169"`
+	LogLevel              string            `envconfig:"LOG_LEVEL" default:"info"`
+	Environment           string            `envconfig:"ENVIRONMENT" default:"development"`
+	LogFileCapacityInMB   int               `envconfig:"LOG_FILE_CAPACITY_IN_MB" default:"10"`
+	DBMaxRetries          int               `envconfig:"DB_MAX_RETRIES" default:"10s"`
+	DBRetryDelay          time.Duration     `envconfig:"DB_RETRY_DELAY" default:"5s"`
+	BillingDaemonInterval time.Duration     `envconfig:"BILLING_DAEMON_INTERVAL" default:"1m"`
+	LeaderElection        bool              `envconfig:"LEADER_ELECTION_ENABLED" default:"true"`
+	LeaderRenewInterval   time.Duration     `envconfig:"LEADER_RENEW_INTERVAL" default:"5s"`
+	LeaderLeaseTimeout    time.Duration     `envconfig:"LEADER_LEASE_TIMEOUT" default:"15s"`
+	ForecastLookback      time.Duration     `envconfig:"FORECAST_LOOKBACK" default:"168h"`
+	BudgetWebhookTimeout  time.Duration     `envconfig:"BUDGET_WEBHOOK_TIMEOUT" default:"5s"`
+	LowBalanceThreshold   float64           `envconfig:"LOW_BALANCE_THRESHOLD" default:"10"`
+	ReaperDryRun          bool              `envconfig:"REAPER_DRY_RUN" default:"false"`
+	ReaperExemptTag       string            `envconfig:"REAPER_EXEMPT_TAG" default:"reaper-exempt"`
+	ReaperWebhookTimeout  time.Duration     `envconfig:"REAPER_WEBHOOK_TIMEOUT" default:"5s"`
+	TelemetryGenerator    bool              `envconfig:"TELEMETRY_GENERATOR_ENABLED" default:"true"`
+	TelemetryInterval     time.Duration     `envconfig:"TELEMETRY_INTERVAL" default:"1m"`
+	TelemetryRetention    time.Duration     `envconfig:"TELEMETRY_RETENTION" default:"168h"`
+	ServerEventsRetention time.Duration     `envconfig:"SERVER_EVENTS_RETENTION" default:"720h"`
+	ConsistencyInterval   time.Duration     `envconfig:"CONSISTENCY_INTERVAL" default:"5m"`
+	ConsistencyAutoRepair bool              `envconfig:"CONSISTENCY_AUTO_REPAIR" default:"false"`
+	WatchdogInterval      time.Duration     `envconfig:"WATCHDOG_INTERVAL" default:"1m"`
+	StuckStateTimeouts    StateTimeouts     `envconfig:"STUCK_STATE_TIMEOUTS" default:"provisioning:15m"`
+	StuckStateRetries     int               `envconfig:"STUCK_STATE_RETRIES" default:"0"`
+	ReservationDiscount   float64           `envconfig:"RESERVATION_DISCOUNT" default:"0.3"`
+	ReservationTerm       time.Duration     `envconfig:"RESERVATION_TERM" default:"8760h"`
+	RightsizingLookback   time.Duration     `envconfig:"RIGHTSIZING_LOOKBACK" default:"168h"`
+	RightsizingMaxCPU     float64           `envconfig:"RIGHTSIZING_MAX_CPU_PERCENT" default:"80"`
+	ReserveMinUptime      float64           `envconfig:"RIGHTSIZING_RESERVE_MIN_UPTIME" default:"0.9"`
+	SpotMarketInterval    time.Duration     `envconfig:"SPOT_MARKET_INTERVAL" default:"1m"`
+	SpotDiscount          float64           `envconfig:"SPOT_DISCOUNT" default:"0.7"`
+	SpotVolatility        float64           `envconfig:"SPOT_VOLATILITY" default:"0.15"`
+	SpotPriceFloor        float64           `envconfig:"SPOT_PRICE_FLOOR" default:"0.1"`
+	SpotNotice            time.Duration     `envconfig:"SPOT_INTERRUPTION_NOTICE" default:"2m"`
+	DefaultDiskGB         int32             `envconfig:"DEFAULT_DISK_GB" default:"8"`
+	DiskGBHourPrice       float64           `envconfig:"DISK_GB_HOUR_PRICE" default:"0.000137"`
+	PublicIPHourPrice     float64           `envconfig:"PUBLIC_IP_HOUR_PRICE" default:"0.005"`
+	EgressGBPrice         float64           `envconfig:"EGRESS_GB_PRICE" default:"0.09"`
+	EgressGBPerHour       float64           `envconfig:"EGRESS_GB_PER_HOUR" default:"0.5"`
+	ExchangeRatesFile     string            `envconfig:"EXCHANGE_RATES_FILE" default:"exchange_rates.json"`
+	ServerTypeWisePricing ServerPricingMap  `envconfig:"SERVER_TYPE_WISE_PRICING" default:"t2.micro:0.0116,m5.large:0.096,c5.xlarge:0.17"`
 }
 
 // Load loads configuration from environment variables.
@@ -46,7 +151,7 @@ func Load() (*Config, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to load config: %w", err)
 		} else {
-			dotEnvPath := filepath.Join(homeDir, ".env") 
+			dotEnvPath := filepath.Join(homeDir, ".env")
 
 			// godotenv.Load() will load variables from the .env file into the process's environment.
 			err = godotenv.Load(dotEnvPath)
@@ -64,5 +169,19 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load config: %w", err)
 
 	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
 	return &cfg, nil
 }
+
+// validate rejects settings that are well-formed on their own but do not work together.
+func (cfg *Config) validate() error {
+	// The lease session is renewed every LEADER_RENEW_INTERVAL and Postgres ends it
+	// after LEADER_LEASE_TIMEOUT idle, so a timeout no longer than the interval
+	// would drop every lease between renewals
+	if cfg.LeaderElection && cfg.LeaderLeaseTimeout <= cfg.LeaderRenewInterval {
+		return fmt.Errorf("LEADER_LEASE_TIMEOUT (%s) must be longer than LEADER_RENEW_INTERVAL (%s)", cfg.LeaderLeaseTimeout, cfg.LeaderRenewInterval)
+	}
+	return nil
+}
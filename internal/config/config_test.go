@@ -0,0 +1,29 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		election bool
+		renew    time.Duration
+		lease    time.Duration
+		wantErr  bool
+	}{
+		{name: "lease longer than renewal", election: true, renew: 5 * time.Second, lease: 15 * time.Second},
+		{name: "lease as long as renewal", election: true, renew: 5 * time.Second, lease: 5 * time.Second, wantErr: true},
+		{name: "lease shorter than renewal", election: true, renew: 15 * time.Second, lease: 5 * time.Second, wantErr: true},
+		{name: "election disabled", election: false, renew: 15 * time.Second, lease: 5 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Config{LeaderElection: tt.election, LeaderRenewInterval: tt.renew, LeaderLeaseTimeout: tt.lease}
+			if err := cfg.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v; want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"go-virtual-server/internal/database/sqlc"
 	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool" 
 	"go.uber.org/zap"
 )
@@ -14,6 +15,8 @@ import (
 type DBClient struct {
 	Pool    *pgxpool.Pool
 	Queries *sqlc.Queries
+	// tx is set on clients that run everything in one transaction, see NewTxClient.
+	tx pgx.Tx
 }
 
 // NewDBClient initializes a new database client with connection retry logic.
@@ -45,9 +48,41 @@ func NewDBClient(ctx context.Context, databaseURL string, maxRetries int, retryD
 	return nil, fmt.Errorf("failed to connect to database after %d retries: %w", maxRetries, err)
 }
 
+// NewTxClient returns a client that runs everything in tx: its queries use tx and
+// WithTx opens savepoints in it, so rolling tx back undoes all that was done
+// through the client. It has no pool.
+func NewTxClient(tx pgx.Tx) *DBClient {
+	return &DBClient{
+		Queries: sqlc.New(tx),
+		tx:      tx,
+	}
+}
+
 // Close closes the database connection pool.
 func (db *DBClient) Close() {
 	if db.Pool != nil {
 		db.Pool.Close()
 	}
 }
+
+// WithTx runs fn with queries bound to a new transaction, committing if fn
+// returns nil and rolling back otherwise.
+func (db *DBClient) WithTx(ctx context.Context, fn func(*sqlc.Queries) error) error {
+	begin := db.Pool.Begin
+	if db.tx != nil {
+		begin = db.tx.Begin
+	}
+	tx, err := begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(db.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
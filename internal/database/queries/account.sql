@@ -0,0 +1,71 @@
+-- sql/account.sql
+
+-- name: GetAccount :one
+SELECT * FROM accounts WHERE project = $1;
+
+-- name: ListAccounts :many
+SELECT * FROM accounts
+ORDER BY project;
+
+-- name: TopUpAccount :one
+-- Adds to the balance of a project, opening its account on the first top-up with
+-- the charges of the project so far as already drawn. The low-balance warning is
+-- rearmed once the balance is above the threshold again.
+INSERT INTO accounts (project, balance, low_balance_threshold, charged)
+VALUES ($1, @amount::DOUBLE PRECISION, @low_balance_threshold::DOUBLE PRECISION, @charged::DOUBLE PRECISION)
+ON CONFLICT (project) DO UPDATE SET
+    balance = accounts.balance + EXCLUDED.balance,
+    low_balance_threshold = EXCLUDED.low_balance_threshold,
+    low_balance_warned = accounts.low_balance_warned
+        AND accounts.balance + EXCLUDED.balance <= EXCLUDED.low_balance_threshold,
+    updated_at = NOW()
+RETURNING *;
+
+-- name: DrawDownAccount :one
+-- Draws the difference between the charges of the project now and what was drawn
+-- before; a negative difference, e.g. from a discount, is paid back.
+UPDATE accounts
+SET balance = balance + charged - @total_charged::DOUBLE PRECISION,
+    charged = @total_charged::DOUBLE PRECISION,
+    drawn_until = @drawn_until::timestamptz,
+    updated_at = NOW()
+WHERE project = $1
+RETURNING *;
+
+-- name: SetAccountLowBalanceWarned :exec
+UPDATE accounts SET low_balance_warned = TRUE, updated_at = NOW() WHERE project = $1;
+
+-- name: SuspendAccount :exec
+UPDATE accounts SET suspended_at = NOW(), updated_at = NOW() WHERE project = $1;
+
+-- name: ResumeAccount :exec
+UPDATE accounts SET suspended_at = NULL, updated_at = NOW() WHERE project = $1;
+
+-- name: CreateAccountEvent :one
+INSERT INTO account_events (project, type, amount, balance)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: ListAccountEvents :many
+SELECT * FROM account_events
+WHERE project = $1
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: SumProjectLedger :one
+-- Everything written to the ledger for a project, adjustments included.
+SELECT COALESCE(SUM(amount), 0)::DOUBLE PRECISION AS amount
+FROM ledger_entries
+WHERE project = $1;
+
+-- name: ListUnbilledServerIDsByProject :many
+-- Servers of a project that may have usage not in the ledger yet: the live ones,
+-- and those terminated since the given instant.
+SELECT id FROM servers
+WHERE project = $1
+  AND (status <> 'terminated' OR last_status_update >= @since::timestamptz);
+
+-- name: ListServersByProjectAndStatus :many
+SELECT * FROM servers
+WHERE project = $1 AND status = $2
+ORDER BY created_at;
@@ -0,0 +1,64 @@
+-- sql/adjustment.sql
+
+-- name: CreateVolumeTier :one
+INSERT INTO volume_tiers (server_type, from_hours, percent_off)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: ListVolumeTiers :many
+SELECT * FROM volume_tiers
+ORDER BY server_type, from_hours;
+
+-- name: CreateDiscount :one
+INSERT INTO discounts (name, server_type, region, percent_off, starts_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListDiscounts :many
+SELECT * FROM discounts
+ORDER BY created_at DESC;
+
+-- name: ListDiscountsInPeriod :many
+SELECT * FROM discounts
+WHERE starts_at < @period_end::timestamptz
+  AND (expires_at IS NULL OR expires_at > @period_start::timestamptz)
+ORDER BY percent_off DESC, created_at;
+
+-- name: CreateCredit :one
+INSERT INTO credits (project, name, scope_type, scope_value, amount, remaining, currency, starts_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: ListCredits :many
+SELECT * FROM credits
+WHERE (sqlc.narg(project)::VARCHAR IS NULL OR project = sqlc.narg(project))
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: ListCreditsInPeriodForUpdate :many
+-- Credits of a project with an amount left that overlap the period, soonest to
+-- expire first. Rows are locked so two invoices cannot spend the same credit.
+SELECT * FROM credits
+WHERE project = $1 AND remaining > 0
+  AND starts_at < @period_end::timestamptz AND expires_at > @period_start::timestamptz
+ORDER BY expires_at, created_at
+FOR UPDATE;
+
+-- name: DrawDownCredit :exec
+UPDATE credits
+SET remaining = GREATEST(0, remaining - @amount::DOUBLE PRECISION)
+WHERE id = $1;
+
+-- name: SummarizeLedgerCharges :many
+-- Charges of one project period per charge type, server type and region, the
+-- base the adjustments are computed on.
+SELECT
+    charge_type,
+    server_type,
+    region,
+    SUM(quantity)::DOUBLE PRECISION AS quantity,
+    SUM(amount)::DOUBLE PRECISION AS amount
+FROM ledger_entries
+WHERE project = $1 AND period_start = $2
+GROUP BY charge_type, server_type, region
+ORDER BY charge_type, server_type, region;
@@ -0,0 +1,51 @@
+-- sql/budget.sql
+
+-- name: CreateBudget :one
+INSERT INTO budgets (name, scope_type, scope_key, scope_value, amount, currency, period, thresholds, webhook_url, enforce)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetBudget :one
+SELECT * FROM budgets WHERE id = $1;
+
+-- name: ListBudgets :many
+SELECT * FROM budgets
+ORDER BY created_at;
+
+-- name: DeleteBudget :execrows
+DELETE FROM budgets WHERE id = $1;
+
+-- name: CreateBudgetAlert :one
+-- Records a threshold crossing; no row is returned if it was already recorded this period.
+INSERT INTO budget_alerts (budget_id, period_start, threshold, spend, amount)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (budget_id, period_start, threshold) DO NOTHING
+RETURNING *;
+
+-- name: SetBudgetAlertWebhookStatus :exec
+UPDATE budget_alerts
+SET webhook_status = $1
+WHERE id = $2;
+
+-- name: ListBudgetAlerts :many
+SELECT * FROM budget_alerts
+WHERE budget_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ListRunningServersInScope :many
+SELECT * FROM servers
+WHERE status = 'running'
+  AND (sqlc.narg('project')::varchar IS NULL OR project = sqlc.narg('project')::varchar)
+  AND (sqlc.narg('region')::varchar IS NULL OR region = sqlc.narg('region')::varchar)
+  AND (sqlc.narg('tag_key')::text IS NULL OR tags ->> sqlc.narg('tag_key')::text = sqlc.narg('tag_value')::text);
+
+-- name: ListServersInScope :many
+-- Servers that may have usage after @since, the live ones and those terminated
+-- since, optionally only of one project, region or tag value.
+SELECT * FROM servers
+WHERE (status <> 'terminated' OR last_status_update >= @since::timestamptz)
+  AND (sqlc.narg('project')::varchar IS NULL OR project = sqlc.narg('project')::varchar)
+  AND (sqlc.narg('region')::varchar IS NULL OR region = sqlc.narg('region')::varchar)
+  AND (sqlc.narg('tag_key')::text IS NULL OR tags ->> sqlc.narg('tag_key')::text = sqlc.narg('tag_value')::text)
+ORDER BY created_at;
@@ -0,0 +1,52 @@
+-- sql/consistency.sql
+
+-- name: ListAddressMismatches :many
+-- Live servers whose address is not the primary address of their primary interface,
+-- including servers left without one. Servers created after settled_before are
+-- skipped, as they may still be provisioning.
+SELECT s.id AS server_id, s.region, s.status, s.address, ni.id AS interface_id, a.id AS ip_id, a.address AS ip_address
+FROM servers s
+LEFT JOIN network_interfaces ni ON ni.server_id = s.id AND ni.is_primary
+LEFT JOIN ip_addresses a ON a.interface_id = ni.id AND a.is_primary
+WHERE s.status <> 'terminated'
+  AND s.created_at < @settled_before::timestamptz
+  AND a.address IS DISTINCT FROM s.address
+ORDER BY s.created_at;
+
+-- name: ListIPAddressesOfTerminatedServers :many
+SELECT a.*
+FROM ip_addresses a
+JOIN servers s ON s.id = a.server_id
+WHERE s.status = 'terminated'
+ORDER BY a.updated_at;
+
+-- name: ListActiveNATMappingsOfTerminatedServers :many
+SELECT m.*
+FROM nat_mappings m
+JOIN servers s ON s.id = m.server_id
+WHERE s.status = 'terminated' AND m.released_at IS NULL
+ORDER BY m.created_at;
+
+-- name: ListOrphanedIPAddresses :many
+-- Addresses reserved for a server that was never bound to one.
+SELECT * FROM ip_addresses
+WHERE is_allocated AND server_id IS NULL AND updated_at < @settled_before::timestamptz
+ORDER BY updated_at;
+
+-- name: ListStuckProvisioningServers :many
+-- Servers provisioning since before stuck_before that never got their primary
+-- address, i.e. whose provisioning did not complete.
+SELECT s.* FROM servers s
+WHERE s.status = 'provisioning' AND s.last_status_update < @stuck_before::timestamptz
+  AND NOT EXISTS (
+        SELECT 1 FROM network_interfaces ni
+        JOIN ip_addresses ia ON ia.interface_id = ni.id
+        WHERE ni.server_id = s.id AND ni.is_primary AND ia.is_primary AND ia.is_allocated
+      )
+ORDER BY s.last_status_update;
+
+-- name: SetServerAddress :one
+UPDATE servers
+SET address = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
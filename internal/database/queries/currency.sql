@@ -0,0 +1,25 @@
+-- sql/currency.sql
+
+-- name: ListExchangeRates :many
+SELECT * FROM exchange_rates
+ORDER BY currency, effective_from;
+
+-- name: CreateExchangeRate :one
+INSERT INTO exchange_rates (currency, rate, effective_from)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: SeedExchangeRate :exec
+-- Seeds a rate from the exchange rate file, unless that version already exists.
+INSERT INTO exchange_rates (currency, rate, effective_from)
+VALUES ($1, $2, $3)
+ON CONFLICT (currency, effective_from) DO NOTHING;
+
+-- name: GetProject :one
+SELECT * FROM projects WHERE name = $1;
+
+-- name: SetProjectCurrency :one
+INSERT INTO projects (name, currency)
+VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET currency = EXCLUDED.currency, updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,28 @@
+-- sql/export.sql
+
+-- name: ListUsageSegmentsInRange :many
+-- Segments overlapping [range_start, range_end), optionally only of one project.
+SELECT us.*, s.name AS server_name, s.project, s.region, s.billing_model, s.purchase_option, s.tags
+FROM usage_segments us
+JOIN servers s ON s.id = us.server_id
+WHERE us.started_at < @range_end::timestamptz
+  AND (us.ended_at IS NULL OR us.ended_at > @range_start::timestamptz)
+  AND (sqlc.narg('project')::varchar IS NULL OR s.project = sqlc.narg('project')::varchar)
+ORDER BY us.server_id, us.started_at;
+
+-- name: ListResourceSegmentsInRange :many
+SELECT rs.*, s.name AS server_name, s.project, s.region, s.type AS server_type, s.tags
+FROM resource_segments rs
+JOIN servers s ON s.id = rs.server_id
+WHERE rs.started_at < @range_end::timestamptz
+  AND (rs.ended_at IS NULL OR rs.ended_at > @range_start::timestamptz)
+  AND (sqlc.narg('project')::varchar IS NULL OR s.project = sqlc.narg('project')::varchar)
+ORDER BY rs.server_id, rs.resource, rs.started_at;
+
+-- name: ListEgressInRange :many
+SELECT e.server_id, e.hour, e.gb, s.name AS server_name, s.project, s.region, s.type AS server_type, s.tags
+FROM egress_usage e
+JOIN servers s ON s.id = e.server_id
+WHERE e.hour >= @range_start::timestamptz AND e.hour < @range_end::timestamptz
+  AND (sqlc.narg('project')::varchar IS NULL OR s.project = sqlc.narg('project')::varchar)
+ORDER BY e.server_id, e.hour;
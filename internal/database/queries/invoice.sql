@@ -0,0 +1,58 @@
+-- sql/invoices.sql
+
+-- name: ListPeriodsToClose :many
+-- Project periods that ended before @before and have ledger entries but no invoice yet.
+SELECT DISTINCT le.project, le.period_start
+FROM ledger_entries le
+WHERE le.period_start < @before::date
+  AND NOT EXISTS (
+      SELECT 1 FROM invoices i
+      WHERE i.project = le.project AND i.period_start = le.period_start
+  )
+ORDER BY le.period_start, le.project;
+
+-- name: SummarizeLedgerPeriod :many
+-- Ledger entries of one project period grouped into invoice lines, in USD. Amounts
+-- are rounded to cents once converted to the invoice currency.
+SELECT
+    le.server_id,
+    COALESCE(s.name, '')::VARCHAR AS server_name,
+    le.charge_type,
+    le.server_type,
+    le.region,
+    SUM(le.quantity)::DOUBLE PRECISION AS quantity,
+    le.unit,
+    le.unit_price,
+    SUM(le.amount)::DOUBLE PRECISION AS amount
+FROM ledger_entries le
+LEFT JOIN servers s ON s.id = le.server_id
+WHERE le.project = $1 AND le.period_start = $2
+GROUP BY le.server_id, s.name, le.charge_type, le.server_type, le.region, le.unit, le.unit_price
+ORDER BY s.name, le.charge_type, le.unit_price;
+
+-- name: CreateInvoice :one
+INSERT INTO invoices (project, period_start, period_end, currency, exchange_rate, total)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CreateInvoiceLine :one
+INSERT INTO invoice_lines (
+    invoice_id, server_id, server_name, charge_type, server_type, region, quantity, unit, unit_price, amount
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetInvoice :one
+SELECT * FROM invoices WHERE id = $1;
+
+-- name: ListInvoiceLines :many
+SELECT * FROM invoice_lines
+WHERE invoice_id = $1
+ORDER BY server_name, charge_type, unit_price;
+
+-- name: ListInvoices :many
+SELECT * FROM invoices
+WHERE (sqlc.narg(project)::VARCHAR IS NULL OR project = sqlc.narg(project))
+  AND (sqlc.narg(period_start)::DATE IS NULL OR period_start = sqlc.narg(period_start))
+ORDER BY period_start DESC, project
+LIMIT @row_limit OFFSET @row_offset;
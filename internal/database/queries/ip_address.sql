@@ -1,31 +1,68 @@
 -- sql/ip_address.sql
 
--- name: CreateIPAddress :one
-INSERT INTO ip_addresses (address)
-VALUES ($1)
+-- name: ReserveIPAddress :one
+-- Creates the row for an address on first use, or reclaims a released one.
+-- Returns no row when the address is already held in the pool, e.g. by another replica.
+INSERT INTO ip_addresses (pool_id, address, is_allocated)
+VALUES ($1, $2, TRUE)
+ON CONFLICT (pool_id, address) DO UPDATE
+SET is_allocated = TRUE, updated_at = NOW()
+WHERE ip_addresses.is_allocated = FALSE AND ip_addresses.server_id IS NULL
 RETURNING *;
 
--- name: GetAvailableIPForAllocation :one
-SELECT * FROM ip_addresses
-WHERE is_allocated = FALSE AND server_id IS NULL
-ORDER BY created_at ASC
-FOR UPDATE SKIP LOCKED
-LIMIT 1;
-
 -- name: AllocateIPAddress :one
 UPDATE ip_addresses
-SET is_allocated = TRUE, server_id = $1, updated_at = NOW()
-WHERE id = $2 AND is_allocated = FALSE AND server_id IS NULL
+SET is_allocated = TRUE, server_id = $1, interface_id = $2, is_primary = $3, updated_at = NOW()
+WHERE id = $4 AND server_id IS NULL
 RETURNING *;
 
+-- name: ListIPAddressesByPool :many
+SELECT * FROM ip_addresses
+WHERE pool_id = $1
+ORDER BY updated_at ASC;
+
 -- name: DeallocateIPAddress :one
 UPDATE ip_addresses
-SET is_allocated = FALSE, server_id = NULL, updated_at = NOW()
+SET is_allocated = FALSE, server_id = NULL, interface_id = NULL, is_primary = FALSE, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
--- name: GetIPAddressByServerID :one
-SELECT * FROM ip_addresses WHERE server_id = $1;
+-- name: ListIPAddressesByServerID :many
+SELECT * FROM ip_addresses
+WHERE server_id = $1
+ORDER BY is_primary DESC, updated_at ASC;
+
+-- name: ListIPAddressesByInterfaceID :many
+SELECT * FROM ip_addresses
+WHERE interface_id = $1
+ORDER BY is_primary DESC, updated_at ASC;
+
+-- name: ListIPAddressesByServerIDs :many
+SELECT a.id, a.address, a.server_id, a.interface_id, a.is_primary, p.name AS pool_name
+FROM ip_addresses a
+LEFT JOIN ip_pools p ON p.id = a.pool_id
+WHERE a.server_id = ANY(@server_ids::uuid[])
+ORDER BY a.is_primary DESC, a.updated_at ASC;
+
+-- name: ReleaseAllIPAddresses :exec
+-- Returns every address to its pool. Rows are kept rather than truncated, so
+-- the NAT mappings referencing them survive as history.
+UPDATE ip_addresses
+SET is_allocated = FALSE, server_id = NULL, interface_id = NULL, is_primary = FALSE, updated_at = NOW()
+WHERE is_allocated OR server_id IS NOT NULL;
+
+-- name: UpsertIPPool :one
+INSERT INTO ip_pools (name, cidr, strategy, excluded_addresses)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (name) DO UPDATE
+SET cidr = EXCLUDED.cidr,
+    strategy = EXCLUDED.strategy,
+    excluded_addresses = EXCLUDED.excluded_addresses,
+    updated_at = NOW()
+RETURNING *;
 
--- name: TruncateIPAddresses :exec
-TRUNCATE ip_addresses RESTART IDENTITY CASCADE;
\ No newline at end of file
+-- name: ListIPPoolsInUse :many
+-- Pools holding allocated addresses.
+SELECT p.* FROM ip_pools p
+WHERE EXISTS (SELECT 1 FROM ip_addresses ia WHERE ia.pool_id = p.id AND ia.is_allocated)
+ORDER BY p.name;
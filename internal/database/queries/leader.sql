@@ -0,0 +1,15 @@
+-- sql/leader.sql
+
+-- name: TryAdvisoryLock :one
+-- Session-level locks; they are held until released or the session ends.
+SELECT pg_try_advisory_lock(@lock_key::BIGINT) AS acquired;
+
+-- name: AdvisoryLockShared :exec
+SELECT pg_advisory_lock_shared(@lock_key::BIGINT);
+
+-- name: AdvisoryUnlock :exec
+SELECT pg_advisory_unlock(@lock_key::BIGINT);
+
+-- name: SetIdleSessionTimeout :exec
+-- The server ends the session, releasing its locks, once it has been idle this long.
+SELECT set_config('idle_session_timeout', @timeout::TEXT, false);
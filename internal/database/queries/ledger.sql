@@ -0,0 +1,77 @@
+-- sql/ledger.sql
+
+-- name: ListUnbilledUsageSegments :many
+-- Segments with usage that can be written to the ledger: closed segments not
+-- billed to their end, and open segments with usage before the current period.
+SELECT us.*, s.project, s.region, s.name AS server_name, s.billing_model, s.purchase_option
+FROM usage_segments us
+JOIN servers s ON s.id = us.server_id
+WHERE (us.ended_at IS NOT NULL AND us.billed_until < us.ended_at)
+   OR (us.ended_at IS NULL AND us.billed_until < @current_period_start::timestamptz);
+
+-- name: CreateLedgerEntry :one
+INSERT INTO ledger_entries (
+    project, server_id, segment_id, period_start, charge_type, server_type, region,
+    description, usage_start, usage_end, quantity, unit, unit_price, amount, currency
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+RETURNING *;
+
+-- name: CreateLedgerEntries :exec
+-- Writes many ledger entries at once; entry i is made of element i of every array.
+INSERT INTO ledger_entries (
+    project, server_id, segment_id, period_start, charge_type, server_type, region,
+    description, usage_start, usage_end, quantity, unit, unit_price, amount, currency
+)
+SELECT
+    unnest(@projects::VARCHAR[]), unnest(@server_ids::UUID[]), unnest(@segment_ids::UUID[]),
+    unnest(@period_starts::DATE[]), unnest(@charge_types::VARCHAR[]), unnest(@server_types::VARCHAR[]),
+    unnest(@regions::VARCHAR[]), unnest(@descriptions::TEXT[]), unnest(@usage_starts::TIMESTAMPTZ[]),
+    unnest(@usage_ends::TIMESTAMPTZ[]), unnest(@quantities::DOUBLE PRECISION[]), unnest(@units::VARCHAR[]),
+    unnest(@unit_prices::DOUBLE PRECISION[]), unnest(@amounts::DOUBLE PRECISION[]), unnest(@currencies::VARCHAR[]);
+
+-- name: SetUsageSegmentsBilledUntil :exec
+UPDATE usage_segments us
+SET billed_until = b.billed_until
+FROM (SELECT unnest(@ids::UUID[]) AS segment_id, unnest(@billed_untils::TIMESTAMPTZ[]) AS billed_until) b
+WHERE us.id = b.segment_id;
+
+-- name: SumLedgerChargesByServerIDs :many
+SELECT
+    server_id,
+    charge_type,
+    unit,
+    SUM(quantity)::DOUBLE PRECISION AS quantity,
+    SUM(amount)::DOUBLE PRECISION AS amount
+FROM ledger_entries
+WHERE server_id = ANY(@server_ids::uuid[])
+GROUP BY server_id, charge_type, unit;
+
+-- name: SumLedgerChargesByServerIDsSince :many
+-- Like SumLedgerChargesByServerIDs, but of the usage after @since only: an entry
+-- spanning it counts for the share of its usage time after it.
+SELECT
+    server_id,
+    charge_type,
+    unit,
+    SUM(quantity * share)::DOUBLE PRECISION AS quantity,
+    SUM(amount * share)::DOUBLE PRECISION AS amount
+FROM (
+    SELECT server_id, charge_type, unit, quantity, amount,
+        CASE WHEN usage_start >= @since::timestamptz THEN 1
+            ELSE EXTRACT(EPOCH FROM usage_end - @since::timestamptz) / EXTRACT(EPOCH FROM usage_end - usage_start)
+        END::DOUBLE PRECISION AS share
+    FROM ledger_entries
+    WHERE server_id = ANY(@server_ids::uuid[]) AND usage_end > @since::timestamptz
+) entries
+GROUP BY server_id, charge_type, unit;
+
+-- name: ListLedgerEntriesByServerID :many
+SELECT * FROM ledger_entries
+WHERE server_id = $1
+ORDER BY usage_start;
+
+-- name: ListInvoicedPeriods :many
+-- Project periods from since on that are already invoiced.
+SELECT project, period_start FROM invoices
+WHERE period_start >= @since::date;
@@ -0,0 +1,37 @@
+-- sql/nat_mapping.sql
+
+-- name: CreateNATMapping :one
+INSERT INTO nat_mappings (server_id, public_ip_id, private_ip_id, public_address, private_address)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: GetActiveNATMappingByServerID :one
+SELECT * FROM nat_mappings
+WHERE server_id = $1 AND released_at IS NULL;
+
+-- name: ReleaseNATMapping :exec
+UPDATE nat_mappings
+SET released_at = NOW()
+WHERE id = $1 AND released_at IS NULL;
+
+-- name: ReleaseNATMappingsByServerID :exec
+UPDATE nat_mappings
+SET released_at = NOW()
+WHERE server_id = $1 AND released_at IS NULL;
+
+-- name: ReleaseAllNATMappings :exec
+UPDATE nat_mappings
+SET released_at = NOW()
+WHERE released_at IS NULL;
+
+-- name: ListActiveNATMappingsByServerIDs :many
+SELECT * FROM nat_mappings
+WHERE server_id = ANY(@server_ids::uuid[]) AND released_at IS NULL;
+
+-- name: ListNATMappings :many
+SELECT * FROM nat_mappings
+WHERE (sqlc.narg(server_id)::uuid IS NULL OR server_id = sqlc.narg(server_id))
+  AND (sqlc.narg(address)::text IS NULL OR public_address = sqlc.narg(address) OR private_address = sqlc.narg(address))
+  AND (NOT @active_only::boolean OR released_at IS NULL)
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
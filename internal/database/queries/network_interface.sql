@@ -0,0 +1,23 @@
+-- sql/network_interface.sql
+
+-- name: CreateNetworkInterface :one
+INSERT INTO network_interfaces (server_id, device_index, is_primary)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetNetworkInterface :one
+SELECT * FROM network_interfaces
+WHERE id = $1 AND server_id = $2;
+
+-- name: GetNextDeviceIndex :one
+SELECT COALESCE(MAX(device_index) + 1, 0)::INT AS device_index
+FROM network_interfaces
+WHERE server_id = $1;
+
+-- name: ListNetworkInterfacesByServerIDs :many
+SELECT * FROM network_interfaces
+WHERE server_id = ANY(@server_ids::uuid[])
+ORDER BY server_id, device_index;
+
+-- name: DeleteNetworkInterface :exec
+DELETE FROM network_interfaces WHERE id = $1;
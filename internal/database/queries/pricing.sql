@@ -0,0 +1,37 @@
+-- sql/pricing.sql
+
+-- name: ListPrices :many
+SELECT * FROM prices
+ORDER BY server_type, region, effective_from;
+
+-- name: CreatePrice :one
+INSERT INTO prices (server_type, region, hourly_rate, currency, effective_from)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: SeedPrice :exec
+-- Seeds the all-regions price of a type, unless the catalog already has one.
+INSERT INTO prices (server_type, region, hourly_rate, effective_from)
+SELECT @server_type::varchar, '*', @hourly_rate::double precision, 'epoch'::timestamptz
+WHERE NOT EXISTS (
+    SELECT 1 FROM prices WHERE server_type = @server_type::varchar AND region = '*'
+);
+
+-- name: RefreshServerHourlyCosts :exec
+-- servers.hourly_cost caches the price in effect now for each live on-demand
+-- server; a region's own price wins over the all-regions one. The spot market
+-- daemon keeps it current for spot servers.
+WITH current_prices AS (
+    SELECT DISTINCT ON (s.id) s.id AS server_id, p.hourly_rate
+    FROM servers s
+    JOIN prices p ON p.server_type = s.type
+        AND p.region IN (s.region, '*')
+        AND p.effective_from <= NOW()
+    WHERE s.status <> 'terminated' AND s.purchase_option = 'on_demand'
+    ORDER BY s.id, (p.region = '*'), p.effective_from DESC
+)
+UPDATE servers
+SET hourly_cost = current_prices.hourly_rate
+FROM current_prices
+WHERE servers.id = current_prices.server_id
+  AND servers.hourly_cost <> current_prices.hourly_rate;
@@ -0,0 +1,63 @@
+-- sql/reaper.sql
+
+-- name: CreateReaperPolicy :one
+INSERT INTO reaper_policies (name, match_type, match_region, match_tag_key, match_tag_value, condition, duration_seconds, max_cpu_percent, action, webhook_url)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetReaperPolicy :one
+SELECT * FROM reaper_policies WHERE id = $1;
+
+-- name: ListReaperPolicies :many
+SELECT * FROM reaper_policies
+ORDER BY created_at;
+
+-- name: DeleteReaperPolicy :execrows
+DELETE FROM reaper_policies WHERE id = $1;
+
+-- name: ListReaperCandidates :many
+-- Servers the policy applies to at the given instant, oldest status first. Servers
+-- carrying the exempt tag, and servers the policy already acted on in their
+-- current status, are left out; servers without telemetry are never idle. Only
+-- servers stopped through the API or by the reaper count as stopped: a stop by
+-- budget enforcement, a spot interruption or another daemon is not the choice of
+-- the owner.
+SELECT s.*
+FROM servers s
+JOIN reaper_policies p ON p.id = @policy_id
+WHERE s.status = CASE p.condition WHEN 'stopped_for' THEN 'stopped' ELSE 'running' END
+  AND s.last_status_update <= (@now::timestamptz) - p.duration_seconds * INTERVAL '1 second'
+  AND (p.match_type = '' OR s.type = p.match_type)
+  AND (p.match_region = '' OR s.region = p.match_region)
+  AND (p.match_tag_key = '' OR s.tags ->> p.match_tag_key = p.match_tag_value)
+  AND (@exempt_tag::text = '' OR s.tags ->> @exempt_tag::text IS NULL)
+  AND (p.condition <> 'idle_for' OR (
+        SELECT SUM(t.cpu_percent_sum) / SUM(t.samples)
+        FROM server_telemetry t
+        WHERE t.server_id = s.id
+          AND t.minute >= date_trunc('minute', (@now::timestamptz) - p.duration_seconds * INTERVAL '1 second')
+      ) <= p.max_cpu_percent)
+  AND (p.condition <> 'stopped_for' OR s.last_status_actor IN ('api', 'reaper'))
+  AND NOT EXISTS (
+        SELECT 1 FROM reaper_actions a
+        WHERE a.policy_id = p.id AND a.server_id = s.id AND a.state_since = s.last_status_update
+      )
+ORDER BY s.last_status_update;
+
+-- name: CreateReaperAction :one
+-- Records a policy acting on a server; no row is returned if it already did in this status.
+INSERT INTO reaper_actions (policy_id, server_id, action, state_since)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (policy_id, server_id, state_since) DO NOTHING
+RETURNING *;
+
+-- name: SetReaperActionOutcome :exec
+UPDATE reaper_actions
+SET outcome = $1
+WHERE id = $2;
+
+-- name: ListReaperActions :many
+SELECT * FROM reaper_actions
+WHERE sqlc.narg('policy_id')::uuid IS NULL OR policy_id = sqlc.narg('policy_id')::uuid
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
@@ -0,0 +1,39 @@
+-- sql/recommendation.sql
+
+-- name: ListServerUtilization :many
+-- Running time and CPU telemetry over [from_time, to_time) of the running servers
+-- that existed for all of it, optionally of one project or one server only.
+SELECT
+    s.id, s.name, s.project, s.region, s.type, s.hourly_cost, s.billing_model, s.purchase_option,
+    COALESCE(u.running_seconds, 0)::BIGINT AS running_seconds,
+    COALESCE(t.minutes, 0)::BIGINT AS telemetry_minutes,
+    COALESCE(t.cpu_percent_avg, 0)::DOUBLE PRECISION AS cpu_percent_avg,
+    COALESCE(t.cpu_percent_max, 0)::DOUBLE PRECISION AS cpu_percent_max
+FROM servers s
+LEFT JOIN (
+    SELECT server_id,
+           SUM(EXTRACT(EPOCH FROM (LEAST(COALESCE(ended_at, @to_time::timestamptz), @to_time::timestamptz) - GREATEST(started_at, @from_time::timestamptz)))) AS running_seconds
+    FROM usage_segments
+    WHERE started_at < @to_time::timestamptz AND (ended_at IS NULL OR ended_at > @from_time::timestamptz)
+    GROUP BY server_id
+) u ON u.server_id = s.id
+LEFT JOIN (
+    SELECT server_id,
+           COUNT(*) AS minutes,
+           SUM(cpu_percent_sum) / SUM(samples) AS cpu_percent_avg,
+           MAX(cpu_percent_max) AS cpu_percent_max
+    FROM server_telemetry
+    WHERE minute >= @from_time::timestamptz AND minute < @to_time::timestamptz
+    GROUP BY server_id
+) t ON t.server_id = s.id
+WHERE s.status = 'running'
+  AND s.provisioned_at <= @from_time::timestamptz
+  AND (sqlc.narg(project)::text IS NULL OR s.project = sqlc.narg(project))
+  AND (sqlc.narg(server_id)::uuid IS NULL OR s.id = sqlc.narg(server_id))
+ORDER BY s.project, s.created_at;
+
+-- name: ResizeServer :one
+UPDATE servers
+SET type = $1, hourly_cost = $2, updated_at = NOW()
+WHERE id = $3
+RETURNING *;
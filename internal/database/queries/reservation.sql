@@ -0,0 +1,38 @@
+-- sql/reservation.sql
+
+-- name: CreateReservation :one
+INSERT INTO reservations (project, server_type, region, hours, hourly_rate, currency, starts_at, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetReservation :one
+SELECT * FROM reservations WHERE id = $1;
+
+-- name: ListReservations :many
+SELECT * FROM reservations
+WHERE (sqlc.narg(project)::VARCHAR IS NULL OR project = sqlc.narg(project))
+ORDER BY created_at DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: ListReservationsForDrawDown :many
+-- Reservations of the projects with hours left that are in effect at some time
+-- in [since, until), soonest to expire first. Rows are locked so concurrent
+-- draw-downs cannot overspend them.
+SELECT * FROM reservations
+WHERE project = ANY(@projects::VARCHAR[])
+  AND starts_at < @until::timestamptz AND expires_at > @since::timestamptz
+  AND hours_used < hours
+ORDER BY expires_at, created_at
+FOR UPDATE;
+
+-- name: SetReservationsHoursUsed :exec
+UPDATE reservations r
+SET hours_used = LEAST(r.hours, u.hours_used)
+FROM (SELECT unnest(@ids::UUID[]) AS reservation_id, unnest(@used_hours::DOUBLE PRECISION[]) AS hours_used) u
+WHERE r.id = u.reservation_id;
+
+-- name: SumRemainingReservationHours :one
+SELECT COALESCE(SUM(hours - hours_used), 0)::DOUBLE PRECISION AS hours
+FROM reservations
+WHERE project = $1 AND server_type = $2 AND region = $3
+  AND starts_at <= @at::timestamptz AND expires_at > @at::timestamptz;
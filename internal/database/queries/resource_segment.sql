@@ -0,0 +1,80 @@
+-- sql/resource_segments.sql
+
+-- name: OpenResourceSegment :one
+INSERT INTO resource_segments (server_id, resource, quantity)
+VALUES ($1, $2, $3)
+ON CONFLICT (server_id, resource) WHERE ended_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: CloseResourceSegment :exec
+UPDATE resource_segments
+SET ended_at = NOW()
+WHERE server_id = $1 AND resource = $2 AND ended_at IS NULL;
+
+-- name: CloseResourceSegmentsByServerID :exec
+UPDATE resource_segments
+SET ended_at = NOW()
+WHERE server_id = $1 AND ended_at IS NULL;
+
+-- name: CloseAllResourceSegments :exec
+UPDATE resource_segments
+SET ended_at = NOW()
+WHERE ended_at IS NULL;
+
+-- name: ListResourceSegmentsByServerIDs :many
+SELECT * FROM resource_segments
+WHERE server_id = ANY(@server_ids::uuid[])
+ORDER BY started_at;
+
+-- name: ListUnbilledResourceSegments :many
+-- Same rules as ListUnbilledUsageSegments.
+SELECT rs.*, s.project, s.region, s.type AS server_type, s.name AS server_name
+FROM resource_segments rs
+JOIN servers s ON s.id = rs.server_id
+WHERE (rs.ended_at IS NOT NULL AND rs.billed_until < rs.ended_at)
+   OR (rs.ended_at IS NULL AND rs.billed_until < @current_period_start::timestamptz);
+
+-- name: SetResourceSegmentsBilledUntil :exec
+UPDATE resource_segments rs
+SET billed_until = b.billed_until
+FROM (SELECT unnest(@ids::UUID[]) AS segment_id, unnest(@billed_untils::TIMESTAMPTZ[]) AS billed_until) b
+WHERE rs.id = b.segment_id;
+
+-- name: RecordEgress :exec
+-- Simulated traffic: every running server sends mean_gb on average, give or
+-- take half of it.
+INSERT INTO egress_usage (server_id, hour, gb)
+SELECT id, date_trunc('hour', NOW()), (0.5 + random()) * @mean_gb::double precision
+FROM servers
+WHERE status = 'running'
+ON CONFLICT (server_id, hour) DO UPDATE SET gb = egress_usage.gb + EXCLUDED.gb;
+
+-- name: ListUnbilledEgress :many
+-- Egress before the current period that is not in the ledger yet, per server and period.
+SELECT
+    e.server_id,
+    s.project,
+    s.region,
+    s.type AS server_type,
+    s.name AS server_name,
+    date_trunc('month', e.hour AT TIME ZONE 'UTC')::date AS period_start,
+    MIN(e.hour)::timestamptz AS usage_start,
+    (MAX(e.hour) + INTERVAL '1 hour')::timestamptz AS usage_end,
+    SUM(e.gb)::DOUBLE PRECISION AS gb
+FROM egress_usage e
+JOIN servers s ON s.id = e.server_id
+WHERE NOT e.billed AND e.hour < @current_period_start::timestamptz
+GROUP BY e.server_id, s.project, s.region, s.type, s.name, date_trunc('month', e.hour AT TIME ZONE 'UTC');
+
+-- name: MarkEgressBilled :exec
+-- Marks what ListUnbilledEgress returns as billed.
+UPDATE egress_usage
+SET billed = TRUE
+WHERE NOT billed AND hour < @current_period_start::timestamptz;
+
+-- name: SumUnbilledEgressByServerIDs :many
+-- Egress not billed yet from the hour of @since on.
+SELECT server_id, SUM(gb)::DOUBLE PRECISION AS gb
+FROM egress_usage
+WHERE server_id = ANY(@server_ids::uuid[]) AND NOT billed AND hour >= date_trunc('hour', @since::timestamptz)
+GROUP BY server_id;
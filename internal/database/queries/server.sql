@@ -1,8 +1,9 @@
 -- sql/servers.sql
 
 -- name: CreateNewServer :one
-INSERT INTO servers (name, region, status, type, address, hourly_cost)
-VALUES ($1, $2, $3, $4, $5, $6)
+INSERT INTO servers (name, hostname, region, project, status, type, address, hourly_cost, billing_model,
+    purchase_option, spot_max_price, interruption_behavior, assign_public_ip, tags, user_data, disk_gb)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 RETURNING *;
 
 -- name: GetServer :one
@@ -15,52 +16,65 @@ ORDER BY created_at DESC;
 
 -- name: UpdateServerStatus :one
 UPDATE servers
-SET status = $1, last_status_update = NOW()
+SET status = $1, last_status_update = NOW(), last_status_actor = $3, stuck_since = NULL, transition_attempts = 0
 WHERE id = $2
 RETURNING *;
 
--- name: UpdateServerUptime :one
+-- name: UpdateServerName :one
 UPDATE servers
-SET uptime_seconds = $1, updated_at = NOW()
-WHERE id = $2
+SET name = $1, hostname = $2, updated_at = NOW()
+WHERE id = $3
 RETURNING *;
 
--- name: DeleteServer :exec
-DELETE FROM servers WHERE id = $1;
+-- name: HostnameInUse :one
+SELECT EXISTS (
+    SELECT 1 FROM servers
+    WHERE hostname = $1 AND id <> $2 AND status <> 'terminated'
+);
 
--- name: GetServerLifecycleLogs :one
-SELECT lifecycle_logs FROM servers WHERE id = $1;
+-- name: GetLiveServerByHostname :one
+SELECT * FROM servers
+WHERE hostname = $1 AND status <> 'terminated';
 
--- name: AppendServerLifecycleLog :one
-UPDATE servers
-SET lifecycle_logs = jsonb_build_array($1::jsonb) || lifecycle_logs
-WHERE id = $2
-RETURNING lifecycle_logs;
+-- name: GetLiveServerByAddress :one
+SELECT s.* FROM servers s
+JOIN ip_addresses ia ON ia.server_id = s.id
+WHERE ia.address = $1 AND ia.is_allocated AND s.status <> 'terminated'
+LIMIT 1;
 
--- name: EnforceLifecycleLogsLimit :exec
-UPDATE servers
-SET lifecycle_logs =
-    CASE
-        WHEN jsonb_array_length(lifecycle_logs) > 100 
-            THEN jsonb_path_query_array(lifecycle_logs, '$[0 to 14]') 
-        ELSE lifecycle_logs
-    END
-WHERE id = $1;
+-- name: RefreshServerUptimes :exec
+UPDATE servers s
+SET uptime_seconds = u.uptime_seconds, updated_at = NOW()
+FROM (
+    SELECT server_id, SUM(EXTRACT(EPOCH FROM (COALESCE(ended_at, NOW()) - started_at)))::BIGINT AS uptime_seconds
+    FROM usage_segments
+    GROUP BY server_id
+) u
+WHERE u.server_id = s.id AND s.uptime_seconds <> u.uptime_seconds;
+
+-- name: DeleteServer :exec
+DELETE FROM servers WHERE id = $1;
 
 -- name: TerminateAllServers :exec
-UPDATE servers
-SET status = 'terminated',
-    last_status_update = NOW(),
-    lifecycle_logs = lifecycle_logs || jsonb_build_object(
-        'timestamp', NOW(),
-        'event', 'System Reset: Server Terminated by System',
-        'request_id', 'system-reset'
-    )::jsonb
-WHERE status != 'terminated';
+-- Terminates every live server, recording the status change in its events.
+WITH terminated AS (
+    UPDATE servers s
+    SET status = 'terminated',
+        last_status_update = NOW(),
+        last_status_actor = 'system',
+        event_sequence = s.event_sequence + 1
+    FROM servers previous
+    WHERE previous.id = s.id AND s.status != 'terminated'
+    RETURNING s.id, s.event_sequence, previous.status AS from_status
+)
+INSERT INTO server_events (server_id, sequence, type, from_status, to_status, actor, request_id, payload)
+SELECT id, event_sequence, 'status_change', from_status, 'terminated', 'system', 'system-reset',
+    jsonb_build_object('message', 'System reset: server terminated')
+FROM terminated;
 
 
 -- name: TruncateServers :exec
 TRUNCATE servers RESTART IDENTITY CASCADE;
 
 -- name: SelectAllServers :many
-SELECT * FROM servers;
\ No newline at end of file
+SELECT * FROM servers;
@@ -0,0 +1,29 @@
+-- sql/server_event.sql
+
+-- name: RecordServerEvent :one
+-- Appends an event to a server's history under its next sequence number.
+WITH next AS (
+    UPDATE servers s
+    SET event_sequence = s.event_sequence + 1
+    WHERE s.id = @server_id
+    RETURNING s.id, s.event_sequence
+)
+INSERT INTO server_events (server_id, sequence, type, from_status, to_status, actor, request_id, payload)
+SELECT id, event_sequence, @type, sqlc.narg('from_status'), sqlc.narg('to_status'), @actor, sqlc.narg('request_id'), @payload::jsonb
+FROM next
+RETURNING *;
+
+-- name: ListServerEvents :many
+-- Events of a server, newest first, optionally of some types only and within
+-- [from, to).
+SELECT * FROM server_events
+WHERE server_id = @server_id
+    AND (sqlc.narg('types')::text[] IS NULL OR type = ANY(sqlc.narg('types')::text[]))
+    AND (sqlc.narg('from_time')::timestamptz IS NULL OR created_at >= sqlc.narg('from_time')::timestamptz)
+    AND (sqlc.narg('to_time')::timestamptz IS NULL OR created_at < sqlc.narg('to_time')::timestamptz)
+ORDER BY sequence DESC
+LIMIT @row_limit OFFSET @row_offset;
+
+-- name: PruneServerEvents :execrows
+DELETE FROM server_events
+WHERE created_at < @before::timestamptz;
@@ -0,0 +1,55 @@
+-- sql/spot.sql
+
+-- name: CreateSpotPrice :one
+INSERT INTO spot_prices (server_type, region, price, effective_from)
+VALUES ($1, $2, $3, $4)
+RETURNING *;
+
+-- name: GetCurrentSpotPrice :one
+SELECT * FROM spot_prices
+WHERE server_type = $1 AND region = $2
+ORDER BY effective_from DESC
+LIMIT 1;
+
+-- name: ListCurrentSpotPrices :many
+SELECT DISTINCT ON (server_type, region) *
+FROM spot_prices
+WHERE (sqlc.narg(server_type)::VARCHAR IS NULL OR server_type = sqlc.narg(server_type))
+  AND (sqlc.narg(region)::VARCHAR IS NULL OR region = sqlc.narg(region))
+ORDER BY server_type, region, effective_from DESC;
+
+-- name: ListSpotPricesSince :many
+-- The spot prices in effect from @since on: the latest price before it per
+-- market, and every later one.
+SELECT * FROM spot_prices sp
+WHERE sp.effective_from >= COALESCE((
+    SELECT MAX(p.effective_from) FROM spot_prices p
+    WHERE p.server_type = sp.server_type AND p.region = sp.region
+      AND p.effective_from <= @since::timestamptz
+), '-infinity'::timestamptz)
+ORDER BY sp.server_type, sp.region, sp.effective_from;
+
+-- name: SetSpotServerHourlyCosts :exec
+UPDATE servers
+SET hourly_cost = @price::DOUBLE PRECISION
+WHERE purchase_option = 'spot' AND type = $1 AND region = $2 AND status <> 'terminated';
+
+-- name: NoticeOutbidSpotServers :many
+-- Gives running spot servers of a market whose bid is below @price their
+-- interruption notice, once.
+UPDATE servers
+SET interruption_notice_at = @notice_at::timestamptz
+WHERE purchase_option = 'spot' AND type = $1 AND region = $2
+  AND status = 'running' AND interruption_notice_at IS NULL
+  AND spot_max_price < @price::DOUBLE PRECISION
+RETURNING *;
+
+-- name: ListDueSpotInterruptions :many
+SELECT * FROM servers
+WHERE interruption_notice_at <= @due::timestamptz AND status <> 'terminated'
+ORDER BY interruption_notice_at;
+
+-- name: ClearSpotInterruptionNotice :exec
+UPDATE servers
+SET interruption_notice_at = NULL
+WHERE id = $1;
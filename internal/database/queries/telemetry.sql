@@ -0,0 +1,40 @@
+-- sql/telemetry.sql
+
+-- name: RecordTelemetry :exec
+-- Folds samples, one per server, into the minute they were taken in.
+INSERT INTO server_telemetry AS t (server_id, minute, samples, cpu_percent_sum, cpu_percent_max, network_rx_bytes, network_tx_bytes)
+SELECT server_id, date_trunc('minute', sampled_at), 1, cpu_percent, cpu_percent, network_rx_bytes, network_tx_bytes
+FROM (
+    SELECT
+        unnest(@server_ids::uuid[]) AS server_id,
+        unnest(@sampled_at::timestamptz[]) AS sampled_at,
+        unnest(@cpu_percent::double precision[]) AS cpu_percent,
+        unnest(@network_rx_bytes::bigint[]) AS network_rx_bytes,
+        unnest(@network_tx_bytes::bigint[]) AS network_tx_bytes
+) AS sample
+ON CONFLICT (server_id, minute) DO UPDATE
+SET samples = t.samples + 1,
+    cpu_percent_sum = t.cpu_percent_sum + EXCLUDED.cpu_percent_sum,
+    cpu_percent_max = GREATEST(t.cpu_percent_max, EXCLUDED.cpu_percent_max),
+    network_rx_bytes = t.network_rx_bytes + EXCLUDED.network_rx_bytes,
+    network_tx_bytes = t.network_tx_bytes + EXCLUDED.network_tx_bytes;
+
+-- name: GetTelemetrySeries :many
+-- Telemetry of a server over [from, to) in buckets of step, starting at from.
+SELECT
+    date_bin(@step::interval, minute, @from_time::timestamptz)::timestamptz AS bucket,
+    (SUM(cpu_percent_sum) / SUM(samples))::DOUBLE PRECISION AS cpu_percent_avg,
+    MAX(cpu_percent_max)::DOUBLE PRECISION AS cpu_percent_max,
+    SUM(network_rx_bytes)::BIGINT AS network_rx_bytes,
+    SUM(network_tx_bytes)::BIGINT AS network_tx_bytes,
+    SUM(samples)::BIGINT AS samples
+FROM server_telemetry
+WHERE server_id = @server_id AND minute >= @from_time::timestamptz AND minute < @to_time::timestamptz
+GROUP BY bucket
+ORDER BY bucket;
+
+-- name: ListRunningServerIDs :many
+SELECT id FROM servers WHERE status = 'running';
+
+-- name: PruneTelemetry :execrows
+DELETE FROM server_telemetry WHERE minute < @before::timestamptz;
@@ -0,0 +1,29 @@
+-- sql/usage_segments.sql
+
+-- name: OpenUsageSegment :one
+INSERT INTO usage_segments (server_id, server_type, hourly_rate)
+VALUES ($1, $2, $3)
+ON CONFLICT (server_id) WHERE ended_at IS NULL DO NOTHING
+RETURNING *;
+
+-- name: CloseUsageSegment :exec
+UPDATE usage_segments
+SET ended_at = NOW()
+WHERE server_id = $1 AND ended_at IS NULL;
+
+-- name: CloseAllUsageSegments :exec
+UPDATE usage_segments
+SET ended_at = NOW()
+WHERE ended_at IS NULL;
+
+-- name: ListUsageSegmentsByServerID :many
+SELECT * FROM usage_segments
+WHERE server_id = $1
+ORDER BY started_at;
+
+-- name: ListUsageSegmentsByServerIDs :many
+SELECT us.*, s.region, s.project, s.billing_model, s.purchase_option
+FROM usage_segments us
+JOIN servers s ON s.id = us.server_id
+WHERE us.server_id = ANY(@server_ids::uuid[])
+ORDER BY us.started_at;
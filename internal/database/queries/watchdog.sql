@@ -0,0 +1,33 @@
+-- sql/watchdog.sql
+
+-- name: ListStuckServers :many
+-- Servers that have been in status since before stuck_before. A provisioning
+-- server whose primary interface holds its primary address has finished
+-- provisioning and is only waiting to be started, so it is not stuck.
+SELECT s.* FROM servers s
+WHERE s.status = @status AND s.last_status_update < @stuck_before::timestamptz
+  AND NOT (s.status = 'provisioning' AND EXISTS (
+        SELECT 1 FROM network_interfaces ni
+        JOIN ip_addresses ia ON ia.interface_id = ni.id
+        WHERE ni.server_id = s.id AND ni.is_primary AND ia.is_primary AND ia.is_allocated
+      ))
+ORDER BY s.last_status_update;
+
+-- name: RecordTransitionAttempt :one
+-- Counts a failed retry of a stuck server's transition and restarts its timeout.
+UPDATE servers
+SET transition_attempts = transition_attempts + 1,
+    stuck_since = COALESCE(stuck_since, last_status_update),
+    last_status_update = NOW()
+WHERE id = @id AND status = @status
+RETURNING *;
+
+-- name: MarkServerStuck :one
+-- Moves a server that is still in status to the error state.
+UPDATE servers
+SET status = 'error',
+    stuck_since = COALESCE(stuck_since, last_status_update),
+    last_status_update = NOW(),
+    last_status_actor = 'watchdog'
+WHERE id = @id AND status = @status
+RETURNING *;
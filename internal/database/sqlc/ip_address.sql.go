@@ -13,46 +13,34 @@ import (
 
 const allocateIPAddress = `-- name: AllocateIPAddress :one
 UPDATE ip_addresses
-SET is_allocated = TRUE, server_id = $1, updated_at = NOW()
-WHERE id = $2 AND is_allocated = FALSE AND server_id IS NULL
-RETURNING id, address, is_allocated, server_id, created_at, updated_at
+SET is_allocated = TRUE, server_id = $1, interface_id = $2, is_primary = $3, updated_at = NOW()
+WHERE id = $4 AND server_id IS NULL
+RETURNING id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at
 `
 
 type AllocateIPAddressParams struct {
-	ServerID pgtype.UUID `json:"server_id"`
-	ID       pgtype.UUID `json:"id"`
+	ServerID    pgtype.UUID `json:"server_id"`
+	InterfaceID pgtype.UUID `json:"interface_id"`
+	IsPrimary   bool        `json:"is_primary"`
+	ID          pgtype.UUID `json:"id"`
 }
 
 func (q *Queries) AllocateIPAddress(ctx context.Context, arg AllocateIPAddressParams) (IpAddress, error) {
-	row := q.db.QueryRow(ctx, allocateIPAddress, arg.ServerID, arg.ID)
-	var i IpAddress
-	err := row.Scan(
-		&i.ID,
-		&i.Address,
-		&i.IsAllocated,
-		&i.ServerID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
+	row := q.db.QueryRow(ctx, allocateIPAddress,
+		arg.ServerID,
+		arg.InterfaceID,
+		arg.IsPrimary,
+		arg.ID,
 	)
-	return i, err
-}
-
-const createIPAddress = `-- name: CreateIPAddress :one
-
-INSERT INTO ip_addresses (address)
-VALUES ($1)
-RETURNING id, address, is_allocated, server_id, created_at, updated_at
-`
-
-// sql/ip_address.sql
-func (q *Queries) CreateIPAddress(ctx context.Context, address string) (IpAddress, error) {
-	row := q.db.QueryRow(ctx, createIPAddress, address)
 	var i IpAddress
 	err := row.Scan(
 		&i.ID,
+		&i.PoolID,
 		&i.Address,
 		&i.IsAllocated,
 		&i.ServerID,
+		&i.InterfaceID,
+		&i.IsPrimary,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -61,9 +49,9 @@ func (q *Queries) CreateIPAddress(ctx context.Context, address string) (IpAddres
 
 const deallocateIPAddress = `-- name: DeallocateIPAddress :one
 UPDATE ip_addresses
-SET is_allocated = FALSE, server_id = NULL, updated_at = NOW()
+SET is_allocated = FALSE, server_id = NULL, interface_id = NULL, is_primary = FALSE, updated_at = NOW()
 WHERE id = $1
-RETURNING id, address, is_allocated, server_id, created_at, updated_at
+RETURNING id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at
 `
 
 func (q *Queries) DeallocateIPAddress(ctx context.Context, id pgtype.UUID) (IpAddress, error) {
@@ -71,60 +59,287 @@ func (q *Queries) DeallocateIPAddress(ctx context.Context, id pgtype.UUID) (IpAd
 	var i IpAddress
 	err := row.Scan(
 		&i.ID,
+		&i.PoolID,
 		&i.Address,
 		&i.IsAllocated,
 		&i.ServerID,
+		&i.InterfaceID,
+		&i.IsPrimary,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getAvailableIPForAllocation = `-- name: GetAvailableIPForAllocation :one
-SELECT id, address, is_allocated, server_id, created_at, updated_at FROM ip_addresses
-WHERE is_allocated = FALSE AND server_id IS NULL
-ORDER BY created_at ASC
-FOR UPDATE SKIP LOCKED
-LIMIT 1
+const listIPAddressesByInterfaceID = `-- name: ListIPAddressesByInterfaceID :many
+SELECT id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at FROM ip_addresses
+WHERE interface_id = $1
+ORDER BY is_primary DESC, updated_at ASC
 `
 
-func (q *Queries) GetAvailableIPForAllocation(ctx context.Context) (IpAddress, error) {
-	row := q.db.QueryRow(ctx, getAvailableIPForAllocation)
+func (q *Queries) ListIPAddressesByInterfaceID(ctx context.Context, interfaceID pgtype.UUID) ([]IpAddress, error) {
+	rows, err := q.db.Query(ctx, listIPAddressesByInterfaceID, interfaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IpAddress
+	for rows.Next() {
+		var i IpAddress
+		if err := rows.Scan(
+			&i.ID,
+			&i.PoolID,
+			&i.Address,
+			&i.IsAllocated,
+			&i.ServerID,
+			&i.InterfaceID,
+			&i.IsPrimary,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIPAddressesByPool = `-- name: ListIPAddressesByPool :many
+SELECT id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at FROM ip_addresses
+WHERE pool_id = $1
+ORDER BY updated_at ASC
+`
+
+func (q *Queries) ListIPAddressesByPool(ctx context.Context, poolID pgtype.UUID) ([]IpAddress, error) {
+	rows, err := q.db.Query(ctx, listIPAddressesByPool, poolID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IpAddress
+	for rows.Next() {
+		var i IpAddress
+		if err := rows.Scan(
+			&i.ID,
+			&i.PoolID,
+			&i.Address,
+			&i.IsAllocated,
+			&i.ServerID,
+			&i.InterfaceID,
+			&i.IsPrimary,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIPAddressesByServerID = `-- name: ListIPAddressesByServerID :many
+SELECT id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at FROM ip_addresses
+WHERE server_id = $1
+ORDER BY is_primary DESC, updated_at ASC
+`
+
+func (q *Queries) ListIPAddressesByServerID(ctx context.Context, serverID pgtype.UUID) ([]IpAddress, error) {
+	rows, err := q.db.Query(ctx, listIPAddressesByServerID, serverID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IpAddress
+	for rows.Next() {
+		var i IpAddress
+		if err := rows.Scan(
+			&i.ID,
+			&i.PoolID,
+			&i.Address,
+			&i.IsAllocated,
+			&i.ServerID,
+			&i.InterfaceID,
+			&i.IsPrimary,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIPAddressesByServerIDs = `-- name: ListIPAddressesByServerIDs :many
+SELECT a.id, a.address, a.server_id, a.interface_id, a.is_primary, p.name AS pool_name
+FROM ip_addresses a
+LEFT JOIN ip_pools p ON p.id = a.pool_id
+WHERE a.server_id = ANY($1::uuid[])
+ORDER BY a.is_primary DESC, a.updated_at ASC
+`
+
+type ListIPAddressesByServerIDsRow struct {
+	ID          pgtype.UUID `json:"id"`
+	Address     string      `json:"address"`
+	ServerID    pgtype.UUID `json:"server_id"`
+	InterfaceID pgtype.UUID `json:"interface_id"`
+	IsPrimary   bool        `json:"is_primary"`
+	PoolName    pgtype.Text `json:"pool_name"`
+}
+
+func (q *Queries) ListIPAddressesByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]ListIPAddressesByServerIDsRow, error) {
+	rows, err := q.db.Query(ctx, listIPAddressesByServerIDs, serverIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListIPAddressesByServerIDsRow
+	for rows.Next() {
+		var i ListIPAddressesByServerIDsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Address,
+			&i.ServerID,
+			&i.InterfaceID,
+			&i.IsPrimary,
+			&i.PoolName,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listIPPoolsInUse = `-- name: ListIPPoolsInUse :many
+SELECT p.id, p.name, p.cidr, p.strategy, p.excluded_addresses, p.created_at, p.updated_at FROM ip_pools p
+WHERE EXISTS (SELECT 1 FROM ip_addresses ia WHERE ia.pool_id = p.id AND ia.is_allocated)
+ORDER BY p.name
+`
+
+// Pools holding allocated addresses.
+func (q *Queries) ListIPPoolsInUse(ctx context.Context) ([]IpPool, error) {
+	rows, err := q.db.Query(ctx, listIPPoolsInUse)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []IpPool
+	for rows.Next() {
+		var i IpPool
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Cidr,
+			&i.Strategy,
+			&i.ExcludedAddresses,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const releaseAllIPAddresses = `-- name: ReleaseAllIPAddresses :exec
+UPDATE ip_addresses
+SET is_allocated = FALSE, server_id = NULL, interface_id = NULL, is_primary = FALSE, updated_at = NOW()
+WHERE is_allocated OR server_id IS NOT NULL
+`
+
+// Returns every address to its pool. Rows are kept rather than truncated, so
+// the NAT mappings referencing them survive as history.
+func (q *Queries) ReleaseAllIPAddresses(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, releaseAllIPAddresses)
+	return err
+}
+
+const reserveIPAddress = `-- name: ReserveIPAddress :one
+
+INSERT INTO ip_addresses (pool_id, address, is_allocated)
+VALUES ($1, $2, TRUE)
+ON CONFLICT (pool_id, address) DO UPDATE
+SET is_allocated = TRUE, updated_at = NOW()
+WHERE ip_addresses.is_allocated = FALSE AND ip_addresses.server_id IS NULL
+RETURNING id, pool_id, address, is_allocated, server_id, interface_id, is_primary, created_at, updated_at
+`
+
+type ReserveIPAddressParams struct {
+	PoolID  pgtype.UUID `json:"pool_id"`
+	Address string      `json:"address"`
+}
+
+// sql/ip_address.sql
+// Creates the row for an address on first use, or reclaims a released one.
+// Returns no row when the address is already held in the pool, e.g. by another replica.
+func (q *Queries) ReserveIPAddress(ctx context.Context, arg ReserveIPAddressParams) (IpAddress, error) {
+	row := q.db.QueryRow(ctx, reserveIPAddress, arg.PoolID, arg.Address)
 	var i IpAddress
 	err := row.Scan(
 		&i.ID,
+		&i.PoolID,
 		&i.Address,
 		&i.IsAllocated,
 		&i.ServerID,
+		&i.InterfaceID,
+		&i.IsPrimary,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getIPAddressByServerID = `-- name: GetIPAddressByServerID :one
-SELECT id, address, is_allocated, server_id, created_at, updated_at FROM ip_addresses WHERE server_id = $1
+const upsertIPPool = `-- name: UpsertIPPool :one
+INSERT INTO ip_pools (name, cidr, strategy, excluded_addresses)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (name) DO UPDATE
+SET cidr = EXCLUDED.cidr,
+    strategy = EXCLUDED.strategy,
+    excluded_addresses = EXCLUDED.excluded_addresses,
+    updated_at = NOW()
+RETURNING id, name, cidr, strategy, excluded_addresses, created_at, updated_at
 `
 
-func (q *Queries) GetIPAddressByServerID(ctx context.Context, serverID pgtype.UUID) (IpAddress, error) {
-	row := q.db.QueryRow(ctx, getIPAddressByServerID, serverID)
-	var i IpAddress
+type UpsertIPPoolParams struct {
+	Name              string   `json:"name"`
+	Cidr              string   `json:"cidr"`
+	Strategy          string   `json:"strategy"`
+	ExcludedAddresses []string `json:"excluded_addresses"`
+}
+
+func (q *Queries) UpsertIPPool(ctx context.Context, arg UpsertIPPoolParams) (IpPool, error) {
+	row := q.db.QueryRow(ctx, upsertIPPool,
+		arg.Name,
+		arg.Cidr,
+		arg.Strategy,
+		arg.ExcludedAddresses,
+	)
+	var i IpPool
 	err := row.Scan(
 		&i.ID,
-		&i.Address,
-		&i.IsAllocated,
-		&i.ServerID,
+		&i.Name,
+		&i.Cidr,
+		&i.Strategy,
+		&i.ExcludedAddresses,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
-
-const truncateIPAddresses = `-- name: TruncateIPAddresses :exec
-TRUNCATE ip_addresses RESTART IDENTITY CASCADE
-`
-
-func (q *Queries) TruncateIPAddresses(ctx context.Context) error {
-	_, err := q.db.Exec(ctx, truncateIPAddresses)
-	return err
-}
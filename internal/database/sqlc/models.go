@@ -8,27 +8,326 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+type Account struct {
+	Project             string             `json:"project"`
+	Balance             float64            `json:"balance"`
+	Currency            string             `json:"currency"`
+	LowBalanceThreshold float64            `json:"low_balance_threshold"`
+	Charged             float64            `json:"charged"`
+	DrawnUntil          pgtype.Timestamptz `json:"drawn_until"`
+	LowBalanceWarned    bool               `json:"low_balance_warned"`
+	SuspendedAt         pgtype.Timestamptz `json:"suspended_at"`
+	CreatedAt           pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt           pgtype.Timestamptz `json:"updated_at"`
+}
+
+type AccountEvent struct {
+	ID        pgtype.UUID        `json:"id"`
+	Project   string             `json:"project"`
+	Type      string             `json:"type"`
+	Amount    float64            `json:"amount"`
+	Balance   float64            `json:"balance"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+}
+
+type Budget struct {
+	ID         pgtype.UUID        `json:"id"`
+	Name       string             `json:"name"`
+	ScopeType  string             `json:"scope_type"`
+	ScopeKey   string             `json:"scope_key"`
+	ScopeValue string             `json:"scope_value"`
+	Amount     float64            `json:"amount"`
+	Currency   string             `json:"currency"`
+	Period     string             `json:"period"`
+	Thresholds []int32            `json:"thresholds"`
+	WebhookUrl string             `json:"webhook_url"`
+	Enforce    bool               `json:"enforce"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type BudgetAlert struct {
+	ID            pgtype.UUID        `json:"id"`
+	BudgetID      pgtype.UUID        `json:"budget_id"`
+	PeriodStart   pgtype.Timestamptz `json:"period_start"`
+	Threshold     int32              `json:"threshold"`
+	Spend         float64            `json:"spend"`
+	Amount        float64            `json:"amount"`
+	WebhookStatus string             `json:"webhook_status"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type Credit struct {
+	ID         pgtype.UUID        `json:"id"`
+	Project    string             `json:"project"`
+	Name       string             `json:"name"`
+	ScopeType  string             `json:"scope_type"`
+	ScopeValue string             `json:"scope_value"`
+	Amount     float64            `json:"amount"`
+	Remaining  float64            `json:"remaining"`
+	Currency   string             `json:"currency"`
+	StartsAt   pgtype.Timestamptz `json:"starts_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type Discount struct {
+	ID         pgtype.UUID        `json:"id"`
+	Name       string             `json:"name"`
+	ServerType string             `json:"server_type"`
+	Region     string             `json:"region"`
+	PercentOff float64            `json:"percent_off"`
+	StartsAt   pgtype.Timestamptz `json:"starts_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type EgressUsage struct {
+	ServerID pgtype.UUID        `json:"server_id"`
+	Hour     pgtype.Timestamptz `json:"hour"`
+	Gb       float64            `json:"gb"`
+	Billed   bool               `json:"billed"`
+}
+
+type ExchangeRate struct {
+	ID            pgtype.UUID        `json:"id"`
+	Currency      string             `json:"currency"`
+	Rate          float64            `json:"rate"`
+	EffectiveFrom pgtype.Timestamptz `json:"effective_from"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type Invoice struct {
+	ID           pgtype.UUID        `json:"id"`
+	Project      string             `json:"project"`
+	PeriodStart  pgtype.Date        `json:"period_start"`
+	PeriodEnd    pgtype.Date        `json:"period_end"`
+	Currency     string             `json:"currency"`
+	ExchangeRate float64            `json:"exchange_rate"`
+	Total        float64            `json:"total"`
+	IssuedAt     pgtype.Timestamptz `json:"issued_at"`
+}
+
+type InvoiceLine struct {
+	ID         pgtype.UUID `json:"id"`
+	InvoiceID  pgtype.UUID `json:"invoice_id"`
+	ServerID   pgtype.UUID `json:"server_id"`
+	ServerName string      `json:"server_name"`
+	ChargeType string      `json:"charge_type"`
+	ServerType string      `json:"server_type"`
+	Region     string      `json:"region"`
+	Quantity   float64     `json:"quantity"`
+	Unit       string      `json:"unit"`
+	UnitPrice  float64     `json:"unit_price"`
+	Amount     float64     `json:"amount"`
+}
+
 type IpAddress struct {
 	ID          pgtype.UUID        `json:"id"`
+	PoolID      pgtype.UUID        `json:"pool_id"`
 	Address     string             `json:"address"`
 	IsAllocated bool               `json:"is_allocated"`
 	ServerID    pgtype.UUID        `json:"server_id"`
+	InterfaceID pgtype.UUID        `json:"interface_id"`
+	IsPrimary   bool               `json:"is_primary"`
 	CreatedAt   pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
 }
 
+type IpPool struct {
+	ID                pgtype.UUID        `json:"id"`
+	Name              string             `json:"name"`
+	Cidr              string             `json:"cidr"`
+	Strategy          string             `json:"strategy"`
+	ExcludedAddresses []string           `json:"excluded_addresses"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+}
+
+type LedgerEntry struct {
+	ID          pgtype.UUID        `json:"id"`
+	Project     string             `json:"project"`
+	ServerID    pgtype.UUID        `json:"server_id"`
+	SegmentID   pgtype.UUID        `json:"segment_id"`
+	PeriodStart pgtype.Date        `json:"period_start"`
+	ChargeType  string             `json:"charge_type"`
+	ServerType  string             `json:"server_type"`
+	Region      string             `json:"region"`
+	Description string             `json:"description"`
+	UsageStart  pgtype.Timestamptz `json:"usage_start"`
+	UsageEnd    pgtype.Timestamptz `json:"usage_end"`
+	Quantity    float64            `json:"quantity"`
+	Unit        string             `json:"unit"`
+	UnitPrice   float64            `json:"unit_price"`
+	Amount      float64            `json:"amount"`
+	Currency    string             `json:"currency"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type NatMapping struct {
+	ID             pgtype.UUID        `json:"id"`
+	ServerID       pgtype.UUID        `json:"server_id"`
+	PublicIpID     pgtype.UUID        `json:"public_ip_id"`
+	PrivateIpID    pgtype.UUID        `json:"private_ip_id"`
+	PublicAddress  string             `json:"public_address"`
+	PrivateAddress string             `json:"private_address"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	ReleasedAt     pgtype.Timestamptz `json:"released_at"`
+}
+
+type NetworkInterface struct {
+	ID          pgtype.UUID        `json:"id"`
+	ServerID    pgtype.UUID        `json:"server_id"`
+	DeviceIndex int32              `json:"device_index"`
+	IsPrimary   bool               `json:"is_primary"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Price struct {
+	ID            pgtype.UUID        `json:"id"`
+	ServerType    string             `json:"server_type"`
+	Region        string             `json:"region"`
+	HourlyRate    float64            `json:"hourly_rate"`
+	Currency      string             `json:"currency"`
+	EffectiveFrom pgtype.Timestamptz `json:"effective_from"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+}
+
+type Project struct {
+	Name      string             `json:"name"`
+	Currency  string             `json:"currency"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ReaperAction struct {
+	ID         pgtype.UUID        `json:"id"`
+	PolicyID   pgtype.UUID        `json:"policy_id"`
+	ServerID   pgtype.UUID        `json:"server_id"`
+	Action     string             `json:"action"`
+	StateSince pgtype.Timestamptz `json:"state_since"`
+	Outcome    string             `json:"outcome"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type ReaperPolicy struct {
+	ID              pgtype.UUID        `json:"id"`
+	Name            string             `json:"name"`
+	MatchType       string             `json:"match_type"`
+	MatchRegion     string             `json:"match_region"`
+	MatchTagKey     string             `json:"match_tag_key"`
+	MatchTagValue   string             `json:"match_tag_value"`
+	Condition       string             `json:"condition"`
+	DurationSeconds int64              `json:"duration_seconds"`
+	MaxCpuPercent   float64            `json:"max_cpu_percent"`
+	Action          string             `json:"action"`
+	WebhookUrl      string             `json:"webhook_url"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Reservation struct {
+	ID         pgtype.UUID        `json:"id"`
+	Project    string             `json:"project"`
+	ServerType string             `json:"server_type"`
+	Region     string             `json:"region"`
+	Hours      float64            `json:"hours"`
+	HoursUsed  float64            `json:"hours_used"`
+	HourlyRate float64            `json:"hourly_rate"`
+	Currency   string             `json:"currency"`
+	StartsAt   pgtype.Timestamptz `json:"starts_at"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type ResourceSegment struct {
+	ID          pgtype.UUID        `json:"id"`
+	ServerID    pgtype.UUID        `json:"server_id"`
+	Resource    string             `json:"resource"`
+	Quantity    float64            `json:"quantity"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	EndedAt     pgtype.Timestamptz `json:"ended_at"`
+	BilledUntil pgtype.Timestamptz `json:"billed_until"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
 type Server struct {
-	ID               pgtype.UUID        `json:"id"`
-	Name             string             `json:"name"`
-	Region           string             `json:"region"`
-	Status           string             `json:"status"`
-	Address          string             `json:"address"`
-	Type             string             `json:"type"`
-	ProvisionedAt    pgtype.Timestamptz `json:"provisioned_at"`
-	LastStatusUpdate pgtype.Timestamptz `json:"last_status_update"`
-	UptimeSeconds    int64              `json:"uptime_seconds"`
-	HourlyCost       float64            `json:"hourly_cost"`
-	LifecycleLogs    []byte             `json:"lifecycle_logs"`
-	CreatedAt        pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	ID                   pgtype.UUID        `json:"id"`
+	Name                 string             `json:"name"`
+	Hostname             string             `json:"hostname"`
+	Region               string             `json:"region"`
+	Project              string             `json:"project"`
+	Status               string             `json:"status"`
+	Address              string             `json:"address"`
+	Type                 string             `json:"type"`
+	DiskGb               int32              `json:"disk_gb"`
+	ProvisionedAt        pgtype.Timestamptz `json:"provisioned_at"`
+	LastStatusUpdate     pgtype.Timestamptz `json:"last_status_update"`
+	LastStatusActor      string             `json:"last_status_actor"`
+	StuckSince           pgtype.Timestamptz `json:"stuck_since"`
+	TransitionAttempts   int32              `json:"transition_attempts"`
+	UptimeSeconds        int64              `json:"uptime_seconds"`
+	HourlyCost           float64            `json:"hourly_cost"`
+	BillingModel         string             `json:"billing_model"`
+	PurchaseOption       string             `json:"purchase_option"`
+	SpotMaxPrice         float64            `json:"spot_max_price"`
+	InterruptionBehavior string             `json:"interruption_behavior"`
+	InterruptionNoticeAt pgtype.Timestamptz `json:"interruption_notice_at"`
+	AssignPublicIp       bool               `json:"assign_public_ip"`
+	Tags                 []byte             `json:"tags"`
+	UserData             string             `json:"user_data"`
+	EventSequence        int64              `json:"event_sequence"`
+	CreatedAt            pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt            pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ServerEvent struct {
+	ID         pgtype.UUID        `json:"id"`
+	ServerID   pgtype.UUID        `json:"server_id"`
+	Sequence   int64              `json:"sequence"`
+	Type       string             `json:"type"`
+	FromStatus pgtype.Text        `json:"from_status"`
+	ToStatus   pgtype.Text        `json:"to_status"`
+	Actor      string             `json:"actor"`
+	RequestID  pgtype.Text        `json:"request_id"`
+	Payload    []byte             `json:"payload"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+}
+
+type ServerTelemetry struct {
+	ServerID       pgtype.UUID        `json:"server_id"`
+	Minute         pgtype.Timestamptz `json:"minute"`
+	Samples        int32              `json:"samples"`
+	CpuPercentSum  float64            `json:"cpu_percent_sum"`
+	CpuPercentMax  float64            `json:"cpu_percent_max"`
+	NetworkRxBytes int64              `json:"network_rx_bytes"`
+	NetworkTxBytes int64              `json:"network_tx_bytes"`
+}
+
+type SpotPrice struct {
+	ID            pgtype.UUID        `json:"id"`
+	ServerType    string             `json:"server_type"`
+	Region        string             `json:"region"`
+	Price         float64            `json:"price"`
+	EffectiveFrom pgtype.Timestamptz `json:"effective_from"`
+}
+
+type UsageSegment struct {
+	ID          pgtype.UUID        `json:"id"`
+	ServerID    pgtype.UUID        `json:"server_id"`
+	ServerType  string             `json:"server_type"`
+	HourlyRate  float64            `json:"hourly_rate"`
+	StartedAt   pgtype.Timestamptz `json:"started_at"`
+	EndedAt     pgtype.Timestamptz `json:"ended_at"`
+	BilledUntil pgtype.Timestamptz `json:"billed_until"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type VolumeTier struct {
+	ID         pgtype.UUID        `json:"id"`
+	ServerType string             `json:"server_type"`
+	FromHours  float64            `json:"from_hours"`
+	PercentOff float64            `json:"percent_off"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 }
@@ -11,26 +11,258 @@ import (
 )
 
 type Querier interface {
+	AdvisoryLockShared(ctx context.Context, lockKey int64) error
+	AdvisoryUnlock(ctx context.Context, lockKey int64) error
 	AllocateIPAddress(ctx context.Context, arg AllocateIPAddressParams) (IpAddress, error)
-	AppendServerLifecycleLog(ctx context.Context, arg AppendServerLifecycleLogParams) ([]byte, error)
-	// sql/ip_address.sql
-	CreateIPAddress(ctx context.Context, address string) (IpAddress, error)
+	ClearSpotInterruptionNotice(ctx context.Context, id pgtype.UUID) error
+	CloseAllResourceSegments(ctx context.Context) error
+	CloseAllUsageSegments(ctx context.Context) error
+	CloseResourceSegment(ctx context.Context, arg CloseResourceSegmentParams) error
+	CloseResourceSegmentsByServerID(ctx context.Context, serverID pgtype.UUID) error
+	CloseUsageSegment(ctx context.Context, serverID pgtype.UUID) error
+	CreateAccountEvent(ctx context.Context, arg CreateAccountEventParams) (AccountEvent, error)
+	// sql/budget.sql
+	CreateBudget(ctx context.Context, arg CreateBudgetParams) (Budget, error)
+	// Records a threshold crossing; no row is returned if it was already recorded this period.
+	CreateBudgetAlert(ctx context.Context, arg CreateBudgetAlertParams) (BudgetAlert, error)
+	CreateCredit(ctx context.Context, arg CreateCreditParams) (Credit, error)
+	CreateDiscount(ctx context.Context, arg CreateDiscountParams) (Discount, error)
+	CreateExchangeRate(ctx context.Context, arg CreateExchangeRateParams) (ExchangeRate, error)
+	CreateInvoice(ctx context.Context, arg CreateInvoiceParams) (Invoice, error)
+	CreateInvoiceLine(ctx context.Context, arg CreateInvoiceLineParams) (InvoiceLine, error)
+	// Writes many ledger entries at once; entry i is made of element i of every array.
+	CreateLedgerEntries(ctx context.Context, arg CreateLedgerEntriesParams) error
+	CreateLedgerEntry(ctx context.Context, arg CreateLedgerEntryParams) (LedgerEntry, error)
+	// sql/nat_mapping.sql
+	CreateNATMapping(ctx context.Context, arg CreateNATMappingParams) (NatMapping, error)
+	// sql/network_interface.sql
+	CreateNetworkInterface(ctx context.Context, arg CreateNetworkInterfaceParams) (NetworkInterface, error)
 	// sql/servers.sql
 	CreateNewServer(ctx context.Context, arg CreateNewServerParams) (Server, error)
+	CreatePrice(ctx context.Context, arg CreatePriceParams) (Price, error)
+	// Records a policy acting on a server; no row is returned if it already did in this status.
+	CreateReaperAction(ctx context.Context, arg CreateReaperActionParams) (ReaperAction, error)
+	// sql/reaper.sql
+	CreateReaperPolicy(ctx context.Context, arg CreateReaperPolicyParams) (ReaperPolicy, error)
+	// sql/reservation.sql
+	CreateReservation(ctx context.Context, arg CreateReservationParams) (Reservation, error)
+	// sql/spot.sql
+	CreateSpotPrice(ctx context.Context, arg CreateSpotPriceParams) (SpotPrice, error)
+	// sql/adjustment.sql
+	CreateVolumeTier(ctx context.Context, arg CreateVolumeTierParams) (VolumeTier, error)
 	DeallocateIPAddress(ctx context.Context, id pgtype.UUID) (IpAddress, error)
+	DeleteBudget(ctx context.Context, id pgtype.UUID) (int64, error)
+	DeleteNetworkInterface(ctx context.Context, id pgtype.UUID) error
+	DeleteReaperPolicy(ctx context.Context, id pgtype.UUID) (int64, error)
 	DeleteServer(ctx context.Context, id pgtype.UUID) error
-	EnforceLifecycleLogsLimit(ctx context.Context, id pgtype.UUID) error
-	GetAvailableIPForAllocation(ctx context.Context) (IpAddress, error)
-	GetIPAddressByServerID(ctx context.Context, serverID pgtype.UUID) (IpAddress, error)
+	// Draws the difference between the charges of the project now and what was drawn
+	// before; a negative difference, e.g. from a discount, is paid back.
+	DrawDownAccount(ctx context.Context, arg DrawDownAccountParams) (Account, error)
+	DrawDownCredit(ctx context.Context, arg DrawDownCreditParams) error
+	// sql/account.sql
+	GetAccount(ctx context.Context, project string) (Account, error)
+	GetActiveNATMappingByServerID(ctx context.Context, serverID pgtype.UUID) (NatMapping, error)
+	GetBudget(ctx context.Context, id pgtype.UUID) (Budget, error)
+	GetCurrentSpotPrice(ctx context.Context, arg GetCurrentSpotPriceParams) (SpotPrice, error)
+	GetInvoice(ctx context.Context, id pgtype.UUID) (Invoice, error)
+	GetLiveServerByAddress(ctx context.Context, address string) (Server, error)
+	GetLiveServerByHostname(ctx context.Context, hostname string) (Server, error)
+	GetNetworkInterface(ctx context.Context, arg GetNetworkInterfaceParams) (NetworkInterface, error)
+	GetNextDeviceIndex(ctx context.Context, serverID pgtype.UUID) (int32, error)
+	GetProject(ctx context.Context, name string) (Project, error)
+	GetReaperPolicy(ctx context.Context, id pgtype.UUID) (ReaperPolicy, error)
+	GetReservation(ctx context.Context, id pgtype.UUID) (Reservation, error)
 	GetServer(ctx context.Context, id pgtype.UUID) (Server, error)
-	GetServerLifecycleLogs(ctx context.Context, id pgtype.UUID) ([]byte, error)
+	// Telemetry of a server over [from, to) in buckets of step, starting at from.
+	GetTelemetrySeries(ctx context.Context, arg GetTelemetrySeriesParams) ([]GetTelemetrySeriesRow, error)
+	HostnameInUse(ctx context.Context, arg HostnameInUseParams) (bool, error)
+	ListAccountEvents(ctx context.Context, arg ListAccountEventsParams) ([]AccountEvent, error)
+	ListAccounts(ctx context.Context) ([]Account, error)
+	ListActiveNATMappingsByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]NatMapping, error)
+	ListActiveNATMappingsOfTerminatedServers(ctx context.Context) ([]NatMapping, error)
+	// sql/consistency.sql
+	// Live servers whose address is not the primary address of their primary interface,
+	// including servers left without one. Servers created after settled_before are
+	// skipped, as they may still be provisioning.
+	ListAddressMismatches(ctx context.Context, settledBefore pgtype.Timestamptz) ([]ListAddressMismatchesRow, error)
+	ListBudgetAlerts(ctx context.Context, arg ListBudgetAlertsParams) ([]BudgetAlert, error)
+	ListBudgets(ctx context.Context) ([]Budget, error)
+	ListCredits(ctx context.Context, arg ListCreditsParams) ([]Credit, error)
+	// Credits of a project with an amount left that overlap the period, soonest to
+	// expire first. Rows are locked so two invoices cannot spend the same credit.
+	ListCreditsInPeriodForUpdate(ctx context.Context, arg ListCreditsInPeriodForUpdateParams) ([]Credit, error)
+	ListCurrentSpotPrices(ctx context.Context, arg ListCurrentSpotPricesParams) ([]SpotPrice, error)
+	ListDiscounts(ctx context.Context) ([]Discount, error)
+	ListDiscountsInPeriod(ctx context.Context, arg ListDiscountsInPeriodParams) ([]Discount, error)
+	ListDueSpotInterruptions(ctx context.Context, due pgtype.Timestamptz) ([]Server, error)
+	ListEgressInRange(ctx context.Context, arg ListEgressInRangeParams) ([]ListEgressInRangeRow, error)
+	// sql/currency.sql
+	ListExchangeRates(ctx context.Context) ([]ExchangeRate, error)
+	ListIPAddressesByInterfaceID(ctx context.Context, interfaceID pgtype.UUID) ([]IpAddress, error)
+	ListIPAddressesByPool(ctx context.Context, poolID pgtype.UUID) ([]IpAddress, error)
+	ListIPAddressesByServerID(ctx context.Context, serverID pgtype.UUID) ([]IpAddress, error)
+	ListIPAddressesByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]ListIPAddressesByServerIDsRow, error)
+	ListIPAddressesOfTerminatedServers(ctx context.Context) ([]IpAddress, error)
+	// Pools holding allocated addresses.
+	ListIPPoolsInUse(ctx context.Context) ([]IpPool, error)
+	ListInvoiceLines(ctx context.Context, invoiceID pgtype.UUID) ([]InvoiceLine, error)
+	// Project periods from since on that are already invoiced.
+	ListInvoicedPeriods(ctx context.Context, since pgtype.Date) ([]ListInvoicedPeriodsRow, error)
+	ListInvoices(ctx context.Context, arg ListInvoicesParams) ([]Invoice, error)
+	ListLedgerEntriesByServerID(ctx context.Context, serverID pgtype.UUID) ([]LedgerEntry, error)
+	ListNATMappings(ctx context.Context, arg ListNATMappingsParams) ([]NatMapping, error)
+	ListNetworkInterfacesByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]NetworkInterface, error)
+	// Addresses reserved for a server that was never bound to one.
+	ListOrphanedIPAddresses(ctx context.Context, settledBefore pgtype.Timestamptz) ([]IpAddress, error)
+	// sql/invoices.sql
+	// Project periods that ended before @before and have ledger entries but no invoice yet.
+	ListPeriodsToClose(ctx context.Context, before pgtype.Date) ([]ListPeriodsToCloseRow, error)
+	// sql/pricing.sql
+	ListPrices(ctx context.Context) ([]Price, error)
+	ListReaperActions(ctx context.Context, arg ListReaperActionsParams) ([]ReaperAction, error)
+	// Servers the policy applies to at the given instant, oldest status first. Servers
+	// carrying the exempt tag, and servers the policy already acted on in their
+	// current status, are left out; servers without telemetry are never idle. Only
+	// servers stopped through the API or by the reaper count as stopped: a stop by
+	// budget enforcement, a spot interruption or another daemon is not the choice of
+	// the owner.
+	ListReaperCandidates(ctx context.Context, arg ListReaperCandidatesParams) ([]Server, error)
+	ListReaperPolicies(ctx context.Context) ([]ReaperPolicy, error)
+	ListReservations(ctx context.Context, arg ListReservationsParams) ([]Reservation, error)
+	// Reservations of the projects with hours left that are in effect at some time
+	// in [since, until), soonest to expire first. Rows are locked so concurrent
+	// draw-downs cannot overspend them.
+	ListReservationsForDrawDown(ctx context.Context, arg ListReservationsForDrawDownParams) ([]Reservation, error)
+	ListResourceSegmentsByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]ResourceSegment, error)
+	ListResourceSegmentsInRange(ctx context.Context, arg ListResourceSegmentsInRangeParams) ([]ListResourceSegmentsInRangeRow, error)
+	ListRunningServerIDs(ctx context.Context) ([]pgtype.UUID, error)
+	ListRunningServersInScope(ctx context.Context, arg ListRunningServersInScopeParams) ([]Server, error)
+	// Events of a server, newest first, optionally of some types only and within
+	// [from, to).
+	ListServerEvents(ctx context.Context, arg ListServerEventsParams) ([]ServerEvent, error)
+	// sql/recommendation.sql
+	// Running time and CPU telemetry over [from_time, to_time) of the running servers
+	// that existed for all of it, optionally of one project only.
+	ListServerUtilization(ctx context.Context, arg ListServerUtilizationParams) ([]ListServerUtilizationRow, error)
 	ListServers(ctx context.Context, status string) ([]Server, error)
+	ListServersByProjectAndStatus(ctx context.Context, arg ListServersByProjectAndStatusParams) ([]Server, error)
+	// Servers that may have usage after @since, the live ones and those terminated
+	// since, optionally only of one project, region or tag value.
+	ListServersInScope(ctx context.Context, arg ListServersInScopeParams) ([]Server, error)
+	// The spot prices in effect from @since on: the latest price before it per
+	// market, and every later one.
+	ListSpotPricesSince(ctx context.Context, since pgtype.Timestamptz) ([]SpotPrice, error)
+	// Servers provisioning since before stuck_before that never got their primary
+	// address, i.e. whose provisioning did not complete.
+	ListStuckProvisioningServers(ctx context.Context, stuckBefore pgtype.Timestamptz) ([]Server, error)
+	// sql/watchdog.sql
+	// Servers that have been in status since before stuck_before. A provisioning
+	// server whose primary interface holds its primary address has finished
+	// provisioning and is only waiting to be started, so it is not stuck.
+	ListStuckServers(ctx context.Context, arg ListStuckServersParams) ([]Server, error)
+	// Egress before the current period that is not in the ledger yet, per server and period.
+	ListUnbilledEgress(ctx context.Context, currentPeriodStart pgtype.Timestamptz) ([]ListUnbilledEgressRow, error)
+	// Same rules as ListUnbilledUsageSegments.
+	ListUnbilledResourceSegments(ctx context.Context, currentPeriodStart pgtype.Timestamptz) ([]ListUnbilledResourceSegmentsRow, error)
+	// Servers of a project that may have usage not in the ledger yet: the live ones,
+	// and those terminated since the given instant.
+	ListUnbilledServerIDsByProject(ctx context.Context, arg ListUnbilledServerIDsByProjectParams) ([]pgtype.UUID, error)
+	// sql/ledger.sql
+	// Segments with usage that can be written to the ledger: closed segments not
+	// billed to their end, and open segments with usage before the current period.
+	ListUnbilledUsageSegments(ctx context.Context, currentPeriodStart pgtype.Timestamptz) ([]ListUnbilledUsageSegmentsRow, error)
+	ListUsageSegmentsByServerID(ctx context.Context, serverID pgtype.UUID) ([]UsageSegment, error)
+	ListUsageSegmentsByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]ListUsageSegmentsByServerIDsRow, error)
+	// sql/export.sql
+	// Segments overlapping [range_start, range_end), optionally only of one project.
+	ListUsageSegmentsInRange(ctx context.Context, arg ListUsageSegmentsInRangeParams) ([]ListUsageSegmentsInRangeRow, error)
+	ListVolumeTiers(ctx context.Context) ([]VolumeTier, error)
+	// Marks what ListUnbilledEgress returns as billed.
+	MarkEgressBilled(ctx context.Context, currentPeriodStart pgtype.Timestamptz) error
+	// Moves a server that is still in status to the error state.
+	MarkServerStuck(ctx context.Context, arg MarkServerStuckParams) (Server, error)
+	// Gives running spot servers of a market whose bid is below @price their
+	// interruption notice, once.
+	NoticeOutbidSpotServers(ctx context.Context, arg NoticeOutbidSpotServersParams) ([]Server, error)
+	// sql/resource_segments.sql
+	OpenResourceSegment(ctx context.Context, arg OpenResourceSegmentParams) (ResourceSegment, error)
+	// sql/usage_segments.sql
+	OpenUsageSegment(ctx context.Context, arg OpenUsageSegmentParams) (UsageSegment, error)
+	PruneServerEvents(ctx context.Context, before pgtype.Timestamptz) (int64, error)
+	PruneTelemetry(ctx context.Context, before pgtype.Timestamptz) (int64, error)
+	// Simulated traffic: every running server sends mean_gb on average, give or
+	// take half of it.
+	RecordEgress(ctx context.Context, meanGb float64) error
+	// sql/server_event.sql
+	// Appends an event to a server's history under its next sequence number.
+	RecordServerEvent(ctx context.Context, arg RecordServerEventParams) (ServerEvent, error)
+	// sql/telemetry.sql
+	// Folds samples, one per server, into the minute they were taken in.
+	RecordTelemetry(ctx context.Context, arg RecordTelemetryParams) error
+	// Counts a failed retry of a stuck server's transition and restarts its timeout.
+	RecordTransitionAttempt(ctx context.Context, arg RecordTransitionAttemptParams) (Server, error)
+	// servers.hourly_cost caches the price in effect now for each live on-demand
+	// server; a region's own price wins over the all-regions one. The spot market
+	// daemon keeps it current for spot servers.
+	RefreshServerHourlyCosts(ctx context.Context) error
+	RefreshServerUptimes(ctx context.Context) error
+	// Returns every address to its pool. Rows are kept rather than truncated, so
+	// the NAT mappings referencing them survive as history.
+	ReleaseAllIPAddresses(ctx context.Context) error
+	ReleaseAllNATMappings(ctx context.Context) error
+	ReleaseNATMapping(ctx context.Context, id pgtype.UUID) error
+	ReleaseNATMappingsByServerID(ctx context.Context, serverID pgtype.UUID) error
+	// sql/ip_address.sql
+	// Creates the row for an address on first use, or reclaims a released one.
+	// Returns no row when the address is already held in the pool, e.g. by another replica.
+	ReserveIPAddress(ctx context.Context, arg ReserveIPAddressParams) (IpAddress, error)
+	ResizeServer(ctx context.Context, arg ResizeServerParams) (Server, error)
+	ResumeAccount(ctx context.Context, project string) error
+	// Seeds a rate from the exchange rate file, unless that version already exists.
+	SeedExchangeRate(ctx context.Context, arg SeedExchangeRateParams) error
+	// Seeds the all-regions price of a type, unless the catalog already has one.
+	SeedPrice(ctx context.Context, arg SeedPriceParams) error
 	SelectAllServers(ctx context.Context) ([]Server, error)
+	SetAccountLowBalanceWarned(ctx context.Context, project string) error
+	SetBudgetAlertWebhookStatus(ctx context.Context, arg SetBudgetAlertWebhookStatusParams) error
+	// The server ends the session, releasing its locks, once it has been idle this long.
+	SetIdleSessionTimeout(ctx context.Context, timeout string) error
+	SetProjectCurrency(ctx context.Context, arg SetProjectCurrencyParams) (Project, error)
+	SetReaperActionOutcome(ctx context.Context, arg SetReaperActionOutcomeParams) error
+	SetReservationsHoursUsed(ctx context.Context, arg SetReservationsHoursUsedParams) error
+	SetResourceSegmentsBilledUntil(ctx context.Context, arg SetResourceSegmentsBilledUntilParams) error
+	SetServerAddress(ctx context.Context, arg SetServerAddressParams) (Server, error)
+	SetSpotServerHourlyCosts(ctx context.Context, arg SetSpotServerHourlyCostsParams) error
+	SetUsageSegmentsBilledUntil(ctx context.Context, arg SetUsageSegmentsBilledUntilParams) error
+	SumLedgerChargesByServerIDs(ctx context.Context, serverIds []pgtype.UUID) ([]SumLedgerChargesByServerIDsRow, error)
+	// Like SumLedgerChargesByServerIDs, but of the usage after @since only: an entry
+	// spanning it counts for the share of its usage time after it.
+	SumLedgerChargesByServerIDsSince(ctx context.Context, arg SumLedgerChargesByServerIDsSinceParams) ([]SumLedgerChargesByServerIDsSinceRow, error)
+	// Everything written to the ledger for a project, adjustments included.
+	SumProjectLedger(ctx context.Context, project string) (float64, error)
+	SumRemainingReservationHours(ctx context.Context, arg SumRemainingReservationHoursParams) (float64, error)
+	// Egress not billed yet from the hour of @since on.
+	SumUnbilledEgressByServerIDs(ctx context.Context, arg SumUnbilledEgressByServerIDsParams) ([]SumUnbilledEgressByServerIDsRow, error)
+	// Charges of one project period per charge type, server type and region, the
+	// base the adjustments are computed on.
+	SummarizeLedgerCharges(ctx context.Context, arg SummarizeLedgerChargesParams) ([]SummarizeLedgerChargesRow, error)
+	// Ledger entries of one project period grouped into invoice lines, in USD. Amounts
+	// are rounded to cents once converted to the invoice currency.
+	SummarizeLedgerPeriod(ctx context.Context, arg SummarizeLedgerPeriodParams) ([]SummarizeLedgerPeriodRow, error)
+	SuspendAccount(ctx context.Context, project string) error
+	// Terminates every live server, recording the status change in its events.
 	TerminateAllServers(ctx context.Context) error
-	TruncateIPAddresses(ctx context.Context) error
+	// Adds to the balance of a project, opening its account on the first top-up with
+	// the charges of the project so far as already drawn. The low-balance warning is
+	// rearmed once the balance is above the threshold again.
+	TopUpAccount(ctx context.Context, arg TopUpAccountParams) (Account, error)
 	TruncateServers(ctx context.Context) error
+	// sql/leader.sql
+	// Session-level locks; they are held until released or the session ends.
+	TryAdvisoryLock(ctx context.Context, lockKey int64) (bool, error)
+	UpdateServerName(ctx context.Context, arg UpdateServerNameParams) (Server, error)
 	UpdateServerStatus(ctx context.Context, arg UpdateServerStatusParams) (Server, error)
-	UpdateServerUptime(ctx context.Context, arg UpdateServerUptimeParams) (Server, error)
+	UpsertIPPool(ctx context.Context, arg UpsertIPPoolParams) (IpPool, error)
 }
 
 var _ Querier = (*Queries)(nil)
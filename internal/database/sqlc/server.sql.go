@@ -11,64 +11,80 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
-const appendServerLifecycleLog = `-- name: AppendServerLifecycleLog :one
-UPDATE servers
-SET lifecycle_logs = jsonb_build_array($1::jsonb) || lifecycle_logs
-WHERE id = $2
-RETURNING lifecycle_logs
-`
-
-type AppendServerLifecycleLogParams struct {
-	Column1 []byte      `json:"column_1"`
-	ID      pgtype.UUID `json:"id"`
-}
-
-func (q *Queries) AppendServerLifecycleLog(ctx context.Context, arg AppendServerLifecycleLogParams) ([]byte, error) {
-	row := q.db.QueryRow(ctx, appendServerLifecycleLog, arg.Column1, arg.ID)
-	var lifecycle_logs []byte
-	err := row.Scan(&lifecycle_logs)
-	return lifecycle_logs, err
-}
-
 const createNewServer = `-- name: CreateNewServer :one
 
-INSERT INTO servers (name, region, status, type, address, hourly_cost)
-VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at
+INSERT INTO servers (name, hostname, region, project, status, type, address, hourly_cost, billing_model,
+    purchase_option, spot_max_price, interruption_behavior, assign_public_ip, tags, user_data, disk_gb)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+RETURNING id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at
 `
 
 type CreateNewServerParams struct {
-	Name       string  `json:"name"`
-	Region     string  `json:"region"`
-	Status     string  `json:"status"`
-	Type       string  `json:"type"`
-	Address    string  `json:"address"`
-	HourlyCost float64 `json:"hourly_cost"`
+	Name                 string  `json:"name"`
+	Hostname             string  `json:"hostname"`
+	Region               string  `json:"region"`
+	Project              string  `json:"project"`
+	Status               string  `json:"status"`
+	Type                 string  `json:"type"`
+	Address              string  `json:"address"`
+	HourlyCost           float64 `json:"hourly_cost"`
+	BillingModel         string  `json:"billing_model"`
+	PurchaseOption       string  `json:"purchase_option"`
+	SpotMaxPrice         float64 `json:"spot_max_price"`
+	InterruptionBehavior string  `json:"interruption_behavior"`
+	AssignPublicIp       bool    `json:"assign_public_ip"`
+	Tags                 []byte  `json:"tags"`
+	UserData             string  `json:"user_data"`
+	DiskGb               int32   `json:"disk_gb"`
 }
 
 // sql/servers.sql
 func (q *Queries) CreateNewServer(ctx context.Context, arg CreateNewServerParams) (Server, error) {
 	row := q.db.QueryRow(ctx, createNewServer,
 		arg.Name,
+		arg.Hostname,
 		arg.Region,
+		arg.Project,
 		arg.Status,
 		arg.Type,
 		arg.Address,
 		arg.HourlyCost,
+		arg.BillingModel,
+		arg.PurchaseOption,
+		arg.SpotMaxPrice,
+		arg.InterruptionBehavior,
+		arg.AssignPublicIp,
+		arg.Tags,
+		arg.UserData,
+		arg.DiskGb,
 	)
 	var i Server
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Hostname,
 		&i.Region,
+		&i.Project,
 		&i.Status,
 		&i.Address,
 		&i.Type,
+		&i.DiskGb,
 		&i.ProvisionedAt,
 		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
 		&i.UptimeSeconds,
 		&i.HourlyCost,
-		&i.LifecycleLogs,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
@@ -84,24 +100,90 @@ func (q *Queries) DeleteServer(ctx context.Context, id pgtype.UUID) error {
 	return err
 }
 
-const enforceLifecycleLogsLimit = `-- name: EnforceLifecycleLogsLimit :exec
-UPDATE servers
-SET lifecycle_logs =
-    CASE
-        WHEN jsonb_array_length(lifecycle_logs) > 100 
-            THEN jsonb_path_query_array(lifecycle_logs, '$[0 to 14]') 
-        ELSE lifecycle_logs
-    END
-WHERE id = $1
+const getLiveServerByAddress = `-- name: GetLiveServerByAddress :one
+SELECT s.id, s.name, s.hostname, s.region, s.project, s.status, s.address, s.type, s.disk_gb, s.provisioned_at, s.last_status_update, s.last_status_actor, s.stuck_since, s.transition_attempts, s.uptime_seconds, s.hourly_cost, s.billing_model, s.purchase_option, s.spot_max_price, s.interruption_behavior, s.interruption_notice_at, s.assign_public_ip, s.tags, s.user_data, s.event_sequence, s.created_at, s.updated_at FROM servers s
+JOIN ip_addresses ia ON ia.server_id = s.id
+WHERE ia.address = $1 AND ia.is_allocated AND s.status <> 'terminated'
+LIMIT 1
 `
 
-func (q *Queries) EnforceLifecycleLogsLimit(ctx context.Context, id pgtype.UUID) error {
-	_, err := q.db.Exec(ctx, enforceLifecycleLogsLimit, id)
-	return err
+func (q *Queries) GetLiveServerByAddress(ctx context.Context, address string) (Server, error) {
+	row := q.db.QueryRow(ctx, getLiveServerByAddress, address)
+	var i Server
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Hostname,
+		&i.Region,
+		&i.Project,
+		&i.Status,
+		&i.Address,
+		&i.Type,
+		&i.DiskGb,
+		&i.ProvisionedAt,
+		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
+		&i.UptimeSeconds,
+		&i.HourlyCost,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getLiveServerByHostname = `-- name: GetLiveServerByHostname :one
+SELECT id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at FROM servers
+WHERE hostname = $1 AND status <> 'terminated'
+`
+
+func (q *Queries) GetLiveServerByHostname(ctx context.Context, hostname string) (Server, error) {
+	row := q.db.QueryRow(ctx, getLiveServerByHostname, hostname)
+	var i Server
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Hostname,
+		&i.Region,
+		&i.Project,
+		&i.Status,
+		&i.Address,
+		&i.Type,
+		&i.DiskGb,
+		&i.ProvisionedAt,
+		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
+		&i.UptimeSeconds,
+		&i.HourlyCost,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
 }
 
 const getServer = `-- name: GetServer :one
-SELECT id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at FROM servers WHERE id = $1
+SELECT id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at FROM servers WHERE id = $1
 `
 
 func (q *Queries) GetServer(ctx context.Context, id pgtype.UUID) (Server, error) {
@@ -110,34 +192,56 @@ func (q *Queries) GetServer(ctx context.Context, id pgtype.UUID) (Server, error)
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Hostname,
 		&i.Region,
+		&i.Project,
 		&i.Status,
 		&i.Address,
 		&i.Type,
+		&i.DiskGb,
 		&i.ProvisionedAt,
 		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
 		&i.UptimeSeconds,
 		&i.HourlyCost,
-		&i.LifecycleLogs,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const getServerLifecycleLogs = `-- name: GetServerLifecycleLogs :one
-SELECT lifecycle_logs FROM servers WHERE id = $1
+const hostnameInUse = `-- name: HostnameInUse :one
+SELECT EXISTS (
+    SELECT 1 FROM servers
+    WHERE hostname = $1 AND id <> $2 AND status <> 'terminated'
+)
 `
 
-func (q *Queries) GetServerLifecycleLogs(ctx context.Context, id pgtype.UUID) ([]byte, error) {
-	row := q.db.QueryRow(ctx, getServerLifecycleLogs, id)
-	var lifecycle_logs []byte
-	err := row.Scan(&lifecycle_logs)
-	return lifecycle_logs, err
+type HostnameInUseParams struct {
+	Hostname string      `json:"hostname"`
+	ID       pgtype.UUID `json:"id"`
+}
+
+func (q *Queries) HostnameInUse(ctx context.Context, arg HostnameInUseParams) (bool, error) {
+	row := q.db.QueryRow(ctx, hostnameInUse, arg.Hostname, arg.ID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
 }
 
 const listServers = `-- name: ListServers :many
-SELECT id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at FROM servers
+SELECT id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at FROM servers
 WHERE status = $1
 ORDER BY created_at DESC
 `
@@ -154,15 +258,29 @@ func (q *Queries) ListServers(ctx context.Context, status string) ([]Server, err
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
+			&i.Hostname,
 			&i.Region,
+			&i.Project,
 			&i.Status,
 			&i.Address,
 			&i.Type,
+			&i.DiskGb,
 			&i.ProvisionedAt,
 			&i.LastStatusUpdate,
+			&i.LastStatusActor,
+			&i.StuckSince,
+			&i.TransitionAttempts,
 			&i.UptimeSeconds,
 			&i.HourlyCost,
-			&i.LifecycleLogs,
+			&i.BillingModel,
+			&i.PurchaseOption,
+			&i.SpotMaxPrice,
+			&i.InterruptionBehavior,
+			&i.InterruptionNoticeAt,
+			&i.AssignPublicIp,
+			&i.Tags,
+			&i.UserData,
+			&i.EventSequence,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -176,8 +294,24 @@ func (q *Queries) ListServers(ctx context.Context, status string) ([]Server, err
 	return items, nil
 }
 
+const refreshServerUptimes = `-- name: RefreshServerUptimes :exec
+UPDATE servers s
+SET uptime_seconds = u.uptime_seconds, updated_at = NOW()
+FROM (
+    SELECT server_id, SUM(EXTRACT(EPOCH FROM (COALESCE(ended_at, NOW()) - started_at)))::BIGINT AS uptime_seconds
+    FROM usage_segments
+    GROUP BY server_id
+) u
+WHERE u.server_id = s.id AND s.uptime_seconds <> u.uptime_seconds
+`
+
+func (q *Queries) RefreshServerUptimes(ctx context.Context) error {
+	_, err := q.db.Exec(ctx, refreshServerUptimes)
+	return err
+}
+
 const selectAllServers = `-- name: SelectAllServers :many
-SELECT id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at FROM servers
+SELECT id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at FROM servers
 `
 
 func (q *Queries) SelectAllServers(ctx context.Context) ([]Server, error) {
@@ -192,15 +326,29 @@ func (q *Queries) SelectAllServers(ctx context.Context) ([]Server, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
+			&i.Hostname,
 			&i.Region,
+			&i.Project,
 			&i.Status,
 			&i.Address,
 			&i.Type,
+			&i.DiskGb,
 			&i.ProvisionedAt,
 			&i.LastStatusUpdate,
+			&i.LastStatusActor,
+			&i.StuckSince,
+			&i.TransitionAttempts,
 			&i.UptimeSeconds,
 			&i.HourlyCost,
-			&i.LifecycleLogs,
+			&i.BillingModel,
+			&i.PurchaseOption,
+			&i.SpotMaxPrice,
+			&i.InterruptionBehavior,
+			&i.InterruptionNoticeAt,
+			&i.AssignPublicIp,
+			&i.Tags,
+			&i.UserData,
+			&i.EventSequence,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -215,17 +363,23 @@ func (q *Queries) SelectAllServers(ctx context.Context) ([]Server, error) {
 }
 
 const terminateAllServers = `-- name: TerminateAllServers :exec
-UPDATE servers
-SET status = 'terminated',
-    last_status_update = NOW(),
-    lifecycle_logs = lifecycle_logs || jsonb_build_object(
-        'timestamp', NOW(),
-        'event', 'System Reset: Server Terminated by System',
-        'request_id', 'system-reset'
-    )::jsonb
-WHERE status != 'terminated'
+WITH terminated AS (
+    UPDATE servers s
+    SET status = 'terminated',
+        last_status_update = NOW(),
+        last_status_actor = 'system',
+        event_sequence = s.event_sequence + 1
+    FROM servers previous
+    WHERE previous.id = s.id AND s.status != 'terminated'
+    RETURNING s.id, s.event_sequence, previous.status AS from_status
+)
+INSERT INTO server_events (server_id, sequence, type, from_status, to_status, actor, request_id, payload)
+SELECT id, event_sequence, 'status_change', from_status, 'terminated', 'system', 'system-reset',
+    jsonb_build_object('message', 'System reset: server terminated')
+FROM terminated
 `
 
+// Terminates every live server, recording the status change in its events.
 func (q *Queries) TerminateAllServers(ctx context.Context) error {
 	_, err := q.db.Exec(ctx, terminateAllServers)
 	return err
@@ -240,66 +394,96 @@ func (q *Queries) TruncateServers(ctx context.Context) error {
 	return err
 }
 
-const updateServerStatus = `-- name: UpdateServerStatus :one
+const updateServerName = `-- name: UpdateServerName :one
 UPDATE servers
-SET status = $1, last_status_update = NOW()
-WHERE id = $2
-RETURNING id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at
+SET name = $1, hostname = $2, updated_at = NOW()
+WHERE id = $3
+RETURNING id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at
 `
 
-type UpdateServerStatusParams struct {
-	Status string      `json:"status"`
-	ID     pgtype.UUID `json:"id"`
+type UpdateServerNameParams struct {
+	Name     string      `json:"name"`
+	Hostname string      `json:"hostname"`
+	ID       pgtype.UUID `json:"id"`
 }
 
-func (q *Queries) UpdateServerStatus(ctx context.Context, arg UpdateServerStatusParams) (Server, error) {
-	row := q.db.QueryRow(ctx, updateServerStatus, arg.Status, arg.ID)
+func (q *Queries) UpdateServerName(ctx context.Context, arg UpdateServerNameParams) (Server, error) {
+	row := q.db.QueryRow(ctx, updateServerName, arg.Name, arg.Hostname, arg.ID)
 	var i Server
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Hostname,
 		&i.Region,
+		&i.Project,
 		&i.Status,
 		&i.Address,
 		&i.Type,
+		&i.DiskGb,
 		&i.ProvisionedAt,
 		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
 		&i.UptimeSeconds,
 		&i.HourlyCost,
-		&i.LifecycleLogs,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const updateServerUptime = `-- name: UpdateServerUptime :one
+const updateServerStatus = `-- name: UpdateServerStatus :one
 UPDATE servers
-SET uptime_seconds = $1, updated_at = NOW()
+SET status = $1, last_status_update = NOW(), last_status_actor = $3, stuck_since = NULL, transition_attempts = 0
 WHERE id = $2
-RETURNING id, name, region, status, address, type, provisioned_at, last_status_update, uptime_seconds, hourly_cost, lifecycle_logs, created_at, updated_at
+RETURNING id, name, hostname, region, project, status, address, type, disk_gb, provisioned_at, last_status_update, last_status_actor, stuck_since, transition_attempts, uptime_seconds, hourly_cost, billing_model, purchase_option, spot_max_price, interruption_behavior, interruption_notice_at, assign_public_ip, tags, user_data, event_sequence, created_at, updated_at
 `
 
-type UpdateServerUptimeParams struct {
-	UptimeSeconds int64       `json:"uptime_seconds"`
-	ID            pgtype.UUID `json:"id"`
+type UpdateServerStatusParams struct {
+	Status          string      `json:"status"`
+	ID              pgtype.UUID `json:"id"`
+	LastStatusActor string      `json:"last_status_actor"`
 }
 
-func (q *Queries) UpdateServerUptime(ctx context.Context, arg UpdateServerUptimeParams) (Server, error) {
-	row := q.db.QueryRow(ctx, updateServerUptime, arg.UptimeSeconds, arg.ID)
+func (q *Queries) UpdateServerStatus(ctx context.Context, arg UpdateServerStatusParams) (Server, error) {
+	row := q.db.QueryRow(ctx, updateServerStatus, arg.Status, arg.ID, arg.LastStatusActor)
 	var i Server
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Hostname,
 		&i.Region,
+		&i.Project,
 		&i.Status,
 		&i.Address,
 		&i.Type,
+		&i.DiskGb,
 		&i.ProvisionedAt,
 		&i.LastStatusUpdate,
+		&i.LastStatusActor,
+		&i.StuckSince,
+		&i.TransitionAttempts,
 		&i.UptimeSeconds,
 		&i.HourlyCost,
-		&i.LifecycleLogs,
+		&i.BillingModel,
+		&i.PurchaseOption,
+		&i.SpotMaxPrice,
+		&i.InterruptionBehavior,
+		&i.InterruptionNoticeAt,
+		&i.AssignPublicIp,
+		&i.Tags,
+		&i.UserData,
+		&i.EventSequence,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
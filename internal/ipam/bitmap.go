@@ -0,0 +1,97 @@
+package ipam
+
+import "math/bits"
+
+const fullWord = ^uint64(0)
+
+// bitmap is a fixed size bit set, one bit per address offset in a range.
+// It is hierarchical: levels[0] holds the bits themselves and each bit of
+// levels[i+1] is set when the matching word of levels[i] is full. Finding the
+// next clear bit therefore costs one word per level instead of a linear scan,
+// which keeps allocation latency flat as pools grow.
+type bitmap struct {
+	levels [][]uint64
+}
+
+// newBitmap creates a bitmap holding n bits. Padding bits past n are set so
+// that partially used trailing words can still be reported as full.
+func newBitmap(n uint32) *bitmap {
+	b := &bitmap{}
+	count := uint64(n)
+	for {
+		words := make([]uint64, (count+63)/64)
+		if rem := count % 64; rem != 0 {
+			words[len(words)-1] = fullWord << rem
+		}
+		b.levels = append(b.levels, words)
+		if len(words) == 1 {
+			break
+		}
+		count = uint64(len(words))
+	}
+	return b
+}
+
+func (b *bitmap) set(i uint32) {
+	for _, words := range b.levels {
+		w := i >> 6
+		words[w] |= 1 << (i & 63)
+		if words[w] != fullWord {
+			return
+		}
+		i = w
+	}
+}
+
+func (b *bitmap) clear(i uint32) {
+	for _, words := range b.levels {
+		w := i >> 6
+		wasFull := words[w] == fullWord
+		words[w] &^= 1 << (i & 63)
+		if !wasFull {
+			return
+		}
+		i = w
+	}
+}
+
+func (b *bitmap) test(i uint32) bool {
+	return b.levels[0][i>>6]&(1<<(i&63)) != 0
+}
+
+// nextClear returns the first clear bit in [from, limit).
+func (b *bitmap) nextClear(from, limit uint32) (uint32, bool) {
+	if from >= limit {
+		return 0, false
+	}
+
+	// Climb until a level has a clear bit at or after pos.
+	pos, level := from, 0
+	for {
+		words := b.levels[level]
+		w := pos >> 6
+		if int(w) >= len(words) {
+			return 0, false
+		}
+		if word := ^words[w] >> (pos & 63); word != 0 {
+			pos += uint32(bits.TrailingZeros64(word))
+			break
+		}
+		if level == len(b.levels)-1 {
+			return 0, false
+		}
+		pos = w + 1
+		level++
+	}
+
+	// Descend into the first non-full word at each lower level.
+	for level > 0 {
+		level--
+		pos = pos<<6 + uint32(bits.TrailingZeros64(^b.levels[level][pos]))
+	}
+
+	if pos >= limit {
+		return 0, false
+	}
+	return pos, true
+}
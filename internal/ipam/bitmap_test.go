@@ -0,0 +1,163 @@
+package ipam
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+// naiveNextClear is the linear scan nextClear replaces.
+func naiveNextClear(bits []bool, from, limit uint32) (uint32, bool) {
+	for i := from; i < limit; i++ {
+		if !bits[i] {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func TestBitmapNextClear(t *testing.T) {
+	const n = 1 << 18 // 4096 words of bits under 64 summary words and a top word
+	tests := []struct {
+		name     string
+		set      [][2]uint32 // [from, to) ranges of set bits
+		from     uint32
+		limit    uint32
+		want     uint32
+		wantFree bool
+	}{
+		{name: "empty", from: 0, limit: n, want: 0, wantFree: true},
+		{name: "from inside a word", from: 70, limit: n, want: 70, wantFree: true},
+		{name: "first word full", set: [][2]uint32{{0, 64}}, from: 0, limit: n, want: 64, wantFree: true},
+		{name: "clear bit in the middle of a word", set: [][2]uint32{{0, 100}, {101, 200}}, from: 0, limit: n, want: 100, wantFree: true},
+		{name: "across a summary word", set: [][2]uint32{{0, 64*64 + 3}}, from: 5, limit: n, want: 64*64 + 3, wantFree: true},
+		{name: "across summary words of both levels", set: [][2]uint32{{0, 3*64*64 + 5}}, from: 0, limit: n, want: 3*64*64 + 5, wantFree: true},
+		{name: "skips full words after from", set: [][2]uint32{{1000, 9000}}, from: 1000, limit: n, want: 9000, wantFree: true},
+		{name: "before from is ignored", set: [][2]uint32{{10, 20}}, from: 15, limit: n, want: 20, wantFree: true},
+		{name: "result at limit", set: [][2]uint32{{0, 128}}, from: 0, limit: 128, wantFree: false},
+		{name: "from at limit", from: 64, limit: 64, wantFree: false},
+		{name: "full", set: [][2]uint32{{0, n}}, from: 0, limit: n, wantFree: false},
+		{name: "last bit", set: [][2]uint32{{0, n - 1}}, from: 0, limit: n, want: n - 1, wantFree: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBitmap(n)
+			for _, r := range tt.set {
+				for i := r[0]; i < r[1]; i++ {
+					b.set(i)
+				}
+			}
+			got, ok := b.nextClear(tt.from, tt.limit)
+			if ok != tt.wantFree || (ok && got != tt.want) {
+				t.Errorf("nextClear(%d, %d) = %d, %v; want %d, %v", tt.from, tt.limit, got, ok, tt.want, tt.wantFree)
+			}
+		})
+	}
+}
+
+// TestBitmapSetClear checks that set and clear keep every summary level in step
+// with the bits, whatever the size, by comparing nextClear with a linear scan
+// after each change.
+func TestBitmapSetClear(t *testing.T) {
+	for _, n := range []uint32{1, 2, 63, 64, 65, 4095, 4096, 4097, 1<<18 + 3} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			b := newBitmap(n)
+			bits := make([]bool, n)
+			rng := rand.New(rand.NewPCG(1, uint64(n)))
+			check := func() {
+				t.Helper()
+				from := rng.Uint32N(n)
+				got, ok := b.nextClear(from, n)
+				want, wantOK := naiveNextClear(bits, from, n)
+				if ok != wantOK || got != want {
+					t.Fatalf("nextClear(%d, %d) = %d, %v; want %d, %v", from, n, got, ok, want, wantOK)
+				}
+			}
+
+			// Fill completely, so every summary bit is set, then empty again
+			for i := uint32(0); i < n; i++ {
+				b.set(i)
+				bits[i] = true
+			}
+			if _, ok := b.nextClear(0, n); ok {
+				t.Fatalf("full bitmap has a clear bit")
+			}
+			for i := uint32(0); i < n; i++ {
+				b.clear(i)
+				bits[i] = false
+				if got, ok := b.nextClear(0, n); !ok || got != i {
+					t.Fatalf("after clearing %d, nextClear(0, %d) = %d, %v; want %d, true", i, n, got, ok, i)
+				}
+				b.set(i)
+				bits[i] = true
+			}
+
+			for i := 0; i < 2000; i++ {
+				offset := rng.Uint32N(n)
+				if rng.IntN(2) == 0 {
+					b.set(offset)
+					bits[offset] = true
+				} else {
+					b.clear(offset)
+					bits[offset] = false
+				}
+				if b.test(offset) != bits[offset] {
+					t.Fatalf("test(%d) = %v; want %v", offset, b.test(offset), bits[offset])
+				}
+				check()
+			}
+		})
+	}
+}
+
+// BenchmarkAllocate measures allocation latency for every strategy on pools from
+// /24 up to /8. Each pool is first filled to 90%, then every iteration releases a
+// random address and allocates a new one, which is the steady state of a
+// long-running simulation.
+func BenchmarkAllocate(b *testing.B) {
+	prefixes := []string{"10.0.0.0/24", "10.0.0.0/20", "10.0.0.0/16", "10.0.0.0/12", "10.0.0.0/8"}
+	for _, strategyName := range []string{StrategySequential, StrategyRandom, StrategyLRU} {
+		for _, cidr := range prefixes {
+			prefix := netip.MustParsePrefix(cidr)
+			b.Run(fmt.Sprintf("%s/size=%d", strategyName, 1<<(32-prefix.Bits())), func(b *testing.B) {
+				benchmarkChurn(b, cidr, strategyName, 0.9)
+			})
+		}
+	}
+}
+
+func benchmarkChurn(b *testing.B, cidr string, strategyName string, fill float64) {
+	strategy, err := NewStrategy(strategyName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	addrs, err := NewRange(cidr, nil, strategy)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	target := int(float64(addrs.Size()) * fill)
+	held := make([]netip.Addr, 0, target)
+	for len(held) < target {
+		addr, err := addrs.Allocate()
+		if err != nil {
+			b.Fatal(err)
+		}
+		held = append(held, addr)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		j := rand.IntN(len(held))
+		if err := addrs.Release(held[j]); err != nil {
+			b.Fatal(err)
+		}
+		addr, err := addrs.Allocate()
+		if err != nil {
+			b.Fatal(err)
+		}
+		held[j] = addr
+	}
+}
@@ -0,0 +1,208 @@
+package ipam
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+)
+
+// MinPrefixBits is the largest block (smallest prefix length) a Range accepts.
+// A /8 needs a 2 MiB bitmap, which is the most we are willing to keep in memory per pool.
+const MinPrefixBits = 8
+
+var (
+	// ErrExhausted is returned when a range has no free addresses left.
+	ErrExhausted = errors.New("ip range exhausted")
+	// ErrOutOfRange is returned for addresses that do not belong to the range.
+	ErrOutOfRange = errors.New("address is outside of the range")
+)
+
+// Range is a compact, on-demand view of an IPv4 CIDR block.
+// Instead of one database row per address it keeps a bitmap of allocated offsets,
+// so memory is one bit per address and allocating does not depend on the block size.
+// Range is not safe for concurrent use; callers serialise access.
+type Range struct {
+	prefix    netip.Prefix
+	base      uint32
+	size      uint32
+	reserved  uint32
+	used      uint32
+	allocated *bitmap // addresses currently handed out (or excluded)
+	touched   *bitmap // addresses that have ever been handed out (or excluded)
+	excluded  map[uint32]struct{}
+	strategy  Strategy
+}
+
+// NewRange builds a Range for the given CIDR. The network address, the broadcast
+// address of blocks larger than a /31 and every address in exclusions are never
+// handed out.
+func NewRange(cidr string, exclusions []string, strategy Strategy) (*Range, error) {
+	prefix, err := netip.ParsePrefix(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+	}
+	if !prefix.Addr().Is4() {
+		return nil, fmt.Errorf("invalid CIDR %q: only IPv4 ranges are supported", cidr)
+	}
+	if prefix.Bits() < MinPrefixBits {
+		return nil, fmt.Errorf("invalid CIDR %q: ranges larger than /%d are not supported", cidr, MinPrefixBits)
+	}
+	if strategy == nil {
+		return nil, errors.New("allocation strategy is required")
+	}
+	prefix = prefix.Masked()
+
+	r := &Range{
+		prefix:   prefix,
+		base:     addrToUint32(prefix.Addr()),
+		size:     uint32(1) << (32 - prefix.Bits()),
+		excluded: make(map[uint32]struct{}),
+		strategy: strategy,
+	}
+	r.allocated = newBitmap(r.size)
+	r.touched = newBitmap(r.size)
+
+	// The network address is never handed out, nor is the broadcast address;
+	// /31 point-to-point links have none (RFC 3021).
+	r.reserve(0)
+	if prefix.Bits() < 31 {
+		r.reserve(r.size - 1)
+	}
+	for _, ex := range exclusions {
+		addr, err := netip.ParseAddr(ex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclusion address %q: %w", ex, err)
+		}
+		if offset, ok := r.offset(addr); ok {
+			r.reserve(offset)
+		}
+	}
+	return r, nil
+}
+
+// Prefix returns the CIDR block covered by the range.
+func (r *Range) Prefix() netip.Prefix {
+	return r.prefix
+}
+
+// Strategy returns the allocation strategy used by the range.
+func (r *Range) Strategy() Strategy {
+	return r.strategy
+}
+
+// Size returns the number of allocatable addresses (excluded addresses are not counted).
+func (r *Range) Size() uint32 {
+	return r.size - r.reserved
+}
+
+// Free returns the number of addresses that can still be allocated.
+func (r *Range) Free() uint32 {
+	return r.size - r.reserved - r.used
+}
+
+// Contains reports whether addr belongs to the range.
+func (r *Range) Contains(addr netip.Addr) bool {
+	_, ok := r.offset(addr)
+	return ok
+}
+
+// Allocate picks a free address using the range's strategy and marks it allocated.
+func (r *Range) Allocate() (netip.Addr, error) {
+	if r.Free() == 0 {
+		return netip.Addr{}, ErrExhausted
+	}
+	offset, ok := r.strategy.Next(r)
+	if !ok {
+		return netip.Addr{}, ErrExhausted
+	}
+	r.mark(offset)
+	return r.addr(offset), nil
+}
+
+// MarkAllocated records an address that was allocated outside of this Range,
+// e.g. rows loaded from the database at startup.
+func (r *Range) MarkAllocated(addr netip.Addr) error {
+	offset, ok := r.offset(addr)
+	if !ok {
+		return ErrOutOfRange
+	}
+	if !r.allocated.test(offset) {
+		r.mark(offset)
+	}
+	return nil
+}
+
+// MarkReleased records a previously used address that is free again.
+// Addresses must be passed in the order they were released so that
+// strategies which care about history (LRU) can rebuild it.
+func (r *Range) MarkReleased(addr netip.Addr) error {
+	offset, ok := r.offset(addr)
+	if !ok {
+		return ErrOutOfRange
+	}
+	if r.isExcluded(offset) {
+		return nil
+	}
+	r.touched.set(offset)
+	if r.allocated.test(offset) {
+		r.allocated.clear(offset)
+		r.used--
+	}
+	r.strategy.Released(offset)
+	return nil
+}
+
+// Release returns an allocated address to the range.
+func (r *Range) Release(addr netip.Addr) error {
+	offset, ok := r.offset(addr)
+	if !ok {
+		return ErrOutOfRange
+	}
+	if !r.allocated.test(offset) || r.isExcluded(offset) {
+		return nil
+	}
+	r.allocated.clear(offset)
+	r.used--
+	r.strategy.Released(offset)
+	return nil
+}
+
+func (r *Range) reserve(offset uint32) {
+	if r.isExcluded(offset) {
+		return
+	}
+	r.excluded[offset] = struct{}{}
+	r.allocated.set(offset)
+	r.touched.set(offset)
+	r.reserved++
+}
+
+func (r *Range) isExcluded(offset uint32) bool {
+	_, ok := r.excluded[offset]
+	return ok
+}
+
+func (r *Range) mark(offset uint32) {
+	r.allocated.set(offset)
+	r.touched.set(offset)
+	r.used++
+}
+
+func (r *Range) offset(addr netip.Addr) (uint32, bool) {
+	if !addr.Is4() || !r.prefix.Contains(addr) {
+		return 0, false
+	}
+	return addrToUint32(addr) - r.base, true
+}
+
+func (r *Range) addr(offset uint32) netip.Addr {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], r.base+offset)
+	return netip.AddrFrom4(b)
+}
+
+func addrToUint32(addr netip.Addr) uint32 {
+	b := addr.As4()
+	return binary.BigEndian.Uint32(b[:])
+}
@@ -0,0 +1,204 @@
+package ipam
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+// TestRangeStrategies fills pools of every strategy until they are exhausted and
+// checks that exactly the allocatable addresses were handed out: never the
+// network or broadcast address, nor an excluded one.
+func TestRangeStrategies(t *testing.T) {
+	tests := []struct {
+		name       string
+		cidr       string
+		exclusions []string
+		// reserved are the addresses that must never be handed out.
+		reserved []string
+		size     uint32
+	}{
+		{name: "/30", cidr: "10.0.0.0/30", reserved: []string{"10.0.0.0", "10.0.0.3"}, size: 2},
+		{name: "/30 partially excluded", cidr: "10.0.0.0/30", exclusions: []string{"10.0.0.1"}, reserved: []string{"10.0.0.0", "10.0.0.1", "10.0.0.3"}, size: 1},
+		{name: "/30 fully excluded", cidr: "10.0.0.0/30", exclusions: []string{"10.0.0.1", "10.0.0.2"}, size: 0},
+		{name: "/31 has no broadcast", cidr: "10.0.0.0/31", reserved: []string{"10.0.0.0"}, size: 1},
+		{name: "/31 fully excluded", cidr: "10.0.0.0/31", exclusions: []string{"10.0.0.1"}, size: 0},
+		{name: "/24", cidr: "192.168.1.0/24", reserved: []string{"192.168.1.0", "192.168.1.255"}, size: 254},
+		{
+			name:       "/24 partially excluded",
+			cidr:       "192.168.1.0/24",
+			exclusions: []string{"192.168.1.1", "192.168.1.64", "192.168.1.254", "192.168.1.255", "10.0.0.1"},
+			reserved:   []string{"192.168.1.0", "192.168.1.1", "192.168.1.64", "192.168.1.254", "192.168.1.255"},
+			size:       251,
+		},
+		{name: "/16", cidr: "172.16.0.0/16", reserved: []string{"172.16.0.0", "172.16.255.255"}, size: 65534},
+		{
+			name:       "/16 partially excluded",
+			cidr:       "172.16.0.0/16",
+			exclusions: []string{"172.16.0.1", "172.16.0.63", "172.16.0.64", "172.16.16.0", "172.16.255.254"},
+			reserved:   []string{"172.16.0.0", "172.16.0.1", "172.16.0.63", "172.16.0.64", "172.16.16.0", "172.16.255.254", "172.16.255.255"},
+			size:       65529,
+		},
+	}
+	for _, strategyName := range []string{StrategySequential, StrategyRandom, StrategyLRU} {
+		for _, tt := range tests {
+			t.Run(strategyName+" "+tt.name, func(t *testing.T) {
+				strategy, err := NewStrategy(strategyName)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r, err := NewRange(tt.cidr, tt.exclusions, strategy)
+				if err != nil {
+					t.Fatalf("NewRange(%q) failed: %v", tt.cidr, err)
+				}
+				if r.Size() != tt.size || r.Free() != tt.size {
+					t.Fatalf("Size() = %d, Free() = %d; want %d", r.Size(), r.Free(), tt.size)
+				}
+
+				reserved := make(map[netip.Addr]bool)
+				for _, addr := range tt.reserved {
+					reserved[netip.MustParseAddr(addr)] = true
+				}
+				seen := make(map[netip.Addr]bool)
+				for {
+					addr, err := r.Allocate()
+					if errors.Is(err, ErrExhausted) {
+						break
+					}
+					if err != nil {
+						t.Fatalf("Allocate() failed: %v", err)
+					}
+					if !r.Contains(addr) {
+						t.Fatalf("Allocate() = %v, outside of %s", addr, tt.cidr)
+					}
+					if reserved[addr] {
+						t.Fatalf("Allocate() = %v, which is reserved", addr)
+					}
+					if seen[addr] {
+						t.Fatalf("Allocate() = %v twice", addr)
+					}
+					seen[addr] = true
+				}
+				if uint32(len(seen)) != tt.size || r.Free() != 0 {
+					t.Fatalf("allocated %d addresses with %d free; want %d with none free", len(seen), r.Free(), tt.size)
+				}
+
+				// Releasing a reserved address does not make it allocatable
+				for addr := range reserved {
+					if err := r.Release(addr); err != nil {
+						t.Fatalf("Release(%v) failed: %v", addr, err)
+					}
+				}
+				if _, err := r.Allocate(); !errors.Is(err, ErrExhausted) {
+					t.Fatalf("Allocate() after releasing reserved addresses = %v; want ErrExhausted", err)
+				}
+
+				// A full pool hands out what is released, and only that
+				for addr := range seen {
+					if err := r.Release(addr); err != nil {
+						t.Fatalf("Release(%v) failed: %v", addr, err)
+					}
+					got, err := r.Allocate()
+					if err != nil || got != addr {
+						t.Fatalf("Allocate() after releasing %v = %v, %v; want %v", addr, got, err, addr)
+					}
+					if _, err := r.Allocate(); !errors.Is(err, ErrExhausted) {
+						t.Fatalf("Allocate() on a full pool = %v; want ErrExhausted", err)
+					}
+					break
+				}
+			})
+		}
+	}
+}
+
+func TestSequentialStrategyOrder(t *testing.T) {
+	r, err := NewRange("10.0.0.0/29", []string{"10.0.0.2"}, &sequentialStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"10.0.0.1", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"}
+	for _, addr := range want {
+		if got, err := r.Allocate(); err != nil || got.String() != addr {
+			t.Fatalf("Allocate() = %v, %v; want %s", got, err, addr)
+		}
+	}
+
+	// The lowest free address is handed out first
+	for _, addr := range []string{"10.0.0.5", "10.0.0.3"} {
+		if err := r.Release(netip.MustParseAddr(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, addr := range []string{"10.0.0.3", "10.0.0.5"} {
+		if got, err := r.Allocate(); err != nil || got.String() != addr {
+			t.Fatalf("Allocate() = %v, %v; want %s", got, err, addr)
+		}
+	}
+}
+
+func TestLRUStrategyOrder(t *testing.T) {
+	r, err := NewRange("10.0.0.0/29", nil, &lruStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.Allocate()
+	if err := r.Release(first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Addresses never used go before the released one
+	for _, addr := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"} {
+		if got, err := r.Allocate(); err != nil || got.String() != addr {
+			t.Fatalf("Allocate() = %v, %v; want %s", got, err, addr)
+		}
+	}
+
+	// Then released addresses, in release order
+	for _, addr := range []string{"10.0.0.4", "10.0.0.2"} {
+		if err := r.Release(netip.MustParseAddr(addr)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, addr := range []string{first.String(), "10.0.0.4", "10.0.0.2"} {
+		if got, err := r.Allocate(); err != nil || got.String() != addr {
+			t.Fatalf("Allocate() = %v, %v; want %s", got, err, addr)
+		}
+	}
+	if _, err := r.Allocate(); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Allocate() on a full pool = %v; want ErrExhausted", err)
+	}
+}
+
+func TestRangeMarkAllocated(t *testing.T) {
+	r, err := NewRange("10.0.0.0/30", nil, &sequentialStrategy{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := r.MarkAllocated(netip.MustParseAddr("10.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.MarkAllocated(netip.MustParseAddr("10.0.1.1")); !errors.Is(err, ErrOutOfRange) {
+		t.Fatalf("MarkAllocated outside of the range = %v; want ErrOutOfRange", err)
+	}
+	if got, err := r.Allocate(); err != nil || got.String() != "10.0.0.2" {
+		t.Fatalf("Allocate() = %v, %v; want 10.0.0.2", got, err)
+	}
+	if _, err := r.Allocate(); !errors.Is(err, ErrExhausted) {
+		t.Fatalf("Allocate() on a full pool = %v; want ErrExhausted", err)
+	}
+}
+
+func TestNewRangeRejects(t *testing.T) {
+	for _, cidr := range []string{"10.0.0.0", "::1/128", "10.0.0.0/7"} {
+		if _, err := NewRange(cidr, nil, &sequentialStrategy{}); err == nil {
+			t.Errorf("NewRange(%q) succeeded; want an error", cidr)
+		}
+	}
+	if _, err := NewRange("10.0.0.0/24", []string{"not-an-address"}, &sequentialStrategy{}); err == nil {
+		t.Errorf("NewRange with an invalid exclusion succeeded; want an error")
+	}
+	if _, err := NewStrategy("fastest"); err == nil {
+		t.Errorf("NewStrategy(%q) succeeded; want an error", "fastest")
+	}
+}
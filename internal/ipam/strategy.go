@@ -0,0 +1,130 @@
+package ipam
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Supported allocation strategy names.
+const (
+	StrategySequential = "sequential"
+	StrategyRandom     = "random"
+	StrategyLRU        = "lru"
+)
+
+// Strategy decides which free address of a Range is handed out next.
+// A Strategy instance belongs to exactly one Range and may keep per-range state.
+type Strategy interface {
+	// Name returns the strategy name as stored on the pool.
+	Name() string
+	// Next returns the offset of a free address, or false when none is left.
+	Next(r *Range) (uint32, bool)
+	// Released is called after an offset has been returned to the range.
+	Released(offset uint32)
+}
+
+// NewStrategy returns a fresh strategy instance for the given name.
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case StrategySequential, "":
+		return &sequentialStrategy{}, nil
+	case StrategyRandom:
+		return &randomStrategy{}, nil
+	case StrategyLRU:
+		return &lruStrategy{}, nil
+	default:
+		return nil, fmt.Errorf("unknown IP allocation strategy %q: must be %s, %s or %s",
+			name, StrategySequential, StrategyRandom, StrategyLRU)
+	}
+}
+
+// IsValidStrategy reports whether name is a supported strategy.
+func IsValidStrategy(name string) bool {
+	_, err := NewStrategy(name)
+	return err == nil
+}
+
+// sequentialStrategy always hands out the lowest free address.
+// lowest is a hint: every offset below it is known to be allocated,
+// so a steadily filling pool never rescans its head.
+type sequentialStrategy struct {
+	lowest uint32
+}
+
+func (s *sequentialStrategy) Name() string { return StrategySequential }
+
+func (s *sequentialStrategy) Next(r *Range) (uint32, bool) {
+	offset, ok := r.allocated.nextClear(s.lowest, r.size)
+	if !ok {
+		return 0, false
+	}
+	s.lowest = offset
+	return offset, true
+}
+
+func (s *sequentialStrategy) Released(offset uint32) {
+	if offset < s.lowest {
+		s.lowest = offset
+	}
+}
+
+// randomStrategy picks a random offset and probes forward to the next free one,
+// wrapping around to the start of the range.
+type randomStrategy struct{}
+
+func (s *randomStrategy) Name() string { return StrategyRandom }
+
+func (s *randomStrategy) Next(r *Range) (uint32, bool) {
+	start := rand.Uint32N(r.size)
+	if offset, ok := r.allocated.nextClear(start, r.size); ok {
+		return offset, true
+	}
+	return r.allocated.nextClear(0, start)
+}
+
+func (s *randomStrategy) Released(offset uint32) {}
+
+// lruStrategy hands out the address that has been free the longest.
+// Addresses that were never used count as the oldest and are consumed first
+// (tracked by the frontier over the touched bitmap); after that, released
+// addresses are reused in release order.
+type lruStrategy struct {
+	frontier uint32
+	queue    []uint32
+	head     int
+}
+
+func (s *lruStrategy) Name() string { return StrategyLRU }
+
+func (s *lruStrategy) Next(r *Range) (uint32, bool) {
+	if offset, ok := r.touched.nextClear(s.frontier, r.size); ok {
+		s.frontier = offset
+		return offset, true
+	}
+	s.frontier = r.size
+
+	for s.head < len(s.queue) {
+		offset := s.queue[s.head]
+		s.head++
+		// The queue may hold stale entries for addresses that were
+		// marked allocated after they were released.
+		if !r.allocated.test(offset) {
+			s.compact()
+			return offset, true
+		}
+	}
+	s.compact()
+	return 0, false
+}
+
+func (s *lruStrategy) Released(offset uint32) {
+	s.queue = append(s.queue, offset)
+}
+
+// compact drops consumed queue entries once they dominate the backing array.
+func (s *lruStrategy) compact() {
+	if s.head > 1024 && s.head*2 > len(s.queue) {
+		s.queue = append(s.queue[:0], s.queue[s.head:]...)
+		s.head = 0
+	}
+}
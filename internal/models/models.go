@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
+
 	"go-virtual-server/internal/database/sqlc"
 )
 
@@ -12,37 +14,126 @@ type ProvisionServerRequest struct {
 	Name   string `json:"name" example:"my-app-server"`
 	Region string `json:"region" example:"us-east-1"`
 	Type   string `json:"type" example:"t2.micro"`
+
+	Project        string            `json:"project,omitempty" example:"checkout"`               // Project invoiced for the server, defaults to "default"
+	AssignPublicIP bool              `json:"assignPublicIp" example:"false"`                     // Also map a public address to the server's private address
+	Tags           map[string]string `json:"tags,omitempty"`                                     // Free-form labels, served by the metadata service
+	UserData       string            `json:"userData,omitempty" example:"#!/bin/sh\necho hello"` // Served as-is at /latest/user-data, max 16 KiB
+	BillingModel   string            `json:"billingModel,omitempty" example:"hourly"`            // per_second (default, 60s minimum), hourly or reserved
+	DiskGB         int32             `json:"diskGb,omitempty" example:"20"`                      // Disk size, billed per GB-hour until terminated; defaults to DEFAULT_DISK_GB
+
+	PurchaseOption       string  `json:"purchaseOption,omitempty" example:"spot"`       // on_demand (default) or spot
+	SpotMaxPrice         float64 `json:"spotMaxPrice,omitempty" example:"0.05"`         // Highest hourly spot price to run at, defaults to the on-demand price
+	InterruptionBehavior string  `json:"interruptionBehavior,omitempty" example:"stop"` // What happens to an outbid spot server: terminate (default) or stop
 }
 
 // ServerActionRequest defines the request body for performing a server action
 type ServerActionRequest struct {
 	Action string `json:"action" example:"start"` // start, stop, reboot, terminate
 }
+
+// RenameServerRequest defines the request body for renaming a server
+type RenameServerRequest struct {
+	Name string `json:"name" example:"my-renamed-server"`
+}
+
+// AttachInterfaceRequest defines the request body for attaching a network interface
+type AttachInterfaceRequest struct {
+	Pool string `json:"pool" example:"default"` // IP pool for the interface's address, defaults to "default"
+}
+
+// AssignIPRequest defines the request body for assigning a secondary IP to an interface
+type AssignIPRequest struct {
+	Pool string `json:"pool" example:"default"` // IP pool for the secondary address, defaults to "default"
+}
+
 type BillingInfo struct {
-	BillingModel         string    `json:"billingModel" example:"hourly"`              // e.g., "hourly", "monthly", "per_request"
+	BillingModel         string    `json:"billingModel" example:"hourly"`              // per_second, hourly or reserved
 	CurrencyUnit         string    `json:"currencyUnit" example:"USD"`                 // e.g., "USD", "EUR", "GBP"
 	UnitPrice            float64   `json:"unitPrice" example:"0.01"`                   // Price per unit (e.g., per hour, per request)
 	UpdatedTime          time.Time `json:"updatedTime" example:"2023-10-27T09:00:00Z"` // Start of the current billing period
 	TotalUptimeSeconds   int64     `json:"totalUptimeSeconds" example:"3600"`          // Total cumulative active time for the server
 	EstimatedCurrentCost float64   `json:"estimatedCurrentCost" example:"0.01"`        // Estimated cost for the current (partial) billing cycle or total accumulated cost
+
+	Charges []BillingCharge `json:"charges"` // EstimatedCurrentCost by charge type
+}
+
+// BillingCharge is the usage and cost of a server for one charge type
+type BillingCharge struct {
+	ChargeType string  `json:"chargeType" example:"storage"` // compute, compute_rounding, compute_reserved, storage, public_ip or egress
+	Quantity   float64 `json:"quantity" example:"160"`
+	Unit       string  `json:"unit" example:"GB-hour"` // hour, GB-hour or GB
+	Amount     float64 `json:"amount" example:"0.02"`
 }
 
 // ServerResponse represents the response structure for a server
 type ServerResponse struct {
-	ID               string          `json:"id" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
-	Name             string          `json:"name" example:"my-app-server"`
-	Region           string          `json:"region" example:"us-east-1"`
-	Status           string          `json:"status" example:"running"`
-	Type             string          `json:"type" example:"t2.micro"`
-	IPAddress        string          `json:"ipAddress" example:"192.168.1.10"`
-	ProvisionedAt    time.Time       `json:"provisionedAt" example:"2023-10-27T10:00:00Z"`
-	LastStatusUpdate time.Time       `json:"lastStatusUpdate" example:"2023-10-27T10:15:00Z"`
-	UptimeSeconds    int64           `json:"uptimeSeconds" example:"900"`
-	BillingInfo      BillingInfo     `json:"billingInfo"`
-	HourlyCost       float64         `json:"hourlyCost" example:"0.01"`
-	LifecycleLogs    json.RawMessage `json:"lifecycleLogs"`
-	CreatedAt        time.Time       `json:"createdAt" example:"2023-10-27T09:55:00Z"`
-	UpdatedAt        time.Time       `json:"updatedAt" example:"2023-10-27T10:15:00Z"`
+	ID               string            `json:"id" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	Name             string            `json:"name" example:"my-app-server"`
+	Hostname         string            `json:"hostname" example:"my-app-server.vs.internal"`
+	Region           string            `json:"region" example:"us-east-1"`
+	Project          string            `json:"project" example:"checkout"`
+	Status           string            `json:"status" example:"running"`
+	Type             string            `json:"type" example:"t2.micro"`
+	DiskGB           int32             `json:"diskGb" example:"8"`
+	Tags             map[string]string `json:"tags"`
+	IPAddress        string            `json:"ipAddress" example:"192.168.1.10"` // Primary address of the primary interface
+	PrivateIPAddress string            `json:"privateIpAddress" example:"10.0.0.12"`
+	PublicIPAddress  string            `json:"publicIpAddress,omitempty" example:"203.0.113.25"` // Set while a NAT mapping is active
+	ProvisionedAt    time.Time         `json:"provisionedAt" example:"2023-10-27T10:00:00Z"`
+	LastStatusUpdate time.Time         `json:"lastStatusUpdate" example:"2023-10-27T10:15:00Z"`
+	StuckSince       *time.Time        `json:"stuckSince,omitempty" example:"2023-10-27T10:00:00Z"` // Set while the watchdog retries a stuck transition and in the error status: when the server entered the status it got stuck in
+	UptimeSeconds    int64             `json:"uptimeSeconds" example:"900"`
+	BillingInfo      BillingInfo       `json:"billingInfo"`
+	HourlyCost       float64           `json:"hourlyCost" example:"0.01"`
+	BillingModel     string            `json:"billingModel" example:"per_second"`
+	PurchaseOption   string            `json:"purchaseOption" example:"on_demand"`
+	Spot             *SpotInfo         `json:"spot,omitempty"` // Set for spot servers
+	CreatedAt        time.Time         `json:"createdAt" example:"2023-10-27T09:55:00Z"`
+	UpdatedAt        time.Time         `json:"updatedAt" example:"2023-10-27T10:15:00Z"`
+
+	Interfaces []NetworkInterfaceResponse `json:"interfaces"`
+}
+
+// SpotInfo is the bid of a spot server and its pending interruption, if any
+type SpotInfo struct {
+	MaxPrice             float64    `json:"maxPrice" example:"0.05"`
+	InterruptionBehavior string     `json:"interruptionBehavior" example:"stop"`
+	InterruptionNoticeAt *time.Time `json:"interruptionNoticeAt,omitempty" example:"2023-10-27T10:20:00Z"` // When the server was told it will be interrupted
+}
+
+// NetworkInterfaceResponse represents a network interface and its addresses
+type NetworkInterfaceResponse struct {
+	ID          string                   `json:"id" example:"0b6f1c2e-7d0a-4a39-9f57-1d2c3b4a5e6f"`
+	DeviceIndex int32                    `json:"deviceIndex" example:"0"`
+	IsPrimary   bool                     `json:"isPrimary" example:"true"`
+	IPAddresses []InterfaceAddressResult `json:"ipAddresses"`
+	CreatedAt   time.Time                `json:"createdAt" example:"2023-10-27T09:55:00Z"`
+}
+
+// NATMappingResponse represents a 1:1 NAT mapping between a public and a private address
+type NATMappingResponse struct {
+	ID             string     `json:"id" example:"3f1d2c4b-5a69-4e7f-8d9c-0b1a2c3d4e5f"`
+	ServerID       string     `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	PublicAddress  string     `json:"publicAddress" example:"203.0.113.25"`
+	PrivateAddress string     `json:"privateAddress" example:"10.0.0.12"`
+	Active         bool       `json:"active" example:"true"`
+	CreatedAt      time.Time  `json:"createdAt" example:"2023-10-27T10:00:00Z"`
+	ReleasedAt     *time.Time `json:"releasedAt,omitempty" example:"2023-10-27T12:00:00Z"`
+}
+
+// ListNATMappingsResponse for listing NAT mappings
+type ListNATMappingsResponse struct {
+	Mappings []NATMappingResponse `json:"mappings"`
+	Limit    int                  `json:"limit"`
+	Offset   int                  `json:"offset"`
+}
+
+// InterfaceAddressResult represents one address bound to a network interface
+type InterfaceAddressResult struct {
+	Address   string `json:"address" example:"192.168.1.10"`
+	Pool      string `json:"pool" example:"default"`
+	IsPrimary bool   `json:"isPrimary" example:"true"` // The interface's primary address
 }
 
 // ListServersResponse for listing servers
@@ -53,47 +144,958 @@ type ListServersResponse struct {
 	Offset  int              `json:"offset"`
 }
 
-// ServerLifecycleLogEntry represents a single entry in the server's lifecycle_logs JSONB array.
-type ServerLifecycleLogEntry struct {
-	RequestID string `json:"REQUEST_ID"`
-	Action    string `json:"ACTION"`
-	ServerID  string `json:"SERVER_ID"`
-	Time      string `json:"TIME"`
-} // ServerLogsResponse for server logs
+// InvoiceResponse represents an invoice for one project and billing period
+type InvoiceResponse struct {
+	ID           string                `json:"id" example:"5c0e7d1a-2b3c-4d5e-8f90-a1b2c3d4e5f6"`
+	Project      string                `json:"project" example:"checkout"`
+	PeriodStart  string                `json:"periodStart" example:"2023-10-01"`
+	PeriodEnd    string                `json:"periodEnd" example:"2023-11-01"` // Exclusive
+	Currency     string                `json:"currency" example:"EUR"`
+	ExchangeRate float64               `json:"exchangeRate" example:"0.92"` // Units of currency per USD the ledger was converted at
+	Total        float64               `json:"total" example:"12.34"`
+	IssuedAt     time.Time             `json:"issuedAt" example:"2023-11-01T00:01:00Z"`
+	Lines        []InvoiceLineResponse `json:"lines,omitempty"`
+}
+
+// InvoiceLineResponse represents the charges of one server and charge type on an invoice
+type InvoiceLineResponse struct {
+	ServerID   string  `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	ServerName string  `json:"serverName" example:"my-app-server"`
+	ChargeType string  `json:"chargeType" example:"compute"`
+	ServerType string  `json:"serverType" example:"t2.micro"`
+	Region     string  `json:"region" example:"us-east-1"`
+	Quantity   float64 `json:"quantity" example:"720"`
+	Unit       string  `json:"unit" example:"hour"`
+	UnitPrice  float64 `json:"unitPrice" example:"0.01"`
+	Amount     float64 `json:"amount" example:"7.2"`
+}
+
+// ListInvoicesResponse for listing invoices
+type ListInvoicesResponse struct {
+	Invoices []InvoiceResponse `json:"invoices"`
+	Limit    int               `json:"limit"`
+	Offset   int               `json:"offset"`
+}
+
+// PriceResponse represents one version of the price of a server type in a region
+type PriceResponse struct {
+	ID             string     `json:"id" example:"0f1e2d3c-4b5a-6978-8695-a4b3c2d1e0f9"`
+	Type           string     `json:"type" example:"t2.micro"`
+	Region         string     `json:"region" example:"*"` // "*" applies to regions without a price of their own
+	HourlyRate     float64    `json:"hourlyRate" example:"0.0116"`
+	Currency       string     `json:"currency" example:"USD"`
+	EffectiveFrom  time.Time  `json:"effectiveFrom" example:"2023-11-01T00:00:00Z"`
+	EffectiveUntil *time.Time `json:"effectiveUntil,omitempty" example:"2024-01-01T00:00:00Z"` // Unset for the latest version
+	Current        bool       `json:"current" example:"true"`                                  // In effect now
+}
+
+// ListPricesResponse for listing the price catalog
+type ListPricesResponse struct {
+	Prices []PriceResponse `json:"prices"`
+}
+
+// CreatePriceRequest schedules a new price version
+type CreatePriceRequest struct {
+	Type          string    `json:"type" example:"t2.micro"`
+	Region        string    `json:"region,omitempty" example:"us-east-1"` // Defaults to "*"
+	HourlyRate    float64   `json:"hourlyRate" example:"0.0104"`
+	EffectiveFrom time.Time `json:"effectiveFrom" example:"2024-01-01T00:00:00Z"` // Must be in the future
+}
+
+// QuoteRequest asks for the cost of running servers before provisioning them
+type QuoteRequest struct {
+	Type         string  `json:"type" example:"m5.large"`
+	Region       string  `json:"region" example:"us-east-1"`
+	Count        int     `json:"count,omitempty" example:"3"` // Defaults to 1
+	Hours        float64 `json:"hours" example:"720"`
+	BillingModel string  `json:"billingModel,omitempty" example:"reserved"` // per_second (default), hourly or reserved
+	Project      string  `json:"project,omitempty" example:"checkout"`      // Whose reservations a reserved quote draws down, defaults to "default"
+}
+
+// QuoteResponse is the cost breakdown of a quote
+type QuoteResponse struct {
+	Type          string              `json:"type" example:"m5.large"`
+	Region        string              `json:"region" example:"us-east-1"`
+	Count         int                 `json:"count" example:"3"`
+	Hours         float64             `json:"hours" example:"720"`
+	BillingModel  string              `json:"billingModel" example:"reserved"`
+	Currency      string              `json:"currency" example:"USD"`
+	Lines         []QuoteLineResponse `json:"lines"`                        // Metered time, per hourly rate
+	BilledHours   float64             `json:"billedHours" example:"720"`    // Time the billing model charges per server
+	ReservedHours float64             `json:"reservedHours" example:"1000"` // Time of all servers covered by the project's reservations
+	UnitCost      float64             `json:"unitCost" example:"69.12"`     // Cost of one server without reservations
+	Total         float64             `json:"total" example:"111.36"`       // Cost of all servers, less the reserved hours
+}
+
+// QuoteLineResponse is the part of a quote priced at one hourly rate
+type QuoteLineResponse struct {
+	From       time.Time `json:"from" example:"2023-10-26T10:00:00Z"`
+	Until      time.Time `json:"until" example:"2023-11-25T10:00:00Z"`
+	Hours      float64   `json:"hours" example:"720"`
+	HourlyRate float64   `json:"hourlyRate" example:"0.096"`
+	Amount     float64   `json:"amount" example:"207.36"` // For all servers
+}
+
+// ForecastResponse projects the spend of a server to the end of the billing period
+type ForecastResponse struct {
+	ServerID      string    `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	Project       string    `json:"project" example:"checkout"`
+	PeriodStart   time.Time `json:"periodStart" example:"2023-10-01T00:00:00Z"`
+	PeriodEnd     time.Time `json:"periodEnd" example:"2023-11-01T00:00:00Z"`
+	Currency      string    `json:"currency" example:"USD"`
+	ActualCost    float64   `json:"actualCost" example:"4.12"`    // Metered so far this period
+	ProjectedCost float64   `json:"projectedCost" example:"3.05"` // Expected until the period ends
+	ForecastCost  float64   `json:"forecastCost" example:"7.17"`  // Actual plus projected
+	Utilization   float64   `json:"utilization" example:"1"`      // Expected share of the remaining period spent running
+}
+
+// BillingForecastResponse projects the spend of every project to the end of the billing period
+type BillingForecastResponse struct {
+	PeriodStart   time.Time                 `json:"periodStart" example:"2023-10-01T00:00:00Z"`
+	PeriodEnd     time.Time                 `json:"periodEnd" example:"2023-11-01T00:00:00Z"`
+	Currency      string                    `json:"currency" example:"USD"`
+	ActualCost    float64                   `json:"actualCost" example:"41.2"`
+	ProjectedCost float64                   `json:"projectedCost" example:"30.5"`
+	ForecastCost  float64                   `json:"forecastCost" example:"71.7"`
+	Projects      []ProjectForecastResponse `json:"projects"`
+}
+
+// ProjectForecastResponse is the forecast of one project
+type ProjectForecastResponse struct {
+	Project       string  `json:"project" example:"checkout"`
+	Servers       int     `json:"servers" example:"4"`
+	ActualCost    float64 `json:"actualCost" example:"41.2"`
+	ProjectedCost float64 `json:"projectedCost" example:"30.5"`
+	ForecastCost  float64 `json:"forecastCost" example:"71.7"`
+}
+
+// ExchangeRateResponse represents one version of the exchange rate of a currency
+type ExchangeRateResponse struct {
+	ID             string     `json:"id" example:"7a6b5c4d-3e2f-4a1b-9c8d-7e6f5a4b3c2d"`
+	Currency       string     `json:"currency" example:"EUR"`
+	Rate           float64    `json:"rate" example:"0.92"` // Units of the currency one USD buys
+	EffectiveFrom  time.Time  `json:"effectiveFrom" example:"2024-01-01T00:00:00Z"`
+	EffectiveUntil *time.Time `json:"effectiveUntil,omitempty" example:"2024-02-01T00:00:00Z"` // Unset for the latest version
+	Current        bool       `json:"current" example:"true"`                                  // In effect now
+}
+
+// ListExchangeRatesResponse for listing exchange rates
+type ListExchangeRatesResponse struct {
+	Base  string                 `json:"base" example:"USD"` // Currency prices and the ledger are kept in
+	Rates []ExchangeRateResponse `json:"rates"`
+}
+
+// CreateExchangeRateRequest adds a version of a currency's exchange rate
+type CreateExchangeRateRequest struct {
+	Currency      string    `json:"currency" example:"EUR"`
+	Rate          float64   `json:"rate" example:"0.92"`                                    // Units of the currency one USD buys
+	EffectiveFrom time.Time `json:"effectiveFrom,omitempty" example:"2024-01-01T00:00:00Z"` // Defaults to now
+}
+
+// ProjectResponse represents the billing settings of a project
+type ProjectResponse struct {
+	Name     string `json:"name" example:"checkout"`
+	Currency string `json:"currency" example:"EUR"` // Invoices and billing responses are in this currency
+}
+
+// UpdateProjectRequest changes the billing settings of a project
+type UpdateProjectRequest struct {
+	Currency string `json:"currency" example:"EUR"` // Needs an exchange rate in effect
+}
+
+// CreateBudgetRequest defines a spending limit on the servers of a project, region or tag value
+type CreateBudgetRequest struct {
+	Name       string  `json:"name" example:"checkout monthly"`
+	ScopeType  string  `json:"scopeType" example:"project"`       // project, region or tag
+	ScopeKey   string  `json:"scopeKey,omitempty" example:"team"` // Tag key, for tag budgets
+	ScopeValue string  `json:"scopeValue" example:"checkout"`     // Project, region or tag value
+	Amount     float64 `json:"amount" example:"250"`
+	Period     string  `json:"period,omitempty" example:"monthly"`       // daily or monthly (default)
+	Thresholds []int32 `json:"thresholds,omitempty" example:"50,80,100"` // Percentages of the amount; default 50, 80, 100
+	WebhookURL string  `json:"webhookUrl,omitempty" example:"https://hooks.example.com/budget"`
+	Enforce    bool    `json:"enforce" example:"false"` // Stop running servers in scope once the amount is spent
+}
+
+// BudgetResponse represents a budget
+type BudgetResponse struct {
+	ID         string                `json:"id" example:"7d3c2b1a-0f9e-8d7c-6b5a-4f3e2d1c0b9a"`
+	Name       string                `json:"name" example:"checkout monthly"`
+	ScopeType  string                `json:"scopeType" example:"project"`
+	ScopeKey   string                `json:"scopeKey,omitempty" example:""`
+	ScopeValue string                `json:"scopeValue" example:"checkout"`
+	Amount     float64               `json:"amount" example:"250"`
+	Currency   string                `json:"currency" example:"USD"`
+	Period     string                `json:"period" example:"monthly"`
+	Thresholds []int32               `json:"thresholds" example:"50,80,100"`
+	WebhookURL string                `json:"webhookUrl,omitempty" example:"https://hooks.example.com/budget"`
+	Enforce    bool                  `json:"enforce" example:"false"`
+	CreatedAt  time.Time             `json:"createdAt" example:"2023-10-26T10:00:00Z"`
+	Status     *BudgetStatusResponse `json:"status,omitempty"`
+}
+
+// BudgetStatusResponse is the spend of a budget in its current period
+type BudgetStatusResponse struct {
+	PeriodStart time.Time `json:"periodStart" example:"2023-10-01T00:00:00Z"`
+	PeriodEnd   time.Time `json:"periodEnd" example:"2023-11-01T00:00:00Z"`
+	Spend       float64   `json:"spend" example:"212.5"`
+	PercentUsed float64   `json:"percentUsed" example:"85"`
+}
+
+// ListBudgetsResponse for listing budgets
+type ListBudgetsResponse struct {
+	Budgets []BudgetResponse `json:"budgets"`
+}
+
+// BudgetAlertResponse represents a budget threshold crossed in a budget period
+type BudgetAlertResponse struct {
+	ID            string    `json:"id" example:"1a2b3c4d-5e6f-7a8b-9c0d-e1f2a3b4c5d6"`
+	BudgetID      string    `json:"budgetId" example:"7d3c2b1a-0f9e-8d7c-6b5a-4f3e2d1c0b9a"`
+	PeriodStart   time.Time `json:"periodStart" example:"2023-10-01T00:00:00Z"`
+	Threshold     int32     `json:"threshold" example:"80"`
+	Spend         float64   `json:"spend" example:"200.1"`
+	Amount        float64   `json:"amount" example:"250"`
+	WebhookStatus string    `json:"webhookStatus" example:"delivered"` // none, delivered or failed: <reason>
+	CreatedAt     time.Time `json:"createdAt" example:"2023-10-21T08:13:00Z"`
+}
+
+// ListBudgetAlertsResponse for listing the alerts of a budget
+type ListBudgetAlertsResponse struct {
+	Alerts []BudgetAlertResponse `json:"alerts"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// SpotPriceResponse is the current spot price of a server type in a region
+type SpotPriceResponse struct {
+	Type          string    `json:"type" example:"m5.large"`
+	Region        string    `json:"region" example:"us-east-1"`
+	Price         float64   `json:"price" example:"0.0288"`
+	Currency      string    `json:"currency" example:"USD"`
+	EffectiveFrom time.Time `json:"effectiveFrom" example:"2023-10-27T10:00:00Z"`
+}
+
+// ListSpotPricesResponse for listing spot prices
+type ListSpotPricesResponse struct {
+	Prices []SpotPriceResponse `json:"prices"`
+}
+
+// SetSpotPriceRequest moves the spot price of a server type in a region
+type SetSpotPriceRequest struct {
+	Type   string  `json:"type" example:"m5.large"`
+	Region string  `json:"region" example:"us-east-1"`
+	Price  float64 `json:"price" example:"0.09"`
+}
+
+// CreateReservationRequest prepays hours of a server type in a region
+type CreateReservationRequest struct {
+	Project string  `json:"project,omitempty" example:"checkout"` // Defaults to "default"
+	Type    string  `json:"type" example:"m5.large"`
+	Region  string  `json:"region" example:"us-east-1"`
+	Hours   float64 `json:"hours" example:"1000"`
+}
+
+// ReservationResponse represents a reservation and how much of it is used
+type ReservationResponse struct {
+	ID             string    `json:"id" example:"5e6f7a8b-9c0d-4e1f-a2b3-c4d5e6f7a8b9"`
+	Project        string    `json:"project" example:"checkout"`
+	Type           string    `json:"type" example:"m5.large"`
+	Region         string    `json:"region" example:"us-east-1"`
+	Hours          float64   `json:"hours" example:"1000"`
+	HoursUsed      float64   `json:"hoursUsed" example:"250.5"`
+	HoursRemaining float64   `json:"hoursRemaining" example:"749.5"`
+	HourlyRate     float64   `json:"hourlyRate" example:"0.0672"` // Catalog price at purchase, less the discount
+	UpfrontAmount  float64   `json:"upfrontAmount" example:"67.2"`
+	Currency       string    `json:"currency" example:"USD"`
+	StartsAt       time.Time `json:"startsAt" example:"2023-10-01T00:00:00Z"`
+	ExpiresAt      time.Time `json:"expiresAt" example:"2024-09-30T00:00:00Z"`
+	CreatedAt      time.Time `json:"createdAt" example:"2023-10-01T00:00:00Z"`
+}
+
+// ListReservationsResponse for listing reservations
+type ListReservationsResponse struct {
+	Reservations []ReservationResponse `json:"reservations"`
+	Limit        int                   `json:"limit"`
+	Offset       int                   `json:"offset"`
+}
+
+// RecommendationResponse is a change to a running server that would lower its cost
+type RecommendationResponse struct {
+	Kind             string  `json:"kind" example:"downsize"` // downsize, stop or reserve
+	ServerID         string  `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	ServerName       string  `json:"serverName" example:"my-app-server"`
+	Project          string  `json:"project" example:"checkout"`
+	Region           string  `json:"region" example:"us-east-1"`
+	Type             string  `json:"type" example:"c5.xlarge"`
+	TargetType       string  `json:"targetType,omitempty" example:"m5.large"` // Set for downsize
+	HourlyCost       float64 `json:"hourlyCost" example:"0.17"`
+	TargetHourlyCost float64 `json:"targetHourlyCost" example:"0.096"` // Once applied; 0 for stop
+	UptimePercent    float64 `json:"uptimePercent" example:"100"`      // Share of the lookback the server was running
+	CPUPercentAvg    float64 `json:"cpuPercentAvg" example:"12.4"`     // 0 without enough telemetry
+	CPUPercentMax    float64 `json:"cpuPercentMax" example:"31.7"`
+	ReservedHours    float64 `json:"reservedHours,omitempty" example:"8760"` // Hours to reserve for RESERVATION_TERM, set for reserve
+	MonthlySavings   float64 `json:"monthlySavings" example:"54.02"`         // For a month of running as much as over the lookback
+	Reason           string  `json:"reason" example:"CPU load peaked at 31.7% (12.4% on average) over the last 168h0m0s, about 63.4% on m5.large"`
+}
+
+// ListRecommendationsResponse lists the right-sizing recommendations
+type ListRecommendationsResponse struct {
+	GeneratedAt         time.Time                `json:"generatedAt" example:"2023-10-27T10:15:00Z"`
+	Lookback            string                   `json:"lookback" example:"168h0m0s"`
+	Currency            string                   `json:"currency" example:"USD"`
+	TotalMonthlySavings float64                  `json:"totalMonthlySavings" example:"54.02"`
+	Recommendations     []RecommendationResponse `json:"recommendations"`
+}
+
+// ApplyRecommendationRequest applies the current recommendation of a kind to a server
+type ApplyRecommendationRequest struct {
+	ServerID string `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	Kind     string `json:"kind" example:"downsize"` // downsize or stop
+}
+
+// ApplyRecommendationResponse is the recommendation applied and the server after it
+type ApplyRecommendationResponse struct {
+	Recommendation RecommendationResponse `json:"recommendation"`
+	Server         ServerResponse         `json:"server"`
+}
+
+// CreateVolumeTierRequest adds a tier to the volume pricing of a server type
+type CreateVolumeTierRequest struct {
+	Type       string  `json:"type" example:"t2.micro"`
+	FromHours  float64 `json:"fromHours" example:"1000"` // Compute hours of a project period after which the tier applies
+	PercentOff float64 `json:"percentOff" example:"20"`
+}
+
+// VolumeTierResponse represents a volume pricing tier
+type VolumeTierResponse struct {
+	ID         string    `json:"id" example:"6f7a8b9c-0d1e-4f2a-b3c4-d5e6f7a8b9c0"`
+	Type       string    `json:"type" example:"t2.micro"`
+	FromHours  float64   `json:"fromHours" example:"1000"`
+	PercentOff float64   `json:"percentOff" example:"20"`
+	CreatedAt  time.Time `json:"createdAt" example:"2023-10-01T00:00:00Z"`
+}
+
+// ListVolumeTiersResponse for listing volume tiers
+type ListVolumeTiersResponse struct {
+	Tiers []VolumeTierResponse `json:"tiers"`
+}
+
+// CreateDiscountRequest defines a percentage discount on compute charges
+type CreateDiscountRequest struct {
+	Name       string     `json:"name" example:"launch promotion"`
+	Type       string     `json:"type,omitempty" example:"m5.large"`                  // Server type; any if empty
+	Region     string     `json:"region,omitempty" example:"us-east-1"`               // Any if empty
+	PercentOff float64    `json:"percentOff" example:"10"`                            // Off the compute charges left after volume tiers
+	StartsAt   time.Time  `json:"startsAt,omitempty" example:"2023-10-01T00:00:00Z"`  // Defaults to now
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" example:"2024-01-01T00:00:00Z"` // Never expires if empty
+}
+
+// DiscountResponse represents a discount
+type DiscountResponse struct {
+	ID         string     `json:"id" example:"7a8b9c0d-1e2f-4a3b-c4d5-e6f7a8b9c0d1"`
+	Name       string     `json:"name" example:"launch promotion"`
+	Type       string     `json:"type" example:"m5.large"` // * matches any
+	Region     string     `json:"region" example:"*"`      // * matches any
+	PercentOff float64    `json:"percentOff" example:"10"`
+	StartsAt   time.Time  `json:"startsAt" example:"2023-10-01T00:00:00Z"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty" example:"2024-01-01T00:00:00Z"`
+	CreatedAt  time.Time  `json:"createdAt" example:"2023-10-01T00:00:00Z"`
+}
+
+// ListDiscountsResponse for listing discounts
+type ListDiscountsResponse struct {
+	Discounts []DiscountResponse `json:"discounts"`
+}
+
+// CreateCreditRequest grants a project a promotional credit
+type CreateCreditRequest struct {
+	Project    string    `json:"project,omitempty" example:"checkout"` // Defaults to "default"
+	Name       string    `json:"name" example:"welcome credit"`
+	ScopeType  string    `json:"scopeType,omitempty" example:"project"`             // project (default), server_type or region
+	ScopeValue string    `json:"scopeValue,omitempty" example:""`                   // Server type or region, for scoped credits
+	Amount     float64   `json:"amount" example:"100"`                              // In USD
+	StartsAt   time.Time `json:"startsAt,omitempty" example:"2023-10-01T00:00:00Z"` // Defaults to now
+	ExpiresAt  time.Time `json:"expiresAt" example:"2024-01-01T00:00:00Z"`
+}
+
+// CreditResponse represents a credit and how much of it is left
+type CreditResponse struct {
+	ID         string    `json:"id" example:"8b9c0d1e-2f3a-4b4c-d5e6-f7a8b9c0d1e2"`
+	Project    string    `json:"project" example:"checkout"`
+	Name       string    `json:"name" example:"welcome credit"`
+	ScopeType  string    `json:"scopeType" example:"project"`
+	ScopeValue string    `json:"scopeValue,omitempty" example:""`
+	Amount     float64   `json:"amount" example:"100"`
+	Remaining  float64   `json:"remaining" example:"62.5"`
+	Currency   string    `json:"currency" example:"USD"`
+	StartsAt   time.Time `json:"startsAt" example:"2023-10-01T00:00:00Z"`
+	ExpiresAt  time.Time `json:"expiresAt" example:"2024-01-01T00:00:00Z"`
+	CreatedAt  time.Time `json:"createdAt" example:"2023-10-01T00:00:00Z"`
+}
+
+// ListCreditsResponse for listing credits
+type ListCreditsResponse struct {
+	Credits []CreditResponse `json:"credits"`
+	Limit   int              `json:"limit"`
+	Offset  int              `json:"offset"`
+}
+
+// TopUpAccountRequest adds funds to a project's prepaid account
+type TopUpAccountRequest struct {
+	Amount              float64  `json:"amount" example:"50"`                        // In USD
+	LowBalanceThreshold *float64 `json:"lowBalanceThreshold,omitempty" example:"10"` // Keeps the current threshold if omitted
+}
+
+// AccountResponse represents a project's prepaid balance
+type AccountResponse struct {
+	Project             string     `json:"project" example:"checkout"`
+	Balance             float64    `json:"balance" example:"42.5"`
+	Currency            string     `json:"currency" example:"USD"`
+	LowBalanceThreshold float64    `json:"lowBalanceThreshold" example:"10"`
+	Suspended           bool       `json:"suspended" example:"false"`
+	SuspendedAt         *time.Time `json:"suspendedAt,omitempty" example:"2023-10-27T10:00:00Z"`
+	DrawnUntil          time.Time  `json:"drawnUntil" example:"2023-10-27T10:00:00Z"`
+	CreatedAt           time.Time  `json:"createdAt" example:"2023-10-01T00:00:00Z"`
+	UpdatedAt           time.Time  `json:"updatedAt" example:"2023-10-27T10:00:00Z"`
+}
+
+// AccountEventResponse represents a top-up, low-balance warning, suspension or resumption of an account
+type AccountEventResponse struct {
+	ID        string    `json:"id" example:"9c0d1e2f-3a4b-4c5d-e6f7-a8b9c0d1e2f3"`
+	Project   string    `json:"project" example:"checkout"`
+	Type      string    `json:"type" example:"low_balance"` // topup, low_balance, exhausted or resumed
+	Amount    float64   `json:"amount" example:"0"`
+	Balance   float64   `json:"balance" example:"9.75"` // After the event
+	CreatedAt time.Time `json:"createdAt" example:"2023-10-27T10:00:00Z"`
+}
+
+// ListAccountEventsResponse for listing account events
+type ListAccountEventsResponse struct {
+	Events []AccountEventResponse `json:"events"`
+	Limit  int                    `json:"limit"`
+	Offset int                    `json:"offset"`
+}
+
+// RecordTelemetryRequest is a utilization sample reported by a server
+type RecordTelemetryRequest struct {
+	CPUPercent     float64    `json:"cpuPercent" example:"37.5"`
+	NetworkRxBytes int64      `json:"networkRxBytes" example:"1048576"`                   // Received since the previous sample
+	NetworkTxBytes int64      `json:"networkTxBytes" example:"524288"`                    // Sent since the previous sample
+	SampledAt      *time.Time `json:"sampledAt,omitempty" example:"2023-10-27T10:15:00Z"` // Defaults to now
+}
+
+// TelemetryPointResponse is a server's utilization over one step of a series
+type TelemetryPointResponse struct {
+	Time           time.Time `json:"time" example:"2023-10-27T10:00:00Z"` // Start of the step
+	CPUPercentAvg  float64   `json:"cpuPercentAvg" example:"35.2"`
+	CPUPercentMax  float64   `json:"cpuPercentMax" example:"61.8"`
+	NetworkRxBytes int64     `json:"networkRxBytes" example:"52428800"`
+	NetworkTxBytes int64     `json:"networkTxBytes" example:"26214400"`
+	Samples        int64     `json:"samples" example:"5"`
+}
+
+// TelemetrySeriesResponse is a server's utilization over a time range; steps
+// without samples are left out
+type TelemetrySeriesResponse struct {
+	ServerID string                   `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	From     time.Time                `json:"from" example:"2023-10-27T09:00:00Z"`
+	To       time.Time                `json:"to" example:"2023-10-27T10:00:00Z"`
+	Step     string                   `json:"step" example:"5m0s"`
+	Points   []TelemetryPointResponse `json:"points"`
+}
+
+// ConsistencyAnomalyResponse is an inconsistency between a server and its addresses
+type ConsistencyAnomalyResponse struct {
+	Kind        string `json:"kind" example:"ip_on_terminated_server"` // address_mismatch, ip_on_terminated_server, nat_on_terminated_server, orphaned_ip or stuck_provisioning
+	ServerID    string `json:"serverId,omitempty" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	IPAddressID string `json:"ipAddressId,omitempty" example:"9f8e7d6c-5b4a-3f2e-1d0c-b9a8f7e6d5c4"` // The address, or the NAT mapping for nat_on_terminated_server
+	Detail      string `json:"detail" example:"IP 10.0.0.12 still bound to the terminated server"`
+	Repaired    bool   `json:"repaired" example:"false"`
+	RepairError string `json:"repairError,omitempty" example:""`
+}
+
+// ConsistencyReportResponse lists the inconsistencies between servers and their addresses
+type ConsistencyReportResponse struct {
+	CheckedAt  time.Time                    `json:"checkedAt" example:"2023-10-27T10:15:00Z"`
+	AutoRepair bool                         `json:"autoRepair" example:"false"` // Whether the consistency daemon repairs what it finds
+	Anomalies  []ConsistencyAnomalyResponse `json:"anomalies"`
+}
+
+// CreateReaperPolicyRequest defines a rule of the idle reaper
+type CreateReaperPolicyRequest struct {
+	Name            string   `json:"name" example:"stop idle dev servers"`
+	MatchType       string   `json:"matchType,omitempty" example:"m5.large"`    // Server type; empty matches any
+	MatchRegion     string   `json:"matchRegion,omitempty" example:"us-east-1"` // Region; empty matches any
+	MatchTagKey     string   `json:"matchTagKey,omitempty" example:"env"`       // Tag key; empty matches any
+	MatchTagValue   string   `json:"matchTagValue,omitempty" example:"dev"`
+	Condition       string   `json:"condition" example:"idle_for"` // stopped_for, running_for or idle_for
+	DurationSeconds int64    `json:"durationSeconds" example:"3600"`
+	MaxCPUPercent   *float64 `json:"maxCpuPercent,omitempty" example:"5"`                             // CPU average over the duration at or below which a running server is idle; default 5
+	Action          string   `json:"action" example:"stop"`                                           // stop, terminate or notify
+	WebhookURL      string   `json:"webhookUrl,omitempty" example:"https://hooks.example.com/reaper"` // Required for notify
+}
+
+// ReaperPolicyResponse represents a rule of the idle reaper
+type ReaperPolicyResponse struct {
+	ID              string    `json:"id" example:"5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"`
+	Name            string    `json:"name" example:"stop idle dev servers"`
+	MatchType       string    `json:"matchType,omitempty" example:"m5.large"`
+	MatchRegion     string    `json:"matchRegion,omitempty" example:"us-east-1"`
+	MatchTagKey     string    `json:"matchTagKey,omitempty" example:"env"`
+	MatchTagValue   string    `json:"matchTagValue,omitempty" example:"dev"`
+	Condition       string    `json:"condition" example:"idle_for"`
+	DurationSeconds int64     `json:"durationSeconds" example:"3600"`
+	MaxCPUPercent   float64   `json:"maxCpuPercent" example:"5"`
+	Action          string    `json:"action" example:"stop"`
+	WebhookURL      string    `json:"webhookUrl,omitempty" example:"https://hooks.example.com/reaper"`
+	CreatedAt       time.Time `json:"createdAt" example:"2023-10-26T10:00:00Z"`
+}
+
+// ListReaperPoliciesResponse for listing reaper policies
+type ListReaperPoliciesResponse struct {
+	Policies []ReaperPolicyResponse `json:"policies"`
+}
+
+// ReaperCandidateResponse is a server a reaper policy applies to
+type ReaperCandidateResponse struct {
+	PolicyID   string    `json:"policyId" example:"5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"`
+	PolicyName string    `json:"policyName" example:"stop idle dev servers"`
+	Action     string    `json:"action" example:"stop"`
+	ServerID   string    `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	ServerName string    `json:"serverName" example:"my-app-server"`
+	Status     string    `json:"status" example:"running"`
+	Since      time.Time `json:"since" example:"2023-10-27T09:00:00Z"` // When the server entered its status
+}
+
+// ReaperDryRunResponse lists what the reaper would do now
+type ReaperDryRunResponse struct {
+	EvaluatedAt time.Time                 `json:"evaluatedAt" example:"2023-10-27T10:15:00Z"`
+	ExemptTag   string                    `json:"exemptTag" example:"reaper-exempt"`
+	Candidates  []ReaperCandidateResponse `json:"candidates"`
+}
+
+// ReaperActionResponse represents a reaper policy applied to a server
+type ReaperActionResponse struct {
+	ID         string    `json:"id" example:"9f8e7d6c-5b4a-3f2e-1d0c-b9a8f7e6d5c4"`
+	PolicyID   string    `json:"policyId" example:"5e4d3c2b-1a0f-9e8d-7c6b-5a4f3e2d1c0b"`
+	ServerID   string    `json:"serverId" example:"a1b2c3d4-e5f6-7890-1234-567890abcdef"`
+	Action     string    `json:"action" example:"stop"`
+	StateSince time.Time `json:"stateSince" example:"2023-10-27T09:00:00Z"`
+	Outcome    string    `json:"outcome" example:"done"` // pending, done or failed: <reason>
+	CreatedAt  time.Time `json:"createdAt" example:"2023-10-27T10:15:00Z"`
+}
+
+// ListReaperActionsResponse for listing reaper actions
+type ListReaperActionsResponse struct {
+	Actions []ReaperActionResponse `json:"actions"`
+	Limit   int                    `json:"limit"`
+	Offset  int                    `json:"offset"`
+}
+
+// ServerEventResponse is one event in a server's lifecycle history
+type ServerEventResponse struct {
+	ID         string          `json:"id" example:"3c2b1a0f-9e8d-7c6b-5a4f-3e2d1c0b9a8f"`
+	Sequence   int64           `json:"sequence" example:"42"` // Numbers the server's events in the order they were recorded
+	Type       string          `json:"type" example:"status_change"`
+	FromStatus string          `json:"fromStatus,omitempty" example:"stopped"` // Set for status changes
+	ToStatus   string          `json:"toStatus,omitempty" example:"running"`   // Set for status changes
+	Actor      string          `json:"actor" example:"api"`                    // api, system or the daemon that caused the event
+	RequestID  string          `json:"requestId,omitempty" example:"host/abcdef-000001"`
+	Payload    json.RawMessage `json:"payload" swaggertype:"object"` // The event's message and details
+	Timestamp  time.Time       `json:"timestamp" example:"2023-10-27T10:15:00Z"`
+}
+
+// ServerLogsResponse for server logs
 type ServerLogsResponse struct {
-	Logs []ServerLifecycleLogEntry `json:"logs"`
+	Logs   []ServerEventResponse `json:"logs"`
+	Limit  int                   `json:"limit"`
+	Offset int                   `json:"offset"`
+}
+
+// ReadyzResponse reports readiness and, per background job, whether this replica runs it;
+// Leader is left out when leader election is disabled
+type ReadyzResponse struct {
+	Status string          `json:"status" example:"OK"`
+	Leader map[string]bool `json:"leader,omitempty"`
 }
 
 // ToServerResponse converts a sqlc.Server to a ServerResponse
 func ToServerResponse(s sqlc.Server) ServerResponse {
-	return ServerResponse{
+	tags := map[string]string{}
+	_ = json.Unmarshal(s.Tags, &tags)
+
+	response := ServerResponse{
 		ID:               s.ID.String(),
 		Name:             s.Name,
+		Hostname:         s.Hostname,
 		Region:           s.Region,
+		Project:          s.Project,
 		Status:           string(s.Status),
 		Type:             string(s.Type),
+		DiskGB:           s.DiskGb,
+		Tags:             tags,
 		IPAddress:        s.Address,
+		PrivateIPAddress: s.Address,
 		ProvisionedAt:    s.ProvisionedAt.Time,
 		LastStatusUpdate: s.LastStatusUpdate.Time,
 		UptimeSeconds:    s.UptimeSeconds,
 		BillingInfo:      BillingInfo{},
 		HourlyCost:       float64(s.HourlyCost),
-		LifecycleLogs:    s.LifecycleLogs,
+		BillingModel:     s.BillingModel,
+		PurchaseOption:   s.PurchaseOption,
+		Spot:             ToSpotInfo(s.PurchaseOption, s.SpotMaxPrice, s.InterruptionBehavior, s.InterruptionNoticeAt),
 		CreatedAt:        s.CreatedAt.Time,
 		UpdatedAt:        s.UpdatedAt.Time,
 	}
+	if s.StuckSince.Valid {
+		response.StuckSince = &s.StuckSince.Time
+	}
+	return response
 }
 
-// ToBillingInfo converts server uptime and hourly cost into a BillingInfo struct.
-func ToBillingInfo(s sqlc.Server) BillingInfo {
-	estimatedCost := (float64(s.UptimeSeconds) / 3600.0) * s.HourlyCost
+// ToSpotInfo returns the spot details of a server, or nil for on-demand servers.
+func ToSpotInfo(purchaseOption string, maxPrice float64, interruptionBehavior string, noticeAt pgtype.Timestamptz) *SpotInfo {
+	if purchaseOption != "spot" {
+		return nil
+	}
+	info := &SpotInfo{MaxPrice: maxPrice, InterruptionBehavior: interruptionBehavior}
+	if noticeAt.Valid {
+		info.InterruptionNoticeAt = &noticeAt.Time
+	}
+	return info
+}
 
+// ToBillingInfo converts a server's metered usage into a BillingInfo struct.
+// UnitPrice is the catalog price in effect now; cost is what the server is
+// charged for the usage, see services.ServerUsage. Prices, cost and charges are
+// in USD and converted to currency at rate units per USD.
+func ToBillingInfo(s sqlc.Server, uptimeSeconds int64, cost float64, charges []BillingCharge, currency string, rate float64) BillingInfo {
+	for i := range charges {
+		charges[i].Amount *= rate
+	}
 	return BillingInfo{
-		BillingModel:         "immediate",
-		CurrencyUnit:         "USD",
-		UnitPrice:            s.HourlyCost,
+		BillingModel:         s.BillingModel,
+		CurrencyUnit:         currency,
+		UnitPrice:            s.HourlyCost * rate,
 		UpdatedTime:          s.UpdatedAt.Time,
-		TotalUptimeSeconds:   s.UptimeSeconds,
-		EstimatedCurrentCost: estimatedCost,
+		TotalUptimeSeconds:   uptimeSeconds,
+		EstimatedCurrentCost: cost * rate,
+		Charges:              charges,
+	}
+}
+
+// ToNetworkInterfaceResponses groups interfaces and their addresses by server ID.
+func ToNetworkInterfaceResponses(interfaces []sqlc.NetworkInterface, addresses []sqlc.ListIPAddressesByServerIDsRow) map[string][]NetworkInterfaceResponse {
+	byInterface := make(map[string][]InterfaceAddressResult, len(interfaces))
+	for _, a := range addresses {
+		byInterface[a.InterfaceID.String()] = append(byInterface[a.InterfaceID.String()], InterfaceAddressResult{
+			Address:   a.Address,
+			Pool:      a.PoolName.String,
+			IsPrimary: a.IsPrimary,
+		})
+	}
+
+	byServer := make(map[string][]NetworkInterfaceResponse)
+	for _, ni := range interfaces {
+		ips := byInterface[ni.ID.String()]
+		if ips == nil {
+			ips = []InterfaceAddressResult{}
+		}
+		byServer[ni.ServerID.String()] = append(byServer[ni.ServerID.String()], NetworkInterfaceResponse{
+			ID:          ni.ID.String(),
+			DeviceIndex: ni.DeviceIndex,
+			IsPrimary:   ni.IsPrimary,
+			IPAddresses: ips,
+			CreatedAt:   ni.CreatedAt.Time,
+		})
+	}
+	return byServer
+}
+
+// ToNATMappingResponse converts a sqlc.NatMapping to a NATMappingResponse
+func ToNATMappingResponse(m sqlc.NatMapping) NATMappingResponse {
+	response := NATMappingResponse{
+		ID:             m.ID.String(),
+		ServerID:       m.ServerID.String(),
+		PublicAddress:  m.PublicAddress,
+		PrivateAddress: m.PrivateAddress,
+		Active:         !m.ReleasedAt.Valid,
+		CreatedAt:      m.CreatedAt.Time,
+	}
+	if m.ReleasedAt.Valid {
+		releasedAt := m.ReleasedAt.Time
+		response.ReleasedAt = &releasedAt
+	}
+	return response
+}
+
+// ToInvoiceResponse converts a sqlc.Invoice and its lines to an InvoiceResponse
+func ToInvoiceResponse(invoice sqlc.Invoice, lines []sqlc.InvoiceLine) InvoiceResponse {
+	response := InvoiceResponse{
+		ID:           invoice.ID.String(),
+		Project:      invoice.Project,
+		PeriodStart:  invoice.PeriodStart.Time.Format(time.DateOnly),
+		PeriodEnd:    invoice.PeriodEnd.Time.Format(time.DateOnly),
+		Currency:     invoice.Currency,
+		ExchangeRate: invoice.ExchangeRate,
+		Total:        invoice.Total,
+		IssuedAt:     invoice.IssuedAt.Time,
+	}
+	for _, line := range lines {
+		response.Lines = append(response.Lines, InvoiceLineResponse{
+			ServerID:   line.ServerID.String(),
+			ServerName: line.ServerName,
+			ChargeType: line.ChargeType,
+			ServerType: line.ServerType,
+			Region:     line.Region,
+			Quantity:   line.Quantity,
+			Unit:       line.Unit,
+			UnitPrice:  line.UnitPrice,
+			Amount:     line.Amount,
+		})
+	}
+	return response
+}
+
+// ToPriceResponse converts a catalog entry to a PriceResponse; until is the time the next version replaces it
+func ToPriceResponse(price sqlc.Price, until pgtype.Timestamptz, now time.Time) PriceResponse {
+	response := PriceResponse{
+		ID:            price.ID.String(),
+		Type:          price.ServerType,
+		Region:        price.Region,
+		HourlyRate:    price.HourlyRate,
+		Currency:      price.Currency,
+		EffectiveFrom: price.EffectiveFrom.Time,
+		Current:       !price.EffectiveFrom.Time.After(now) && (!until.Valid || until.Time.After(now)),
+	}
+	if until.Valid {
+		effectiveUntil := until.Time
+		response.EffectiveUntil = &effectiveUntil
+	}
+	return response
+}
+
+// ToExchangeRateResponse converts an exchange rate version to an ExchangeRateResponse; until is the time the next version replaces it
+func ToExchangeRateResponse(rate sqlc.ExchangeRate, until pgtype.Timestamptz, now time.Time) ExchangeRateResponse {
+	response := ExchangeRateResponse{
+		ID:            rate.ID.String(),
+		Currency:      rate.Currency,
+		Rate:          rate.Rate,
+		EffectiveFrom: rate.EffectiveFrom.Time,
+		Current:       !rate.EffectiveFrom.Time.After(now) && (!until.Valid || until.Time.After(now)),
+	}
+	if until.Valid {
+		effectiveUntil := until.Time
+		response.EffectiveUntil = &effectiveUntil
+	}
+	return response
+}
+
+// ToBudgetResponse converts a sqlc.Budget to a BudgetResponse
+func ToBudgetResponse(budget sqlc.Budget) BudgetResponse {
+	return BudgetResponse{
+		ID:         budget.ID.String(),
+		Name:       budget.Name,
+		ScopeType:  budget.ScopeType,
+		ScopeKey:   budget.ScopeKey,
+		ScopeValue: budget.ScopeValue,
+		Amount:     budget.Amount,
+		Currency:   budget.Currency,
+		Period:     budget.Period,
+		Thresholds: budget.Thresholds,
+		WebhookURL: budget.WebhookUrl,
+		Enforce:    budget.Enforce,
+		CreatedAt:  budget.CreatedAt.Time,
+	}
+}
+
+// ToBudgetAlertResponse converts a sqlc.BudgetAlert to a BudgetAlertResponse
+func ToBudgetAlertResponse(alert sqlc.BudgetAlert) BudgetAlertResponse {
+	return BudgetAlertResponse{
+		ID:            alert.ID.String(),
+		BudgetID:      alert.BudgetID.String(),
+		PeriodStart:   alert.PeriodStart.Time,
+		Threshold:     alert.Threshold,
+		Spend:         alert.Spend,
+		Amount:        alert.Amount,
+		WebhookStatus: alert.WebhookStatus,
+		CreatedAt:     alert.CreatedAt.Time,
+	}
+}
+
+// ToReservationResponse converts a sqlc.Reservation to a ReservationResponse
+func ToReservationResponse(reservation sqlc.Reservation) ReservationResponse {
+	return ReservationResponse{
+		ID:             reservation.ID.String(),
+		Project:        reservation.Project,
+		Type:           reservation.ServerType,
+		Region:         reservation.Region,
+		Hours:          reservation.Hours,
+		HoursUsed:      reservation.HoursUsed,
+		HoursRemaining: reservation.Hours - reservation.HoursUsed,
+		HourlyRate:     reservation.HourlyRate,
+		UpfrontAmount:  reservation.Hours * reservation.HourlyRate,
+		Currency:       reservation.Currency,
+		StartsAt:       reservation.StartsAt.Time,
+		ExpiresAt:      reservation.ExpiresAt.Time,
+		CreatedAt:      reservation.CreatedAt.Time,
+	}
+}
+
+// ToVolumeTierResponse converts a sqlc.VolumeTier to a VolumeTierResponse
+func ToVolumeTierResponse(tier sqlc.VolumeTier) VolumeTierResponse {
+	return VolumeTierResponse{
+		ID:         tier.ID.String(),
+		Type:       tier.ServerType,
+		FromHours:  tier.FromHours,
+		PercentOff: tier.PercentOff,
+		CreatedAt:  tier.CreatedAt.Time,
+	}
+}
+
+// ToDiscountResponse converts a sqlc.Discount to a DiscountResponse
+func ToDiscountResponse(discount sqlc.Discount) DiscountResponse {
+	response := DiscountResponse{
+		ID:         discount.ID.String(),
+		Name:       discount.Name,
+		Type:       discount.ServerType,
+		Region:     discount.Region,
+		PercentOff: discount.PercentOff,
+		StartsAt:   discount.StartsAt.Time,
+		CreatedAt:  discount.CreatedAt.Time,
+	}
+	if discount.ExpiresAt.Valid {
+		expiresAt := discount.ExpiresAt.Time
+		response.ExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// ToCreditResponse converts a sqlc.Credit to a CreditResponse
+func ToCreditResponse(credit sqlc.Credit) CreditResponse {
+	return CreditResponse{
+		ID:         credit.ID.String(),
+		Project:    credit.Project,
+		Name:       credit.Name,
+		ScopeType:  credit.ScopeType,
+		ScopeValue: credit.ScopeValue,
+		Amount:     credit.Amount,
+		Remaining:  credit.Remaining,
+		Currency:   credit.Currency,
+		StartsAt:   credit.StartsAt.Time,
+		ExpiresAt:  credit.ExpiresAt.Time,
+		CreatedAt:  credit.CreatedAt.Time,
+	}
+}
+
+// ToAccountResponse converts a sqlc.Account to an AccountResponse
+func ToAccountResponse(account sqlc.Account) AccountResponse {
+	response := AccountResponse{
+		Project:             account.Project,
+		Balance:             account.Balance,
+		Currency:            account.Currency,
+		LowBalanceThreshold: account.LowBalanceThreshold,
+		Suspended:           account.SuspendedAt.Valid,
+		DrawnUntil:          account.DrawnUntil.Time,
+		CreatedAt:           account.CreatedAt.Time,
+		UpdatedAt:           account.UpdatedAt.Time,
+	}
+	if account.SuspendedAt.Valid {
+		suspendedAt := account.SuspendedAt.Time
+		response.SuspendedAt = &suspendedAt
+	}
+	return response
+}
+
+// ToAccountEventResponse converts a sqlc.AccountEvent to an AccountEventResponse
+func ToAccountEventResponse(event sqlc.AccountEvent) AccountEventResponse {
+	return AccountEventResponse{
+		ID:        event.ID.String(),
+		Project:   event.Project,
+		Type:      event.Type,
+		Amount:    event.Amount,
+		Balance:   event.Balance,
+		CreatedAt: event.CreatedAt.Time,
+	}
+}
+
+// ToTelemetryPointResponse converts a sqlc.GetTelemetrySeriesRow to a TelemetryPointResponse
+func ToTelemetryPointResponse(point sqlc.GetTelemetrySeriesRow) TelemetryPointResponse {
+	return TelemetryPointResponse{
+		Time:           point.Bucket.Time,
+		CPUPercentAvg:  point.CpuPercentAvg,
+		CPUPercentMax:  point.CpuPercentMax,
+		NetworkRxBytes: point.NetworkRxBytes,
+		NetworkTxBytes: point.NetworkTxBytes,
+		Samples:        point.Samples,
+	}
+}
+
+// ToReaperPolicyResponse converts a sqlc.ReaperPolicy to a ReaperPolicyResponse
+func ToReaperPolicyResponse(policy sqlc.ReaperPolicy) ReaperPolicyResponse {
+	return ReaperPolicyResponse{
+		ID:              policy.ID.String(),
+		Name:            policy.Name,
+		MatchType:       policy.MatchType,
+		MatchRegion:     policy.MatchRegion,
+		MatchTagKey:     policy.MatchTagKey,
+		MatchTagValue:   policy.MatchTagValue,
+		Condition:       policy.Condition,
+		DurationSeconds: policy.DurationSeconds,
+		MaxCPUPercent:   policy.MaxCpuPercent,
+		Action:          policy.Action,
+		WebhookURL:      policy.WebhookUrl,
+		CreatedAt:       policy.CreatedAt.Time,
+	}
+}
+
+// ToReaperCandidateResponse describes a server a reaper policy applies to
+func ToReaperCandidateResponse(policy sqlc.ReaperPolicy, server sqlc.Server) ReaperCandidateResponse {
+	return ReaperCandidateResponse{
+		PolicyID:   policy.ID.String(),
+		PolicyName: policy.Name,
+		Action:     policy.Action,
+		ServerID:   server.ID.String(),
+		ServerName: server.Name,
+		Status:     server.Status,
+		Since:      server.LastStatusUpdate.Time,
+	}
+}
+
+// ToReaperActionResponse converts a sqlc.ReaperAction to a ReaperActionResponse
+func ToReaperActionResponse(action sqlc.ReaperAction) ReaperActionResponse {
+	return ReaperActionResponse{
+		ID:         action.ID.String(),
+		PolicyID:   action.PolicyID.String(),
+		ServerID:   action.ServerID.String(),
+		Action:     action.Action,
+		StateSince: action.StateSince.Time,
+		Outcome:    action.Outcome,
+		CreatedAt:  action.CreatedAt.Time,
+	}
+}
+
+// ToServerEventResponse converts a sqlc.ServerEvent to a ServerEventResponse
+func ToServerEventResponse(event sqlc.ServerEvent) ServerEventResponse {
+	return ServerEventResponse{
+		ID:         event.ID.String(),
+		Sequence:   event.Sequence,
+		Type:       event.Type,
+		FromStatus: event.FromStatus.String,
+		ToStatus:   event.ToStatus.String,
+		Actor:      event.Actor,
+		RequestID:  event.RequestID.String,
+		Payload:    event.Payload,
+		Timestamp:  event.CreatedAt.Time,
+	}
+}
+
+// ToSpotPriceResponse converts a sqlc.SpotPrice to a SpotPriceResponse
+func ToSpotPriceResponse(price sqlc.SpotPrice) SpotPriceResponse {
+	return SpotPriceResponse{
+		Type:          price.ServerType,
+		Region:        price.Region,
+		Price:         price.Price,
+		Currency:      "USD",
+		EffectiveFrom: price.EffectiveFrom.Time,
 	}
 }
@@ -0,0 +1,435 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+const (
+	// AccountEventTopUp records funds added to an account.
+	AccountEventTopUp = "topup"
+	// AccountEventLowBalance records the balance falling to the account's low-balance threshold.
+	AccountEventLowBalance = "low_balance"
+	// AccountEventExhausted records the balance running out and the project's servers being suspended.
+	AccountEventExhausted = "exhausted"
+	// AccountEventResumed records the suspended servers of a topped-up project running again.
+	AccountEventResumed = "resumed"
+)
+
+var (
+	// ErrAccountNotFound is returned when a project has no prepaid account.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrInvalidTopUp is returned for top-ups that are not a positive amount.
+	ErrInvalidTopUp = errors.New("invalid top-up")
+	// ErrAccountExhausted is returned when a server of a project whose prepaid balance has run out is started.
+	ErrAccountExhausted = errors.New("prepaid balance of the project is exhausted; top up its account to start servers")
+)
+
+// AccountService manages the prepaid balances of projects. The billing daemon
+// draws them down as servers accrue cost and suspends the running servers of a
+// project whose balance runs out; a top-up resumes them.
+type AccountService struct {
+	queries       *sqlc.Queries
+	billing       *BillingService
+	serverService *ServerService
+	logger        *zap.Logger
+	config        *config.Config
+}
+
+// NewAccountService creates a new AccountService. Servers are suspended and resumed through serverService.
+func NewAccountService(queries *sqlc.Queries, billing *BillingService, serverService *ServerService, logger *zap.Logger, config *config.Config) *AccountService {
+	return &AccountService{
+		queries:       queries,
+		billing:       billing,
+		serverService: serverService,
+		logger:        logger,
+		config:        config,
+	}
+}
+
+// GetAccount returns the prepaid account of a project.
+func (as *AccountService) GetAccount(ctx context.Context, project string) (sqlc.Account, error) {
+	account, err := as.queries.GetAccount(ctx, project)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Account{}, ErrAccountNotFound
+	}
+	if err != nil {
+		return sqlc.Account{}, fmt.Errorf("failed to get account: %+v", err)
+	}
+	return account, nil
+}
+
+// ListAccountEvents returns the events of a project's account, newest first.
+func (as *AccountService) ListAccountEvents(ctx context.Context, project string, limit, offset int) ([]sqlc.AccountEvent, error) {
+	events, err := as.queries.ListAccountEvents(ctx, sqlc.ListAccountEventsParams{
+		Project:   project,
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list account events: %+v", err)
+	}
+	return events, nil
+}
+
+// TopUp adds amount, in USD, to a project's balance, opening its account on the
+// first top-up. A nil lowBalanceThreshold keeps the account's threshold, or
+// LOW_BALANCE_THRESHOLD for a new account. If the project was suspended and the
+// balance is positive again, its suspended servers are resumed.
+func (as *AccountService) TopUp(ctx context.Context, project string, amount float64, lowBalanceThreshold *float64) (sqlc.Account, error) {
+	if amount <= 0 {
+		return sqlc.Account{}, fmt.Errorf("%w: amount must be positive", ErrInvalidTopUp)
+	}
+	if lowBalanceThreshold != nil && *lowBalanceThreshold < 0 {
+		return sqlc.Account{}, fmt.Errorf("%w: lowBalanceThreshold must not be negative", ErrInvalidTopUp)
+	}
+
+	threshold := as.config.LowBalanceThreshold
+	existing, err := as.GetAccount(ctx, project)
+	if err != nil && !errors.Is(err, ErrAccountNotFound) {
+		return sqlc.Account{}, err
+	}
+	if lowBalanceThreshold != nil {
+		threshold = *lowBalanceThreshold
+	} else if err == nil {
+		threshold = existing.LowBalanceThreshold
+	}
+	// A new account only pays for what the project is charged from now on
+	var charged float64
+	if errors.Is(err, ErrAccountNotFound) {
+		charged, err = as.projectCharges(ctx, project, time.Now())
+		if err != nil {
+			return sqlc.Account{}, err
+		}
+	}
+
+	account, err := as.queries.TopUpAccount(ctx, sqlc.TopUpAccountParams{
+		Project:             project,
+		Amount:              amount,
+		LowBalanceThreshold: threshold,
+		Charged:             charged,
+	})
+	if err != nil {
+		return sqlc.Account{}, fmt.Errorf("failed to top up account: %+v", err)
+	}
+	as.recordEvent(ctx, account, AccountEventTopUp, amount)
+	as.logger.Info("Account topped up",
+		zap.String("project", project),
+		zap.Float64("amount", amount),
+		zap.Float64("balance", account.Balance),
+	)
+
+	if account.SuspendedAt.Valid && account.Balance > 0 {
+		if err := as.resumeProject(ctx, account); err != nil {
+			return sqlc.Account{}, err
+		}
+		return as.GetAccount(ctx, project)
+	}
+	return account, nil
+}
+
+// DrawDownAccounts charges every account for the usage of its project since the
+// previous draw-down, then warns about low balances and suspends projects whose
+// balance has run out.
+func (as *AccountService) DrawDownAccounts(ctx context.Context, now time.Time) error {
+	accounts, err := as.queries.ListAccounts(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list accounts: %+v", err)
+	}
+
+	for _, account := range accounts {
+		if err := as.drawDown(ctx, account, now); err != nil {
+			as.logger.Error("Failed to draw down account", zap.Error(err), zap.String("project", account.Project))
+		}
+	}
+	return nil
+}
+
+// projectCharges returns what a project is charged so far, in USD: the amounts
+// written to its ledger, with reservations, rounding, volume tiers, discounts and
+// credits applied, plus the estimate of its usage not written yet that server
+// costs show. Servers terminated before since are known to be in the ledger.
+func (as *AccountService) projectCharges(ctx context.Context, project string, since time.Time) (float64, error) {
+	charged, err := as.queries.SumProjectLedger(ctx, project)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum ledger: %+v", err)
+	}
+	serverIDs, err := as.queries.ListUnbilledServerIDsByProject(ctx, sqlc.ListUnbilledServerIDsByProjectParams{
+		Project: project,
+		Since:   pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list servers of project: %+v", err)
+	}
+	unbilled, err := as.serverService.unbilledUsage(ctx, serverIDs)
+	if err != nil {
+		return 0, err
+	}
+	for _, usage := range unbilled {
+		charged += usage.Cost
+	}
+	return charged, nil
+}
+
+// drawDown charges one account for what its project was charged since the
+// previous draw-down, see projectCharges.
+func (as *AccountService) drawDown(ctx context.Context, account sqlc.Account, now time.Time) error {
+	charged, err := as.projectCharges(ctx, account.Project, account.DrawnUntil.Time)
+	if err != nil {
+		return err
+	}
+	spend := charged - account.Charged
+
+	updated, err := as.queries.DrawDownAccount(ctx, sqlc.DrawDownAccountParams{
+		Project:      account.Project,
+		TotalCharged: charged,
+		DrawnUntil:   pgtype.Timestamptz{Time: maxTime(account.DrawnUntil.Time, now), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to draw down account: %+v", err)
+	}
+	as.logger.Debug("Account drawn down",
+		zap.String("project", updated.Project),
+		zap.Float64("spend", spend),
+		zap.Float64("balance", updated.Balance),
+	)
+
+	if needsLowBalanceWarning(updated) {
+		if err := as.queries.SetAccountLowBalanceWarned(ctx, updated.Project); err != nil {
+			return fmt.Errorf("failed to mark low balance warned: %+v", err)
+		}
+		as.recordEvent(ctx, updated, AccountEventLowBalance, 0)
+		as.logger.Warn("Account balance is low",
+			zap.String("project", updated.Project),
+			zap.Float64("balance", updated.Balance),
+			zap.Float64("threshold", updated.LowBalanceThreshold),
+		)
+	}
+	if needsSuspension(updated) {
+		return as.suspendProject(ctx, updated)
+	}
+	return nil
+}
+
+// needsLowBalanceWarning reports whether the account's balance has fallen to its
+// threshold without a warning for it yet.
+func needsLowBalanceWarning(account sqlc.Account) bool {
+	return account.Balance <= account.LowBalanceThreshold && !account.LowBalanceWarned
+}
+
+// needsSuspension reports whether the account's balance has run out and its
+// project is not suspended yet.
+func needsSuspension(account sqlc.Account) bool {
+	return account.Balance <= 0 && !account.SuspendedAt.Valid
+}
+
+// suspendProject marks the account suspended, which keeps the project's servers
+// from being started, and suspends its running servers.
+func (as *AccountService) suspendProject(ctx context.Context, account sqlc.Account) error {
+	if err := as.queries.SuspendAccount(ctx, account.Project); err != nil {
+		return fmt.Errorf("failed to suspend account: %+v", err)
+	}
+	as.recordEvent(ctx, account, AccountEventExhausted, 0)
+	ctx = WithEventActor(ctx, ActorAccount)
+
+	servers, err := as.queries.ListServersByProjectAndStatus(ctx, sqlc.ListServersByProjectAndStatusParams{
+		Project: account.Project,
+		Status:  util.ServerStatusRunning,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list running servers of project: %+v", err)
+	}
+	for _, server := range servers {
+		if _, err := as.serverService.SuspendServer(ctx, server); err != nil {
+			as.logger.Error("Failed to suspend server", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+
+	as.logger.Warn("Account balance exhausted, project suspended",
+		zap.String("project", account.Project),
+		zap.Float64("balance", account.Balance),
+		zap.Int("servers", len(servers)),
+	)
+	return nil
+}
+
+// resumeProject lifts the suspension of a topped-up account and runs its suspended servers again.
+func (as *AccountService) resumeProject(ctx context.Context, account sqlc.Account) error {
+	if err := as.queries.ResumeAccount(ctx, account.Project); err != nil {
+		return fmt.Errorf("failed to resume account: %+v", err)
+	}
+	as.recordEvent(ctx, account, AccountEventResumed, 0)
+	ctx = WithEventActor(ctx, ActorAccount)
+
+	servers, err := as.queries.ListServersByProjectAndStatus(ctx, sqlc.ListServersByProjectAndStatusParams{
+		Project: account.Project,
+		Status:  util.ServerStatusSuspended,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list suspended servers of project: %+v", err)
+	}
+	for _, server := range servers {
+		if _, err := as.serverService.ResumeServer(ctx, server); err != nil {
+			as.logger.Error("Failed to resume server", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+
+	as.logger.Info("Account topped up, project resumed",
+		zap.String("project", account.Project),
+		zap.Float64("balance", account.Balance),
+		zap.Int("servers", len(servers)),
+	)
+	return nil
+}
+
+// recordEvent stores an account event; failures are logged, as the balance itself is already updated.
+func (as *AccountService) recordEvent(ctx context.Context, account sqlc.Account, eventType string, amount float64) {
+	_, err := as.queries.CreateAccountEvent(ctx, sqlc.CreateAccountEventParams{
+		Project: account.Project,
+		Type:    eventType,
+		Amount:  amount,
+		Balance: account.Balance,
+	})
+	if err != nil {
+		as.logger.Error("Failed to record account event", zap.Error(err), zap.String("project", account.Project), zap.String("type", eventType))
+	}
+}
+
+// checkAccount returns ErrAccountExhausted if the server's project has a prepaid
+// account without any balance left. Projects without an account are not limited.
+func (s *ServerService) checkAccount(ctx context.Context, server sqlc.Server) error {
+	account, err := s.queries.GetAccount(ctx, server.Project)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get account: %+v", err)
+	}
+	if account.Balance <= 0 {
+		return ErrAccountExhausted
+	}
+	return nil
+}
+
+// SuspendServer moves a running server to suspended and stops metering its
+// compute, disk and public IP, so it costs nothing until it is resumed. Its
+// addresses are kept.
+func (s *ServerService) SuspendServer(ctx context.Context, server sqlc.Server) (sqlc.Server, error) {
+	if !util.IsValidTransition(server.Status, util.ServerStatusSuspended) {
+		return sqlc.Server{}, fmt.Errorf("%+v from %s to %s", "invalid state transition", server.Status, util.ServerStatusSuspended)
+	}
+
+	var updatedServer sqlc.Server
+	err := s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		updatedServer, err = q.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
+			Status:          util.ServerStatusSuspended,
+			ID:              server.ID,
+			LastStatusActor: eventActor(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update server status to suspended: %+v", err)
+		}
+		if err := s.closeUsageSegment(ctx, q, server.ID); err != nil {
+			return err
+		}
+		if err := q.CloseResourceSegmentsByServerID(ctx, server.ID); err != nil {
+			return fmt.Errorf("failed to close resource segments: %+v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   util.ServerStatusSuspended,
+		Message:    "Server suspended: prepaid balance exhausted",
+		Details:    map[string]any{"project": server.Project},
+	})
+
+	s.logger.Info("Server suspended", zap.String("server_id", server.ID.String()), zap.String("project", server.Project))
+	return updatedServer, nil
+}
+
+// ResumeServer runs a suspended server again and resumes metering what it held
+// when it was suspended. A spot server whose bid is now below the spot price is
+// stopped instead, as the spot market would have done, and can be started once
+// its bid covers the price again.
+func (s *ServerService) ResumeServer(ctx context.Context, server sqlc.Server) (sqlc.Server, error) {
+	if server.Status != util.ServerStatusSuspended {
+		return sqlc.Server{}, fmt.Errorf("%+v from %s to %s", "invalid state transition", server.Status, util.ServerStatusRunning)
+	}
+
+	status, message := util.ServerStatusRunning, "Server resumed after account top-up"
+	if err := s.checkSpotBid(ctx, server); errors.Is(err, ErrSpotBidTooLow) {
+		status, message = util.ServerStatusStopped, "Server stopped after account top-up: outbid by the spot price"
+	} else if err != nil {
+		return sqlc.Server{}, err
+	}
+	// A stopped server keeps paying for its disk, and for its public IP unless it is released on stop
+	releasePublicIP := status == util.ServerStatusStopped && s.config.ReleasePublicIPOnStop
+
+	var updatedServer sqlc.Server
+	err := s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		updatedServer, err = q.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
+			Status:          status,
+			ID:              server.ID,
+			LastStatusActor: eventActor(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update server status to %s: %+v", status, err)
+		}
+		if status == util.ServerStatusRunning {
+			if err := s.openUsageSegment(ctx, q, updatedServer); err != nil {
+				return err
+			}
+		}
+		if err := s.openResourceSegment(ctx, q, server.ID, ResourceDisk, float64(server.DiskGb)); err != nil {
+			return err
+		}
+		if releasePublicIP {
+			return nil
+		}
+		_, err = q.GetActiveNATMappingByServerID(ctx, server.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get NAT mapping: %+v", err)
+		}
+		return s.openResourceSegment(ctx, q, server.ID, ResourcePublicIP, 1)
+	})
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	if releasePublicIP {
+		if err := s.releasePublicIP(ctx, server); err != nil {
+			s.logger.Error("Failed to release public IP on stop", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   status,
+		Message:    message,
+		Details:    map[string]any{"project": server.Project},
+	})
+
+	s.logger.Info("Server resumed", zap.String("server_id", server.ID.String()), zap.String("project", server.Project), zap.String("status", status))
+	return updatedServer, nil
+}
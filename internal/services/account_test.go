@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+func TestAccountThresholds(t *testing.T) {
+	suspended := timestamptz(mustTime(t, "2026-03-01T00:00:00Z"))
+	tests := []struct {
+		name        string
+		account     sqlc.Account
+		wantWarning bool
+		wantSuspend bool
+	}{
+		{name: "above threshold", account: sqlc.Account{Balance: 50, LowBalanceThreshold: 10}},
+		{name: "at threshold", account: sqlc.Account{Balance: 10, LowBalanceThreshold: 10}, wantWarning: true},
+		{name: "below threshold, warned", account: sqlc.Account{Balance: 5, LowBalanceThreshold: 10, LowBalanceWarned: true}},
+		{name: "exhausted", account: sqlc.Account{Balance: 0, LowBalanceThreshold: 10}, wantWarning: true, wantSuspend: true},
+		{name: "overdrawn, warned", account: sqlc.Account{Balance: -2, LowBalanceThreshold: 10, LowBalanceWarned: true}, wantSuspend: true},
+		{name: "overdrawn, suspended", account: sqlc.Account{Balance: -2, LowBalanceWarned: true, SuspendedAt: suspended}},
+		{name: "no threshold", account: sqlc.Account{Balance: 0.01}},
+	}
+	for _, tt := range tests {
+		if got := needsLowBalanceWarning(tt.account); got != tt.wantWarning {
+			t.Errorf("needsLowBalanceWarning(%s) = %v, want %v", tt.name, got, tt.wantWarning)
+		}
+		if got := needsSuspension(tt.account); got != tt.wantSuspend {
+			t.Errorf("needsSuspension(%s) = %v, want %v", tt.name, got, tt.wantSuspend)
+		}
+	}
+}
+
+func TestTopUpRejects(t *testing.T) {
+	var as AccountService
+	negative := -1.0
+	tests := []struct {
+		name      string
+		amount    float64
+		threshold *float64
+	}{
+		{name: "no amount", amount: 0},
+		{name: "negative amount", amount: -10},
+		{name: "negative threshold", amount: 10, threshold: &negative},
+	}
+	for _, tt := range tests {
+		if _, err := as.TopUp(context.Background(), "acme", tt.amount, tt.threshold); !errors.Is(err, ErrInvalidTopUp) {
+			t.Errorf("TopUp with %s = %v, want ErrInvalidTopUp", tt.name, err)
+		}
+	}
+}
+
+func TestSuspendAndResumeRejectTransitions(t *testing.T) {
+	var s ServerService
+	ctx := context.Background()
+	for _, status := range []string{util.ServerStatusStopped, util.ServerStatusSuspended, util.ServerStatusTerminated} {
+		if _, err := s.SuspendServer(ctx, sqlc.Server{Status: status}); err == nil {
+			t.Errorf("SuspendServer of a %s server succeeded", status)
+		}
+	}
+	for _, status := range []string{util.ServerStatusRunning, util.ServerStatusStopped} {
+		if _, err := s.ResumeServer(ctx, sqlc.Server{Status: status}); err == nil {
+			t.Errorf("ResumeServer of a %s server succeeded", status)
+		}
+	}
+	if util.IsValidTransition(util.ServerStatusSuspended, util.ServerStatusRunning) {
+		t.Error("a suspended server can be started without a top-up")
+	}
+}
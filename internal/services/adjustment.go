@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// CreditScopeProject credits apply to every charge of the project.
+	CreditScopeProject = "project"
+	// CreditScopeServerType credits apply to the charges of one server type.
+	CreditScopeServerType = "server_type"
+	// CreditScopeRegion credits apply to the charges of one region.
+	CreditScopeRegion = "region"
+
+	// adjustmentAny matches every server type or region in a discount.
+	adjustmentAny = "*"
+)
+
+var (
+	// ErrInvalidPercentOff is returned when a discount or tier is not between 0 and 100 percent.
+	ErrInvalidPercentOff = errors.New("percentOff must be greater than 0 and at most 100")
+	// ErrInvalidVolumeTier is returned when a volume tier does not start after some hours.
+	ErrInvalidVolumeTier = errors.New("fromHours must be positive")
+	// ErrVolumeTierExists is returned when the server type already has a tier starting at those hours.
+	ErrVolumeTierExists = errors.New("a volume tier already starts at these hours for this server type")
+	// ErrInvalidDiscount is returned when a discount expires before it starts.
+	ErrInvalidDiscount = errors.New("expiresAt must be after startsAt")
+	// ErrInvalidCredit is returned for credits without an amount, an expiry or a valid scope.
+	ErrInvalidCredit = errors.New("invalid credit")
+)
+
+// CreateVolumeTier adds a tier to the volume pricing of a server type: compute
+// hours of a project period beyond fromHours get percentOff, up to the next tier.
+func (b *BillingService) CreateVolumeTier(ctx context.Context, serverType string, fromHours, percentOff float64) (sqlc.VolumeTier, error) {
+	if fromHours <= 0 {
+		return sqlc.VolumeTier{}, ErrInvalidVolumeTier
+	}
+	if percentOff <= 0 || percentOff > 100 {
+		return sqlc.VolumeTier{}, ErrInvalidPercentOff
+	}
+
+	tier, err := b.db.Queries.CreateVolumeTier(ctx, sqlc.CreateVolumeTierParams{
+		ServerType: serverType,
+		FromHours:  fromHours,
+		PercentOff: percentOff,
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return sqlc.VolumeTier{}, ErrVolumeTierExists
+	}
+	if err != nil {
+		return sqlc.VolumeTier{}, fmt.Errorf("failed to create volume tier: %+v", err)
+	}
+
+	b.logger.Info("Volume tier added",
+		zap.String("type", tier.ServerType),
+		zap.Float64("from_hours", tier.FromHours),
+		zap.Float64("percent_off", tier.PercentOff),
+	)
+	return tier, nil
+}
+
+// ListVolumeTiers returns every volume tier, by server type and threshold.
+func (b *BillingService) ListVolumeTiers(ctx context.Context) ([]sqlc.VolumeTier, error) {
+	tiers, err := b.db.Queries.ListVolumeTiers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volume tiers: %+v", err)
+	}
+	return tiers, nil
+}
+
+// CreateDiscount stores a percentage discount on compute charges. An empty server
+// type or region matches any, a zero start means now and no expiry means it
+// never expires.
+func (b *BillingService) CreateDiscount(ctx context.Context, params sqlc.CreateDiscountParams) (sqlc.Discount, error) {
+	if params.PercentOff <= 0 || params.PercentOff > 100 {
+		return sqlc.Discount{}, ErrInvalidPercentOff
+	}
+	if params.ServerType == "" {
+		params.ServerType = adjustmentAny
+	}
+	if params.Region == "" {
+		params.Region = adjustmentAny
+	}
+	if !params.StartsAt.Valid {
+		params.StartsAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	if params.ExpiresAt.Valid && !params.ExpiresAt.Time.After(params.StartsAt.Time) {
+		return sqlc.Discount{}, ErrInvalidDiscount
+	}
+
+	discount, err := b.db.Queries.CreateDiscount(ctx, params)
+	if err != nil {
+		return sqlc.Discount{}, fmt.Errorf("failed to create discount: %+v", err)
+	}
+
+	b.logger.Info("Discount added",
+		zap.String("discount_id", discount.ID.String()),
+		zap.String("type", discount.ServerType),
+		zap.String("region", discount.Region),
+		zap.Float64("percent_off", discount.PercentOff),
+	)
+	return discount, nil
+}
+
+// ListDiscounts returns every discount, newest first.
+func (b *BillingService) ListDiscounts(ctx context.Context) ([]sqlc.Discount, error) {
+	discounts, err := b.db.Queries.ListDiscounts(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list discounts: %+v", err)
+	}
+	return discounts, nil
+}
+
+// CreateCredit grants a project a promotional credit in USD. A zero start means now.
+func (b *BillingService) CreateCredit(ctx context.Context, params sqlc.CreateCreditParams) (sqlc.Credit, error) {
+	if params.Project == "" {
+		params.Project = DefaultProject
+	}
+	if params.ScopeType == "" {
+		params.ScopeType = CreditScopeProject
+	}
+	if !params.StartsAt.Valid {
+		params.StartsAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	params.Currency = CurrencyUSD
+
+	switch {
+	case params.Amount <= 0:
+		return sqlc.Credit{}, fmt.Errorf("%w: amount must be positive", ErrInvalidCredit)
+	case !params.ExpiresAt.Valid || !params.ExpiresAt.Time.After(params.StartsAt.Time):
+		return sqlc.Credit{}, fmt.Errorf("%w: expiresAt must be after startsAt", ErrInvalidCredit)
+	case params.ScopeType == CreditScopeProject:
+		params.ScopeValue = ""
+	case params.ScopeType == CreditScopeServerType || params.ScopeType == CreditScopeRegion:
+		if params.ScopeValue == "" {
+			return sqlc.Credit{}, fmt.Errorf("%w: scopeValue is required for %s credits", ErrInvalidCredit, params.ScopeType)
+		}
+	default:
+		return sqlc.Credit{}, fmt.Errorf("%w: scopeType must be project, server_type or region", ErrInvalidCredit)
+	}
+
+	credit, err := b.db.Queries.CreateCredit(ctx, params)
+	if err != nil {
+		return sqlc.Credit{}, fmt.Errorf("failed to create credit: %+v", err)
+	}
+
+	b.logger.Info("Credit granted",
+		zap.String("credit_id", credit.ID.String()),
+		zap.String("project", credit.Project),
+		zap.String("scope_type", credit.ScopeType),
+		zap.String("scope_value", credit.ScopeValue),
+		zap.Float64("amount", credit.Amount),
+		zap.Time("expires_at", credit.ExpiresAt.Time),
+	)
+	return credit, nil
+}
+
+// ListCredits returns credits, newest first, optionally of one project only.
+func (b *BillingService) ListCredits(ctx context.Context, project string, limit, offset int) ([]sqlc.Credit, error) {
+	credits, err := b.db.Queries.ListCredits(ctx, sqlc.ListCreditsParams{
+		Project:   pgtype.Text{String: project, Valid: project != ""},
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credits: %+v", err)
+	}
+	return credits, nil
+}
+
+type computeKey struct {
+	serverType string
+	region     string
+}
+
+// computeCharge is the running time of a server type in a region in a period and
+// what is left to pay for it after the adjustments applied so far.
+type computeCharge struct {
+	hours  float64
+	amount float64
+}
+
+// applyAdjustments books the adjustments of a project period to the ledger before
+// it is invoiced, each step on what the previous ones left to pay:
+//
+//  1. volume tiers, on the compute hours of each server type across regions,
+//     at their average price;
+//  2. discounts, the largest one matching each server type and region, on
+//     what is left of its compute charges;
+//  3. credits, soonest to expire first, on what is left of every charge in
+//     their scope, until they run out.
+//
+// Every adjustment is a negative ledger entry of its own, so it shows as its own
+// invoice line.
+func (b *BillingService) applyAdjustments(ctx context.Context, q *sqlc.Queries, project string, periodStart time.Time) error {
+	period := pgtype.Date{Time: periodStart, Valid: true}
+	periodEnd := PeriodEnd(periodStart)
+	charges, err := q.SummarizeLedgerCharges(ctx, sqlc.SummarizeLedgerChargesParams{
+		Project:     project,
+		PeriodStart: period,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to summarize ledger: %+v", err)
+	}
+
+	compute := make(map[computeKey]*computeCharge)
+	var keys []computeKey
+	for _, charge := range charges {
+		if charge.ChargeType != ChargeTypeCompute && charge.ChargeType != ChargeTypeComputeRounding {
+			continue
+		}
+		key := computeKey{charge.ServerType, charge.Region}
+		if compute[key] == nil {
+			compute[key] = &computeCharge{}
+			keys = append(keys, key)
+		}
+		compute[key].hours += charge.Quantity
+		compute[key].amount += charge.Amount
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].serverType != keys[j].serverType {
+			return keys[i].serverType < keys[j].serverType
+		}
+		return keys[i].region < keys[j].region
+	})
+
+	book := func(chargeType, serverType, region, description string, quantity float64, unit string, unitPrice float64) error {
+		_, err := q.CreateLedgerEntry(ctx, sqlc.CreateLedgerEntryParams{
+			Project:     project,
+			PeriodStart: period,
+			ChargeType:  chargeType,
+			ServerType:  serverType,
+			Region:      region,
+			Description: description,
+			UsageStart:  pgtype.Timestamptz{Time: periodStart, Valid: true},
+			UsageEnd:    pgtype.Timestamptz{Time: periodEnd, Valid: true},
+			Quantity:    quantity,
+			Unit:        unit,
+			UnitPrice:   unitPrice,
+			Amount:      quantity * unitPrice,
+			Currency:    CurrencyUSD,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create %s ledger entry: %+v", chargeType, err)
+		}
+		return nil
+	}
+
+	// 1. Volume tiers
+	tiers, err := q.ListVolumeTiers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list volume tiers: %+v", err)
+	}
+	var tierTypes []string
+	tiersByType := make(map[string][]sqlc.VolumeTier)
+	for _, tier := range tiers {
+		if tiersByType[tier.ServerType] == nil {
+			tierTypes = append(tierTypes, tier.ServerType)
+		}
+		tiersByType[tier.ServerType] = append(tiersByType[tier.ServerType], tier)
+	}
+	for _, serverType := range tierTypes {
+		typeTiers := tiersByType[serverType]
+		var hours, amount float64
+		for _, key := range keys {
+			if key.serverType == serverType {
+				hours += compute[key].hours
+				amount += compute[key].amount
+			}
+		}
+		if hours <= 0 || amount <= 0 {
+			continue
+		}
+		averageRate := amount / hours
+
+		var discount float64
+		for i, band := range volumeTierBands(typeTiers, hours) {
+			if band <= 0 {
+				continue
+			}
+			tier := typeTiers[i]
+			unitPrice := -averageRate * tier.PercentOff / 100
+			description := fmt.Sprintf("%s hours over %g, %g%% volume discount", serverType, tier.FromHours, tier.PercentOff)
+			if err := book(ChargeTypeVolumeDiscount, serverType, "", description, band, "hour", unitPrice); err != nil {
+				return err
+			}
+			discount -= band * unitPrice
+		}
+		// The discount is shared between regions by what their hours cost
+		for _, key := range keys {
+			if key.serverType == serverType {
+				compute[key].amount -= discount * compute[key].amount / amount
+			}
+		}
+	}
+
+	// 2. Discounts
+	discounts, err := q.ListDiscountsInPeriod(ctx, sqlc.ListDiscountsInPeriodParams{
+		PeriodStart: pgtype.Timestamptz{Time: periodStart, Valid: true},
+		PeriodEnd:   pgtype.Timestamptz{Time: periodEnd, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list discounts: %+v", err)
+	}
+	for _, key := range keys {
+		charge := compute[key]
+		if charge.hours <= 0 || charge.amount <= 0 {
+			continue
+		}
+		discount, ok := matchDiscount(discounts, key)
+		if !ok {
+			continue
+		}
+		unitPrice := -charge.amount / charge.hours * discount.PercentOff / 100
+		description := fmt.Sprintf("%s in %s, %g%% off (%s)", key.serverType, key.region, discount.PercentOff, discount.Name)
+		if err := book(ChargeTypeDiscount, key.serverType, key.region, description, charge.hours, "hour", unitPrice); err != nil {
+			return err
+		}
+		charge.amount += charge.hours * unitPrice
+	}
+
+	// 3. Credits, on what is left to pay per server type and region
+	credits, err := q.ListCreditsInPeriodForUpdate(ctx, sqlc.ListCreditsInPeriodForUpdateParams{
+		Project:     project,
+		PeriodStart: pgtype.Timestamptz{Time: periodStart, Valid: true},
+		PeriodEnd:   pgtype.Timestamptz{Time: periodEnd, Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list credits: %+v", err)
+	}
+	if len(credits) == 0 {
+		return nil
+	}
+	due := make(map[computeKey]float64)
+	for _, charge := range charges {
+		if charge.ChargeType != ChargeTypeCompute && charge.ChargeType != ChargeTypeComputeRounding {
+			due[computeKey{charge.ServerType, charge.Region}] += charge.Amount
+		}
+	}
+	for _, key := range keys {
+		due[key] += compute[key].amount
+	}
+	dueKeys := make([]computeKey, 0, len(due))
+	for key := range due {
+		dueKeys = append(dueKeys, key)
+	}
+	sort.Slice(dueKeys, func(i, j int) bool {
+		if dueKeys[i].serverType != dueKeys[j].serverType {
+			return dueKeys[i].serverType < dueKeys[j].serverType
+		}
+		return dueKeys[i].region < dueKeys[j].region
+	})
+
+	for _, credit := range credits {
+		applied := drawCredit(credit, due, dueKeys)
+		if applied <= 0 {
+			continue
+		}
+
+		serverType, region := "", ""
+		switch credit.ScopeType {
+		case CreditScopeServerType:
+			serverType = credit.ScopeValue
+		case CreditScopeRegion:
+			region = credit.ScopeValue
+		}
+		if err := book(ChargeTypeCredit, serverType, region, "credit "+credit.Name, 1, "credit", -applied); err != nil {
+			return err
+		}
+		if err := q.DrawDownCredit(ctx, sqlc.DrawDownCreditParams{ID: credit.ID, Amount: applied}); err != nil {
+			return fmt.Errorf("failed to draw down credit %s: %+v", credit.ID.String(), err)
+		}
+		b.logger.Info("Credit applied",
+			zap.String("credit_id", credit.ID.String()),
+			zap.String("project", project),
+			zap.Time("period_start", periodStart),
+			zap.Float64("amount", applied),
+		)
+	}
+	return nil
+}
+
+// volumeTierBands returns the hours of hours that fall in each of tiers, sorted
+// by threshold: those from its threshold up to the next one's.
+func volumeTierBands(tiers []sqlc.VolumeTier, hours float64) []float64 {
+	bands := make([]float64, len(tiers))
+	for i, tier := range tiers {
+		upTo := math.Inf(1)
+		if i+1 < len(tiers) {
+			upTo = tiers[i+1].FromHours
+		}
+		bands[i] = max(min(hours, upTo)-tier.FromHours, 0)
+	}
+	return bands
+}
+
+// matchDiscount returns the discount that applies to the compute charges of key.
+// Discounts are listed largest first; only that one applies.
+func matchDiscount(discounts []sqlc.Discount, key computeKey) (sqlc.Discount, bool) {
+	for _, discount := range discounts {
+		if (discount.ServerType == adjustmentAny || discount.ServerType == key.serverType) &&
+			(discount.Region == adjustmentAny || discount.Region == key.region) {
+			return discount, true
+		}
+	}
+	return sqlc.Discount{}, false
+}
+
+// drawCredit takes what is left of credit from the amounts due in its scope, in
+// the order of keys, and returns how much it covered.
+func drawCredit(credit sqlc.Credit, due map[computeKey]float64, keys []computeKey) float64 {
+	var applied float64
+	for _, key := range keys {
+		if (credit.ScopeType == CreditScopeServerType && key.serverType != credit.ScopeValue) ||
+			(credit.ScopeType == CreditScopeRegion && key.region != credit.ScopeValue) {
+			continue
+		}
+		draw := min(due[key], credit.Remaining-applied)
+		if draw <= 0 {
+			continue
+		}
+		due[key] -= draw
+		applied += draw
+	}
+	return applied
+}
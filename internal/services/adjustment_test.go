@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func TestVolumeTierBands(t *testing.T) {
+	tiers := []sqlc.VolumeTier{{FromHours: 100, PercentOff: 10}, {FromHours: 500, PercentOff: 20}}
+	tests := []struct {
+		hours float64
+		want  []float64
+	}{
+		{hours: 50, want: []float64{0, 0}},
+		{hours: 100, want: []float64{0, 0}},
+		{hours: 300, want: []float64{200, 0}},
+		{hours: 500, want: []float64{400, 0}},
+		{hours: 750, want: []float64{400, 250}},
+	}
+	for _, tt := range tests {
+		got := volumeTierBands(tiers, tt.hours)
+		if len(got) != 2 || got[0] != tt.want[0] || got[1] != tt.want[1] {
+			t.Errorf("volumeTierBands(%v) = %v, want %v", tt.hours, got, tt.want)
+		}
+	}
+}
+
+func TestMatchDiscount(t *testing.T) {
+	// Listed largest first, as ListDiscountsInPeriod returns them
+	discounts := []sqlc.Discount{
+		{Name: "micro-west", ServerType: "t2.micro", Region: "us-west-2", PercentOff: 30},
+		{Name: "east", ServerType: adjustmentAny, Region: "us-east-1", PercentOff: 20},
+		{Name: "micro", ServerType: "t2.micro", Region: adjustmentAny, PercentOff: 10},
+	}
+	tests := []struct {
+		key  computeKey
+		want string
+	}{
+		{key: computeKey{"t2.micro", "us-west-2"}, want: "micro-west"},
+		{key: computeKey{"t2.micro", "us-east-1"}, want: "east"},
+		{key: computeKey{"t2.large", "us-east-1"}, want: "east"},
+		{key: computeKey{"t2.micro", "eu-west-1"}, want: "micro"},
+		{key: computeKey{"t2.large", "eu-west-1"}},
+	}
+	for _, tt := range tests {
+		got, ok := matchDiscount(discounts, tt.key)
+		if ok != (tt.want != "") || got.Name != tt.want {
+			t.Errorf("matchDiscount(%v) = %q, %v; want %q", tt.key, got.Name, ok, tt.want)
+		}
+	}
+}
+
+func TestDrawCredit(t *testing.T) {
+	keys := []computeKey{{"t2.large", "us-east-1"}, {"t2.micro", "eu-west-1"}, {"t2.micro", "us-east-1"}}
+	newDue := func() map[computeKey]float64 {
+		return map[computeKey]float64{keys[0]: 40, keys[1]: 10, keys[2]: 25}
+	}
+	tests := []struct {
+		name   string
+		credit sqlc.Credit
+		want   float64
+		left   []float64
+	}{
+		{
+			name:   "project credit drawn in key order",
+			credit: sqlc.Credit{ScopeType: CreditScopeProject, Remaining: 45},
+			want:   45,
+			left:   []float64{0, 5, 25},
+		},
+		{
+			name:   "project credit larger than what is due",
+			credit: sqlc.Credit{ScopeType: CreditScopeProject, Remaining: 100},
+			want:   75,
+			left:   []float64{0, 0, 0},
+		},
+		{
+			name:   "server type credit",
+			credit: sqlc.Credit{ScopeType: CreditScopeServerType, ScopeValue: "t2.micro", Remaining: 30},
+			want:   30,
+			left:   []float64{40, 0, 5},
+		},
+		{
+			name:   "region credit",
+			credit: sqlc.Credit{ScopeType: CreditScopeRegion, ScopeValue: "eu-west-1", Remaining: 30},
+			want:   10,
+			left:   []float64{40, 0, 25},
+		},
+		{
+			name:   "credit out of scope",
+			credit: sqlc.Credit{ScopeType: CreditScopeRegion, ScopeValue: "ap-south-1", Remaining: 30},
+			want:   0,
+			left:   []float64{40, 10, 25},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			due := newDue()
+			if got := drawCredit(tt.credit, due, keys); got != tt.want {
+				t.Errorf("drawCredit = %v, want %v", got, tt.want)
+			}
+			for i, key := range keys {
+				if due[key] != tt.left[i] {
+					t.Errorf("due %v = %v, want %v", key, due[key], tt.left[i])
+				}
+			}
+		})
+	}
+
+	// Credits draw one after the other on what the previous ones left
+	due := newDue()
+	drawCredit(sqlc.Credit{ScopeType: CreditScopeServerType, ScopeValue: "t2.large", Remaining: 30}, due, keys)
+	if got := drawCredit(sqlc.Credit{ScopeType: CreditScopeProject, Remaining: 20}, due, keys); got != 20 || due[keys[0]] != 0 || due[keys[1]] != 0 {
+		t.Errorf("second credit drew %v leaving %v, want 20 leaving only t2.micro in us-east-1", got, due)
+	}
+}
+
+func TestCreateVolumeTierRejects(t *testing.T) {
+	var b BillingService
+	tests := []struct {
+		fromHours  float64
+		percentOff float64
+		want       error
+	}{
+		{fromHours: 0, percentOff: 10, want: ErrInvalidVolumeTier},
+		{fromHours: -5, percentOff: 10, want: ErrInvalidVolumeTier},
+		{fromHours: 100, percentOff: 0, want: ErrInvalidPercentOff},
+		{fromHours: 100, percentOff: 101, want: ErrInvalidPercentOff},
+	}
+	for _, tt := range tests {
+		if _, err := b.CreateVolumeTier(context.Background(), "t2.micro", tt.fromHours, tt.percentOff); !errors.Is(err, tt.want) {
+			t.Errorf("CreateVolumeTier(%v, %v) = %v, want %v", tt.fromHours, tt.percentOff, err, tt.want)
+		}
+	}
+}
+
+func TestCreateDiscountRejects(t *testing.T) {
+	var b BillingService
+	start := time.Now().Add(time.Hour)
+	tests := []struct {
+		name   string
+		params sqlc.CreateDiscountParams
+		want   error
+	}{
+		{name: "no percentage", params: sqlc.CreateDiscountParams{}, want: ErrInvalidPercentOff},
+		{name: "over 100 percent", params: sqlc.CreateDiscountParams{PercentOff: 150}, want: ErrInvalidPercentOff},
+		{
+			name:   "expires at its start",
+			params: sqlc.CreateDiscountParams{PercentOff: 10, StartsAt: timestamptz(start), ExpiresAt: timestamptz(start)},
+			want:   ErrInvalidDiscount,
+		},
+		{
+			name:   "expires before now when starting now",
+			params: sqlc.CreateDiscountParams{PercentOff: 10, ExpiresAt: timestamptz(time.Now().Add(-time.Hour))},
+			want:   ErrInvalidDiscount,
+		},
+	}
+	for _, tt := range tests {
+		if _, err := b.CreateDiscount(context.Background(), tt.params); !errors.Is(err, tt.want) {
+			t.Errorf("CreateDiscount with %s = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestCreateCreditRejects(t *testing.T) {
+	var b BillingService
+	expires := pgtype.Timestamptz{Time: time.Now().Add(30 * 24 * time.Hour), Valid: true}
+	tests := []struct {
+		name   string
+		params sqlc.CreateCreditParams
+	}{
+		{name: "no amount", params: sqlc.CreateCreditParams{ExpiresAt: expires}},
+		{name: "negative amount", params: sqlc.CreateCreditParams{Amount: -10, ExpiresAt: expires}},
+		{name: "no expiry", params: sqlc.CreateCreditParams{Amount: 10}},
+		{name: "expired", params: sqlc.CreateCreditParams{Amount: 10, ExpiresAt: timestamptz(time.Now().Add(-time.Hour))}},
+		{name: "server type scope without a type", params: sqlc.CreateCreditParams{Amount: 10, ExpiresAt: expires, ScopeType: CreditScopeServerType}},
+		{name: "region scope without a region", params: sqlc.CreateCreditParams{Amount: 10, ExpiresAt: expires, ScopeType: CreditScopeRegion}},
+		{name: "unknown scope", params: sqlc.CreateCreditParams{Amount: 10, ExpiresAt: expires, ScopeType: "server"}},
+	}
+	for _, tt := range tests {
+		if _, err := b.CreateCredit(context.Background(), tt.params); !errors.Is(err, ErrInvalidCredit) {
+			t.Errorf("CreateCredit with %s = %v, want ErrInvalidCredit", tt.name, err)
+		}
+	}
+}
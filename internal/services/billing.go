@@ -2,28 +2,38 @@ package services
 
 import (
 	"context"
+	"fmt"
 	"sync"
 	"time"
 
-	"github.com/go-chi/chi/middleware"
 	"go.uber.org/zap"
 
 	"go-virtual-server/internal/database/sqlc"
-	"go-virtual-server/internal/util"
 )
 
 // BillingDaemon calculates and updates server uptime for billing purposes.
 type BillingDaemon struct {
 	queries  *sqlc.Queries
+	billing  *BillingService
+	budgets  *BudgetService
+	accounts *AccountService
+	reaper   *ReaperService
+	servers  *ServerService
 	logger   *zap.Logger
 	interval time.Duration
 	mutex    *sync.Mutex
+	lastRun  time.Time
 }
 
 // NewBillingAndReaperDaemon creates a new BillingDaemon.
-func NewBillingAndReaperDaemon(queries *sqlc.Queries, logger *zap.Logger, interval time.Duration) *BillingDaemon {
+func NewBillingAndReaperDaemon(queries *sqlc.Queries, billing *BillingService, budgets *BudgetService, accounts *AccountService, reaper *ReaperService, servers *ServerService, logger *zap.Logger, interval time.Duration) *BillingDaemon {
 	return &BillingDaemon{
 		queries:  queries,
+		billing:  billing,
+		budgets:  budgets,
+		accounts: accounts,
+		reaper:   reaper,
+		servers:  servers,
 		logger:   logger,
 		interval: interval,
 	}
@@ -34,6 +44,8 @@ func (billingDaemon *BillingDaemon) Start(ctx context.Context) {
 	ticker := time.NewTicker(billingDaemon.interval)
 	defer ticker.Stop()
 
+	ctx = WithEventActor(ctx, ActorBilling)
+
 	billingDaemon.logger.Info("Billing daemon started", zap.Duration("interval", billingDaemon.interval))
 	for {
 		select {
@@ -46,73 +58,108 @@ func (billingDaemon *BillingDaemon) Start(ctx context.Context) {
 	}
 }
 
-// processBilling fetches running servers and updates their uptime.
+// TickStep is how long one step of a billing tick took.
+type TickStep struct {
+	Name     string
+	Duration time.Duration
+}
+
+// TickStats is what a billing tick did.
+type TickStats struct {
+	Steps []TickStep
+}
+
+// step runs fn as the named step of a tick and records how long it took.
+func (stats *TickStats) step(name string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	stats.Steps = append(stats.Steps, TickStep{Name: name, Duration: time.Since(start)})
+	return err
+}
+
+// processBilling runs one billing tick on the daemon's schedule and logs it.
 func (billingDaemon *BillingDaemon) processBilling(ctx context.Context) {
+	billingDaemon.logger.Debug("Running billing process...")
 
+	now := time.Now()
+	if _, err := billingDaemon.Tick(ctx, now); err != nil {
+		billingDaemon.logger.Error("Failed to update servers", zap.Error(err))
+		return
+	}
+	billingDaemon.logger.Info("Billing tick done", zap.Duration("duration", time.Since(now)))
+}
+
+// Tick runs one billing tick as of now: the per-server updates, then metering,
+// accrual, invoicing, budgets, prepaid accounts, the idle reaper and the pruning
+// of server events, timing each. Only a failure of the per-server updates ends
+// the tick early; the later steps log their errors and the tick carries on.
+func (billingDaemon *BillingDaemon) Tick(ctx context.Context, now time.Time) (TickStats, error) {
 	if billingDaemon.mutex == nil {
 		billingDaemon.mutex = &sync.Mutex{}
 	}
 	billingDaemon.mutex.Lock()
 	defer billingDaemon.mutex.Unlock()
 
-	billingDaemon.logger.Debug("Running billing process...")
+	stats, err := billingDaemon.TickServers(ctx, now)
+	if err != nil {
+		return stats, err
+	}
 
-	// Fetch all running servers
-	servers, err := billingDaemon.queries.ListServers(ctx, util.ServerStatusRunning)
+	// Simulate the egress of running servers since the last run
+	elapsed := billingDaemon.interval
+	if !billingDaemon.lastRun.IsZero() {
+		elapsed = now.Sub(billingDaemon.lastRun)
+	}
+	billingDaemon.lastRun = now
+	if err := stats.step("egress", func() error { return billingDaemon.billing.RecordEgress(ctx, elapsed) }); err != nil {
+		billingDaemon.logger.Error("Failed to record egress", zap.Error(err))
+	}
+
+	// Write completed usage to the ledger, then invoice every period that has ended
+	if err := stats.step("accrue", func() error { return billingDaemon.billing.AccrueUsage(ctx, now) }); err != nil {
+		billingDaemon.logger.Error("Failed to accrue usage", zap.Error(err))
+	}
+	if err := stats.step("close_periods", func() error { return billingDaemon.billing.ClosePeriods(ctx, now) }); err != nil {
+		billingDaemon.logger.Error("Failed to close billing periods", zap.Error(err))
+	}
+	if err := stats.step("budgets", func() error { return billingDaemon.budgets.EvaluateBudgets(ctx, now) }); err != nil {
+		billingDaemon.logger.Error("Failed to evaluate budgets", zap.Error(err))
+	}
+	if err := stats.step("accounts", func() error { return billingDaemon.accounts.DrawDownAccounts(ctx, now) }); err != nil {
+		billingDaemon.logger.Error("Failed to draw down prepaid accounts", zap.Error(err))
+	}
+	if err := stats.step("reaper", func() error { return billingDaemon.reaper.Reap(ctx, now) }); err != nil {
+		billingDaemon.logger.Error("Failed to run reaper policies", zap.Error(err))
+	}
+	var pruned int64
+	err = stats.step("prune_events", func() error {
+		var err error
+		pruned, err = billingDaemon.servers.PruneEvents(ctx, now)
+		return err
+	})
 	if err != nil {
-		billingDaemon.logger.Error("Failed to list running servers for billing", zap.Error(err))
-		return
+		billingDaemon.logger.Error("Failed to prune server events", zap.Error(err))
+	} else if pruned > 0 {
+		billingDaemon.logger.Debug("Server events pruned", zap.Int64("rows", pruned))
 	}
-	billingDaemon.logger.Info("Found running servers", zap.Int("count", len(servers)))
-	for _, server := range servers {
-		billingDaemon.logger.Info("Processing server", zap.String("server_id", server.ID.String()))
-		elapsed := time.Since(server.LastStatusUpdate.Time)
-		newUptimeSeconds := server.UptimeSeconds + elapsed.Nanoseconds()/int64(time.Second)
-
-		_, err := billingDaemon.queries.UpdateServerUptime(ctx, sqlc.UpdateServerUptimeParams{
-			UptimeSeconds: newUptimeSeconds,
-			ID:            server.ID,
-		})
-
-		if err != nil {
-			billingDaemon.logger.Error("Failed to update server uptime",
-				zap.Error(err),
-				zap.String("server_id", server.ID.String()),
-				zap.Int64("current_uptime", server.UptimeSeconds),
-				zap.Int64("new_uptime", newUptimeSeconds),
-			)
-		} else {
-			billingDaemon.logger.Info("Updated server uptime",
-				zap.String("server_id", server.ID.String()),
-				zap.Int64("new_uptime", newUptimeSeconds),
-				zap.Float64("hourly_cost", server.HourlyCost),
-				zap.Float64("estimated_current_bill", float64(newUptimeSeconds)/3600*float64(server.HourlyCost)),
-			)
-		}
+	return stats, nil
+}
 
-		AppendServerLifecycleLogs(nil, billingDaemon, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Server uptime updated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
-
-		//  IDLE Reaper to terminate server if it is not used for more than 30 minmutes
-		if newUptimeSeconds > 1800 && server.Status != util.ServerStatusTerminated {
-			_, err := billingDaemon.queries.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
-				Status: util.ServerStatusTerminated,
-				ID:     server.ID,
-			})
-			if err != nil {
-				billingDaemon.logger.Error("Failed to update server status to terminated",
-					zap.Error(err),
-					zap.String("server_id", server.ID.String()),
-					zap.String("current_status", string(server.Status)),
-					zap.String("desired_status", string(util.ServerStatusTerminated)),
-				)
-			} else {
-				billingDaemon.logger.Info("Server status updated to terminated",
-					zap.String("server_id", server.ID.String()),
-					zap.String("current_status", string(server.Status)),
-					zap.String("desired_status", string(util.ServerStatusTerminated)),
-				)
-				AppendServerLifecycleLogs(nil, billingDaemon, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Timeout detected, server status updated to terminated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
-			}
-		}
+// TickServers runs the per-server part of a billing tick as a fixed number of
+// set-based statements, however many servers there are: it refreshes the uptime
+// and hourly cost of every server.
+func (billingDaemon *BillingDaemon) TickServers(ctx context.Context, now time.Time) (TickStats, error) {
+	var stats TickStats
+	step := stats.step
+
+	// Uptime is derived from usage segments, so refreshing the cached value is
+	// idempotent no matter how often the daemon runs.
+	if err := step("uptime", func() error { return billingDaemon.queries.RefreshServerUptimes(ctx) }); err != nil {
+		return stats, fmt.Errorf("failed to refresh server uptimes: %+v", err)
+	}
+	// Price versions take effect on their own; keep the cached hourly cost of live servers current
+	if err := step("hourly_cost", func() error { return billingDaemon.queries.RefreshServerHourlyCosts(ctx) }); err != nil {
+		billingDaemon.logger.Error("Failed to refresh server hourly costs", zap.Error(err))
 	}
+	return stats, nil
 }
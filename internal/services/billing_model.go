@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// BillingModelPerSecond charges running time to the second, with a minimum of
+	// MinimumBilledDuration every time the server runs.
+	BillingModelPerSecond = "per_second"
+	// BillingModelHourly charges every started hour of running time in full.
+	BillingModelHourly = "hourly"
+	// BillingModelReserved draws running time down from the project's reservations
+	// for the server's type and region first, and charges the rest to the second.
+	BillingModelReserved = "reserved"
+
+	// MinimumBilledDuration is the least running time charged under BillingModelPerSecond.
+	MinimumBilledDuration = time.Minute
+)
+
+// ErrInvalidBillingModel is returned for unknown billing models.
+var ErrInvalidBillingModel = errors.New("billing model must be per_second, hourly or reserved")
+
+// IsValidBillingModel reports whether model is a known billing model.
+func IsValidBillingModel(model string) bool {
+	switch model {
+	case BillingModelPerSecond, BillingModelHourly, BillingModelReserved:
+		return true
+	}
+	return false
+}
+
+// BilledDuration returns how much of a running period of length d a billing model
+// charges. Usage is metered exactly; the difference is charged when the period ends.
+func BilledDuration(model string, d time.Duration) time.Duration {
+	switch model {
+	case BillingModelHourly:
+		hours := math.Ceil(d.Hours())
+		return time.Duration(max(hours, 1)) * time.Hour
+	case BillingModelPerSecond:
+		return max((d + time.Second - 1).Truncate(time.Second), MinimumBilledDuration)
+	}
+	return d
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBilledDuration(t *testing.T) {
+	tests := []struct {
+		model string
+		d     time.Duration
+		want  time.Duration
+	}{
+		{BillingModelPerSecond, 0, MinimumBilledDuration},
+		{BillingModelPerSecond, 30 * time.Second, MinimumBilledDuration},
+		{BillingModelPerSecond, time.Minute, time.Minute},
+		{BillingModelPerSecond, 90*time.Second + time.Millisecond, 91 * time.Second},
+		{BillingModelPerSecond, 2 * time.Hour, 2 * time.Hour},
+		{BillingModelHourly, 0, time.Hour},
+		{BillingModelHourly, time.Second, time.Hour},
+		{BillingModelHourly, time.Hour, time.Hour},
+		{BillingModelHourly, time.Hour + time.Second, 2 * time.Hour},
+		{BillingModelReserved, 30 * time.Second, 30 * time.Second},
+		{BillingModelReserved, 90*time.Minute + time.Millisecond, 90*time.Minute + time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := BilledDuration(tt.model, tt.d); got != tt.want {
+			t.Errorf("BilledDuration(%s, %v) = %v, want %v", tt.model, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestIsValidBillingModel(t *testing.T) {
+	for _, model := range []string{BillingModelPerSecond, BillingModelHourly, BillingModelReserved} {
+		if !IsValidBillingModel(model) {
+			t.Errorf("IsValidBillingModel(%q) = false, want true", model)
+		}
+	}
+	for _, model := range []string{"", "monthly", "Hourly"} {
+		if IsValidBillingModel(model) {
+			t.Errorf("IsValidBillingModel(%q) = true, want false", model)
+		}
+	}
+}
+
+func TestCreateReservationRejects(t *testing.T) {
+	var b BillingService
+	for _, hours := range []float64{0, -10} {
+		if _, err := b.CreateReservation(context.Background(), "acme", "t2.micro", "us-east-1", hours); !errors.Is(err, ErrInvalidReservation) {
+			t.Errorf("CreateReservation of %v hours = %v, want ErrInvalidReservation", hours, err)
+		}
+	}
+}
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// BudgetScopeProject limits the servers of one project.
+	BudgetScopeProject = "project"
+	// BudgetScopeRegion limits the servers of one region.
+	BudgetScopeRegion = "region"
+	// BudgetScopeTag limits the servers carrying one tag value.
+	BudgetScopeTag = "tag"
+
+	// BudgetPeriodDaily budgets reset every UTC day.
+	BudgetPeriodDaily = "daily"
+	// BudgetPeriodMonthly budgets follow the billing period.
+	BudgetPeriodMonthly = "monthly"
+
+	// budgetEnforcementThreshold is the share of the amount, in percent, at which enforced budgets stop servers.
+	budgetEnforcementThreshold = 100
+)
+
+var (
+	// ErrBudgetNotFound is returned when a budget does not exist.
+	ErrBudgetNotFound = errors.New("budget not found")
+	// ErrInvalidBudget is returned for budgets that cannot be evaluated.
+	ErrInvalidBudget = errors.New("invalid budget")
+)
+
+// DefaultBudgetThresholds are the alert thresholds of budgets created without any.
+var DefaultBudgetThresholds = []int32{50, 80, 100}
+
+// BudgetStatus is a budget's spend in its current period.
+type BudgetStatus struct {
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Spend       float64
+}
+
+// BudgetAlertEvent is the payload posted to a budget's webhook when a threshold is crossed.
+type BudgetAlertEvent struct {
+	AlertID     string    `json:"alertId"`
+	BudgetID    string    `json:"budgetId"`
+	BudgetName  string    `json:"budgetName"`
+	ScopeType   string    `json:"scopeType"`
+	ScopeKey    string    `json:"scopeKey,omitempty"`
+	ScopeValue  string    `json:"scopeValue"`
+	PeriodStart time.Time `json:"periodStart"`
+	Threshold   int32     `json:"threshold"`
+	Spend       float64   `json:"spend"`
+	Amount      float64   `json:"amount"`
+	Currency    string    `json:"currency"`
+	Enforced    bool      `json:"enforced"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// BudgetService manages budgets and evaluates them against metered usage.
+type BudgetService struct {
+	queries       *sqlc.Queries
+	serverService *ServerService
+	logger        *zap.Logger
+	config        *config.Config
+	httpClient    *http.Client
+}
+
+// NewBudgetService creates a new BudgetService. Enforcement stops servers through serverService.
+func NewBudgetService(queries *sqlc.Queries, serverService *ServerService, logger *zap.Logger, config *config.Config) *BudgetService {
+	return &BudgetService{
+		queries:       queries,
+		serverService: serverService,
+		logger:        logger,
+		config:        config,
+		httpClient:    &http.Client{Timeout: config.BudgetWebhookTimeout},
+	}
+}
+
+// BudgetPeriod returns the bounds of the budget period of the given kind containing t, in UTC.
+func BudgetPeriod(period string, t time.Time) (time.Time, time.Time) {
+	if period == BudgetPeriodDaily {
+		t = t.UTC()
+		start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 0, 1)
+	}
+	return PeriodStart(t), PeriodEnd(t)
+}
+
+// CreateBudget validates and stores a budget. Empty period and thresholds take their defaults.
+func (bs *BudgetService) CreateBudget(ctx context.Context, params sqlc.CreateBudgetParams) (sqlc.Budget, error) {
+	if params.Period == "" {
+		params.Period = BudgetPeriodMonthly
+	}
+	if len(params.Thresholds) == 0 {
+		params.Thresholds = append([]int32(nil), DefaultBudgetThresholds...)
+	}
+	params.Currency = CurrencyUSD
+	if err := validateBudget(&params); err != nil {
+		return sqlc.Budget{}, err
+	}
+
+	budget, err := bs.queries.CreateBudget(ctx, params)
+	if err != nil {
+		return sqlc.Budget{}, fmt.Errorf("failed to create budget: %+v", err)
+	}
+	bs.logger.Info("Budget created",
+		zap.String("budget_id", budget.ID.String()),
+		zap.String("scope_type", budget.ScopeType),
+		zap.String("scope_value", budget.ScopeValue),
+		zap.Float64("amount", budget.Amount),
+		zap.Bool("enforce", budget.Enforce),
+	)
+	return budget, nil
+}
+
+func validateBudget(params *sqlc.CreateBudgetParams) error {
+	switch {
+	case params.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidBudget)
+	case params.ScopeType != BudgetScopeProject && params.ScopeType != BudgetScopeRegion && params.ScopeType != BudgetScopeTag:
+		return fmt.Errorf("%w: scope type must be %s, %s or %s", ErrInvalidBudget, BudgetScopeProject, BudgetScopeRegion, BudgetScopeTag)
+	case params.ScopeType == BudgetScopeTag && params.ScopeKey == "":
+		return fmt.Errorf("%w: tag budgets need a scope key", ErrInvalidBudget)
+	case params.ScopeValue == "":
+		return fmt.Errorf("%w: scope value is required", ErrInvalidBudget)
+	case params.Amount <= 0:
+		return fmt.Errorf("%w: amount must be positive", ErrInvalidBudget)
+	case params.Period != BudgetPeriodDaily && params.Period != BudgetPeriodMonthly:
+		return fmt.Errorf("%w: period must be %s or %s", ErrInvalidBudget, BudgetPeriodDaily, BudgetPeriodMonthly)
+	}
+	if params.ScopeType != BudgetScopeTag {
+		params.ScopeKey = ""
+	}
+
+	for _, threshold := range params.Thresholds {
+		if threshold <= 0 || threshold > 1000 {
+			return fmt.Errorf("%w: thresholds must be percentages between 1 and 1000", ErrInvalidBudget)
+		}
+	}
+	sort.Slice(params.Thresholds, func(i, j int) bool { return params.Thresholds[i] < params.Thresholds[j] })
+
+	if params.WebhookUrl != "" {
+		webhook, err := url.Parse(params.WebhookUrl)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("%w: webhook URL must be an absolute http(s) URL", ErrInvalidBudget)
+		}
+	}
+	return nil
+}
+
+// GetBudget returns a budget.
+func (bs *BudgetService) GetBudget(ctx context.Context, budgetID pgtype.UUID) (sqlc.Budget, error) {
+	budget, err := bs.queries.GetBudget(ctx, budgetID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Budget{}, ErrBudgetNotFound
+	}
+	if err != nil {
+		return sqlc.Budget{}, fmt.Errorf("failed to get budget: %+v", err)
+	}
+	return budget, nil
+}
+
+// ListBudgets returns every budget.
+func (bs *BudgetService) ListBudgets(ctx context.Context) ([]sqlc.Budget, error) {
+	budgets, err := bs.queries.ListBudgets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budgets: %+v", err)
+	}
+	return budgets, nil
+}
+
+// DeleteBudget removes a budget and its alerts.
+func (bs *BudgetService) DeleteBudget(ctx context.Context, budgetID pgtype.UUID) error {
+	deleted, err := bs.queries.DeleteBudget(ctx, budgetID)
+	if err != nil {
+		return fmt.Errorf("failed to delete budget: %+v", err)
+	}
+	if deleted == 0 {
+		return ErrBudgetNotFound
+	}
+	return nil
+}
+
+// ListBudgetAlerts returns the thresholds a budget has crossed, newest first.
+func (bs *BudgetService) ListBudgetAlerts(ctx context.Context, budgetID pgtype.UUID, limit, offset int) ([]sqlc.BudgetAlert, error) {
+	alerts, err := bs.queries.ListBudgetAlerts(ctx, sqlc.ListBudgetAlertsParams{
+		BudgetID: budgetID,
+		Limit:    int32(limit),
+		Offset:   int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list budget alerts: %+v", err)
+	}
+	return alerts, nil
+}
+
+// GetBudgetStatus computes a budget's spend in its current period, priced as it
+// is invoiced: what the ledger holds of the period plus the usage not written to
+// it yet.
+func (bs *BudgetService) GetBudgetStatus(ctx context.Context, budget sqlc.Budget, now time.Time) (BudgetStatus, error) {
+	periodStart, periodEnd := BudgetPeriod(budget.Period, now)
+	project, region, tagKey, tagValue := budgetScope(budget)
+	servers, err := bs.queries.ListServersInScope(ctx, sqlc.ListServersInScopeParams{
+		Since:    pgtype.Timestamptz{Time: periodStart, Valid: true},
+		Project:  project,
+		Region:   region,
+		TagKey:   tagKey,
+		TagValue: tagValue,
+	})
+	if err != nil {
+		return BudgetStatus{}, fmt.Errorf("failed to list servers in budget scope: %+v", err)
+	}
+	serverIDs := make([]pgtype.UUID, 0, len(servers))
+	for _, server := range servers {
+		serverIDs = append(serverIDs, server.ID)
+	}
+
+	usage, err := usageSince(ctx, bs.queries, bs.config, serverIDs, periodStart, now)
+	if err != nil {
+		return BudgetStatus{}, err
+	}
+	var spend float64
+	for _, serverUsage := range usage {
+		spend += serverUsage.Cost
+	}
+	return BudgetStatus{PeriodStart: periodStart, PeriodEnd: periodEnd, Spend: spend}, nil
+}
+
+// budgetScope turns a budget's scope into the filters of the scoped queries.
+func budgetScope(budget sqlc.Budget) (project, region, tagKey, tagValue pgtype.Text) {
+	value := pgtype.Text{String: budget.ScopeValue, Valid: true}
+	switch budget.ScopeType {
+	case BudgetScopeProject:
+		project = value
+	case BudgetScopeRegion:
+		region = value
+	case BudgetScopeTag:
+		tagKey = pgtype.Text{String: budget.ScopeKey, Valid: true}
+		tagValue = value
+	}
+	return project, region, tagKey, tagValue
+}
+
+// EvaluateBudgets checks every budget against its current spend. Each threshold
+// crossed for the first time in a period is recorded and posted to the budget's
+// webhook; enforced budgets that are fully spent stop their running servers.
+func (bs *BudgetService) EvaluateBudgets(ctx context.Context, now time.Time) error {
+	budgets, err := bs.ListBudgets(ctx)
+	if err != nil {
+		return err
+	}
+	for _, budget := range budgets {
+		if err := bs.evaluateBudget(ctx, budget, now); err != nil {
+			bs.logger.Error("Failed to evaluate budget", zap.Error(err), zap.String("budget_id", budget.ID.String()))
+		}
+	}
+	return nil
+}
+
+func (bs *BudgetService) evaluateBudget(ctx context.Context, budget sqlc.Budget, now time.Time) error {
+	status, err := bs.GetBudgetStatus(ctx, budget, now)
+	if err != nil {
+		return err
+	}
+	percent := status.Spend / budget.Amount * 100
+
+	for _, threshold := range crossedThresholds(budget.Thresholds, percent) {
+		alert, err := bs.queries.CreateBudgetAlert(ctx, sqlc.CreateBudgetAlertParams{
+			BudgetID:    budget.ID,
+			PeriodStart: pgtype.Timestamptz{Time: status.PeriodStart, Valid: true},
+			Threshold:   threshold,
+			Spend:       status.Spend,
+			Amount:      budget.Amount,
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already alerted this period
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to record budget alert: %+v", err)
+		}
+
+		bs.logger.Warn("Budget threshold crossed",
+			zap.String("budget_id", budget.ID.String()),
+			zap.String("budget_name", budget.Name),
+			zap.Int32("threshold", threshold),
+			zap.Float64("spend", status.Spend),
+			zap.Float64("amount", budget.Amount),
+		)
+		if budget.WebhookUrl != "" {
+			bs.notify(ctx, budget, alert, now)
+		}
+	}
+
+	if budget.Enforce && percent >= budgetEnforcementThreshold {
+		return bs.enforceBudget(ctx, budget, status)
+	}
+	return nil
+}
+
+// crossedThresholds returns the thresholds, sorted ascending, that a spend of
+// percent of the budget has reached.
+func crossedThresholds(thresholds []int32, percent float64) []int32 {
+	for i, threshold := range thresholds {
+		if percent < float64(threshold) {
+			return thresholds[:i]
+		}
+	}
+	return thresholds
+}
+
+// notify posts an alert to the budget's webhook and records the outcome on the alert.
+func (bs *BudgetService) notify(ctx context.Context, budget sqlc.Budget, alert sqlc.BudgetAlert, now time.Time) {
+	event := BudgetAlertEvent{
+		AlertID:     alert.ID.String(),
+		BudgetID:    budget.ID.String(),
+		BudgetName:  budget.Name,
+		ScopeType:   budget.ScopeType,
+		ScopeKey:    budget.ScopeKey,
+		ScopeValue:  budget.ScopeValue,
+		PeriodStart: alert.PeriodStart.Time,
+		Threshold:   alert.Threshold,
+		Spend:       alert.Spend,
+		Amount:      alert.Amount,
+		Currency:    budget.Currency,
+		Enforced:    budget.Enforce && alert.Threshold >= budgetEnforcementThreshold,
+		TriggeredAt: now,
+	}
+
+	webhookStatus := "delivered"
+	if err := postWebhook(ctx, bs.httpClient, budget.WebhookUrl, event); err != nil {
+		bs.logger.Error("Failed to deliver budget alert webhook", zap.Error(err), zap.String("budget_id", budget.ID.String()))
+		webhookStatus = "failed: " + err.Error()
+		if len(webhookStatus) > 255 {
+			webhookStatus = webhookStatus[:255]
+		}
+	}
+	if err := bs.queries.SetBudgetAlertWebhookStatus(ctx, sqlc.SetBudgetAlertWebhookStatusParams{
+		WebhookStatus: webhookStatus,
+		ID:            alert.ID,
+	}); err != nil {
+		bs.logger.Error("Failed to record webhook status", zap.Error(err), zap.String("alert_id", alert.ID.String()))
+	}
+}
+
+// postWebhook posts event as JSON to webhookURL, failing on non-2xx responses.
+func postWebhook(ctx context.Context, client *http.Client, webhookURL string, event any) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %+v", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %+v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+// enforceBudget stops, without terminating, every running server in the budget's scope.
+func (bs *BudgetService) enforceBudget(ctx context.Context, budget sqlc.Budget, status BudgetStatus) error {
+	project, region, tagKey, tagValue := budgetScope(budget)
+	servers, err := bs.queries.ListRunningServersInScope(ctx, sqlc.ListRunningServersInScopeParams{
+		Project:  project,
+		Region:   region,
+		TagKey:   tagKey,
+		TagValue: tagValue,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list running servers in budget scope: %+v", err)
+	}
+
+	ctx = WithEventActor(ctx, ActorBudget)
+	for _, server := range servers {
+		if _, err := bs.serverService.StopServer(ctx, server); err != nil {
+			bs.logger.Error("Failed to stop server over budget", zap.Error(err), zap.String("server_id", server.ID.String()), zap.String("budget_id", budget.ID.String()))
+			continue
+		}
+
+		bs.serverService.RecordEvent(ctx, server.ID, ServerEvent{
+			Type:    EventBudget,
+			Message: "Server stopped by budget enforcement",
+			Details: map[string]any{"budgetId": budget.ID.String(), "spend": status.Spend, "amount": budget.Amount},
+		})
+		bs.logger.Warn("Server stopped by budget enforcement",
+			zap.String("server_id", server.ID.String()),
+			zap.String("budget_id", budget.ID.String()),
+			zap.Float64("spend", status.Spend),
+			zap.Float64("amount", budget.Amount),
+		)
+	}
+	return nil
+}
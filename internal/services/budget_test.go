@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func TestBudgetPeriod(t *testing.T) {
+	tests := []struct {
+		period    string
+		t         string
+		wantStart string
+		wantEnd   string
+	}{
+		{period: BudgetPeriodDaily, t: "2026-03-15T12:30:00Z", wantStart: "2026-03-15T00:00:00Z", wantEnd: "2026-03-16T00:00:00Z"},
+		{period: BudgetPeriodDaily, t: "2026-03-31T23:59:59Z", wantStart: "2026-03-31T00:00:00Z", wantEnd: "2026-04-01T00:00:00Z"},
+		{period: BudgetPeriodDaily, t: "2026-03-16T01:00:00+02:00", wantStart: "2026-03-15T00:00:00Z", wantEnd: "2026-03-16T00:00:00Z"},
+		{period: BudgetPeriodMonthly, t: "2026-03-15T12:30:00Z", wantStart: "2026-03-01T00:00:00Z", wantEnd: "2026-04-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		start, end := BudgetPeriod(tt.period, mustTime(t, tt.t))
+		if !start.Equal(mustTime(t, tt.wantStart)) || !end.Equal(mustTime(t, tt.wantEnd)) {
+			t.Errorf("BudgetPeriod(%s, %s) = [%v, %v), want [%s, %s)", tt.period, tt.t, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestValidateBudget(t *testing.T) {
+	valid := func() sqlc.CreateBudgetParams {
+		return sqlc.CreateBudgetParams{
+			Name:       "team",
+			ScopeType:  BudgetScopeProject,
+			ScopeValue: "acme",
+			Amount:     100,
+			Period:     BudgetPeriodMonthly,
+			Thresholds: []int32{100, 50, 80},
+		}
+	}
+	tests := []struct {
+		name    string
+		modify  func(*sqlc.CreateBudgetParams)
+		wantErr bool
+	}{
+		{name: "valid", modify: func(*sqlc.CreateBudgetParams) {}},
+		{name: "tag scope", modify: func(p *sqlc.CreateBudgetParams) { p.ScopeType, p.ScopeKey = BudgetScopeTag, "team" }},
+		{name: "daily", modify: func(p *sqlc.CreateBudgetParams) { p.Period = BudgetPeriodDaily }},
+		{name: "https webhook", modify: func(p *sqlc.CreateBudgetParams) { p.WebhookUrl = "https://hooks.example.com/budget" }},
+		{name: "no name", modify: func(p *sqlc.CreateBudgetParams) { p.Name = "" }, wantErr: true},
+		{name: "unknown scope", modify: func(p *sqlc.CreateBudgetParams) { p.ScopeType = "account" }, wantErr: true},
+		{name: "tag scope without key", modify: func(p *sqlc.CreateBudgetParams) { p.ScopeType = BudgetScopeTag }, wantErr: true},
+		{name: "no scope value", modify: func(p *sqlc.CreateBudgetParams) { p.ScopeValue = "" }, wantErr: true},
+		{name: "zero amount", modify: func(p *sqlc.CreateBudgetParams) { p.Amount = 0 }, wantErr: true},
+		{name: "weekly", modify: func(p *sqlc.CreateBudgetParams) { p.Period = "weekly" }, wantErr: true},
+		{name: "zero threshold", modify: func(p *sqlc.CreateBudgetParams) { p.Thresholds = []int32{0} }, wantErr: true},
+		{name: "threshold over 1000", modify: func(p *sqlc.CreateBudgetParams) { p.Thresholds = []int32{1001} }, wantErr: true},
+		{name: "relative webhook", modify: func(p *sqlc.CreateBudgetParams) { p.WebhookUrl = "/budget" }, wantErr: true},
+		{name: "non-http webhook", modify: func(p *sqlc.CreateBudgetParams) { p.WebhookUrl = "ftp://example.com/budget" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := valid()
+			tt.modify(&params)
+			err := validateBudget(&params)
+			if tt.wantErr != (err != nil) || (err != nil && !errors.Is(err, ErrInvalidBudget)) {
+				t.Fatalf("validateBudget = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && !slices.IsSorted(params.Thresholds) {
+				t.Errorf("thresholds %v are not sorted", params.Thresholds)
+			}
+		})
+	}
+
+	// Only tag budgets keep a scope key
+	params := valid()
+	params.ScopeKey = "team"
+	if err := validateBudget(&params); err != nil || params.ScopeKey != "" {
+		t.Errorf("project budget kept scope key %q (%v)", params.ScopeKey, err)
+	}
+}
+
+func TestBudgetScope(t *testing.T) {
+	project, region, tagKey, tagValue := budgetScope(sqlc.Budget{ScopeType: BudgetScopeProject, ScopeValue: "acme"})
+	if project.String != "acme" || !project.Valid || region.Valid || tagKey.Valid || tagValue.Valid {
+		t.Errorf("project scope = %v, %v, %v, %v", project, region, tagKey, tagValue)
+	}
+	project, region, tagKey, tagValue = budgetScope(sqlc.Budget{ScopeType: BudgetScopeRegion, ScopeValue: "us-east-1"})
+	if project.Valid || region.String != "us-east-1" || tagKey.Valid || tagValue.Valid {
+		t.Errorf("region scope = %v, %v, %v, %v", project, region, tagKey, tagValue)
+	}
+	project, region, tagKey, tagValue = budgetScope(sqlc.Budget{ScopeType: BudgetScopeTag, ScopeKey: "team", ScopeValue: "web"})
+	if project.Valid || region.Valid || tagKey.String != "team" || tagValue.String != "web" {
+		t.Errorf("tag scope = %v, %v, %v, %v", project, region, tagKey, tagValue)
+	}
+}
+
+func TestCrossedThresholds(t *testing.T) {
+	thresholds := []int32{50, 80, 100}
+	tests := []struct {
+		percent float64
+		want    []int32
+	}{
+		{percent: 0, want: []int32{}},
+		{percent: 49.9, want: []int32{}},
+		{percent: 50, want: []int32{50}},
+		{percent: 99.9, want: []int32{50, 80}},
+		{percent: 100, want: []int32{50, 80, 100}},
+		{percent: 250, want: []int32{50, 80, 100}},
+	}
+	for _, tt := range tests {
+		if got := crossedThresholds(thresholds, tt.percent); !slices.Equal(got, tt.want) {
+			t.Errorf("crossedThresholds(%v) = %v, want %v", tt.percent, got, tt.want)
+		}
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	var received BudgetAlertEvent
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Content-Type = %q, want application/json", r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("failed to decode webhook body: %v", err)
+		}
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	event := BudgetAlertEvent{BudgetName: "team", Threshold: 80, Spend: 81, Amount: 100}
+	if err := postWebhook(context.Background(), server.Client(), server.URL, event); err != nil {
+		t.Fatalf("postWebhook failed: %v", err)
+	}
+	if received.BudgetName != "team" || received.Threshold != 80 {
+		t.Errorf("webhook received %+v, want %+v", received, event)
+	}
+
+	status = http.StatusInternalServerError
+	if err := postWebhook(context.Background(), server.Client(), server.URL, event); err == nil {
+		t.Error("postWebhook succeeded on a 500 response; want an error")
+	}
+}
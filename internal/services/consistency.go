@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// Kinds of anomaly the consistency check detects.
+const (
+	// AnomalyAddressMismatch is a live server whose address is not the primary
+	// address of its primary interface, or that has none.
+	AnomalyAddressMismatch = "address_mismatch"
+	// AnomalyIPOnTerminatedServer is an address still bound to a terminated server.
+	AnomalyIPOnTerminatedServer = "ip_on_terminated_server"
+	// AnomalyNATOnTerminatedServer is an active NAT mapping of a terminated server.
+	AnomalyNATOnTerminatedServer = "nat_on_terminated_server"
+	// AnomalyOrphanedIP is an address reserved for a server but never bound to one.
+	AnomalyOrphanedIP = "orphaned_ip"
+	// AnomalyStuckProvisioning is a server that has been provisioning for longer
+	// than the provisioning entry of STUCK_STATE_TIMEOUTS without completing. It
+	// is reported only; the stuck-state watchdog deals with it.
+	AnomalyStuckProvisioning = "stuck_provisioning"
+)
+
+// consistencySettleTime is how long provisioning may take to bind a new server's
+// address before the check considers the server or the address inconsistent.
+const consistencySettleTime = time.Minute
+
+// ConsistencyAnomaly is one inconsistency between servers and their IP bindings.
+type ConsistencyAnomaly struct {
+	Kind     string
+	ServerID pgtype.UUID
+	// IPAddressID is the address or NAT mapping concerned, if any.
+	IPAddressID pgtype.UUID
+	Detail      string
+	Repaired    bool
+	// RepairError is why a repair was attempted and failed.
+	RepairError string
+}
+
+// ConsistencyReport is the outcome of one consistency check.
+type ConsistencyReport struct {
+	CheckedAt time.Time
+	Repair    bool
+	Anomalies []ConsistencyAnomaly
+}
+
+// ConsistencyService checks that servers, their addresses and NAT mappings agree
+// and, with CONSISTENCY_AUTO_REPAIR, fixes what does not.
+type ConsistencyService struct {
+	queries *sqlc.Queries
+	servers *ServerService
+	logger  *zap.Logger
+	config  *config.Config
+}
+
+// NewConsistencyService creates a new ConsistencyService.
+func NewConsistencyService(queries *sqlc.Queries, servers *ServerService, logger *zap.Logger, config *config.Config) *ConsistencyService {
+	return &ConsistencyService{
+		queries: queries,
+		servers: servers,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+// Start runs the check every CONSISTENCY_INTERVAL until ctx is cancelled, repairing
+// the anomalies it finds if CONSISTENCY_AUTO_REPAIR is set.
+func (c *ConsistencyService) Start(ctx context.Context) {
+	ticker := time.NewTicker(c.config.ConsistencyInterval)
+	defer ticker.Stop()
+
+	c.logger.Info("Consistency daemon started",
+		zap.Duration("interval", c.config.ConsistencyInterval),
+		zap.Bool("auto_repair", c.config.ConsistencyAutoRepair),
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			c.logger.Info("Consistency daemon stopped due to context cancellation.")
+			return
+		case <-ticker.C:
+			report, err := c.Check(ctx, time.Now(), c.config.ConsistencyAutoRepair)
+			if err != nil {
+				c.logger.Error("Consistency check failed", zap.Error(err))
+				continue
+			}
+			for _, anomaly := range report.Anomalies {
+				c.logger.Warn("Consistency anomaly",
+					zap.String("kind", anomaly.Kind),
+					zap.String("server_id", anomaly.ServerID.String()),
+					zap.String("ip_id", anomaly.IPAddressID.String()),
+					zap.String("detail", anomaly.Detail),
+					zap.Bool("repaired", anomaly.Repaired),
+					zap.String("repair_error", anomaly.RepairError),
+				)
+			}
+		}
+	}
+}
+
+// Check looks for anomalies as of now and, if repair is set, repairs each one as it
+// is found. Servers stuck provisioning are reported but never repaired here: the
+// stuck-state watchdog retries them or moves them to error, so their other
+// anomalies are left alone too.
+func (c *ConsistencyService) Check(ctx context.Context, now time.Time, repair bool) (ConsistencyReport, error) {
+	report := ConsistencyReport{CheckedAt: now, Repair: repair, Anomalies: []ConsistencyAnomaly{}}
+	settledBefore := pgtype.Timestamptz{Time: now.Add(-consistencySettleTime), Valid: true}
+
+	// Provisioning is stuck by the watchdog's timeout, so the two never disagree;
+	// without one, nothing is stuck and address mismatches are repaired as usual
+	var stuckServers []sqlc.Server
+	if timeout, ok := c.config.StuckStateTimeouts[util.ServerStatusProvisioning]; ok {
+		var err error
+		stuckServers, err = c.queries.ListStuckProvisioningServers(ctx, pgtype.Timestamptz{Time: now.Add(-timeout), Valid: true})
+		if err != nil {
+			return ConsistencyReport{}, fmt.Errorf("failed to list stuck servers: %+v", err)
+		}
+	}
+	stuck := make(map[pgtype.UUID]bool, len(stuckServers))
+	for _, server := range stuckServers {
+		stuck[server.ID] = true
+		report.Anomalies = append(report.Anomalies, ConsistencyAnomaly{
+			Kind:     AnomalyStuckProvisioning,
+			ServerID: server.ID,
+			Detail:   fmt.Sprintf("provisioning since %s; left to the stuck-state watchdog", server.LastStatusUpdate.Time.Format(time.RFC3339)),
+		})
+	}
+
+	mismatches, err := c.queries.ListAddressMismatches(ctx, settledBefore)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("failed to list address mismatches: %+v", err)
+	}
+	for _, mismatch := range mismatches {
+		if stuck[mismatch.ServerID] {
+			continue
+		}
+		anomaly := ConsistencyAnomaly{
+			Kind:        AnomalyAddressMismatch,
+			ServerID:    mismatch.ServerID,
+			IPAddressID: mismatch.IpID,
+			Detail:      fmt.Sprintf("server address %s, primary interface has no primary address", mismatch.Address),
+		}
+		if mismatch.IpID.Valid {
+			anomaly.Detail = fmt.Sprintf("server address %s, primary address %s", mismatch.Address, mismatch.IpAddress.String)
+		}
+		if repair {
+			c.repair(ctx, &anomaly, "address restored", func() error {
+				return c.restoreAddress(ctx, mismatch, &anomaly)
+			})
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+	}
+
+	mappings, err := c.queries.ListActiveNATMappingsOfTerminatedServers(ctx)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("failed to list NAT mappings of terminated servers: %+v", err)
+	}
+	for _, mapping := range mappings {
+		anomaly := ConsistencyAnomaly{
+			Kind:        AnomalyNATOnTerminatedServer,
+			ServerID:    mapping.ServerID,
+			IPAddressID: mapping.ID,
+			Detail:      fmt.Sprintf("public IP %s still mapped to %s", mapping.PublicAddress, mapping.PrivateAddress),
+		}
+		if repair {
+			c.repair(ctx, &anomaly, "NAT mapping released", func() error {
+				if err := c.queries.ReleaseNATMapping(ctx, mapping.ID); err != nil {
+					return fmt.Errorf("failed to release NAT mapping: %+v", err)
+				}
+				return nil
+			})
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+	}
+
+	ipAddresses, err := c.queries.ListIPAddressesOfTerminatedServers(ctx)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("failed to list IP addresses of terminated servers: %+v", err)
+	}
+	for _, ipAddress := range ipAddresses {
+		anomaly := ConsistencyAnomaly{
+			Kind:        AnomalyIPOnTerminatedServer,
+			ServerID:    ipAddress.ServerID,
+			IPAddressID: ipAddress.ID,
+			Detail:      fmt.Sprintf("IP %s still bound to the terminated server", ipAddress.Address),
+		}
+		if repair {
+			c.repair(ctx, &anomaly, "IP "+ipAddress.Address+" released", func() error {
+				return c.servers.ipAllocator.ReleaseIP(ctx, ipAddress)
+			})
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+	}
+
+	orphans, err := c.queries.ListOrphanedIPAddresses(ctx, settledBefore)
+	if err != nil {
+		return ConsistencyReport{}, fmt.Errorf("failed to list orphaned IP addresses: %+v", err)
+	}
+	for _, ipAddress := range orphans {
+		anomaly := ConsistencyAnomaly{
+			Kind:        AnomalyOrphanedIP,
+			IPAddressID: ipAddress.ID,
+			Detail:      fmt.Sprintf("IP %s reserved since %s but bound to no server", ipAddress.Address, ipAddress.UpdatedAt.Time.Format(time.RFC3339)),
+		}
+		if repair {
+			c.repair(ctx, &anomaly, "IP "+ipAddress.Address+" released", func() error {
+				return c.servers.ipAllocator.ReleaseIP(ctx, ipAddress)
+			})
+		}
+		report.Anomalies = append(report.Anomalies, anomaly)
+	}
+
+	return report, nil
+}
+
+// repair runs fix for an anomaly and records the outcome on it and, for anomalies
+// of a server, in the server's lifecycle logs.
+func (c *ConsistencyService) repair(ctx context.Context, anomaly *ConsistencyAnomaly, action string, fix func() error) {
+	if err := fix(); err != nil {
+		anomaly.RepairError = err.Error()
+		c.logger.Error("Consistency repair failed",
+			zap.Error(err),
+			zap.String("kind", anomaly.Kind),
+			zap.String("server_id", anomaly.ServerID.String()),
+			zap.String("ip_id", anomaly.IPAddressID.String()),
+		)
+		return
+	}
+	anomaly.Repaired = true
+	c.logger.Info("Consistency anomaly repaired",
+		zap.String("kind", anomaly.Kind),
+		zap.String("server_id", anomaly.ServerID.String()),
+		zap.String("ip_id", anomaly.IPAddressID.String()),
+		zap.String("action", action),
+	)
+
+	if !anomaly.ServerID.Valid {
+		return
+	}
+	c.servers.RecordEvent(WithEventActor(ctx, ActorConsistency), anomaly.ServerID, ServerEvent{
+		Type:    EventRepair,
+		Message: "Consistency repair (" + anomaly.Kind + "): " + action,
+		Details: map[string]any{"kind": anomaly.Kind, "detail": anomaly.Detail},
+	})
+}
+
+// restoreAddress points the server's address at its primary address, allocating
+// one from the region's private pool, and the primary interface to hold it, if
+// the server has none.
+func (c *ConsistencyService) restoreAddress(ctx context.Context, mismatch sqlc.ListAddressMismatchesRow, anomaly *ConsistencyAnomaly) error {
+	address := mismatch.IpAddress.String
+	if !mismatch.IpID.Valid {
+		interfaceID := mismatch.InterfaceID
+		if !interfaceID.Valid {
+			deviceIndex, err := c.queries.GetNextDeviceIndex(ctx, mismatch.ServerID)
+			if err != nil {
+				return fmt.Errorf("failed to get next device index: %+v", err)
+			}
+			primaryInterface, err := c.queries.CreateNetworkInterface(ctx, sqlc.CreateNetworkInterfaceParams{
+				ServerID:    mismatch.ServerID,
+				DeviceIndex: deviceIndex,
+				IsPrimary:   true,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create primary network interface: %+v", err)
+			}
+			interfaceID = primaryInterface.ID
+		}
+		poolName, err := c.servers.privatePoolFor(mismatch.Region)
+		if err != nil {
+			return err
+		}
+		ipAddress, err := c.servers.assignIP(ctx, mismatch.ServerID, interfaceID, true, poolName)
+		if err != nil {
+			return err
+		}
+		address = ipAddress.Address
+		anomaly.IPAddressID = ipAddress.ID
+	}
+
+	if _, err := c.queries.SetServerAddress(ctx, sqlc.SetServerAddressParams{
+		Address: address,
+		ID:      mismatch.ServerID,
+	}); err != nil {
+		return fmt.Errorf("failed to set server address: %+v", err)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// consistencyDB answers listings with rows[name] and single-row statements with
+// one[name], by sqlc query name, and records every statement in the order it ran.
+// Other single-row statements fail as if the row were gone; Exec always succeeds.
+type consistencyDB struct {
+	rows map[string][]any
+	one  map[string]any
+	ran  []string
+}
+
+func (db *consistencyDB) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	db.ran = append(db.ran, queryName(sql))
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *consistencyDB) Query(_ context.Context, sql string, _ ...interface{}) (pgx.Rows, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, name)
+	return &structRows{rows: db.rows[name]}, nil
+}
+
+func (db *consistencyDB) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	name := queryName(sql)
+	db.ran = append(db.ran, name)
+	if row, ok := db.one[name]; ok {
+		// Positioned on its only row, structRows scans like a pgx.Row
+		return &structRows{rows: []any{row}, next: 1}
+	}
+	return serverRow{err: pgx.ErrNoRows}
+}
+
+func TestConsistencyCheck(t *testing.T) {
+	now := mustTime(t, "2026-03-01T12:00:00Z")
+	stuck := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusProvisioning, LastStatusUpdate: timestamptz(now.Add(-time.Hour))}
+	rows := map[string][]any{
+		"ListStuckProvisioningServers": {stuck},
+		"ListAddressMismatches": {
+			// Left to the watchdog with the rest of the stuck server
+			sqlc.ListAddressMismatchesRow{ServerID: stuck.ID, Region: "us-east-1", Address: ""},
+			sqlc.ListAddressMismatchesRow{
+				ServerID: testUUID(2), Region: "us-east-1", Address: "10.0.0.5",
+				InterfaceID: testUUID(20), IpID: testUUID(21), IpAddress: pgtype.Text{String: "10.0.0.9", Valid: true},
+			},
+			// No pool for the region, so there is nothing to allocate its address from
+			sqlc.ListAddressMismatchesRow{ServerID: testUUID(3), Region: "ap-south-1", Address: "10.0.0.6", InterfaceID: testUUID(30)},
+		},
+		"ListActiveNATMappingsOfTerminatedServers": {
+			sqlc.NatMapping{ID: testUUID(40), ServerID: testUUID(4), PublicAddress: "203.0.113.7", PrivateAddress: "10.0.0.7"},
+		},
+		"ListIPAddressesOfTerminatedServers": {
+			sqlc.IpAddress{ID: testUUID(41), ServerID: testUUID(4), Address: "10.0.0.7"},
+		},
+		"ListOrphanedIPAddresses": {
+			sqlc.IpAddress{ID: testUUID(50), Address: "10.0.0.8", UpdatedAt: timestamptz(now.Add(-time.Hour))},
+		},
+	}
+
+	type wantAnomaly struct {
+		kind      string
+		server    byte
+		detail    string // substring of the anomaly's detail
+		repaired  bool
+		repairErr string // substring of the anomaly's repair error
+	}
+	tests := []struct {
+		name     string
+		timeouts config.StateTimeouts
+		repair   bool
+		want     []wantAnomaly
+		wantRan  []string
+	}{
+		{
+			name:     "report only",
+			timeouts: config.StateTimeouts{util.ServerStatusProvisioning: 15 * time.Minute},
+			want: []wantAnomaly{
+				{kind: AnomalyStuckProvisioning, server: 1, detail: "provisioning since 2026-03-01T11:00:00Z"},
+				{kind: AnomalyAddressMismatch, server: 2, detail: "server address 10.0.0.5, primary address 10.0.0.9"},
+				{kind: AnomalyAddressMismatch, server: 3, detail: "primary interface has no primary address"},
+				{kind: AnomalyNATOnTerminatedServer, server: 4, detail: "public IP 203.0.113.7 still mapped to 10.0.0.7"},
+				{kind: AnomalyIPOnTerminatedServer, server: 4, detail: "IP 10.0.0.7 still bound"},
+				{kind: AnomalyOrphanedIP, detail: "IP 10.0.0.8 reserved since 2026-03-01T11:00:00Z"},
+			},
+			wantRan: []string{
+				"ListStuckProvisioningServers", "ListAddressMismatches", "ListActiveNATMappingsOfTerminatedServers",
+				"ListIPAddressesOfTerminatedServers", "ListOrphanedIPAddresses",
+			},
+		},
+		{
+			name: "nothing stuck without a provisioning timeout",
+			want: []wantAnomaly{
+				{kind: AnomalyAddressMismatch, server: 1, detail: "primary interface has no primary address"},
+				{kind: AnomalyAddressMismatch, server: 2},
+				{kind: AnomalyAddressMismatch, server: 3},
+				{kind: AnomalyNATOnTerminatedServer, server: 4},
+				{kind: AnomalyIPOnTerminatedServer, server: 4},
+				{kind: AnomalyOrphanedIP},
+			},
+			wantRan: []string{
+				"ListAddressMismatches", "ListActiveNATMappingsOfTerminatedServers",
+				"ListIPAddressesOfTerminatedServers", "ListOrphanedIPAddresses",
+			},
+		},
+		{
+			// Recording the repairs in the servers' lifecycle logs fails, which is only logged
+			name:     "repair",
+			timeouts: config.StateTimeouts{util.ServerStatusProvisioning: 15 * time.Minute},
+			repair:   true,
+			want: []wantAnomaly{
+				{kind: AnomalyStuckProvisioning, server: 1},
+				{kind: AnomalyAddressMismatch, server: 2, repaired: true},
+				{kind: AnomalyAddressMismatch, server: 3, repairErr: `"ap-south-1"`},
+				{kind: AnomalyNATOnTerminatedServer, server: 4, repaired: true},
+				{kind: AnomalyIPOnTerminatedServer, server: 4, repaired: true},
+				{kind: AnomalyOrphanedIP, repaired: true},
+			},
+			wantRan: []string{
+				"ListStuckProvisioningServers",
+				"ListAddressMismatches", "SetServerAddress", "RecordServerEvent",
+				"ListActiveNATMappingsOfTerminatedServers", "ReleaseNATMapping", "RecordServerEvent",
+				"ListIPAddressesOfTerminatedServers", "DeallocateIPAddress", "RecordServerEvent",
+				"ListOrphanedIPAddresses", "DeallocateIPAddress",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &consistencyDB{
+				rows: rows,
+				one: map[string]any{
+					"SetServerAddress":    sqlc.Server{ID: testUUID(2), Address: "10.0.0.9"},
+					"DeallocateIPAddress": sqlc.IpAddress{Address: "10.0.0.7"},
+				},
+			}
+			cfg := &config.Config{
+				StuckStateTimeouts: tt.timeouts,
+				PrivateIPPools:     map[string]string{"us-east-1": "10.0.0.0/16"},
+			}
+			queries := sqlc.New(db)
+			servers := &ServerService{queries: queries, logger: zap.NewNop(), config: cfg, ipAllocator: &IPAllocator{queries: queries, logger: zap.NewNop()}}
+
+			report, err := NewConsistencyService(queries, servers, zap.NewNop(), cfg).Check(context.Background(), now, tt.repair)
+			if err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if strings.Join(db.ran, ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("Check() ran %v; want %v", db.ran, tt.wantRan)
+			}
+			if len(report.Anomalies) != len(tt.want) {
+				t.Fatalf("Check() found %d anomalies; want %d: %+v", len(report.Anomalies), len(tt.want), report.Anomalies)
+			}
+			for i, want := range tt.want {
+				got := report.Anomalies[i]
+				wantServer := pgtype.UUID{}
+				if want.server != 0 {
+					wantServer = testUUID(want.server)
+				}
+				if got.Kind != want.kind || got.ServerID != wantServer || !strings.Contains(got.Detail, want.detail) ||
+					got.Repaired != want.repaired || !strings.Contains(got.RepairError, want.repairErr) || (want.repairErr == "" && got.RepairError != "") {
+					t.Errorf("anomaly %d = %+v; want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+var (
+	// ErrInvalidCurrency is returned for a currency that is not a three-letter code.
+	ErrInvalidCurrency = errors.New("currency must be a three-letter code such as EUR")
+	// ErrUnknownCurrency is returned for a currency without an exchange rate in effect.
+	ErrUnknownCurrency = errors.New("no exchange rate is defined for this currency")
+	// ErrInvalidExchangeRate is returned when an exchange rate is not positive.
+	ErrInvalidExchangeRate = errors.New("rate must be positive")
+	// ErrExchangeRateExists is returned when a version of the same currency already takes effect at that time.
+	ErrExchangeRateExists = errors.New("an exchange rate already takes effect at this time")
+)
+
+// ExchangeRates holds every exchange rate version, ordered by effective time per currency.
+type ExchangeRates struct {
+	versions map[string][]sqlc.ExchangeRate
+}
+
+// ExchangeRateVersion is an exchange rate together with the time the next version replaces it.
+type ExchangeRateVersion struct {
+	Rate           sqlc.ExchangeRate
+	EffectiveUntil pgtype.Timestamptz
+}
+
+// NormalizeCurrency upper-cases a currency code and checks its format.
+func NormalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if len(currency) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, c := range currency {
+		if c < 'A' || c > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return currency, nil
+}
+
+// ExchangeRates reads every exchange rate version.
+func (b *BillingService) ExchangeRates(ctx context.Context) (ExchangeRates, error) {
+	rates, err := b.db.Queries.ListExchangeRates(ctx)
+	if err != nil {
+		return ExchangeRates{}, fmt.Errorf("failed to list exchange rates: %+v", err)
+	}
+
+	exchangeRates := ExchangeRates{versions: make(map[string][]sqlc.ExchangeRate)}
+	for _, rate := range rates {
+		exchangeRates.versions[rate.Currency] = append(exchangeRates.versions[rate.Currency], rate)
+	}
+	return exchangeRates, nil
+}
+
+// RateAt returns the units of a currency one USD buys at time t.
+func (e ExchangeRates) RateAt(currency string, t time.Time) (float64, error) {
+	if currency == CurrencyUSD {
+		return 1, nil
+	}
+	versions := e.versions[currency]
+	for i := len(versions) - 1; i >= 0; i-- {
+		if !versions[i].EffectiveFrom.Time.After(t) {
+			return versions[i].Rate, nil
+		}
+	}
+	return 0, ErrUnknownCurrency
+}
+
+// Versions lists the exchange rates of a currency (empty matches any), each with
+// the time it is replaced.
+func (e ExchangeRates) Versions(currency string) []ExchangeRateVersion {
+	var result []ExchangeRateVersion
+	for key, versions := range e.versions {
+		if currency != "" && key != currency {
+			continue
+		}
+		for i, rate := range versions {
+			version := ExchangeRateVersion{Rate: rate}
+			if i+1 < len(versions) {
+				version.EffectiveUntil = versions[i+1].EffectiveFrom
+			}
+			result = append(result, version)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Rate, result[j].Rate
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.EffectiveFrom.Time.Before(b.EffectiveFrom.Time)
+	})
+	return result
+}
+
+// ConvertInvoice restates an invoice and its lines in another currency, at the
+// rate in effect when its period ended. The stored invoice is not changed.
+func (e ExchangeRates) ConvertInvoice(invoice sqlc.Invoice, lines []sqlc.InvoiceLine, currency string) (sqlc.Invoice, []sqlc.InvoiceLine, error) {
+	if currency == invoice.Currency {
+		return invoice, lines, nil
+	}
+	rate, err := e.RateAt(currency, invoice.PeriodEnd.Time)
+	if err != nil {
+		return sqlc.Invoice{}, nil, err
+	}
+
+	factor := rate / invoice.ExchangeRate
+	converted := make([]sqlc.InvoiceLine, len(lines))
+	for i, line := range lines {
+		line.UnitPrice *= factor
+		line.Amount = roundCents(line.Amount * factor)
+		converted[i] = line
+	}
+	invoice.Currency = currency
+	invoice.ExchangeRate = rate
+	invoice.Total = roundCents(invoice.Total * factor)
+	return invoice, converted, nil
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// exchangeRateSeed is an entry of the EXCHANGE_RATES_FILE.
+type exchangeRateSeed struct {
+	Currency      string    `json:"currency"`
+	Rate          float64   `json:"rate"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+}
+
+// SeedExchangeRates writes the versions listed in EXCHANGE_RATES_FILE that the
+// table does not have yet. Entries without effectiveFrom apply from the epoch.
+// A missing file is not an error; every project is billed in USD until rates
+// are added through the API.
+func (b *BillingService) SeedExchangeRates(ctx context.Context) error {
+	if b.config.ExchangeRatesFile == "" {
+		return nil
+	}
+	raw, err := os.ReadFile(b.config.ExchangeRatesFile)
+	if errors.Is(err, os.ErrNotExist) {
+		b.logger.Warn("Exchange rate file not found", zap.String("file", b.config.ExchangeRatesFile))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read exchange rate file: %+v", err)
+	}
+
+	var seeds []exchangeRateSeed
+	if err := json.Unmarshal(raw, &seeds); err != nil {
+		return fmt.Errorf("failed to parse exchange rate file: %+v", err)
+	}
+	for _, seed := range seeds {
+		currency, err := NormalizeCurrency(seed.Currency)
+		if err != nil || currency == CurrencyUSD || seed.Rate <= 0 {
+			b.logger.Warn("Ignoring invalid exchange rate", zap.String("currency", seed.Currency), zap.Float64("rate", seed.Rate))
+			continue
+		}
+		if seed.EffectiveFrom.IsZero() {
+			seed.EffectiveFrom = time.Unix(0, 0)
+		}
+		err = b.db.Queries.SeedExchangeRate(ctx, sqlc.SeedExchangeRateParams{
+			Currency:      currency,
+			Rate:          seed.Rate,
+			EffectiveFrom: pgtype.Timestamptz{Time: seed.EffectiveFrom, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to seed exchange rate of %s: %+v", currency, err)
+		}
+	}
+	return nil
+}
+
+// CreateExchangeRate adds a version of a currency's exchange rate. Unlike prices,
+// versions may take effect in the past: issued invoices keep the rate they were
+// issued at, so only periods not invoiced yet are affected.
+func (b *BillingService) CreateExchangeRate(ctx context.Context, currency string, rate float64, effectiveFrom time.Time) (ExchangeRateVersion, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return ExchangeRateVersion{}, err
+	}
+	if currency == CurrencyUSD {
+		return ExchangeRateVersion{}, fmt.Errorf("%w: USD is the base currency", ErrInvalidCurrency)
+	}
+	if rate <= 0 {
+		return ExchangeRateVersion{}, ErrInvalidExchangeRate
+	}
+	if effectiveFrom.IsZero() {
+		effectiveFrom = time.Now()
+	}
+
+	created, err := b.db.Queries.CreateExchangeRate(ctx, sqlc.CreateExchangeRateParams{
+		Currency:      currency,
+		Rate:          rate,
+		EffectiveFrom: pgtype.Timestamptz{Time: effectiveFrom, Valid: true},
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ExchangeRateVersion{}, ErrExchangeRateExists
+	}
+	if err != nil {
+		return ExchangeRateVersion{}, fmt.Errorf("failed to create exchange rate: %+v", err)
+	}
+
+	b.logger.Info("Exchange rate added",
+		zap.String("currency", created.Currency),
+		zap.Float64("rate", created.Rate),
+		zap.Time("effective_from", created.EffectiveFrom.Time),
+	)
+
+	rates, err := b.ExchangeRates(ctx)
+	if err != nil {
+		return ExchangeRateVersion{}, err
+	}
+	for _, version := range rates.Versions(created.Currency) {
+		if version.Rate.ID == created.ID {
+			return version, nil
+		}
+	}
+	return ExchangeRateVersion{Rate: created}, nil
+}
+
+// SetProjectCurrency sets the currency a project's invoices are issued in from
+// now on. The currency needs an exchange rate in effect.
+func (b *BillingService) SetProjectCurrency(ctx context.Context, project, currency string) (sqlc.Project, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return sqlc.Project{}, err
+	}
+	rates, err := b.ExchangeRates(ctx)
+	if err != nil {
+		return sqlc.Project{}, err
+	}
+	if _, err := rates.RateAt(currency, time.Now()); err != nil {
+		return sqlc.Project{}, err
+	}
+
+	settings, err := b.db.Queries.SetProjectCurrency(ctx, sqlc.SetProjectCurrencyParams{
+		Name:     project,
+		Currency: currency,
+	})
+	if err != nil {
+		return sqlc.Project{}, fmt.Errorf("failed to set project currency: %+v", err)
+	}
+	b.logger.Info("Project currency set", zap.String("project", project), zap.String("currency", currency))
+	return settings, nil
+}
+
+// GetProject returns the billing settings of a project; a project without any is
+// billed in USD.
+func (b *BillingService) GetProject(ctx context.Context, project string) (sqlc.Project, error) {
+	settings, err := b.db.Queries.GetProject(ctx, project)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Project{Name: project, Currency: CurrencyUSD}, nil
+	}
+	if err != nil {
+		return sqlc.Project{}, fmt.Errorf("failed to get project: %+v", err)
+	}
+	return settings, nil
+}
+
+// ResolveCurrency picks the currency of a billing response, the requested one or
+// else the project's (USD without a project), and returns it with the units of it
+// one USD buys at t.
+func (b *BillingService) ResolveCurrency(ctx context.Context, requested, project string, t time.Time) (string, float64, error) {
+	currency := CurrencyUSD
+	if requested != "" {
+		normalized, err := NormalizeCurrency(requested)
+		if err != nil {
+			return "", 0, err
+		}
+		currency = normalized
+	} else if project != "" {
+		settings, err := b.GetProject(ctx, project)
+		if err != nil {
+			return "", 0, err
+		}
+		currency = settings.Currency
+	}
+
+	rates, err := b.ExchangeRates(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	rate, err := rates.RateAt(currency, t)
+	if err != nil {
+		return "", 0, err
+	}
+	return currency, rate, nil
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// testExchangeRates has EUR at 0.9 per USD, then 0.95 from March, and GBP at 0.8.
+func testExchangeRates(t *testing.T) ExchangeRates {
+	rate := func(currency string, rate float64, from string) sqlc.ExchangeRate {
+		return sqlc.ExchangeRate{Currency: currency, Rate: rate, EffectiveFrom: timestamptz(mustTime(t, from))}
+	}
+	return ExchangeRates{versions: map[string][]sqlc.ExchangeRate{
+		"EUR": {rate("EUR", 0.9, "2026-01-01T00:00:00Z"), rate("EUR", 0.95, "2026-03-01T00:00:00Z")},
+		"GBP": {rate("GBP", 0.8, "2026-01-01T00:00:00Z")},
+	}}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	tests := []struct {
+		currency string
+		want     string
+		wantErr  bool
+	}{
+		{currency: "EUR", want: "EUR"},
+		{currency: " eur ", want: "EUR"},
+		{currency: "usd", want: "USD"},
+		{currency: "", wantErr: true},
+		{currency: "EURO", wantErr: true},
+		{currency: "E1R", wantErr: true},
+		{currency: "€UR", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeCurrency(tt.currency)
+		if tt.wantErr != errors.Is(err, ErrInvalidCurrency) || got != tt.want {
+			t.Errorf("NormalizeCurrency(%q) = %q, %v; want %q, error %v", tt.currency, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExchangeRatesRateAt(t *testing.T) {
+	rates := testExchangeRates(t)
+	tests := []struct {
+		currency string
+		at       string
+		want     float64
+		wantErr  error
+	}{
+		{currency: CurrencyUSD, at: "2020-01-01T00:00:00Z", want: 1},
+		{currency: "EUR", at: "2026-02-28T23:59:59Z", want: 0.9},
+		{currency: "EUR", at: "2026-03-01T00:00:00Z", want: 0.95},
+		{currency: "EUR", at: "2025-12-31T00:00:00Z", wantErr: ErrUnknownCurrency},
+		{currency: "JPY", at: "2026-03-01T00:00:00Z", wantErr: ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		got, err := rates.RateAt(tt.currency, mustTime(t, tt.at))
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("RateAt(%s, %s) = %v, %v; want %v, %v", tt.currency, tt.at, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestExchangeRatesVersions(t *testing.T) {
+	versions := testExchangeRates(t).Versions("")
+	if len(versions) != 3 || versions[0].Rate.Currency != "EUR" || versions[2].Rate.Currency != "GBP" {
+		t.Fatalf("Versions = %+v, want EUR twice then GBP", versions)
+	}
+	if !versions[0].EffectiveUntil.Time.Equal(versions[1].Rate.EffectiveFrom.Time) || versions[1].EffectiveUntil.Valid || versions[2].EffectiveUntil.Valid {
+		t.Errorf("EffectiveUntil = %v, %v, %v; want March, none, none", versions[0].EffectiveUntil, versions[1].EffectiveUntil, versions[2].EffectiveUntil)
+	}
+	if versions := testExchangeRates(t).Versions("GBP"); len(versions) != 1 {
+		t.Errorf("Versions(GBP) = %+v, want one", versions)
+	}
+}
+
+func TestConvertInvoice(t *testing.T) {
+	rates := testExchangeRates(t)
+	invoice := sqlc.Invoice{
+		Currency:     "EUR",
+		ExchangeRate: 0.9,
+		PeriodEnd:    pgtype.Date{Time: mustTime(t, "2026-02-01T00:00:00Z"), Valid: true},
+		Total:        9.01,
+	}
+	lines := []sqlc.InvoiceLine{{UnitPrice: 0.09, Amount: 9.01}}
+
+	// Same currency is returned as is
+	if converted, convertedLines, err := rates.ConvertInvoice(invoice, lines, "EUR"); err != nil || converted != invoice || convertedLines[0] != lines[0] {
+		t.Errorf("ConvertInvoice to its own currency = %+v, %+v, %v", converted, convertedLines, err)
+	}
+
+	converted, convertedLines, err := rates.ConvertInvoice(invoice, lines, "GBP")
+	if err != nil {
+		t.Fatalf("ConvertInvoice failed: %v", err)
+	}
+	if converted.Currency != "GBP" || converted.ExchangeRate != 0.8 || converted.Total != 8.01 {
+		t.Errorf("converted invoice = %s at %v totalling %v; want GBP at 0.8 totalling 8.01", converted.Currency, converted.ExchangeRate, converted.Total)
+	}
+	if !closeTo(convertedLines[0].UnitPrice, 0.08) || convertedLines[0].Amount != 8.01 {
+		t.Errorf("converted line = %v per unit for %v; want 0.08 for 8.01", convertedLines[0].UnitPrice, convertedLines[0].Amount)
+	}
+	if lines[0].Amount != 9.01 || invoice.Currency != "EUR" {
+		t.Error("ConvertInvoice changed the stored invoice")
+	}
+
+	// The rate is the one in effect when the period ended
+	march := invoice
+	march.Currency, march.ExchangeRate, march.PeriodEnd.Time = CurrencyUSD, 1, mustTime(t, "2026-04-01T00:00:00Z")
+	if converted, _, err := rates.ConvertInvoice(march, nil, "EUR"); err != nil || converted.ExchangeRate != 0.95 {
+		t.Errorf("ConvertInvoice of March = %v, %v; want rate 0.95", converted.ExchangeRate, err)
+	}
+	if _, _, err := rates.ConvertInvoice(invoice, lines, "JPY"); !errors.Is(err, ErrUnknownCurrency) {
+		t.Errorf("ConvertInvoice to JPY = %v, want ErrUnknownCurrency", err)
+	}
+}
+
+func TestRoundCents(t *testing.T) {
+	for amount, want := range map[float64]float64{0: 0, 1.234: 1.23, 1.235: 1.24, 1.005: 1.0, -2.5: -2.5, 10.999: 11} {
+		if got := roundCents(amount); got != want {
+			t.Errorf("roundCents(%v) = %v, want %v", amount, got, want)
+		}
+	}
+}
+
+func TestCreateExchangeRateRejects(t *testing.T) {
+	var b BillingService
+	tests := []struct {
+		currency string
+		rate     float64
+		want     error
+	}{
+		{currency: "EURO", rate: 0.9, want: ErrInvalidCurrency},
+		{currency: "usd", rate: 1, want: ErrInvalidCurrency},
+		{currency: "EUR", rate: 0, want: ErrInvalidExchangeRate},
+		{currency: "EUR", rate: -1, want: ErrInvalidExchangeRate},
+	}
+	for _, tt := range tests {
+		if _, err := b.CreateExchangeRate(context.Background(), tt.currency, tt.rate, time.Time{}); !errors.Is(err, tt.want) {
+			t.Errorf("CreateExchangeRate(%s, %v) = %v, want %v", tt.currency, tt.rate, err, tt.want)
+		}
+	}
+}
+
+func TestSeedExchangeRatesWithoutFile(t *testing.T) {
+	for _, file := range []string{"", filepath.Join(t.TempDir(), "missing.json")} {
+		b := BillingService{config: &config.Config{ExchangeRatesFile: file}, logger: zap.NewNop()}
+		if err := b.SeedExchangeRates(context.Background()); err != nil {
+			t.Errorf("SeedExchangeRates with file %q = %v, want nil", file, err)
+		}
+	}
+}
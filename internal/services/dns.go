@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// maxUDPMessageSize is the classic DNS limit for responses over UDP without EDNS.
+	maxUDPMessageSize = 512
+	// dnsTCPIdleTimeout closes TCP connections that stop sending queries.
+	dnsTCPIdleTimeout = 10 * time.Second
+	reverseZoneSuffix = ".in-addr.arpa"
+)
+
+// DNSServer answers A, AAAA and PTR queries for server hostnames. Every query is
+// resolved against the servers and ip_addresses tables, so provisioning, renaming
+// and terminating a server is visible immediately without a separate zone file.
+type DNSServer struct {
+	queries *sqlc.Queries
+	logger  *zap.Logger
+	addr    string
+	zone    string
+	ttl     uint32
+}
+
+// NewDNSServer creates a new DNSServer for the zone in DNS_ZONE.
+func NewDNSServer(queries *sqlc.Queries, logger *zap.Logger, cfg *config.Config) *DNSServer {
+	return &DNSServer{
+		queries: queries,
+		logger:  logger,
+		addr:    cfg.DNSListenAddr,
+		zone:    strings.ToLower(strings.Trim(cfg.DNSZone, ".")),
+		ttl:     uint32(cfg.DNSTTL / time.Second),
+	}
+}
+
+// Start binds the UDP and TCP listeners and serves queries in the background
+// until ctx is cancelled. It only returns an error if a listener cannot be opened.
+func (dnsServer *DNSServer) Start(ctx context.Context) error {
+	packetConn, err := net.ListenPacket("udp", dnsServer.addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", dnsServer.addr, err)
+	}
+	listener, err := net.Listen("tcp", dnsServer.addr)
+	if err != nil {
+		packetConn.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %w", dnsServer.addr, err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		packetConn.Close()
+		listener.Close()
+		dnsServer.logger.Info("DNS server stopped due to context cancellation.")
+	}()
+	go dnsServer.serveUDP(ctx, packetConn)
+	go dnsServer.serveTCP(ctx, listener)
+
+	dnsServer.logger.Info("DNS server started", zap.String("address", dnsServer.addr), zap.String("zone", dnsServer.zone))
+	return nil
+}
+
+func (dnsServer *DNSServer) serveUDP(ctx context.Context, packetConn net.PacketConn) {
+	buf := make([]byte, 65535)
+	for {
+		n, remote, err := packetConn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil {
+				dnsServer.logger.Error("DNS UDP read failed", zap.Error(err))
+			}
+			return
+		}
+
+		query := append([]byte(nil), buf[:n]...)
+		go func() {
+			response := dnsServer.handle(ctx, query)
+			if response == nil {
+				return
+			}
+			if len(response) > maxUDPMessageSize {
+				response = truncate(response)
+			}
+			if _, err := packetConn.WriteTo(response, remote); err != nil {
+				dnsServer.logger.Debug("DNS UDP write failed", zap.Error(err), zap.String("remote", remote.String()))
+			}
+		}()
+	}
+}
+
+func (dnsServer *DNSServer) serveTCP(ctx context.Context, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				dnsServer.logger.Error("DNS TCP accept failed", zap.Error(err))
+			}
+			return
+		}
+		go dnsServer.serveTCPConn(ctx, conn)
+	}
+}
+
+// serveTCPConn answers length-prefixed queries on one connection until the client
+// closes it or goes idle.
+func (dnsServer *DNSServer) serveTCPConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+
+	var length [2]byte
+	for {
+		conn.SetDeadline(time.Now().Add(dnsTCPIdleTimeout))
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+
+		response := dnsServer.handle(ctx, query)
+		if response == nil {
+			return
+		}
+		framed := binary.BigEndian.AppendUint16(make([]byte, 0, len(response)+2), uint16(len(response)))
+		if _, err := conn.Write(append(framed, response...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle builds the response to a single DNS message. It returns nil for
+// messages too broken to answer.
+func (dnsServer *DNSServer) handle(ctx context.Context, query []byte) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil
+	}
+	if header.Response {
+		return nil
+	}
+
+	responseHeader := dnsmessage.Header{
+		ID:               header.ID,
+		Response:         true,
+		OpCode:           header.OpCode,
+		Authoritative:    true,
+		RecursionDesired: header.RecursionDesired,
+	}
+
+	question, err := parser.Question()
+	if err != nil {
+		responseHeader.RCode = dnsmessage.RCodeFormatError
+		return dnsServer.build(responseHeader, nil, nil, nil)
+	}
+	if header.OpCode != 0 {
+		responseHeader.RCode = dnsmessage.RCodeNotImplemented
+		return dnsServer.build(responseHeader, &question, nil, nil)
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	var answers, authorities []dnsmessage.Resource
+	switch {
+	case strings.HasSuffix(name, reverseZoneSuffix):
+		answers, responseHeader.RCode = dnsServer.answerPTR(ctx, name, question)
+	case name == dnsServer.zone || strings.HasSuffix(name, "."+dnsServer.zone):
+		answers, responseHeader.RCode = dnsServer.answerZone(ctx, name, question)
+		if len(answers) == 0 {
+			// Negative answers carry the SOA so resolvers know how long to cache them.
+			authorities = append(authorities, dnsServer.soa())
+		}
+	default:
+		responseHeader.Authoritative = false
+		responseHeader.RCode = dnsmessage.RCodeRefused
+	}
+
+	dnsServer.logger.Debug("DNS query answered",
+		zap.String("name", name),
+		zap.String("type", question.Type.String()),
+		zap.String("rcode", responseHeader.RCode.String()),
+		zap.Int("answers", len(answers)),
+	)
+	return dnsServer.build(responseHeader, &question, answers, authorities)
+}
+
+// answerZone resolves names inside the configured zone.
+func (dnsServer *DNSServer) answerZone(ctx context.Context, name string, question dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode) {
+	if name == dnsServer.zone {
+		if question.Type == dnsmessage.TypeSOA || question.Type == dnsmessage.TypeALL {
+			return []dnsmessage.Resource{dnsServer.soa()}, dnsmessage.RCodeSuccess
+		}
+		return nil, dnsmessage.RCodeSuccess
+	}
+
+	server, err := dnsServer.queries.GetLiveServerByHostname(ctx, name)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dnsmessage.RCodeNameError
+	}
+	if err != nil {
+		dnsServer.logger.Error("Failed to look up hostname", zap.Error(err), zap.String("hostname", name))
+		return nil, dnsmessage.RCodeServerFailure
+	}
+
+	// Pools are IPv4 only, so AAAA queries get an empty answer for names that exist.
+	if question.Type != dnsmessage.TypeA && question.Type != dnsmessage.TypeALL {
+		return nil, dnsmessage.RCodeSuccess
+	}
+	addr, err := netip.ParseAddr(server.Address)
+	if err != nil || !addr.Is4() {
+		return nil, dnsmessage.RCodeSuccess
+	}
+	return []dnsmessage.Resource{{
+		Header: dnsServer.resourceHeader(question.Name, dnsmessage.TypeA),
+		Body:   &dnsmessage.AResource{A: addr.As4()},
+	}}, dnsmessage.RCodeSuccess
+}
+
+// answerPTR resolves reverse lookups for any address bound to a live server,
+// private or public.
+func (dnsServer *DNSServer) answerPTR(ctx context.Context, name string, question dnsmessage.Question) ([]dnsmessage.Resource, dnsmessage.RCode) {
+	addr, ok := parseReverseName(name)
+	if !ok {
+		return nil, dnsmessage.RCodeNameError
+	}
+
+	server, err := dnsServer.queries.GetLiveServerByAddress(ctx, addr.String())
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, dnsmessage.RCodeNameError
+	}
+	if err != nil {
+		dnsServer.logger.Error("Failed to look up address", zap.Error(err), zap.String("ip", addr.String()))
+		return nil, dnsmessage.RCodeServerFailure
+	}
+
+	if question.Type != dnsmessage.TypePTR && question.Type != dnsmessage.TypeALL {
+		return nil, dnsmessage.RCodeSuccess
+	}
+	target, err := dnsmessage.NewName(server.Hostname + ".")
+	if err != nil {
+		dnsServer.logger.Error("Invalid hostname in database", zap.Error(err), zap.String("hostname", server.Hostname))
+		return nil, dnsmessage.RCodeServerFailure
+	}
+	return []dnsmessage.Resource{{
+		Header: dnsServer.resourceHeader(question.Name, dnsmessage.TypePTR),
+		Body:   &dnsmessage.PTRResource{PTR: target},
+	}}, dnsmessage.RCodeSuccess
+}
+
+func (dnsServer *DNSServer) soa() dnsmessage.Resource {
+	zone := dnsmessage.MustNewName(dnsServer.zone + ".")
+	return dnsmessage.Resource{
+		Header: dnsServer.resourceHeader(zone, dnsmessage.TypeSOA),
+		Body: &dnsmessage.SOAResource{
+			NS:      dnsmessage.MustNewName("ns." + dnsServer.zone + "."),
+			MBox:    dnsmessage.MustNewName("hostmaster." + dnsServer.zone + "."),
+			Serial:  uint32(time.Now().Unix()),
+			Refresh: 3600,
+			Retry:   600,
+			Expire:  86400,
+			MinTTL:  dnsServer.ttl,
+		},
+	}
+}
+
+func (dnsServer *DNSServer) resourceHeader(name dnsmessage.Name, recordType dnsmessage.Type) dnsmessage.ResourceHeader {
+	return dnsmessage.ResourceHeader{
+		Name:  name,
+		Type:  recordType,
+		Class: dnsmessage.ClassINET,
+		TTL:   dnsServer.ttl,
+	}
+}
+
+func (dnsServer *DNSServer) build(header dnsmessage.Header, question *dnsmessage.Question, answers, authorities []dnsmessage.Resource) []byte {
+	message := dnsmessage.Message{
+		Header:      header,
+		Answers:     answers,
+		Authorities: authorities,
+	}
+	if question != nil {
+		message.Questions = []dnsmessage.Question{*question}
+	}
+	response, err := message.Pack()
+	if err != nil {
+		dnsServer.logger.Error("Failed to pack DNS response", zap.Error(err))
+		return nil
+	}
+	return response
+}
+
+// truncate drops everything after the question section and sets the TC bit,
+// telling the client to retry over TCP.
+func truncate(response []byte) []byte {
+	var message dnsmessage.Message
+	if err := message.Unpack(response); err != nil {
+		return nil
+	}
+	message.Header.Truncated = true
+	message.Answers, message.Authorities, message.Additionals = nil, nil, nil
+	truncated, err := message.Pack()
+	if err != nil {
+		return nil
+	}
+	return truncated
+}
+
+// parseReverseName turns "4.3.2.1.in-addr.arpa" into 1.2.3.4.
+func parseReverseName(name string) (netip.Addr, bool) {
+	labels := strings.Split(strings.TrimSuffix(name, reverseZoneSuffix), ".")
+	if len(labels) != 4 {
+		return netip.Addr{}, false
+	}
+	addr, err := netip.ParseAddr(labels[3] + "." + labels[2] + "." + labels[1] + "." + labels[0])
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
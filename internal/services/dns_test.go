@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+	"golang.org/x/net/dns/dnsmessage"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// fakeServerDB answers the two server lookups the DNS server makes. addresses maps
+// every bound address, private or public, to the server holding it.
+type fakeServerDB struct {
+	servers   []sqlc.Server
+	addresses map[string]string // address -> hostname
+}
+
+func (db fakeServerDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, errors.New("fakeServerDB: unexpected Exec")
+}
+
+func (db fakeServerDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("fakeServerDB: unexpected Query")
+}
+
+func (db fakeServerDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	hostname := args[0].(string)
+	if strings.Contains(sql, "name: GetLiveServerByAddress") {
+		hostname = db.addresses[hostname]
+	}
+	for _, server := range db.servers {
+		if server.Hostname == hostname {
+			return serverRow{server: server}
+		}
+	}
+	return serverRow{err: pgx.ErrNoRows}
+}
+
+// serverRow scans a sqlc.Server column by column, in the field order sqlc selects.
+type serverRow struct {
+	server sqlc.Server
+	err    error
+}
+
+func (row serverRow) Scan(dest ...any) error {
+	if row.err != nil {
+		return row.err
+	}
+	value := reflect.ValueOf(row.server)
+	if len(dest) != value.NumField() {
+		return errors.New("serverRow: column count mismatch")
+	}
+	for i := range dest {
+		reflect.ValueOf(dest[i]).Elem().Set(value.Field(i))
+	}
+	return nil
+}
+
+func testDNSServer() *DNSServer {
+	db := fakeServerDB{
+		servers: []sqlc.Server{
+			{Name: "web", Hostname: "web.vs.test", Address: "10.0.0.5", Status: "running"},
+			{Name: "odd", Hostname: "odd.vs.test", Address: "not-an-ip", Status: "running"},
+		},
+		addresses: map[string]string{
+			"10.0.0.5":    "web.vs.test",
+			"203.0.113.7": "web.vs.test",
+		},
+	}
+	return &DNSServer{queries: sqlc.New(db), logger: zap.NewNop(), zone: "vs.test", ttl: 60}
+}
+
+func packQuery(t *testing.T, header dnsmessage.Header, name string, recordType dnsmessage.Type) []byte {
+	t.Helper()
+	message := dnsmessage.Message{Header: header}
+	if name != "" {
+		message.Questions = []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: recordType, Class: dnsmessage.ClassINET}}
+	}
+	query, err := message.Pack()
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	return query
+}
+
+func TestParseReverseName(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{name: "address", input: "5.0.0.10.in-addr.arpa", want: "10.0.0.5", wantOK: true},
+		{name: "octets reversed", input: "4.3.2.1.in-addr.arpa", want: "1.2.3.4", wantOK: true},
+		{name: "too few labels", input: "0.10.in-addr.arpa"},
+		{name: "too many labels", input: "1.5.0.0.10.in-addr.arpa"},
+		{name: "octet out of range", input: "256.0.0.10.in-addr.arpa"},
+		{name: "not a number", input: "a.0.0.10.in-addr.arpa"},
+		{name: "zone only", input: "in-addr.arpa"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseReverseName(tt.input)
+			if ok != tt.wantOK || (ok && got.String() != tt.want) {
+				t.Errorf("parseReverseName(%q) = %v, %v; want %s, %v", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDNSServerHandle(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        dnsmessage.Header
+		question      string
+		qtype         dnsmessage.Type
+		wantRCode     dnsmessage.RCode
+		wantAuth      bool
+		wantAnswer    string // A address, PTR target or SOA primary name server
+		wantAnswers   int
+		wantAuthority bool
+	}{
+		{name: "A record", question: "web.vs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAnswer: "10.0.0.5", wantAnswers: 1},
+		{name: "A record is case insensitive", question: "WEB.vs.Test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAnswer: "10.0.0.5", wantAnswers: 1},
+		{name: "AAAA for an existing name is empty", question: "web.vs.test.", qtype: dnsmessage.TypeAAAA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAuthority: true},
+		{name: "A with a stored address that is not IPv4", question: "odd.vs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAuthority: true},
+		{name: "unknown hostname", question: "missing.vs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeNameError, wantAuth: true, wantAuthority: true},
+		{name: "SOA at the apex", question: "vs.test.", qtype: dnsmessage.TypeSOA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAnswer: "ns.vs.test.", wantAnswers: 1},
+		{name: "A at the apex is empty", question: "vs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAuthority: true},
+		{name: "PTR for a private address", question: "5.0.0.10.in-addr.arpa.", qtype: dnsmessage.TypePTR, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAnswer: "web.vs.test.", wantAnswers: 1},
+		{name: "PTR for a public address", question: "7.113.0.203.in-addr.arpa.", qtype: dnsmessage.TypePTR, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true, wantAnswer: "web.vs.test.", wantAnswers: 1},
+		{name: "A for a reverse name is empty", question: "5.0.0.10.in-addr.arpa.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeSuccess, wantAuth: true},
+		{name: "PTR for an unbound address", question: "9.0.0.10.in-addr.arpa.", qtype: dnsmessage.TypePTR, wantRCode: dnsmessage.RCodeNameError, wantAuth: true},
+		{name: "malformed reverse name", question: "0.10.in-addr.arpa.", qtype: dnsmessage.TypePTR, wantRCode: dnsmessage.RCodeNameError, wantAuth: true},
+		{name: "outside the zone", question: "example.com.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeRefused},
+		{name: "zone as a suffix of another name", question: "notvs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeRefused},
+		{name: "no question", wantRCode: dnsmessage.RCodeFormatError, wantAuth: true},
+		{name: "non-query opcode", header: dnsmessage.Header{OpCode: 5}, question: "web.vs.test.", qtype: dnsmessage.TypeA, wantRCode: dnsmessage.RCodeNotImplemented, wantAuth: true},
+	}
+	dnsServer := testDNSServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.header.ID = 0x1234
+			tt.header.RecursionDesired = true
+			response := dnsServer.handle(context.Background(), packQuery(t, tt.header, tt.question, tt.qtype))
+
+			var message dnsmessage.Message
+			if err := message.Unpack(response); err != nil {
+				t.Fatalf("Unpack() error = %v", err)
+			}
+			if message.ID != 0x1234 || !message.Response || !message.RecursionDesired {
+				t.Errorf("header = %+v; want ID 0x1234, response, recursion desired", message.Header)
+			}
+			if message.RCode != tt.wantRCode || message.Authoritative != tt.wantAuth {
+				t.Errorf("rcode, authoritative = %v, %v; want %v, %v", message.RCode, message.Authoritative, tt.wantRCode, tt.wantAuth)
+			}
+			if len(message.Answers) != tt.wantAnswers {
+				t.Fatalf("answers = %d; want %d", len(message.Answers), tt.wantAnswers)
+			}
+			if got := len(message.Authorities) == 1; got != tt.wantAuthority {
+				t.Errorf("SOA authority = %v; want %v", got, tt.wantAuthority)
+			}
+			if tt.wantAnswers == 0 {
+				return
+			}
+			answer := message.Answers[0]
+			if answer.Header.TTL != 60 {
+				t.Errorf("TTL = %d; want 60", answer.Header.TTL)
+			}
+			var got string
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				got = netip.AddrFrom4(body.A).String()
+			case *dnsmessage.PTRResource:
+				got = body.PTR.String()
+			case *dnsmessage.SOAResource:
+				got = body.NS.String()
+				if body.MinTTL != 60 {
+					t.Errorf("SOA MinTTL = %d; want 60", body.MinTTL)
+				}
+			}
+			if got != tt.wantAnswer {
+				t.Errorf("answer = %q; want %q", got, tt.wantAnswer)
+			}
+		})
+	}
+}
+
+func TestDNSServerHandleIgnoresResponsesAndGarbage(t *testing.T) {
+	dnsServer := testDNSServer()
+	if got := dnsServer.handle(context.Background(), []byte{0x12}); got != nil {
+		t.Errorf("handle(garbage) = %x; want nil", got)
+	}
+	response := packQuery(t, dnsmessage.Header{Response: true}, "web.vs.test.", dnsmessage.TypeA)
+	if got := dnsServer.handle(context.Background(), response); got != nil {
+		t.Errorf("handle(response) = %x; want nil", got)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	name := dnsmessage.MustNewName("web.vs.test.")
+	message := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 7, Response: true, Authoritative: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET}},
+	}
+	for i := 0; i < 64; i++ {
+		message.Answers = append(message.Answers, dnsmessage.Resource{
+			Header: dnsmessage.ResourceHeader{Name: name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET, TTL: 60},
+			Body:   &dnsmessage.AResource{A: [4]byte{10, 0, 0, byte(i)}},
+		})
+	}
+	response, err := message.Pack()
+	if err != nil {
+		t.Fatalf("Pack() error = %v", err)
+	}
+	if len(response) <= maxUDPMessageSize {
+		t.Fatalf("test response is %d bytes; want more than %d", len(response), maxUDPMessageSize)
+	}
+
+	truncated := truncate(response)
+	if len(truncated) > maxUDPMessageSize {
+		t.Errorf("truncate() = %d bytes; want at most %d", len(truncated), maxUDPMessageSize)
+	}
+	var got dnsmessage.Message
+	if err := got.Unpack(truncated); err != nil {
+		t.Fatalf("Unpack() error = %v", err)
+	}
+	if !got.Truncated || got.ID != 7 || len(got.Questions) != 1 || len(got.Answers) != 0 {
+		t.Errorf("truncate() = TC %v, ID %d, %d questions, %d answers; want TC true, ID 7, 1 question, 0 answers",
+			got.Truncated, got.ID, len(got.Questions), len(got.Answers))
+	}
+}
+
+func TestDNSServerServeTCPConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	go testDNSServer().serveTCPConn(context.Background(), server)
+
+	// Two queries on one connection, each framed with a two byte length.
+	for _, id := range []uint16{1, 2} {
+		query := packQuery(t, dnsmessage.Header{ID: id}, "web.vs.test.", dnsmessage.TypeA)
+		framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+		if _, err := client.Write(append(framed, query...)); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+
+		var length [2]byte
+		if _, err := io.ReadFull(client, length[:]); err != nil {
+			t.Fatalf("read length: %v", err)
+		}
+		response := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(client, response); err != nil {
+			t.Fatalf("read response: %v", err)
+		}
+		var message dnsmessage.Message
+		if err := message.Unpack(response); err != nil {
+			t.Fatalf("Unpack() error = %v", err)
+		}
+		if message.ID != id || len(message.Answers) != 1 {
+			t.Errorf("response = ID %d, %d answers; want ID %d, 1 answer", message.ID, len(message.Answers), id)
+		}
+	}
+
+	// A response message instead of a query closes the connection.
+	query := packQuery(t, dnsmessage.Header{Response: true}, "web.vs.test.", dnsmessage.TypeA)
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := client.Write(append(framed, query...)); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if _, err := client.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read() after a response message error = %v; want EOF", err)
+	}
+}
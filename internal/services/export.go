@@ -0,0 +1,361 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// ExportFormatCSV writes the cost export as CSV with a header row.
+	ExportFormatCSV = "csv"
+	// ExportFormatNDJSON writes the cost export as one JSON object per line.
+	ExportFormatNDJSON = "ndjson"
+	// MaxExportRange is the longest date range a single export covers.
+	MaxExportRange = 366 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidExportFormat is returned for an export format other than csv or ndjson.
+	ErrInvalidExportFormat = errors.New("format must be csv or ndjson")
+	// ErrInvalidExportRange is returned when the export range is empty or too long.
+	ErrInvalidExportRange = errors.New("from must be before to, and at most 366 days before it")
+)
+
+// CostExportRow is the usage and cost of one charge of a server over one hour,
+// or the part of it the usage covers, named after the FOCUS columns. Columns
+// FOCUS does not define are prefixed with x_.
+type CostExportRow struct {
+	BillingPeriodStart time.Time       `json:"BillingPeriodStart"`
+	BillingPeriodEnd   time.Time       `json:"BillingPeriodEnd"`
+	ChargePeriodStart  time.Time       `json:"ChargePeriodStart"`
+	ChargePeriodEnd    time.Time       `json:"ChargePeriodEnd"`
+	ChargeCategory     string          `json:"ChargeCategory"`
+	ChargeDescription  string          `json:"ChargeDescription"`
+	BillingCurrency    string          `json:"BillingCurrency"`
+	SubAccountId       string          `json:"SubAccountId"`
+	RegionId           string          `json:"RegionId"`
+	ResourceId         string          `json:"ResourceId"`
+	ResourceName       string          `json:"ResourceName"`
+	ResourceType       string          `json:"ResourceType"`
+	ServiceCategory    string          `json:"ServiceCategory"`
+	PricingCategory    string          `json:"PricingCategory"`
+	ConsumedQuantity   float64         `json:"ConsumedQuantity"`
+	ConsumedUnit       string          `json:"ConsumedUnit"`
+	ListUnitPrice      float64         `json:"ListUnitPrice"`
+	ListCost           float64         `json:"ListCost"`
+	EffectiveCost      float64         `json:"EffectiveCost"`
+	Tags               json.RawMessage `json:"Tags"`
+	ChargeType         string          `json:"x_ChargeType"`
+}
+
+var costExportColumns = []string{
+	"BillingPeriodStart", "BillingPeriodEnd", "ChargePeriodStart", "ChargePeriodEnd",
+	"ChargeCategory", "ChargeDescription", "BillingCurrency", "SubAccountId", "RegionId",
+	"ResourceId", "ResourceName", "ResourceType", "ServiceCategory", "PricingCategory",
+	"ConsumedQuantity", "ConsumedUnit", "ListUnitPrice", "ListCost", "EffectiveCost",
+	"Tags", "x_ChargeType",
+}
+
+func (r CostExportRow) record() []string {
+	formatTime := func(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+	formatFloat := func(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+	return []string{
+		formatTime(r.BillingPeriodStart), formatTime(r.BillingPeriodEnd),
+		formatTime(r.ChargePeriodStart), formatTime(r.ChargePeriodEnd),
+		r.ChargeCategory, r.ChargeDescription, r.BillingCurrency, r.SubAccountId, r.RegionId,
+		r.ResourceId, r.ResourceName, r.ResourceType, r.ServiceCategory, r.PricingCategory,
+		formatFloat(r.ConsumedQuantity), r.ConsumedUnit,
+		formatFloat(r.ListUnitPrice), formatFloat(r.ListCost), formatFloat(r.EffectiveCost),
+		string(r.Tags), r.ChargeType,
+	}
+}
+
+// CostExportWriter writes cost export rows in one of the export formats.
+type CostExportWriter struct {
+	sent   *countingWriter
+	out    *bufio.Writer
+	csv    *csv.Writer
+	json   *json.Encoder
+	format string
+	rows   int
+}
+
+// NewCostExportWriter creates a writer of the given format. Nothing is written
+// to w before the first row or Flush.
+func NewCostExportWriter(w io.Writer, format string) (*CostExportWriter, error) {
+	sent := &countingWriter{w: w}
+	out := bufio.NewWriter(sent)
+	switch format {
+	case ExportFormatCSV:
+		return &CostExportWriter{sent: sent, out: out, csv: csv.NewWriter(out), format: format}, nil
+	case ExportFormatNDJSON:
+		return &CostExportWriter{sent: sent, out: out, json: json.NewEncoder(out), format: format}, nil
+	default:
+		return nil, ErrInvalidExportFormat
+	}
+}
+
+// ContentType returns the media type of the format.
+func (e *CostExportWriter) ContentType() string {
+	if e.format == ExportFormatCSV {
+		return "text/csv"
+	}
+	return "application/x-ndjson"
+}
+
+// Rows returns the number of rows written so far.
+func (e *CostExportWriter) Rows() int {
+	return e.rows
+}
+
+// Sent reports whether any bytes have reached the underlying writer. Rows are
+// buffered, so an HTTP response can still be replaced by an error until then.
+func (e *CostExportWriter) Sent() bool {
+	return e.sent.n > 0
+}
+
+// Write appends a row, preceded by the header row on the first call to a CSV writer.
+func (e *CostExportWriter) Write(row CostExportRow) error {
+	if e.csv != nil {
+		if e.rows == 0 {
+			if err := e.csv.Write(costExportColumns); err != nil {
+				return err
+			}
+		}
+		if err := e.csv.Write(row.record()); err != nil {
+			return err
+		}
+	} else if err := e.json.Encode(row); err != nil {
+		return err
+	}
+	e.rows++
+	return nil
+}
+
+// Flush writes out buffered rows; a CSV export without rows still gets its header.
+func (e *CostExportWriter) Flush() error {
+	if e.csv != nil {
+		if e.rows == 0 {
+			if err := e.csv.Write(costExportColumns); err != nil {
+				return err
+			}
+		}
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return e.out.Flush()
+}
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// ExportCosts derives hourly usage and cost rows of every server, or those of one
+// project, over [from, to) from its metered segments and egress, and passes
+// them to emit. Running time is priced at the catalog rates in effect each
+// hour, and spot servers' effective cost at the spot price history; disks,
+// public IPs and egress at the configured prices. Rows are in USD. Reservation
+// draw-down and billing-model rounding are settled in the ledger and are not
+// part of the hourly rows, so reserved hours appear at list price.
+func (b *BillingService) ExportCosts(ctx context.Context, from, to time.Time, project string, emit func(CostExportRow) error) error {
+	if !from.Before(to) || to.Sub(from) > MaxExportRange {
+		return ErrInvalidExportRange
+	}
+	projectFilter := pgtype.Text{String: project, Valid: project != ""}
+	rangeStart := pgtype.Timestamptz{Time: from, Valid: true}
+	rangeEnd := pgtype.Timestamptz{Time: to, Valid: true}
+
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return err
+	}
+	spotCatalog, err := LoadSpotPriceCatalog(ctx, b.db.Queries, from)
+	if err != nil {
+		return err
+	}
+	segments, err := b.db.Queries.ListUsageSegmentsInRange(ctx, sqlc.ListUsageSegmentsInRangeParams{
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+		Project:    projectFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list usage segments: %+v", err)
+	}
+	resources, err := b.db.Queries.ListResourceSegmentsInRange(ctx, sqlc.ListResourceSegmentsInRangeParams{
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+		Project:    projectFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list resource segments: %+v", err)
+	}
+	egress, err := b.db.Queries.ListEgressInRange(ctx, sqlc.ListEgressInRangeParams{
+		RangeStart: rangeStart,
+		RangeEnd:   rangeEnd,
+		Project:    projectFilter,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list egress: %+v", err)
+	}
+
+	// Open segments are exported up to now
+	now := time.Now()
+	to = minTime(to, now)
+
+	for _, segment := range segments {
+		end := to
+		if segment.EndedAt.Valid {
+			end = minTime(end, segment.EndedAt.Time)
+		}
+		pricingCategory := "Standard"
+		switch {
+		case segment.PurchaseOption == PurchaseOptionSpot:
+			pricingCategory = "Dynamic"
+		case segment.BillingModel == BillingModelReserved:
+			pricingCategory = "Committed"
+		}
+
+		err := exportHours(maxTime(segment.StartedAt.Time, from), end, func(start, end time.Time) error {
+			listCost, err := catalog.Cost(segment.ServerType, segment.Region, start, end)
+			if err != nil {
+				return fmt.Errorf("failed to price usage segment %s: %w", segment.ID.String(), err)
+			}
+			effectiveCost := listCost
+			if segment.PurchaseOption == PurchaseOptionSpot {
+				effectiveCost, err = spotCatalog.Cost(segment.ServerType, segment.Region, start, end)
+				if err != nil {
+					return fmt.Errorf("failed to price usage segment %s: %w", segment.ID.String(), err)
+				}
+			}
+
+			hours := end.Sub(start).Hours()
+			row := newCostExportRow(start, end, segment.ServerID, segment.ServerName, segment.Project, segment.Region, segment.ServerType, segment.Tags)
+			row.ChargeType = ChargeTypeCompute
+			row.ChargeDescription = fmt.Sprintf("%s running in %s (%s)", segment.ServerType, segment.Region, segment.ServerName)
+			row.ServiceCategory = "Compute"
+			row.PricingCategory = pricingCategory
+			row.ConsumedQuantity = hours
+			row.ConsumedUnit = "hour"
+			row.ListUnitPrice = listCost / hours
+			row.ListCost = listCost
+			row.EffectiveCost = effectiveCost
+			return emit(row)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, segment := range resources {
+		end := to
+		if segment.EndedAt.Valid {
+			end = minTime(end, segment.EndedAt.Time)
+		}
+		chargeType, unit, price := resourceCharge(b.config, segment.Resource)
+		description := "public IP of " + segment.ServerName
+		serviceCategory := "Networking"
+		if segment.Resource == ResourceDisk {
+			description = fmt.Sprintf("%.0f GB disk of %s", segment.Quantity, segment.ServerName)
+			serviceCategory = "Storage"
+		}
+
+		err := exportHours(maxTime(segment.StartedAt.Time, from), end, func(start, end time.Time) error {
+			quantity := end.Sub(start).Hours() * segment.Quantity
+			row := newCostExportRow(start, end, segment.ServerID, segment.ServerName, segment.Project, segment.Region, segment.ServerType, segment.Tags)
+			row.ChargeType = chargeType
+			row.ChargeDescription = description
+			row.ServiceCategory = serviceCategory
+			row.PricingCategory = "Standard"
+			row.ConsumedQuantity = quantity
+			row.ConsumedUnit = unit
+			row.ListUnitPrice = price
+			row.ListCost = quantity * price
+			row.EffectiveCost = quantity * price
+			return emit(row)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	price := b.config.EgressGBPrice
+	for _, usage := range egress {
+		start := usage.Hour.Time
+		row := newCostExportRow(start, start.Add(time.Hour), usage.ServerID, usage.ServerName, usage.Project, usage.Region, usage.ServerType, usage.Tags)
+		row.ChargeType = ChargeTypeEgress
+		row.ChargeDescription = "egress of " + usage.ServerName
+		row.ServiceCategory = "Networking"
+		row.PricingCategory = "Standard"
+		row.ConsumedQuantity = usage.Gb
+		row.ConsumedUnit = "GB"
+		row.ListUnitPrice = price
+		row.ListCost = usage.Gb * price
+		row.EffectiveCost = usage.Gb * price
+		if err := emit(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ParseExportTime parses a bound of an export range, a date (YYYY-MM-DD, midnight
+// UTC) or an RFC 3339 timestamp.
+func ParseExportTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// exportHours calls fn for each clock hour [from, to) touches, clipped to it.
+func exportHours(from, to time.Time, fn func(start, end time.Time) error) error {
+	for start := from; start.Before(to); {
+		end := minTime(start.Truncate(time.Hour).Add(time.Hour), to)
+		if err := fn(start, end); err != nil {
+			return err
+		}
+		start = end
+	}
+	return nil
+}
+
+func newCostExportRow(start, end time.Time, serverID pgtype.UUID, serverName, project, region, serverType string, tags []byte) CostExportRow {
+	if len(tags) == 0 {
+		tags = []byte("{}")
+	}
+	return CostExportRow{
+		BillingPeriodStart: PeriodStart(start),
+		BillingPeriodEnd:   PeriodEnd(start),
+		ChargePeriodStart:  start.UTC(),
+		ChargePeriodEnd:    end.UTC(),
+		ChargeCategory:     "Usage",
+		BillingCurrency:    CurrencyUSD,
+		SubAccountId:       project,
+		RegionId:           region,
+		ResourceId:         serverID.String(),
+		ResourceName:       serverName,
+		ResourceType:       serverType,
+		Tags:               tags,
+	}
+}
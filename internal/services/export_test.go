@@ -0,0 +1,196 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testExportRow(t *testing.T) CostExportRow {
+	row := newCostExportRow(mustTime(t, "2026-01-31T23:30:00Z"), mustTime(t, "2026-02-01T00:00:00Z"), pgtype.UUID{Bytes: [16]byte{1}, Valid: true}, "web", "acme", "us-east-1", "t2.micro", nil)
+	row.ChargeType = ChargeTypeCompute
+	row.ConsumedQuantity = 0.5
+	row.ConsumedUnit = "hour"
+	row.ListUnitPrice = 0.0116
+	row.ListCost = 0.0058
+	row.EffectiveCost = 0.0058
+	return row
+}
+
+func TestNewCostExportRow(t *testing.T) {
+	row := testExportRow(t)
+	if !row.BillingPeriodStart.Equal(mustTime(t, "2026-01-01T00:00:00Z")) || !row.BillingPeriodEnd.Equal(mustTime(t, "2026-02-01T00:00:00Z")) {
+		t.Errorf("billing period = %s to %s, want January", row.BillingPeriodStart, row.BillingPeriodEnd)
+	}
+	if string(row.Tags) != "{}" || row.BillingCurrency != CurrencyUSD || row.ChargeCategory != "Usage" || row.SubAccountId != "acme" {
+		t.Errorf("row = %+v, want empty tags, USD usage of acme", row)
+	}
+}
+
+func TestCostExportWriterCSV(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewCostExportWriter(&out, ExportFormatCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.ContentType() != "text/csv" {
+		t.Errorf("ContentType = %s, want text/csv", writer.ContentType())
+	}
+	for range 2 {
+		if err := writer.Write(testExportRow(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() != 0 {
+		t.Error("rows were written before Flush")
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || writer.Rows() != 2 {
+		t.Fatalf("got %d lines and %d rows, want a header and 2 rows:\n%s", len(lines), writer.Rows(), out.String())
+	}
+	if lines[0] != strings.Join(costExportColumns, ",") {
+		t.Errorf("header = %s", lines[0])
+	}
+	want := "2026-01-01T00:00:00Z,2026-02-01T00:00:00Z,2026-01-31T23:30:00Z,2026-02-01T00:00:00Z,Usage,,USD,acme,us-east-1"
+	if !strings.HasPrefix(lines[1], want) || !strings.HasSuffix(lines[1], ",0.5,hour,0.0116,0.0058,0.0058,{},compute") {
+		t.Errorf("row = %s", lines[1])
+	}
+}
+
+func TestCostExportWriterCSVWithoutRows(t *testing.T) {
+	var out bytes.Buffer
+	writer, _ := NewCostExportWriter(&out, ExportFormatCSV)
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(out.String()); got != strings.Join(costExportColumns, ",") {
+		t.Errorf("empty export = %q, want the header", got)
+	}
+}
+
+func TestCostExportWriterNDJSON(t *testing.T) {
+	var out bytes.Buffer
+	writer, err := NewCostExportWriter(&out, ExportFormatNDJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.ContentType() != "application/x-ndjson" {
+		t.Errorf("ContentType = %s, want application/x-ndjson", writer.ContentType())
+	}
+	if err := writer.Flush(); err != nil || out.Len() != 0 {
+		t.Errorf("empty export = %q, %v; want nothing", out.String(), err)
+	}
+	for range 2 {
+		if err := writer.Write(testExportRow(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2:\n%s", len(lines), out.String())
+	}
+	var row map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["x_ChargeType"] != ChargeTypeCompute || row["ConsumedQuantity"] != 0.5 || row["ResourceName"] != "web" {
+		t.Errorf("row = %v", row)
+	}
+}
+
+func TestCostExportWriterSent(t *testing.T) {
+	var out bytes.Buffer
+	writer, _ := NewCostExportWriter(&out, ExportFormatNDJSON)
+	if err := writer.Write(testExportRow(t)); err != nil {
+		t.Fatal(err)
+	}
+	if writer.Sent() || out.Len() != 0 {
+		t.Errorf("Sent = %v with %d bytes out after one row, want a buffered row", writer.Sent(), out.Len())
+	}
+
+	// Rows beyond the buffer reach the underlying writer before Flush
+	for !writer.Sent() {
+		if writer.Rows() > 1000 {
+			t.Fatal("rows were never sent before Flush")
+		}
+		if err := writer.Write(testExportRow(t)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out.Len() == 0 {
+		t.Error("Sent with nothing written")
+	}
+}
+
+func TestNewCostExportWriterRejectsFormat(t *testing.T) {
+	if _, err := NewCostExportWriter(&bytes.Buffer{}, "xlsx"); !errors.Is(err, ErrInvalidExportFormat) {
+		t.Errorf("NewCostExportWriter(xlsx) = %v, want ErrInvalidExportFormat", err)
+	}
+}
+
+func TestExportCostsRejectsRange(t *testing.T) {
+	var b BillingService
+	from := mustTime(t, "2026-01-01T00:00:00Z")
+	for _, to := range []time.Time{from, from.Add(-time.Hour), from.Add(MaxExportRange + time.Hour)} {
+		if err := b.ExportCosts(context.Background(), from, to, "", nil); !errors.Is(err, ErrInvalidExportRange) {
+			t.Errorf("ExportCosts to %s = %v, want ErrInvalidExportRange", to, err)
+		}
+	}
+}
+
+func TestParseExportTime(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    string
+		wantErr bool
+	}{
+		{value: "2026-03-01", want: "2026-03-01T00:00:00Z"},
+		{value: "2026-03-01T12:30:00Z", want: "2026-03-01T12:30:00Z"},
+		{value: "2026-03-01T12:30:00+02:00", want: "2026-03-01T10:30:00Z"},
+		{value: "March", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseExportTime(tt.value)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && !got.Equal(mustTime(t, tt.want))) {
+			t.Errorf("ParseExportTime(%q) = %s, %v; want %s", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestExportHours(t *testing.T) {
+	var got []string
+	err := exportHours(mustTime(t, "2026-01-01T10:15:00Z"), mustTime(t, "2026-01-01T12:30:00Z"), func(start, end time.Time) error {
+		got = append(got, start.Format("15:04")+"-"+end.Format("15:04"))
+		return nil
+	})
+	want := []string{"10:15-11:00", "11:00-12:00", "12:00-12:30"}
+	if err != nil || strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("exportHours = %v, %v; want %v", got, err, want)
+	}
+
+	// An empty range calls nothing, and an error stops the walk
+	calls := 0
+	stop := errors.New("stop")
+	from := mustTime(t, "2026-01-01T10:00:00Z")
+	if err := exportHours(from, from, func(time.Time, time.Time) error { calls++; return nil }); err != nil || calls != 0 {
+		t.Errorf("exportHours of an empty range made %d calls, %v", calls, err)
+	}
+	if err := exportHours(from, from.Add(3*time.Hour), func(time.Time, time.Time) error { calls++; return stop }); !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("exportHours = %v after %d calls, want stop after 1", err, calls)
+	}
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// Forecast is the spend of the current billing period: what has been metered so
+// far and what is projected until the period ends.
+type Forecast struct {
+	PeriodStart   time.Time
+	PeriodEnd     time.Time
+	ActualCost    float64
+	ProjectedCost float64
+}
+
+// ServerForecast is the forecast of one server. Utilization is the share of the
+// remaining period the server is expected to run.
+type ServerForecast struct {
+	Forecast
+	ServerID    pgtype.UUID
+	Project     string
+	Utilization float64
+}
+
+// ProjectForecast is the forecast of all servers of a project.
+type ProjectForecast struct {
+	Forecast
+	Project string
+	Servers int
+}
+
+// ForecastServer projects a server's spend to the end of the current billing period.
+// The spend so far is priced as it is invoiced. A running server is expected to
+// keep running; any other live server to run as much as it did over the last
+// FORECAST_LOOKBACK. Disk and public IPs the server holds are expected to be kept.
+func (b *BillingService) ForecastServer(ctx context.Context, server sqlc.Server, now time.Time) (ServerForecast, error) {
+	forecasts, err := b.forecastServers(ctx, []sqlc.Server{server}, now)
+	if err != nil {
+		return ServerForecast{}, err
+	}
+	return forecasts[server.ID.String()], nil
+}
+
+// ForecastProjects projects the spend of every project, or only of the given one,
+// to the end of the current billing period; see ForecastServer.
+func (b *BillingService) ForecastProjects(ctx context.Context, project string, now time.Time) ([]ProjectForecast, error) {
+	servers, err := b.db.Queries.ListServersInScope(ctx, sqlc.ListServersInScopeParams{
+		Since:   pgtype.Timestamptz{Time: PeriodStart(now), Valid: true},
+		Project: pgtype.Text{String: project, Valid: project != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list servers: %+v", err)
+	}
+	forecasts, err := b.forecastServers(ctx, servers, now)
+	if err != nil {
+		return nil, err
+	}
+
+	byProject := make(map[string]*ProjectForecast)
+	for _, forecast := range forecasts {
+		projectForecast, ok := byProject[forecast.Project]
+		if !ok {
+			projectForecast = &ProjectForecast{
+				Forecast: Forecast{PeriodStart: forecast.PeriodStart, PeriodEnd: forecast.PeriodEnd},
+				Project:  forecast.Project,
+			}
+			byProject[forecast.Project] = projectForecast
+		}
+		projectForecast.Servers++
+		projectForecast.ActualCost += forecast.ActualCost
+		projectForecast.ProjectedCost += forecast.ProjectedCost
+	}
+
+	result := make([]ProjectForecast, 0, len(byProject))
+	for _, projectForecast := range byProject {
+		result = append(result, *projectForecast)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Project < result[j].Project })
+	return result, nil
+}
+
+// forecastServers forecasts the given servers, keyed by server ID.
+func (b *BillingService) forecastServers(ctx context.Context, servers []sqlc.Server, now time.Time) (map[string]ServerForecast, error) {
+	periodStart := PeriodStart(now)
+	serverIDs := make([]pgtype.UUID, 0, len(servers))
+	for _, server := range servers {
+		serverIDs = append(serverIDs, server.ID)
+	}
+
+	metered, err := loadMeteredUsage(ctx, b.db.Queries, b.config, serverIDs, periodStart, now)
+	if err != nil {
+		return nil, err
+	}
+	billed := make(map[string]ServerUsage)
+	if err := addBilledUsage(ctx, b.db.Queries, billed, serverIDs, periodStart); err != nil {
+		return nil, err
+	}
+	return forecastUsage(metered, billed, servers, now.Add(-b.config.ForecastLookback), now)
+}
+
+// forecastUsage adds the usage of the current period billed so far to the metered
+// usage priced to now, and projects the servers to the period end: open segments
+// are priced as if they stayed open, and live servers that are not running are
+// expected to run as much as they did since lookbackStart. Egress is expected at
+// EGRESS_GB_PER_HOUR for the time a server runs.
+func forecastUsage(metered meteredUsage, billed map[string]ServerUsage, servers []sqlc.Server, lookbackStart, now time.Time) (map[string]ServerForecast, error) {
+	periodStart, periodEnd := PeriodStart(now), PeriodEnd(now)
+	actual, err := metered.price(periodStart, now)
+	if err != nil {
+		return nil, err
+	}
+	atPeriodEnd, err := metered.price(periodStart, periodEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	runningSeconds := make(map[string]float64)
+	for _, segment := range metered.segments {
+		end := now
+		if segment.EndedAt.Valid {
+			end = segment.EndedAt.Time
+		}
+		if from := maxTime(segment.StartedAt.Time, lookbackStart); from.Before(end) {
+			runningSeconds[segment.ServerID.String()] += end.Sub(from).Seconds()
+		}
+	}
+
+	forecasts := make(map[string]ServerForecast, len(servers))
+	for _, server := range servers {
+		serverID := server.ID.String()
+		forecast := ServerForecast{
+			Forecast: Forecast{
+				PeriodStart:   periodStart,
+				PeriodEnd:     periodEnd,
+				ActualCost:    billed[serverID].Cost + actual[serverID].Cost,
+				ProjectedCost: atPeriodEnd[serverID].Cost - actual[serverID].Cost,
+			},
+			ServerID: server.ID,
+			Project:  server.Project,
+		}
+
+		switch server.Status {
+		case util.ServerStatusRunning:
+			forecast.Utilization = 1
+		case util.ServerStatusTerminated:
+		default:
+			if window := now.Sub(maxTime(lookbackStart, server.CreatedAt.Time)).Seconds(); window > 0 {
+				forecast.Utilization = min(runningSeconds[serverID]/window, 1)
+			}
+			if forecast.Utilization > 0 {
+				catalog := metered.onDemand
+				if server.PurchaseOption == PurchaseOptionSpot {
+					catalog = metered.spot
+				}
+				cost, err := catalog.Cost(server.Type, server.Region, now, periodEnd)
+				if err != nil {
+					return nil, fmt.Errorf("failed to price server %s: %w", serverID, err)
+				}
+				forecast.ProjectedCost += cost * forecast.Utilization
+			}
+		}
+		egressGB := metered.config.EgressGBPerHour * periodEnd.Sub(now).Hours() * forecast.Utilization
+		forecast.ProjectedCost += egressGB * metered.config.EgressGBPrice
+		forecasts[serverID] = forecast
+	}
+	return forecasts, nil
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
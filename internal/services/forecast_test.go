@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+func testUUID(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func closeTo(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestForecastUsage(t *testing.T) {
+	at := func(value string) time.Time { return mustTime(t, value) }
+	now := at("2026-03-11T00:00:00Z") // 504 hours left in the period
+	lookbackStart := now.AddDate(0, 0, -7)
+
+	server := func(id byte, status, purchaseOption, createdAt string) sqlc.Server {
+		return sqlc.Server{ID: testUUID(id), Project: "acme", Status: status, Type: "t2.micro", Region: "us-east-1", PurchaseOption: purchaseOption, CreatedAt: timestamptz(at(createdAt))}
+	}
+	segment := func(id byte, purchaseOption, startedAt, endedAt, billedUntil string) sqlc.ListUsageSegmentsByServerIDsRow {
+		row := sqlc.ListUsageSegmentsByServerIDsRow{
+			ServerID:       testUUID(id),
+			Project:        "acme",
+			ServerType:     "t2.micro",
+			Region:         "us-east-1",
+			BillingModel:   BillingModelPerSecond,
+			PurchaseOption: purchaseOption,
+			StartedAt:      timestamptz(at(startedAt)),
+			BilledUntil:    timestamptz(at(billedUntil)),
+		}
+		if endedAt != "" {
+			row.EndedAt = timestamptz(at(endedAt))
+		}
+		return row
+	}
+	servers := []sqlc.Server{
+		server(1, util.ServerStatusRunning, PurchaseOptionOnDemand, "2026-01-01T00:00:00Z"),
+		server(2, util.ServerStatusStopped, PurchaseOptionOnDemand, "2026-01-01T00:00:00Z"),
+		server(3, util.ServerStatusTerminated, PurchaseOptionOnDemand, "2026-01-01T00:00:00Z"),
+		server(4, util.ServerStatusStopped, PurchaseOptionOnDemand, "2026-03-10T00:00:00Z"),
+		server(5, util.ServerStatusStopped, PurchaseOptionSpot, "2026-03-10T12:00:00Z"),
+	}
+	metered := meteredUsage{
+		segments: []sqlc.ListUsageSegmentsByServerIDsRow{
+			segment(1, PurchaseOptionOnDemand, "2026-02-20T00:00:00Z", "", "2026-03-01T00:00:00Z"),
+			segment(2, PurchaseOptionOnDemand, "2026-03-05T00:00:00Z", "2026-03-06T18:00:00Z", "2026-03-06T18:00:00Z"),
+			segment(3, PurchaseOptionOnDemand, "2026-02-27T00:00:00Z", "2026-03-02T00:00:00Z", "2026-03-02T00:00:00Z"),
+			segment(5, PurchaseOptionSpot, "2026-03-10T12:00:00Z", "2026-03-10T18:00:00Z", "2026-03-10T12:00:00Z"),
+		},
+		resources: []sqlc.ResourceSegment{
+			{ServerID: testUUID(2), Resource: ResourceDisk, Quantity: 10, StartedAt: timestamptz(at("2026-01-01T00:00:00Z")), BilledUntil: timestamptz(at("2026-03-01T00:00:00Z"))},
+		},
+		onDemand: testCatalog(testPrice("t2.micro", PriceRegionAny, 0.1, at("2025-01-01T00:00:00Z"))),
+		spot:     testCatalog(testPrice("t2.micro", "us-east-1", 0.03, at("2026-03-01T00:00:00Z"))),
+		config:   &config.Config{DiskGBHourPrice: 0.001, EgressGBPerHour: 0.1, EgressGBPrice: 0.01},
+	}
+	// The ledger holds the closed segments billed in the period, and a late charge of server 1
+	billed := map[string]ServerUsage{
+		testUUID(1).String(): {Cost: 1.5},
+		testUUID(2).String(): {Cost: 42 * 0.1},
+		testUUID(3).String(): {Cost: 24 * 0.1},
+	}
+
+	forecasts, err := forecastUsage(metered, billed, servers, lookbackStart, now)
+	if err != nil {
+		t.Fatalf("forecastUsage failed: %v", err)
+	}
+	egress := 504 * 0.1 * 0.01
+	tests := []struct {
+		name        string
+		id          byte
+		actual      float64
+		utilization float64
+		projected   float64
+	}{
+		{name: "running server keeps running", id: 1, actual: 1.5 + 240*0.1, utilization: 1, projected: 504*0.1 + egress},
+		{name: "stopped server runs as it did over the lookback and keeps its disk", id: 2, actual: 42*0.1 + 240*10*0.001, utilization: 0.25, projected: 504*0.1*0.25 + 504*10*0.001 + egress*0.25},
+		{name: "terminated server only has actual cost", id: 3, actual: 24 * 0.1},
+		{name: "server that never ran", id: 4},
+		{name: "spot server at the spot price, lookback from its creation", id: 5, actual: 6 * 0.03, utilization: 0.5, projected: 504*0.03*0.5 + egress*0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forecast, ok := forecasts[testUUID(tt.id).String()]
+			if !ok {
+				t.Fatalf("no forecast of server %d", tt.id)
+			}
+			if !closeTo(forecast.ActualCost, tt.actual) || !closeTo(forecast.Utilization, tt.utilization) || !closeTo(forecast.ProjectedCost, tt.projected) {
+				t.Errorf("actual %v, utilization %v, projected %v; want %v, %v, %v",
+					forecast.ActualCost, forecast.Utilization, forecast.ProjectedCost, tt.actual, tt.utilization, tt.projected)
+			}
+			if !forecast.PeriodStart.Equal(at("2026-03-01T00:00:00Z")) || !forecast.PeriodEnd.Equal(at("2026-04-01T00:00:00Z")) {
+				t.Errorf("period [%v, %v), want March", forecast.PeriodStart, forecast.PeriodEnd)
+			}
+		})
+	}
+	if len(forecasts) != len(tests) {
+		t.Errorf("got %d forecasts, want %d", len(forecasts), len(tests))
+	}
+}
+
+func TestPriceQuote(t *testing.T) {
+	catalog := testCatalog(
+		testPrice("t2.micro", PriceRegionAny, 0.1, mustTime(t, "2025-01-01T00:00:00Z")),
+		testPrice("t2.micro", PriceRegionAny, 0.2, mustTime(t, "2026-03-01T00:00:00Z")),
+	)
+	now := mustTime(t, "2026-02-28T22:00:00Z")
+	tests := []struct {
+		name       string
+		model      string
+		hours      float64
+		wantSlices int
+		wantBilled float64
+		wantUnit   float64
+	}{
+		{name: "across a price change", model: BillingModelPerSecond, hours: 4, wantSlices: 2, wantBilled: 4, wantUnit: 2*0.1 + 2*0.2},
+		{name: "per-second minimum", model: BillingModelPerSecond, hours: 0.25 / 60, wantSlices: 1, wantBilled: 1.0 / 60, wantUnit: 1.0 / 60 * 0.1},
+		{name: "hourly rounds up at the closing rate", model: BillingModelHourly, hours: 2.5, wantSlices: 2, wantBilled: 3, wantUnit: 2*0.1 + 0.5*0.2 + 0.5*0.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quote, err := priceQuote(catalog, Quote{ServerType: "t2.micro", Region: "us-east-1", BillingModel: tt.model, Count: 3, Hours: tt.hours}, now)
+			if err != nil {
+				t.Fatalf("priceQuote failed: %v", err)
+			}
+			if len(quote.Slices) != tt.wantSlices || !closeTo(quote.BilledHours, tt.wantBilled) || !closeTo(quote.UnitCost, tt.wantUnit) || !closeTo(quote.Total, 3*tt.wantUnit) {
+				t.Errorf("%d slices, %v billed hours, unit cost %v, total %v; want %d, %v, %v, %v",
+					len(quote.Slices), quote.BilledHours, quote.UnitCost, quote.Total, tt.wantSlices, tt.wantBilled, tt.wantUnit, 3*tt.wantUnit)
+			}
+		})
+	}
+
+	if _, err := priceQuote(catalog, Quote{ServerType: "m5.large", Region: "us-east-1", BillingModel: BillingModelPerSecond, Count: 1, Hours: 1}, now); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("quote of an unpriced type = %v, want ErrNoPrice", err)
+	}
+}
+
+func TestQuotePriceRejects(t *testing.T) {
+	var b BillingService
+	now := time.Now()
+	if _, err := b.QuotePrice(context.Background(), "", "t2.micro", "us-east-1", 1, 1, "monthly", now); !errors.Is(err, ErrInvalidBillingModel) {
+		t.Errorf("quote with an unknown billing model = %v, want ErrInvalidBillingModel", err)
+	}
+	if _, err := b.QuotePrice(context.Background(), "", "t2.micro", "us-east-1", 0, 1, "", now); !errors.Is(err, ErrInvalidQuote) {
+		t.Errorf("quote of no servers = %v, want ErrInvalidQuote", err)
+	}
+	if _, err := b.QuotePrice(context.Background(), "", "t2.micro", "us-east-1", 1, 0, "", now); !errors.Is(err, ErrInvalidQuote) {
+		t.Errorf("quote of no time = %v, want ErrInvalidQuote", err)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// maxLabelLength is the longest single label DNS allows.
+const maxLabelLength = 63
+
+// ErrEmptyServerName is returned when renaming a server to an empty name.
+var ErrEmptyServerName = errors.New("server name is required")
+
+// HostnameLabel turns a free-form server name into a DNS-safe label:
+// lower case letters, digits and single hyphens, at most 63 characters.
+func HostnameLabel(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+			hyphen = false
+		case !hyphen && b.Len() > 0:
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+
+	label := b.String()
+	if len(label) > maxLabelLength {
+		label = label[:maxLabelLength]
+	}
+	label = strings.TrimRight(label, "-")
+	if label == "" {
+		return "server"
+	}
+	return label
+}
+
+// DNSZone returns the configured zone without surrounding dots, in lower case.
+func (s *ServerService) DNSZone() string {
+	return strings.ToLower(strings.Trim(s.config.DNSZone, "."))
+}
+
+// hostnameFor picks a fully qualified hostname for a server named name. If
+// another live server already holds the plain label, a short random suffix is added.
+// serverID is the server being renamed, or an invalid UUID for a new server.
+func (s *ServerService) hostnameFor(ctx context.Context, name string, serverID pgtype.UUID) (string, error) {
+	label := HostnameLabel(name)
+	hostname := label + "." + s.DNSZone()
+
+	inUse, err := s.queries.HostnameInUse(ctx, sqlc.HostnameInUseParams{
+		Hostname: hostname,
+		ID:       serverID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to check hostname: %+v", err)
+	}
+	if !inUse {
+		return hostname, nil
+	}
+
+	suffix := strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	if len(label) > maxLabelLength-len(suffix)-1 {
+		label = strings.TrimRight(label[:maxLabelLength-len(suffix)-1], "-")
+	}
+	return label + "-" + suffix + "." + s.DNSZone(), nil
+}
+
+// RenameServer changes the server's name and moves it to a matching hostname.
+func (s *ServerService) RenameServer(ctx context.Context, server sqlc.Server, name string) (sqlc.Server, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return sqlc.Server{}, ErrEmptyServerName
+	}
+	if server.Status == util.ServerStatusTerminated {
+		return sqlc.Server{}, ErrServerTerminated
+	}
+
+	hostname := server.Hostname
+	if HostnameLabel(name) != HostnameLabel(server.Name) {
+		var err error
+		hostname, err = s.hostnameFor(ctx, name, server.ID)
+		if err != nil {
+			return sqlc.Server{}, err
+		}
+	}
+
+	updatedServer, err := s.queries.UpdateServerName(ctx, sqlc.UpdateServerNameParams{
+		Name:     name,
+		Hostname: hostname,
+		ID:       server.ID,
+	})
+	if err != nil {
+		s.logger.Error("Failed to rename server", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return sqlc.Server{}, fmt.Errorf("failed to rename server: %+v", err)
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventRenamed,
+		Message: "Server renamed to " + updatedServer.Hostname,
+		Details: map[string]any{"name": updatedServer.Name, "hostname": updatedServer.Hostname},
+	})
+
+	s.logger.Info("Server renamed",
+		zap.String("server_id", server.ID.String()),
+		zap.String("name", updatedServer.Name),
+		zap.String("hostname", updatedServer.Hostname),
+	)
+	return updatedServer, nil
+}
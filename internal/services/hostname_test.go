@@ -0,0 +1,32 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostnameLabel(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "already a label", input: "web-1", want: "web-1"},
+		{name: "upper case", input: "Web-Server", want: "web-server"},
+		{name: "spaces and punctuation collapse", input: "my  web_server.v2!", want: "my-web-server-v2"},
+		{name: "leading symbols dropped", input: "--_web", want: "web"},
+		{name: "trailing symbols dropped", input: "web!!", want: "web"},
+		{name: "non-ascii letters are separators", input: "café münchen", want: "caf-m-nchen"},
+		{name: "empty", input: "", want: "server"},
+		{name: "only symbols", input: "!!--__", want: "server"},
+		{name: "truncated to 63", input: strings.Repeat("a", 70), want: strings.Repeat("a", 63)},
+		{name: "trailing hyphen after truncation trimmed", input: strings.Repeat("a", 62) + " b", want: strings.Repeat("a", 62)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HostnameLabel(tt.input); got != tt.want {
+				t.Errorf("HostnameLabel(%q) = %q; want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,23 +2,51 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/netip"
 	"os"
 	"strings"
 	"sync"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 
 	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/ipam"
 )
 
+// DefaultIPPoolName is the pool built from IP_ALLOCATION_CIDR.
+const DefaultIPPoolName = "default"
+
+var (
+	// ErrUnknownIPPool is returned when allocating from a pool that was never registered.
+	ErrUnknownIPPool = errors.New("unknown IP pool")
+	// ErrOverlappingIPPool is returned when registering a pool that shares addresses with another.
+	ErrOverlappingIPPool = errors.New("IP pool overlaps another pool")
+)
+
+// maxReserveAttempts bounds how often AllocateIPFromPool picks another address
+// after finding its pick already held, e.g. by another replica.
+const maxReserveAttempts = 5
+
 // IPAllocator manages the allocation and deallocation of IP addresses.
+// Each pool is tracked in memory as an ipam.Range; rows in ip_addresses are
+// only written for addresses that have actually been handed out. The database
+// stays the source of truth: a Range is rebuilt from it whenever it runs out or
+// proves stale, which is how releases made by other replicas are picked up.
 type IPAllocator struct {
 	queries *sqlc.Queries
 	logger  *zap.Logger
 	ipMutex sync.Mutex
+	pools   map[string]*ipPool
+}
+
+// ipPool pairs a persisted pool with its in-memory allocation bitmap.
+type ipPool struct {
+	pool  sqlc.IpPool
+	addrs *ipam.Range
 }
 
 // NewIPAllocator creates a new IPAllocator.
@@ -26,10 +54,13 @@ func NewIPAllocator(queries *sqlc.Queries, logger *zap.Logger) *IPAllocator {
 	return &IPAllocator{
 		queries: queries,
 		logger:  logger,
+		pools:   make(map[string]*ipPool),
 	}
 }
 
-func (ipa *IPAllocator) TerminateAllServers(ctx context.Context, cidr string, exclusionList []string) error {
+// TerminateAllServers resets the simulation on startup: every server is marked
+// terminated and all address bindings are dropped.
+func (ipa *IPAllocator) TerminateAllServers(ctx context.Context) error {
 
 	ipa.ipMutex.Lock()
 	defer ipa.ipMutex.Unlock()
@@ -44,80 +75,328 @@ func (ipa *IPAllocator) TerminateAllServers(ctx context.Context, cidr string, ex
 		ipa.logger.Error("Failed to terminate all servers", zap.Error(err))
 	}
 
-	err = ipa.queries.TruncateIPAddresses(ctx)
+	// Servers that were running until the reset are metered up to now
+	err = ipa.queries.CloseAllUsageSegments(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), " does not exist") {
+			ipa.logger.Error("SCHEMA Error:", zap.Error(err))
+			os.Exit(0)
+		}
+		ipa.logger.Error("Failed to close usage segments", zap.Error(err))
+	}
+	// So are the disks and public IPs of every server
+	err = ipa.queries.CloseAllResourceSegments(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), " does not exist") {
+			ipa.logger.Error("SCHEMA Error:", zap.Error(err))
+			os.Exit(0)
+		}
+		ipa.logger.Error("Failed to close resource segments", zap.Error(err))
+	}
+
+	// Addresses go back to their pools; the NAT mappings over them are closed but kept
+	err = ipa.queries.ReleaseAllNATMappings(ctx)
 	if err != nil {
 		if strings.Contains(err.Error(), " does not exist") {
 			ipa.logger.Error("SCHEMA Error:", zap.Error(err))
 			os.Exit(0)
 		}
-		ipa.logger.Error("Failed to truncate IP addresses", zap.Error(err))
+		ipa.logger.Error("Failed to release NAT mappings", zap.Error(err))
 	}
+	err = ipa.queries.ReleaseAllIPAddresses(ctx)
+	if err != nil {
+		if strings.Contains(err.Error(), " does not exist") {
+			ipa.logger.Error("SCHEMA Error:", zap.Error(err))
+			os.Exit(0)
+		}
+		ipa.logger.Error("Failed to release IP addresses", zap.Error(err))
+	}
+
+	// Drop the in-memory view of every pool; RegisterPool rebuilds them.
+	ipa.pools = make(map[string]*ipPool)
+	return nil
+}
+
+// RegisterPool creates or updates a named pool and loads its current allocations.
+// No per-address rows are written; addresses are materialised when allocated.
+func (ipa *IPAllocator) RegisterPool(ctx context.Context, name string, cidr string, exclusionList []string, strategyName string) error {
+	ipa.ipMutex.Lock()
+	defer ipa.ipMutex.Unlock()
 
-	prefix, err := netip.ParsePrefix(cidr)
+	strategy, err := ipam.NewStrategy(strategyName)
 	if err != nil {
-		return fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		return err
 	}
 
-	exclude := make(map[netip.Addr]bool, len(exclusionList))
+	// Invalid exclusions are skipped rather than failing the whole pool.
+	exclusions := make([]string, 0, len(exclusionList))
 	for _, ex := range exclusionList {
-		addr, err := netip.ParseAddr(ex)
-		if err != nil {
+		ex = strings.TrimSpace(ex)
+		if ex == "" {
+			continue
+		}
+		if _, err := netip.ParseAddr(ex); err != nil {
 			ipa.logger.Warn("Invalid exclusion address", zap.String("ip", ex), zap.Error(err))
 			continue
 		}
-		exclude[addr] = true
+		exclusions = append(exclusions, ex)
+	}
+
+	// Validated up front so a bad range is never saved; loadRange builds the live one.
+	valid, err := ipam.NewRange(cidr, exclusions, strategy)
+	if err != nil {
+		return err
+	}
+	if err := ipa.checkOverlap(ctx, name, valid.Prefix()); err != nil {
+		return err
+	}
+
+	pool, err := ipa.queries.UpsertIPPool(ctx, sqlc.UpsertIPPoolParams{
+		Name:              name,
+		Cidr:              valid.Prefix().String(),
+		Strategy:          strategy.Name(),
+		ExcludedAddresses: exclusions,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save IP pool %q: %w", name, err)
+	}
+
+	addrs, allocated, err := ipa.loadRange(ctx, pool)
+	if err != nil {
+		return err
 	}
-	var count int
-	for addr := prefix.Addr(); prefix.Contains(addr); addr = addr.Next() {
-		if exclude[addr] || addr == prefix.Addr() {
+
+	ipa.pools[name] = &ipPool{pool: pool, addrs: addrs}
+	ipa.logger.Info("IP pool registered",
+		zap.String("pool", name),
+		zap.String("cidr", pool.Cidr),
+		zap.String("strategy", pool.Strategy),
+		zap.Uint32("size", addrs.Size()),
+		zap.Int("allocated", allocated),
+	)
+	return nil
+}
+
+// checkOverlap returns ErrOverlappingIPPool if prefix shares addresses with
+// another registered pool, or with a pool that still holds allocated addresses
+// though it is no longer configured. An address must identify a single server.
+func (ipa *IPAllocator) checkOverlap(ctx context.Context, name string, prefix netip.Prefix) error {
+	inUse, err := ipa.queries.ListIPPoolsInUse(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list IP pools: %w", err)
+	}
+	cidrs := make(map[string]string, len(ipa.pools)+len(inUse))
+	for _, pool := range inUse {
+		cidrs[pool.Name] = pool.Cidr
+	}
+	for poolName, pool := range ipa.pools {
+		cidrs[poolName] = pool.pool.Cidr
+	}
+
+	for poolName, cidr := range cidrs {
+		if poolName == name {
+			continue
+		}
+		other, err := netip.ParsePrefix(cidr)
+		if err != nil {
 			continue
 		}
-		_, err := ipa.queries.CreateIPAddress(ctx, addr.String())
+		if other.Overlaps(prefix) {
+			return fmt.Errorf("%w: %s of pool %q overlaps %s of pool %q", ErrOverlappingIPPool, prefix, name, other, poolName)
+		}
+	}
+	return nil
+}
+
+// loadRange builds a pool's in-memory Range from the addresses the database
+// holds for it, and returns it with the number of addresses allocated.
+func (ipa *IPAllocator) loadRange(ctx context.Context, pool sqlc.IpPool) (*ipam.Range, int, error) {
+	strategy, err := ipam.NewStrategy(pool.Strategy)
+	if err != nil {
+		return nil, 0, err
+	}
+	addrs, err := ipam.NewRange(pool.Cidr, pool.ExcludedAddresses, strategy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Rows come back oldest update first, which is the release order LRU needs.
+	rows, err := ipa.queries.ListIPAddressesByPool(ctx, pool.ID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load IP addresses for pool %q: %w", pool.Name, err)
+	}
+	var allocated int
+	for _, row := range rows {
+		addr, err := netip.ParseAddr(row.Address)
 		if err != nil {
+			ipa.logger.Warn("Skipping unparsable IP address row", zap.String("ip", row.Address), zap.Error(err))
 			continue
 		}
-		count++
+		if row.IsAllocated {
+			err = addrs.MarkAllocated(addr)
+			allocated++
+		} else {
+			err = addrs.MarkReleased(addr)
+		}
+		if err != nil {
+			ipa.logger.Warn("IP address row does not belong to its pool",
+				zap.String("ip", row.Address), zap.String("pool", pool.Name), zap.String("cidr", pool.Cidr))
+		}
 	}
+	return addrs, allocated, nil
+}
 
-	ipa.logger.Info("IP pool pre-populated", zap.Int("added_ips", count), zap.String("cidr", cidr))
+// syncPool rebuilds a pool's Range from the database, picking up the addresses
+// other replicas have allocated or released since it was last loaded.
+func (ipa *IPAllocator) syncPool(ctx context.Context, p *ipPool) error {
+	addrs, allocated, err := ipa.loadRange(ctx, p.pool)
+	if err != nil {
+		return err
+	}
+	p.addrs = addrs
+	ipa.logger.Debug("IP pool resynced",
+		zap.String("pool", p.pool.Name),
+		zap.Int("allocated", allocated),
+	)
 	return nil
 }
 
-// AllocateIP attempts to atomically allocate an available IP address.
+// AllocateIP attempts to atomically allocate an available IP address from the default pool.
 func (ipa *IPAllocator) AllocateIP(ctx context.Context) (sqlc.IpAddress, error) {
+	return ipa.AllocateIPFromPool(ctx, DefaultIPPoolName)
+}
+
+// AllocateIPFromPool picks a free address from the named pool using the pool's
+// strategy and reserves it in the database. The address is bound to a server
+// afterwards with saveAllocatedIP.
+func (ipa *IPAllocator) AllocateIPFromPool(ctx context.Context, poolName string) (sqlc.IpAddress, error) {
+	ipa.ipMutex.Lock()
+	defer ipa.ipMutex.Unlock()
+
+	p, ok := ipa.pools[poolName]
+	if !ok {
+		return sqlc.IpAddress{}, fmt.Errorf("%w %q", ErrUnknownIPPool, poolName)
+	}
+
+	synced := false
+	for attempt := 0; attempt < maxReserveAttempts; attempt++ {
+		addr, err := p.addrs.Allocate()
+		if errors.Is(err, ipam.ErrExhausted) && !synced {
+			// Other replicas may have released addresses this one never saw.
+			if err := ipa.syncPool(ctx, p); err != nil {
+				return sqlc.IpAddress{}, err
+			}
+			synced = true
+			addr, err = p.addrs.Allocate()
+		}
+		if err != nil {
+			ipa.logger.Error("IP allocation failed", zap.String("pool", poolName), zap.Error(err))
+			return sqlc.IpAddress{}, fmt.Errorf("IP pool %q: %w", poolName, err)
+		}
+
+		reservedIP, err := ipa.queries.ReserveIPAddress(ctx, sqlc.ReserveIPAddressParams{
+			PoolID:  p.pool.ID,
+			Address: addr.String(),
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Someone else holds this address, so the bitmap is stale: reload it
+			// from the database, which marks the address and any others held
+			// elsewhere, and try again.
+			_ = p.addrs.Release(addr)
+			ipa.logger.Debug("IP address already held, resyncing pool", zap.String("ip", addr.String()))
+			if err := ipa.syncPool(ctx, p); err != nil {
+				return sqlc.IpAddress{}, err
+			}
+			synced = true
+			continue
+		}
+		if err != nil {
+			_ = p.addrs.Release(addr)
+			ipa.logger.Error("IP allocation failed", zap.Error(err))
+			return sqlc.IpAddress{}, err
+		}
+
+		ipa.logger.Info("Successfully selected IP for allocation",
+			zap.String("ip_id", reservedIP.ID.String()),
+			zap.String("ip", reservedIP.Address),
+			zap.String("pool", poolName),
+		)
+		return reservedIP, nil
+	}
+
+	ipa.logger.Error("IP allocation failed: every pick was already held", zap.String("pool", poolName))
+	return sqlc.IpAddress{}, fmt.Errorf("IP pool %q: no address could be reserved after %d attempts", poolName, maxReserveAttempts)
+}
+
+// ReleaseIP unbinds an address from its server and returns it to its pool.
+func (ipa *IPAllocator) ReleaseIP(ctx context.Context, ip sqlc.IpAddress) error {
 	ipa.ipMutex.Lock()
 	defer ipa.ipMutex.Unlock()
 
-	availableIP, err := ipa.queries.GetAvailableIPForAllocation(ctx)
+	released, err := deallocateIP(ctx, ipa.queries, ip)
 	if err != nil {
-		return sqlc.IpAddress{}, err
+		return err
 	}
+	return ipa.freeInPool(released)
+}
 
+// deallocateIP unbinds an address through q, so that it can be part of a
+// transaction. The address stays taken in its pool's bitmap until freeInPools is
+// called once the transaction has committed.
+func deallocateIP(ctx context.Context, q *sqlc.Queries, ip sqlc.IpAddress) (sqlc.IpAddress, error) {
+	released, err := q.DeallocateIPAddress(ctx, ip.ID)
 	if err != nil {
-		ipa.logger.Error("IP allocation failed", zap.Error(err))
-		return sqlc.IpAddress{}, err
+		return sqlc.IpAddress{}, fmt.Errorf("failed to deallocate IP address: %+v", err)
+	}
+	return released, nil
+}
+
+// freeInPools returns addresses deallocated by deallocateIP to their pools.
+func (ipa *IPAllocator) freeInPools(released []sqlc.IpAddress) {
+	ipa.ipMutex.Lock()
+	defer ipa.ipMutex.Unlock()
+
+	for _, ip := range released {
+		if err := ipa.freeInPool(ip); err != nil {
+			ipa.logger.Error("Failed to return IP address to its pool", zap.Error(err), zap.String("ip_id", ip.ID.String()))
+		}
 	}
+}
 
-	ipa.logger.Info("Successfully selected IP for allocation", zap.String("ip_id", availableIP.ID.String()))
-	return availableIP, nil
+// freeInPool marks a deallocated address free in its pool's bitmap. The caller holds ipMutex.
+func (ipa *IPAllocator) freeInPool(released sqlc.IpAddress) error {
+	addr, err := netip.ParseAddr(released.Address)
+	if err != nil {
+		return fmt.Errorf("invalid IP address %q: %w", released.Address, err)
+	}
+	for _, p := range ipa.pools {
+		if p.pool.ID == released.PoolID {
+			_ = p.addrs.Release(addr)
+			break
+		}
+	}
+
+	ipa.logger.Info("IP address released", zap.String("ip", released.Address), zap.String("ip_id", released.ID.String()))
+	return nil
 }
 
-// AllocateIP attempts to atomically allocate an available IP address.
-func (ipa *IPAllocator) saveAllocatedIP(ctx context.Context, serverID pgtype.UUID, allocatedIP pgtype.UUID) error {
+// saveAllocatedIP binds a reserved IP address to a server's network interface.
+func (ipa *IPAllocator) saveAllocatedIP(ctx context.Context, serverID pgtype.UUID, interfaceID pgtype.UUID, isPrimary bool, allocatedIP pgtype.UUID) error {
 
 	ipa.ipMutex.Lock()
 	defer ipa.ipMutex.Unlock()
-	ipa.logger.Info("Attempting to save allocated IP address", zap.String("allocated_ip", allocatedIP.String()), zap.String("server_id", serverID.String()))
+	ipa.logger.Info("Attempting to save allocated IP address",
+		zap.String("allocated_ip", allocatedIP.String()),
+		zap.String("server_id", serverID.String()),
+		zap.String("interface_id", interfaceID.String()),
+	)
 
 	var allocateIP sqlc.AllocateIPAddressParams
 	allocateIP.ID = allocatedIP
 	allocateIP.ServerID = serverID
-	// Select an available IP address, by checking ip_allocated=FALSE
+	allocateIP.InterfaceID = interfaceID
+	allocateIP.IsPrimary = isPrimary
 	availableIP, err := ipa.queries.AllocateIPAddress(ctx, allocateIP)
-	if err != nil {
-		return err
-	}
-
 	if err != nil {
 		ipa.logger.Error("IP allocation failed", zap.Error(err))
 		return err
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// replicasLockName names the advisory lock every live replica holds shared, so a
+// starting replica can tell whether it is the only one.
+const replicasLockName = "replicas"
+
+// leaderJob is a background job that only the replica holding its lease runs.
+type leaderJob struct {
+	name   string
+	key    int64
+	run    func(ctx context.Context)
+	leader bool
+	cancel context.CancelFunc
+	// done is closed once the last run of the job has returned.
+	done chan struct{}
+}
+
+// LeaderElector runs background jobs on a single replica at a time. Each job's
+// lease is a session-level Postgres advisory lock, held on a connection the
+// elector keeps outside the pool: the leader renews it by keeping the session alive every
+// LEADER_RENEW_INTERVAL, and Postgres ends a session idle for LEADER_LEASE_TIMEOUT,
+// so the lease of a replica that dies or hangs passes to another one.
+type LeaderElector struct {
+	connConfig *pgx.ConnConfig
+	logger     *zap.Logger
+	config     *config.Config
+	mutex      sync.Mutex
+	conn       *pgx.Conn
+	queries    *sqlc.Queries
+	jobs       []*leaderJob
+}
+
+// NewLeaderElector creates a new LeaderElector connecting to the database of db.
+func NewLeaderElector(db *database.DBClient, logger *zap.Logger, config *config.Config) *LeaderElector {
+	return &LeaderElector{
+		connConfig: db.Pool.Config().ConnConfig.Copy(),
+		logger:     logger,
+		config:     config,
+	}
+}
+
+// advisoryLockKey derives the advisory lock key of a lease from its name.
+func advisoryLockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("go-virtual-server/" + name))
+	return int64(hash.Sum64())
+}
+
+// Register adds a job that runs, with a context cancelled when the lease is lost,
+// while this replica holds the lease of name. Jobs are registered before Start.
+func (e *LeaderElector) Register(name string, run func(ctx context.Context)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	e.jobs = append(e.jobs, &leaderJob{name: name, key: advisoryLockKey(name), run: run})
+	leaderElectionIsLeader.WithLabelValues(name).Set(0)
+}
+
+// Start campaigns for the lease of every registered job every LEADER_RENEW_INTERVAL
+// and renews the leases held, until ctx is cancelled. With leader election
+// disabled every job simply runs.
+func (e *LeaderElector) Start(ctx context.Context) {
+	if !e.config.LeaderElection {
+		e.mutex.Lock()
+		for _, job := range e.jobs {
+			e.lead(ctx, job)
+		}
+		e.mutex.Unlock()
+		<-ctx.Done()
+		return
+	}
+
+	ticker := time.NewTicker(e.config.LeaderRenewInterval)
+	defer ticker.Stop()
+
+	e.logger.Info("Leader election started", zap.Duration("renew_interval", e.config.LeaderRenewInterval))
+	e.tick(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			e.mutex.Lock()
+			e.resign()
+			e.mutex.Unlock()
+			e.logger.Info("Leader election stopped due to context cancellation.")
+			return
+		case <-ticker.C:
+			e.tick(ctx)
+		}
+	}
+}
+
+// tick renews the session holding the leases, stepping down from every job if it
+// is gone, and tries to acquire the lease of every job this replica does not run.
+func (e *LeaderElector) tick(ctx context.Context) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if err := e.connect(ctx); err != nil {
+		e.logger.Error("Failed to connect for leader election", zap.Error(err))
+		return
+	}
+	renewCtx, cancel := context.WithTimeout(ctx, e.config.LeaderLeaseTimeout)
+	err := e.conn.Ping(renewCtx)
+	cancel()
+	if err != nil {
+		e.logger.Error("Failed to renew leases, stepping down", zap.Error(err))
+		e.resign()
+		return
+	}
+
+	for _, job := range e.jobs {
+		if job.leader {
+			continue
+		}
+		if job.done != nil {
+			select {
+			case <-job.done:
+			default:
+				// The previous run is still winding down
+				continue
+			}
+		}
+		acquired, err := e.queries.TryAdvisoryLock(ctx, job.key)
+		if err != nil {
+			e.logger.Error("Failed to acquire lease", zap.Error(err), zap.String("job", job.name))
+			continue
+		}
+		if acquired {
+			e.logger.Info("Lease acquired, running job", zap.String("job", job.name))
+			e.lead(ctx, job)
+		}
+	}
+}
+
+// connect opens the session the leases are held on, if it is not open.
+func (e *LeaderElector) connect(ctx context.Context) error {
+	if e.conn != nil && !e.conn.IsClosed() {
+		return nil
+	}
+	e.resign()
+
+	conn, err := pgx.ConnectConfig(ctx, e.connConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open leader election session: %+v", err)
+	}
+	return e.join(ctx, conn)
+}
+
+// join makes conn the session the leases are held on: it joins the live replicas,
+// waiting for a replica that is resetting the simulation, and arms the lease timeout.
+func (e *LeaderElector) join(ctx context.Context, conn *pgx.Conn) error {
+	queries := sqlc.New(conn)
+	if err := queries.AdvisoryLockShared(ctx, advisoryLockKey(replicasLockName)); err != nil {
+		conn.Close(ctx)
+		return fmt.Errorf("failed to join replicas: %+v", err)
+	}
+	timeout := fmt.Sprintf("%dms", e.config.LeaderLeaseTimeout.Milliseconds())
+	if err := queries.SetIdleSessionTimeout(ctx, timeout); err != nil {
+		conn.Close(ctx)
+		return fmt.Errorf("failed to set lease timeout: %+v", err)
+	}
+	e.conn = conn
+	e.queries = queries
+	return nil
+}
+
+// lead starts a job this replica now holds the lease of.
+func (e *LeaderElector) lead(ctx context.Context, job *leaderJob) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	job.leader = true
+	job.cancel = cancel
+	job.done = done
+	leaderElectionIsLeader.WithLabelValues(job.name).Set(1)
+
+	go func() {
+		defer close(done)
+		job.run(jobCtx)
+	}()
+}
+
+// resign stops every job this replica runs and ends the session, releasing all
+// its leases at once. The session is only ended once the jobs have returned, or
+// after LEADER_LEASE_TIMEOUT, so that a replica taking a lease over does not run
+// the job alongside a billing tick still in flight here.
+func (e *LeaderElector) resign() {
+	var stopping []*leaderJob
+	for _, job := range e.jobs {
+		if !job.leader {
+			continue
+		}
+		job.cancel()
+		job.leader = false
+		leaderElectionIsLeader.WithLabelValues(job.name).Set(0)
+		stopping = append(stopping, job)
+	}
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), e.config.LeaderLeaseTimeout)
+	defer cancel()
+	for _, job := range stopping {
+		select {
+		case <-job.done:
+			e.logger.Warn("Lease released, job stopped", zap.String("job", job.name))
+		case <-waitCtx.Done():
+			e.logger.Error("Job did not stop in time, releasing its lease anyway", zap.String("job", job.name))
+		}
+	}
+
+	if e.conn != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), e.config.LeaderRenewInterval)
+		e.conn.Close(ctx)
+		cancel()
+		e.conn = nil
+		e.queries = nil
+	}
+}
+
+// RunIfFirstReplica runs fn only if no other replica is live, such as the reset
+// of the simulation on startup, and holds off replicas starting meanwhile until
+// it returns. With leader election disabled fn always runs.
+func (e *LeaderElector) RunIfFirstReplica(ctx context.Context, fn func() error) error {
+	if !e.config.LeaderElection {
+		return fn()
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	conn, err := pgx.ConnectConfig(ctx, e.connConfig)
+	if err != nil {
+		return fmt.Errorf("failed to open leader election session: %+v", err)
+	}
+	queries := sqlc.New(conn)
+	key := advisoryLockKey(replicasLockName)
+	// Live replicas hold the lock shared, so it can only be taken exclusively by the first
+	first, err := queries.TryAdvisoryLock(ctx, key)
+	if err != nil {
+		conn.Close(ctx)
+		return fmt.Errorf("failed to check for live replicas: %+v", err)
+	}
+	if first {
+		if err := fn(); err != nil {
+			conn.Close(ctx)
+			return err
+		}
+	} else {
+		e.logger.Info("Other replicas are live, skipping startup reset")
+	}
+
+	if err := e.join(ctx, conn); err != nil {
+		return err
+	}
+	if first {
+		if err := queries.AdvisoryUnlock(ctx, key); err != nil {
+			return fmt.Errorf("failed to release startup lock: %+v", err)
+		}
+	}
+	return nil
+}
+
+// Leadership reports, per registered job, whether this replica runs it. With
+// leader election disabled every replica runs every job, so it returns nil.
+func (e *LeaderElector) Leadership() map[string]bool {
+	if !e.config.LeaderElection {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	leadership := make(map[string]bool, len(e.jobs))
+	for _, job := range e.jobs {
+		leadership[job.name] = job.leader
+	}
+	return leadership
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+)
+
+func TestLeadership(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	for _, enabled := range []bool{false, true} {
+		e := &LeaderElector{config: &config.Config{LeaderElection: enabled}, logger: zap.NewNop()}
+		e.Register("billing", func(ctx context.Context) { <-ctx.Done() })
+		e.Register("watchdog", func(ctx context.Context) { <-ctx.Done() })
+
+		// Leading a job is what Start does for every job when election is disabled
+		e.mutex.Lock()
+		e.lead(ctx, e.jobs[0])
+		e.mutex.Unlock()
+
+		leadership := e.Leadership()
+		if !enabled && leadership != nil {
+			t.Errorf("Leadership with election disabled = %v, want nil", leadership)
+		}
+		if enabled && (len(leadership) != 2 || !leadership["billing"] || leadership["watchdog"]) {
+			t.Errorf("Leadership = %v, want billing only", leadership)
+		}
+	}
+}
+
+func TestStartWithElectionDisabled(t *testing.T) {
+	e := &LeaderElector{config: &config.Config{LeaderElection: false}, logger: zap.NewNop()}
+	started := make(chan string, 2)
+	stopped := make(chan string, 2)
+	for _, name := range []string{"billing", "watchdog"} {
+		e.Register(name, func(ctx context.Context) {
+			started <- name
+			<-ctx.Done()
+			stopped <- name
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	returned := make(chan struct{})
+	go func() {
+		e.Start(ctx)
+		close(returned)
+	}()
+	for range 2 {
+		<-started
+	}
+	if leadership := e.Leadership(); leadership != nil {
+		t.Errorf("Leadership with election disabled = %v, want nil", leadership)
+	}
+
+	cancel()
+	<-returned
+	for range 2 {
+		select {
+		case <-stopped:
+		case <-time.After(time.Second):
+			t.Fatal("jobs were not stopped when Start returned")
+		}
+	}
+}
+
+func TestResign(t *testing.T) {
+	tests := []struct {
+		name string
+		// stop is how long a job takes to return once cancelled; negative never
+		stop     time.Duration
+		wantDone bool
+	}{
+		{name: "waits for jobs to return", stop: 20 * time.Millisecond, wantDone: true},
+		{name: "gives up after the lease timeout", stop: -1, wantDone: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &LeaderElector{
+				config: &config.Config{LeaderElection: true, LeaderLeaseTimeout: 100 * time.Millisecond, LeaderRenewInterval: 10 * time.Millisecond},
+				logger: zap.NewNop(),
+			}
+			release := make(chan struct{})
+			defer close(release)
+			e.Register("billing", func(ctx context.Context) {
+				<-ctx.Done()
+				if tt.stop < 0 {
+					<-release
+				}
+				time.Sleep(tt.stop)
+			})
+			e.Register("watchdog", func(ctx context.Context) { <-ctx.Done() })
+
+			e.mutex.Lock()
+			for _, job := range e.jobs {
+				e.lead(context.Background(), job)
+			}
+			e.mutex.Unlock()
+			if leadership := e.Leadership(); !leadership["billing"] || !leadership["watchdog"] {
+				t.Fatalf("Leadership before resigning = %v, want both jobs", leadership)
+			}
+
+			e.mutex.Lock()
+			began := time.Now()
+			e.resign()
+			elapsed := time.Since(began)
+			e.mutex.Unlock()
+
+			if leadership := e.Leadership(); leadership["billing"] || leadership["watchdog"] {
+				t.Errorf("Leadership after resigning = %v, want none", leadership)
+			}
+			select {
+			case <-e.jobs[0].done:
+				if !tt.wantDone {
+					t.Error("job that ignores cancellation returned")
+				}
+			default:
+				if tt.wantDone {
+					t.Errorf("resign returned after %s before the job did", elapsed)
+				}
+			}
+			if !tt.wantDone && elapsed < e.config.LeaderLeaseTimeout {
+				t.Errorf("resign gave up after %s, want the lease timeout of %s", elapsed, e.config.LeaderLeaseTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,541 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// ChargeTypeCompute is the ledger charge for the running time of a server.
+	ChargeTypeCompute = "compute"
+	// ChargeTypeComputeRounding is the time a billing model charges beyond the
+	// metered running time, see BilledDuration.
+	ChargeTypeComputeRounding = "compute_rounding"
+	// ChargeTypeComputeReserved is running time drawn down from a reservation. It
+	// is recorded at no charge since the reservation was paid upfront.
+	ChargeTypeComputeReserved = "compute_reserved"
+	// ChargeTypeReservation is the upfront payment of a reservation.
+	ChargeTypeReservation = "reservation"
+	// ChargeTypeStorage is the disk a server holds in every state but terminated, per GB-hour.
+	ChargeTypeStorage = "storage"
+	// ChargeTypePublicIP is the time a server holds a public address.
+	ChargeTypePublicIP = "public_ip"
+	// ChargeTypeEgress is the outbound traffic of a server, per GB.
+	ChargeTypeEgress = "egress"
+	// ChargeTypeVolumeDiscount is the volume tier discount of a period, see applyAdjustments.
+	ChargeTypeVolumeDiscount = "volume_discount"
+	// ChargeTypeDiscount is a percentage discount on the compute charges of a period.
+	ChargeTypeDiscount = "discount"
+	// ChargeTypeCredit is the part of a period's charges paid by a promotional credit.
+	ChargeTypeCredit = "credit"
+	// CurrencyUSD is the currency prices are defined in.
+	CurrencyUSD = "USD"
+	// DefaultProject is used for servers provisioned without a project.
+	DefaultProject = "default"
+)
+
+// ErrInvoiceNotFound is returned when an invoice does not exist.
+var ErrInvoiceNotFound = errors.New("invoice not found")
+
+// BillingService turns metered usage into ledger entries and invoices.
+type BillingService struct {
+	db     *database.DBClient
+	logger *zap.Logger
+	config *config.Config
+}
+
+// NewBillingService creates a new BillingService.
+func NewBillingService(db *database.DBClient, logger *zap.Logger, config *config.Config) *BillingService {
+	return &BillingService{
+		db:     db,
+		logger: logger,
+		config: config,
+	}
+}
+
+// PeriodStart returns the start of the monthly billing period containing t, in UTC.
+func PeriodStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// PeriodEnd returns the end (exclusive) of the billing period containing t.
+func PeriodEnd(t time.Time) time.Time {
+	return PeriodStart(t).AddDate(0, 1, 0)
+}
+
+// AccrueUsage writes completed usage to the ledger: closed segments up to their
+// end, and open segments up to the start of the current period. Usage of the
+// current period on running servers stays unbilled until the segment closes
+// or the period ends. Spot servers are priced from the spot price history.
+// Disks, public IPs and egress are written the same way, see resourceEntries.
+//
+// Entries are priced in memory, so accrual takes a fixed number of set-based
+// statements in one transaction however many segments there are: the unbilled
+// usage, the invoiced periods and the reservations to draw down are read once,
+// then all entries, billed-until times and draw-downs are written with one
+// statement each. A segment that cannot be priced is logged and left unbilled.
+func (b *BillingService) AccrueUsage(ctx context.Context, now time.Time) error {
+	currentPeriod := PeriodStart(now)
+	currentPeriodStart := pgtype.Timestamptz{Time: currentPeriod, Valid: true}
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return err
+	}
+
+	return b.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		segments, err := q.ListUnbilledUsageSegments(ctx, currentPeriodStart)
+		if err != nil {
+			return fmt.Errorf("failed to list unbilled usage segments: %+v", err)
+		}
+		resources, err := q.ListUnbilledResourceSegments(ctx, currentPeriodStart)
+		if err != nil {
+			return fmt.Errorf("failed to list unbilled resource segments: %+v", err)
+		}
+		egress, err := q.ListUnbilledEgress(ctx, currentPeriodStart)
+		if err != nil {
+			return fmt.Errorf("failed to list unbilled egress: %+v", err)
+		}
+
+		// The earliest unbilled usage bounds the invoices, spot prices and reservations needed
+		since := currentPeriod
+		var reservedProjects []string
+		for _, segment := range segments {
+			if segment.BilledUntil.Time.Before(since) {
+				since = segment.BilledUntil.Time
+			}
+			if segment.BillingModel == BillingModelReserved {
+				reservedProjects = append(reservedProjects, segment.Project)
+			}
+		}
+		for _, segment := range resources {
+			if segment.BilledUntil.Time.Before(since) {
+				since = segment.BilledUntil.Time
+			}
+		}
+		for _, usage := range egress {
+			if usage.PeriodStart.Time.Before(since) {
+				since = usage.PeriodStart.Time
+			}
+		}
+
+		invoiced, err := loadInvoicedPeriods(ctx, q, since)
+		if err != nil {
+			return err
+		}
+		spotCatalog, err := LoadSpotPriceCatalog(ctx, q, since)
+		if err != nil {
+			return err
+		}
+		var reservations []sqlc.Reservation
+		if len(reservedProjects) > 0 {
+			reservations, err = q.ListReservationsForDrawDown(ctx, sqlc.ListReservationsForDrawDownParams{
+				Projects: reservedProjects,
+				Since:    pgtype.Timestamptz{Time: since, Valid: true},
+				Until:    pgtype.Timestamptz{Time: now, Valid: true},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list reservations: %+v", err)
+			}
+		}
+		usedBefore := make([]float64, len(reservations))
+		for i, reservation := range reservations {
+			usedBefore[i] = reservation.HoursUsed
+		}
+
+		var entries []sqlc.CreateLedgerEntryParams
+		var usageBilled sqlc.SetUsageSegmentsBilledUntilParams
+		for _, segment := range segments {
+			until := accruedUntil(segment.EndedAt, currentPeriod)
+			if !segment.BilledUntil.Time.Before(until) {
+				continue
+			}
+			segmentCatalog := catalog
+			if segment.PurchaseOption == PurchaseOptionSpot {
+				segmentCatalog = spotCatalog
+			}
+			used := make([]float64, len(reservations))
+			for i, reservation := range reservations {
+				used[i] = reservation.HoursUsed
+			}
+
+			segmentEntries, err := usageEntries(segmentCatalog, invoiced, reservations, segment, until, currentPeriod)
+			if err != nil {
+				// Give back what the segment drew down
+				for i := range reservations {
+					reservations[i].HoursUsed = used[i]
+				}
+				b.logger.Error("Failed to accrue usage segment",
+					zap.Error(err),
+					zap.String("segment_id", segment.ID.String()),
+					zap.String("server_id", segment.ServerID.String()),
+				)
+				continue
+			}
+			entries = append(entries, segmentEntries...)
+			usageBilled.Ids = append(usageBilled.Ids, segment.ID)
+			usageBilled.BilledUntils = append(usageBilled.BilledUntils, pgtype.Timestamptz{Time: until, Valid: true})
+		}
+
+		var resourcesBilled sqlc.SetResourceSegmentsBilledUntilParams
+		for _, segment := range resources {
+			until := accruedUntil(segment.EndedAt, currentPeriod)
+			if !segment.BilledUntil.Time.Before(until) {
+				continue
+			}
+			entries = append(entries, resourceEntries(b.config, invoiced, segment, until, currentPeriod)...)
+			resourcesBilled.Ids = append(resourcesBilled.Ids, segment.ID)
+			resourcesBilled.BilledUntils = append(resourcesBilled.BilledUntils, pgtype.Timestamptz{Time: until, Valid: true})
+		}
+		// Egress is kept per hour; hours of past periods are billed as one entry per server and period
+		for _, usage := range egress {
+			entries = append(entries, egressEntry(b.config, invoiced, usage, currentPeriod))
+		}
+
+		if err := createLedgerEntries(ctx, q, entries); err != nil {
+			return err
+		}
+		if len(usageBilled.Ids) > 0 {
+			if err := q.SetUsageSegmentsBilledUntil(ctx, usageBilled); err != nil {
+				return fmt.Errorf("failed to advance usage segments: %+v", err)
+			}
+		}
+		if len(resourcesBilled.Ids) > 0 {
+			if err := q.SetResourceSegmentsBilledUntil(ctx, resourcesBilled); err != nil {
+				return fmt.Errorf("failed to advance resource segments: %+v", err)
+			}
+		}
+		if len(egress) > 0 {
+			if err := q.MarkEgressBilled(ctx, currentPeriodStart); err != nil {
+				return fmt.Errorf("failed to mark egress billed: %+v", err)
+			}
+		}
+
+		var drawn sqlc.SetReservationsHoursUsedParams
+		for i, reservation := range reservations {
+			if reservation.HoursUsed != usedBefore[i] {
+				drawn.Ids = append(drawn.Ids, reservation.ID)
+				drawn.UsedHours = append(drawn.UsedHours, reservation.HoursUsed)
+			}
+		}
+		if len(drawn.Ids) > 0 {
+			if err := q.SetReservationsHoursUsed(ctx, drawn); err != nil {
+				return fmt.Errorf("failed to draw down reservations: %+v", err)
+			}
+		}
+		return nil
+	})
+}
+
+// accruedUntil returns how far a segment is billed once accrued: to its end if
+// it is closed, to the start of the current period otherwise.
+func accruedUntil(endedAt pgtype.Timestamptz, currentPeriod time.Time) time.Time {
+	if endedAt.Valid {
+		return endedAt.Time
+	}
+	return currentPeriod
+}
+
+// usageEntries returns the ledger entries of a usage segment from its billed_until
+// to until: one per billing period and price the usage spans. Usage of servers on
+// BillingModelReserved is drawn down from reservations first. Once a closed
+// segment is billed to its end, the time its billing model charges beyond the
+// metered time is added.
+func usageEntries(catalog PriceCatalog, invoiced invoicedPeriods, reservations []sqlc.Reservation, segment sqlc.ListUnbilledUsageSegmentsRow, until, currentPeriod time.Time) ([]sqlc.CreateLedgerEntryParams, error) {
+	var entries []sqlc.CreateLedgerEntryParams
+	for start := segment.BilledUntil.Time; start.Before(until); start = PeriodEnd(start) {
+		end := PeriodEnd(start)
+		if end.After(until) {
+			end = until
+		}
+		period := billingPeriod(invoiced, segment.Project, start, currentPeriod)
+
+		slices, err := catalog.Slices(segment.ServerType, segment.Region, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to price usage of %s in %s: %w", segment.ServerType, segment.Region, err)
+		}
+		for _, slice := range slices {
+			hours := slice.End.Sub(slice.Start).Hours()
+			if segment.BillingModel == BillingModelReserved {
+				if covered := drawDownReservations(reservations, segment, slice.Start, hours); covered > 0 {
+					coveredEnd := slice.Start.Add(time.Duration(covered * float64(time.Hour)))
+					entries = append(entries, usageEntry(segment, period, ChargeTypeComputeReserved, slice.Start, coveredEnd, covered, 0))
+					slice.Start, hours = coveredEnd, hours-covered
+				}
+			}
+			if hours > 0 {
+				entries = append(entries, usageEntry(segment, period, ChargeTypeCompute, slice.Start, slice.End, hours, slice.Rate))
+			}
+		}
+	}
+
+	if segment.EndedAt.Valid {
+		metered := segment.EndedAt.Time.Sub(segment.StartedAt.Time)
+		if extra := BilledDuration(segment.BillingModel, metered) - metered; extra > 0 {
+			rate, err := catalog.RateAt(segment.ServerType, segment.Region, segment.EndedAt.Time)
+			if err != nil {
+				return nil, fmt.Errorf("failed to price usage of %s in %s: %w", segment.ServerType, segment.Region, err)
+			}
+			period := billingPeriod(invoiced, segment.Project, segment.EndedAt.Time, currentPeriod)
+			entries = append(entries, usageEntry(segment, period, ChargeTypeComputeRounding,
+				segment.StartedAt.Time, segment.EndedAt.Time, extra.Hours(), rate))
+		}
+	}
+	return entries, nil
+}
+
+type invoicedPeriod struct {
+	project string
+	period  time.Time
+}
+
+// invoicedPeriods holds the project periods that are already invoiced.
+type invoicedPeriods map[invoicedPeriod]bool
+
+// loadInvoicedPeriods reads the invoiced project periods from the one containing since on.
+func loadInvoicedPeriods(ctx context.Context, q *sqlc.Queries, since time.Time) (invoicedPeriods, error) {
+	rows, err := q.ListInvoicedPeriods(ctx, pgtype.Date{Time: PeriodStart(since), Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoiced periods: %+v", err)
+	}
+	invoiced := make(invoicedPeriods, len(rows))
+	for _, row := range rows {
+		invoiced[invoicedPeriod{row.Project, PeriodStart(row.PeriodStart.Time)}] = true
+	}
+	return invoiced, nil
+}
+
+// billingPeriod returns the period usage at t is booked to. Usage of a period
+// that was already invoiced is booked as a late charge on the current one.
+func billingPeriod(invoiced invoicedPeriods, project string, t, currentPeriod time.Time) time.Time {
+	period := PeriodStart(t)
+	if invoiced[invoicedPeriod{project, period}] {
+		return currentPeriod
+	}
+	return period
+}
+
+// drawDownReservations covers up to hours of a reserved server's usage at t from
+// the project's reservations for its type and region, in the order given (soonest
+// to expire first), and returns the hours covered. The hours are added to the
+// HoursUsed of the reservations they are drawn from.
+func drawDownReservations(reservations []sqlc.Reservation, segment sqlc.ListUnbilledUsageSegmentsRow, at time.Time, hours float64) float64 {
+	var covered float64
+	for i := range reservations {
+		reservation := &reservations[i]
+		if covered >= hours {
+			break
+		}
+		if reservation.Project != segment.Project || reservation.ServerType != segment.ServerType || reservation.Region != segment.Region {
+			continue
+		}
+		if reservation.StartsAt.Time.After(at) || !reservation.ExpiresAt.Time.After(at) || reservation.HoursUsed >= reservation.Hours {
+			continue
+		}
+		draw := min(reservation.Hours-reservation.HoursUsed, hours-covered)
+		reservation.HoursUsed += draw
+		covered += draw
+	}
+	return covered
+}
+
+// usageEntry returns a ledger entry for usage of the segment's server.
+func usageEntry(segment sqlc.ListUnbilledUsageSegmentsRow, period time.Time, chargeType string, start, end time.Time, hours, rate float64) sqlc.CreateLedgerEntryParams {
+	description := fmt.Sprintf("%s running in %s (%s)", segment.ServerType, segment.Region, segment.ServerName)
+	switch chargeType {
+	case ChargeTypeComputeReserved:
+		description += ", covered by reservation"
+	case ChargeTypeComputeRounding:
+		description += ", " + segment.BillingModel + " rounding"
+	}
+
+	return sqlc.CreateLedgerEntryParams{
+		Project:     segment.Project,
+		ServerID:    segment.ServerID,
+		SegmentID:   segment.ID,
+		PeriodStart: pgtype.Date{Time: period, Valid: true},
+		ChargeType:  chargeType,
+		ServerType:  segment.ServerType,
+		Region:      segment.Region,
+		Description: description,
+		UsageStart:  pgtype.Timestamptz{Time: start, Valid: true},
+		UsageEnd:    pgtype.Timestamptz{Time: end, Valid: true},
+		Quantity:    hours,
+		Unit:        "hour",
+		UnitPrice:   rate,
+		Amount:      hours * rate,
+		Currency:    CurrencyUSD,
+	}
+}
+
+// createLedgerEntries writes entries to the ledger with one statement.
+func createLedgerEntries(ctx context.Context, q *sqlc.Queries, entries []sqlc.CreateLedgerEntryParams) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	var params sqlc.CreateLedgerEntriesParams
+	for _, entry := range entries {
+		params.Projects = append(params.Projects, entry.Project)
+		params.ServerIds = append(params.ServerIds, entry.ServerID)
+		params.SegmentIds = append(params.SegmentIds, entry.SegmentID)
+		params.PeriodStarts = append(params.PeriodStarts, entry.PeriodStart)
+		params.ChargeTypes = append(params.ChargeTypes, entry.ChargeType)
+		params.ServerTypes = append(params.ServerTypes, entry.ServerType)
+		params.Regions = append(params.Regions, entry.Region)
+		params.Descriptions = append(params.Descriptions, entry.Description)
+		params.UsageStarts = append(params.UsageStarts, entry.UsageStart)
+		params.UsageEnds = append(params.UsageEnds, entry.UsageEnd)
+		params.Quantities = append(params.Quantities, entry.Quantity)
+		params.Units = append(params.Units, entry.Unit)
+		params.UnitPrices = append(params.UnitPrices, entry.UnitPrice)
+		params.Amounts = append(params.Amounts, entry.Amount)
+		params.Currencies = append(params.Currencies, entry.Currency)
+	}
+	if err := q.CreateLedgerEntries(ctx, params); err != nil {
+		return fmt.Errorf("failed to create ledger entries: %+v", err)
+	}
+	return nil
+}
+
+// ClosePeriods freezes every ended billing period that has ledger entries into
+// one invoice per project. It runs after AccrueUsage, so the ledger is complete
+// for every period before the current one.
+func (b *BillingService) ClosePeriods(ctx context.Context, now time.Time) error {
+	periods, err := b.db.Queries.ListPeriodsToClose(ctx, pgtype.Date{Time: PeriodStart(now), Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to list periods to close: %+v", err)
+	}
+
+	for _, period := range periods {
+		invoice, err := b.closePeriod(ctx, period.Project, period.PeriodStart.Time)
+		if err != nil {
+			b.logger.Error("Failed to close billing period",
+				zap.Error(err),
+				zap.String("project", period.Project),
+				zap.Time("period_start", period.PeriodStart.Time),
+			)
+			continue
+		}
+		b.logger.Info("Billing period closed",
+			zap.String("invoice_id", invoice.ID.String()),
+			zap.String("project", invoice.Project),
+			zap.Time("period_start", invoice.PeriodStart.Time),
+			zap.Float64("total", invoice.Total),
+		)
+	}
+	return nil
+}
+
+// closePeriod issues the invoice of a project period in the project's currency, at
+// the exchange rate in effect when the period ended. The rate is stored on the
+// invoice. If the currency had no rate yet, the invoice is issued in USD. Volume
+// tiers, discounts and credits are booked to the period first, see applyAdjustments.
+func (b *BillingService) closePeriod(ctx context.Context, project string, periodStart time.Time) (sqlc.Invoice, error) {
+	settings, err := b.GetProject(ctx, project)
+	if err != nil {
+		return sqlc.Invoice{}, err
+	}
+	rates, err := b.ExchangeRates(ctx)
+	if err != nil {
+		return sqlc.Invoice{}, err
+	}
+	currency := settings.Currency
+	rate, err := rates.RateAt(currency, PeriodEnd(periodStart))
+	if err != nil {
+		b.logger.Warn("No exchange rate at the end of the period, invoicing in USD",
+			zap.String("project", project),
+			zap.String("currency", currency),
+			zap.Time("period_start", periodStart),
+		)
+		currency, rate = CurrencyUSD, 1
+	}
+
+	var invoice sqlc.Invoice
+	err = b.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		if err := b.applyAdjustments(ctx, q, project, periodStart); err != nil {
+			return err
+		}
+		lines, err := q.SummarizeLedgerPeriod(ctx, sqlc.SummarizeLedgerPeriodParams{
+			Project:     project,
+			PeriodStart: pgtype.Date{Time: periodStart, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to summarize ledger: %+v", err)
+		}
+
+		var total float64
+		for i := range lines {
+			lines[i].UnitPrice *= rate
+			lines[i].Amount = roundCents(lines[i].Amount * rate)
+			total += lines[i].Amount
+		}
+
+		invoice, err = q.CreateInvoice(ctx, sqlc.CreateInvoiceParams{
+			Project:      project,
+			PeriodStart:  pgtype.Date{Time: periodStart, Valid: true},
+			PeriodEnd:    pgtype.Date{Time: PeriodEnd(periodStart), Valid: true},
+			Currency:     currency,
+			ExchangeRate: rate,
+			Total:        roundCents(total),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create invoice: %+v", err)
+		}
+
+		for _, line := range lines {
+			_, err := q.CreateInvoiceLine(ctx, sqlc.CreateInvoiceLineParams{
+				InvoiceID:  invoice.ID,
+				ServerID:   line.ServerID,
+				ServerName: line.ServerName,
+				ChargeType: line.ChargeType,
+				ServerType: line.ServerType,
+				Region:     line.Region,
+				Quantity:   line.Quantity,
+				Unit:       line.Unit,
+				UnitPrice:  line.UnitPrice,
+				Amount:     line.Amount,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create invoice line: %+v", err)
+			}
+		}
+		return nil
+	})
+	return invoice, err
+}
+
+// ListInvoices returns invoices, optionally filtered by project and period.
+func (b *BillingService) ListInvoices(ctx context.Context, params sqlc.ListInvoicesParams) ([]sqlc.Invoice, error) {
+	invoices, err := b.db.Queries.ListInvoices(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invoices: %+v", err)
+	}
+	return invoices, nil
+}
+
+// GetInvoice returns an invoice with its line items.
+func (b *BillingService) GetInvoice(ctx context.Context, invoiceID pgtype.UUID) (sqlc.Invoice, []sqlc.InvoiceLine, error) {
+	invoice, err := b.db.Queries.GetInvoice(ctx, invoiceID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Invoice{}, nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return sqlc.Invoice{}, nil, fmt.Errorf("failed to get invoice: %+v", err)
+	}
+
+	lines, err := b.db.Queries.ListInvoiceLines(ctx, invoiceID)
+	if err != nil {
+		return sqlc.Invoice{}, nil, fmt.Errorf("failed to list invoice lines: %+v", err)
+	}
+	return invoice, lines, nil
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func mustTime(t *testing.T, value string) time.Time {
+	t.Helper()
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: !t.IsZero()}
+}
+
+// testCatalog builds a catalog the way LoadPriceCatalog does from rows ordered
+// by effective time.
+func testCatalog(prices ...sqlc.Price) PriceCatalog {
+	sort.SliceStable(prices, func(i, j int) bool { return prices[i].EffectiveFrom.Time.Before(prices[j].EffectiveFrom.Time) })
+	catalog := PriceCatalog{versions: make(map[priceKey][]sqlc.Price)}
+	for _, price := range prices {
+		key := priceKey{price.ServerType, price.Region}
+		catalog.versions[key] = append(catalog.versions[key], price)
+	}
+	return catalog
+}
+
+func testPrice(serverType, region string, rate float64, from time.Time) sqlc.Price {
+	return sqlc.Price{ServerType: serverType, Region: region, HourlyRate: rate, Currency: CurrencyUSD, EffectiveFrom: timestamptz(from)}
+}
+
+func testSegment(billingModel string, startedAt, endedAt, billedUntil time.Time) sqlc.ListUnbilledUsageSegmentsRow {
+	return sqlc.ListUnbilledUsageSegmentsRow{
+		StartedAt:    timestamptz(startedAt),
+		EndedAt:      timestamptz(endedAt),
+		BilledUntil:  timestamptz(billedUntil),
+		Project:      "acme",
+		Region:       "us-east-1",
+		ServerType:   "t2.micro",
+		ServerName:   "web",
+		BillingModel: billingModel,
+	}
+}
+
+// wantEntry is the part of a ledger entry the accrual tests check.
+type wantEntry struct {
+	chargeType string
+	period     string
+	start      string
+	end        string
+	hours      float64
+	rate       float64
+}
+
+func checkEntries(t *testing.T, got []sqlc.CreateLedgerEntryParams, want []wantEntry) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d entries, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		g := got[i]
+		switch {
+		case g.ChargeType != w.chargeType,
+			!g.PeriodStart.Time.Equal(mustTime(t, w.period)),
+			!g.UsageStart.Time.Equal(mustTime(t, w.start)),
+			!g.UsageEnd.Time.Equal(mustTime(t, w.end)),
+			g.Quantity != w.hours,
+			g.UnitPrice != w.rate,
+			g.Amount != w.hours*w.rate:
+			t.Errorf("entry %d = %s in %s, [%s, %s), %v hours at %v for %v; want %+v", i,
+				g.ChargeType, g.PeriodStart.Time.Format(time.DateOnly), g.UsageStart.Time.Format(time.RFC3339), g.UsageEnd.Time.Format(time.RFC3339),
+				g.Quantity, g.UnitPrice, g.Amount, w)
+		}
+	}
+}
+
+func TestPeriodStartEnd(t *testing.T) {
+	tests := []struct {
+		t         string
+		wantStart string
+		wantEnd   string
+	}{
+		{t: "2026-03-15T12:30:00Z", wantStart: "2026-03-01T00:00:00Z", wantEnd: "2026-04-01T00:00:00Z"},
+		{t: "2026-03-01T00:00:00Z", wantStart: "2026-03-01T00:00:00Z", wantEnd: "2026-04-01T00:00:00Z"},
+		{t: "2026-02-28T23:59:59Z", wantStart: "2026-02-01T00:00:00Z", wantEnd: "2026-03-01T00:00:00Z"},
+		{t: "2026-12-31T23:00:00Z", wantStart: "2026-12-01T00:00:00Z", wantEnd: "2027-01-01T00:00:00Z"},
+		// Periods are UTC months, whatever the zone of t
+		{t: "2026-03-01T01:00:00+02:00", wantStart: "2026-02-01T00:00:00Z", wantEnd: "2026-03-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		at := mustTime(t, tt.t)
+		if got := PeriodStart(at); !got.Equal(mustTime(t, tt.wantStart)) || got.Location() != time.UTC {
+			t.Errorf("PeriodStart(%s) = %v, want %s", tt.t, got, tt.wantStart)
+		}
+		if got := PeriodEnd(at); !got.Equal(mustTime(t, tt.wantEnd)) {
+			t.Errorf("PeriodEnd(%s) = %v, want %s", tt.t, got, tt.wantEnd)
+		}
+	}
+}
+
+func TestBillingPeriod(t *testing.T) {
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	invoiced := invoicedPeriods{{"acme", mustTime(t, "2026-01-01T00:00:00Z")}: true}
+	tests := []struct {
+		name    string
+		project string
+		t       string
+		want    string
+	}{
+		{name: "open period", project: "acme", t: "2026-02-10T08:00:00Z", want: "2026-02-01T00:00:00Z"},
+		{name: "current period", project: "acme", t: "2026-03-02T08:00:00Z", want: "2026-03-01T00:00:00Z"},
+		{name: "invoiced period is charged late", project: "acme", t: "2026-01-31T23:00:00Z", want: "2026-03-01T00:00:00Z"},
+		{name: "other project's invoice", project: "other", t: "2026-01-31T23:00:00Z", want: "2026-01-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := billingPeriod(invoiced, tt.project, mustTime(t, tt.t), current); !got.Equal(mustTime(t, tt.want)) {
+				t.Errorf("billingPeriod(%s, %s) = %v, want %s", tt.project, tt.t, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccruedUntil(t *testing.T) {
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	ended := mustTime(t, "2026-03-04T10:00:00Z")
+	if got := accruedUntil(timestamptz(ended), current); !got.Equal(ended) {
+		t.Errorf("closed segment accrued until %v, want its end %v", got, ended)
+	}
+	if got := accruedUntil(pgtype.Timestamptz{}, current); !got.Equal(current) {
+		t.Errorf("open segment accrued until %v, want the current period %v", got, current)
+	}
+}
+
+func TestUsageEntries(t *testing.T) {
+	catalog := testCatalog(testPrice("t2.micro", PriceRegionAny, 0.5, mustTime(t, "2025-01-01T00:00:00Z")))
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	tests := []struct {
+		name     string
+		segment  sqlc.ListUnbilledUsageSegmentsRow
+		invoiced invoicedPeriods
+		want     []wantEntry
+	}{
+		{
+			name:    "open segment up to the current period",
+			segment: testSegment(BillingModelPerSecond, mustTime(t, "2026-02-27T10:00:00Z"), time.Time{}, mustTime(t, "2026-02-27T10:00:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-27T10:00:00Z", "2026-03-01T00:00:00Z", 38, 0.5},
+			},
+		},
+		{
+			name:    "closed segment split at the period boundary",
+			segment: testSegment(BillingModelPerSecond, mustTime(t, "2026-01-31T20:00:00Z"), mustTime(t, "2026-02-01T04:00:00Z"), mustTime(t, "2026-01-31T20:00:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-01-01T00:00:00Z", "2026-01-31T20:00:00Z", "2026-02-01T00:00:00Z", 4, 0.5},
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", "2026-02-01T04:00:00Z", 4, 0.5},
+			},
+		},
+		{
+			name:    "billed from billed_until",
+			segment: testSegment(BillingModelPerSecond, mustTime(t, "2026-01-10T00:00:00Z"), mustTime(t, "2026-02-10T12:00:00Z"), mustTime(t, "2026-02-10T00:00:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-10T00:00:00Z", "2026-02-10T12:00:00Z", 12, 0.5},
+			},
+		},
+		{
+			name:     "usage of an invoiced period is charged to the current one",
+			segment:  testSegment(BillingModelPerSecond, mustTime(t, "2026-01-31T20:00:00Z"), mustTime(t, "2026-02-01T04:00:00Z"), mustTime(t, "2026-01-31T20:00:00Z")),
+			invoiced: invoicedPeriods{{"acme", mustTime(t, "2026-01-01T00:00:00Z")}: true},
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-03-01T00:00:00Z", "2026-01-31T20:00:00Z", "2026-02-01T00:00:00Z", 4, 0.5},
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", "2026-02-01T04:00:00Z", 4, 0.5},
+			},
+		},
+		{
+			name:    "nothing left to bill",
+			segment: testSegment(BillingModelPerSecond, mustTime(t, "2026-02-01T00:00:00Z"), mustTime(t, "2026-02-01T04:00:00Z"), mustTime(t, "2026-02-01T04:00:00Z")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until := accruedUntil(tt.segment.EndedAt, current)
+			entries, err := usageEntries(catalog, tt.invoiced, nil, tt.segment, until, current)
+			if err != nil {
+				t.Fatalf("usageEntries failed: %v", err)
+			}
+			checkEntries(t, entries, tt.want)
+			for _, entry := range entries {
+				if entry.Project != "acme" || entry.Currency != CurrencyUSD || entry.Unit != "hour" {
+					t.Errorf("entry of project %q in %q per %q, want acme in %s per hour", entry.Project, entry.Currency, entry.Unit, CurrencyUSD)
+				}
+			}
+		})
+	}
+}
+
+func TestUsageEntriesWithoutPrice(t *testing.T) {
+	catalog := testCatalog(testPrice("m5.large", PriceRegionAny, 0.096, mustTime(t, "2025-01-01T00:00:00Z")))
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	segment := testSegment(BillingModelPerSecond, mustTime(t, "2026-02-01T00:00:00Z"), mustTime(t, "2026-02-01T04:00:00Z"), mustTime(t, "2026-02-01T00:00:00Z"))
+	if _, err := usageEntries(catalog, nil, nil, segment, segment.EndedAt.Time, current); !errors.Is(err, ErrNoPrice) {
+		t.Fatalf("usageEntries without a price = %v, want ErrNoPrice", err)
+	}
+}
+
+func testReservation(project, region string, hours, hoursUsed float64, startsAt, expiresAt time.Time) sqlc.Reservation {
+	return sqlc.Reservation{
+		Project:    project,
+		ServerType: "t2.micro",
+		Region:     region,
+		Hours:      hours,
+		HoursUsed:  hoursUsed,
+		StartsAt:   timestamptz(startsAt),
+		ExpiresAt:  timestamptz(expiresAt),
+	}
+}
+
+func TestDrawDownReservations(t *testing.T) {
+	at := mustTime(t, "2026-03-10T00:00:00Z")
+	starts, expires := mustTime(t, "2026-01-01T00:00:00Z"), mustTime(t, "2027-01-01T00:00:00Z")
+	reservations := func() []sqlc.Reservation {
+		return []sqlc.Reservation{
+			testReservation("other", "us-east-1", 100, 0, starts, expires),
+			testReservation("acme", "us-east-1", 10, 10, starts, expires),                           // used up
+			testReservation("acme", "eu-west-1", 100, 0, starts, expires),                           // other region
+			testReservation("acme", "us-east-1", 100, 0, at.Add(time.Hour), expires),                // not started
+			testReservation("acme", "us-east-1", 100, 0, starts, at),                                // expired
+			testReservation("acme", "us-east-1", 5, 2, starts, mustTime(t, "2026-06-01T00:00:00Z")), // expires first
+			testReservation("acme", "us-east-1", 10, 0, starts, expires),
+		}
+	}
+	segment := testSegment(BillingModelReserved, at, time.Time{}, at)
+	tests := []struct {
+		name         string
+		hours        float64
+		wantCovered  float64
+		wantUsedLast [2]float64 // HoursUsed of the last two reservations afterwards
+	}{
+		{name: "first reservation covers it", hours: 2, wantCovered: 2, wantUsedLast: [2]float64{4, 0}},
+		{name: "spills into the next one", hours: 5, wantCovered: 5, wantUsedLast: [2]float64{5, 2}},
+		{name: "more than reserved", hours: 20, wantCovered: 13, wantUsedLast: [2]float64{5, 10}},
+		{name: "nothing", hours: 0, wantCovered: 0, wantUsedLast: [2]float64{2, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rs := reservations()
+			if got := drawDownReservations(rs, segment, at, tt.hours); got != tt.wantCovered {
+				t.Errorf("covered %v hours, want %v", got, tt.wantCovered)
+			}
+			if used := [2]float64{rs[5].HoursUsed, rs[6].HoursUsed}; used != tt.wantUsedLast {
+				t.Errorf("hours used = %v, want %v", used, tt.wantUsedLast)
+			}
+			for i, r := range rs[:5] {
+				if want := reservations()[i].HoursUsed; r.HoursUsed != want {
+					t.Errorf("reservation %d that does not apply was drawn down to %v", i, r.HoursUsed)
+				}
+			}
+		})
+	}
+}
+
+func TestUsageEntriesReserved(t *testing.T) {
+	catalog := testCatalog(testPrice("t2.micro", PriceRegionAny, 0.5, mustTime(t, "2025-01-01T00:00:00Z")))
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	reservations := []sqlc.Reservation{
+		testReservation("acme", "us-east-1", 10, 0, mustTime(t, "2026-01-01T00:00:00Z"), mustTime(t, "2027-01-01T00:00:00Z")),
+	}
+	segment := testSegment(BillingModelReserved, mustTime(t, "2026-02-27T10:00:00Z"), time.Time{}, mustTime(t, "2026-02-27T10:00:00Z"))
+	entries, err := usageEntries(catalog, nil, reservations, segment, current, current)
+	if err != nil {
+		t.Fatalf("usageEntries failed: %v", err)
+	}
+	checkEntries(t, entries, []wantEntry{
+		{ChargeTypeComputeReserved, "2026-02-01T00:00:00Z", "2026-02-27T10:00:00Z", "2026-02-27T20:00:00Z", 10, 0},
+		{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-27T20:00:00Z", "2026-03-01T00:00:00Z", 28, 0.5},
+	})
+	if reservations[0].HoursUsed != 10 {
+		t.Errorf("reservation used %v hours, want 10", reservations[0].HoursUsed)
+	}
+}
+
+func TestUsageEntriesRounding(t *testing.T) {
+	catalog := testCatalog(
+		testPrice("t2.micro", PriceRegionAny, 0.5, mustTime(t, "2025-01-01T00:00:00Z")),
+		testPrice("t2.micro", PriceRegionAny, 0.6, mustTime(t, "2026-02-10T02:00:00Z")),
+	)
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	tests := []struct {
+		name    string
+		segment sqlc.ListUnbilledUsageSegmentsRow
+		want    []wantEntry
+	}{
+		{
+			name:    "hourly charges the started hour at the closing rate",
+			segment: testSegment(BillingModelHourly, mustTime(t, "2026-02-10T00:00:00Z"), mustTime(t, "2026-02-10T02:30:00Z"), mustTime(t, "2026-02-10T00:00:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-10T00:00:00Z", "2026-02-10T02:00:00Z", 2, 0.5},
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-10T02:00:00Z", "2026-02-10T02:30:00Z", 0.5, 0.6},
+				{ChargeTypeComputeRounding, "2026-02-01T00:00:00Z", "2026-02-10T00:00:00Z", "2026-02-10T02:30:00Z", 0.5, 0.6},
+			},
+		},
+		{
+			name:    "per-second charges the minimum",
+			segment: testSegment(BillingModelPerSecond, mustTime(t, "2026-02-01T00:00:00Z"), mustTime(t, "2026-02-01T00:00:30Z"), mustTime(t, "2026-02-01T00:00:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", "2026-02-01T00:00:30Z", 30.0 / 3600, 0.5},
+				{ChargeTypeComputeRounding, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", "2026-02-01T00:00:30Z", 30.0 / 3600, 0.5},
+			},
+		},
+		{
+			name:    "rounding waits for the segment to be billed to its end",
+			segment: testSegment(BillingModelHourly, mustTime(t, "2026-02-28T23:30:00Z"), time.Time{}, mustTime(t, "2026-02-28T23:30:00Z")),
+			want: []wantEntry{
+				{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-28T23:30:00Z", "2026-03-01T00:00:00Z", 0.5, 0.6},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until := accruedUntil(tt.segment.EndedAt, current)
+			entries, err := usageEntries(catalog, nil, nil, tt.segment, until, current)
+			if err != nil {
+				t.Fatalf("usageEntries failed: %v", err)
+			}
+			checkEntries(t, entries, tt.want)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// MaxUserDataBytes matches the user-data limit of the real cloud.
+const MaxUserDataBytes = 16 * 1024
+
+var (
+	// ErrInvalidTag is returned for tag keys that cannot be used as a metadata path segment.
+	ErrInvalidTag = errors.New("tag keys must be non-empty and must not contain '/'")
+	// ErrUserDataTooLarge is returned when user-data exceeds MaxUserDataBytes.
+	ErrUserDataTooLarge = fmt.Errorf("user-data must be at most %d bytes", MaxUserDataBytes)
+	// ErrUnknownCaller is returned when no live server holds the caller's address.
+	ErrUnknownCaller = errors.New("no server is bound to the caller's address")
+)
+
+// InstanceMetadata is everything the metadata service exposes about one server.
+type InstanceMetadata struct {
+	Server     sqlc.Server
+	Tags       map[string]string
+	PublicIP   string
+	Interfaces []sqlc.NetworkInterface
+	Addresses  []sqlc.ListIPAddressesByServerIDsRow
+	// InterruptAt is when a spot server that has been given its interruption
+	// notice will be interrupted; zero otherwise.
+	InterruptAt time.Time
+}
+
+// GetInstanceMetadata identifies the calling server by one of its addresses,
+// private or public, and gathers its metadata.
+func (s *ServerService) GetInstanceMetadata(ctx context.Context, callerIP string) (InstanceMetadata, error) {
+	server, err := s.queries.GetLiveServerByAddress(ctx, callerIP)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return InstanceMetadata{}, ErrUnknownCaller
+	}
+	if err != nil {
+		return InstanceMetadata{}, fmt.Errorf("failed to get server by address: %+v", err)
+	}
+
+	tags, err := UnmarshalTags(server.Tags)
+	if err != nil {
+		return InstanceMetadata{}, err
+	}
+
+	interfaces, addresses, err := s.GetNetworkInterfaces(ctx, server.ID)
+	if err != nil {
+		return InstanceMetadata{}, err
+	}
+
+	metadata := InstanceMetadata{
+		Server:     server,
+		Tags:       tags,
+		Interfaces: interfaces,
+		Addresses:  addresses,
+	}
+	mappings, err := s.GetActiveNATMappings(ctx, server.ID)
+	if err != nil {
+		return InstanceMetadata{}, err
+	}
+	if len(mappings) > 0 {
+		metadata.PublicIP = mappings[0].PublicAddress
+	}
+	if server.InterruptionNoticeAt.Valid {
+		metadata.InterruptAt = server.InterruptionNoticeAt.Time.Add(s.config.SpotNotice)
+	}
+	return metadata, nil
+}
+
+// validateProvisionOptions rejects tags and user-data the metadata service could not serve.
+func validateProvisionOptions(opts ProvisionOptions) error {
+	for key := range opts.Tags {
+		if key == "" || strings.Contains(key, "/") {
+			return ErrInvalidTag
+		}
+	}
+	if len(opts.UserData) > MaxUserDataBytes {
+		return ErrUserDataTooLarge
+	}
+	return nil
+}
+
+// marshalTags encodes tags for the servers.tags column; nil becomes an empty object.
+func marshalTags(tags map[string]string) ([]byte, error) {
+	if tags == nil {
+		tags = map[string]string{}
+	}
+	encoded, err := json.Marshal(tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tags: %+v", err)
+	}
+	return encoded, nil
+}
+
+// UnmarshalTags decodes the servers.tags column.
+func UnmarshalTags(raw []byte) (map[string]string, error) {
+	tags := map[string]string{}
+	if len(raw) == 0 {
+		return tags, nil
+	}
+	if err := json.Unmarshal(raw, &tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %+v", err)
+	}
+	return tags, nil
+}
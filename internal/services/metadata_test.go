@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func TestValidateProvisionOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ProvisionOptions
+		want error
+	}{
+		{name: "empty", opts: ProvisionOptions{}},
+		{name: "tags", opts: ProvisionOptions{Tags: map[string]string{"env": "prod", "a.b-c": ""}}},
+		{name: "empty tag key", opts: ProvisionOptions{Tags: map[string]string{"": "x"}}, want: ErrInvalidTag},
+		{name: "slash in tag key", opts: ProvisionOptions{Tags: map[string]string{"a/b": "x"}}, want: ErrInvalidTag},
+		{name: "user-data at the limit", opts: ProvisionOptions{UserData: strings.Repeat("x", MaxUserDataBytes)}},
+		{name: "user-data over the limit", opts: ProvisionOptions{UserData: strings.Repeat("x", MaxUserDataBytes+1)}, want: ErrUserDataTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateProvisionOptions(tt.opts); got != tt.want {
+				t.Errorf("validateProvisionOptions() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		tags map[string]string
+		want map[string]string
+	}{
+		{name: "nil", tags: nil, want: map[string]string{}},
+		{name: "tags", tags: map[string]string{"env": "prod", "team": ""}, want: map[string]string{"env": "prod", "team": ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := marshalTags(tt.tags)
+			if err != nil {
+				t.Fatalf("marshalTags() error = %v", err)
+			}
+			got, err := UnmarshalTags(raw)
+			if err != nil || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalTags(%s) = %v, %v; want %v, nil", raw, got, err, tt.want)
+			}
+		})
+	}
+
+	if got, err := UnmarshalTags(nil); err != nil || len(got) != 0 {
+		t.Errorf("UnmarshalTags(nil) = %v, %v; want empty, nil", got, err)
+	}
+	if _, err := UnmarshalTags([]byte(`["not", "an", "object"]`)); err == nil {
+		t.Error("UnmarshalTags(array) error = nil; want an error")
+	}
+}
+
+func TestGetInstanceMetadataUnknownCaller(t *testing.T) {
+	s := &ServerService{queries: sqlc.New(fakeServerDB{})}
+	if _, err := s.GetInstanceMetadata(context.Background(), "192.0.2.1"); !errors.Is(err, ErrUnknownCaller) {
+		t.Errorf("GetInstanceMetadata(unknown) error = %v; want %v", err, ErrUnknownCaller)
+	}
+}
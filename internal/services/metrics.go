@@ -26,7 +26,7 @@ var (
 	serverCurrentStatusCount = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "server_current_status",
-			Help: "Current  servers by status (e.g.,provisioning : 0 running : 1, stopped : 2, terminated : 3).",
+			Help: "Current  servers by status (e.g.,provisioning : 0 running : 1, stopped : 2, terminated : 3, suspended : 4, error : 5).",
 		},
 		[]string{"status"},
 	)
@@ -49,6 +49,25 @@ var (
 		},
 		[]string{"server_id"},
 	)
+
+	// leaderElectionIsLeader is a GaugeVec that shows, per background job, whether this replica holds its lease.
+	leaderElectionIsLeader = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "leader_election_is_leader",
+			Help: "Whether this replica holds the lease of a background job and runs it (1) or not (0).",
+		},
+		[]string{"job"},
+	)
+
+	// serverStuckTotal is a CounterVec that counts servers the watchdog found stuck in a
+	// transient status, by that status and by what it did: recovered, retry_failed or error.
+	serverStuckTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "server_stuck_total",
+			Help: "Servers found stuck in a transient status, by status and outcome (recovered, retry_failed, error).",
+		},
+		[]string{"status", "outcome"},
+	)
 )
 
 // The init() function runs automatically when the package is loaded.
@@ -59,6 +78,8 @@ func init() {
 	prometheus.MustRegister(serverCurrentStatusCount)
 	prometheus.MustRegister(serverHourlyCost)
 	prometheus.MustRegister(serverUptimeSeconds)
+	prometheus.MustRegister(leaderElectionIsLeader)
+	prometheus.MustRegister(serverStuckTotal)
 }
 
 // MetricsUpdater is a struct that manages updating our Prometheus metrics.
@@ -85,25 +106,25 @@ func NewMetricsUpdater(ctx context.Context, cancel context.CancelFunc, queries *
 	}
 }
 
-// Start begins the metrics updater's background process.
-// It runs in its own goroutine, periodically calling updateMetrics.
+// Start runs the metrics updater's background process, periodically calling
+// updateMetrics until ctx or the updater's own context is cancelled.
 func (mu *MetricsUpdater) Start(ctx context.Context) {
 	mu.logger.Info("Metrics Updater started. Updating metrics every 30 seconds")
-	go func() {
-		ticker := time.NewTicker(30 * time.Second)
-		time.Sleep(30 * time.Second)
-		defer ticker.Stop() // Ensure the ticker is stopped when the goroutine exits
-
-		for {
-			select {
-			case <-ticker.C: // When the ticker "ticks" (sends a signal)
-				mu.updateMetrics() // Call the function to update all metrics
-			case <-mu.ctx.Done(): // If the context is cancelled (e.g., app shutting down)
-				log.Println("Metrics Updater shutting down.")
-				return
-			}
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop() // Ensure the ticker is stopped when the updater exits
+
+	for {
+		select {
+		case <-ticker.C: // When the ticker "ticks" (sends a signal)
+			mu.updateMetrics() // Call the function to update all metrics
+		case <-ctx.Done(): // If the context is cancelled (e.g., leadership lost)
+			log.Println("Metrics Updater stopped.")
+			return
+		case <-mu.ctx.Done(): // If the updater's context is cancelled (e.g., app shutting down)
+			log.Println("Metrics Updater shutting down.")
+			return
 		}
-	}()
+	}
 }
 
 // Stop signals the metrics updater to shut down gracefully.
@@ -148,6 +169,10 @@ func getStatusNumber(status string) float64 {
 		return 2
 	case "terminated":
 		return 3
+	case "suspended":
+		return 4
+	case "error":
+		return 5
 	default:
 		return 0
 	}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// PublicIPPoolName is the pool built from PUBLIC_IP_CIDR.
+const PublicIPPoolName = "public"
+
+// PrivateIPPoolName returns the name of the private pool registered for a region.
+func PrivateIPPoolName(region string) string {
+	return "private-" + region
+}
+
+// ErrNoPrivateIPPool is returned when provisioning in a region that has no
+// private pool while PRIVATE_IP_POOLS is set.
+var ErrNoPrivateIPPool = errors.New("no private IP pool configured for region")
+
+// privatePoolFor returns the private pool for a region. Only when
+// PRIVATE_IP_POOLS is empty do servers share the default pool; otherwise a
+// region without a pool of its own is an error.
+func (s *ServerService) privatePoolFor(region string) (string, error) {
+	if len(s.config.PrivateIPPools) == 0 {
+		return DefaultIPPoolName, nil
+	}
+	if _, ok := s.config.PrivateIPPools[region]; ok {
+		return PrivateIPPoolName(region), nil
+	}
+	return "", fmt.Errorf("%w %q", ErrNoPrivateIPPool, region)
+}
+
+// assignPublicIP allocates a public address for the server and maps it 1:1 to
+// the server's primary private address. It is a no-op if a mapping is already active.
+func (s *ServerService) assignPublicIP(ctx context.Context, server sqlc.Server) (sqlc.NatMapping, error) {
+	active, err := s.queries.GetActiveNATMappingByServerID(ctx, server.ID)
+	if err == nil {
+		return active, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.NatMapping{}, fmt.Errorf("failed to get NAT mapping: %+v", err)
+	}
+
+	privateIP, err := s.primaryIP(ctx, server)
+	if err != nil {
+		return sqlc.NatMapping{}, err
+	}
+
+	publicIP, err := s.ipAllocator.AllocateIPFromPool(ctx, PublicIPPoolName)
+	if err != nil {
+		return sqlc.NatMapping{}, err
+	}
+	// Public addresses are bound to the server, not to an interface; the interface
+	// only ever sees the private side of the mapping.
+	if err := s.ipAllocator.saveAllocatedIP(ctx, server.ID, pgtype.UUID{}, false, publicIP.ID); err != nil {
+		if releaseErr := s.ipAllocator.ReleaseIP(ctx, publicIP); releaseErr != nil {
+			s.logger.Error("Failed to release public IP after bind failure", zap.Error(releaseErr), zap.String("ip_id", publicIP.ID.String()))
+		}
+		return sqlc.NatMapping{}, fmt.Errorf("failed to bind public IP address: %+v", err)
+	}
+
+	mapping, err := s.queries.CreateNATMapping(ctx, sqlc.CreateNATMappingParams{
+		ServerID:       server.ID,
+		PublicIpID:     publicIP.ID,
+		PrivateIpID:    privateIP.ID,
+		PublicAddress:  publicIP.Address,
+		PrivateAddress: privateIP.Address,
+	})
+	if err != nil {
+		if releaseErr := s.ipAllocator.ReleaseIP(ctx, publicIP); releaseErr != nil {
+			s.logger.Error("Failed to release public IP after NAT mapping failure", zap.Error(releaseErr), zap.String("ip_id", publicIP.ID.String()))
+		}
+		return sqlc.NatMapping{}, fmt.Errorf("failed to create NAT mapping: %+v", err)
+	}
+	if err := s.openResourceSegment(ctx, s.queries, server.ID, ResourcePublicIP, 1); err != nil {
+		s.logger.Error("Failed to start metering public IP", zap.Error(err), zap.String("server_id", server.ID.String()))
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventNetwork,
+		Message: "Public IP " + mapping.PublicAddress + " mapped to " + mapping.PrivateAddress,
+		Details: map[string]any{"publicAddress": mapping.PublicAddress, "privateAddress": mapping.PrivateAddress},
+	})
+
+	s.logger.Info("Public IP assigned",
+		zap.String("server_id", server.ID.String()),
+		zap.String("public_ip", mapping.PublicAddress),
+		zap.String("private_ip", mapping.PrivateAddress),
+	)
+	return mapping, nil
+}
+
+// releasePublicIP returns the server's public address to the public pool and closes its NAT mapping.
+// The private address is kept.
+func (s *ServerService) releasePublicIP(ctx context.Context, server sqlc.Server) error {
+	mapping, err := s.queries.GetActiveNATMappingByServerID(ctx, server.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get NAT mapping: %+v", err)
+	}
+
+	if err := s.queries.ReleaseNATMapping(ctx, mapping.ID); err != nil {
+		return fmt.Errorf("failed to release NAT mapping: %+v", err)
+	}
+	if err := s.closeResourceSegment(ctx, s.queries, server.ID, ResourcePublicIP); err != nil {
+		s.logger.Error("Failed to stop metering public IP", zap.Error(err), zap.String("server_id", server.ID.String()))
+	}
+	if mapping.PublicIpID.Valid {
+		if err := s.ipAllocator.ReleaseIP(ctx, sqlc.IpAddress{ID: mapping.PublicIpID}); err != nil {
+			return err
+		}
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventNetwork,
+		Message: "Public IP " + mapping.PublicAddress + " released",
+		Details: map[string]any{"publicAddress": mapping.PublicAddress},
+	})
+
+	s.logger.Info("Public IP released", zap.String("server_id", server.ID.String()), zap.String("public_ip", mapping.PublicAddress))
+	return nil
+}
+
+// primaryIP returns the primary address of the server's primary interface.
+func (s *ServerService) primaryIP(ctx context.Context, server sqlc.Server) (sqlc.IpAddress, error) {
+	ipAddresses, err := s.queries.ListIPAddressesByServerID(ctx, server.ID)
+	if err != nil {
+		return sqlc.IpAddress{}, fmt.Errorf("failed to get IP addresses by server ID: %+v", err)
+	}
+	for _, ipAddress := range ipAddresses {
+		if ipAddress.Address == server.Address {
+			return ipAddress, nil
+		}
+	}
+	return sqlc.IpAddress{}, fmt.Errorf("server %s has no primary IP address", server.ID.String())
+}
+
+// GetActiveNATMappings returns the active NAT mappings of the given servers.
+func (s *ServerService) GetActiveNATMappings(ctx context.Context, serverIDs ...pgtype.UUID) ([]sqlc.NatMapping, error) {
+	if len(serverIDs) == 0 {
+		return nil, nil
+	}
+	mappings, err := s.queries.ListActiveNATMappingsByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NAT mappings: %+v", err)
+	}
+	return mappings, nil
+}
+
+// ListNATMappings returns NAT mappings, optionally filtered by server or by public/private address.
+func (s *ServerService) ListNATMappings(ctx context.Context, params sqlc.ListNATMappingsParams) ([]sqlc.NatMapping, error) {
+	mappings, err := s.queries.ListNATMappings(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list NAT mappings: %+v", err)
+	}
+	return mappings, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+func TestPrivatePoolFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		pools    map[string]string
+		region   string
+		wantPool string
+		wantErr  error
+	}{
+		{name: "no private pools", region: "us-east-1", wantPool: DefaultIPPoolName},
+		{name: "region with a pool", pools: map[string]string{"us-east-1": "10.1.0.0/16"}, region: "us-east-1", wantPool: "private-us-east-1"},
+		{name: "region without a pool", pools: map[string]string{"us-east-1": "10.1.0.0/16"}, region: "eu-west-1", wantErr: ErrNoPrivateIPPool},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &ServerService{config: &config.Config{PrivateIPPools: tt.pools}}
+			got, err := s.privatePoolFor(tt.region)
+			if got != tt.wantPool || !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Errorf("privatePoolFor(%q) = %q, %v; want %q, %v", tt.region, got, err, tt.wantPool, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssignPublicIP(t *testing.T) {
+	server := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusRunning, Address: "10.0.0.2"}
+	active := sqlc.NatMapping{ID: testUUID(30), ServerID: server.ID, PublicAddress: "203.0.113.1", PrivateAddress: "10.0.0.2"}
+	primary := sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.2", InterfaceID: testUUID(10), IsPrimary: true}
+	secondary := sqlc.IpAddress{ID: testUUID(21), Address: "10.0.0.3", InterfaceID: testUUID(10)}
+	tests := []struct {
+		name     string
+		active   bool
+		bound    []any
+		missing  string // answer left out, so that statement fails
+		errs     map[string]error
+		wantFail bool
+		wantRan  []string
+		wantMap  bool
+	}{
+		{name: "already mapped", active: true, wantRan: []string{"GetActiveNATMappingByServerID"}},
+		{
+			name: "maps the primary address", bound: []any{secondary, primary},
+			wantRan: []string{
+				"GetActiveNATMappingByServerID", "ListIPAddressesByServerID", "ReserveIPAddress", "AllocateIPAddress",
+				"CreateNATMapping", "OpenResourceSegment", "RecordServerEvent",
+			},
+			wantMap: true,
+		},
+		{
+			name: "no primary address", bound: []any{secondary}, wantFail: true,
+			wantRan: []string{"GetActiveNATMappingByServerID", "ListIPAddressesByServerID"},
+		},
+		{
+			name: "mapping fails", bound: []any{primary}, missing: "CreateNATMapping", wantFail: true,
+			wantRan: []string{
+				"GetActiveNATMappingByServerID", "ListIPAddressesByServerID", "ReserveIPAddress", "AllocateIPAddress",
+				"CreateNATMapping", "DeallocateIPAddress",
+			},
+		},
+		{
+			name: "looking up the mapping fails", errs: map[string]error{"GetActiveNATMappingByServerID": errors.New("connection reset")}, wantFail: true,
+			wantRan: []string{"GetActiveNATMappingByServerID"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &networkDB{
+				rows: map[string][]any{"ListIPAddressesByServerID": tt.bound},
+				one: map[string]any{
+					"ReserveIPAddress":    sqlc.IpAddress{ID: testUUID(40), Address: "203.0.113.1"},
+					"AllocateIPAddress":   sqlc.IpAddress{ID: testUUID(40), Address: "203.0.113.1"},
+					"CreateNATMapping":    active,
+					"DeallocateIPAddress": sqlc.IpAddress{ID: testUUID(40), Address: "203.0.113.1", PoolID: testUUID(101)},
+				},
+				errs: tt.errs,
+			}
+			if tt.active {
+				db.one["GetActiveNATMappingByServerID"] = active
+			}
+			delete(db.one, tt.missing)
+
+			got, err := testNetworkService(t, db).assignPublicIP(context.Background(), server)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("assignPublicIP() error = %v; want error %v", err, tt.wantFail)
+			}
+			if strings.Join(db.names(), ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("assignPublicIP() ran %v; want %v", db.names(), tt.wantRan)
+			}
+			if err == nil && got != active {
+				t.Errorf("assignPublicIP() = %+v; want %+v", got, active)
+			}
+			if !tt.wantMap {
+				return
+			}
+			// The public address belongs to the server, on no interface
+			if args := db.args("AllocateIPAddress"); args[0] != server.ID || args[1] != (pgtype.UUID{}) || args[2] != false {
+				t.Errorf("assignPublicIP() bound the public address with %v; want the server, no interface, not primary", args)
+			}
+			if args := db.args("CreateNATMapping"); args[1] != testUUID(40) || args[2] != primary.ID || args[3] != "203.0.113.1" || args[4] != primary.Address {
+				t.Errorf("assignPublicIP() mapped %v; want 203.0.113.1 to the primary address %s", args, primary.Address)
+			}
+			if args := db.args("OpenResourceSegment"); args[1] != ResourcePublicIP {
+				t.Errorf("assignPublicIP() metered %v; want %s", args[1], ResourcePublicIP)
+			}
+		})
+	}
+}
+
+func TestReleasePublicIP(t *testing.T) {
+	server := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusStopped, Address: "10.0.0.2"}
+	active := sqlc.NatMapping{ID: testUUID(30), ServerID: server.ID, PublicIpID: testUUID(40), PublicAddress: "203.0.113.1", PrivateAddress: "10.0.0.2"}
+	tests := []struct {
+		name     string
+		active   bool
+		errs     map[string]error
+		wantFail bool
+		wantRan  []string
+	}{
+		{name: "no mapping", wantRan: []string{"GetActiveNATMappingByServerID"}},
+		{
+			name: "mapped", active: true,
+			wantRan: []string{"GetActiveNATMappingByServerID", "ReleaseNATMapping", "CloseResourceSegment", "DeallocateIPAddress", "RecordServerEvent"},
+		},
+		{
+			name: "releasing the mapping fails", active: true, errs: map[string]error{"ReleaseNATMapping": errors.New("connection reset")}, wantFail: true,
+			wantRan: []string{"GetActiveNATMappingByServerID", "ReleaseNATMapping"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &networkDB{
+				one:  map[string]any{"DeallocateIPAddress": sqlc.IpAddress{ID: testUUID(40), Address: "203.0.113.1", PoolID: testUUID(101)}},
+				errs: tt.errs,
+			}
+			if tt.active {
+				db.one["GetActiveNATMappingByServerID"] = active
+			}
+			err := testNetworkService(t, db).releasePublicIP(context.Background(), server)
+			if (err != nil) != tt.wantFail {
+				t.Fatalf("releasePublicIP() error = %v; want error %v", err, tt.wantFail)
+			}
+			if strings.Join(db.names(), ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("releasePublicIP() ran %v; want %v", db.names(), tt.wantRan)
+			}
+			if args := db.args("DeallocateIPAddress"); args != nil && args[0] != active.PublicIpID {
+				t.Errorf("releasePublicIP() released %v; want the public address %v", args[0], active.PublicIpID)
+			}
+		})
+	}
+}
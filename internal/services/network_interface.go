@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+var (
+	// ErrInterfaceNotFound is returned when an interface does not belong to the server.
+	ErrInterfaceNotFound = errors.New("network interface not found")
+	// ErrPrimaryInterface is returned when trying to detach a server's primary interface.
+	ErrPrimaryInterface = errors.New("the primary network interface cannot be detached")
+	// ErrServerTerminated is returned when changing the network of a terminated server.
+	ErrServerTerminated = errors.New("server is terminated")
+)
+
+// AttachNetworkInterface adds a secondary interface to the server with one address from poolName.
+func (s *ServerService) AttachNetworkInterface(ctx context.Context, server sqlc.Server, poolName string) (sqlc.NetworkInterface, error) {
+	if server.Status == util.ServerStatusTerminated {
+		return sqlc.NetworkInterface{}, ErrServerTerminated
+	}
+
+	deviceIndex, err := s.queries.GetNextDeviceIndex(ctx, server.ID)
+	if err != nil {
+		return sqlc.NetworkInterface{}, fmt.Errorf("failed to get next device index: %+v", err)
+	}
+
+	networkInterface, err := s.queries.CreateNetworkInterface(ctx, sqlc.CreateNetworkInterfaceParams{
+		ServerID:    server.ID,
+		DeviceIndex: deviceIndex,
+		IsPrimary:   false,
+	})
+	if err != nil {
+		return sqlc.NetworkInterface{}, fmt.Errorf("failed to create network interface: %+v", err)
+	}
+
+	if _, err := s.assignIP(ctx, server.ID, networkInterface.ID, true, poolName); err != nil {
+		if deleteErr := s.queries.DeleteNetworkInterface(ctx, networkInterface.ID); deleteErr != nil {
+			s.logger.Error("Failed to remove network interface after IP allocation failure",
+				zap.Error(deleteErr), zap.String("interface_id", networkInterface.ID.String()))
+		}
+		return sqlc.NetworkInterface{}, err
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventNetwork,
+		Message: "Network interface attached",
+		Details: map[string]any{"interfaceId": networkInterface.ID.String(), "deviceIndex": networkInterface.DeviceIndex},
+	})
+
+	s.logger.Info("Network interface attached",
+		zap.String("server_id", server.ID.String()),
+		zap.String("interface_id", networkInterface.ID.String()),
+		zap.Int32("device_index", networkInterface.DeviceIndex),
+	)
+	return networkInterface, nil
+}
+
+// DetachNetworkInterface releases every address of a secondary interface and removes it.
+func (s *ServerService) DetachNetworkInterface(ctx context.Context, server sqlc.Server, interfaceID pgtype.UUID) error {
+	if server.Status == util.ServerStatusTerminated {
+		return ErrServerTerminated
+	}
+
+	networkInterface, err := s.getNetworkInterface(ctx, server.ID, interfaceID)
+	if err != nil {
+		return err
+	}
+	if networkInterface.IsPrimary {
+		return ErrPrimaryInterface
+	}
+
+	ipAddresses, err := s.queries.ListIPAddressesByInterfaceID(ctx, networkInterface.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list interface IP addresses: %+v", err)
+	}
+	for _, ipData := range ipAddresses {
+		if err := s.ipAllocator.ReleaseIP(ctx, ipData); err != nil {
+			return err
+		}
+	}
+
+	if err := s.queries.DeleteNetworkInterface(ctx, networkInterface.ID); err != nil {
+		return fmt.Errorf("failed to delete network interface: %+v", err)
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventNetwork,
+		Message: "Network interface detached",
+		Details: map[string]any{"interfaceId": networkInterface.ID.String(), "deviceIndex": networkInterface.DeviceIndex},
+	})
+
+	s.logger.Info("Network interface detached",
+		zap.String("server_id", server.ID.String()),
+		zap.String("interface_id", networkInterface.ID.String()),
+		zap.Int("released_ips", len(ipAddresses)),
+	)
+	return nil
+}
+
+// AssignSecondaryIP adds another address from poolName to an existing interface.
+func (s *ServerService) AssignSecondaryIP(ctx context.Context, server sqlc.Server, interfaceID pgtype.UUID, poolName string) (sqlc.IpAddress, error) {
+	if server.Status == util.ServerStatusTerminated {
+		return sqlc.IpAddress{}, ErrServerTerminated
+	}
+
+	networkInterface, err := s.getNetworkInterface(ctx, server.ID, interfaceID)
+	if err != nil {
+		return sqlc.IpAddress{}, err
+	}
+
+	ipAddress, err := s.assignIP(ctx, server.ID, networkInterface.ID, false, poolName)
+	if err != nil {
+		return sqlc.IpAddress{}, err
+	}
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventNetwork,
+		Message: "Secondary IP " + ipAddress.Address + " assigned",
+		Details: map[string]any{"interfaceId": networkInterface.ID.String(), "address": ipAddress.Address},
+	})
+
+	s.logger.Info("Secondary IP assigned",
+		zap.String("server_id", server.ID.String()),
+		zap.String("interface_id", networkInterface.ID.String()),
+		zap.String("ip_address", ipAddress.Address),
+	)
+	return ipAddress, nil
+}
+
+// GetNetworkInterfaces returns the interfaces and bound addresses of the given servers.
+func (s *ServerService) GetNetworkInterfaces(ctx context.Context, serverIDs ...pgtype.UUID) ([]sqlc.NetworkInterface, []sqlc.ListIPAddressesByServerIDsRow, error) {
+	if len(serverIDs) == 0 {
+		return nil, nil, nil
+	}
+	interfaces, err := s.queries.ListNetworkInterfacesByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list network interfaces: %+v", err)
+	}
+	ipAddresses, err := s.queries.ListIPAddressesByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list IP addresses: %+v", err)
+	}
+	return interfaces, ipAddresses, nil
+}
+
+// assignIP allocates an address from poolName and binds it to the interface.
+func (s *ServerService) assignIP(ctx context.Context, serverID pgtype.UUID, interfaceID pgtype.UUID, isPrimary bool, poolName string) (sqlc.IpAddress, error) {
+	if poolName == "" {
+		poolName = DefaultIPPoolName
+	}
+
+	allocatedIP, err := s.ipAllocator.AllocateIPFromPool(ctx, poolName)
+	if err != nil {
+		return sqlc.IpAddress{}, err
+	}
+
+	if err := s.ipAllocator.saveAllocatedIP(ctx, serverID, interfaceID, isPrimary, allocatedIP.ID); err != nil {
+		if releaseErr := s.ipAllocator.ReleaseIP(ctx, allocatedIP); releaseErr != nil {
+			s.logger.Error("Failed to release IP after bind failure", zap.Error(releaseErr), zap.String("ip_id", allocatedIP.ID.String()))
+		}
+		return sqlc.IpAddress{}, fmt.Errorf("failed to bind IP address: %+v", err)
+	}
+	allocatedIP.ServerID = serverID
+	allocatedIP.InterfaceID = interfaceID
+	allocatedIP.IsPrimary = isPrimary
+	return allocatedIP, nil
+}
+
+func (s *ServerService) getNetworkInterface(ctx context.Context, serverID pgtype.UUID, interfaceID pgtype.UUID) (sqlc.NetworkInterface, error) {
+	networkInterface, err := s.queries.GetNetworkInterface(ctx, sqlc.GetNetworkInterfaceParams{
+		ID:       interfaceID,
+		ServerID: serverID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.NetworkInterface{}, ErrInterfaceNotFound
+	}
+	if err != nil {
+		return sqlc.NetworkInterface{}, fmt.Errorf("failed to get network interface: %+v", err)
+	}
+	return networkInterface, nil
+}
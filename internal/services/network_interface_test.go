@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/ipam"
+	"go-virtual-server/internal/util"
+)
+
+// networkDB answers statements by sqlc query name: listings with rows[name] and
+// single rows with one[name], or fails them with errs[name]. Single rows it has
+// no answer for fail as if gone, and Exec succeeds. Every statement is recorded
+// with its arguments in the order it ran.
+type networkDB struct {
+	rows map[string][]any
+	one  map[string]any
+	errs map[string]error
+	ran  []statement
+}
+
+func (db *networkDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	return pgconn.CommandTag{}, db.errs[name]
+}
+
+func (db *networkDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	if err, ok := db.errs[name]; ok {
+		return nil, err
+	}
+	return &structRows{rows: db.rows[name]}, nil
+}
+
+func (db *networkDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	if err, ok := db.errs[name]; ok {
+		return serverRow{err: err}
+	}
+	if row, ok := db.one[name]; ok {
+		return &structRows{rows: []any{row}, next: 1}
+	}
+	return serverRow{err: pgx.ErrNoRows}
+}
+
+// names returns the names of the statements that ran, in order.
+func (db *networkDB) names() []string {
+	names := make([]string, len(db.ran))
+	for i, stmt := range db.ran {
+		names[i] = stmt.name
+	}
+	return names
+}
+
+// args returns the arguments of the first statement of that name.
+func (db *networkDB) args(name string) []interface{} {
+	for _, stmt := range db.ran {
+		if stmt.name == name {
+			return stmt.args
+		}
+	}
+	return nil
+}
+
+// testNetworkService returns a ServerService over db with the default pool and
+// the public pool registered, each a /29.
+func testNetworkService(t *testing.T, db *networkDB) *ServerService {
+	t.Helper()
+	queries := sqlc.New(db)
+	allocator := NewIPAllocator(queries, zap.NewNop())
+	for i, pool := range []sqlc.IpPool{
+		{Name: DefaultIPPoolName, Cidr: "10.0.0.0/29"},
+		{Name: PublicIPPoolName, Cidr: "203.0.113.0/29"},
+	} {
+		addrs, err := ipam.NewRange(pool.Cidr, nil, mustStrategy(t, ipam.StrategySequential))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.ID = testUUID(byte(100 + i))
+		allocator.pools[pool.Name] = &ipPool{pool: pool, addrs: addrs}
+	}
+	return &ServerService{queries: queries, logger: zap.NewNop(), config: &config.Config{}, ipAllocator: allocator}
+}
+
+func mustStrategy(t *testing.T, name string) ipam.Strategy {
+	t.Helper()
+	strategy, err := ipam.NewStrategy(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strategy
+}
+
+func TestAttachNetworkInterface(t *testing.T) {
+	running := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusRunning}
+	answers := map[string]any{
+		"GetNextDeviceIndex":     int32(1),
+		"CreateNetworkInterface": sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, DeviceIndex: 1},
+		"ReserveIPAddress":       sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.1"},
+		"AllocateIPAddress":      sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.1"},
+	}
+	tests := []struct {
+		name     string
+		server   sqlc.Server
+		pool     string
+		missing  string // answer left out, so that statement fails
+		wantErr  error
+		wantFail bool // an error that wraps no sentinel
+		wantRan  []string
+		wantBind bool
+	}{
+		{
+			name: "secondary interface with its own primary address", server: running,
+			wantRan:  []string{"GetNextDeviceIndex", "CreateNetworkInterface", "ReserveIPAddress", "AllocateIPAddress", "RecordServerEvent"},
+			wantBind: true,
+		},
+		{
+			name: "unknown pool", server: running, pool: "nope", wantErr: ErrUnknownIPPool,
+			wantRan: []string{"GetNextDeviceIndex", "CreateNetworkInterface", "DeleteNetworkInterface"},
+		},
+		{
+			name: "binding fails", server: running, missing: "AllocateIPAddress", wantFail: true,
+			wantRan: []string{"GetNextDeviceIndex", "CreateNetworkInterface", "ReserveIPAddress", "AllocateIPAddress", "DeallocateIPAddress", "DeleteNetworkInterface"},
+		},
+		{name: "terminated server", server: sqlc.Server{ID: testUUID(1), Status: util.ServerStatusTerminated}, wantErr: ErrServerTerminated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &networkDB{one: map[string]any{}}
+			for name, answer := range answers {
+				if name != tt.missing {
+					db.one[name] = answer
+				}
+			}
+			got, err := testNetworkService(t, db).AttachNetworkInterface(context.Background(), tt.server, tt.pool)
+			if (err != nil) != (tt.wantErr != nil || tt.wantFail) || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+				t.Fatalf("AttachNetworkInterface() error = %v; want %v", err, tt.wantErr)
+			}
+			if strings.Join(db.names(), ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("AttachNetworkInterface() ran %v; want %v", db.names(), tt.wantRan)
+			}
+			if !tt.wantBind {
+				return
+			}
+			if args := db.args("CreateNetworkInterface"); args[2] != false {
+				t.Errorf("AttachNetworkInterface() created an interface with is_primary %v; want false", args[2])
+			}
+			if args := db.args("AllocateIPAddress"); args[1] != got.ID || args[2] != true {
+				t.Errorf("AttachNetworkInterface() bound the address to %v with is_primary %v; want %v and true", args[1], args[2], got.ID)
+			}
+		})
+	}
+}
+
+func TestDetachNetworkInterface(t *testing.T) {
+	running := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusRunning}
+	secondary := sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, DeviceIndex: 1}
+	tests := []struct {
+		name             string
+		server           sqlc.Server
+		networkInterface any
+		wantErr          error
+		wantRan          []string
+	}{
+		{
+			name: "secondary interface", server: running, networkInterface: secondary,
+			wantRan: []string{"GetNetworkInterface", "ListIPAddressesByInterfaceID", "DeallocateIPAddress", "DeallocateIPAddress", "DeleteNetworkInterface", "RecordServerEvent"},
+		},
+		{
+			name: "primary interface", server: running, networkInterface: sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, IsPrimary: true},
+			wantErr: ErrPrimaryInterface, wantRan: []string{"GetNetworkInterface"},
+		},
+		{name: "interface of another server", server: running, wantErr: ErrInterfaceNotFound, wantRan: []string{"GetNetworkInterface"}},
+		{name: "terminated server", server: sqlc.Server{ID: testUUID(1), Status: util.ServerStatusTerminated}, networkInterface: secondary, wantErr: ErrServerTerminated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &networkDB{
+				rows: map[string][]any{"ListIPAddressesByInterfaceID": {
+					sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.2", InterfaceID: secondary.ID, IsPrimary: true},
+					sqlc.IpAddress{ID: testUUID(21), Address: "10.0.0.3", InterfaceID: secondary.ID},
+				}},
+				one: map[string]any{"DeallocateIPAddress": sqlc.IpAddress{Address: "10.0.0.2", PoolID: testUUID(100)}},
+			}
+			if tt.networkInterface != nil {
+				db.one["GetNetworkInterface"] = tt.networkInterface
+			}
+			err := testNetworkService(t, db).DetachNetworkInterface(context.Background(), tt.server, secondary.ID)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("DetachNetworkInterface() error = %v; want %v", err, tt.wantErr)
+			}
+			if strings.Join(db.names(), ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("DetachNetworkInterface() ran %v; want %v", db.names(), tt.wantRan)
+			}
+		})
+	}
+}
+
+func TestAssignSecondaryIP(t *testing.T) {
+	running := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusRunning}
+	tests := []struct {
+		name             string
+		server           sqlc.Server
+		networkInterface any
+		pool             string
+		wantErr          error
+		wantRan          []string
+	}{
+		{
+			name: "on the primary interface", server: running,
+			networkInterface: sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, IsPrimary: true},
+			wantRan:          []string{"GetNetworkInterface", "ReserveIPAddress", "AllocateIPAddress", "RecordServerEvent"},
+		},
+		{
+			name: "on a secondary interface", server: running,
+			networkInterface: sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, DeviceIndex: 1},
+			wantRan:          []string{"GetNetworkInterface", "ReserveIPAddress", "AllocateIPAddress", "RecordServerEvent"},
+		},
+		{
+			name: "unknown pool", server: running, pool: "nope",
+			networkInterface: sqlc.NetworkInterface{ID: testUUID(10), ServerID: running.ID, IsPrimary: true},
+			wantErr:          ErrUnknownIPPool, wantRan: []string{"GetNetworkInterface"},
+		},
+		{name: "interface of another server", server: running, wantErr: ErrInterfaceNotFound, wantRan: []string{"GetNetworkInterface"}},
+		{name: "terminated server", server: sqlc.Server{ID: testUUID(1), Status: util.ServerStatusTerminated}, wantErr: ErrServerTerminated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &networkDB{one: map[string]any{
+				"ReserveIPAddress":  sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.1"},
+				"AllocateIPAddress": sqlc.IpAddress{ID: testUUID(20), Address: "10.0.0.1"},
+			}}
+			if tt.networkInterface != nil {
+				db.one["GetNetworkInterface"] = tt.networkInterface
+			}
+			got, err := testNetworkService(t, db).AssignSecondaryIP(context.Background(), tt.server, testUUID(10), tt.pool)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("AssignSecondaryIP() error = %v; want %v", err, tt.wantErr)
+			}
+			if strings.Join(db.names(), ",") != strings.Join(tt.wantRan, ",") {
+				t.Errorf("AssignSecondaryIP() ran %v; want %v", db.names(), tt.wantRan)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			// A secondary address is never the interface's primary, whichever interface it is on
+			if args := db.args("AllocateIPAddress"); args[1] != testUUID(10) || args[2] != false {
+				t.Errorf("AssignSecondaryIP() bound the address to %v with is_primary %v; want %v and false", args[1], args[2], testUUID(10))
+			}
+			if got.Address != "10.0.0.1" || got.InterfaceID != testUUID(10) || got.IsPrimary {
+				t.Errorf("AssignSecondaryIP() = %s on %v, primary %v; want 10.0.0.1 on %v, not primary", got.Address, got.InterfaceID, got.IsPrimary, testUUID(10))
+			}
+		})
+	}
+}
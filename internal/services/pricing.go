@@ -0,0 +1,346 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+const (
+	// PriceRegionAny is the catalog region that prices every region without a price of its own.
+	PriceRegionAny = "*"
+)
+
+var (
+	// ErrNoPrice is returned when the catalog has no price for a server type in a region.
+	ErrNoPrice = errors.New("no price is defined for this server type and region")
+	// ErrPriceNotInFuture is returned when a new price version would change already-metered usage.
+	ErrPriceNotInFuture = errors.New("a price version must take effect in the future")
+	// ErrPriceExists is returned when a version of the same type and region already takes effect at that time.
+	ErrPriceExists = errors.New("a price version already takes effect at this time")
+	// ErrInvalidQuote is returned when a quote asks for no servers or no time.
+	ErrInvalidQuote = errors.New("count and hours must be positive")
+)
+
+type priceKey struct {
+	serverType string
+	region     string
+}
+
+// PriceCatalog holds every price version, ordered by effective time per type and region.
+type PriceCatalog struct {
+	versions map[priceKey][]sqlc.Price
+}
+
+// PriceSlice is a span of time billed at a single hourly rate.
+type PriceSlice struct {
+	Start time.Time
+	End   time.Time
+	Rate  float64
+}
+
+// PriceVersion is a catalog entry together with the time the next version replaces it.
+type PriceVersion struct {
+	Price          sqlc.Price
+	EffectiveUntil pgtype.Timestamptz
+}
+
+// LoadPriceCatalog reads the whole catalog; it holds a handful of rows per server type.
+func LoadPriceCatalog(ctx context.Context, queries *sqlc.Queries) (PriceCatalog, error) {
+	prices, err := queries.ListPrices(ctx)
+	if err != nil {
+		return PriceCatalog{}, fmt.Errorf("failed to list prices: %+v", err)
+	}
+
+	catalog := PriceCatalog{versions: make(map[priceKey][]sqlc.Price)}
+	for _, price := range prices {
+		key := priceKey{price.ServerType, price.Region}
+		catalog.versions[key] = append(catalog.versions[key], price)
+	}
+	return catalog, nil
+}
+
+// RateAt returns the hourly rate of a server type in a region at time t. The
+// region's own price wins over the all-regions price.
+func (c PriceCatalog) RateAt(serverType, region string, t time.Time) (float64, error) {
+	for _, candidate := range []string{region, PriceRegionAny} {
+		versions := c.versions[priceKey{serverType, candidate}]
+		for i := len(versions) - 1; i >= 0; i-- {
+			if !versions[i].EffectiveFrom.Time.After(t) {
+				return versions[i].HourlyRate, nil
+			}
+		}
+	}
+	return 0, ErrNoPrice
+}
+
+// Slices splits [from, to) wherever the rate of a server type in a region changes.
+func (c PriceCatalog) Slices(serverType, region string, from, to time.Time) ([]PriceSlice, error) {
+	boundaries := []time.Time{to}
+	for _, candidate := range []string{region, PriceRegionAny} {
+		for _, version := range c.versions[priceKey{serverType, candidate}] {
+			if effective := version.EffectiveFrom.Time; effective.After(from) && effective.Before(to) {
+				boundaries = append(boundaries, effective)
+			}
+		}
+	}
+	sort.Slice(boundaries, func(i, j int) bool { return boundaries[i].Before(boundaries[j]) })
+
+	var slices []PriceSlice
+	start := from
+	for _, end := range boundaries {
+		if !end.After(start) {
+			continue
+		}
+		rate, err := c.RateAt(serverType, region, start)
+		if err != nil {
+			return nil, err
+		}
+		// A version that repeats the previous rate does not need its own slice
+		if n := len(slices); n > 0 && slices[n-1].Rate == rate {
+			slices[n-1].End = end
+		} else {
+			slices = append(slices, PriceSlice{Start: start, End: end, Rate: rate})
+		}
+		start = end
+	}
+	return slices, nil
+}
+
+// Cost prices [from, to) of a server type in a region, applying each rate to the hours it was in effect.
+func (c PriceCatalog) Cost(serverType, region string, from, to time.Time) (float64, error) {
+	slices, err := c.Slices(serverType, region, from, to)
+	if err != nil {
+		return 0, err
+	}
+	var cost float64
+	for _, slice := range slices {
+		cost += slice.End.Sub(slice.Start).Hours() * slice.Rate
+	}
+	return cost, nil
+}
+
+// Versions lists the catalog entries matching serverType and region (empty matches
+// any), each with the time it is replaced. If at is set, only the versions in
+// effect at that time are returned.
+func (c PriceCatalog) Versions(serverType, region string, at *time.Time) []PriceVersion {
+	var result []PriceVersion
+	for key, versions := range c.versions {
+		if (serverType != "" && key.serverType != serverType) || (region != "" && key.region != region) {
+			continue
+		}
+		for i, price := range versions {
+			version := PriceVersion{Price: price}
+			if i+1 < len(versions) {
+				version.EffectiveUntil = versions[i+1].EffectiveFrom
+			}
+			if at != nil && !inEffect(version, *at) {
+				continue
+			}
+			result = append(result, version)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].Price, result[j].Price
+		if a.ServerType != b.ServerType {
+			return a.ServerType < b.ServerType
+		}
+		if a.Region != b.Region {
+			return a.Region < b.Region
+		}
+		return a.EffectiveFrom.Time.Before(b.EffectiveFrom.Time)
+	})
+	return result
+}
+
+func inEffect(version PriceVersion, t time.Time) bool {
+	return !version.Price.EffectiveFrom.Time.After(t) &&
+		(!version.EffectiveUntil.Valid || version.EffectiveUntil.Time.After(t))
+}
+
+// SeedPrices writes the SERVER_TYPE_WISE_PRICING rates to the catalog as
+// all-regions prices, for types that do not have one yet. Later price changes go
+// through the catalog, not the configuration.
+func (b *BillingService) SeedPrices(ctx context.Context) error {
+	for serverType, rate := range b.config.ServerTypeWisePricing {
+		if !util.IsValidServerType(serverType) {
+			b.logger.Warn("Ignoring price for unknown server type", zap.String("type", serverType))
+			continue
+		}
+		if err := b.db.Queries.SeedPrice(ctx, sqlc.SeedPriceParams{ServerType: serverType, HourlyRate: rate}); err != nil {
+			return fmt.Errorf("failed to seed price of %s: %+v", serverType, err)
+		}
+	}
+
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return err
+	}
+	for _, serverType := range []string{util.ServerTypeT2Micro, util.ServerTypeM5Large, util.ServerTypeC5Xlarge} {
+		if len(catalog.versions[priceKey{serverType, PriceRegionAny}]) == 0 {
+			b.logger.Warn("Server type has no all-regions price; it can only be provisioned in regions with their own price",
+				zap.String("type", serverType))
+		}
+	}
+	return nil
+}
+
+// CreatePrice schedules a new price version. Versions only take effect in the
+// future, so usage that has already been metered keeps its price.
+func (b *BillingService) CreatePrice(ctx context.Context, serverType, region string, hourlyRate float64, effectiveFrom time.Time) (PriceVersion, error) {
+	if !effectiveFrom.After(time.Now()) {
+		return PriceVersion{}, ErrPriceNotInFuture
+	}
+	if region == "" {
+		region = PriceRegionAny
+	}
+
+	price, err := b.db.Queries.CreatePrice(ctx, sqlc.CreatePriceParams{
+		ServerType:    serverType,
+		Region:        region,
+		HourlyRate:    hourlyRate,
+		Currency:      CurrencyUSD,
+		EffectiveFrom: pgtype.Timestamptz{Time: effectiveFrom, Valid: true},
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return PriceVersion{}, ErrPriceExists
+	}
+	if err != nil {
+		return PriceVersion{}, fmt.Errorf("failed to create price: %+v", err)
+	}
+
+	b.logger.Info("Price version scheduled",
+		zap.String("price_id", price.ID.String()),
+		zap.String("type", price.ServerType),
+		zap.String("region", price.Region),
+		zap.Float64("hourly_rate", price.HourlyRate),
+		zap.Time("effective_from", price.EffectiveFrom.Time),
+	)
+
+	// A version scheduled between existing ones ends where the next one starts
+	versions, err := b.ListPrices(ctx, price.ServerType, price.Region, nil)
+	if err != nil {
+		return PriceVersion{}, err
+	}
+	for _, version := range versions {
+		if version.Price.ID == price.ID {
+			return version, nil
+		}
+	}
+	return PriceVersion{Price: price}, nil
+}
+
+// ListPrices returns the catalog entries matching serverType and region; see PriceCatalog.Versions.
+func (b *BillingService) ListPrices(ctx context.Context, serverType, region string, at *time.Time) ([]PriceVersion, error) {
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return nil, err
+	}
+	return catalog.Versions(serverType, region, at), nil
+}
+
+// Quote is the expected cost of running servers of one type in one region for a
+// number of hours from now, broken down by the price versions in effect.
+type Quote struct {
+	ServerType   string
+	Region       string
+	BillingModel string
+	Count        int
+	Hours        float64
+	Slices       []PriceSlice
+	// BilledHours is the time the billing model charges for each server.
+	BilledHours float64
+	// ReservedHours is the time of all servers the project's reservations cover.
+	ReservedHours float64
+	// UnitCost is the cost of one server without reservations; Total the cost of
+	// all of them, less the hours reservations cover.
+	UnitCost float64
+	Total    float64
+}
+
+// QuotePrice prices count servers running for the given hours from now, including
+// any price change already scheduled in that window. Each server's time is charged
+// as its billing model would; under BillingModelReserved the project's remaining
+// reserved hours are drawn down first.
+func (b *BillingService) QuotePrice(ctx context.Context, project, serverType, region string, count int, hours float64, billingModel string, now time.Time) (Quote, error) {
+	if billingModel == "" {
+		billingModel = BillingModelPerSecond
+	}
+	if !IsValidBillingModel(billingModel) {
+		return Quote{}, ErrInvalidBillingModel
+	}
+	if count <= 0 || hours <= 0 {
+		return Quote{}, ErrInvalidQuote
+	}
+	if project == "" {
+		project = DefaultProject
+	}
+
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return Quote{}, err
+	}
+	quote, err := priceQuote(catalog, Quote{
+		ServerType:   serverType,
+		Region:       region,
+		BillingModel: billingModel,
+		Count:        count,
+		Hours:        hours,
+	}, now)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	if billingModel == BillingModelReserved {
+		remaining, err := b.db.Queries.SumRemainingReservationHours(ctx, sqlc.SumRemainingReservationHoursParams{
+			Project:    project,
+			ServerType: serverType,
+			Region:     region,
+			At:         pgtype.Timestamptz{Time: now, Valid: true},
+		})
+		if err != nil {
+			return Quote{}, fmt.Errorf("failed to sum reserved hours: %+v", err)
+		}
+		totalHours := hours * float64(count)
+		quote.ReservedHours = min(remaining, totalHours)
+		quote.Total *= 1 - quote.ReservedHours/totalHours
+	}
+	return quote, nil
+}
+
+// priceQuote fills in the slices, billed hours and costs of a quote for servers
+// starting at now, before reservations.
+func priceQuote(catalog PriceCatalog, quote Quote, now time.Time) (Quote, error) {
+	metered := time.Duration(quote.Hours * float64(time.Hour))
+	end := now.Add(metered)
+	slices, err := catalog.Slices(quote.ServerType, quote.Region, now, end)
+	if err != nil {
+		return Quote{}, err
+	}
+
+	billed := BilledDuration(quote.BillingModel, metered)
+	quote.Slices = slices
+	quote.BilledHours = billed.Hours()
+	for _, slice := range slices {
+		quote.UnitCost += slice.End.Sub(slice.Start).Hours() * slice.Rate
+	}
+	if extra := billed - metered; extra > 0 {
+		rate, err := catalog.RateAt(quote.ServerType, quote.Region, end)
+		if err != nil {
+			return Quote{}, err
+		}
+		quote.UnitCost += extra.Hours() * rate
+	}
+	quote.Total = quote.UnitCost * float64(quote.Count)
+	return quote, nil
+}
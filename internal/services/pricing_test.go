@@ -0,0 +1,185 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// pricingTestCatalog prices t2.micro everywhere at 0.01, then 0.02 from March;
+// us-west-2 gets a price of its own in the middle of March, and a version in
+// April that repeats it.
+func pricingTestCatalog(t *testing.T) PriceCatalog {
+	return testCatalog(
+		testPrice("t2.micro", PriceRegionAny, 0.01, mustTime(t, "2026-01-01T00:00:00Z")),
+		testPrice("t2.micro", PriceRegionAny, 0.02, mustTime(t, "2026-03-01T00:00:00Z")),
+		testPrice("t2.micro", "us-west-2", 0.03, mustTime(t, "2026-03-15T00:00:00Z")),
+		testPrice("t2.micro", "us-west-2", 0.03, mustTime(t, "2026-04-01T00:00:00Z")),
+	)
+}
+
+func TestPriceCatalogRateAt(t *testing.T) {
+	catalog := pricingTestCatalog(t)
+	tests := []struct {
+		name       string
+		serverType string
+		region     string
+		at         string
+		want       float64
+		wantErr    error
+	}{
+		{name: "all-regions price", serverType: "t2.micro", region: "us-east-1", at: "2026-02-10T00:00:00Z", want: 0.01},
+		{name: "new version from its effective time", serverType: "t2.micro", region: "us-east-1", at: "2026-03-01T00:00:00Z", want: 0.02},
+		{name: "just before a new version", serverType: "t2.micro", region: "us-east-1", at: "2026-02-28T23:59:59Z", want: 0.01},
+		{name: "region before its own price", serverType: "t2.micro", region: "us-west-2", at: "2026-03-14T00:00:00Z", want: 0.02},
+		{name: "region's own price wins", serverType: "t2.micro", region: "us-west-2", at: "2026-03-15T00:00:00Z", want: 0.03},
+		{name: "before the first version", serverType: "t2.micro", region: "us-east-1", at: "2025-12-31T00:00:00Z", wantErr: ErrNoPrice},
+		{name: "unpriced type", serverType: "m5.large", region: "us-east-1", at: "2026-02-10T00:00:00Z", wantErr: ErrNoPrice},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := catalog.RateAt(tt.serverType, tt.region, mustTime(t, tt.at))
+			if !errors.Is(err, tt.wantErr) || got != tt.want {
+				t.Errorf("RateAt(%s, %s, %s) = %v, %v; want %v, %v", tt.serverType, tt.region, tt.at, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPriceCatalogSlices(t *testing.T) {
+	catalog := pricingTestCatalog(t)
+	tests := []struct {
+		name   string
+		region string
+		from   string
+		to     string
+		want   []PriceSlice
+	}{
+		{
+			name: "single version", region: "us-east-1", from: "2026-02-01T00:00:00Z", to: "2026-02-02T00:00:00Z",
+			want: []PriceSlice{{mustTime(t, "2026-02-01T00:00:00Z"), mustTime(t, "2026-02-02T00:00:00Z"), 0.01}},
+		},
+		{
+			name: "split where the price changes", region: "us-east-1", from: "2026-02-28T20:00:00Z", to: "2026-03-01T04:00:00Z",
+			want: []PriceSlice{
+				{mustTime(t, "2026-02-28T20:00:00Z"), mustTime(t, "2026-03-01T00:00:00Z"), 0.01},
+				{mustTime(t, "2026-03-01T00:00:00Z"), mustTime(t, "2026-03-01T04:00:00Z"), 0.02},
+			},
+		},
+		{
+			name: "change at the start is not a boundary", region: "us-east-1", from: "2026-03-01T00:00:00Z", to: "2026-03-02T00:00:00Z",
+			want: []PriceSlice{{mustTime(t, "2026-03-01T00:00:00Z"), mustTime(t, "2026-03-02T00:00:00Z"), 0.02}},
+		},
+		{
+			name: "all-regions and regional versions", region: "us-west-2", from: "2026-02-28T00:00:00Z", to: "2026-03-16T00:00:00Z",
+			want: []PriceSlice{
+				{mustTime(t, "2026-02-28T00:00:00Z"), mustTime(t, "2026-03-01T00:00:00Z"), 0.01},
+				{mustTime(t, "2026-03-01T00:00:00Z"), mustTime(t, "2026-03-15T00:00:00Z"), 0.02},
+				{mustTime(t, "2026-03-15T00:00:00Z"), mustTime(t, "2026-03-16T00:00:00Z"), 0.03},
+			},
+		},
+		{
+			name: "repeated rate is merged", region: "us-west-2", from: "2026-03-20T00:00:00Z", to: "2026-04-10T00:00:00Z",
+			want: []PriceSlice{{mustTime(t, "2026-03-20T00:00:00Z"), mustTime(t, "2026-04-10T00:00:00Z"), 0.03}},
+		},
+		{name: "empty range", region: "us-east-1", from: "2026-03-01T00:00:00Z", to: "2026-03-01T00:00:00Z"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := catalog.Slices("t2.micro", tt.region, mustTime(t, tt.from), mustTime(t, tt.to))
+			if err != nil {
+				t.Fatalf("Slices failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Slices = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Start.Equal(tt.want[i].Start) || !got[i].End.Equal(tt.want[i].End) || got[i].Rate != tt.want[i].Rate {
+					t.Errorf("slice %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	if _, err := catalog.Slices("t2.micro", "us-east-1", mustTime(t, "2025-12-31T00:00:00Z"), mustTime(t, "2026-01-02T00:00:00Z")); !errors.Is(err, ErrNoPrice) {
+		t.Errorf("Slices starting before the first version = %v, want ErrNoPrice", err)
+	}
+}
+
+func TestPriceCatalogCost(t *testing.T) {
+	catalog := pricingTestCatalog(t)
+	got, err := catalog.Cost("t2.micro", "us-east-1", mustTime(t, "2026-02-28T20:00:00Z"), mustTime(t, "2026-03-01T04:00:00Z"))
+	if err != nil {
+		t.Fatalf("Cost failed: %v", err)
+	}
+	if want := 4*0.01 + 4*0.02; got != want {
+		t.Errorf("Cost = %v, want %v", got, want)
+	}
+}
+
+func TestPriceCatalogVersions(t *testing.T) {
+	catalog := pricingTestCatalog(t)
+	at := mustTime(t, "2026-03-20T00:00:00Z")
+	tests := []struct {
+		name   string
+		region string
+		at     *time.Time
+		want   []string // region and effective time of each version, in order
+	}{
+		{
+			name: "every version",
+			want: []string{"* 2026-01-01", "* 2026-03-01", "us-west-2 2026-03-15", "us-west-2 2026-04-01"},
+		},
+		{name: "one region", region: "us-west-2", want: []string{"us-west-2 2026-03-15", "us-west-2 2026-04-01"}},
+		{name: "in effect", at: &at, want: []string{"* 2026-03-01", "us-west-2 2026-03-15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			versions := catalog.Versions("t2.micro", tt.region, tt.at)
+			var got []string
+			for _, version := range versions {
+				got = append(got, version.Price.Region+" "+version.Price.EffectiveFrom.Time.Format(time.DateOnly))
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Versions = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Versions = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	// Each version is replaced by the next of its type and region only
+	versions := catalog.Versions("t2.micro", PriceRegionAny, nil)
+	if !versions[0].EffectiveUntil.Time.Equal(mustTime(t, "2026-03-01T00:00:00Z")) || versions[1].EffectiveUntil.Valid {
+		t.Errorf("EffectiveUntil = %v, %v; want 2026-03-01 and none", versions[0].EffectiveUntil, versions[1].EffectiveUntil)
+	}
+}
+
+func TestUsageEntriesPriceChange(t *testing.T) {
+	catalog := pricingTestCatalog(t)
+	current := mustTime(t, "2026-04-01T00:00:00Z")
+	segment := testSegment(BillingModelPerSecond, mustTime(t, "2026-02-28T22:00:00Z"), mustTime(t, "2026-03-15T02:00:00Z"), mustTime(t, "2026-02-28T22:00:00Z"))
+	segment.Region = "us-west-2"
+	entries, err := usageEntries(catalog, nil, []sqlc.Reservation{}, segment, segment.EndedAt.Time, current)
+	if err != nil {
+		t.Fatalf("usageEntries failed: %v", err)
+	}
+	checkEntries(t, entries, []wantEntry{
+		{ChargeTypeCompute, "2026-02-01T00:00:00Z", "2026-02-28T22:00:00Z", "2026-03-01T00:00:00Z", 2, 0.01},
+		{ChargeTypeCompute, "2026-03-01T00:00:00Z", "2026-03-01T00:00:00Z", "2026-03-15T00:00:00Z", 336, 0.02},
+		{ChargeTypeCompute, "2026-03-01T00:00:00Z", "2026-03-15T00:00:00Z", "2026-03-15T02:00:00Z", 2, 0.03},
+	})
+}
+
+func TestCreatePriceInPast(t *testing.T) {
+	var b BillingService
+	if _, err := b.CreatePrice(context.Background(), "t2.micro", "", 0.01, time.Now()); !errors.Is(err, ErrPriceNotInFuture) {
+		t.Errorf("CreatePrice taking effect now = %v, want ErrPriceNotInFuture", err)
+	}
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+const (
+	// ReaperConditionStoppedFor matches servers stopped for the policy's duration.
+	ReaperConditionStoppedFor = "stopped_for"
+	// ReaperConditionRunningFor matches servers running for the policy's duration.
+	ReaperConditionRunningFor = "running_for"
+	// ReaperConditionIdleFor matches servers running for the policy's duration
+	// whose CPU averaged at most its max CPU percent over that time.
+	ReaperConditionIdleFor = "idle_for"
+
+	// DefaultIdleCPUPercent is the max CPU percent of idle_for policies created without one.
+	DefaultIdleCPUPercent = 5.0
+
+	// ReaperActionStop stops the server.
+	ReaperActionStop = "stop"
+	// ReaperActionTerminate terminates the server, releasing its addresses.
+	ReaperActionTerminate = "terminate"
+	// ReaperActionNotify posts the server to the policy's webhook and leaves it be.
+	ReaperActionNotify = "notify"
+)
+
+var (
+	// ErrReaperPolicyNotFound is returned when a reaper policy does not exist.
+	ErrReaperPolicyNotFound = errors.New("reaper policy not found")
+	// ErrInvalidReaperPolicy is returned for reaper policies that cannot be applied.
+	ErrInvalidReaperPolicy = errors.New("invalid reaper policy")
+)
+
+// ReaperCandidate is a server a reaper policy applies to.
+type ReaperCandidate struct {
+	Policy sqlc.ReaperPolicy
+	Server sqlc.Server
+}
+
+// ReaperEvent is the payload posted to the webhook of a notify policy.
+type ReaperEvent struct {
+	ActionID    string    `json:"actionId"`
+	PolicyID    string    `json:"policyId"`
+	PolicyName  string    `json:"policyName"`
+	Condition   string    `json:"condition"`
+	ServerID    string    `json:"serverId"`
+	ServerName  string    `json:"serverName"`
+	Project     string    `json:"project"`
+	Status      string    `json:"status"`
+	Since       time.Time `json:"since"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// ReaperService manages the idle reaper's policies and applies them.
+type ReaperService struct {
+	queries       *sqlc.Queries
+	serverService *ServerService
+	logger        *zap.Logger
+	config        *config.Config
+	httpClient    *http.Client
+}
+
+// NewReaperService creates a new ReaperService. Servers are stopped and
+// terminated through serverService.
+func NewReaperService(queries *sqlc.Queries, serverService *ServerService, logger *zap.Logger, config *config.Config) *ReaperService {
+	return &ReaperService{
+		queries:       queries,
+		serverService: serverService,
+		logger:        logger,
+		config:        config,
+		httpClient:    &http.Client{Timeout: config.ReaperWebhookTimeout},
+	}
+}
+
+// CreatePolicy validates and stores a reaper policy.
+func (rs *ReaperService) CreatePolicy(ctx context.Context, params sqlc.CreateReaperPolicyParams) (sqlc.ReaperPolicy, error) {
+	if err := validateReaperPolicy(&params); err != nil {
+		return sqlc.ReaperPolicy{}, err
+	}
+
+	policy, err := rs.queries.CreateReaperPolicy(ctx, params)
+	if err != nil {
+		return sqlc.ReaperPolicy{}, fmt.Errorf("failed to create reaper policy: %+v", err)
+	}
+	rs.logger.Info("Reaper policy created",
+		zap.String("policy_id", policy.ID.String()),
+		zap.String("condition", policy.Condition),
+		zap.Int64("duration_seconds", policy.DurationSeconds),
+		zap.String("action", policy.Action),
+	)
+	return policy, nil
+}
+
+func validateReaperPolicy(params *sqlc.CreateReaperPolicyParams) error {
+	switch {
+	case params.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidReaperPolicy)
+	case params.MatchType != "" && !util.IsValidServerType(params.MatchType):
+		return fmt.Errorf("%w: unknown server type %s", ErrInvalidReaperPolicy, params.MatchType)
+	case params.MatchTagKey == "" && params.MatchTagValue != "":
+		return fmt.Errorf("%w: a tag value needs a tag key", ErrInvalidReaperPolicy)
+	case params.Condition != ReaperConditionStoppedFor && params.Condition != ReaperConditionRunningFor && params.Condition != ReaperConditionIdleFor:
+		return fmt.Errorf("%w: condition must be %s, %s or %s", ErrInvalidReaperPolicy, ReaperConditionStoppedFor, ReaperConditionRunningFor, ReaperConditionIdleFor)
+	case params.DurationSeconds <= 0:
+		return fmt.Errorf("%w: duration must be positive", ErrInvalidReaperPolicy)
+	case params.MaxCpuPercent < 0 || params.MaxCpuPercent > 100:
+		return fmt.Errorf("%w: max CPU percent must be between 0 and 100", ErrInvalidReaperPolicy)
+	case params.Action != ReaperActionStop && params.Action != ReaperActionTerminate && params.Action != ReaperActionNotify:
+		return fmt.Errorf("%w: action must be %s, %s or %s", ErrInvalidReaperPolicy, ReaperActionStop, ReaperActionTerminate, ReaperActionNotify)
+	case params.Condition == ReaperConditionStoppedFor && params.Action == ReaperActionStop:
+		return fmt.Errorf("%w: stopped servers cannot be stopped", ErrInvalidReaperPolicy)
+	case params.Action == ReaperActionNotify && params.WebhookUrl == "":
+		return fmt.Errorf("%w: notify policies need a webhook URL", ErrInvalidReaperPolicy)
+	}
+	if params.Condition != ReaperConditionIdleFor {
+		params.MaxCpuPercent = 0
+	}
+
+	if params.WebhookUrl != "" {
+		webhook, err := url.Parse(params.WebhookUrl)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("%w: webhook URL must be an absolute http(s) URL", ErrInvalidReaperPolicy)
+		}
+	}
+	return nil
+}
+
+// ListPolicies returns every reaper policy, in the order they are applied.
+func (rs *ReaperService) ListPolicies(ctx context.Context) ([]sqlc.ReaperPolicy, error) {
+	policies, err := rs.queries.ListReaperPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reaper policies: %+v", err)
+	}
+	return policies, nil
+}
+
+// DeletePolicy deletes a reaper policy and the record of its actions.
+func (rs *ReaperService) DeletePolicy(ctx context.Context, policyID pgtype.UUID) error {
+	deleted, err := rs.queries.DeleteReaperPolicy(ctx, policyID)
+	if err != nil {
+		return fmt.Errorf("failed to delete reaper policy: %+v", err)
+	}
+	if deleted == 0 {
+		return ErrReaperPolicyNotFound
+	}
+	rs.logger.Info("Reaper policy deleted", zap.String("policy_id", policyID.String()))
+	return nil
+}
+
+// ListActions returns what the reaper did, newest first, optionally of one policy only.
+func (rs *ReaperService) ListActions(ctx context.Context, policyID pgtype.UUID, limit, offset int) ([]sqlc.ReaperAction, error) {
+	actions, err := rs.queries.ListReaperActions(ctx, sqlc.ListReaperActionsParams{
+		PolicyID:  policyID,
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reaper actions: %+v", err)
+	}
+	return actions, nil
+}
+
+// Candidates returns the servers the reaper would act on at now, each with the
+// first policy, in creation order, that applies to it.
+func (rs *ReaperService) Candidates(ctx context.Context, now time.Time) ([]ReaperCandidate, error) {
+	policies, err := rs.queries.ListReaperPolicies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reaper policies: %+v", err)
+	}
+
+	var candidates []ReaperCandidate
+	matched := make(map[pgtype.UUID]bool)
+	for _, policy := range policies {
+		servers, err := rs.queries.ListReaperCandidates(ctx, sqlc.ListReaperCandidatesParams{
+			PolicyID:  policy.ID,
+			Now:       pgtype.Timestamptz{Time: now, Valid: true},
+			ExemptTag: rs.config.ReaperExemptTag,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list servers matching reaper policy %s: %+v", policy.ID.String(), err)
+		}
+		for _, server := range servers {
+			if matched[server.ID] {
+				continue
+			}
+			matched[server.ID] = true
+			candidates = append(candidates, ReaperCandidate{Policy: policy, Server: server})
+		}
+	}
+	return candidates, nil
+}
+
+// Reap applies the reaper policies at now. With REAPER_DRY_RUN it only logs
+// what it would do.
+func (rs *ReaperService) Reap(ctx context.Context, now time.Time) error {
+	candidates, err := rs.Candidates(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	ctx = WithEventActor(ctx, ActorReaper)
+	for _, candidate := range candidates {
+		if rs.config.ReaperDryRun {
+			rs.logger.Info("Reaper dry run: policy applies to server",
+				zap.String("policy_id", candidate.Policy.ID.String()),
+				zap.String("server_id", candidate.Server.ID.String()),
+				zap.String("action", candidate.Policy.Action),
+			)
+			continue
+		}
+		if err := rs.apply(ctx, candidate, now); err != nil {
+			rs.logger.Error("Failed to apply reaper policy",
+				zap.Error(err),
+				zap.String("policy_id", candidate.Policy.ID.String()),
+				zap.String("server_id", candidate.Server.ID.String()),
+			)
+		}
+	}
+	return nil
+}
+
+// apply records a policy acting on a server, then takes its action and records the outcome.
+func (rs *ReaperService) apply(ctx context.Context, candidate ReaperCandidate, now time.Time) error {
+	policy, server := candidate.Policy, candidate.Server
+	action, err := rs.queries.CreateReaperAction(ctx, sqlc.CreateReaperActionParams{
+		PolicyID:   policy.ID,
+		ServerID:   server.ID,
+		Action:     policy.Action,
+		StateSince: server.LastStatusUpdate,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// Another run already acted on it
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record reaper action: %+v", err)
+	}
+
+	switch policy.Action {
+	case ReaperActionStop:
+		_, err = rs.serverService.StopServer(ctx, server)
+	case ReaperActionTerminate:
+		_, err = rs.serverService.TerminateServer(ctx, server)
+	case ReaperActionNotify:
+		err = postWebhook(ctx, rs.httpClient, policy.WebhookUrl, ReaperEvent{
+			ActionID:    action.ID.String(),
+			PolicyID:    policy.ID.String(),
+			PolicyName:  policy.Name,
+			Condition:   policy.Condition,
+			ServerID:    server.ID.String(),
+			ServerName:  server.Name,
+			Project:     server.Project,
+			Status:      server.Status,
+			Since:       server.LastStatusUpdate.Time,
+			TriggeredAt: now,
+		})
+	}
+
+	outcome := "done"
+	if err != nil {
+		outcome = "failed: " + err.Error()
+		if len(outcome) > 255 {
+			outcome = outcome[:255]
+		}
+	}
+	if err := rs.queries.SetReaperActionOutcome(ctx, sqlc.SetReaperActionOutcomeParams{
+		Outcome: outcome,
+		ID:      action.ID,
+	}); err != nil {
+		rs.logger.Error("Failed to record reaper action outcome", zap.Error(err), zap.String("action_id", action.ID.String()))
+	}
+	if err != nil {
+		return err
+	}
+
+	rs.serverService.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventReaper,
+		Message: "Reaper policy applied",
+		Details: map[string]any{"policyId": policy.ID.String(), "policyName": policy.Name, "action": policy.Action},
+	})
+	rs.logger.Warn("Reaper policy applied",
+		zap.String("policy_id", policy.ID.String()),
+		zap.String("policy_name", policy.Name),
+		zap.String("server_id", server.ID.String()),
+		zap.String("action", policy.Action),
+	)
+	return nil
+}
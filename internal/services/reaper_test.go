@@ -0,0 +1,303 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+func TestValidateReaperPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(p *sqlc.CreateReaperPolicyParams)
+		wantErr bool
+		wantCPU float64
+	}{
+		{name: "idle for", modify: func(p *sqlc.CreateReaperPolicyParams) {}, wantCPU: 5},
+		{name: "idle at 0 percent", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MaxCpuPercent = 0 }},
+		{name: "idle at 100 percent", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MaxCpuPercent = 100 }, wantCPU: 100},
+		{name: "max CPU dropped for running for", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Condition = ReaperConditionRunningFor }},
+		{name: "stopped for terminates", modify: func(p *sqlc.CreateReaperPolicyParams) {
+			p.Condition, p.Action = ReaperConditionStoppedFor, ReaperActionTerminate
+		}},
+		{name: "notify with a webhook", modify: func(p *sqlc.CreateReaperPolicyParams) {
+			p.Action, p.WebhookUrl = ReaperActionNotify, "https://hooks.example.com/reaper"
+		}, wantCPU: 5},
+		{name: "known server type", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MatchType = util.ServerTypeT2Micro }, wantCPU: 5},
+		{name: "tag key alone", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MatchTagKey = "env" }, wantCPU: 5},
+		{name: "missing name", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Name = "" }, wantErr: true},
+		{name: "unknown server type", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MatchType = "x1.huge" }, wantErr: true},
+		{name: "tag value without key", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MatchTagValue = "dev" }, wantErr: true},
+		{name: "unknown condition", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Condition = "busy_for" }, wantErr: true},
+		{name: "zero duration", modify: func(p *sqlc.CreateReaperPolicyParams) { p.DurationSeconds = 0 }, wantErr: true},
+		{name: "negative duration", modify: func(p *sqlc.CreateReaperPolicyParams) { p.DurationSeconds = -1 }, wantErr: true},
+		{name: "max CPU below 0", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MaxCpuPercent = -0.1 }, wantErr: true},
+		{name: "max CPU above 100", modify: func(p *sqlc.CreateReaperPolicyParams) { p.MaxCpuPercent = 100.1 }, wantErr: true},
+		{name: "unknown action", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Action = "reboot" }, wantErr: true},
+		{name: "stopped for stops", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Condition = ReaperConditionStoppedFor }, wantErr: true},
+		{name: "notify without a webhook", modify: func(p *sqlc.CreateReaperPolicyParams) { p.Action = ReaperActionNotify }, wantErr: true},
+		{name: "relative webhook", modify: func(p *sqlc.CreateReaperPolicyParams) {
+			p.Action, p.WebhookUrl = ReaperActionNotify, "/reaper"
+		}, wantErr: true},
+		{name: "webhook without host", modify: func(p *sqlc.CreateReaperPolicyParams) {
+			p.Action, p.WebhookUrl = ReaperActionNotify, "https://"
+		}, wantErr: true},
+		{name: "webhook not http", modify: func(p *sqlc.CreateReaperPolicyParams) {
+			p.Action, p.WebhookUrl = ReaperActionNotify, "ftp://hooks.example.com/reaper"
+		}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := sqlc.CreateReaperPolicyParams{
+				Name:            "idle dev servers",
+				Condition:       ReaperConditionIdleFor,
+				DurationSeconds: 3600,
+				MaxCpuPercent:   5,
+				Action:          ReaperActionStop,
+			}
+			tt.modify(&params)
+			err := validateReaperPolicy(&params)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidReaperPolicy)) {
+				t.Fatalf("validateReaperPolicy(%+v) error = %v; want error %v", params, err, tt.wantErr)
+			}
+			if err == nil && params.MaxCpuPercent != tt.wantCPU {
+				t.Errorf("validateReaperPolicy() max CPU percent = %v; want %v", params.MaxCpuPercent, tt.wantCPU)
+			}
+		})
+	}
+}
+
+// structRows returns sqlc row structs, scanned column by column in field order,
+// or single-column values. The methods sqlc does not call are left to the nil
+// embedded pgx.Rows.
+type structRows struct {
+	pgx.Rows
+	rows []any
+	next int
+}
+
+func (r *structRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *structRows) Scan(dest ...any) error {
+	value := reflect.ValueOf(r.rows[r.next-1])
+	if value.Kind() != reflect.Struct {
+		reflect.ValueOf(dest[0]).Elem().Set(value)
+		return nil
+	}
+	if len(dest) != value.NumField() {
+		return errors.New("structRows: column count mismatch")
+	}
+	for i := range dest {
+		reflect.ValueOf(dest[i]).Elem().Set(value.Field(i))
+	}
+	return nil
+}
+
+func (r *structRows) Close()     {}
+func (r *structRows) Err() error { return nil }
+
+// reaperDB lists policies and, per policy, the servers they apply to. Every other
+// statement is recorded and fails as if the row it wanted were gone.
+type reaperDB struct {
+	policies   []sqlc.ReaperPolicy
+	candidates map[pgtype.UUID][]sqlc.Server
+	ran        []string
+}
+
+func (db *reaperDB) Exec(_ context.Context, sql string, _ ...interface{}) (pgconn.CommandTag, error) {
+	db.ran = append(db.ran, queryName(sql))
+	return pgconn.CommandTag{}, pgx.ErrNoRows
+}
+
+func (db *reaperDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, name)
+	rows := &structRows{}
+	switch name {
+	case "ListReaperPolicies":
+		for _, policy := range db.policies {
+			rows.rows = append(rows.rows, policy)
+		}
+	case "ListReaperCandidates":
+		for _, server := range db.candidates[args[0].(pgtype.UUID)] {
+			rows.rows = append(rows.rows, server)
+		}
+	default:
+		return nil, fmt.Errorf("reaperDB: unexpected query %s", name)
+	}
+	return rows, nil
+}
+
+func (db *reaperDB) QueryRow(_ context.Context, sql string, _ ...interface{}) pgx.Row {
+	db.ran = append(db.ran, queryName(sql))
+	return serverRow{err: pgx.ErrNoRows}
+}
+
+func TestReap(t *testing.T) {
+	stopIdle := sqlc.ReaperPolicy{ID: testUUID(1), Name: "stop idle", Condition: ReaperConditionIdleFor, Action: ReaperActionStop}
+	terminateOld := sqlc.ReaperPolicy{ID: testUUID(2), Name: "terminate old", Condition: ReaperConditionRunningFor, Action: ReaperActionTerminate}
+	idle := sqlc.Server{ID: testUUID(10), Name: "idle", Status: util.ServerStatusRunning}
+	old := sqlc.Server{ID: testUUID(11), Name: "old", Status: util.ServerStatusRunning}
+
+	tests := []struct {
+		name   string
+		dryRun bool
+		want   []string
+	}{
+		{
+			name:   "dry run only lists",
+			dryRun: true,
+			want:   []string{"ListReaperPolicies", "ListReaperCandidates", "ListReaperCandidates"},
+		},
+		{
+			// Recording each action fails as if another run had taken it, so nothing goes further
+			name: "each server once, by the first policy",
+			want: []string{"ListReaperPolicies", "ListReaperCandidates", "ListReaperCandidates", "CreateReaperAction", "CreateReaperAction"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &reaperDB{
+				policies: []sqlc.ReaperPolicy{stopIdle, terminateOld},
+				candidates: map[pgtype.UUID][]sqlc.Server{
+					stopIdle.ID:     {idle},
+					terminateOld.ID: {old, idle},
+				},
+			}
+			core, logs := observer.New(zapcore.InfoLevel)
+			rs := NewReaperService(sqlc.New(db), nil, zap.New(core), &config.Config{ReaperDryRun: tt.dryRun, ReaperExemptTag: "reaper-exempt"})
+
+			if err := rs.Reap(context.Background(), mustTime(t, "2026-03-01T12:00:00Z")); err != nil {
+				t.Fatalf("Reap() error = %v", err)
+			}
+			if strings.Join(db.ran, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Reap() ran %v; want %v", db.ran, tt.want)
+			}
+			if !tt.dryRun {
+				return
+			}
+			got := map[string]string{}
+			for _, entry := range logs.FilterMessage("Reaper dry run: policy applies to server").All() {
+				fields := entry.ContextMap()
+				got[fields["server_id"].(string)] = fields["action"].(string)
+			}
+			want := map[string]string{idle.ID.String(): ReaperActionStop, old.ID.String(): ReaperActionTerminate}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Reap() dry run logged %v; want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestListReaperCandidates checks which servers the candidate query leaves out.
+// It runs against the database of the repository's .env and is skipped when
+// there is none.
+func TestListReaperCandidates(t *testing.T) {
+	t.Chdir("../..")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode)
+	dbClient, err := database.NewDBClient(context.Background(), databaseURL, 1, time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Skipf("no database: %v", err)
+	}
+	defer dbClient.Close()
+	ctx := context.Background()
+
+	// The policy only matches this run's servers, through a tag they all carry
+	run := "reaper-" + uuid.NewString()[:8]
+	stoppedFor, err := dbClient.Queries.CreateReaperPolicy(ctx, sqlc.CreateReaperPolicyParams{
+		Name:            run,
+		MatchTagKey:     "run",
+		MatchTagValue:   run,
+		Condition:       ReaperConditionStoppedFor,
+		DurationSeconds: 3600,
+		Action:          ReaperActionTerminate,
+	})
+	if err != nil {
+		t.Fatalf("failed to seed policy: %v", err)
+	}
+	defer func() {
+		if _, err := dbClient.Queries.DeleteReaperPolicy(ctx, stoppedFor.ID); err != nil {
+			t.Errorf("failed to delete policy: %v", err)
+		}
+	}()
+
+	seed := func(name, actor, tags string) {
+		t.Helper()
+		_, err := dbClient.Pool.Exec(ctx, `
+			INSERT INTO servers (name, hostname, region, project, status, type, hourly_cost, last_status_update, last_status_actor, tags)
+			VALUES ($1 || '-' || $2, $1 || '-' || $2 || '.test.invalid', 'us-east-1', $1, 'stopped', 't2.micro', 0.0116,
+			        NOW() - INTERVAL '2 hours', $3, $4::jsonb || jsonb_build_object('run', $1::text))`,
+			run, name, actor, tags)
+		if err != nil {
+			t.Fatalf("failed to seed server %s: %v", name, err)
+		}
+	}
+	defer func() {
+		if _, err := dbClient.Pool.Exec(ctx, `DELETE FROM servers WHERE project = $1`, run); err != nil {
+			t.Errorf("failed to delete servers: %v", err)
+		}
+	}()
+	seed("api", ActorAPI, `{}`)
+	seed("reaper", ActorReaper, `{}`)
+	seed("budget", ActorBudget, `{}`)
+	seed("spot", ActorSpotMarket, `{}`)
+	seed("watchdog", ActorWatchdog, `{}`)
+	seed("exempt", ActorAPI, `{"reaper-exempt": "true"}`)
+
+	tests := []struct {
+		name      string
+		exemptTag string
+		want      []string
+	}{
+		{name: "exempt tag honored", exemptTag: "reaper-exempt", want: []string{"api", "reaper"}},
+		{name: "no exempt tag", exemptTag: "", want: []string{"api", "exempt", "reaper"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			servers, err := dbClient.Queries.ListReaperCandidates(ctx, sqlc.ListReaperCandidatesParams{
+				PolicyID:  stoppedFor.ID,
+				Now:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+				ExemptTag: tt.exemptTag,
+			})
+			if err != nil {
+				t.Fatalf("ListReaperCandidates() error = %v", err)
+			}
+			var got []string
+			for _, server := range servers {
+				got = append(got, strings.TrimPrefix(server.Name, run+"-"))
+			}
+			sort.Strings(got)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("ListReaperCandidates() = %v; want %v", got, tt.want)
+			}
+		})
+	}
+}
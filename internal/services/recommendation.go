@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// Kinds of right-sizing recommendation.
+const (
+	// RecommendationDownsize moves an over-provisioned server to a smaller type.
+	RecommendationDownsize = "downsize"
+	// RecommendationStop stops a server that has been idle all along.
+	RecommendationStop = "stop"
+	// RecommendationReserve buys reserved hours for a server that runs steadily.
+	RecommendationReserve = "reserve"
+)
+
+const (
+	// hoursPerMonth is the average number of hours in a month, which monthly
+	// savings are estimated over.
+	hoursPerMonth = 730
+	// minTelemetryCoverage is the share of a server's running time its telemetry
+	// must cover for its utilization to be judged.
+	minTelemetryCoverage = 0.5
+)
+
+// serverTypesBySize lists the server types from smallest to largest, with the
+// vCPUs CPU load is scaled by when a server changes type.
+var serverTypesBySize = []struct {
+	serverType string
+	vcpus      float64
+}{
+	{util.ServerTypeT2Micro, 1},
+	{util.ServerTypeM5Large, 2},
+	{util.ServerTypeC5Xlarge, 4},
+}
+
+var (
+	// ErrInvalidRecommendation is returned for unknown recommendation kinds.
+	ErrInvalidRecommendation = errors.New("kind must be downsize, stop or reserve")
+	// ErrRecommendationNotFound is returned when a server has no recommendation of the kind applied.
+	ErrRecommendationNotFound = errors.New("the server has no recommendation of this kind")
+	// ErrReserveNotApplicable is returned when applying a reserve recommendation.
+	ErrReserveNotApplicable = errors.New("reserve recommendations are applied by buying a reservation")
+)
+
+// Recommendation is a change to a running server that would lower its cost.
+type Recommendation struct {
+	Kind       string
+	ServerID   pgtype.UUID
+	ServerName string
+	Project    string
+	Region     string
+	Type       string
+	// TargetType is the type a downsize recommendation moves the server to.
+	TargetType string
+	// HourlyCost is what the server costs an hour now, TargetHourlyCost what it
+	// would cost once the recommendation is applied.
+	HourlyCost       float64
+	TargetHourlyCost float64
+	// UptimeShare is the share of RIGHTSIZING_LOOKBACK the server was running.
+	UptimeShare float64
+	// CPUPercentAvg and CPUPercentMax are the server's CPU load over the lookback;
+	// zero for servers without enough telemetry.
+	CPUPercentAvg float64
+	CPUPercentMax float64
+	// ReservedHours is how many hours a reserve recommendation buys for RESERVATION_TERM.
+	ReservedHours float64
+	// MonthlySavings is in USD, for a month of running as much as over the lookback.
+	MonthlySavings float64
+	Reason         string
+}
+
+// RecommendationService suggests right-sizing changes from the running time and
+// CPU telemetry of servers over RIGHTSIZING_LOOKBACK, and applies them.
+type RecommendationService struct {
+	queries *sqlc.Queries
+	servers *ServerService
+	logger  *zap.Logger
+	config  *config.Config
+}
+
+// NewRecommendationService creates a new RecommendationService.
+func NewRecommendationService(queries *sqlc.Queries, servers *ServerService, logger *zap.Logger, config *config.Config) *RecommendationService {
+	return &RecommendationService{
+		queries: queries,
+		servers: servers,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+// List returns the recommendations for the running servers that existed for the
+// whole lookback, optionally of one project only. A server is either stopped,
+// downsized or, if it is already the right size, reserved for.
+func (rec *RecommendationService) List(ctx context.Context, project string, now time.Time) ([]Recommendation, error) {
+	return rec.recommendations(ctx, sqlc.ListServerUtilizationParams{
+		Project: pgtype.Text{String: project, Valid: project != ""},
+	}, now)
+}
+
+// recommendations returns the recommendations for the servers the filters of arg
+// select, over the lookback ending at now.
+func (rec *RecommendationService) recommendations(ctx context.Context, arg sqlc.ListServerUtilizationParams, now time.Time) ([]Recommendation, error) {
+	arg.ToTime = pgtype.Timestamptz{Time: now, Valid: true}
+	arg.FromTime = pgtype.Timestamptz{Time: now.Add(-rec.config.RightsizingLookback), Valid: true}
+	rows, err := rec.queries.ListServerUtilization(ctx, arg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server utilization: %+v", err)
+	}
+	catalog, err := LoadPriceCatalog(ctx, rec.queries)
+	if err != nil {
+		return nil, err
+	}
+
+	recommendations := []Recommendation{}
+	for _, row := range rows {
+		if recommendation, ok := rec.recommend(row, catalog, now); ok {
+			recommendations = append(recommendations, recommendation)
+		}
+	}
+	return recommendations, nil
+}
+
+// recommend picks the recommendation for one server, if any.
+func (rec *RecommendationService) recommend(row sqlc.ListServerUtilizationRow, catalog PriceCatalog, now time.Time) (Recommendation, bool) {
+	lookback := rec.config.RightsizingLookback
+	uptimeShare := min(float64(row.RunningSeconds)/lookback.Seconds(), 1)
+	if uptimeShare <= 0 {
+		return Recommendation{}, false
+	}
+	monthlyHours := uptimeShare * hoursPerMonth
+	recommendation := Recommendation{
+		ServerID:    row.ID,
+		ServerName:  row.Name,
+		Project:     row.Project,
+		Region:      row.Region,
+		Type:        row.Type,
+		HourlyCost:  row.HourlyCost,
+		UptimeShare: uptimeShare,
+	}
+
+	if float64(row.TelemetryMinutes*60) >= minTelemetryCoverage*float64(row.RunningSeconds) {
+		recommendation.CPUPercentAvg = row.CpuPercentAvg
+		recommendation.CPUPercentMax = row.CpuPercentMax
+
+		if row.CpuPercentMax <= DefaultIdleCPUPercent {
+			recommendation.Kind = RecommendationStop
+			recommendation.MonthlySavings = row.HourlyCost * monthlyHours
+			recommendation.Reason = fmt.Sprintf("CPU load never exceeded %.1f%% over the last %s", row.CpuPercentMax, lookback)
+			return recommendation, true
+		}
+
+		if targetType, rate, projected, ok := rec.smallerType(row, catalog, now); ok {
+			recommendation.Kind = RecommendationDownsize
+			recommendation.TargetType = targetType
+			recommendation.TargetHourlyCost = rate
+			recommendation.MonthlySavings = (row.HourlyCost - rate) * monthlyHours
+			recommendation.Reason = fmt.Sprintf("CPU load peaked at %.1f%% (%.1f%% on average) over the last %s, about %.1f%% on %s",
+				row.CpuPercentMax, row.CpuPercentAvg, lookback, projected, targetType)
+			return recommendation, true
+		}
+	}
+
+	if uptimeShare >= rec.config.ReserveMinUptime && row.PurchaseOption == PurchaseOptionOnDemand && row.BillingModel != BillingModelReserved {
+		recommendation.Kind = RecommendationReserve
+		recommendation.TargetHourlyCost = row.HourlyCost * (1 - rec.config.ReservationDiscount)
+		recommendation.ReservedHours = uptimeShare * rec.config.ReservationTerm.Hours()
+		recommendation.MonthlySavings = row.HourlyCost * rec.config.ReservationDiscount * monthlyHours
+		recommendation.Reason = fmt.Sprintf("Running %.0f%% of the last %s", uptimeShare*100, lookback)
+		return recommendation, true
+	}
+	return Recommendation{}, false
+}
+
+// smallerType returns the smallest, cheaper server type an on-demand server would
+// keep its peak CPU load under RIGHTSIZING_MAX_CPU_PERCENT on, with its catalog
+// price and the peak load projected on it.
+func (rec *RecommendationService) smallerType(row sqlc.ListServerUtilizationRow, catalog PriceCatalog, now time.Time) (string, float64, float64, bool) {
+	if row.PurchaseOption != PurchaseOptionOnDemand || row.BillingModel == BillingModelReserved {
+		return "", 0, 0, false
+	}
+	var vcpus float64
+	for _, size := range serverTypesBySize {
+		if size.serverType == row.Type {
+			vcpus = size.vcpus
+		}
+	}
+
+	for _, size := range serverTypesBySize {
+		if size.vcpus >= vcpus {
+			break
+		}
+		projected := row.CpuPercentMax * vcpus / size.vcpus
+		if projected > rec.config.RightsizingMaxCPU {
+			continue
+		}
+		rate, err := catalog.RateAt(size.serverType, row.Region, now)
+		if err != nil || rate >= row.HourlyCost {
+			continue
+		}
+		return size.serverType, rate, projected, true
+	}
+	return "", 0, 0, false
+}
+
+// Apply carries out the current recommendation of a kind for a server: downsize
+// changes its type and hourly cost, stop stops it. It is recorded in the server's
+// lifecycle logs.
+func (rec *RecommendationService) Apply(ctx context.Context, server sqlc.Server, kind string, now time.Time) (sqlc.Server, Recommendation, error) {
+	switch kind {
+	case RecommendationDownsize, RecommendationStop:
+	case RecommendationReserve:
+		return sqlc.Server{}, Recommendation{}, ErrReserveNotApplicable
+	default:
+		return sqlc.Server{}, Recommendation{}, ErrInvalidRecommendation
+	}
+
+	recommendations, err := rec.recommendations(ctx, sqlc.ListServerUtilizationParams{ServerID: server.ID}, now)
+	if err != nil {
+		return sqlc.Server{}, Recommendation{}, err
+	}
+	var recommendation Recommendation
+	for _, candidate := range recommendations {
+		if candidate.Kind == kind {
+			recommendation = candidate
+		}
+	}
+	if recommendation.Kind == "" {
+		return sqlc.Server{}, Recommendation{}, ErrRecommendationNotFound
+	}
+
+	var updatedServer sqlc.Server
+	if kind == RecommendationDownsize {
+		updatedServer, err = rec.servers.ResizeServer(ctx, server, recommendation.TargetType)
+	} else {
+		updatedServer, err = rec.servers.StopServer(ctx, server)
+	}
+	if err != nil {
+		return sqlc.Server{}, Recommendation{}, err
+	}
+
+	rec.servers.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventRecommendation,
+		Message: "Recommendation applied: " + kind + ", saving about " + strconv.FormatFloat(recommendation.MonthlySavings, 'f', 2, 64) + " USD a month",
+		Details: map[string]any{"kind": kind, "targetType": recommendation.TargetType, "monthlySavings": recommendation.MonthlySavings},
+	})
+
+	rec.logger.Info("Recommendation applied",
+		zap.String("server_id", server.ID.String()),
+		zap.String("kind", kind),
+		zap.String("target_type", recommendation.TargetType),
+		zap.Float64("monthly_savings", recommendation.MonthlySavings),
+	)
+	return updatedServer, recommendation, nil
+}
@@ -0,0 +1,142 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+func testRecommendationService() *RecommendationService {
+	return &RecommendationService{config: &config.Config{
+		RightsizingLookback: 168 * time.Hour,
+		RightsizingMaxCPU:   80,
+		ReserveMinUptime:    0.9,
+		ReservationDiscount: 0.3,
+		ReservationTerm:     8760 * time.Hour,
+	}}
+}
+
+func testRightsizingCatalog(t *testing.T) PriceCatalog {
+	from := mustTime(t, "2024-01-01T00:00:00Z")
+	return testCatalog(
+		testPrice(util.ServerTypeT2Micro, "us-east-1", 0.0116, from),
+		testPrice(util.ServerTypeM5Large, "us-east-1", 0.096, from),
+		testPrice(util.ServerTypeC5Xlarge, "us-east-1", 0.17, from),
+	)
+}
+
+// utilizationRow is an on-demand server that ran and reported telemetry for the
+// whole week-long lookback at its catalog rate.
+func utilizationRow(serverType string, hourlyCost, cpuPercentMax float64) sqlc.ListServerUtilizationRow {
+	return sqlc.ListServerUtilizationRow{
+		ID:               testUUID(1),
+		Name:             "web",
+		Project:          "acme",
+		Region:           "us-east-1",
+		Type:             serverType,
+		HourlyCost:       hourlyCost,
+		BillingModel:     BillingModelPerSecond,
+		PurchaseOption:   PurchaseOptionOnDemand,
+		RunningSeconds:   168 * 3600,
+		TelemetryMinutes: 168 * 60,
+		CpuPercentAvg:    cpuPercentMax / 2,
+		CpuPercentMax:    cpuPercentMax,
+	}
+}
+
+func TestSmallerType(t *testing.T) {
+	rec := testRecommendationService()
+	catalog := testRightsizingCatalog(t)
+	now := mustTime(t, "2024-06-01T00:00:00Z")
+
+	spot := utilizationRow(util.ServerTypeC5Xlarge, 0.17, 10)
+	spot.PurchaseOption = PurchaseOptionSpot
+	reserved := utilizationRow(util.ServerTypeC5Xlarge, 0.17, 10)
+	reserved.BillingModel = BillingModelReserved
+	otherRegion := utilizationRow(util.ServerTypeC5Xlarge, 0.17, 10)
+	otherRegion.Region = "eu-west-1"
+
+	tests := []struct {
+		name          string
+		row           sqlc.ListServerUtilizationRow
+		wantOK        bool
+		wantType      string
+		wantRate      float64
+		wantProjected float64
+	}{
+		{name: "smallest type at the limit", row: utilizationRow(util.ServerTypeC5Xlarge, 0.17, 20), wantOK: true, wantType: util.ServerTypeT2Micro, wantRate: 0.0116, wantProjected: 80},
+		{name: "next type up over the limit", row: utilizationRow(util.ServerTypeC5Xlarge, 0.17, 21), wantOK: true, wantType: util.ServerTypeM5Large, wantRate: 0.096, wantProjected: 42},
+		{name: "one step down", row: utilizationRow(util.ServerTypeM5Large, 0.096, 40), wantOK: true, wantType: util.ServerTypeT2Micro, wantRate: 0.0116, wantProjected: 80},
+		{name: "over the limit on every smaller type", row: utilizationRow(util.ServerTypeC5Xlarge, 0.17, 41)},
+		{name: "already the smallest type", row: utilizationRow(util.ServerTypeT2Micro, 0.0116, 1)},
+		{name: "unknown type", row: utilizationRow("x1.huge", 1, 1)},
+		{name: "smaller types not cheaper", row: utilizationRow(util.ServerTypeC5Xlarge, 0.01, 10)},
+		{name: "no catalog price in the region", row: otherRegion},
+		{name: "spot", row: spot},
+		{name: "reserved billing", row: reserved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotRate, gotProjected, gotOK := rec.smallerType(tt.row, catalog, now)
+			if gotOK != tt.wantOK || gotType != tt.wantType || !closeTo(gotRate, tt.wantRate) || !closeTo(gotProjected, tt.wantProjected) {
+				t.Errorf("smallerType(%s at %v%%) = %q, %v, %v, %v; want %q, %v, %v, %v", tt.row.Type, tt.row.CpuPercentMax,
+					gotType, gotRate, gotProjected, gotOK, tt.wantType, tt.wantRate, tt.wantProjected, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRecommend(t *testing.T) {
+	rec := testRecommendationService()
+	catalog := testRightsizingCatalog(t)
+	now := mustTime(t, "2024-06-01T00:00:00Z")
+
+	idle := utilizationRow(util.ServerTypeM5Large, 0.096, DefaultIdleCPUPercent)
+	stopped := utilizationRow(util.ServerTypeM5Large, 0.096, 1)
+	stopped.RunningSeconds = 0
+	thinTelemetry := utilizationRow(util.ServerTypeC5Xlarge, 0.17, 1)
+	thinTelemetry.TelemetryMinutes = 168*60/2 - 1
+	partTime := utilizationRow(util.ServerTypeT2Micro, 0.0116, 50)
+	partTime.RunningSeconds = 151 * 3600
+	overLookback := utilizationRow(util.ServerTypeT2Micro, 0.0116, 50)
+	overLookback.RunningSeconds = 200 * 3600
+	spot := utilizationRow(util.ServerTypeT2Micro, 0.0116, 50)
+	spot.PurchaseOption = PurchaseOptionSpot
+	reserved := utilizationRow(util.ServerTypeT2Micro, 0.0116, 50)
+	reserved.BillingModel = BillingModelReserved
+
+	tests := []struct {
+		name              string
+		row               sqlc.ListServerUtilizationRow
+		wantOK            bool
+		wantKind          string
+		wantTargetType    string
+		wantMonthlySaving float64
+	}{
+		{name: "idle at the threshold", row: idle, wantOK: true, wantKind: RecommendationStop, wantMonthlySaving: 0.096 * hoursPerMonth},
+		{name: "busy just over idle on the smallest type", row: utilizationRow(util.ServerTypeT2Micro, 0.0116, DefaultIdleCPUPercent+0.1), wantOK: true, wantKind: RecommendationReserve, wantMonthlySaving: 0.0116 * 0.3 * hoursPerMonth},
+		{name: "over-provisioned", row: utilizationRow(util.ServerTypeC5Xlarge, 0.17, 20), wantOK: true, wantKind: RecommendationDownsize, wantTargetType: util.ServerTypeT2Micro, wantMonthlySaving: (0.17 - 0.0116) * hoursPerMonth},
+		{name: "right-sized", row: utilizationRow(util.ServerTypeC5Xlarge, 0.17, 50), wantOK: true, wantKind: RecommendationReserve, wantMonthlySaving: 0.17 * 0.3 * hoursPerMonth},
+		{name: "unknown type", row: utilizationRow("x1.huge", 1, 50), wantOK: true, wantKind: RecommendationReserve, wantMonthlySaving: 0.3 * hoursPerMonth},
+		{name: "too little telemetry to judge", row: thinTelemetry, wantOK: true, wantKind: RecommendationReserve, wantMonthlySaving: 0.17 * 0.3 * hoursPerMonth},
+		{name: "uptime capped at the lookback", row: overLookback, wantOK: true, wantKind: RecommendationReserve, wantMonthlySaving: 0.0116 * 0.3 * hoursPerMonth},
+		{name: "running too little to reserve", row: partTime},
+		{name: "not running over the lookback", row: stopped},
+		{name: "spot", row: spot},
+		{name: "already reserved", row: reserved},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := rec.recommend(tt.row, catalog, now)
+			if ok != tt.wantOK || got.Kind != tt.wantKind || got.TargetType != tt.wantTargetType || !closeTo(got.MonthlySavings, tt.wantMonthlySaving) {
+				t.Errorf("recommend(%s at %v%%) = %q, %q, %v, %v; want %q, %q, %v, %v", tt.row.Type, tt.row.CpuPercentMax,
+					got.Kind, got.TargetType, got.MonthlySavings, ok, tt.wantKind, tt.wantTargetType, tt.wantMonthlySaving, tt.wantOK)
+			}
+		})
+	}
+}
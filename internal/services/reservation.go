@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+var (
+	// ErrReservationNotFound is returned when a reservation does not exist.
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrInvalidReservation is returned when a reservation has no hours.
+	ErrInvalidReservation = errors.New("hours must be positive")
+)
+
+// CreateReservation prepays hours of a server type in a region for a project, at
+// the catalog price in effect now less RESERVATION_DISCOUNT. The hours can be used
+// for RESERVATION_TERM by the project's servers on BillingModelReserved. The full
+// amount is charged to the ledger upfront.
+func (b *BillingService) CreateReservation(ctx context.Context, project, serverType, region string, hours float64) (sqlc.Reservation, error) {
+	if hours <= 0 {
+		return sqlc.Reservation{}, ErrInvalidReservation
+	}
+	if project == "" {
+		project = DefaultProject
+	}
+
+	now := time.Now()
+	catalog, err := LoadPriceCatalog(ctx, b.db.Queries)
+	if err != nil {
+		return sqlc.Reservation{}, err
+	}
+	rate, err := catalog.RateAt(serverType, region, now)
+	if err != nil {
+		return sqlc.Reservation{}, err
+	}
+	rate *= 1 - b.config.ReservationDiscount
+
+	var reservation sqlc.Reservation
+	err = b.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		reservation, err = q.CreateReservation(ctx, sqlc.CreateReservationParams{
+			Project:    project,
+			ServerType: serverType,
+			Region:     region,
+			Hours:      hours,
+			HourlyRate: rate,
+			Currency:   CurrencyUSD,
+			StartsAt:   pgtype.Timestamptz{Time: now, Valid: true},
+			ExpiresAt:  pgtype.Timestamptz{Time: now.Add(b.config.ReservationTerm), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create reservation: %+v", err)
+		}
+
+		_, err = q.CreateLedgerEntry(ctx, sqlc.CreateLedgerEntryParams{
+			Project:     project,
+			PeriodStart: pgtype.Date{Time: PeriodStart(now), Valid: true},
+			ChargeType:  ChargeTypeReservation,
+			ServerType:  serverType,
+			Region:      region,
+			Description: fmt.Sprintf("%.0f reserved hours of %s in %s", hours, serverType, region),
+			UsageStart:  reservation.StartsAt,
+			UsageEnd:    reservation.ExpiresAt,
+			Quantity:    hours,
+			Unit:        "hour",
+			UnitPrice:   rate,
+			Amount:      hours * rate,
+			Currency:    CurrencyUSD,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create ledger entry: %+v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return sqlc.Reservation{}, err
+	}
+
+	b.logger.Info("Reservation created",
+		zap.String("reservation_id", reservation.ID.String()),
+		zap.String("project", reservation.Project),
+		zap.String("type", reservation.ServerType),
+		zap.String("region", reservation.Region),
+		zap.Float64("hours", reservation.Hours),
+		zap.Float64("hourly_rate", reservation.HourlyRate),
+	)
+	return reservation, nil
+}
+
+// GetReservation returns a reservation.
+func (b *BillingService) GetReservation(ctx context.Context, reservationID pgtype.UUID) (sqlc.Reservation, error) {
+	reservation, err := b.db.Queries.GetReservation(ctx, reservationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return sqlc.Reservation{}, ErrReservationNotFound
+	}
+	if err != nil {
+		return sqlc.Reservation{}, fmt.Errorf("failed to get reservation: %+v", err)
+	}
+	return reservation, nil
+}
+
+// ListReservations returns reservations, newest first, optionally of one project only.
+func (b *BillingService) ListReservations(ctx context.Context, project string, limit, offset int) ([]sqlc.Reservation, error) {
+	reservations, err := b.db.Queries.ListReservations(ctx, sqlc.ListReservationsParams{
+		Project:   pgtype.Text{String: project, Valid: project != ""},
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list reservations: %+v", err)
+	}
+	return reservations, nil
+}
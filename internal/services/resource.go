@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+const (
+	// ResourceDisk is a server's disk, held from provisioning to termination.
+	ResourceDisk = "disk"
+	// ResourcePublicIP is a public address, held while a NAT mapping is active.
+	ResourcePublicIP = "public_ip"
+	// MaxDiskGB is the largest disk a server can be provisioned with.
+	MaxDiskGB = 16384
+)
+
+// ErrInvalidDiskSize is returned when a server is provisioned with a disk out of range.
+var ErrInvalidDiskSize = errors.New("diskGb must be between 1 and 16384")
+
+// resourceCharge returns the ledger charge type, unit and price of a resource.
+// Prices come from the configuration; DISK_GB_HOUR_PRICE is per GB.
+func resourceCharge(cfg *config.Config, resource string) (string, string, float64) {
+	if resource == ResourceDisk {
+		return ChargeTypeStorage, "GB-hour", cfg.DiskGBHourPrice
+	}
+	return ChargeTypePublicIP, "hour", cfg.PublicIPHourPrice
+}
+
+// openResourceSegment starts metering a resource of a server. It is a no-op if
+// the server already holds the resource.
+func (s *ServerService) openResourceSegment(ctx context.Context, q *sqlc.Queries, serverID pgtype.UUID, resource string, quantity float64) error {
+	segment, err := q.OpenResourceSegment(ctx, sqlc.OpenResourceSegmentParams{
+		ServerID: serverID,
+		Resource: resource,
+		Quantity: quantity,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open %s segment: %+v", resource, err)
+	}
+
+	s.logger.Debug("Resource segment opened",
+		zap.String("server_id", serverID.String()),
+		zap.String("segment_id", segment.ID.String()),
+		zap.String("resource", resource),
+		zap.Float64("quantity", quantity),
+	)
+	return nil
+}
+
+// closeResourceSegment stops metering a resource the server has released.
+func (s *ServerService) closeResourceSegment(ctx context.Context, q *sqlc.Queries, serverID pgtype.UUID, resource string) error {
+	err := q.CloseResourceSegment(ctx, sqlc.CloseResourceSegmentParams{
+		ServerID: serverID,
+		Resource: resource,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to close %s segment: %+v", resource, err)
+	}
+	return nil
+}
+
+// RecordEgress adds simulated outbound traffic of every running server for the
+// time elapsed since the previous call, EGRESS_GB_PER_HOUR on average.
+func (b *BillingService) RecordEgress(ctx context.Context, elapsed time.Duration) error {
+	meanGB := b.config.EgressGBPerHour * elapsed.Hours()
+	if meanGB <= 0 {
+		return nil
+	}
+	if err := b.db.Queries.RecordEgress(ctx, meanGB); err != nil {
+		return fmt.Errorf("failed to record egress: %+v", err)
+	}
+	return nil
+}
+
+// resourceEntries returns the ledger entries of a disk or public IP segment from
+// its billed_until to until, one per billing period it spans, by the same rules as
+// compute usage and at the prices configured when they are written.
+func resourceEntries(cfg *config.Config, invoiced invoicedPeriods, segment sqlc.ListUnbilledResourceSegmentsRow, until, currentPeriod time.Time) []sqlc.CreateLedgerEntryParams {
+	chargeType, unit, price := resourceCharge(cfg, segment.Resource)
+	description := "public IP of " + segment.ServerName
+	if segment.Resource == ResourceDisk {
+		description = fmt.Sprintf("%.0f GB disk of %s", segment.Quantity, segment.ServerName)
+	}
+
+	var entries []sqlc.CreateLedgerEntryParams
+	for start := segment.BilledUntil.Time; start.Before(until); start = PeriodEnd(start) {
+		end := PeriodEnd(start)
+		if end.After(until) {
+			end = until
+		}
+		quantity := end.Sub(start).Hours() * segment.Quantity
+		entries = append(entries, sqlc.CreateLedgerEntryParams{
+			Project:     segment.Project,
+			ServerID:    segment.ServerID,
+			PeriodStart: pgtype.Date{Time: billingPeriod(invoiced, segment.Project, start, currentPeriod), Valid: true},
+			ChargeType:  chargeType,
+			ServerType:  segment.ServerType,
+			Region:      segment.Region,
+			Description: description,
+			UsageStart:  pgtype.Timestamptz{Time: start, Valid: true},
+			UsageEnd:    pgtype.Timestamptz{Time: end, Valid: true},
+			Quantity:    quantity,
+			Unit:        unit,
+			UnitPrice:   price,
+			Amount:      quantity * price,
+			Currency:    CurrencyUSD,
+		})
+	}
+	return entries
+}
+
+// egressEntry returns the ledger entry of a server's egress of one period.
+func egressEntry(cfg *config.Config, invoiced invoicedPeriods, usage sqlc.ListUnbilledEgressRow, currentPeriod time.Time) sqlc.CreateLedgerEntryParams {
+	return sqlc.CreateLedgerEntryParams{
+		Project:     usage.Project,
+		ServerID:    usage.ServerID,
+		PeriodStart: pgtype.Date{Time: billingPeriod(invoiced, usage.Project, usage.PeriodStart.Time, currentPeriod), Valid: true},
+		ChargeType:  ChargeTypeEgress,
+		ServerType:  usage.ServerType,
+		Region:      usage.Region,
+		Description: "egress of " + usage.ServerName,
+		UsageStart:  usage.UsageStart,
+		UsageEnd:    usage.UsageEnd,
+		Quantity:    usage.Gb,
+		Unit:        "GB",
+		UnitPrice:   cfg.EgressGBPrice,
+		Amount:      usage.Gb * cfg.EgressGBPrice,
+		Currency:    CurrencyUSD,
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func resourceTestConfig() *config.Config {
+	return &config.Config{DiskGBHourPrice: 0.0001, PublicIPHourPrice: 0.005, EgressGBPrice: 0.09, EgressGBPerHour: 0.5}
+}
+
+func TestResourceCharge(t *testing.T) {
+	cfg := resourceTestConfig()
+	if chargeType, unit, price := resourceCharge(cfg, ResourceDisk); chargeType != ChargeTypeStorage || unit != "GB-hour" || price != cfg.DiskGBHourPrice {
+		t.Errorf("disk charge = %s, %s, %v", chargeType, unit, price)
+	}
+	if chargeType, unit, price := resourceCharge(cfg, ResourcePublicIP); chargeType != ChargeTypePublicIP || unit != "hour" || price != cfg.PublicIPHourPrice {
+		t.Errorf("public IP charge = %s, %s, %v", chargeType, unit, price)
+	}
+}
+
+func TestResourceEntries(t *testing.T) {
+	cfg := resourceTestConfig()
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	segment := func(resource string, quantity float64, startedAt, endedAt string) sqlc.ListUnbilledResourceSegmentsRow {
+		row := sqlc.ListUnbilledResourceSegmentsRow{
+			Resource:    resource,
+			Quantity:    quantity,
+			StartedAt:   timestamptz(mustTime(t, startedAt)),
+			BilledUntil: timestamptz(mustTime(t, startedAt)),
+			Project:     "acme",
+			Region:      "us-east-1",
+			ServerType:  "t2.micro",
+			ServerName:  "web",
+		}
+		if endedAt != "" {
+			row.EndedAt = timestamptz(mustTime(t, endedAt))
+		}
+		return row
+	}
+	tests := []struct {
+		name     string
+		segment  sqlc.ListUnbilledResourceSegmentsRow
+		invoiced invoicedPeriods
+		want     []wantEntry
+	}{
+		{
+			name:    "disk split at the period boundary",
+			segment: segment(ResourceDisk, 20, "2026-01-31T20:00:00Z", "2026-02-01T04:00:00Z"),
+			want: []wantEntry{
+				{ChargeTypeStorage, "2026-01-01T00:00:00Z", "2026-01-31T20:00:00Z", "2026-02-01T00:00:00Z", 80, cfg.DiskGBHourPrice},
+				{ChargeTypeStorage, "2026-02-01T00:00:00Z", "2026-02-01T00:00:00Z", "2026-02-01T04:00:00Z", 80, cfg.DiskGBHourPrice},
+			},
+		},
+		{
+			name:    "held public IP up to the current period",
+			segment: segment(ResourcePublicIP, 1, "2026-02-27T00:00:00Z", ""),
+			want: []wantEntry{
+				{ChargeTypePublicIP, "2026-02-01T00:00:00Z", "2026-02-27T00:00:00Z", "2026-03-01T00:00:00Z", 48, cfg.PublicIPHourPrice},
+			},
+		},
+		{
+			name:     "invoiced period is charged late",
+			segment:  segment(ResourcePublicIP, 1, "2026-01-31T22:00:00Z", "2026-02-01T00:00:00Z"),
+			invoiced: invoicedPeriods{{"acme", mustTime(t, "2026-01-01T00:00:00Z")}: true},
+			want: []wantEntry{
+				{ChargeTypePublicIP, "2026-03-01T00:00:00Z", "2026-01-31T22:00:00Z", "2026-02-01T00:00:00Z", 2, cfg.PublicIPHourPrice},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := resourceEntries(cfg, tt.invoiced, tt.segment, accruedUntil(tt.segment.EndedAt, current), current)
+			checkEntries(t, entries, tt.want)
+		})
+	}
+}
+
+func TestEgressEntry(t *testing.T) {
+	cfg := resourceTestConfig()
+	current := mustTime(t, "2026-03-01T00:00:00Z")
+	usage := sqlc.ListUnbilledEgressRow{
+		Project:     "acme",
+		ServerName:  "web",
+		PeriodStart: pgtype.Date{Time: mustTime(t, "2026-02-01T00:00:00Z"), Valid: true},
+		UsageStart:  timestamptz(mustTime(t, "2026-02-03T00:00:00Z")),
+		UsageEnd:    timestamptz(mustTime(t, "2026-02-28T23:00:00Z")),
+		Gb:          12.5,
+	}
+	entry := egressEntry(cfg, nil, usage, current)
+	checkEntries(t, []sqlc.CreateLedgerEntryParams{entry}, []wantEntry{
+		{ChargeTypeEgress, "2026-02-01T00:00:00Z", "2026-02-03T00:00:00Z", "2026-02-28T23:00:00Z", 12.5, cfg.EgressGBPrice},
+	})
+	if entry.Unit != "GB" {
+		t.Errorf("egress unit = %q, want GB", entry.Unit)
+	}
+
+	invoiced := invoicedPeriods{{"acme", mustTime(t, "2026-02-01T00:00:00Z")}: true}
+	if entry := egressEntry(cfg, invoiced, usage, current); !entry.PeriodStart.Time.Equal(current) {
+		t.Errorf("egress of an invoiced period booked to %v, want %v", entry.PeriodStart.Time, current)
+	}
+}
+
+func TestRecordEgressWithoutTime(t *testing.T) {
+	b := BillingService{config: resourceTestConfig()}
+	if err := b.RecordEgress(context.Background(), 0); err != nil {
+		t.Errorf("RecordEgress of no time = %v, want nil", err)
+	}
+}
@@ -6,18 +6,19 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-chi/chi/middleware"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"go.uber.org/zap"
 
 	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
 	"go-virtual-server/internal/database/sqlc"
 	"go-virtual-server/internal/util"
 )
 
 // ServerService handles business logic related to servers.
 type ServerService struct {
+	db          *database.DBClient
 	queries     *sqlc.Queries
 	ipAllocator *IPAllocator
 	logger      *zap.Logger
@@ -25,77 +26,286 @@ type ServerService struct {
 }
 
 // NewServerService creates a new ServerService.
-func NewServerService(queries *sqlc.Queries, ipAllocator *IPAllocator, logger *zap.Logger, config *config.Config) *ServerService {
+func NewServerService(db *database.DBClient, ipAllocator *IPAllocator, logger *zap.Logger, config *config.Config) *ServerService {
 	return &ServerService{
-		queries:     queries,
+		db:          db,
+		queries:     db.Queries,
 		ipAllocator: ipAllocator,
 		logger:      logger,
 		config:      config,
 	}
 }
 
+// ProvisionOptions holds the optional settings of a new server.
+type ProvisionOptions struct {
+	// AssignPublicIP maps a public address to the server's private address while it is running.
+	AssignPublicIP bool
+	// Tags are free-form key/value labels, exposed through the metadata service.
+	Tags map[string]string
+	// UserData is handed to the server through the metadata service as-is.
+	UserData string
+	// Project groups servers for invoicing; empty means DefaultProject.
+	Project string
+	// BillingModel is how the server's running time is charged; empty means BillingModelPerSecond.
+	BillingModel string
+	// PurchaseOption is PurchaseOptionOnDemand (default) or PurchaseOptionSpot.
+	PurchaseOption string
+	// SpotMaxPrice is the highest hourly spot price a spot server runs at; zero
+	// means the on-demand price.
+	SpotMaxPrice float64
+	// InterruptionBehavior is what happens to an outbid spot server:
+	// InterruptionBehaviorTerminate (default) or InterruptionBehaviorStop.
+	InterruptionBehavior string
+	// DiskGB is the size of the server's disk; zero means DEFAULT_DISK_GB.
+	DiskGB int32
+}
+
 // ProvisionNewServer handles the logic for provisioning a new server.
-func (s *ServerService) ProvisionNewServer(ctx context.Context, name string, region string, serverType string) (sqlc.Server, error) {
+func (s *ServerService) ProvisionNewServer(ctx context.Context, name string, region string, serverType string, opts ProvisionOptions) (sqlc.Server, error) {
 
 	s.logger.Info("Attempting to provision new server",
 		zap.String("name", name),
 		zap.String("region", region),
+		zap.String("project", opts.Project),
 		zap.String("type", string(serverType)),
+		zap.String("billing_model", opts.BillingModel),
+		zap.String("purchase_option", opts.PurchaseOption),
+		zap.Bool("assign_public_ip", opts.AssignPublicIP),
 	)
 
-	// 1. Allocate IP Address
-	allocatedIP, err := s.ipAllocator.AllocateIP(ctx)
+	if err := validateProvisionOptions(opts); err != nil {
+		return sqlc.Server{}, err
+	}
+	tags, err := marshalTags(opts.Tags)
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	if opts.Project == "" {
+		opts.Project = DefaultProject
+	}
+	if opts.BillingModel == "" {
+		opts.BillingModel = BillingModelPerSecond
+	}
+	if !IsValidBillingModel(opts.BillingModel) {
+		return sqlc.Server{}, ErrInvalidBillingModel
+	}
+	if err := validateSpotOptions(&opts); err != nil {
+		return sqlc.Server{}, err
+	}
+	if opts.DiskGB == 0 {
+		opts.DiskGB = s.config.DefaultDiskGB
+	}
+	if opts.DiskGB < 1 || opts.DiskGB > MaxDiskGB {
+		return sqlc.Server{}, ErrInvalidDiskSize
+	}
+
+	// The server's hourly cost is the catalog price in effect now; there is no default price
+	catalog, err := LoadPriceCatalog(ctx, s.queries)
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	hourlyRate, err := catalog.RateAt(serverType, region, time.Now())
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	// Spot servers run at the spot price, as long as their bid covers it
+	if opts.PurchaseOption == PurchaseOptionSpot {
+		if opts.SpotMaxPrice == 0 {
+			opts.SpotMaxPrice = hourlyRate
+		}
+		hourlyRate, err = currentSpotPrice(ctx, s.queries, s.config, catalog, serverType, region, time.Now())
+		if err != nil {
+			return sqlc.Server{}, err
+		}
+		if opts.SpotMaxPrice < hourlyRate {
+			return sqlc.Server{}, ErrSpotBidTooLow
+		}
+	}
+
+	// 1. Allocate a private IP Address from the region's pool
+	poolName, err := s.privatePoolFor(region)
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	allocatedIP, err := s.ipAllocator.AllocateIPFromPool(ctx, poolName)
 	if err != nil {
 		s.logger.Error("Failed to allocate IP address", zap.Error(err))
 		return sqlc.Server{}, errors.New("failed to allocate IP address")
 	}
-	hourlyConst := 0.1
 
-	if _, ok := s.config.ServerTypeWisePricing[serverType]; ok {
-		hourlyConst = s.config.ServerTypeWisePricing[serverType]
+	hostname, err := s.hostnameFor(ctx, name, pgtype.UUID{})
+	if err != nil {
+		s.logger.Error("Failed to pick hostname", zap.Error(err))
+		if releaseErr := s.ipAllocator.ReleaseIP(ctx, allocatedIP); releaseErr != nil {
+			s.logger.Error("Failed to release IP after hostname failure", zap.Error(releaseErr), zap.String("ip_id", allocatedIP.ID.String()))
+		}
+		return sqlc.Server{}, err
 	}
 
 	// 2. Create Server in DB
 	createServerParams := sqlc.CreateNewServerParams{
-		Name:       name + "_" + allocatedIP.Address,
-		Region:     region,
-		Type:       serverType,
-		HourlyCost: hourlyConst,
-		Address:    allocatedIP.Address, // pgtype.UUIDallocatedIP.Address,
-		Status:     util.ServerStatusProvisioning,
+		Name:                 name,
+		Hostname:             hostname,
+		Region:               region,
+		Project:              opts.Project,
+		Type:                 serverType,
+		HourlyCost:           hourlyRate,
+		BillingModel:         opts.BillingModel,
+		PurchaseOption:       opts.PurchaseOption,
+		SpotMaxPrice:         opts.SpotMaxPrice,
+		InterruptionBehavior: opts.InterruptionBehavior,
+		Address:              allocatedIP.Address, // pgtype.UUIDallocatedIP.Address,
+		Status:               util.ServerStatusProvisioning,
+		AssignPublicIp:       opts.AssignPublicIP,
+		Tags:                 tags,
+		UserData:             opts.UserData,
+		DiskGb:               opts.DiskGB,
 	}
 	server, err := s.queries.CreateNewServer(ctx, createServerParams)
 	if err != nil {
 		s.logger.Error("Failed to create server in DB", zap.Error(err), zap.String("ip_id", allocatedIP.ID.String()))
 		// Important: If server creation fails, deallocate the IP!
+		if releaseErr := s.ipAllocator.ReleaseIP(ctx, allocatedIP); releaseErr != nil {
+			s.logger.Error("Failed to release IP after server creation failure", zap.Error(releaseErr), zap.String("ip_id", allocatedIP.ID.String()))
+		}
 		return sqlc.Server{}, fmt.Errorf("failed to create server: %+v", err)
 	}
 
-	deallocateErr := s.ipAllocator.saveAllocatedIP(ctx, server.ID, allocatedIP.ID)
-	if deallocateErr != nil {
-		s.logger.Error("Failed to deallocate IP after server creation failure", zap.Error(deallocateErr), zap.String("ip_id", allocatedIP.ID.String()))
+	if err := s.bindPrimaryIP(ctx, server, pgtype.UUID{}, allocatedIP); err != nil {
+		s.rollbackProvision(ctx, server, allocatedIP, false)
+		return sqlc.Server{}, err
 	}
+	s.finishProvision(ctx, server)
 
-	_, err = s.queries.AppendServerLifecycleLog(ctx, sqlc.AppendServerLifecycleLogParams{
-		Column1: []byte(`{"REQUEST_ID":"` + string(middleware.GetReqID(ctx)) + `","ACTION": "Server provisioned successfully","SERVER_ID":"` + server.ID.String() + `","TIME":"` + time.Now().String() + `"}`),
-		ID:      server.ID,
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:     EventProvisioned,
+		ToStatus: server.Status,
+		Message:  "Server provisioned successfully",
+		Details:  map[string]any{"type": server.Type, "region": server.Region, "address": allocatedIP.Address},
 	})
-	if err != nil {
-		s.logger.Warn("Failed to append initial provisioning lifecycle log",
-			zap.Error(err),
-			zap.String("server_id", server.ID.String()),
-			zap.String("request_id", middleware.GetReqID(ctx)),
-		)
-	}
 
 	s.logger.Info("Server provisioned successfully",
 		zap.String("server_id", server.ID.String()),
 		zap.String("ip_address", allocatedIP.Address),
+		zap.String("hostname", server.Hostname),
 	)
 
 	return server, nil
 }
 
+// bindPrimaryIP binds a provisioning server's private IP to its primary network
+// interface, creating the interface (device 0) unless primaryInterfaceID names one
+// an earlier attempt left behind.
+func (s *ServerService) bindPrimaryIP(ctx context.Context, server sqlc.Server, primaryInterfaceID pgtype.UUID, allocatedIP sqlc.IpAddress) error {
+	if !primaryInterfaceID.Valid {
+		primaryInterface, err := s.queries.CreateNetworkInterface(ctx, sqlc.CreateNetworkInterfaceParams{
+			ServerID:    server.ID,
+			DeviceIndex: 0,
+			IsPrimary:   true,
+		})
+		if err != nil {
+			s.logger.Error("Failed to create primary network interface", zap.Error(err), zap.String("server_id", server.ID.String()))
+			return fmt.Errorf("failed to create network interface: %+v", err)
+		}
+		primaryInterfaceID = primaryInterface.ID
+	}
+
+	if err := s.ipAllocator.saveAllocatedIP(ctx, server.ID, primaryInterfaceID, true, allocatedIP.ID); err != nil {
+		s.logger.Error("Failed to bind IP to primary network interface", zap.Error(err), zap.String("ip_id", allocatedIP.ID.String()))
+		return fmt.Errorf("failed to bind IP address: %+v", err)
+	}
+	return nil
+}
+
+// finishProvision starts metering the disk of a server whose primary IP is bound
+// and maps a public IP to it, if it asked for one. Failures are logged only.
+func (s *ServerService) finishProvision(ctx context.Context, server sqlc.Server) {
+	// The disk is billed from now on, whatever state the server is in, until it is terminated
+	if err := s.openResourceSegment(ctx, s.queries, server.ID, ResourceDisk, float64(server.DiskGb)); err != nil {
+		s.logger.Error("Failed to start metering disk", zap.Error(err), zap.String("server_id", server.ID.String()))
+	}
+
+	if server.AssignPublicIp {
+		if _, err := s.assignPublicIP(ctx, server); err != nil {
+			s.logger.Error("Failed to assign public IP", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+}
+
+// rollbackProvision undoes a provisioning attempt that failed before the server's
+// private IP was bound: the IP returns to the pool and, unless keepServer is set,
+// the server row goes, taking its interfaces with it. A server being reprovisioned
+// is kept, still provisioning, for the stuck-state watchdog to retry or move to error.
+func (s *ServerService) rollbackProvision(ctx context.Context, server sqlc.Server, allocatedIP sqlc.IpAddress, keepServer bool) {
+	if !keepServer {
+		if err := s.queries.DeleteServer(ctx, server.ID); err != nil {
+			s.logger.Error("Failed to delete server after provisioning failure", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+	if err := s.ipAllocator.ReleaseIP(ctx, allocatedIP); err != nil {
+		s.logger.Error("Failed to release IP after provisioning failure", zap.Error(err), zap.String("ip_id", allocatedIP.ID.String()))
+	}
+}
+
+// ReprovisionServer re-runs the provisioning of a server left in provisioning
+// without its primary address, e.g. by a restart between creating the server and
+// binding its IP. A fresh private IP from the region's pool is bound to the
+// primary interface, created if the earlier attempt did not get to it, and becomes
+// the server's address; the address the earlier attempt reserved is released by
+// the consistency check as orphaned. If binding fails the fresh IP returns to the
+// pool and the server is left as it was.
+func (s *ServerService) ReprovisionServer(ctx context.Context, server sqlc.Server) (sqlc.Server, error) {
+	if server.Status != util.ServerStatusProvisioning {
+		return sqlc.Server{}, fmt.Errorf("%+v in status %s", "cannot reprovision a server", server.Status)
+	}
+	poolName, err := s.privatePoolFor(server.Region)
+	if err != nil {
+		return sqlc.Server{}, err
+	}
+	interfaces, err := s.queries.ListNetworkInterfacesByServerIDs(ctx, []pgtype.UUID{server.ID})
+	if err != nil {
+		return sqlc.Server{}, fmt.Errorf("failed to list network interfaces: %+v", err)
+	}
+	var primaryInterfaceID pgtype.UUID
+	for _, networkInterface := range interfaces {
+		if networkInterface.IsPrimary {
+			primaryInterfaceID = networkInterface.ID
+		}
+	}
+
+	allocatedIP, err := s.ipAllocator.AllocateIPFromPool(ctx, poolName)
+	if err != nil {
+		s.logger.Error("Failed to allocate IP address", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return sqlc.Server{}, errors.New("failed to allocate IP address")
+	}
+	if err := s.bindPrimaryIP(ctx, server, primaryInterfaceID, allocatedIP); err != nil {
+		s.rollbackProvision(ctx, server, allocatedIP, true)
+		return sqlc.Server{}, err
+	}
+	// From here on the server counts as provisioned; a failure to record the new
+	// address is an address mismatch the consistency check repairs
+	updatedServer, err := s.queries.SetServerAddress(ctx, sqlc.SetServerAddressParams{
+		Address: allocatedIP.Address,
+		ID:      server.ID,
+	})
+	if err != nil {
+		return sqlc.Server{}, fmt.Errorf("failed to set server address: %+v", err)
+	}
+	s.finishProvision(ctx, updatedServer)
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:     EventProvisioned,
+		ToStatus: updatedServer.Status,
+		Message:  "Server reprovisioned",
+		Details:  map[string]any{"address": allocatedIP.Address, "previousAddress": server.Address},
+	})
+	s.logger.Info("Server reprovisioned",
+		zap.String("server_id", server.ID.String()),
+		zap.String("ip_address", allocatedIP.Address),
+	)
+	return updatedServer, nil
+}
+
 // StartServer changes server status to running.
 func (s *ServerService) StartServer(ctx context.Context, server sqlc.Server) (sqlc.Server, error) {
 
@@ -107,25 +317,58 @@ func (s *ServerService) StartServer(ctx context.Context, server sqlc.Server) (sq
 		)
 		return sqlc.Server{}, fmt.Errorf("%+v from %s to %s", "invalid state transition", server.Status, util.ServerStatusRunning)
 	}
+	if err := s.checkSpotBid(ctx, server); err != nil {
+		return sqlc.Server{}, err
+	}
+	if err := s.checkAccount(ctx, server); err != nil {
+		return sqlc.Server{}, err
+	}
 
-	updatedServer, err := s.queries.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
-		Status: util.ServerStatusRunning,
-		ID:     server.ID,
-	})
+	updatedServer, err := s.startRunning(ctx, server)
 	if err != nil {
-		s.logger.Error("Failed to update server status to running", zap.Error(err), zap.String("server_id", server.ID.String()))
+		s.logger.Error("Failed to start server", zap.Error(err), zap.String("server_id", server.ID.String()))
 		return sqlc.Server{}, err
 	}
 
-	//  to update application logs and maintain the ;limit of the logs
-	//
-	err = AppendServerLifecycleLogs(s, nil, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Server start initiated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   util.ServerStatusRunning,
+		Message:    "Server start initiated",
+	})
+
+	s.logger.Info("Server started", zap.String("server_id", server.ID.String()))
+	return updatedServer, nil
+}
 
+// startRunning does what every move to running does: it updates the status and
+// starts metering the server in one transaction, then, if its public IP was
+// released on stop, maps a new one. Metering and the public IP are left as they
+// are for a server that was already running.
+func (s *ServerService) startRunning(ctx context.Context, server sqlc.Server) (sqlc.Server, error) {
+	var updatedServer sqlc.Server
+	err := s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		updatedServer, err = q.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
+			Status:          util.ServerStatusRunning,
+			ID:              server.ID,
+			LastStatusActor: eventActor(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update server status to running: %+v", err)
+		}
+		return s.openUsageSegment(ctx, q, updatedServer)
+	})
 	if err != nil {
-		s.logger.Warn("Failed to append reboot log", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return sqlc.Server{}, err
 	}
 
-	s.logger.Info("Server started", zap.String("server_id", server.ID.String()))
+	// A public IP released on stop is replaced with a new one on start
+	if updatedServer.AssignPublicIp {
+		if _, err := s.assignPublicIP(ctx, updatedServer); err != nil {
+			s.logger.Error("Failed to assign public IP on start", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
 	return updatedServer, nil
 }
 
@@ -140,24 +383,39 @@ func (s *ServerService) StopServer(ctx context.Context, server sqlc.Server) (sql
 		return sqlc.Server{}, fmt.Errorf("%+v from %s to %s", "invalid state transition", server.Status, util.ServerStatusStopped)
 	}
 
-	updatedServer, err := s.queries.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
-		Status: util.ServerStatusStopped,
-		ID:     server.ID,
+	// The status and metering change together, so a stopped server is never billed
+	var updatedServer sqlc.Server
+	err := s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		var err error
+		updatedServer, err = q.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
+			Status:          util.ServerStatusStopped,
+			ID:              server.ID,
+			LastStatusActor: eventActor(ctx),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update server status to stopped: %+v", err)
+		}
+		return s.closeUsageSegment(ctx, q, server.ID)
 	})
-
 	if err != nil {
-		s.logger.Error("Failed to update server status to stopped", zap.Error(err), zap.String("server_id", server.ID.String()))
+		s.logger.Error("Failed to stop server", zap.Error(err), zap.String("server_id", server.ID.String()))
 		return sqlc.Server{}, err
 	}
 
-	//  to update application logs and maintain the ;limit of the logs
-	//
-	err = AppendServerLifecycleLogs(s, nil, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Server stop initiated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
-
-	if err != nil {
-		s.logger.Warn("Failed to append reboot log", zap.Error(err), zap.String("server_id", server.ID.String()))
+	// Public IPs are returned to the pool on stop; the private IP is kept until terminate
+	if s.config.ReleasePublicIPOnStop {
+		if err := s.releasePublicIP(ctx, server); err != nil {
+			s.logger.Error("Failed to release public IP on stop", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
 	}
 
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   util.ServerStatusStopped,
+		Message:    "Server stop initiated",
+	})
+
 	s.logger.Info("Server stopped", zap.String("server_id", server.ID.String()))
 	return updatedServer, nil
 }
@@ -167,27 +425,100 @@ func (s *ServerService) RebootServer(ctx context.Context, server sqlc.Server) (s
 	// A reboot is often an intermediate state in real systems.
 	// For simplicity, we'll model it as a direct transition to running,
 	// potentially with a brief 'rebooting' log.
-	if server.Status == util.ServerStatusTerminated {
-		s.logger.Warn("Cannot reboot server in terminal state", zap.String("server_id", server.ID.String()), zap.String("status", string(server.Status)))
+	if server.Status == util.ServerStatusTerminated || server.Status == util.ServerStatusSuspended {
+		s.logger.Warn("Cannot reboot terminated or suspended server", zap.String("server_id", server.ID.String()), zap.String("status", string(server.Status)))
 		return sqlc.Server{}, fmt.Errorf("%+v: cannot reboot from %s", "invalid state transition", server.Status)
 	}
+	if server.Status != util.ServerStatusRunning {
+		if err := s.checkSpotBid(ctx, server); err != nil {
+			return sqlc.Server{}, err
+		}
+		if err := s.checkAccount(ctx, server); err != nil {
+			return sqlc.Server{}, err
+		}
+	}
 
 	// Log the reboot initiation
-	err := AppendServerLifecycleLogs(s, nil, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Server reboot initiated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   util.ServerStatusRunning,
+		Message:    "Server reboot initiated",
+	})
+
+	// Rebooting a running server keeps its segment and public IP; rebooting a
+	// stopped one starts like StartServer does
+	updatedServer, err := s.startRunning(ctx, server)
+	if err != nil {
+		s.logger.Error("Failed to update server status to running after reboot", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return sqlc.Server{}, err
+	}
+	s.logger.Info("Server rebooted", zap.String("server_id", server.ID.String()))
+	return updatedServer, nil
+}
 
+// ResizeServer changes the type of an on-demand server, and its hourly cost to the
+// catalog price of the new type. A running server keeps running; its usage segment
+// is split so each part is charged at its own type.
+func (s *ServerService) ResizeServer(ctx context.Context, server sqlc.Server, serverType string) (sqlc.Server, error) {
+	if server.Status == util.ServerStatusTerminated {
+		return sqlc.Server{}, ErrServerTerminated
+	}
+	if !util.IsValidServerType(serverType) {
+		return sqlc.Server{}, fmt.Errorf("invalid server type %q", serverType)
+	}
+	if server.PurchaseOption == PurchaseOptionSpot {
+		return sqlc.Server{}, ErrSpotResize
+	}
+
+	catalog, err := LoadPriceCatalog(ctx, s.queries)
 	if err != nil {
-		s.logger.Warn("Failed to append reboot log", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return sqlc.Server{}, err
+	}
+	hourlyRate, err := catalog.RateAt(serverType, server.Region, time.Now())
+	if err != nil {
+		return sqlc.Server{}, err
 	}
 
-	updatedServer, err := s.queries.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
-		Status: util.ServerStatusRunning, // Assuming reboot completes to running state
-		ID:     server.ID,
+	// The segment is split in the same transaction as the resize, so a running
+	// server is never left unmetered or metered at its old type
+	running := server.Status == util.ServerStatusRunning
+	var updatedServer sqlc.Server
+	err = s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		if running {
+			if err := s.closeUsageSegment(ctx, q, server.ID); err != nil {
+				return err
+			}
+		}
+		updatedServer, err = q.ResizeServer(ctx, sqlc.ResizeServerParams{
+			Type:       serverType,
+			HourlyCost: hourlyRate,
+			ID:         server.ID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to resize server: %+v", err)
+		}
+		if running {
+			return s.openUsageSegment(ctx, q, updatedServer)
+		}
+		return nil
 	})
 	if err != nil {
-		s.logger.Error("Failed to update server status to running after reboot", zap.Error(err), zap.String("server_id", server.ID.String()))
 		return sqlc.Server{}, err
 	}
-	s.logger.Info("Server rebooted", zap.String("server_id", server.ID.String()))
+
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:    EventResized,
+		Message: "Server resized from " + server.Type + " to " + serverType,
+		Details: map[string]any{"fromType": server.Type, "toType": serverType, "hourlyCost": hourlyRate},
+	})
+
+	s.logger.Info("Server resized",
+		zap.String("server_id", server.ID.String()),
+		zap.String("from_type", server.Type),
+		zap.String("to_type", serverType),
+		zap.Float64("hourly_cost", hourlyRate),
+	)
 	return updatedServer, nil
 }
 
@@ -202,39 +533,57 @@ func (s *ServerService) TerminateServer(ctx context.Context, server sqlc.Server)
 		return sqlc.Server{}, fmt.Errorf("%+v from %s to %s", "invalid state transition", server.Status, util.ServerStatusTerminated)
 	}
 
-	// Update server status to terminated
-	_, err := s.queries.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
-		Status: util.ServerStatusTerminated,
-		ID:     server.ID,
-	})
-	if err != nil {
-		return sqlc.Server{}, fmt.Errorf("failed to update server status to terminated: %+v", err)
-	}
-
-	// Deallocate IP address
-	ipData, err := s.queries.GetIPAddressByServerID(ctx, server.ID)
-	if err != nil {
-		return sqlc.Server{}, fmt.Errorf("failed to get IP address by server ID: %+v", err)
-	}
-	_, err = s.queries.DeallocateIPAddress(ctx, ipData.ID)
-	if err != nil {
-		return sqlc.Server{}, fmt.Errorf("failed to deallocate IP address: %+v", err)
-	}
+	// Metering stops, the status changes and the addresses and NAT mappings are
+	// released together, so a failure leaves the server live, billed and bound to retry
+	var released []sqlc.IpAddress
+	err := s.db.WithTx(ctx, func(q *sqlc.Queries) error {
+		released = nil
+		if err := s.closeUsageSegment(ctx, q, server.ID); err != nil {
+			return err
+		}
+		if err := q.CloseResourceSegmentsByServerID(ctx, server.ID); err != nil {
+			return fmt.Errorf("failed to close resource segments: %+v", err)
+		}
+		if _, err := q.UpdateServerStatus(ctx, sqlc.UpdateServerStatusParams{
+			Status:          util.ServerStatusTerminated,
+			ID:              server.ID,
+			LastStatusActor: eventActor(ctx),
+		}); err != nil {
+			return fmt.Errorf("failed to update server status to terminated: %+v", err)
+		}
 
+		// Deallocate every IP address bound to the server's interfaces
+		ipAddresses, err := q.ListIPAddressesByServerID(ctx, server.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get IP addresses by server ID: %+v", err)
+		}
+		for _, ipData := range ipAddresses {
+			ip, err := deallocateIP(ctx, q, ipData)
+			if err != nil {
+				return err
+			}
+			released = append(released, ip)
+		}
+		if err := q.ReleaseNATMappingsByServerID(ctx, server.ID); err != nil {
+			return fmt.Errorf("failed to release NAT mappings: %+v", err)
+		}
+		return nil
+	})
 	if err != nil {
 		s.logger.Error("Failed to terminate server or deallocate IP", zap.Error(err), zap.String("server_id", server.ID.String()))
 		return sqlc.Server{}, err
 	}
+	// Only committed releases go back to the pools
+	s.ipAllocator.freeInPools(released)
 
 	s.logger.Info("Server terminated and IP deallocated", zap.String("server_id", server.ID.String()))
 
-	//  to update application logs and maintain the ;limit of the logs
-	//
-	err = AppendServerLifecycleLogs(s, nil, ctx, server.ID, []byte(`{"REQUEST_ID":"`+string(middleware.GetReqID(ctx))+`","ACTION": "Server terminated","SERVER_ID":"`+server.ID.String()+`","TIME":"`+time.Now().String()+`"}`))
-
-	if err != nil {
-		s.logger.Warn("Failed to append reboot log", zap.Error(err), zap.String("server_id", server.ID.String()))
-	}
+	s.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: server.Status,
+		ToStatus:   util.ServerStatusTerminated,
+		Message:    "Server terminated",
+	})
 
 	// Re-fetch the server to return the updated state (if you need the full updated object)
 	// Or simply return the original server with updated status if that's sufficient
@@ -247,53 +596,6 @@ func (s *ServerService) TerminateServer(ctx context.Context, server sqlc.Server)
 
 }
 
-// AppendServerLifecycleLogs updates the lifecycle_logs JSONB array for a server.
-// It appends a new log entry, rotating if the array exceeds 100 entries.
-// It returns the updated lifecycle_logs as []byte (JSONB) and an error.
-func AppendServerLifecycleLogs(s *ServerService, bd *BillingDaemon, ctx context.Context, serverID pgtype.UUID, message []byte) error {
-	if s == nil {
-		return fmt.Errorf("nil ServerService passed to AppendServerLifecycleLogs")
-	}
-	if s.queries != nil {
-		updatedLogs, err := s.queries.AppendServerLifecycleLog(ctx, sqlc.AppendServerLifecycleLogParams{
-			Column1: message,
-			ID:      serverID,
-		})
-		if err != nil {
-			s.logger.Error("Failed to append lifecycle log", zap.Error(err), zap.String("server_id, ", serverID.String()))
-			return err
-		}
-
-		if len(updatedLogs) > 100 {
-			err := s.queries.EnforceLifecycleLogsLimit(ctx, serverID)
-			if err != nil {
-				s.logger.Error("Failed to append lifecycle log", zap.Error(err), zap.String("server_id", serverID.String()))
-				return err
-			}
-		}
-		return nil
-	} else {
-		updatedLogs, err := bd.queries.AppendServerLifecycleLog(ctx, sqlc.AppendServerLifecycleLogParams{
-			Column1: message,
-			ID:      serverID,
-		})
-		if err != nil {
-			bd.logger.Error("Failed to append lifecycle log", zap.Error(err), zap.String("server_id, ", serverID.String()))
-			return err
-		}
-
-		if len(updatedLogs) > 100 {
-			err := bd.queries.EnforceLifecycleLogsLimit(ctx, serverID)
-			if err != nil {
-				bd.logger.Error("Failed to append lifecycle log", zap.Error(err), zap.String("server_id", serverID.String()))
-				return err
-			}
-		}
-	}
-	return nil
-
-}
-
 // StringToPGUUID : function to convert string to pgtype.UUID
 func StringToPGUUID(s string) pgtype.UUID {
 	var pgUUID pgtype.UUID
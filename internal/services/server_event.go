@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// Types of server event.
+const (
+	EventProvisioned      = "provisioned"
+	EventStatusChange     = "status_change"
+	EventResized          = "resized"
+	EventRenamed          = "renamed"
+	EventNetwork          = "network"
+	EventSpotNotice       = "spot_notice"
+	EventSpotInterruption = "spot_interruption"
+	EventBudget           = "budget"
+	EventReaper           = "reaper"
+	EventRepair           = "repair"
+	EventWatchdog         = "watchdog"
+	EventRecommendation   = "recommendation"
+)
+
+// ServerEventTypes lists every type of server event.
+var ServerEventTypes = []string{
+	EventProvisioned, EventStatusChange, EventResized, EventRenamed, EventNetwork,
+	EventSpotNotice, EventSpotInterruption, EventBudget, EventReaper, EventRepair,
+	EventWatchdog, EventRecommendation,
+}
+
+// Actors of server events: who caused them.
+const (
+	// ActorAPI is a caller of the HTTP API.
+	ActorAPI = "api"
+	// ActorSystem is the service itself, outside any request or daemon.
+	ActorSystem      = "system"
+	ActorBilling     = "billing"
+	ActorBudget      = "budget"
+	ActorAccount     = "account"
+	ActorReaper      = "reaper"
+	ActorSpotMarket  = "spot_market"
+	ActorWatchdog    = "watchdog"
+	ActorConsistency = "consistency"
+)
+
+// eventActorKey is the context key of the actor events are recorded for.
+type eventActorKey struct{}
+
+// WithEventActor returns a copy of ctx whose server events are recorded for actor.
+func WithEventActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, eventActorKey{}, actor)
+}
+
+// eventActor returns the actor set on ctx by WithEventActor, or ActorAPI for
+// requests and ActorSystem otherwise.
+func eventActor(ctx context.Context) string {
+	if actor, ok := ctx.Value(eventActorKey{}).(string); ok {
+		return actor
+	}
+	if middleware.GetReqID(ctx) != "" {
+		return ActorAPI
+	}
+	return ActorSystem
+}
+
+// ServerEvent is an event to record in a server's history.
+type ServerEvent struct {
+	Type string
+	// FromStatus and ToStatus are set for status changes.
+	FromStatus string
+	ToStatus   string
+	Message    string
+	// Details are added to the payload next to the message.
+	Details map[string]any
+}
+
+// RecordEvent appends an event to a server's history, with the actor and request
+// ID of ctx. Failures are logged rather than returned, as no lifecycle action
+// should fail for its event.
+func (s *ServerService) RecordEvent(ctx context.Context, serverID pgtype.UUID, event ServerEvent) {
+	payload := map[string]any{"message": event.Message}
+	for key, value := range event.Details {
+		payload[key] = value
+	}
+	encoded, err := json.Marshal(payload)
+	if err == nil {
+		requestID := middleware.GetReqID(ctx)
+		_, err = s.queries.RecordServerEvent(ctx, sqlc.RecordServerEventParams{
+			ServerID:   serverID,
+			Type:       event.Type,
+			FromStatus: pgtype.Text{String: event.FromStatus, Valid: event.FromStatus != ""},
+			ToStatus:   pgtype.Text{String: event.ToStatus, Valid: event.ToStatus != ""},
+			Actor:      eventActor(ctx),
+			RequestID:  pgtype.Text{String: requestID, Valid: requestID != ""},
+			Payload:    encoded,
+		})
+	}
+	if err != nil {
+		s.logger.Warn("Failed to record server event",
+			zap.Error(err),
+			zap.String("server_id", serverID.String()),
+			zap.String("type", event.Type),
+			zap.String("message", event.Message),
+		)
+	}
+}
+
+// PruneEvents deletes the server events older than SERVER_EVENTS_RETENTION as of now.
+func (s *ServerService) PruneEvents(ctx context.Context, now time.Time) (int64, error) {
+	pruned, err := s.queries.PruneServerEvents(ctx, pgtype.Timestamptz{Time: now.Add(-s.config.ServerEventsRetention), Valid: true})
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune server events: %+v", err)
+	}
+	return pruned, nil
+}
+
+// ServerEventFilter selects the events ListEvents returns; zero fields match any.
+type ServerEventFilter struct {
+	Types []string
+	From  time.Time
+	To    time.Time
+}
+
+// ListEvents returns a server's events matching filter, newest first.
+func (s *ServerService) ListEvents(ctx context.Context, serverID pgtype.UUID, filter ServerEventFilter, limit, offset int) ([]sqlc.ServerEvent, error) {
+	events, err := s.queries.ListServerEvents(ctx, sqlc.ListServerEventsParams{
+		ServerID:  serverID,
+		Types:     filter.Types,
+		FromTime:  pgtype.Timestamptz{Time: filter.From, Valid: !filter.From.IsZero()},
+		ToTime:    pgtype.Timestamptz{Time: filter.To, Valid: !filter.To.IsZero()},
+		RowLimit:  int32(limit),
+		RowOffset: int32(offset),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list server events: %+v", err)
+	}
+	return events, nil
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// TestEventActor checks that requests carrying the router's request ID are
+// attributed to the API, and everything else to the system unless set.
+func TestEventActor(t *testing.T) {
+	var actor string
+	handler := middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actor = eventActor(r.Context())
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if actor != ActorAPI {
+		t.Errorf("request actor = %q, want %q", actor, ActorAPI)
+	}
+
+	if actor := eventActor(context.Background()); actor != ActorSystem {
+		t.Errorf("background actor = %q, want %q", actor, ActorSystem)
+	}
+	if actor := eventActor(WithEventActor(context.Background(), ActorWatchdog)); actor != ActorWatchdog {
+		t.Errorf("watchdog actor = %q, want %q", actor, ActorWatchdog)
+	}
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+const (
+	// PurchaseOptionOnDemand servers run until they are stopped, at catalog prices.
+	PurchaseOptionOnDemand = "on_demand"
+	// PurchaseOptionSpot servers run at the simulated spot price for as long as
+	// their bid (spot max price) is not below it.
+	PurchaseOptionSpot = "spot"
+
+	// InterruptionBehaviorTerminate terminates an interrupted spot server.
+	InterruptionBehaviorTerminate = "terminate"
+	// InterruptionBehaviorStop stops an interrupted spot server; it can be started
+	// again once the spot price is back under its bid.
+	InterruptionBehaviorStop = "stop"
+)
+
+var (
+	// ErrInvalidSpotOptions is returned for purchase options that cannot be provisioned.
+	ErrInvalidSpotOptions = errors.New("invalid spot options")
+	// ErrSpotBidTooLow is returned when a spot server's max price is below the spot price.
+	ErrSpotBidTooLow = errors.New("the spot max price is below the current spot price")
+	// ErrSpotResize is returned when changing the type of a spot server.
+	ErrSpotResize = errors.New("spot servers cannot be resized")
+)
+
+// validateSpotOptions checks the purchase options of a new server and fills in
+// their defaults, except for the max price, which defaults to the on-demand price.
+func validateSpotOptions(opts *ProvisionOptions) error {
+	if opts.PurchaseOption == "" {
+		opts.PurchaseOption = PurchaseOptionOnDemand
+	}
+	switch opts.PurchaseOption {
+	case PurchaseOptionOnDemand:
+		opts.SpotMaxPrice, opts.InterruptionBehavior = 0, ""
+		return nil
+	case PurchaseOptionSpot:
+	default:
+		return fmt.Errorf("%w: purchase option must be %s or %s", ErrInvalidSpotOptions, PurchaseOptionOnDemand, PurchaseOptionSpot)
+	}
+
+	if opts.BillingModel == BillingModelReserved {
+		return fmt.Errorf("%w: spot servers cannot use reservations", ErrInvalidSpotOptions)
+	}
+	if opts.SpotMaxPrice < 0 {
+		return fmt.Errorf("%w: spot max price must not be negative", ErrInvalidSpotOptions)
+	}
+	if opts.InterruptionBehavior == "" {
+		opts.InterruptionBehavior = InterruptionBehaviorTerminate
+	}
+	if opts.InterruptionBehavior != InterruptionBehaviorTerminate && opts.InterruptionBehavior != InterruptionBehaviorStop {
+		return fmt.Errorf("%w: interruption behavior must be %s or %s", ErrInvalidSpotOptions, InterruptionBehaviorTerminate, InterruptionBehaviorStop)
+	}
+	return nil
+}
+
+// currentSpotPrice returns the spot price of a server type in a region. A market
+// without a price yet opens at the on-demand price less SPOT_DISCOUNT.
+func currentSpotPrice(ctx context.Context, queries *sqlc.Queries, cfg *config.Config, catalog PriceCatalog, serverType, region string, now time.Time) (float64, error) {
+	price, err := queries.GetCurrentSpotPrice(ctx, sqlc.GetCurrentSpotPriceParams{ServerType: serverType, Region: region})
+	if err == nil {
+		return price.Price, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to get spot price: %+v", err)
+	}
+
+	onDemand, err := catalog.RateAt(serverType, region, now)
+	if err != nil {
+		return 0, err
+	}
+	price, err = queries.CreateSpotPrice(ctx, sqlc.CreateSpotPriceParams{
+		ServerType:    serverType,
+		Region:        region,
+		Price:         onDemand * (1 - cfg.SpotDiscount),
+		EffectiveFrom: pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to open spot market: %+v", err)
+	}
+	return price.Price, nil
+}
+
+// LoadSpotPriceCatalog reads the spot price history in effect from since on as a
+// catalog, so spot usage is priced like on-demand usage, slice by slice.
+func LoadSpotPriceCatalog(ctx context.Context, queries *sqlc.Queries, since time.Time) (PriceCatalog, error) {
+	prices, err := queries.ListSpotPricesSince(ctx, pgtype.Timestamptz{Time: since, Valid: true})
+	if err != nil {
+		return PriceCatalog{}, fmt.Errorf("failed to list spot prices: %+v", err)
+	}
+
+	catalog := PriceCatalog{versions: make(map[priceKey][]sqlc.Price)}
+	for _, price := range prices {
+		key := priceKey{price.ServerType, price.Region}
+		catalog.versions[key] = append(catalog.versions[key], sqlc.Price{
+			ID:            price.ID,
+			ServerType:    price.ServerType,
+			Region:        price.Region,
+			HourlyRate:    price.Price,
+			Currency:      CurrencyUSD,
+			EffectiveFrom: price.EffectiveFrom,
+		})
+	}
+	return catalog, nil
+}
+
+// SpotMarket simulates the spot market: it moves the spot price of every market
+// on each tick and interrupts the spot servers it outbids.
+type SpotMarket struct {
+	queries       *sqlc.Queries
+	serverService *ServerService
+	logger        *zap.Logger
+	config        *config.Config
+	mutex         sync.Mutex
+}
+
+// NewSpotMarketDaemon creates a new SpotMarket.
+func NewSpotMarketDaemon(queries *sqlc.Queries, serverService *ServerService, logger *zap.Logger, config *config.Config) *SpotMarket {
+	return &SpotMarket{
+		queries:       queries,
+		serverService: serverService,
+		logger:        logger,
+		config:        config,
+	}
+}
+
+// Start moves the market every SPOT_MARKET_INTERVAL until ctx is cancelled.
+func (m *SpotMarket) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.config.SpotMarketInterval)
+	defer ticker.Stop()
+
+	ctx = WithEventActor(ctx, ActorSpotMarket)
+	m.logger.Info("Spot market daemon started", zap.Duration("interval", m.config.SpotMarketInterval))
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.Info("Spot market daemon stopped due to context cancellation.")
+			return
+		case <-ticker.C:
+			m.tick(ctx, time.Now())
+		}
+	}
+}
+
+// tick moves every market's price, then carries out the interruptions whose
+// notice has run out.
+func (m *SpotMarket) tick(ctx context.Context, now time.Time) {
+	markets, err := m.queries.ListCurrentSpotPrices(ctx, sqlc.ListCurrentSpotPricesParams{})
+	if err != nil {
+		m.logger.Error("Failed to list spot markets", zap.Error(err))
+		return
+	}
+	catalog, err := LoadPriceCatalog(ctx, m.queries)
+	if err != nil {
+		m.logger.Error("Failed to load price catalog", zap.Error(err))
+		return
+	}
+
+	for _, market := range markets {
+		onDemand, err := catalog.RateAt(market.ServerType, market.Region, now)
+		if err != nil {
+			m.logger.Warn("Spot market has no on-demand price",
+				zap.String("type", market.ServerType), zap.String("region", market.Region), zap.Error(err))
+			continue
+		}
+		if _, err := m.SetPrice(ctx, market.ServerType, market.Region, m.nextPrice(market.Price, onDemand), now); err != nil {
+			m.logger.Error("Failed to move spot price",
+				zap.String("type", market.ServerType), zap.String("region", market.Region), zap.Error(err))
+		}
+	}
+
+	m.interruptDue(ctx, now)
+}
+
+// nextPrice is one step of a random walk that reverts to the opening price of the
+// market (on-demand less SPOT_DISCOUNT) and stays between SPOT_PRICE_FLOOR of
+// the on-demand price and the on-demand price itself.
+func (m *SpotMarket) nextPrice(current, onDemand float64) float64 {
+	target := onDemand * (1 - m.config.SpotDiscount)
+	next := current + 0.2*(target-current) + m.config.SpotVolatility*target*rand.NormFloat64()
+	next = math.Max(next, onDemand*m.config.SpotPriceFloor)
+	next = math.Min(next, onDemand)
+	return math.Round(next*1e6) / 1e6
+}
+
+// SetPrice moves the spot price of a market and gives every running spot server
+// bidding below it its interruption notice. The servers are interrupted once
+// SPOT_INTERRUPTION_NOTICE has passed, even if the price drops back meanwhile.
+func (m *SpotMarket) SetPrice(ctx context.Context, serverType, region string, price float64, now time.Time) (sqlc.SpotPrice, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	spotPrice, err := m.queries.CreateSpotPrice(ctx, sqlc.CreateSpotPriceParams{
+		ServerType:    serverType,
+		Region:        region,
+		Price:         price,
+		EffectiveFrom: pgtype.Timestamptz{Time: now, Valid: true},
+	})
+	if err != nil {
+		return sqlc.SpotPrice{}, fmt.Errorf("failed to create spot price: %+v", err)
+	}
+	err = m.queries.SetSpotServerHourlyCosts(ctx, sqlc.SetSpotServerHourlyCostsParams{Type: serverType, Region: region, Price: price})
+	if err != nil {
+		return sqlc.SpotPrice{}, fmt.Errorf("failed to update spot server hourly costs: %+v", err)
+	}
+
+	outbid, err := m.queries.NoticeOutbidSpotServers(ctx, sqlc.NoticeOutbidSpotServersParams{
+		Type:     serverType,
+		Region:   region,
+		NoticeAt: pgtype.Timestamptz{Time: now, Valid: true},
+		Price:    price,
+	})
+	if err != nil {
+		return sqlc.SpotPrice{}, fmt.Errorf("failed to notice outbid spot servers: %+v", err)
+	}
+	ctx = WithEventActor(ctx, ActorSpotMarket)
+	for _, server := range outbid {
+		interruptAt := now.Add(m.config.SpotNotice)
+		m.logger.Info("Spot interruption notice",
+			zap.String("server_id", server.ID.String()),
+			zap.Float64("spot_price", price),
+			zap.Float64("spot_max_price", server.SpotMaxPrice),
+			zap.String("action", server.InterruptionBehavior),
+			zap.Time("interrupt_at", interruptAt),
+		)
+		m.serverService.RecordEvent(ctx, server.ID, ServerEvent{
+			Type:    EventSpotNotice,
+			Message: "Spot interruption notice, server will " + server.InterruptionBehavior + " at " + interruptAt.Format(time.RFC3339),
+			Details: map[string]any{"spotPrice": price, "spotMaxPrice": server.SpotMaxPrice, "interruptAt": interruptAt},
+		})
+	}
+	return spotPrice, nil
+}
+
+// interruptDue stops or terminates, per their interruption behavior, the spot
+// servers whose notice has run out. Servers no longer running only lose their notice.
+func (m *SpotMarket) interruptDue(ctx context.Context, now time.Time) {
+	due, err := m.queries.ListDueSpotInterruptions(ctx, pgtype.Timestamptz{Time: now.Add(-m.config.SpotNotice), Valid: true})
+	if err != nil {
+		m.logger.Error("Failed to list due spot interruptions", zap.Error(err))
+		return
+	}
+
+	for _, server := range due {
+		if server.Status == util.ServerStatusRunning {
+			var err error
+			if server.InterruptionBehavior == InterruptionBehaviorStop {
+				_, err = m.serverService.StopServer(ctx, server)
+			} else {
+				_, err = m.serverService.TerminateServer(ctx, server)
+			}
+			if err != nil {
+				m.logger.Error("Failed to interrupt spot server", zap.Error(err), zap.String("server_id", server.ID.String()))
+				continue
+			}
+			m.logger.Info("Spot server interrupted",
+				zap.String("server_id", server.ID.String()),
+				zap.String("action", server.InterruptionBehavior),
+			)
+			m.serverService.RecordEvent(ctx, server.ID, ServerEvent{
+				Type:    EventSpotInterruption,
+				Message: "Server interrupted by the spot market",
+				Details: map[string]any{"action": server.InterruptionBehavior},
+			})
+		}
+		if err := m.queries.ClearSpotInterruptionNotice(ctx, server.ID); err != nil {
+			m.logger.Error("Failed to clear spot interruption notice", zap.Error(err), zap.String("server_id", server.ID.String()))
+		}
+	}
+}
+
+// ListPrices returns the current spot price of every market, or of the markets
+// matching serverType and region when set.
+func (m *SpotMarket) ListPrices(ctx context.Context, serverType, region string) ([]sqlc.SpotPrice, error) {
+	prices, err := m.queries.ListCurrentSpotPrices(ctx, sqlc.ListCurrentSpotPricesParams{
+		ServerType: pgtype.Text{String: serverType, Valid: serverType != ""},
+		Region:     pgtype.Text{String: region, Valid: region != ""},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spot prices: %+v", err)
+	}
+	return prices, nil
+}
+
+// checkSpotBid returns ErrSpotBidTooLow if server is a spot server whose bid is
+// below the spot price, so a stopped spot server only runs again once it is covered.
+func (s *ServerService) checkSpotBid(ctx context.Context, server sqlc.Server) error {
+	if server.PurchaseOption != PurchaseOptionSpot {
+		return nil
+	}
+	catalog, err := LoadPriceCatalog(ctx, s.queries)
+	if err != nil {
+		return err
+	}
+	price, err := currentSpotPrice(ctx, s.queries, s.config, catalog, server.Type, server.Region, time.Now())
+	if err != nil {
+		return err
+	}
+	if server.SpotMaxPrice < price {
+		return ErrSpotBidTooLow
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func TestValidateSpotOptions(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         ProvisionOptions
+		wantErr      bool
+		wantOption   string
+		wantBehavior string
+		wantMaxPrice float64
+	}{
+		{name: "on-demand by default", opts: ProvisionOptions{}, wantOption: PurchaseOptionOnDemand},
+		{
+			name:       "on-demand drops spot settings",
+			opts:       ProvisionOptions{PurchaseOption: PurchaseOptionOnDemand, SpotMaxPrice: 0.5, InterruptionBehavior: InterruptionBehaviorStop},
+			wantOption: PurchaseOptionOnDemand,
+		},
+		{
+			name:         "spot terminates by default",
+			opts:         ProvisionOptions{PurchaseOption: PurchaseOptionSpot, SpotMaxPrice: 0.5},
+			wantOption:   PurchaseOptionSpot,
+			wantBehavior: InterruptionBehaviorTerminate,
+			wantMaxPrice: 0.5,
+		},
+		{
+			name:         "spot that stops",
+			opts:         ProvisionOptions{PurchaseOption: PurchaseOptionSpot, InterruptionBehavior: InterruptionBehaviorStop, BillingModel: BillingModelHourly},
+			wantOption:   PurchaseOptionSpot,
+			wantBehavior: InterruptionBehaviorStop,
+		},
+		{name: "unknown option", opts: ProvisionOptions{PurchaseOption: "reserved"}, wantErr: true},
+		{name: "spot with reservations", opts: ProvisionOptions{PurchaseOption: PurchaseOptionSpot, BillingModel: BillingModelReserved}, wantErr: true},
+		{name: "negative max price", opts: ProvisionOptions{PurchaseOption: PurchaseOptionSpot, SpotMaxPrice: -1}, wantErr: true},
+		{name: "unknown behavior", opts: ProvisionOptions{PurchaseOption: PurchaseOptionSpot, InterruptionBehavior: "hibernate"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := validateSpotOptions(&opts)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidSpotOptions) {
+					t.Fatalf("validateSpotOptions = %v, want ErrInvalidSpotOptions", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("validateSpotOptions failed: %v", err)
+			}
+			if opts.PurchaseOption != tt.wantOption || opts.InterruptionBehavior != tt.wantBehavior || opts.SpotMaxPrice != tt.wantMaxPrice {
+				t.Errorf("options = %s, %q, %v; want %s, %q, %v", opts.PurchaseOption, opts.InterruptionBehavior, opts.SpotMaxPrice,
+					tt.wantOption, tt.wantBehavior, tt.wantMaxPrice)
+			}
+		})
+	}
+}
+
+func TestSpotNextPrice(t *testing.T) {
+	const onDemand = 0.1
+	market := &SpotMarket{config: &config.Config{SpotDiscount: 0.7, SpotPriceFloor: 0.1}}
+
+	// Without volatility the price reverts a fifth of the way to on-demand less the discount
+	if got, want := market.nextPrice(0.08, onDemand), 0.08+0.2*(0.03-0.08); !closeTo(got, want) {
+		t.Errorf("nextPrice(0.08) = %v, want %v", got, want)
+	}
+
+	market.config.SpotVolatility = 5
+	price := onDemand * (1 - market.config.SpotDiscount)
+	for i := 0; i < 1000; i++ {
+		price = market.nextPrice(price, onDemand)
+		// Prices are rounded to a millionth after they are bounded
+		if price < onDemand*market.config.SpotPriceFloor-5e-7 || price > onDemand+5e-7 {
+			t.Fatalf("spot price %v left [%v, %v]", price, onDemand*market.config.SpotPriceFloor, onDemand)
+		}
+		if rounded := math.Round(price*1e6) / 1e6; price != rounded {
+			t.Fatalf("spot price %v is not rounded to a millionth", price)
+		}
+	}
+}
+
+func TestCheckSpotBidOnDemand(t *testing.T) {
+	var s ServerService
+	if err := s.checkSpotBid(context.Background(), sqlc.Server{PurchaseOption: PurchaseOptionOnDemand}); err != nil {
+		t.Errorf("checkSpotBid of an on-demand server = %v, want nil", err)
+	}
+}
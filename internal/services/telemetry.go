@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+const (
+	// minTelemetryStep is the resolution telemetry is stored at.
+	minTelemetryStep = time.Minute
+	// maxTelemetryPoints bounds the number of buckets a series query returns.
+	maxTelemetryPoints = 1440
+	// idleServerShare is the share of servers the generator keeps nearly idle.
+	idleServerShare = 0.2
+)
+
+var (
+	// ErrInvalidTelemetry is returned for samples that cannot be recorded.
+	ErrInvalidTelemetry = errors.New("invalid telemetry")
+	// ErrServerNotRunning is returned for telemetry of a server that is not running.
+	ErrServerNotRunning = errors.New("server is not running")
+	// ErrInvalidTelemetryQuery is returned for series that cannot be queried.
+	ErrInvalidTelemetryQuery = errors.New("invalid telemetry query")
+)
+
+// TelemetrySample is the utilization of a server at one instant, or over the
+// time since its previous sample for the network counters.
+type TelemetrySample struct {
+	SampledAt      time.Time
+	CPUPercent     float64
+	NetworkRxBytes int64
+	NetworkTxBytes int64
+}
+
+// TelemetryService records server utilization and, with TELEMETRY_GENERATOR_ENABLED,
+// synthesizes it for every running server.
+type TelemetryService struct {
+	queries *sqlc.Queries
+	logger  *zap.Logger
+	config  *config.Config
+}
+
+// NewTelemetryService creates a new TelemetryService.
+func NewTelemetryService(queries *sqlc.Queries, logger *zap.Logger, config *config.Config) *TelemetryService {
+	return &TelemetryService{
+		queries: queries,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+// Record stores a sample reported by a running server. A zero SampledAt means now.
+func (t *TelemetryService) Record(ctx context.Context, server sqlc.Server, sample TelemetrySample, now time.Time) error {
+	if server.Status != util.ServerStatusRunning {
+		return ErrServerNotRunning
+	}
+	if sample.SampledAt.IsZero() {
+		sample.SampledAt = now
+	}
+	switch {
+	case sample.CPUPercent < 0 || sample.CPUPercent > 100:
+		return fmt.Errorf("%w: cpuPercent must be between 0 and 100", ErrInvalidTelemetry)
+	case sample.NetworkRxBytes < 0 || sample.NetworkTxBytes < 0:
+		return fmt.Errorf("%w: network bytes cannot be negative", ErrInvalidTelemetry)
+	case sample.SampledAt.After(now.Add(minTelemetryStep)):
+		return fmt.Errorf("%w: sampledAt is in the future", ErrInvalidTelemetry)
+	case sample.SampledAt.Before(now.Add(-t.config.TelemetryRetention)):
+		return fmt.Errorf("%w: sampledAt is older than the telemetry retention", ErrInvalidTelemetry)
+	}
+
+	err := t.queries.RecordTelemetry(ctx, sqlc.RecordTelemetryParams{
+		ServerIds:      []pgtype.UUID{server.ID},
+		SampledAt:      []pgtype.Timestamptz{{Time: sample.SampledAt, Valid: true}},
+		CpuPercent:     []float64{sample.CPUPercent},
+		NetworkRxBytes: []int64{sample.NetworkRxBytes},
+		NetworkTxBytes: []int64{sample.NetworkTxBytes},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record telemetry: %+v", err)
+	}
+	return nil
+}
+
+// Series returns the telemetry of a server over [from, to) in buckets of step.
+func (t *TelemetryService) Series(ctx context.Context, serverID pgtype.UUID, from, to time.Time, step time.Duration) ([]sqlc.GetTelemetrySeriesRow, error) {
+	switch {
+	case !from.Before(to):
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidTelemetryQuery)
+	case step < minTelemetryStep || step%minTelemetryStep != 0:
+		return nil, fmt.Errorf("%w: step must be a whole number of minutes", ErrInvalidTelemetryQuery)
+	case to.Sub(from)/step > maxTelemetryPoints:
+		return nil, fmt.Errorf("%w: at most %d steps fit between from and to", ErrInvalidTelemetryQuery, maxTelemetryPoints)
+	}
+
+	series, err := t.queries.GetTelemetrySeries(ctx, sqlc.GetTelemetrySeriesParams{
+		Step:     pgtype.Interval{Microseconds: step.Microseconds(), Valid: true},
+		FromTime: pgtype.Timestamptz{Time: from, Valid: true},
+		ServerID: serverID,
+		ToTime:   pgtype.Timestamptz{Time: to, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query telemetry: %+v", err)
+	}
+	return series, nil
+}
+
+// Start generates telemetry, if enabled, and drops telemetry older than
+// TELEMETRY_RETENTION every TELEMETRY_INTERVAL until ctx is cancelled.
+func (t *TelemetryService) Start(ctx context.Context) {
+	ticker := time.NewTicker(t.config.TelemetryInterval)
+	defer ticker.Stop()
+
+	t.logger.Info("Telemetry daemon started",
+		zap.Duration("interval", t.config.TelemetryInterval),
+		zap.Bool("generator", t.config.TelemetryGenerator),
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			t.logger.Info("Telemetry daemon stopped due to context cancellation.")
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if t.config.TelemetryGenerator {
+				if err := t.Generate(ctx, now); err != nil {
+					t.logger.Error("Failed to generate telemetry", zap.Error(err))
+				}
+			}
+			pruned, err := t.queries.PruneTelemetry(ctx, pgtype.Timestamptz{Time: now.Add(-t.config.TelemetryRetention), Valid: true})
+			if err != nil {
+				t.logger.Error("Failed to prune telemetry", zap.Error(err))
+			} else if pruned > 0 {
+				t.logger.Debug("Telemetry pruned", zap.Int64("rows", pruned))
+			}
+		}
+	}
+}
+
+// Generate records a synthesized sample for every running server in one statement.
+func (t *TelemetryService) Generate(ctx context.Context, now time.Time) error {
+	serverIDs, err := t.queries.ListRunningServerIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list running servers: %+v", err)
+	}
+	if len(serverIDs) == 0 {
+		return nil
+	}
+
+	params := sqlc.RecordTelemetryParams{
+		ServerIds:      serverIDs,
+		SampledAt:      make([]pgtype.Timestamptz, len(serverIDs)),
+		CpuPercent:     make([]float64, len(serverIDs)),
+		NetworkRxBytes: make([]int64, len(serverIDs)),
+		NetworkTxBytes: make([]int64, len(serverIDs)),
+	}
+	for i, serverID := range serverIDs {
+		cpu, rx, tx := telemetryProfileOf(serverID).sample(now, t.config.TelemetryInterval)
+		params.SampledAt[i] = pgtype.Timestamptz{Time: now, Valid: true}
+		params.CpuPercent[i] = cpu
+		params.NetworkRxBytes[i] = rx
+		params.NetworkTxBytes[i] = tx
+	}
+	if err := t.queries.RecordTelemetry(ctx, params); err != nil {
+		return fmt.Errorf("failed to record generated telemetry: %+v", err)
+	}
+	t.logger.Debug("Telemetry generated", zap.Int("servers", len(serverIDs)))
+	return nil
+}
+
+// telemetryProfile is the load curve the generator gives a server: a daily cycle
+// around a base load, with traffic proportional to the load.
+type telemetryProfile struct {
+	base      float64
+	amplitude float64
+	// phase shifts the daily peak, as a fraction of a day.
+	phase float64
+	// bytesPerSecond is the outbound traffic at full load; inbound is rxShare of it.
+	bytesPerSecond float64
+	rxShare        float64
+}
+
+// telemetryProfileOf derives a server's profile from its ID, so it keeps the same
+// curve across ticks and replicas. idleServerShare of the servers barely work.
+func telemetryProfileOf(serverID pgtype.UUID) telemetryProfile {
+	hash := fnv.New64a()
+	hash.Write(serverID.Bytes[:])
+	random := rand.New(rand.NewPCG(hash.Sum64(), 0))
+
+	profile := telemetryProfile{
+		base:           10 + 50*random.Float64(),
+		amplitude:      5 + 25*random.Float64(),
+		phase:          random.Float64(),
+		bytesPerSecond: 1e5 + 1e7*random.Float64(),
+		rxShare:        0.2 + 0.6*random.Float64(),
+	}
+	if random.Float64() < idleServerShare {
+		profile.base = 0.5 + 2*random.Float64()
+		profile.amplitude = random.Float64()
+	}
+	return profile
+}
+
+// sample is the CPU load of the profile at now, with some noise, and the traffic
+// of the interval before it.
+func (p telemetryProfile) sample(now time.Time, interval time.Duration) (float64, int64, int64) {
+	day := float64(now.Unix()) / (24 * 60 * 60)
+	cpu := p.base + p.amplitude*math.Sin(2*math.Pi*(day+p.phase)) + p.base*0.1*rand.NormFloat64()
+	cpu = math.Round(math.Min(math.Max(cpu, 0), 100)*100) / 100
+
+	tx := int64(cpu / 100 * p.bytesPerSecond * interval.Seconds() * (0.8 + 0.4*rand.Float64()))
+	rx := int64(float64(tx) * p.rxShare)
+	return cpu, rx, tx
+}
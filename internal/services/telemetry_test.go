@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// telemetryDB counts the statements the telemetry service sends; series queries
+// return no rows.
+type telemetryDB struct {
+	execs   int
+	queries int
+}
+
+func (db *telemetryDB) Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error) {
+	db.execs++
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *telemetryDB) Query(context.Context, string, ...interface{}) (pgx.Rows, error) {
+	db.queries++
+	return nil, errors.New("telemetryDB: no rows")
+}
+
+func (db *telemetryDB) QueryRow(context.Context, string, ...interface{}) pgx.Row {
+	return serverRow{err: pgx.ErrNoRows}
+}
+
+func testTelemetryService() (*TelemetryService, *telemetryDB) {
+	db := &telemetryDB{}
+	return NewTelemetryService(sqlc.New(db), zap.NewNop(), &config.Config{TelemetryRetention: 168 * time.Hour}), db
+}
+
+func TestTelemetryRecord(t *testing.T) {
+	now := mustTime(t, "2026-03-01T12:00:00Z")
+	running := sqlc.Server{ID: testUUID(1), Status: util.ServerStatusRunning}
+	tests := []struct {
+		name    string
+		server  sqlc.Server
+		sample  TelemetrySample
+		wantErr error
+	}{
+		{name: "valid", server: running, sample: TelemetrySample{SampledAt: now.Add(-time.Minute), CPUPercent: 42, NetworkRxBytes: 10, NetworkTxBytes: 20}},
+		{name: "zero sampledAt means now", server: running, sample: TelemetrySample{CPUPercent: 1}},
+		{name: "cpu at 0", server: running, sample: TelemetrySample{SampledAt: now, CPUPercent: 0}},
+		{name: "cpu at 100", server: running, sample: TelemetrySample{SampledAt: now, CPUPercent: 100}},
+		{name: "cpu below 0", server: running, sample: TelemetrySample{SampledAt: now, CPUPercent: -0.1}, wantErr: ErrInvalidTelemetry},
+		{name: "cpu above 100", server: running, sample: TelemetrySample{SampledAt: now, CPUPercent: 100.1}, wantErr: ErrInvalidTelemetry},
+		{name: "negative rx", server: running, sample: TelemetrySample{SampledAt: now, NetworkRxBytes: -1}, wantErr: ErrInvalidTelemetry},
+		{name: "negative tx", server: running, sample: TelemetrySample{SampledAt: now, NetworkTxBytes: -1}, wantErr: ErrInvalidTelemetry},
+		{name: "clock skew within a minute", server: running, sample: TelemetrySample{SampledAt: now.Add(time.Minute)}},
+		{name: "in the future", server: running, sample: TelemetrySample{SampledAt: now.Add(time.Minute + time.Second)}, wantErr: ErrInvalidTelemetry},
+		{name: "at the retention", server: running, sample: TelemetrySample{SampledAt: now.Add(-168 * time.Hour)}},
+		{name: "older than the retention", server: running, sample: TelemetrySample{SampledAt: now.Add(-168*time.Hour - time.Second)}, wantErr: ErrInvalidTelemetry},
+		{name: "stopped server", server: sqlc.Server{ID: testUUID(1), Status: util.ServerStatusStopped}, sample: TelemetrySample{SampledAt: now}, wantErr: ErrServerNotRunning},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry, db := testTelemetryService()
+			err := telemetry.Record(context.Background(), tt.server, tt.sample, now)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil && err != nil) {
+				t.Fatalf("Record() error = %v; want %v", err, tt.wantErr)
+			}
+			wantExecs := 0
+			if tt.wantErr == nil {
+				wantExecs = 1
+			}
+			if db.execs != wantExecs {
+				t.Errorf("Record() ran %d statements; want %d", db.execs, wantExecs)
+			}
+		})
+	}
+}
+
+func TestTelemetrySeries(t *testing.T) {
+	from := mustTime(t, "2026-03-01T00:00:00Z")
+	tests := []struct {
+		name        string
+		from, to    time.Time
+		step        time.Duration
+		wantInvalid bool
+	}{
+		{name: "one day by minute", from: from, to: from.Add(24 * time.Hour), step: time.Minute},
+		{name: "one week by hour", from: from, to: from.Add(7 * 24 * time.Hour), step: time.Hour},
+		{name: "a partial last step", from: from, to: from.Add(90 * time.Second), step: time.Minute},
+		{name: "point limit", from: from, to: from.Add(maxTelemetryPoints * time.Minute), step: time.Minute},
+		{name: "over the point limit", from: from, to: from.Add((maxTelemetryPoints + 1) * time.Minute), step: time.Minute, wantInvalid: true},
+		{name: "to before from", from: from, to: from.Add(-time.Hour), step: time.Minute, wantInvalid: true},
+		{name: "empty range", from: from, to: from, step: time.Minute, wantInvalid: true},
+		{name: "step under a minute", from: from, to: from.Add(time.Hour), step: 30 * time.Second, wantInvalid: true},
+		{name: "step not whole minutes", from: from, to: from.Add(time.Hour), step: 90 * time.Second, wantInvalid: true},
+		{name: "zero step", from: from, to: from.Add(time.Hour), step: 0, wantInvalid: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry, db := testTelemetryService()
+			_, err := telemetry.Series(context.Background(), testUUID(1), tt.from, tt.to, tt.step)
+			if got := errors.Is(err, ErrInvalidTelemetryQuery); got != tt.wantInvalid {
+				t.Fatalf("Series() error = %v; want invalid query %v", err, tt.wantInvalid)
+			}
+			wantQueries := 1
+			if tt.wantInvalid {
+				wantQueries = 0
+			}
+			if db.queries != wantQueries {
+				t.Errorf("Series() ran %d queries; want %d", db.queries, wantQueries)
+			}
+		})
+	}
+}
+
+func TestTelemetryProfileOf(t *testing.T) {
+	idle := 0
+	const servers = 500
+	for i := 0; i < servers; i++ {
+		serverID := testUUID(byte(i))
+		serverID.Bytes[1] = byte(i >> 8)
+		profile := telemetryProfileOf(serverID)
+		if again := telemetryProfileOf(serverID); again != profile {
+			t.Fatalf("telemetryProfileOf(%v) = %+v, then %+v; want the same profile", serverID, profile, again)
+		}
+		if profile.base < 0.5 || profile.base > 60 || profile.phase < 0 || profile.phase >= 1 {
+			t.Errorf("telemetryProfileOf(%v) = %+v; want base in [0.5, 60] and phase in [0, 1)", serverID, profile)
+		}
+		if profile.base < 10 {
+			idle++
+		}
+	}
+	if telemetryProfileOf(testUUID(1)) == telemetryProfileOf(testUUID(2)) {
+		t.Error("telemetryProfileOf() gave two servers the same profile")
+	}
+	// idleServerShare of the servers are idle; allow for sampling noise.
+	if share := float64(idle) / servers; share < idleServerShare/2 || share > idleServerShare*2 {
+		t.Errorf("idle share = %.2f; want about %.2f", share, idleServerShare)
+	}
+}
+
+func TestTelemetryProfileSample(t *testing.T) {
+	profile := telemetryProfileOf(testUUID(7))
+	now := mustTime(t, "2026-03-01T12:00:00Z")
+	for i := 0; i < 100; i++ {
+		cpu, rx, tx := profile.sample(now.Add(time.Duration(i)*time.Hour), time.Minute)
+		if cpu < 0 || cpu > 100 || rx < 0 || tx < 0 || rx > tx {
+			t.Fatalf("sample() = %v, %d, %d; want cpu in [0, 100] and 0 <= rx <= tx", cpu, rx, tx)
+		}
+	}
+}
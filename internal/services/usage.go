@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+// openUsageSegment starts metering a server that has just become running, at its
+// current type and hourly rate. It is a no-op if a segment is already open.
+func (s *ServerService) openUsageSegment(ctx context.Context, q *sqlc.Queries, server sqlc.Server) error {
+	segment, err := q.OpenUsageSegment(ctx, sqlc.OpenUsageSegmentParams{
+		ServerID:   server.ID,
+		ServerType: server.Type,
+		HourlyRate: server.HourlyCost,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open usage segment: %+v", err)
+	}
+
+	s.logger.Debug("Usage segment opened",
+		zap.String("server_id", server.ID.String()),
+		zap.String("segment_id", segment.ID.String()),
+		zap.Float64("hourly_rate", segment.HourlyRate),
+	)
+	return nil
+}
+
+// closeUsageSegment stops metering a server that has left the running state.
+func (s *ServerService) closeUsageSegment(ctx context.Context, q *sqlc.Queries, serverID pgtype.UUID) error {
+	if err := q.CloseUsageSegment(ctx, serverID); err != nil {
+		return fmt.Errorf("failed to close usage segment: %+v", err)
+	}
+	return nil
+}
+
+// ServerUsage is the metered uptime of a server and what it is charged.
+type ServerUsage struct {
+	UptimeSeconds int64
+	Cost          float64
+	// Charges breaks Cost down by ledger charge type.
+	Charges map[string]UsageCharge
+}
+
+// UsageCharge is the quantity and amount of one charge type.
+type UsageCharge struct {
+	Quantity float64
+	Unit     string
+	Amount   float64
+}
+
+func (u *ServerUsage) add(chargeType, unit string, quantity, amount float64) {
+	if u.Charges == nil {
+		u.Charges = make(map[string]UsageCharge)
+	}
+	charge := u.Charges[chargeType]
+	charge.Unit = unit
+	charge.Quantity += quantity
+	charge.Amount += amount
+	u.Charges[chargeType] = charge
+	u.Cost += amount
+}
+
+type reservationKey struct {
+	project    string
+	serverType string
+	region     string
+}
+
+// GetServerUsage returns the metered uptime and cost of the given servers, keyed by
+// server ID. The cost is what the ledger holds plus an estimate of the usage not
+// written to it yet: compute priced at the catalog rate in effect at the time,
+// rounded as the server's billing model charges it, and less the reserved hours
+// that would cover it; disk, public IP and egress at the configured prices. Spot
+// servers are priced from the spot price history.
+func (s *ServerService) GetServerUsage(ctx context.Context, serverIDs ...pgtype.UUID) (map[string]ServerUsage, error) {
+	usage, err := s.unbilledUsage(ctx, serverIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(serverIDs) == 0 {
+		return usage, nil
+	}
+	billed, err := s.queries.SumLedgerChargesByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sum ledger entries: %+v", err)
+	}
+	for _, entry := range billed {
+		serverUsage := usage[entry.ServerID.String()]
+		serverUsage.add(entry.ChargeType, entry.Unit, entry.Quantity, entry.Amount)
+		usage[entry.ServerID.String()] = serverUsage
+	}
+	return usage, nil
+}
+
+// unbilledUsage returns the metered uptime of the given servers and the estimate
+// of their usage not written to the ledger yet, keyed by server ID; see GetServerUsage.
+func (s *ServerService) unbilledUsage(ctx context.Context, serverIDs []pgtype.UUID) (map[string]ServerUsage, error) {
+	now := time.Now()
+	metered, err := loadMeteredUsage(ctx, s.queries, s.config, serverIDs, time.Time{}, now)
+	if err != nil {
+		return nil, err
+	}
+	return metered.price(time.Time{}, now)
+}
+
+// usageSince returns the cost of the given servers' usage from since to now, keyed
+// by server ID: what the ledger holds of it plus the estimate of what is not
+// written to it yet, priced as GetServerUsage prices it, so that budgets and
+// forecasts add up to what is invoiced.
+func usageSince(ctx context.Context, q *sqlc.Queries, cfg *config.Config, serverIDs []pgtype.UUID, since, now time.Time) (map[string]ServerUsage, error) {
+	metered, err := loadMeteredUsage(ctx, q, cfg, serverIDs, since, now)
+	if err != nil {
+		return nil, err
+	}
+	usage, err := metered.price(since, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := addBilledUsage(ctx, q, usage, serverIDs, since); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+// addBilledUsage adds to usage what the ledger holds of the usage of the given
+// servers after since. Entries spanning since count for their share after it.
+func addBilledUsage(ctx context.Context, q *sqlc.Queries, usage map[string]ServerUsage, serverIDs []pgtype.UUID, since time.Time) error {
+	if len(serverIDs) == 0 {
+		return nil
+	}
+	billed, err := q.SumLedgerChargesByServerIDsSince(ctx, sqlc.SumLedgerChargesByServerIDsSinceParams{
+		Since:     pgtype.Timestamptz{Time: since, Valid: true},
+		ServerIds: serverIDs,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to sum ledger entries: %+v", err)
+	}
+	for _, entry := range billed {
+		serverUsage := usage[entry.ServerID.String()]
+		serverUsage.add(entry.ChargeType, entry.Unit, entry.Quantity, entry.Amount)
+		usage[entry.ServerID.String()] = serverUsage
+	}
+	return nil
+}
+
+// meteredUsage is the usage of some servers that is not written to the ledger
+// yet, with what it is priced at.
+type meteredUsage struct {
+	segments  []sqlc.ListUsageSegmentsByServerIDsRow
+	resources []sqlc.ResourceSegment
+	egress    []sqlc.SumUnbilledEgressByServerIDsRow
+	onDemand  PriceCatalog
+	spot      PriceCatalog
+	// reservedHours are the reserved hours left per project, server type and region.
+	reservedHours map[reservationKey]float64
+	config        *config.Config
+}
+
+// loadMeteredUsage reads the usage of the given servers after since that is not
+// written to the ledger yet, and the prices and reserved hours left at now.
+func loadMeteredUsage(ctx context.Context, q *sqlc.Queries, cfg *config.Config, serverIDs []pgtype.UUID, since, now time.Time) (meteredUsage, error) {
+	metered := meteredUsage{reservedHours: make(map[reservationKey]float64), config: cfg}
+	if len(serverIDs) == 0 {
+		return metered, nil
+	}
+	var err error
+	metered.segments, err = q.ListUsageSegmentsByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return meteredUsage{}, fmt.Errorf("failed to list usage segments: %+v", err)
+	}
+	metered.onDemand, err = LoadPriceCatalog(ctx, q)
+	if err != nil {
+		return meteredUsage{}, err
+	}
+
+	spotSince := now
+	for _, segment := range metered.segments {
+		from := unbilledFrom(segment.StartedAt, segment.BilledUntil, since)
+		if segment.EndedAt.Valid && !from.Before(segment.EndedAt.Time) {
+			continue
+		}
+		if from.Before(spotSince) {
+			spotSince = from
+		}
+		key := reservationKey{segment.Project, segment.ServerType, segment.Region}
+		if _, ok := metered.reservedHours[key]; ok || segment.BillingModel != BillingModelReserved {
+			continue
+		}
+		metered.reservedHours[key], err = q.SumRemainingReservationHours(ctx, sqlc.SumRemainingReservationHoursParams{
+			Project:    key.project,
+			ServerType: key.serverType,
+			Region:     key.region,
+			At:         pgtype.Timestamptz{Time: now, Valid: true},
+		})
+		if err != nil {
+			return meteredUsage{}, fmt.Errorf("failed to sum reserved hours: %+v", err)
+		}
+	}
+	metered.spot, err = LoadSpotPriceCatalog(ctx, q, spotSince)
+	if err != nil {
+		return meteredUsage{}, err
+	}
+
+	metered.resources, err = q.ListResourceSegmentsByServerIDs(ctx, serverIDs)
+	if err != nil {
+		return meteredUsage{}, fmt.Errorf("failed to list resource segments: %+v", err)
+	}
+	metered.egress, err = q.SumUnbilledEgressByServerIDs(ctx, sqlc.SumUnbilledEgressByServerIDsParams{
+		ServerIds: serverIDs,
+		Since:     pgtype.Timestamptz{Time: since, Valid: true},
+	})
+	if err != nil {
+		return meteredUsage{}, fmt.Errorf("failed to sum egress: %+v", err)
+	}
+	return metered, nil
+}
+
+// unbilledFrom returns where the usage of a segment after since that is not
+// written to the ledger yet starts.
+func unbilledFrom(startedAt, billedUntil pgtype.Timestamptz, since time.Time) time.Time {
+	return maxTime(maxTime(startedAt.Time, billedUntil.Time), since)
+}
+
+// price returns the uptime of the servers and the estimate of their unbilled usage
+// after since, keyed by server ID; see GetServerUsage. Segments still open are
+// priced as if they ran until until, so pricing to a later time projects them.
+func (m meteredUsage) price(since, until time.Time) (map[string]ServerUsage, error) {
+	usage := make(map[string]ServerUsage)
+	reservedHours := maps.Clone(m.reservedHours)
+	for _, segment := range m.segments {
+		catalog := m.onDemand
+		if segment.PurchaseOption == PurchaseOptionSpot {
+			catalog = m.spot
+		}
+		start, end := segment.StartedAt.Time, until
+		if segment.EndedAt.Valid {
+			end = segment.EndedAt.Time
+		}
+		serverUsage := usage[segment.ServerID.String()]
+		serverUsage.UptimeSeconds += int64(end.Sub(start).Seconds())
+
+		if from := unbilledFrom(segment.StartedAt, segment.BilledUntil, since); from.Before(end) {
+			cost, err := catalog.Cost(segment.ServerType, segment.Region, from, end)
+			if err != nil {
+				return nil, fmt.Errorf("failed to price usage segment %s: %w", segment.ID.String(), err)
+			}
+			hours := end.Sub(from).Hours()
+			if segment.BillingModel == BillingModelReserved {
+				key := reservationKey{segment.Project, segment.ServerType, segment.Region}
+				covered := min(reservedHours[key], hours)
+				reservedHours[key] -= covered
+				cost *= 1 - covered/hours
+				if covered > 0 {
+					serverUsage.add(ChargeTypeComputeReserved, "hour", covered, 0)
+				}
+				serverUsage.add(ChargeTypeCompute, "hour", hours-covered, cost)
+			} else {
+				serverUsage.add(ChargeTypeCompute, "hour", hours, cost)
+			}
+		}
+
+		// The rounding of a segment is written to the ledger once it is billed to its end
+		if (!segment.EndedAt.Valid || segment.BilledUntil.Time.Before(end)) && end.After(since) {
+			metered := end.Sub(start)
+			if extra := BilledDuration(segment.BillingModel, metered) - metered; extra > 0 {
+				rate, err := catalog.RateAt(segment.ServerType, segment.Region, end)
+				if err != nil {
+					return nil, fmt.Errorf("failed to price usage segment %s: %w", segment.ID.String(), err)
+				}
+				serverUsage.add(ChargeTypeComputeRounding, "hour", extra.Hours(), extra.Hours()*rate)
+			}
+		}
+		usage[segment.ServerID.String()] = serverUsage
+	}
+
+	for _, segment := range m.resources {
+		end := until
+		if segment.EndedAt.Valid {
+			end = segment.EndedAt.Time
+		}
+		if from := unbilledFrom(segment.StartedAt, segment.BilledUntil, since); from.Before(end) {
+			chargeType, unit, price := resourceCharge(m.config, segment.Resource)
+			quantity := end.Sub(from).Hours() * segment.Quantity
+			serverUsage := usage[segment.ServerID.String()]
+			serverUsage.add(chargeType, unit, quantity, quantity*price)
+			usage[segment.ServerID.String()] = serverUsage
+		}
+	}
+
+	for _, row := range m.egress {
+		serverUsage := usage[row.ServerID.String()]
+		serverUsage.add(ChargeTypeEgress, "GB", row.Gb, row.Gb*m.config.EgressGBPrice)
+		usage[row.ServerID.String()] = serverUsage
+	}
+	return usage, nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+)
+
+func TestMeteredUsagePrice(t *testing.T) {
+	at := func(value string) time.Time { return mustTime(t, value) }
+	segment := func(id byte, billingModel, purchaseOption, startedAt, endedAt, billedUntil string) sqlc.ListUsageSegmentsByServerIDsRow {
+		row := sqlc.ListUsageSegmentsByServerIDsRow{
+			ServerID:       testUUID(id),
+			ServerType:     "t2.micro",
+			Region:         "us-east-1",
+			Project:        "acme",
+			BillingModel:   billingModel,
+			PurchaseOption: purchaseOption,
+			StartedAt:      timestamptz(at(startedAt)),
+		}
+		if endedAt != "" {
+			row.EndedAt = timestamptz(at(endedAt))
+		}
+		if billedUntil != "" {
+			row.BilledUntil = timestamptz(at(billedUntil))
+		}
+		return row
+	}
+	metered := meteredUsage{
+		segments: []sqlc.ListUsageSegmentsByServerIDsRow{
+			segment(1, BillingModelPerSecond, PurchaseOptionOnDemand, "2026-03-14T00:00:00Z", "2026-03-14T10:00:00Z", "2026-03-14T10:00:00Z"), // billed, before since
+			segment(1, BillingModelPerSecond, PurchaseOptionOnDemand, "2026-03-14T20:00:00Z", "2026-03-15T02:00:00Z", ""),                     // 2 hours after since
+			segment(2, BillingModelPerSecond, PurchaseOptionSpot, "2026-03-15T06:00:00Z", "", ""),
+			segment(3, BillingModelHourly, PurchaseOptionOnDemand, "2026-03-15T01:00:00Z", "2026-03-15T02:30:00Z", ""),
+			segment(4, BillingModelReserved, PurchaseOptionOnDemand, "2026-03-15T00:00:00Z", "", ""),
+		},
+		resources: []sqlc.ResourceSegment{
+			{ServerID: testUUID(1), Resource: ResourceDisk, Quantity: 10, StartedAt: timestamptz(at("2026-03-14T00:00:00Z")), BilledUntil: timestamptz(at("2026-03-14T00:00:00Z"))},
+			{ServerID: testUUID(2), Resource: ResourcePublicIP, Quantity: 1, StartedAt: timestamptz(at("2026-03-15T06:00:00Z"))},
+		},
+		egress:        []sqlc.SumUnbilledEgressByServerIDsRow{{ServerID: testUUID(2), Gb: 2}},
+		onDemand:      testCatalog(testPrice("t2.micro", PriceRegionAny, 0.1, at("2025-01-01T00:00:00Z"))),
+		spot:          testCatalog(testPrice("t2.micro", "us-east-1", 0.03, at("2026-03-01T00:00:00Z"))),
+		reservedHours: map[reservationKey]float64{{"acme", "t2.micro", "us-east-1"}: 4},
+		config:        &config.Config{DiskGBHourPrice: 0.001, PublicIPHourPrice: 0.005, EgressGBPrice: 0.09},
+	}
+	since := at("2026-03-15T00:00:00Z")
+
+	tests := []struct {
+		name  string
+		until string
+		want  map[byte]float64
+	}{
+		{
+			name:  "to now",
+			until: "2026-03-15T12:00:00Z",
+			want: map[byte]float64{
+				1: 2*0.1 + 12*10*0.001,       // compute and disk after since
+				2: 6*0.03 + 6*0.005 + 2*0.09, // spot compute, public IP and egress
+				3: 1.5*0.1 + 0.5*0.1,         // hourly rounding of the closed segment
+				4: (12 - 4) * 0.1,            // 4 reserved hours
+			},
+		},
+		{
+			name:  "projected to later",
+			until: "2026-03-15T18:00:00Z",
+			want: map[byte]float64{
+				1: 2*0.1 + 18*10*0.001,
+				2: 12*0.03 + 12*0.005 + 2*0.09,
+				3: 1.5*0.1 + 0.5*0.1,
+				4: (18 - 4) * 0.1,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := metered.price(since, at(tt.until))
+			if err != nil {
+				t.Fatalf("price failed: %v", err)
+			}
+			for id, want := range tt.want {
+				if got := usage[testUUID(id).String()].Cost; !closeTo(got, want) {
+					t.Errorf("cost of server %d = %v, want %v", id, got, want)
+				}
+			}
+		})
+	}
+
+	usage, _ := metered.price(since, at("2026-03-15T12:00:00Z"))
+	if got := usage[testUUID(1).String()].UptimeSeconds; got != 16*3600 {
+		t.Errorf("uptime of server 1 = %d, want 16 hours", got)
+	}
+	if got := usage[testUUID(3).String()].Charges[ChargeTypeComputeRounding].Quantity; !closeTo(got, 0.5) {
+		t.Errorf("rounding of server 3 = %v hours, want 0.5", got)
+	}
+	if got := usage[testUUID(4).String()].Charges[ChargeTypeComputeReserved].Quantity; !closeTo(got, 4) {
+		t.Errorf("reserved hours of server 4 = %v, want 4", got)
+	}
+	if metered.reservedHours[reservationKey{"acme", "t2.micro", "us-east-1"}] != 4 {
+		t.Error("pricing drew the reserved hours down")
+	}
+}
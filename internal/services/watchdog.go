@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// transientStatusTargets maps each transient status to the status its transition
+// leads to. Only these can be given a timeout in STUCK_STATE_TIMEOUTS.
+var transientStatusTargets = map[string]string{
+	util.ServerStatusProvisioning: util.ServerStatusRunning,
+}
+
+// StuckStateWatchdog moves servers that stay in a transient status for longer than
+// its STUCK_STATE_TIMEOUTS entry to the error status, after retrying the transition
+// out of it STUCK_STATE_RETRIES times.
+type StuckStateWatchdog struct {
+	queries *sqlc.Queries
+	servers *ServerService
+	logger  *zap.Logger
+	config  *config.Config
+}
+
+// NewStuckStateWatchdog creates a new StuckStateWatchdog.
+func NewStuckStateWatchdog(queries *sqlc.Queries, servers *ServerService, logger *zap.Logger, config *config.Config) *StuckStateWatchdog {
+	return &StuckStateWatchdog{
+		queries: queries,
+		servers: servers,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+// Start checks for stuck servers every WATCHDOG_INTERVAL until ctx is cancelled.
+func (w *StuckStateWatchdog) Start(ctx context.Context) {
+	ticker := time.NewTicker(w.config.WatchdogInterval)
+	defer ticker.Stop()
+
+	for status := range w.config.StuckStateTimeouts {
+		if _, ok := transientStatusTargets[status]; !ok {
+			w.logger.Warn("Ignoring timeout of a status that is not transient", zap.String("status", status))
+		}
+	}
+	ctx = WithEventActor(ctx, ActorWatchdog)
+	w.logger.Info("Stuck-state watchdog started",
+		zap.Duration("interval", w.config.WatchdogInterval),
+		zap.Int("retries", w.config.StuckStateRetries),
+	)
+	for {
+		select {
+		case <-ctx.Done():
+			w.logger.Info("Stuck-state watchdog stopped due to context cancellation.")
+			return
+		case <-ticker.C:
+			w.tick(ctx, time.Now())
+		}
+	}
+}
+
+// tick deals with every server that has been in a transient status for longer
+// than the status's timeout as of now.
+func (w *StuckStateWatchdog) tick(ctx context.Context, now time.Time) {
+	for status, timeout := range w.config.StuckStateTimeouts {
+		target, ok := transientStatusTargets[status]
+		if !ok {
+			continue
+		}
+		servers, err := w.queries.ListStuckServers(ctx, sqlc.ListStuckServersParams{
+			Status:      status,
+			StuckBefore: pgtype.Timestamptz{Time: now.Add(-timeout), Valid: true},
+		})
+		if err != nil {
+			w.logger.Error("Failed to list stuck servers", zap.Error(err), zap.String("status", status))
+			continue
+		}
+		for _, server := range servers {
+			w.unstick(ctx, server, target, timeout)
+		}
+	}
+}
+
+// unstick retries the transition of a stuck server to target while it has retries
+// left, and moves it to the error status once it has none.
+func (w *StuckStateWatchdog) unstick(ctx context.Context, server sqlc.Server, target string, timeout time.Duration) {
+	status := server.Status
+	stuckSince := server.LastStatusUpdate.Time
+	if server.StuckSince.Valid {
+		stuckSince = server.StuckSince.Time
+	}
+	reason := "Stuck in " + status + " since " + stuckSince.Format(time.RFC3339) + ", timeout " + timeout.String()
+
+	if attempt := int(server.TransitionAttempts) + 1; attempt <= w.config.StuckStateRetries {
+		attempts := strconv.Itoa(attempt) + " of " + strconv.Itoa(w.config.StuckStateRetries)
+		if err := w.transition(ctx, server, target); err != nil {
+			w.logger.Warn("Retry of stuck server failed",
+				zap.Error(err),
+				zap.String("server_id", server.ID.String()),
+				zap.String("status", status),
+				zap.String("target", target),
+				zap.Int("attempt", attempt),
+			)
+			serverStuckTotal.WithLabelValues(status, "retry_failed").Inc()
+			if _, err := w.queries.RecordTransitionAttempt(ctx, sqlc.RecordTransitionAttemptParams{ID: server.ID, Status: status}); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+				w.logger.Error("Failed to record transition attempt", zap.Error(err), zap.String("server_id", server.ID.String()))
+				return
+			}
+			w.servers.RecordEvent(ctx, server.ID, ServerEvent{
+				Type:    EventWatchdog,
+				Message: reason + "; transition to " + target + " failed on retry " + attempts,
+				Details: map[string]any{"attempt": attempt, "error": err.Error()},
+			})
+			return
+		}
+		serverStuckTotal.WithLabelValues(status, "recovered").Inc()
+		w.servers.RecordEvent(ctx, server.ID, ServerEvent{
+			Type:    EventWatchdog,
+			Message: reason + "; transition to " + target + " retried (" + attempts + ")",
+			Details: map[string]any{"attempt": attempt},
+		})
+		w.logger.Info("Stuck server recovered",
+			zap.String("server_id", server.ID.String()),
+			zap.String("status", status),
+			zap.String("target", target),
+			zap.Int("attempt", attempt),
+		)
+		return
+	}
+
+	_, err := w.queries.MarkServerStuck(ctx, sqlc.MarkServerStuckParams{ID: server.ID, Status: status})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The server left the status since it was listed
+		return
+	}
+	if err != nil {
+		w.logger.Error("Failed to move stuck server to error", zap.Error(err), zap.String("server_id", server.ID.String()))
+		return
+	}
+	serverStuckTotal.WithLabelValues(status, "error").Inc()
+	w.servers.RecordEvent(ctx, server.ID, ServerEvent{
+		Type:       EventStatusChange,
+		FromStatus: status,
+		ToStatus:   util.ServerStatusError,
+		Message:    reason + "; moved to error",
+		Details:    map[string]any{"stuckSince": stuckSince, "retries": server.TransitionAttempts},
+	})
+	w.logger.Warn("Stuck server moved to error",
+		zap.String("server_id", server.ID.String()),
+		zap.String("status", status),
+		zap.Time("stuck_since", stuckSince),
+		zap.Int32("retries", server.TransitionAttempts),
+	)
+}
+
+// transition carries out the transition of a server to target through the regular
+// server lifecycle. Stuck provisioning servers have no primary address, so
+// provisioning is re-run before they are started.
+func (w *StuckStateWatchdog) transition(ctx context.Context, server sqlc.Server, target string) error {
+	switch target {
+	case util.ServerStatusRunning:
+		if server.Status == util.ServerStatusProvisioning {
+			reprovisioned, err := w.servers.ReprovisionServer(ctx, server)
+			if err != nil {
+				return err
+			}
+			server = reprovisioned
+		}
+		_, err := w.servers.StartServer(ctx, server)
+		return err
+	default:
+		return fmt.Errorf("no transition to %s", target)
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"go-virtual-server/internal/config"
+	"go-virtual-server/internal/database"
+	"go-virtual-server/internal/database/sqlc"
+	"go-virtual-server/internal/util"
+)
+
+// statement is one statement run against a scriptedDB.
+type statement struct {
+	name string
+	args []interface{}
+}
+
+// scriptedDB answers statements by their sqlc query name and records them in the
+// order they ran. Queries of one server return rows[name]; everything else fails
+// with errs[name], or with an error naming the statement.
+type scriptedDB struct {
+	rows map[string]sqlc.Server
+	errs map[string]error
+	ran  []statement
+}
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func (db *scriptedDB) result(name string) error {
+	if err, ok := db.errs[name]; ok {
+		return err
+	}
+	return fmt.Errorf("scriptedDB: no answer for %s", name)
+}
+
+func (db *scriptedDB) Exec(_ context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	return pgconn.CommandTag{}, db.result(name)
+}
+
+func (db *scriptedDB) Query(_ context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	return nil, db.result(name)
+}
+
+func (db *scriptedDB) QueryRow(_ context.Context, sql string, args ...interface{}) pgx.Row {
+	name := queryName(sql)
+	db.ran = append(db.ran, statement{name: name, args: args})
+	if server, ok := db.rows[name]; ok {
+		return serverRow{server: server}
+	}
+	return serverRow{err: db.result(name)}
+}
+
+// names returns the names of the statements that ran, in order.
+func (db *scriptedDB) names() []string {
+	names := make([]string, len(db.ran))
+	for i, stmt := range db.ran {
+		names[i] = stmt.name
+	}
+	return names
+}
+
+func testWatchdog(db *scriptedDB, retries int) *StuckStateWatchdog {
+	cfg := &config.Config{
+		StuckStateRetries:  retries,
+		StuckStateTimeouts: config.StateTimeouts{util.ServerStatusProvisioning: 15 * time.Minute},
+		// No pool for the servers' region, so every retry fails before it allocates
+		PrivateIPPools: map[string]string{"eu-west-1": "10.2.0.0/16"},
+	}
+	queries := sqlc.New(db)
+	servers := &ServerService{queries: queries, logger: zap.NewNop(), config: cfg}
+	return NewStuckStateWatchdog(queries, servers, zap.NewNop(), cfg)
+}
+
+func TestWatchdogUnstick(t *testing.T) {
+	lastUpdate := mustTime(t, "2026-03-01T12:00:00Z")
+	stuckSince := mustTime(t, "2026-03-01T11:00:00Z")
+	tests := []struct {
+		name        string
+		retries     int
+		attempts    int32
+		stuckSince  time.Time
+		errs        map[string]error
+		want        []string
+		wantMessage string // substring of the recorded event's message
+	}{
+		{name: "no retries", retries: 0, want: []string{"MarkServerStuck", "RecordServerEvent"}, wantMessage: "moved to error"},
+		{name: "first retry", retries: 2, attempts: 0, want: []string{"RecordTransitionAttempt", "RecordServerEvent"}, wantMessage: "failed on retry 1 of 2"},
+		{name: "last retry", retries: 2, attempts: 1, want: []string{"RecordTransitionAttempt", "RecordServerEvent"}, wantMessage: "failed on retry 2 of 2"},
+		{name: "retries used up", retries: 2, attempts: 2, want: []string{"MarkServerStuck", "RecordServerEvent"}, wantMessage: "moved to error"},
+		{name: "stuck since carried over", retries: 2, attempts: 2, stuckSince: stuckSince, want: []string{"MarkServerStuck", "RecordServerEvent"}, wantMessage: "since 2026-03-01T11:00:00Z"},
+		{name: "stuck since the last status update", retries: 0, want: []string{"MarkServerStuck", "RecordServerEvent"}, wantMessage: "since 2026-03-01T12:00:00Z"},
+		{
+			name: "server left the status before it was moved", retries: 0,
+			errs: map[string]error{"MarkServerStuck": pgx.ErrNoRows},
+			want: []string{"MarkServerStuck"},
+		},
+		{
+			name: "moving the server fails", retries: 0,
+			errs: map[string]error{"MarkServerStuck": errors.New("connection reset")},
+			want: []string{"MarkServerStuck"},
+		},
+		{
+			name: "counting the attempt fails", retries: 1,
+			errs: map[string]error{"RecordTransitionAttempt": errors.New("connection reset")},
+			want: []string{"RecordTransitionAttempt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := sqlc.Server{
+				ID:                 testUUID(1),
+				Region:             "us-east-1",
+				Status:             util.ServerStatusProvisioning,
+				LastStatusUpdate:   timestamptz(lastUpdate),
+				StuckSince:         timestamptz(tt.stuckSince),
+				TransitionAttempts: tt.attempts,
+			}
+			// Recording the event fails like an insert that returns nothing; unstick only logs it
+			db := &scriptedDB{
+				rows: map[string]sqlc.Server{"MarkServerStuck": server, "RecordTransitionAttempt": server},
+				errs: map[string]error{"RecordServerEvent": pgx.ErrNoRows},
+			}
+			for name, err := range tt.errs {
+				delete(db.rows, name)
+				db.errs[name] = err
+			}
+
+			testWatchdog(db, tt.retries).unstick(context.Background(), server, util.ServerStatusRunning, 15*time.Minute)
+			if got := db.names(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("unstick() ran %v; want %v", got, tt.want)
+			}
+			if tt.wantMessage == "" {
+				return
+			}
+			payload := string(db.ran[len(db.ran)-1].args[5].([]byte))
+			if !strings.Contains(payload, tt.wantMessage) {
+				t.Errorf("event payload = %s; want a message containing %q", payload, tt.wantMessage)
+			}
+		})
+	}
+}
+
+func TestWatchdogWarnsOfNonTransientTimeouts(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	cfg := &config.Config{
+		WatchdogInterval: time.Hour,
+		StuckStateTimeouts: config.StateTimeouts{
+			util.ServerStatusProvisioning: 15 * time.Minute,
+			util.ServerStatusRunning:      time.Hour,
+			"rebooting":                   time.Minute,
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	NewStuckStateWatchdog(nil, nil, zap.New(core), cfg).Start(ctx)
+
+	warned := map[string]bool{}
+	for _, entry := range logs.FilterMessage("Ignoring timeout of a status that is not transient").All() {
+		warned[entry.ContextMap()["status"].(string)] = true
+	}
+	if len(warned) != 2 || !warned[util.ServerStatusRunning] || !warned["rebooting"] {
+		t.Errorf("warned of %v; want running and rebooting, not provisioning", warned)
+	}
+}
+
+// TestWatchdogQueries checks the stuck_since and attempt bookkeeping of the
+// watchdog's statements. It runs against the database of the repository's .env
+// and is skipped when there is none.
+func TestWatchdogQueries(t *testing.T) {
+	t.Chdir("../..")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load configuration: %v", err)
+	}
+	databaseURL := fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
+		cfg.DBUser, cfg.DBPassword, cfg.DBHost, cfg.DBPort, cfg.DBName, cfg.DBSSLMode)
+	dbClient, err := database.NewDBClient(context.Background(), databaseURL, 1, time.Millisecond, zap.NewNop())
+	if err != nil {
+		t.Skipf("no database: %v", err)
+	}
+	defer dbClient.Close()
+	ctx := context.Background()
+
+	name := "watchdog-" + uuid.NewString()[:8]
+	var serverID pgtype.UUID
+	var enteredAt time.Time
+	err = dbClient.Pool.QueryRow(ctx, `
+		INSERT INTO servers (name, hostname, region, project, status, type, hourly_cost, last_status_update)
+		VALUES ($1, $1 || '.test.invalid', 'us-east-1', $1, 'provisioning', 't2.micro', 0.0116, NOW() - INTERVAL '1 hour')
+		RETURNING id, last_status_update`, name).Scan(&serverID, &enteredAt)
+	if err != nil {
+		t.Fatalf("failed to seed server: %v", err)
+	}
+	defer func() {
+		if _, err := dbClient.Pool.Exec(ctx, `DELETE FROM servers WHERE id = $1`, serverID); err != nil {
+			t.Errorf("failed to delete server: %v", err)
+		}
+	}()
+
+	// Each failed retry counts and restarts the timeout, but stuck_since stays
+	// when the server first entered the status
+	for attempt := int32(1); attempt <= 2; attempt++ {
+		server, err := dbClient.Queries.RecordTransitionAttempt(ctx, sqlc.RecordTransitionAttemptParams{ID: serverID, Status: util.ServerStatusProvisioning})
+		if err != nil {
+			t.Fatalf("RecordTransitionAttempt() error = %v", err)
+		}
+		if server.TransitionAttempts != attempt || !server.StuckSince.Time.Equal(enteredAt) || !server.LastStatusUpdate.Time.After(enteredAt) {
+			t.Errorf("after attempt %d: attempts %d, stuck since %s, last update %s; want stuck since %s and a later last update",
+				attempt, server.TransitionAttempts, server.StuckSince.Time, server.LastStatusUpdate.Time, enteredAt)
+		}
+	}
+
+	// A server that has left the status is neither counted nor moved
+	if _, err := dbClient.Queries.RecordTransitionAttempt(ctx, sqlc.RecordTransitionAttemptParams{ID: serverID, Status: util.ServerStatusStopped}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("RecordTransitionAttempt(other status) error = %v; want no rows", err)
+	}
+	if _, err := dbClient.Queries.MarkServerStuck(ctx, sqlc.MarkServerStuckParams{ID: serverID, Status: util.ServerStatusStopped}); !errors.Is(err, pgx.ErrNoRows) {
+		t.Errorf("MarkServerStuck(other status) error = %v; want no rows", err)
+	}
+
+	server, err := dbClient.Queries.MarkServerStuck(ctx, sqlc.MarkServerStuckParams{ID: serverID, Status: util.ServerStatusProvisioning})
+	if err != nil {
+		t.Fatalf("MarkServerStuck() error = %v", err)
+	}
+	if server.Status != util.ServerStatusError || !server.StuckSince.Time.Equal(enteredAt) || server.LastStatusActor != ActorWatchdog {
+		t.Errorf("MarkServerStuck() = %s since %s by %s; want error since %s by the watchdog", server.Status, server.StuckSince.Time, server.LastStatusActor, enteredAt)
+	}
+}
@@ -10,6 +10,8 @@ const (
 	ServerStatusRunning      = "running"
 	ServerStatusStopped      = "stopped"
 	ServerStatusTerminated   = "terminated"
+	ServerStatusSuspended    = "suspended"
+	ServerStatusError        = "error"
 	ServerTypeT2Micro        = "t2.micro"
 	ServerTypeM5Large        = "m5.large"
 	ServerTypeC5Xlarge       = "c5.xlarge"
@@ -25,11 +27,19 @@ type ErrorResponse struct {
 func IsValidTransition(currentStatus, desiredStatus string) bool {
 	switch currentStatus {
 	case ServerStatusProvisioning:
-		return desiredStatus == ServerStatusRunning || desiredStatus == ServerStatusTerminated
+		return desiredStatus == ServerStatusRunning || desiredStatus == ServerStatusTerminated ||
+			desiredStatus == ServerStatusError
 	case ServerStatusRunning:
-		return desiredStatus == ServerStatusStopped || desiredStatus == ServerStatusTerminated
+		return desiredStatus == ServerStatusStopped || desiredStatus == ServerStatusTerminated ||
+			desiredStatus == ServerStatusSuspended
 	case ServerStatusStopped:
 		return desiredStatus == ServerStatusRunning || desiredStatus == ServerStatusTerminated
+	case ServerStatusSuspended:
+		// Suspended servers are resumed by topping up their project's account
+		return desiredStatus == ServerStatusTerminated
+	case ServerStatusError:
+		// Servers the watchdog gave up on can be started again or terminated
+		return desiredStatus == ServerStatusRunning || desiredStatus == ServerStatusTerminated
 	case ServerStatusTerminated:
 		return false
 	default:
@@ -3,24 +3,445 @@
 CREATE TABLE servers (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
     name VARCHAR(255) NOT NULL,
+    hostname VARCHAR(253) NOT NULL,
     region VARCHAR(100) NOT NULL,
+    project VARCHAR(100) NOT NULL DEFAULT 'default',
     status VARCHAR(15) NOT NULL DEFAULT 'provisioning',
     address VARCHAR(15) NOT NULL DEFAULT 'NOT SERVED',
     type VARCHAR(10) NOT NULL,
+    disk_gb INT NOT NULL DEFAULT 8 CHECK (disk_gb > 0),
     provisioned_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     last_status_update TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Actor of the latest status change, as in its event; the reaper only counts
+    -- servers stopped by their owner.
+    last_status_actor VARCHAR(20) NOT NULL DEFAULT '',
+    -- Set by the stuck-state watchdog to when the server entered the state it got
+    -- stuck in; cleared, with the retry count, on the next status change.
+    stuck_since TIMESTAMPTZ,
+    transition_attempts INT NOT NULL DEFAULT 0,
     uptime_seconds BIGINT NOT NULL DEFAULT 0,
     hourly_cost DOUBLE PRECISION NOT NULL,
-    lifecycle_logs JSONB NOT NULL DEFAULT '[]'::jsonb, 
+    billing_model VARCHAR(20) NOT NULL DEFAULT 'per_second',
+    purchase_option VARCHAR(20) NOT NULL DEFAULT 'on_demand',
+    spot_max_price DOUBLE PRECISION NOT NULL DEFAULT 0,
+    interruption_behavior VARCHAR(20) NOT NULL DEFAULT 'terminate',
+    interruption_notice_at TIMESTAMPTZ,
+    assign_public_ip BOOLEAN NOT NULL DEFAULT FALSE,
+    tags JSONB NOT NULL DEFAULT '{}'::jsonb,
+    user_data TEXT NOT NULL DEFAULT '',
+    -- Sequence number of the server's latest event in server_events.
+    event_sequence BIGINT NOT NULL DEFAULT 0,
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+CREATE TABLE ip_pools (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(63) NOT NULL UNIQUE,
+    cidr VARCHAR(18) NOT NULL,
+    strategy VARCHAR(20) NOT NULL DEFAULT 'sequential',
+    excluded_addresses TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE network_interfaces (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    device_index INT NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (server_id, device_index)
+);
+
+-- One row per contiguous running period of a server. Uptime and cost are derived
+-- from these rows; servers.uptime_seconds is only a cache refreshed by the billing daemon.
+CREATE TABLE usage_segments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    server_type VARCHAR(10) NOT NULL,
+    hourly_rate DOUBLE PRECISION NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ,
+    -- Usage up to here has been written to the ledger.
+    billed_until TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Resources a server holds outside of its running time, metered the same way as
+-- usage_segments: the disk from provisioning to termination, and a public IP
+-- while a NAT mapping is active. quantity is the disk size in GB, or 1.
+CREATE TABLE resource_segments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    resource VARCHAR(20) NOT NULL CHECK (resource IN ('disk', 'public_ip')),
+    quantity DOUBLE PRECISION NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    ended_at TIMESTAMPTZ,
+    -- Usage up to here has been written to the ledger.
+    billed_until TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Simulated outbound traffic of running servers in GB, one row per server and hour.
+CREATE TABLE egress_usage (
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    hour TIMESTAMPTZ NOT NULL,
+    gb DOUBLE PRECISION NOT NULL DEFAULT 0,
+    billed BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (server_id, hour)
+);
+
+-- Rows are created on demand when an address is first allocated from a pool,
+-- and kept (is_allocated = FALSE) after release so updated_at records when it was last freed.
+-- Pools do not overlap (RegisterPool rejects it), and an allocated address is held
+-- by a single row, so it identifies one server, e.g. to the metadata service.
 CREATE TABLE ip_addresses (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-    address VARCHAR(15) NOT NULL UNIQUE, 
+    pool_id UUID NOT NULL REFERENCES ip_pools(id) ON DELETE CASCADE,
+    address VARCHAR(15) NOT NULL,
     is_allocated BOOLEAN NOT NULL DEFAULT FALSE,
-    server_id UUID UNIQUE REFERENCES servers(id) ON DELETE SET NULL,
+    server_id UUID REFERENCES servers(id) ON DELETE SET NULL,
+    interface_id UUID REFERENCES network_interfaces(id) ON DELETE SET NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (pool_id, address)
+);
+CREATE UNIQUE INDEX ip_addresses_allocated_address ON ip_addresses (address) WHERE is_allocated;
+
+-- 1:1 NAT between a server's public and private address. Rows are kept after
+-- release (released_at set) so the mapping history stays queryable.
+CREATE TABLE nat_mappings (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    public_ip_id UUID REFERENCES ip_addresses(id) ON DELETE SET NULL,
+    private_ip_id UUID REFERENCES ip_addresses(id) ON DELETE SET NULL,
+    public_address VARCHAR(15) NOT NULL,
+    private_address VARCHAR(15) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    released_at TIMESTAMPTZ
+);
+
+-- Versioned price catalog. A version applies from effective_from until the next
+-- version of the same type and region; region '*' prices every region without its own entry.
+CREATE TABLE prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_type VARCHAR(10) NOT NULL,
+    region VARCHAR(100) NOT NULL DEFAULT '*',
+    hourly_rate DOUBLE PRECISION NOT NULL CHECK (hourly_rate >= 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    effective_from TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (server_type, region, effective_from)
+);
+
+-- Simulated spot market: the price history of each server type and region,
+-- appended by the market daemon. Spot servers are billed from it.
+CREATE TABLE spot_prices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_type VARCHAR(10) NOT NULL,
+    region VARCHAR(100) NOT NULL,
+    price DOUBLE PRECISION NOT NULL CHECK (price >= 0),
+    effective_from TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (server_type, region, effective_from)
+);
+
+-- Committed-use reservations: hours of a type in a region prepaid at a discount.
+-- Usage of the project's servers on the reserved billing model draws them down
+-- before it is charged at catalog prices.
+CREATE TABLE reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project VARCHAR(100) NOT NULL,
+    server_type VARCHAR(10) NOT NULL,
+    region VARCHAR(100) NOT NULL,
+    hours DOUBLE PRECISION NOT NULL CHECK (hours > 0),
+    hours_used DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (hours_used <= hours),
+    hourly_rate DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Price adjustments applied when a billing period is invoiced, in this order:
+-- volume tiers, then discounts, then credits. Each is booked to the ledger as
+-- its own negative entry.
+
+-- Percentage off the compute hours of a server type beyond from_hours in a
+-- project period, across regions, up to the next tier.
+CREATE TABLE volume_tiers (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_type VARCHAR(10) NOT NULL,
+    from_hours DOUBLE PRECISION NOT NULL CHECK (from_hours > 0),
+    percent_off DOUBLE PRECISION NOT NULL CHECK (percent_off > 0 AND percent_off <= 100),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (server_type, from_hours)
+);
+
+-- Percentage off the compute charges of a server type in a region ('*' matches
+-- any) in periods that overlap [starts_at, expires_at).
+CREATE TABLE discounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    server_type VARCHAR(10) NOT NULL DEFAULT '*',
+    region VARCHAR(100) NOT NULL DEFAULT '*',
+    percent_off DOUBLE PRECISION NOT NULL CHECK (percent_off > 0 AND percent_off <= 100),
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Promotional credit of a project, drawn down by the charges in its scope of
+-- periods that overlap [starts_at, expires_at).
+CREATE TABLE credits (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project VARCHAR(100) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    scope_type VARCHAR(15) NOT NULL DEFAULT 'project' CHECK (scope_type IN ('project', 'server_type', 'region')),
+    -- Server type or region of scoped credits; empty for project credits.
+    scope_value VARCHAR(100) NOT NULL DEFAULT '',
+    amount DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    remaining DOUBLE PRECISION NOT NULL CHECK (remaining >= 0 AND remaining <= amount),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    starts_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Append-only record of charges. Each row covers one slice of usage of one
+-- server within one billing period (period_start is the first day of the month, UTC).
+CREATE TABLE ledger_entries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project VARCHAR(100) NOT NULL,
+    server_id UUID REFERENCES servers(id),
+    segment_id UUID REFERENCES usage_segments(id),
+    period_start DATE NOT NULL,
+    charge_type VARCHAR(30) NOT NULL,
+    server_type VARCHAR(10) NOT NULL DEFAULT '',
+    region VARCHAR(100) NOT NULL DEFAULT '',
+    description TEXT NOT NULL DEFAULT '',
+    usage_start TIMESTAMPTZ NOT NULL,
+    usage_end TIMESTAMPTZ NOT NULL,
+    quantity DOUBLE PRECISION NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    unit_price DOUBLE PRECISION NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Units of a currency one USD buys, from effective_from until the next version of
+-- the same currency. USD is always 1 and has no rows.
+CREATE TABLE exchange_rates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    currency VARCHAR(3) NOT NULL CHECK (currency <> 'USD'),
+    rate DOUBLE PRECISION NOT NULL CHECK (rate > 0),
+    effective_from TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (currency, effective_from)
+);
+
+-- Billing settings of a project; projects without a row are billed in USD.
+CREATE TABLE projects (
+    name VARCHAR(100) PRIMARY KEY,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
     created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
+
+-- One invoice per project and billing period, frozen from the ledger when the period closes.
+CREATE TABLE invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project VARCHAR(100) NOT NULL,
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    -- Units of currency per USD the ledger was converted at; the ledger is in USD.
+    exchange_rate DOUBLE PRECISION NOT NULL DEFAULT 1,
+    total DOUBLE PRECISION NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (project, period_start)
+);
+
+-- Ledger entries of an invoice grouped by server, charge type and unit price.
+CREATE TABLE invoice_lines (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    invoice_id UUID NOT NULL REFERENCES invoices(id),
+    server_id UUID REFERENCES servers(id),
+    server_name VARCHAR(255) NOT NULL DEFAULT '',
+    charge_type VARCHAR(30) NOT NULL,
+    server_type VARCHAR(10) NOT NULL DEFAULT '',
+    region VARCHAR(100) NOT NULL DEFAULT '',
+    quantity DOUBLE PRECISION NOT NULL,
+    unit VARCHAR(20) NOT NULL,
+    unit_price DOUBLE PRECISION NOT NULL,
+    amount DOUBLE PRECISION NOT NULL
+);
+
+-- Spending limits on the servers of a project, a region or a tag value, evaluated
+-- by the billing daemon on every tick.
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    scope_type VARCHAR(10) NOT NULL CHECK (scope_type IN ('project', 'region', 'tag')),
+    -- Tag key of tag budgets; empty otherwise.
+    scope_key VARCHAR(255) NOT NULL DEFAULT '',
+    scope_value VARCHAR(255) NOT NULL,
+    amount DOUBLE PRECISION NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    period VARCHAR(10) NOT NULL DEFAULT 'monthly' CHECK (period IN ('daily', 'monthly')),
+    -- Percentages of the amount that raise an alert when spend reaches them.
+    thresholds INT[] NOT NULL DEFAULT '{50,80,100}',
+    webhook_url TEXT NOT NULL DEFAULT '',
+    -- Stop the running servers in scope once the whole amount is spent.
+    enforce BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per threshold crossed per budget period, so each threshold alerts once.
+CREATE TABLE budget_alerts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    budget_id UUID NOT NULL REFERENCES budgets(id) ON DELETE CASCADE,
+    period_start TIMESTAMPTZ NOT NULL,
+    threshold INT NOT NULL,
+    spend DOUBLE PRECISION NOT NULL,
+    amount DOUBLE PRECISION NOT NULL,
+    webhook_status VARCHAR(255) NOT NULL DEFAULT 'none',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (budget_id, period_start, threshold)
+);
+
+-- Prepaid balance of a project, drawn down by the billing daemon as its servers
+-- accrue cost. Projects without an account are not limited.
+CREATE TABLE accounts (
+    project VARCHAR(100) PRIMARY KEY,
+    balance DOUBLE PRECISION NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    low_balance_threshold DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (low_balance_threshold >= 0),
+    -- The project's charges, ledger and unbilled estimate, drawn from the balance
+    -- so far, and when they were last drawn.
+    charged DOUBLE PRECISION NOT NULL DEFAULT 0,
+    drawn_until TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- Set once a low-balance event is recorded, cleared by a top-up above the threshold.
+    low_balance_warned BOOLEAN NOT NULL DEFAULT FALSE,
+    -- When the balance ran out and the project's running servers were suspended.
+    suspended_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Top-ups, low-balance warnings, suspensions and resumptions of an account.
+CREATE TABLE account_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    project VARCHAR(100) NOT NULL REFERENCES accounts(project),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('topup', 'low_balance', 'exhausted', 'resumed')),
+    amount DOUBLE PRECISION NOT NULL DEFAULT 0,
+    -- Balance after the event.
+    balance DOUBLE PRECISION NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Utilization reported by servers or the telemetry generator, folded into one
+-- row per server and minute.
+CREATE TABLE server_telemetry (
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    minute TIMESTAMPTZ NOT NULL,
+    samples INT NOT NULL,
+    cpu_percent_sum DOUBLE PRECISION NOT NULL,
+    cpu_percent_max DOUBLE PRECISION NOT NULL,
+    network_rx_bytes BIGINT NOT NULL,
+    network_tx_bytes BIGINT NOT NULL,
+    PRIMARY KEY (server_id, minute)
+);
+
+-- Lifecycle history of a server, numbered per server in the order the events
+-- were recorded. from_status and to_status are set for status changes; actor is
+-- who caused the event (api, system or the daemon), payload its message and
+-- details. Kept for SERVER_EVENTS_RETENTION.
+CREATE TABLE server_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    sequence BIGINT NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    from_status VARCHAR(15),
+    to_status VARCHAR(15),
+    actor VARCHAR(20) NOT NULL,
+    request_id VARCHAR(255),
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (server_id, sequence)
+);
+
+-- Rules of the idle reaper: servers matching the type, region and tag (empty
+-- matches any) that have met the condition for duration_seconds get the action.
+-- idle_for servers are running and their telemetry averaged at most
+-- max_cpu_percent over that time.
+CREATE TABLE reaper_policies (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    match_type VARCHAR(10) NOT NULL DEFAULT '',
+    match_region VARCHAR(100) NOT NULL DEFAULT '',
+    match_tag_key VARCHAR(255) NOT NULL DEFAULT '',
+    match_tag_value VARCHAR(255) NOT NULL DEFAULT '',
+    condition VARCHAR(15) NOT NULL CHECK (condition IN ('stopped_for', 'running_for', 'idle_for')),
+    duration_seconds BIGINT NOT NULL CHECK (duration_seconds > 0),
+    max_cpu_percent DOUBLE PRECISION NOT NULL DEFAULT 0 CHECK (max_cpu_percent BETWEEN 0 AND 100),
+    action VARCHAR(10) NOT NULL CHECK (action IN ('stop', 'terminate', 'notify')),
+    webhook_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- One row per policy applied to a server, keyed by the status change the
+-- condition was measured from, so a policy acts on a server once per status.
+CREATE TABLE reaper_actions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    policy_id UUID NOT NULL REFERENCES reaper_policies(id) ON DELETE CASCADE,
+    server_id UUID NOT NULL REFERENCES servers(id) ON DELETE CASCADE,
+    action VARCHAR(10) NOT NULL,
+    state_since TIMESTAMPTZ NOT NULL,
+    outcome VARCHAR(255) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (policy_id, server_id, state_since)
+);
+
+-- The ledger and issued invoices are never changed once written.
+CREATE FUNCTION reject_modification() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER ledger_entries_append_only BEFORE UPDATE OR DELETE ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION reject_modification();
+CREATE TRIGGER invoices_append_only BEFORE UPDATE OR DELETE ON invoices
+    FOR EACH ROW EXECUTE FUNCTION reject_modification();
+CREATE TRIGGER invoice_lines_append_only BEFORE UPDATE OR DELETE ON invoice_lines
+    FOR EACH ROW EXECUTE FUNCTION reject_modification();
+
+CREATE UNIQUE INDEX idx_nat_mappings_active_public ON nat_mappings(public_address) WHERE released_at IS NULL;
+CREATE INDEX idx_nat_mappings_server_id ON nat_mappings(server_id);
+-- Hostnames are fully qualified and only need to be unique among live servers.
+CREATE UNIQUE INDEX idx_servers_live_hostname ON servers(hostname) WHERE status <> 'terminated';
+-- A server has at most one open segment.
+CREATE UNIQUE INDEX idx_usage_segments_open ON usage_segments(server_id) WHERE ended_at IS NULL;
+-- A server holds at most one disk and one public IP at a time.
+CREATE UNIQUE INDEX idx_resource_segments_open ON resource_segments(server_id, resource) WHERE ended_at IS NULL;
+CREATE INDEX idx_egress_usage_unbilled ON egress_usage(hour) WHERE NOT billed;
+CREATE INDEX idx_ip_addresses_pool_id ON ip_addresses(pool_id);
+CREATE INDEX idx_ip_addresses_address ON ip_addresses(address);
+CREATE INDEX idx_ip_addresses_server_id ON ip_addresses(server_id);
+CREATE INDEX idx_ip_addresses_interface_id ON ip_addresses(interface_id);
+CREATE INDEX idx_ledger_entries_project_period ON ledger_entries(project, period_start);
+CREATE INDEX idx_ledger_entries_server_id ON ledger_entries(server_id);
+CREATE INDEX idx_invoice_lines_invoice_id ON invoice_lines(invoice_id);
+CREATE INDEX idx_reservations_project_type_region ON reservations(project, server_type, region);
+CREATE INDEX idx_credits_project ON credits(project, expires_at);
+CREATE INDEX idx_servers_interruption_notice_at ON servers(interruption_notice_at) WHERE interruption_notice_at IS NOT NULL;
+CREATE INDEX idx_account_events_project ON account_events(project, created_at);
+CREATE INDEX idx_reaper_actions_created_at ON reaper_actions(created_at);
+CREATE INDEX idx_server_telemetry_minute ON server_telemetry(minute);
+CREATE INDEX idx_server_events_created_at ON server_events(created_at);